@@ -15,6 +15,7 @@ import (
 	"github.com/agpt-go/chatbot-api/internal/handlers"
 	"github.com/agpt-go/chatbot-api/internal/logging"
 	"github.com/agpt-go/chatbot-api/internal/middleware"
+	"github.com/agpt-go/chatbot-api/internal/sandbox"
 	"github.com/agpt-go/chatbot-api/internal/services"
 	"github.com/go-chi/chi/v5"
 	chiMiddleware "github.com/go-chi/chi/v5/middleware"
@@ -35,9 +36,13 @@ import (
 // @securityDefinitions.apikey BearerAuth
 // @in header
 // @name Authorization
-// @description JWT access token. Format: "Bearer {token}"
+// @description JWT access token or personal API key. Format: "Bearer {token}" or "Bearer agpt_..."
 
 func main() {
+	// When started as an analysis sandbox, run the script and exit before
+	// touching configuration, the database or the network
+	sandbox.RunIfChild()
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -65,11 +70,92 @@ func main() {
 	analyticsService := services.NewAnalyticsService(&cfg.Analytics)
 	defer analyticsService.Close()
 
+	// Load the business understanding schema
+	understandingSchema, err := services.LoadUnderstandingSchema(cfg.Understanding.SchemaPath)
+	if err != nil {
+		log.Fatalf("Failed to load understanding schema: %v", err)
+	}
+
+	// Load the tool permission policies
+	toolPolicies, err := services.LoadToolPolicies(cfg.Tools.PolicyPath)
+	if err != nil {
+		log.Fatalf("Failed to load tool policies: %v", err)
+	}
+
+	// Load the agent profiles users can create sessions from
+	agentProfiles, err := services.LoadAgentProfiles(cfg.Tools.ProfilesPath)
+	if err != nil {
+		log.Fatalf("Failed to load agent profiles: %v", err)
+	}
+
+	// Load the MCP servers to import tools from
+	mcpConfig, err := services.LoadMCPConfig(cfg.Tools.MCPServersPath)
+	if err != nil {
+		log.Fatalf("Failed to load MCP servers: %v", err)
+	}
+
+	// Load the keys access tokens are signed with
+	signingKeys, err := services.LoadSigningKeys(cfg.JWT.KeysPath, cfg.JWT.KeyGracePeriod)
+	if err != nil {
+		log.Fatalf("Failed to load JWT signing keys: %v", err)
+	}
+
+	// Load the OAuth providers users can sign in with, besides Google
+	oauthProviders, err := services.LoadOAuthProviders(cfg.OAuth.ProvidersPath)
+	if err != nil {
+		log.Fatalf("Failed to load OAuth providers: %v", err)
+	}
+
 	// Initialize services
 	authService := services.NewAuthService(queries, cfg)
+	authService.SetSigningKeys(signingKeys)
+	authService.SetOAuthProviders(oauthProviders)
+	mailer, err := services.NewMailer(cfg.Mail)
+	if err != nil {
+		log.Fatalf("Failed to set up mail: %v", err)
+	}
+	authService.SetMailer(mailer)
+	authService.SetAnalytics(analyticsService)
 	llmService := services.NewLLMService(&cfg.OpenAI)
-	chatService := services.NewChatService(queries, llmService, analyticsService)
+	if err := agentProfiles.CheckModels(llmService.IsModelAllowed); err != nil {
+		log.Fatalf("Invalid agent profiles: %v", err)
+	}
+	chatService := services.NewChatService(queries, llmService, analyticsService, understandingSchema, agentProfiles, services.ToolExecutorConfig{
+		Policies:    toolPolicies,
+		MaxParallel: cfg.Tools.MaxParallel,
+		Timeout:     cfg.Tools.Timeout,
+	})
+	toolAuditService := services.NewToolAuditService(queries, services.ToolAuditConfig{
+		Retention: cfg.Tools.AuditRetention,
+	})
+	chatService.GetToolService().GetRegistry().SetObserver(toolAuditService)
+	toolAuditService.Start(ctx)
+	mcpManager := services.NewMCPManager(chatService.GetToolService().GetRegistry())
+	mcpManager.Connect(ctx, mcpConfig.Servers)
+	defer mcpManager.Close()
+	webhookToolService := services.NewWebhookToolService(queries, chatService.GetToolService().GetRegistry(), services.WebhookToolsConfig{
+		AllowPrivateNetworks: cfg.Tools.WebhookAllowPrivateNetworks,
+		MaxResponseBytes:     cfg.Tools.WebhookMaxResponseBytes,
+		RefreshInterval:      cfg.Tools.WebhookRefreshInterval,
+	})
+	webhookToolService.Start(ctx)
+	attachmentService := services.NewAttachmentService(queries, services.AttachmentsConfig{
+		MaxFileBytes:    cfg.Attachments.MaxFileBytes,
+		MaxSessionBytes: cfg.Attachments.MaxSessionBytes,
+		MaxFiles:        cfg.Attachments.MaxFiles,
+	})
+	analysisRunner, err := sandbox.NewRunner(sandbox.Limits{
+		Timeout:     cfg.Tools.AnalysisTimeout,
+		MemoryBytes: cfg.Tools.AnalysisMemoryBytes,
+		MaxSteps:    cfg.Tools.AnalysisMaxSteps,
+	})
+	if err != nil {
+		log.Fatalf("Failed to set up the analysis sandbox: %v", err)
+	}
+	services.NewAnalysisService(attachmentService, analysisRunner).Register(chatService.GetToolService().GetRegistry())
 	referralService := services.NewReferralService(queries, analyticsService, cfg.Server.BaseURL, cfg.Referral.IPSalt)
+	understandingService := services.NewBusinessUnderstandingService(queries, understandingSchema)
+	organizationService := services.NewOrganizationService(queries, understandingSchema)
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(authService, analyticsService, referralService)
@@ -77,6 +163,13 @@ func main() {
 	sessionHandler := handlers.NewSessionHandler(queries)
 	openapiHandler := handlers.NewOpenAPIHandler()
 	referralHandler := handlers.NewReferralHandler(referralService)
+	understandingHandler := handlers.NewBusinessUnderstandingHandler(understandingService)
+	organizationHandler := handlers.NewOrganizationHandler(organizationService)
+	toolCallHandler := handlers.NewToolCallHandler(chatService.GetToolExecutor())
+	mcpHandler := handlers.NewMCPHandler(chatService.GetToolExecutor())
+	webhookToolHandler := handlers.NewWebhookToolHandler(webhookToolService)
+	attachmentHandler := handlers.NewAttachmentHandler(attachmentService)
+	toolAuditHandler := handlers.NewToolAuditHandler(toolAuditService, cfg.Admin.MetricsToken)
 
 	// Initialize middleware
 	authMiddleware := middleware.NewAuthMiddleware(authService)
@@ -101,6 +194,12 @@ func main() {
 		_, _ = w.Write([]byte(`{"status":"ok"}`))
 	})
 
+	// Public keys that verify access tokens
+	r.Get("/.well-known/jwks.json", authHandler.JWKS)
+
+	// Prometheus metrics (requires METRICS_TOKEN)
+	r.Get("/metrics", toolAuditHandler.ServeMetrics)
+
 	// Swagger documentation
 	r.Get("/swagger/*", httpSwagger.Handler(
 		httpSwagger.URL("/swagger/doc.json"),
@@ -114,13 +213,19 @@ func main() {
 		// Auth routes (public)
 		r.Route("/auth", func(r chi.Router) {
 			r.Post("/register", authHandler.Register)
-			r.Post("/login", authHandler.Login)
+			r.With(publicRateLimiter.Limit).Post("/login", authHandler.Login)
 			r.Post("/refresh", authHandler.Refresh)
 			r.Post("/logout", authHandler.Logout)
+			r.With(authMiddleware.RequireAuth, middleware.DenyAPIKeys).Post("/logout-all", authHandler.LogoutAll)
+			r.Post("/verify-email", authHandler.VerifyEmail)
+			r.With(publicRateLimiter.Limit).Post("/verify-email/resend", authHandler.ResendVerification)
+			r.With(publicRateLimiter.Limit).Post("/forgot-password", authHandler.ForgotPassword)
+			r.With(publicRateLimiter.Limit).Post("/reset-password", authHandler.ResetPassword)
+			r.With(publicRateLimiter.Limit).Post("/mfa/verify", authHandler.VerifyMFA)
 
 			// OAuth routes
-			r.Get("/google", authHandler.GoogleLogin)
-			r.Get("/google/callback", authHandler.GoogleCallback)
+			r.Get("/{provider}", authHandler.OAuthLogin)
+			r.Get("/{provider}/callback", authHandler.OAuthCallback)
 		})
 
 		// Public referral routes (for tracking clicks and validation)
@@ -135,30 +240,143 @@ func main() {
 		r.Group(func(r chi.Router) {
 			r.Use(authMiddleware.RequireAuth)
 
-			// User routes
-			r.Get("/me", sessionHandler.GetCurrentUser)
-
-			// Chat session routes
-			r.Route("/sessions", func(r chi.Router) {
-				r.Post("/", chatHandler.CreateSession)
-				r.Get("/", chatHandler.ListSessions)
-				r.Get("/{sessionID}", chatHandler.GetSession)
-				r.Patch("/{sessionID}", chatHandler.UpdateSession)
-				r.Delete("/{sessionID}", chatHandler.DeleteSession)
-
-				// Messages
-				r.Get("/{sessionID}/messages", chatHandler.GetMessages)
-				r.Post("/{sessionID}/messages", chatHandler.SendMessage)
-				r.Post("/{sessionID}/messages/stream", chatHandler.SendMessageStream)
+			// Account routes, which need a signed-in user rather than an API key
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.DenyAPIKeys)
+
+				// User routes
+				r.Get("/me", sessionHandler.GetCurrentUser)
+				r.Post("/me/verify-email/resend", authHandler.SendVerificationEmail)
+				r.Post("/me/password", authHandler.ChangePassword)
+				r.Get("/me/devices", authHandler.ListDevices)
+				r.Delete("/me/devices/{deviceID}", authHandler.RevokeDevice)
+				r.Get("/me/identities", authHandler.ListIdentities)
+				r.Post("/me/identities/link", authHandler.LinkIdentity)
+				r.Delete("/me/identities/{identityID}", authHandler.UnlinkIdentity)
+
+				// MFA enrollment, reachable before MFA is enabled
+				r.Get("/me/mfa", authHandler.GetMFAStatus)
+				r.Post("/me/mfa/totp", authHandler.BeginTOTPEnrollment)
+				r.Post("/me/mfa/totp/confirm", authHandler.ConfirmTOTPEnrollment)
+				r.Post("/me/mfa/disable", authHandler.DisableMFA)
+				r.Post("/me/mfa/recovery-codes", authHandler.RegenerateRecoveryCodes)
+
+				// Personal API keys
+				r.Get("/me/api-keys", authHandler.ListAPIKeys)
+				r.Post("/me/api-keys", authHandler.CreateAPIKey)
+				r.Delete("/me/api-keys/{keyID}", authHandler.RevokeAPIKey)
 			})
 
-			// Protected referral routes (for authenticated users)
-			r.Route("/referral", func(r chi.Router) {
-				r.Get("/code", referralHandler.GetReferralCode)
-				r.Post("/share", referralHandler.RecordShare)
-				r.Get("/stats", referralHandler.GetReferralStats)
-				r.Get("/referred", referralHandler.GetReferredUsers)
-				r.Get("/shares", referralHandler.GetShareHistory)
+			// Everything else waits until users who must use MFA have enabled it
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.RequireMFA(authService.MFASatisfied))
+
+				// Chat routes, which can require a verified email address
+				r.Group(func(r chi.Router) {
+					if cfg.Auth.RequireVerifiedEmail {
+						r.Use(middleware.RequireVerifiedEmail(authService.IsEmailVerified))
+					}
+
+					// Chat session routes
+					chatScopes := middleware.RequireScopes(services.ScopeChatRead, services.ScopeChatWrite)
+					r.With(chatScopes).Get("/profiles", chatHandler.ListProfiles)
+					r.With(chatScopes).Get("/tools", chatHandler.ListTools)
+
+					r.Route("/sessions", func(r chi.Router) {
+						r.Use(chatScopes)
+						r.Post("/", chatHandler.CreateSession)
+						r.Get("/", chatHandler.ListSessions)
+						r.Get("/{sessionID}", chatHandler.GetSession)
+						r.Patch("/{sessionID}", chatHandler.UpdateSession)
+						r.Delete("/{sessionID}", chatHandler.DeleteSession)
+
+						// Messages
+						r.Get("/{sessionID}/messages", chatHandler.GetMessages)
+						r.Post("/{sessionID}/messages", chatHandler.SendMessage)
+						r.Post("/{sessionID}/messages/stream", chatHandler.SendMessageStream)
+
+						// Tool calls waiting for confirmation
+						r.Get("/{sessionID}/tool-calls", toolCallHandler.ListPendingToolCalls)
+						r.Post("/{sessionID}/tool-calls/{toolCallID}/approve", toolCallHandler.ApproveToolCall)
+						r.Post("/{sessionID}/tool-calls/{toolCallID}/reject", toolCallHandler.RejectToolCall)
+
+						// Files the assistant can analyze with run_analysis
+						r.Post("/{sessionID}/attachments", attachmentHandler.UploadAttachment)
+						r.Get("/{sessionID}/attachments", attachmentHandler.ListAttachments)
+						r.Delete("/{sessionID}/attachments/{attachmentID}", attachmentHandler.DeleteAttachment)
+					})
+
+					// MCP endpoint (the chatbot's tools for external agents)
+					mcpScope := middleware.RequireScopes(services.ScopeMCP, services.ScopeMCP)
+					r.With(mcpScope).Post("/mcp", mcpHandler.ServeMCP)
+					r.With(mcpScope).Get("/mcp", mcpHandler.RejectMCPStream)
+					r.With(mcpScope).Delete("/mcp", mcpHandler.RejectMCPStream)
+				})
+
+				// Protected referral routes (for authenticated users)
+				r.Route("/referral", func(r chi.Router) {
+					r.Use(middleware.DenyAPIKeys)
+					r.Get("/code", referralHandler.GetReferralCode)
+					r.Post("/share", referralHandler.RecordShare)
+					r.Get("/stats", referralHandler.GetReferralStats)
+					r.Get("/referred", referralHandler.GetReferredUsers)
+					r.Get("/shares", referralHandler.GetShareHistory)
+				})
+
+				// Business understanding routes (review and correct what the model recorded)
+				r.Route("/business-understanding", func(r chi.Router) {
+					r.Use(middleware.RequireScopes(services.ScopeReportsRead, services.ScopeReportsWrite))
+					r.Get("/", understandingHandler.GetBusinessUnderstanding)
+					r.Get("/schema", understandingHandler.GetBusinessUnderstandingSchema)
+					r.Patch("/", understandingHandler.UpdateBusinessUnderstanding)
+					r.Delete("/", understandingHandler.DeleteBusinessUnderstanding)
+					r.Get("/changes", understandingHandler.ListBusinessUnderstandingChanges)
+					r.Post("/changes/{changeID}/revert", understandingHandler.RevertBusinessUnderstandingChange)
+				})
+
+				// Admin routes
+				r.Route("/admin", func(r chi.Router) {
+					r.Use(middleware.DenyAPIKeys)
+					r.Use(middleware.RequireAdmin(cfg.Admin.UserIDs))
+
+					// Tools that call external HTTP endpoints
+					r.Get("/webhook-tools", webhookToolHandler.ListWebhookTools)
+					r.Post("/webhook-tools", webhookToolHandler.CreateWebhookTool)
+					r.Get("/webhook-tools/{toolID}", webhookToolHandler.GetWebhookTool)
+					r.Put("/webhook-tools/{toolID}", webhookToolHandler.UpdateWebhookTool)
+					r.Delete("/webhook-tools/{toolID}", webhookToolHandler.DeleteWebhookTool)
+
+					// Tool call audit log and metrics
+					r.Get("/tool-executions", toolAuditHandler.ListToolExecutions)
+					r.Get("/tool-executions/{executionID}", toolAuditHandler.GetToolExecution)
+					r.Get("/tool-metrics", toolAuditHandler.GetToolMetrics)
+
+					// Sign a user out of every device at once
+					r.Post("/users/{userID}/logout", authHandler.AdminLogoutUser)
+
+					// Require or reset a user's MFA
+					r.Put("/users/{userID}/mfa", authHandler.AdminSetMFARequired)
+					r.Delete("/users/{userID}/mfa", authHandler.AdminResetMFA)
+				})
+
+				// Organization routes (shared company-level business understanding)
+				r.Route("/organizations", func(r chi.Router) {
+					r.Use(middleware.DenyAPIKeys)
+					r.Post("/", organizationHandler.CreateOrganization)
+					r.Get("/me", organizationHandler.GetOrganization)
+					r.Get("/me/invitations", organizationHandler.ListOrganizationInvitations)
+					r.Post("/me/invitations", organizationHandler.InviteOrganizationMember)
+					r.Delete("/me/invitations/{invitationID}", organizationHandler.RevokeOrganizationInvitation)
+					r.Patch("/me/members/{userID}", organizationHandler.UpdateOrganizationMember)
+					r.Delete("/me/members/{userID}", organizationHandler.RemoveOrganizationMember)
+					r.Get("/me/understanding", organizationHandler.GetOrganizationUnderstanding)
+					r.Patch("/me/understanding", organizationHandler.UpdateOrganizationUnderstanding)
+
+					// Invitations addressed to the current user
+					r.Get("/invitations", organizationHandler.ListMyInvitations)
+					r.Post("/invitations/{invitationID}/accept", organizationHandler.AcceptInvitation)
+					r.Post("/invitations/{invitationID}/decline", organizationHandler.DeclineInvitation)
+				})
 			})
 		})
 	})
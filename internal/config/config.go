@@ -5,8 +5,10 @@ import (
 	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/joho/godotenv"
 )
 
@@ -14,10 +16,51 @@ type Config struct {
 	Server    ServerConfig
 	Database  DatabaseConfig
 	JWT       JWTConfig
+	Auth      AuthConfig
+	Mail      MailConfig
 	OAuth     OAuthConfig
 	OpenAI    OpenAIConfig
 	Analytics AnalyticsConfig
 	Referral  ReferralConfig
+
+	Understanding UnderstandingConfig
+	Tools         ToolsConfig
+	Attachments   AttachmentsConfig
+	Admin         AdminConfig
+}
+
+type AttachmentsConfig struct {
+	MaxFileBytes    int64 // Largest file that can be uploaded to a chat session
+	MaxSessionBytes int64 // Total size of a session's files
+	MaxFiles        int   // Files per session
+}
+
+type AdminConfig struct {
+	UserIDs      []uuid.UUID // Users allowed to manage platform-wide settings such as webhook tools
+	MetricsToken string      // Bearer token for scraping /metrics; empty disables the endpoint
+}
+
+type UnderstandingConfig struct {
+	SchemaPath string // YAML or JSON file defining business understanding fields; empty uses the built-in schema
+}
+
+type ToolsConfig struct {
+	PolicyPath   string        // YAML or JSON file with per-tool permission policies; empty uses the built-in policies
+	ProfilesPath string        // YAML or JSON file with agent profiles; empty uses the built-in profiles
+	MaxParallel  int           // Tool calls from one model step that run at once
+	Timeout      time.Duration // Time limit for a single tool call
+
+	MCPServersPath string // YAML or JSON file listing MCP servers whose tools are imported; empty imports none
+
+	WebhookAllowPrivateNetworks bool          // Let webhook tools call private network addresses (development only)
+	WebhookMaxResponseBytes     int64         // Webhook responses larger than this fail the call
+	WebhookRefreshInterval      time.Duration // How often webhook tool definitions are reloaded from the database
+
+	AnalysisTimeout     time.Duration // Wall-clock limit for one run_analysis script
+	AnalysisMemoryBytes int64         // Memory one run_analysis script may use
+	AnalysisMaxSteps    uint64        // Interpreter steps one run_analysis script may take (CPU limit)
+
+	AuditRetention time.Duration // How long tool executions are kept in the audit log; negative keeps them forever
 }
 
 type ReferralConfig struct {
@@ -55,17 +98,48 @@ type JWTConfig struct {
 	AccessExpiresIn  time.Duration
 	RefreshExpiresIn time.Duration
 	Issuer           string
+	KeysPath         string        // YAML or JSON file with RS256/EdDSA signing keys; empty signs with Secret (HS256)
+	KeyGracePeriod   time.Duration // How long a replaced signing key still verifies tokens; defaults to AccessExpiresIn
+}
+
+type AuthConfig struct {
+	RequireVerifiedEmail       bool          // Block chat endpoints until the user verifies their email address
+	EmailVerificationExpiresIn time.Duration // How long a verification link stays valid
+	EmailVerificationURL       string        // Page that receives ?token= and posts it to /auth/verify-email
+	PasswordResetExpiresIn     time.Duration // How long a password reset link stays valid
+	PasswordResetURL           string        // Page that receives ?token= and posts it with a new password to /auth/reset-password
+	RevocationCacheTTL         time.Duration // How long an instance caches a user's revoked access tokens
+
+	MFARequired      bool   // Block chat and admin endpoints until every user enables MFA
+	MFAIssuer        string // Account issuer shown in authenticator apps
+	MFAEncryptionKey string // Encrypts authenticator secrets; empty derives a key from JWT_SECRET
+}
+
+type MailConfig struct {
+	Transport string // smtp, file or log
+	From      string // Sender address
+
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string // Empty sends without authenticating
+	SMTPPassword string
+
+	Dir string // Directory the file transport writes messages to
 }
 
 type OAuthConfig struct {
 	GoogleClientID     string
 	GoogleClientSecret string
 	GoogleRedirectURL  string
+
+	ProvidersPath   string // Other OAuth and OIDC providers
+	RedirectBaseURL string // Provider callbacks default to RedirectBaseURL/{provider}/callback
 }
 
 type OpenAIConfig struct {
-	APIKey string
-	Model  string
+	APIKey        string
+	Model         string   // Default model
+	AllowedModels []string // Other models sessions and agent profiles can choose
 }
 
 func (d DatabaseConfig) ConnectionString() string {
@@ -144,11 +218,34 @@ func Load() (*Config, error) {
 			AccessExpiresIn:  time.Duration(getEnvAsInt("JWT_ACCESS_EXPIRES_MINUTES", 15)) * time.Minute,
 			RefreshExpiresIn: time.Duration(getEnvAsInt("JWT_REFRESH_EXPIRES_DAYS", 7)) * 24 * time.Hour,
 			Issuer:           getEnv("JWT_ISSUER", "chatbot-api"),
+			KeysPath:         getEnv("JWT_KEYS_PATH", ""),
+			KeyGracePeriod:   time.Duration(getEnvAsInt("JWT_KEY_GRACE_MINUTES", getEnvAsInt("JWT_ACCESS_EXPIRES_MINUTES", 15))) * time.Minute,
+		},
+		Auth: AuthConfig{
+			RequireVerifiedEmail:       getEnv("REQUIRE_VERIFIED_EMAIL", "false") == "true",
+			EmailVerificationExpiresIn: time.Duration(getEnvAsInt("EMAIL_VERIFICATION_EXPIRES_HOURS", 24)) * time.Hour,
+			PasswordResetExpiresIn:     time.Duration(getEnvAsInt("PASSWORD_RESET_EXPIRES_MINUTES", 60)) * time.Minute,
+			RevocationCacheTTL:         time.Duration(getEnvAsInt("AUTH_REVOCATION_CACHE_SECONDS", 30)) * time.Second,
+
+			MFARequired:      getEnv("MFA_REQUIRED", "false") == "true",
+			MFAIssuer:        getEnv("MFA_ISSUER", "Chatbot"),
+			MFAEncryptionKey: getEnv("MFA_ENCRYPTION_KEY", ""),
+		},
+		Mail: MailConfig{
+			Transport:    getEnv("MAIL_TRANSPORT", "log"),
+			From:         getEnv("MAIL_FROM", "no-reply@localhost"),
+			SMTPHost:     getEnv("SMTP_HOST", ""),
+			SMTPPort:     getEnv("SMTP_PORT", "587"),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			Dir:          getEnv("MAIL_DIR", "tmp/mail"),
 		},
 		OAuth: OAuthConfig{
 			GoogleClientID:     getEnv("GOOGLE_CLIENT_ID", ""),
 			GoogleClientSecret: getEnv("GOOGLE_CLIENT_SECRET", ""),
 			GoogleRedirectURL:  getEnv("GOOGLE_REDIRECT_URL", "http://localhost:8080/api/v1/auth/google/callback"),
+			ProvidersPath:      getEnv("OAUTH_PROVIDERS_PATH", ""),
+			RedirectBaseURL:    getEnv("OAUTH_REDIRECT_BASE_URL", "http://localhost:8080/api/v1/auth"),
 		},
 		OpenAI: OpenAIConfig{
 			APIKey: getEnv("OPENAI_API_KEY", ""),
@@ -163,6 +260,52 @@ func Load() (*Config, error) {
 			// Troy Hunt: Use environment variable for salt, with a random default for dev
 			IPSalt: getEnv("REFERRAL_IP_SALT", "dev-referral-salt-change-in-production"),
 		},
+		Understanding: UnderstandingConfig{
+			SchemaPath: getEnv("UNDERSTANDING_SCHEMA_PATH", ""),
+		},
+		Tools: ToolsConfig{
+			PolicyPath:   getEnv("TOOL_POLICY_PATH", ""),
+			ProfilesPath: getEnv("AGENT_PROFILES_PATH", ""),
+			MaxParallel:  getEnvAsInt("TOOL_MAX_PARALLEL", 4),
+			Timeout:      time.Duration(getEnvAsInt("TOOL_TIMEOUT_SECONDS", 30)) * time.Second,
+
+			MCPServersPath: getEnv("MCP_SERVERS_PATH", ""),
+
+			WebhookAllowPrivateNetworks: getEnv("WEBHOOK_ALLOW_PRIVATE_NETWORKS", "false") == "true",
+			WebhookMaxResponseBytes:     int64(getEnvAsInt("WEBHOOK_MAX_RESPONSE_BYTES", 1<<20)),
+			WebhookRefreshInterval:      time.Duration(getEnvAsInt("WEBHOOK_REFRESH_SECONDS", 60)) * time.Second,
+
+			AnalysisTimeout:     time.Duration(getEnvAsInt("ANALYSIS_TIMEOUT_SECONDS", 30)) * time.Second,
+			AnalysisMemoryBytes: int64(getEnvAsInt("ANALYSIS_MEMORY_BYTES", 256<<20)),
+			AnalysisMaxSteps:    uint64(getEnvAsInt("ANALYSIS_MAX_STEPS", 100_000_000)),
+		},
+		Attachments: AttachmentsConfig{
+			MaxFileBytes:    int64(getEnvAsInt("ATTACHMENT_MAX_FILE_BYTES", 10<<20)),
+			MaxSessionBytes: int64(getEnvAsInt("ATTACHMENT_MAX_SESSION_BYTES", 25<<20)),
+			MaxFiles:        getEnvAsInt("ATTACHMENT_MAX_FILES", 20),
+		},
+	}
+
+	adminIDs, err := parseUUIDList(getEnv("ADMIN_USER_IDS", ""))
+	if err != nil {
+		return nil, fmt.Errorf("ADMIN_USER_IDS: %w", err)
+	}
+	cfg.Admin.UserIDs = adminIDs
+	cfg.Admin.MetricsToken = getEnv("METRICS_TOKEN", "")
+	cfg.OpenAI.AllowedModels = parseList(getEnv("OPENAI_ALLOWED_MODELS", ""))
+	cfg.Auth.EmailVerificationURL = getEnv("EMAIL_VERIFICATION_URL", "")
+	if cfg.Auth.EmailVerificationURL == "" {
+		cfg.Auth.EmailVerificationURL = cfg.Server.BaseURL + "/verify-email"
+	}
+	cfg.Auth.PasswordResetURL = getEnv("PASSWORD_RESET_URL", "")
+	if cfg.Auth.PasswordResetURL == "" {
+		cfg.Auth.PasswordResetURL = cfg.Server.BaseURL + "/reset-password"
+	}
+
+	// 0 days keeps the tool audit log forever
+	cfg.Tools.AuditRetention = -1
+	if days := getEnvAsInt("TOOL_AUDIT_RETENTION_DAYS", 30); days > 0 {
+		cfg.Tools.AuditRetention = time.Duration(days) * 24 * time.Hour
 	}
 
 	if err := cfg.Validate(); err != nil {
@@ -179,6 +322,19 @@ func (c *Config) Validate() error {
 	if c.OpenAI.APIKey == "" {
 		return fmt.Errorf("OPENAI_API_KEY is required")
 	}
+	switch c.Mail.Transport {
+	case "", "log", "file":
+		// These keep every email where operators can read it, sign-in links included
+		if c.Server.Environment != "development" {
+			return fmt.Errorf("MAIL_TRANSPORT must be smtp outside development")
+		}
+	case "smtp":
+		if c.Mail.SMTPHost == "" {
+			return fmt.Errorf("SMTP_HOST is required when MAIL_TRANSPORT is smtp")
+		}
+	default:
+		return fmt.Errorf("MAIL_TRANSPORT must be smtp, file or log")
+	}
 	return nil
 }
 
@@ -197,3 +353,31 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// parseList parses a comma separated list, skipping empty items
+func parseList(value string) []string {
+	var items []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			items = append(items, part)
+		}
+	}
+	return items
+}
+
+// parseUUIDList parses a comma separated list of UUIDs
+func parseUUIDList(value string) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		id, err := uuid.Parse(part)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user ID %q", part)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -32,11 +32,44 @@ func TestConfigValidate(t *testing.T) {
 		{
 			name: "valid config",
 			cfg: &Config{
+				Server: ServerConfig{Environment: "development"},
 				JWT:    JWTConfig{Secret: "test-secret"},
 				OpenAI: OpenAIConfig{APIKey: "test-key"},
 			},
 			wantErr: false,
 		},
+		{
+			name: "log mail transport in production",
+			cfg: &Config{
+				Server: ServerConfig{Environment: "production"},
+				JWT:    JWTConfig{Secret: "test-secret"},
+				OpenAI: OpenAIConfig{APIKey: "test-key"},
+				Mail:   MailConfig{Transport: "log"},
+			},
+			wantErr: true,
+			errMsg:  "MAIL_TRANSPORT must be smtp outside development",
+		},
+		{
+			name: "file mail transport in staging",
+			cfg: &Config{
+				Server: ServerConfig{Environment: "staging"},
+				JWT:    JWTConfig{Secret: "test-secret"},
+				OpenAI: OpenAIConfig{APIKey: "test-key"},
+				Mail:   MailConfig{Transport: "file"},
+			},
+			wantErr: true,
+			errMsg:  "MAIL_TRANSPORT must be smtp outside development",
+		},
+		{
+			name: "smtp mail transport in production",
+			cfg: &Config{
+				Server: ServerConfig{Environment: "production"},
+				JWT:    JWTConfig{Secret: "test-secret"},
+				OpenAI: OpenAIConfig{APIKey: "test-key"},
+				Mail:   MailConfig{Transport: "smtp", SMTPHost: "smtp.example.com"},
+			},
+			wantErr: false,
+		},
 		{
 			name: "missing JWT secret",
 			cfg: &Config{
@@ -150,6 +183,8 @@ func TestLoadWithCustomValues(t *testing.T) {
 	t.Setenv("OPENAI_API_KEY", "custom-key")
 	t.Setenv("PORT", "9000")
 	t.Setenv("ENVIRONMENT", "production")
+	t.Setenv("MAIL_TRANSPORT", "smtp")
+	t.Setenv("SMTP_HOST", "smtp.example.com")
 	t.Setenv("DB_HOST", "db.example.com")
 	t.Setenv("DB_MAX_CONNS", "50")
 	t.Setenv("OPENAI_MODEL", "gpt-4-turbo")
@@ -13,9 +13,11 @@ import (
 )
 
 const cleanExpiredTokens = `-- name: CleanExpiredTokens :execrows
-DELETE FROM refresh_tokens WHERE expires_at < NOW() OR revoked = TRUE
+DELETE FROM refresh_tokens
+WHERE expires_at < NOW() OR (revoked = TRUE AND rotated_at IS NULL)
 `
 
+// Rotated tokens are kept until they expire so their reuse can be detected
 func (q *Queries) CleanExpiredTokens(ctx context.Context) (int64, error) {
 	result, err := q.db.Exec(ctx, cleanExpiredTokens)
 	if err != nil {
@@ -24,20 +26,68 @@ func (q *Queries) CleanExpiredTokens(ctx context.Context) (int64, error) {
 	return result.RowsAffected(), nil
 }
 
-const createRefreshToken = `-- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (user_id, token_hash, expires_at)
+const consumePasswordResetToken = `-- name: ConsumePasswordResetToken :one
+DELETE FROM password_reset_tokens
+WHERE token_hash = $1
+RETURNING id, user_id, token_hash, expires_at, created_at
+`
+
+// Deleting the token as it is read makes it single-use
+func (q *Queries) ConsumePasswordResetToken(ctx context.Context, tokenHash string) (PasswordResetToken, error) {
+	row := q.db.QueryRow(ctx, consumePasswordResetToken, tokenHash)
+	var i PasswordResetToken
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.TokenHash,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const createPasswordResetToken = `-- name: CreatePasswordResetToken :exec
+INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
 VALUES ($1, $2, $3)
-RETURNING id, user_id, token_hash, expires_at, revoked, created_at
 `
 
-type CreateRefreshTokenParams struct {
+type CreatePasswordResetTokenParams struct {
 	UserID    uuid.UUID `json:"user_id"`
 	TokenHash string    `json:"token_hash"`
 	ExpiresAt time.Time `json:"expires_at"`
 }
 
+func (q *Queries) CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) error {
+	_, err := q.db.Exec(ctx, createPasswordResetToken, arg.UserID, arg.TokenHash, arg.ExpiresAt)
+	return err
+}
+
+const createRefreshToken = `-- name: CreateRefreshToken :one
+INSERT INTO refresh_tokens (user_id, token_hash, expires_at, family_id, user_agent, ip_hash, signed_in_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, user_id, token_hash, expires_at, revoked, created_at, family_id, rotated_at, user_agent, ip_hash, signed_in_at
+`
+
+type CreateRefreshTokenParams struct {
+	UserID     uuid.UUID `json:"user_id"`
+	TokenHash  string    `json:"token_hash"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	FamilyID   uuid.UUID `json:"family_id"`
+	UserAgent  *string   `json:"user_agent"`
+	IpHash     *string   `json:"ip_hash"`
+	SignedInAt time.Time `json:"signed_in_at"`
+}
+
 func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error) {
-	row := q.db.QueryRow(ctx, createRefreshToken, arg.UserID, arg.TokenHash, arg.ExpiresAt)
+	row := q.db.QueryRow(ctx, createRefreshToken,
+		arg.UserID,
+		arg.TokenHash,
+		arg.ExpiresAt,
+		arg.FamilyID,
+		arg.UserAgent,
+		arg.IpHash,
+		arg.SignedInAt,
+	)
 	var i RefreshToken
 	err := row.Scan(
 		&i.ID,
@@ -46,12 +96,50 @@ func (q *Queries) CreateRefreshToken(ctx context.Context, arg CreateRefreshToken
 		&i.ExpiresAt,
 		&i.Revoked,
 		&i.CreatedAt,
+		&i.FamilyID,
+		&i.RotatedAt,
+		&i.UserAgent,
+		&i.IpHash,
+		&i.SignedInAt,
 	)
 	return i, err
 }
 
+const deleteExpiredRevokedAccessTokens = `-- name: DeleteExpiredRevokedAccessTokens :execrows
+DELETE FROM revoked_access_tokens WHERE expires_at <= NOW()
+`
+
+func (q *Queries) DeleteExpiredRevokedAccessTokens(ctx context.Context) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteExpiredRevokedAccessTokens)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteExpiredRevokedSessions = `-- name: DeleteExpiredRevokedSessions :execrows
+DELETE FROM revoked_sessions WHERE expires_at <= NOW()
+`
+
+func (q *Queries) DeleteExpiredRevokedSessions(ctx context.Context) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteExpiredRevokedSessions)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteUserPasswordResetTokens = `-- name: DeleteUserPasswordResetTokens :exec
+DELETE FROM password_reset_tokens WHERE user_id = $1
+`
+
+func (q *Queries) DeleteUserPasswordResetTokens(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteUserPasswordResetTokens, userID)
+	return err
+}
+
 const getRefreshToken = `-- name: GetRefreshToken :one
-SELECT id, user_id, token_hash, expires_at, revoked, created_at FROM refresh_tokens
+SELECT id, user_id, token_hash, expires_at, revoked, created_at, family_id, rotated_at, user_agent, ip_hash, signed_in_at FROM refresh_tokens
 WHERE token_hash = $1 AND revoked = FALSE AND expires_at > NOW()
 `
 
@@ -65,10 +153,145 @@ func (q *Queries) GetRefreshToken(ctx context.Context, tokenHash string) (Refres
 		&i.ExpiresAt,
 		&i.Revoked,
 		&i.CreatedAt,
+		&i.FamilyID,
+		&i.RotatedAt,
+		&i.UserAgent,
+		&i.IpHash,
+		&i.SignedInAt,
+	)
+	return i, err
+}
+
+const getRefreshTokenByHash = `-- name: GetRefreshTokenByHash :one
+SELECT id, user_id, token_hash, expires_at, revoked, created_at, family_id, rotated_at, user_agent, ip_hash, signed_in_at FROM refresh_tokens WHERE token_hash = $1
+`
+
+// Unlike GetRefreshToken this also finds revoked and expired tokens
+func (q *Queries) GetRefreshTokenByHash(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	row := q.db.QueryRow(ctx, getRefreshTokenByHash, tokenHash)
+	var i RefreshToken
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.TokenHash,
+		&i.ExpiresAt,
+		&i.Revoked,
+		&i.CreatedAt,
+		&i.FamilyID,
+		&i.RotatedAt,
+		&i.UserAgent,
+		&i.IpHash,
+		&i.SignedInAt,
 	)
 	return i, err
 }
 
+const listUserDevices = `-- name: ListUserDevices :many
+SELECT id, user_id, token_hash, expires_at, revoked, created_at, family_id, rotated_at, user_agent, ip_hash, signed_in_at FROM refresh_tokens
+WHERE user_id = $1 AND revoked = FALSE AND expires_at > NOW()
+ORDER BY created_at DESC
+`
+
+// A device is a token family; only its newest token is still usable
+func (q *Queries) ListUserDevices(ctx context.Context, userID uuid.UUID) ([]RefreshToken, error) {
+	rows, err := q.db.Query(ctx, listUserDevices, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []RefreshToken{}
+	for rows.Next() {
+		var i RefreshToken
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.TokenHash,
+			&i.ExpiresAt,
+			&i.Revoked,
+			&i.CreatedAt,
+			&i.FamilyID,
+			&i.RotatedAt,
+			&i.UserAgent,
+			&i.IpHash,
+			&i.SignedInAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserRevokedAccessTokens = `-- name: ListUserRevokedAccessTokens :many
+SELECT jti FROM revoked_access_tokens
+WHERE user_id = $1 AND expires_at > NOW()
+`
+
+func (q *Queries) ListUserRevokedAccessTokens(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx, listUserRevokedAccessTokens, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []uuid.UUID{}
+	for rows.Next() {
+		var jti uuid.UUID
+		if err := rows.Scan(&jti); err != nil {
+			return nil, err
+		}
+		items = append(items, jti)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listUserRevokedSessions = `-- name: ListUserRevokedSessions :many
+SELECT family_id FROM revoked_sessions
+WHERE user_id = $1 AND expires_at > NOW()
+`
+
+func (q *Queries) ListUserRevokedSessions(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	rows, err := q.db.Query(ctx, listUserRevokedSessions, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []uuid.UUID{}
+	for rows.Next() {
+		var family_id uuid.UUID
+		if err := rows.Scan(&family_id); err != nil {
+			return nil, err
+		}
+		items = append(items, family_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const revokeAccessToken = `-- name: RevokeAccessToken :exec
+INSERT INTO revoked_access_tokens (jti, user_id, expires_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (jti) DO NOTHING
+`
+
+type RevokeAccessTokenParams struct {
+	Jti       uuid.UUID `json:"jti"`
+	UserID    uuid.UUID `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (q *Queries) RevokeAccessToken(ctx context.Context, arg RevokeAccessTokenParams) error {
+	_, err := q.db.Exec(ctx, revokeAccessToken, arg.Jti, arg.UserID, arg.ExpiresAt)
+	return err
+}
+
 const revokeAllUserTokens = `-- name: RevokeAllUserTokens :exec
 UPDATE refresh_tokens SET revoked = TRUE WHERE user_id = $1
 `
@@ -86,3 +309,78 @@ func (q *Queries) RevokeRefreshToken(ctx context.Context, tokenHash string) erro
 	_, err := q.db.Exec(ctx, revokeRefreshToken, tokenHash)
 	return err
 }
+
+const revokeRefreshTokenFamily = `-- name: RevokeRefreshTokenFamily :execrows
+UPDATE refresh_tokens SET revoked = TRUE
+WHERE family_id = $1 AND revoked = FALSE
+`
+
+func (q *Queries) RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) (int64, error) {
+	result, err := q.db.Exec(ctx, revokeRefreshTokenFamily, familyID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const revokeSession = `-- name: RevokeSession :exec
+INSERT INTO revoked_sessions (family_id, user_id, expires_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (family_id) DO UPDATE SET expires_at = GREATEST(revoked_sessions.expires_at, EXCLUDED.expires_at)
+`
+
+type RevokeSessionParams struct {
+	FamilyID  uuid.UUID `json:"family_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (q *Queries) RevokeSession(ctx context.Context, arg RevokeSessionParams) error {
+	_, err := q.db.Exec(ctx, revokeSession, arg.FamilyID, arg.UserID, arg.ExpiresAt)
+	return err
+}
+
+const revokeUserRefreshTokenFamily = `-- name: RevokeUserRefreshTokenFamily :execrows
+UPDATE refresh_tokens SET revoked = TRUE
+WHERE user_id = $1 AND family_id = $2 AND revoked = FALSE
+`
+
+type RevokeUserRefreshTokenFamilyParams struct {
+	UserID   uuid.UUID `json:"user_id"`
+	FamilyID uuid.UUID `json:"family_id"`
+}
+
+func (q *Queries) RevokeUserRefreshTokenFamily(ctx context.Context, arg RevokeUserRefreshTokenFamilyParams) (int64, error) {
+	result, err := q.db.Exec(ctx, revokeUserRefreshTokenFamily, arg.UserID, arg.FamilyID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const rotateRefreshToken = `-- name: RotateRefreshToken :one
+UPDATE refresh_tokens SET revoked = TRUE, rotated_at = NOW()
+WHERE token_hash = $1 AND revoked = FALSE AND expires_at > NOW()
+RETURNING id, user_id, token_hash, expires_at, revoked, created_at, family_id, rotated_at, user_agent, ip_hash, signed_in_at
+`
+
+// Revokes a usable token and marks it rotated in one statement, so only one
+// of several concurrent refreshes with the same token succeeds
+func (q *Queries) RotateRefreshToken(ctx context.Context, tokenHash string) (RefreshToken, error) {
+	row := q.db.QueryRow(ctx, rotateRefreshToken, tokenHash)
+	var i RefreshToken
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.TokenHash,
+		&i.ExpiresAt,
+		&i.Revoked,
+		&i.CreatedAt,
+		&i.FamilyID,
+		&i.RotatedAt,
+		&i.UserAgent,
+		&i.IpHash,
+		&i.SignedInAt,
+	)
+	return i, err
+}
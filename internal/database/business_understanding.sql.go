@@ -12,7 +12,7 @@ import (
 )
 
 const getBusinessUnderstanding = `-- name: GetBusinessUnderstanding :one
-SELECT id, user_id, user_name, job_title, business_name, industry, business_size, user_role, key_workflows, daily_activities, pain_points, bottlenecks, manual_tasks, automation_goals, current_software, existing_automation, additional_notes, created_at, updated_at FROM business_understanding WHERE user_id = $1
+SELECT id, user_id, created_at, updated_at, data FROM business_understanding WHERE user_id = $1
 `
 
 func (q *Queries) GetBusinessUnderstanding(ctx context.Context, userID uuid.UUID) (BusinessUnderstanding, error) {
@@ -21,136 +21,333 @@ func (q *Queries) GetBusinessUnderstanding(ctx context.Context, userID uuid.UUID
 	err := row.Scan(
 		&i.ID,
 		&i.UserID,
-		&i.UserName,
-		&i.JobTitle,
-		&i.BusinessName,
-		&i.Industry,
-		&i.BusinessSize,
-		&i.UserRole,
-		&i.KeyWorkflows,
-		&i.DailyActivities,
-		&i.PainPoints,
-		&i.Bottlenecks,
-		&i.ManualTasks,
-		&i.AutomationGoals,
-		&i.CurrentSoftware,
-		&i.ExistingAutomation,
-		&i.AdditionalNotes,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Data,
 	)
 	return i, err
 }
 
-const upsertBusinessUnderstanding = `-- name: UpsertBusinessUnderstanding :one
-INSERT INTO business_understanding (
-    user_id,
-    user_name,
-    job_title,
-    business_name,
-    industry,
-    business_size,
-    user_role,
-    key_workflows,
-    daily_activities,
-    pain_points,
-    bottlenecks,
-    manual_tasks,
-    automation_goals,
-    current_software,
-    existing_automation,
-    additional_notes
-) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16
-)
+const lockUserUnderstanding = `-- name: LockUserUnderstanding :exec
+SELECT id FROM users WHERE id = $1 FOR NO KEY UPDATE
+`
+
+// Locks the owning user row so writers of one understanding run one at a time, including the first write when no row exists yet
+func (q *Queries) LockUserUnderstanding(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, lockUserUnderstanding, id)
+	return err
+}
+
+const deleteBusinessUnderstanding = `-- name: DeleteBusinessUnderstanding :exec
+DELETE FROM business_understanding WHERE user_id = $1
+`
+
+func (q *Queries) DeleteBusinessUnderstanding(ctx context.Context, userID uuid.UUID) error {
+	_, err := q.db.Exec(ctx, deleteBusinessUnderstanding, userID)
+	return err
+}
+
+const replaceBusinessUnderstanding = `-- name: ReplaceBusinessUnderstanding :one
+INSERT INTO business_understanding (user_id, data)
+VALUES ($1, $2)
 ON CONFLICT (user_id) DO UPDATE SET
-    user_name = COALESCE(NULLIF(EXCLUDED.user_name, ''), business_understanding.user_name),
-    job_title = COALESCE(NULLIF(EXCLUDED.job_title, ''), business_understanding.job_title),
-    business_name = COALESCE(NULLIF(EXCLUDED.business_name, ''), business_understanding.business_name),
-    industry = COALESCE(NULLIF(EXCLUDED.industry, ''), business_understanding.industry),
-    business_size = COALESCE(NULLIF(EXCLUDED.business_size, ''), business_understanding.business_size),
-    user_role = COALESCE(NULLIF(EXCLUDED.user_role, ''), business_understanding.user_role),
-    key_workflows = EXCLUDED.key_workflows,
-    daily_activities = EXCLUDED.daily_activities,
-    pain_points = EXCLUDED.pain_points,
-    bottlenecks = EXCLUDED.bottlenecks,
-    manual_tasks = EXCLUDED.manual_tasks,
-    automation_goals = EXCLUDED.automation_goals,
-    current_software = EXCLUDED.current_software,
-    existing_automation = EXCLUDED.existing_automation,
-    additional_notes = COALESCE(NULLIF(EXCLUDED.additional_notes, ''), business_understanding.additional_notes),
+    data = EXCLUDED.data,
     updated_at = NOW()
-RETURNING id, user_id, user_name, job_title, business_name, industry, business_size, user_role, key_workflows, daily_activities, pain_points, bottlenecks, manual_tasks, automation_goals, current_software, existing_automation, additional_notes, created_at, updated_at
+RETURNING id, user_id, created_at, updated_at, data
 `
 
-type UpsertBusinessUnderstandingParams struct {
-	UserID             uuid.UUID `json:"user_id"`
-	UserName           *string   `json:"user_name"`
-	JobTitle           *string   `json:"job_title"`
-	BusinessName       *string   `json:"business_name"`
-	Industry           *string   `json:"industry"`
-	BusinessSize       *string   `json:"business_size"`
-	UserRole           *string   `json:"user_role"`
-	KeyWorkflows       []byte    `json:"key_workflows"`
-	DailyActivities    []byte    `json:"daily_activities"`
-	PainPoints         []byte    `json:"pain_points"`
-	Bottlenecks        []byte    `json:"bottlenecks"`
-	ManualTasks        []byte    `json:"manual_tasks"`
-	AutomationGoals    []byte    `json:"automation_goals"`
-	CurrentSoftware    []byte    `json:"current_software"`
-	ExistingAutomation []byte    `json:"existing_automation"`
-	AdditionalNotes    *string   `json:"additional_notes"`
-}
-
-func (q *Queries) UpsertBusinessUnderstanding(ctx context.Context, arg UpsertBusinessUnderstandingParams) (BusinessUnderstanding, error) {
-	row := q.db.QueryRow(ctx, upsertBusinessUnderstanding,
-		arg.UserID,
-		arg.UserName,
-		arg.JobTitle,
-		arg.BusinessName,
-		arg.Industry,
-		arg.BusinessSize,
-		arg.UserRole,
-		arg.KeyWorkflows,
-		arg.DailyActivities,
-		arg.PainPoints,
-		arg.Bottlenecks,
-		arg.ManualTasks,
-		arg.AutomationGoals,
-		arg.CurrentSoftware,
-		arg.ExistingAutomation,
-		arg.AdditionalNotes,
-	)
+type ReplaceBusinessUnderstandingParams struct {
+	UserID uuid.UUID `json:"user_id"`
+	Data   []byte    `json:"data"`
+}
+
+// Writes the whole document; callers merge in Go so every write path shares one set of rules
+func (q *Queries) ReplaceBusinessUnderstanding(ctx context.Context, arg ReplaceBusinessUnderstandingParams) (BusinessUnderstanding, error) {
+	row := q.db.QueryRow(ctx, replaceBusinessUnderstanding, arg.UserID, arg.Data)
 	var i BusinessUnderstanding
 	err := row.Scan(
 		&i.ID,
 		&i.UserID,
-		&i.UserName,
-		&i.JobTitle,
-		&i.BusinessName,
-		&i.Industry,
-		&i.BusinessSize,
-		&i.UserRole,
-		&i.KeyWorkflows,
-		&i.DailyActivities,
-		&i.PainPoints,
-		&i.Bottlenecks,
-		&i.ManualTasks,
-		&i.AutomationGoals,
-		&i.CurrentSoftware,
-		&i.ExistingAutomation,
-		&i.AdditionalNotes,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Data,
 	)
 	return i, err
 }
 
-const deleteBusinessUnderstanding = `-- name: DeleteBusinessUnderstanding :exec
-DELETE FROM business_understanding WHERE user_id = $1
+// Provenance
+
+const upsertUnderstandingProvenance = `-- name: UpsertUnderstandingProvenance :exec
+INSERT INTO business_understanding_provenance (
+    understanding_id, field_name, source, session_id, message_id, tool_name, tool_call_id
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (understanding_id, field_name) DO UPDATE SET
+    source = EXCLUDED.source,
+    session_id = EXCLUDED.session_id,
+    message_id = EXCLUDED.message_id,
+    tool_name = EXCLUDED.tool_name,
+    tool_call_id = EXCLUDED.tool_call_id,
+    set_at = NOW()
 `
 
-func (q *Queries) DeleteBusinessUnderstanding(ctx context.Context, userID uuid.UUID) error {
-	_, err := q.db.Exec(ctx, deleteBusinessUnderstanding, userID)
+type UpsertUnderstandingProvenanceParams struct {
+	UnderstandingID uuid.UUID  `json:"understanding_id"`
+	FieldName       string     `json:"field_name"`
+	Source          string     `json:"source"`
+	SessionID       *uuid.UUID `json:"session_id"`
+	MessageID       *uuid.UUID `json:"message_id"`
+	ToolName        *string    `json:"tool_name"`
+	ToolCallID      *string    `json:"tool_call_id"`
+}
+
+func (q *Queries) UpsertUnderstandingProvenance(ctx context.Context, arg UpsertUnderstandingProvenanceParams) error {
+	_, err := q.db.Exec(ctx, upsertUnderstandingProvenance,
+		arg.UnderstandingID,
+		arg.FieldName,
+		arg.Source,
+		arg.SessionID,
+		arg.MessageID,
+		arg.ToolName,
+		arg.ToolCallID,
+	)
 	return err
 }
+
+const listUnderstandingProvenance = `-- name: ListUnderstandingProvenance :many
+SELECT id, understanding_id, field_name, source, session_id, message_id, tool_name, tool_call_id, set_at FROM business_understanding_provenance
+WHERE understanding_id = $1
+ORDER BY field_name ASC
+`
+
+func (q *Queries) ListUnderstandingProvenance(ctx context.Context, understandingID uuid.UUID) ([]BusinessUnderstandingProvenance, error) {
+	rows, err := q.db.Query(ctx, listUnderstandingProvenance, understandingID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []BusinessUnderstandingProvenance{}
+	for rows.Next() {
+		var i BusinessUnderstandingProvenance
+		if err := rows.Scan(
+			&i.ID,
+			&i.UnderstandingID,
+			&i.FieldName,
+			&i.Source,
+			&i.SessionID,
+			&i.MessageID,
+			&i.ToolName,
+			&i.ToolCallID,
+			&i.SetAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Change Log
+
+const createUnderstandingChange = `-- name: CreateUnderstandingChange :one
+INSERT INTO business_understanding_changes (
+    user_id, source, session_id, message_id, tool_name, tool_call_id,
+    changed_fields, before_snapshot, after_snapshot, reverted_change_id
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING id, user_id, source, session_id, message_id, tool_name, tool_call_id, changed_fields, before_snapshot, after_snapshot, reverted_change_id, created_at
+`
+
+type CreateUnderstandingChangeParams struct {
+	UserID           uuid.UUID  `json:"user_id"`
+	Source           string     `json:"source"`
+	SessionID        *uuid.UUID `json:"session_id"`
+	MessageID        *uuid.UUID `json:"message_id"`
+	ToolName         *string    `json:"tool_name"`
+	ToolCallID       *string    `json:"tool_call_id"`
+	ChangedFields    []string   `json:"changed_fields"`
+	BeforeSnapshot   []byte     `json:"before_snapshot"`
+	AfterSnapshot    []byte     `json:"after_snapshot"`
+	RevertedChangeID *uuid.UUID `json:"reverted_change_id"`
+}
+
+func (q *Queries) CreateUnderstandingChange(ctx context.Context, arg CreateUnderstandingChangeParams) (BusinessUnderstandingChange, error) {
+	row := q.db.QueryRow(ctx, createUnderstandingChange,
+		arg.UserID,
+		arg.Source,
+		arg.SessionID,
+		arg.MessageID,
+		arg.ToolName,
+		arg.ToolCallID,
+		arg.ChangedFields,
+		arg.BeforeSnapshot,
+		arg.AfterSnapshot,
+		arg.RevertedChangeID,
+	)
+	var i BusinessUnderstandingChange
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Source,
+		&i.SessionID,
+		&i.MessageID,
+		&i.ToolName,
+		&i.ToolCallID,
+		&i.ChangedFields,
+		&i.BeforeSnapshot,
+		&i.AfterSnapshot,
+		&i.RevertedChangeID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getUnderstandingChange = `-- name: GetUnderstandingChange :one
+SELECT id, user_id, source, session_id, message_id, tool_name, tool_call_id, changed_fields, before_snapshot, after_snapshot, reverted_change_id, created_at FROM business_understanding_changes WHERE id = $1 AND user_id = $2
+`
+
+type GetUnderstandingChangeParams struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) GetUnderstandingChange(ctx context.Context, arg GetUnderstandingChangeParams) (BusinessUnderstandingChange, error) {
+	row := q.db.QueryRow(ctx, getUnderstandingChange, arg.ID, arg.UserID)
+	var i BusinessUnderstandingChange
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Source,
+		&i.SessionID,
+		&i.MessageID,
+		&i.ToolName,
+		&i.ToolCallID,
+		&i.ChangedFields,
+		&i.BeforeSnapshot,
+		&i.AfterSnapshot,
+		&i.RevertedChangeID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const listUnderstandingChanges = `-- name: ListUnderstandingChanges :many
+SELECT id, user_id, source, session_id, message_id, tool_name, tool_call_id, changed_fields, before_snapshot, after_snapshot, reverted_change_id, created_at FROM business_understanding_changes
+WHERE user_id = $1
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3
+`
+
+type ListUnderstandingChangesParams struct {
+	UserID uuid.UUID `json:"user_id"`
+	Limit  int32     `json:"limit"`
+	Offset int32     `json:"offset"`
+}
+
+func (q *Queries) ListUnderstandingChanges(ctx context.Context, arg ListUnderstandingChangesParams) ([]BusinessUnderstandingChange, error) {
+	rows, err := q.db.Query(ctx, listUnderstandingChanges, arg.UserID, arg.Limit, arg.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []BusinessUnderstandingChange{}
+	for rows.Next() {
+		var i BusinessUnderstandingChange
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Source,
+			&i.SessionID,
+			&i.MessageID,
+			&i.ToolName,
+			&i.ToolCallID,
+			&i.ChangedFields,
+			&i.BeforeSnapshot,
+			&i.AfterSnapshot,
+			&i.RevertedChangeID,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const countUnderstandingChanges = `-- name: CountUnderstandingChanges :one
+SELECT COUNT(*) FROM business_understanding_changes WHERE user_id = $1
+`
+
+func (q *Queries) CountUnderstandingChanges(ctx context.Context, userID uuid.UUID) (int64, error) {
+	row := q.db.QueryRow(ctx, countUnderstandingChanges, userID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+// Interview State
+
+const getUnderstandingInterview = `-- name: GetUnderstandingInterview :one
+SELECT session_id, user_id, report_type, asked_fields, created_at, updated_at FROM understanding_interviews WHERE session_id = $1 AND user_id = $2
+`
+
+type GetUnderstandingInterviewParams struct {
+	SessionID uuid.UUID `json:"session_id"`
+	UserID    uuid.UUID `json:"user_id"`
+}
+
+func (q *Queries) GetUnderstandingInterview(ctx context.Context, arg GetUnderstandingInterviewParams) (UnderstandingInterview, error) {
+	row := q.db.QueryRow(ctx, getUnderstandingInterview, arg.SessionID, arg.UserID)
+	var i UnderstandingInterview
+	err := row.Scan(
+		&i.SessionID,
+		&i.UserID,
+		&i.ReportType,
+		&i.AskedFields,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const upsertUnderstandingInterview = `-- name: UpsertUnderstandingInterview :one
+INSERT INTO understanding_interviews (session_id, user_id, report_type, asked_fields)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (session_id) DO UPDATE SET
+    report_type = EXCLUDED.report_type,
+    asked_fields = EXCLUDED.asked_fields,
+    updated_at = NOW()
+RETURNING session_id, user_id, report_type, asked_fields, created_at, updated_at
+`
+
+type UpsertUnderstandingInterviewParams struct {
+	SessionID   uuid.UUID `json:"session_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	ReportType  *string   `json:"report_type"`
+	AskedFields []string  `json:"asked_fields"`
+}
+
+func (q *Queries) UpsertUnderstandingInterview(ctx context.Context, arg UpsertUnderstandingInterviewParams) (UnderstandingInterview, error) {
+	row := q.db.QueryRow(ctx, upsertUnderstandingInterview,
+		arg.SessionID,
+		arg.UserID,
+		arg.ReportType,
+		arg.AskedFields,
+	)
+	var i UnderstandingInterview
+	err := row.Scan(
+		&i.SessionID,
+		&i.UserID,
+		&i.ReportType,
+		&i.AskedFields,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
@@ -25,7 +25,7 @@ func (q *Queries) CountSessionMessages(ctx context.Context, sessionID uuid.UUID)
 const createChatMessage = `-- name: CreateChatMessage :one
 INSERT INTO chat_messages (session_id, role, content, tokens_used)
 VALUES ($1, $2, $3, $4)
-RETURNING id, session_id, role, content, tokens_used, created_at
+RETURNING id, session_id, role, content, tokens_used, created_at, tool_calls, tool_call_id, tool_name
 `
 
 type CreateChatMessageParams struct {
@@ -50,14 +50,58 @@ func (q *Queries) CreateChatMessage(ctx context.Context, arg CreateChatMessagePa
 		&i.Content,
 		&i.TokensUsed,
 		&i.CreatedAt,
+		&i.ToolCalls,
+		&i.ToolCallID,
+		&i.ToolName,
+	)
+	return i, err
+}
+
+const createChatMessageWithTools = `-- name: CreateChatMessageWithTools :one
+INSERT INTO chat_messages (session_id, role, content, tokens_used, tool_calls, tool_call_id, tool_name)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, session_id, role, content, tokens_used, created_at, tool_calls, tool_call_id, tool_name
+`
+
+type CreateChatMessageWithToolsParams struct {
+	SessionID  uuid.UUID `json:"session_id"`
+	Role       string    `json:"role"`
+	Content    string    `json:"content"`
+	TokensUsed *int32    `json:"tokens_used"`
+	ToolCalls  []byte    `json:"tool_calls"`
+	ToolCallID *string   `json:"tool_call_id"`
+	ToolName   *string   `json:"tool_name"`
+}
+
+func (q *Queries) CreateChatMessageWithTools(ctx context.Context, arg CreateChatMessageWithToolsParams) (ChatMessage, error) {
+	row := q.db.QueryRow(ctx, createChatMessageWithTools,
+		arg.SessionID,
+		arg.Role,
+		arg.Content,
+		arg.TokensUsed,
+		arg.ToolCalls,
+		arg.ToolCallID,
+		arg.ToolName,
+	)
+	var i ChatMessage
+	err := row.Scan(
+		&i.ID,
+		&i.SessionID,
+		&i.Role,
+		&i.Content,
+		&i.TokensUsed,
+		&i.CreatedAt,
+		&i.ToolCalls,
+		&i.ToolCallID,
+		&i.ToolName,
 	)
 	return i, err
 }
 
 const createChatSession = `-- name: CreateChatSession :one
-INSERT INTO chat_sessions (user_id, title, model, system_prompt)
-VALUES ($1, $2, $3, $4)
-RETURNING id, user_id, title, model, system_prompt, created_at, updated_at
+INSERT INTO chat_sessions (user_id, title, model, system_prompt, profile, tools, tool_choice)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING id, user_id, title, model, system_prompt, created_at, updated_at, profile, tools, tool_choice
 `
 
 type CreateChatSessionParams struct {
@@ -65,6 +109,9 @@ type CreateChatSessionParams struct {
 	Title        *string   `json:"title"`
 	Model        *string   `json:"model"`
 	SystemPrompt *string   `json:"system_prompt"`
+	Profile      *string   `json:"profile"`
+	Tools        []string  `json:"tools"`
+	ToolChoice   *string   `json:"tool_choice"`
 }
 
 func (q *Queries) CreateChatSession(ctx context.Context, arg CreateChatSessionParams) (ChatSession, error) {
@@ -73,6 +120,9 @@ func (q *Queries) CreateChatSession(ctx context.Context, arg CreateChatSessionPa
 		arg.Title,
 		arg.Model,
 		arg.SystemPrompt,
+		arg.Profile,
+		arg.Tools,
+		arg.ToolChoice,
 	)
 	var i ChatSession
 	err := row.Scan(
@@ -83,6 +133,9 @@ func (q *Queries) CreateChatSession(ctx context.Context, arg CreateChatSessionPa
 		&i.SystemPrompt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Profile,
+		&i.Tools,
+		&i.ToolChoice,
 	)
 	return i, err
 }
@@ -111,7 +164,7 @@ func (q *Queries) DeleteChatSession(ctx context.Context, arg DeleteChatSessionPa
 }
 
 const getChatMessages = `-- name: GetChatMessages :many
-SELECT id, session_id, role, content, tokens_used, created_at FROM chat_messages
+SELECT id, session_id, role, content, tokens_used, created_at, tool_calls, tool_call_id, tool_name FROM chat_messages
 WHERE session_id = $1
 ORDER BY created_at ASC
 `
@@ -132,6 +185,9 @@ func (q *Queries) GetChatMessages(ctx context.Context, sessionID uuid.UUID) ([]C
 			&i.Content,
 			&i.TokensUsed,
 			&i.CreatedAt,
+			&i.ToolCalls,
+			&i.ToolCallID,
+			&i.ToolName,
 		); err != nil {
 			return nil, err
 		}
@@ -144,7 +200,7 @@ func (q *Queries) GetChatMessages(ctx context.Context, sessionID uuid.UUID) ([]C
 }
 
 const getChatSession = `-- name: GetChatSession :one
-SELECT id, user_id, title, model, system_prompt, created_at, updated_at FROM chat_sessions WHERE id = $1
+SELECT id, user_id, title, model, system_prompt, created_at, updated_at, profile, tools, tool_choice FROM chat_sessions WHERE id = $1
 `
 
 func (q *Queries) GetChatSession(ctx context.Context, id uuid.UUID) (ChatSession, error) {
@@ -158,12 +214,15 @@ func (q *Queries) GetChatSession(ctx context.Context, id uuid.UUID) (ChatSession
 		&i.SystemPrompt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Profile,
+		&i.Tools,
+		&i.ToolChoice,
 	)
 	return i, err
 }
 
 const getChatSessionByUser = `-- name: GetChatSessionByUser :one
-SELECT id, user_id, title, model, system_prompt, created_at, updated_at FROM chat_sessions WHERE id = $1 AND user_id = $2
+SELECT id, user_id, title, model, system_prompt, created_at, updated_at, profile, tools, tool_choice FROM chat_sessions WHERE id = $1 AND user_id = $2
 `
 
 type GetChatSessionByUserParams struct {
@@ -182,12 +241,15 @@ func (q *Queries) GetChatSessionByUser(ctx context.Context, arg GetChatSessionBy
 		&i.SystemPrompt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Profile,
+		&i.Tools,
+		&i.ToolChoice,
 	)
 	return i, err
 }
 
 const getRecentChatMessages = `-- name: GetRecentChatMessages :many
-SELECT id, session_id, role, content, tokens_used, created_at FROM chat_messages
+SELECT id, session_id, role, content, tokens_used, created_at, tool_calls, tool_call_id, tool_name FROM chat_messages
 WHERE session_id = $1
 ORDER BY created_at DESC
 LIMIT $2
@@ -214,6 +276,9 @@ func (q *Queries) GetRecentChatMessages(ctx context.Context, arg GetRecentChatMe
 			&i.Content,
 			&i.TokensUsed,
 			&i.CreatedAt,
+			&i.ToolCalls,
+			&i.ToolCallID,
+			&i.ToolName,
 		); err != nil {
 			return nil, err
 		}
@@ -238,7 +303,7 @@ func (q *Queries) GetSessionTokenCount(ctx context.Context, sessionID uuid.UUID)
 }
 
 const listChatSessions = `-- name: ListChatSessions :many
-SELECT id, user_id, title, model, system_prompt, created_at, updated_at FROM chat_sessions
+SELECT id, user_id, title, model, system_prompt, created_at, updated_at, profile, tools, tool_choice FROM chat_sessions
 WHERE user_id = $1
 ORDER BY updated_at DESC
 LIMIT $2 OFFSET $3
@@ -267,6 +332,9 @@ func (q *Queries) ListChatSessions(ctx context.Context, arg ListChatSessionsPara
 			&i.SystemPrompt,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.Profile,
+			&i.Tools,
+			&i.ToolChoice,
 		); err != nil {
 			return nil, err
 		}
@@ -283,7 +351,7 @@ UPDATE chat_sessions
 SET title = COALESCE($2, title),
     system_prompt = COALESCE($3, system_prompt)
 WHERE id = $1
-RETURNING id, user_id, title, model, system_prompt, created_at, updated_at
+RETURNING id, user_id, title, model, system_prompt, created_at, updated_at, profile, tools, tool_choice
 `
 
 type UpdateChatSessionParams struct {
@@ -303,6 +371,32 @@ func (q *Queries) UpdateChatSession(ctx context.Context, arg UpdateChatSessionPa
 		&i.SystemPrompt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.Profile,
+		&i.Tools,
+		&i.ToolChoice,
 	)
 	return i, err
 }
+
+const updateToolResultMessage = `-- name: UpdateToolResultMessage :exec
+UPDATE chat_messages SET content = $3, tokens_used = $4
+WHERE session_id = $1 AND tool_call_id = $2 AND role = 'tool'
+`
+
+type UpdateToolResultMessageParams struct {
+	SessionID  uuid.UUID `json:"session_id"`
+	ToolCallID *string   `json:"tool_call_id"`
+	Content    string    `json:"content"`
+	TokensUsed *int32    `json:"tokens_used"`
+}
+
+// Replaces the placeholder result saved for a tool call that was waiting for confirmation
+func (q *Queries) UpdateToolResultMessage(ctx context.Context, arg UpdateToolResultMessageParams) error {
+	_, err := q.db.Exec(ctx, updateToolResultMessage,
+		arg.SessionID,
+		arg.ToolCallID,
+		arg.Content,
+		arg.TokensUsed,
+	)
+	return err
+}
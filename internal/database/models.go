@@ -25,6 +25,9 @@ type ChatMessage struct {
 	Content    string             `json:"content"`
 	TokensUsed *int32             `json:"tokens_used"`
 	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	ToolCalls  []byte             `json:"tool_calls"`
+	ToolCallID *string            `json:"tool_call_id"`
+	ToolName   *string            `json:"tool_name"`
 }
 
 type ChatSession struct {
@@ -35,50 +38,230 @@ type ChatSession struct {
 	SystemPrompt *string            `json:"system_prompt"`
 	CreatedAt    pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt    pgtype.Timestamptz `json:"updated_at"`
+	Profile      *string            `json:"profile"`
+	Tools        []string           `json:"tools"`
+	ToolChoice   *string            `json:"tool_choice"`
 }
 
 type RefreshToken struct {
+	ID         uuid.UUID          `json:"id"`
+	UserID     uuid.UUID          `json:"user_id"`
+	TokenHash  string             `json:"token_hash"`
+	ExpiresAt  time.Time          `json:"expires_at"`
+	Revoked    *bool              `json:"revoked"`
+	CreatedAt  pgtype.Timestamptz `json:"created_at"`
+	FamilyID   uuid.UUID          `json:"family_id"`
+	RotatedAt  pgtype.Timestamptz `json:"rotated_at"`
+	UserAgent  *string            `json:"user_agent"`
+	IpHash     *string            `json:"ip_hash"`
+	SignedInAt time.Time          `json:"signed_in_at"`
+}
+
+type User struct {
+	ID               uuid.UUID          `json:"id"`
+	Email            string             `json:"email"`
+	PasswordHash     *string            `json:"password_hash"`
+	Name             string             `json:"name"`
+	AvatarUrl        *string            `json:"avatar_url"`
+	Provider         *string            `json:"provider"`
+	ProviderID       *string            `json:"provider_id"`
+	EmailVerified    *bool              `json:"email_verified"`
+	CreatedAt        pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt        pgtype.Timestamptz `json:"updated_at"`
+	TokensValidAfter pgtype.Timestamptz `json:"tokens_valid_after"`
+	MfaRequired      bool               `json:"mfa_required"`
+}
+
+type BusinessUnderstanding struct {
 	ID        uuid.UUID          `json:"id"`
 	UserID    uuid.UUID          `json:"user_id"`
-	TokenHash string             `json:"token_hash"`
-	ExpiresAt time.Time          `json:"expires_at"`
-	Revoked   *bool              `json:"revoked"`
 	CreatedAt pgtype.Timestamptz `json:"created_at"`
+	UpdatedAt pgtype.Timestamptz `json:"updated_at"`
+	Data      []byte             `json:"data"`
 }
 
-type User struct {
-	ID            uuid.UUID          `json:"id"`
-	Email         string             `json:"email"`
-	PasswordHash  *string            `json:"password_hash"`
-	Name          string             `json:"name"`
-	AvatarUrl     *string            `json:"avatar_url"`
-	Provider      *string            `json:"provider"`
-	ProviderID    *string            `json:"provider_id"`
-	EmailVerified *bool              `json:"email_verified"`
-	CreatedAt     pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt     pgtype.Timestamptz `json:"updated_at"`
+type BusinessUnderstandingProvenance struct {
+	ID              uuid.UUID  `json:"id"`
+	UnderstandingID uuid.UUID  `json:"understanding_id"`
+	FieldName       string     `json:"field_name"`
+	Source          string     `json:"source"`
+	SessionID       *uuid.UUID `json:"session_id"`
+	MessageID       *uuid.UUID `json:"message_id"`
+	ToolName        *string    `json:"tool_name"`
+	ToolCallID      *string    `json:"tool_call_id"`
+	SetAt           time.Time  `json:"set_at"`
 }
 
-type BusinessUnderstanding struct {
-	ID                 uuid.UUID          `json:"id"`
-	UserID             uuid.UUID          `json:"user_id"`
-	UserName           *string            `json:"user_name"`
-	JobTitle           *string            `json:"job_title"`
-	BusinessName       *string            `json:"business_name"`
-	Industry           *string            `json:"industry"`
-	BusinessSize       *string            `json:"business_size"`
-	UserRole           *string            `json:"user_role"`
-	KeyWorkflows       []byte             `json:"key_workflows"`
-	DailyActivities    []byte             `json:"daily_activities"`
-	PainPoints         []byte             `json:"pain_points"`
-	Bottlenecks        []byte             `json:"bottlenecks"`
-	ManualTasks        []byte             `json:"manual_tasks"`
-	AutomationGoals    []byte             `json:"automation_goals"`
-	CurrentSoftware    []byte             `json:"current_software"`
-	ExistingAutomation []byte             `json:"existing_automation"`
-	AdditionalNotes    *string            `json:"additional_notes"`
-	CreatedAt          pgtype.Timestamptz `json:"created_at"`
-	UpdatedAt          pgtype.Timestamptz `json:"updated_at"`
+type BusinessUnderstandingChange struct {
+	ID               uuid.UUID  `json:"id"`
+	UserID           uuid.UUID  `json:"user_id"`
+	Source           string     `json:"source"`
+	SessionID        *uuid.UUID `json:"session_id"`
+	MessageID        *uuid.UUID `json:"message_id"`
+	ToolName         *string    `json:"tool_name"`
+	ToolCallID       *string    `json:"tool_call_id"`
+	ChangedFields    []string   `json:"changed_fields"`
+	BeforeSnapshot   []byte     `json:"before_snapshot"`
+	AfterSnapshot    []byte     `json:"after_snapshot"`
+	RevertedChangeID *uuid.UUID `json:"reverted_change_id"`
+	CreatedAt        time.Time  `json:"created_at"`
+}
+
+type Organization struct {
+	ID        uuid.UUID  `json:"id"`
+	Name      string     `json:"name"`
+	CreatedBy *uuid.UUID `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+type OrganizationMember struct {
+	ID             uuid.UUID `json:"id"`
+	OrganizationID uuid.UUID `json:"organization_id"`
+	UserID         uuid.UUID `json:"user_id"`
+	Role           string    `json:"role"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type OrganizationUnderstanding struct {
+	ID             uuid.UUID `json:"id"`
+	OrganizationID uuid.UUID `json:"organization_id"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	Data           []byte    `json:"data"`
+}
+
+type UnderstandingInterview struct {
+	SessionID   uuid.UUID `json:"session_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	ReportType  *string   `json:"report_type"`
+	AskedFields []string  `json:"asked_fields"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type ToolCallConfirmation struct {
+	ID         uuid.UUID          `json:"id"`
+	SessionID  uuid.UUID          `json:"session_id"`
+	UserID     uuid.UUID          `json:"user_id"`
+	MessageID  *uuid.UUID         `json:"message_id"`
+	ToolCallID string             `json:"tool_call_id"`
+	ToolName   string             `json:"tool_name"`
+	Arguments  string             `json:"arguments"`
+	Status     string             `json:"status"`
+	Result     []byte             `json:"result"`
+	ResolvedAt pgtype.Timestamptz `json:"resolved_at"`
+	CreatedAt  time.Time          `json:"created_at"`
+	UpdatedAt  time.Time          `json:"updated_at"`
+}
+
+type WebhookTool struct {
+	ID              uuid.UUID  `json:"id"`
+	Name            string     `json:"name"`
+	Description     string     `json:"description"`
+	Parameters      []byte     `json:"parameters"`
+	URL             string     `json:"url"`
+	Method          string     `json:"method"`
+	Headers         []byte     `json:"headers"`
+	TimeoutSeconds  int32      `json:"timeout_seconds"`
+	ResponseMapping []byte     `json:"response_mapping"`
+	Enabled         bool       `json:"enabled"`
+	CreatedBy       *uuid.UUID `json:"created_by"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+type ChatAttachment struct {
+	ID          uuid.UUID `json:"id"`
+	SessionID   uuid.UUID `json:"session_id"`
+	UserID      uuid.UUID `json:"user_id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	SizeBytes   int32     `json:"size_bytes"`
+	Data        []byte    `json:"data"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ToolExecution struct {
+	ID            uuid.UUID  `json:"id"`
+	UserID        uuid.UUID  `json:"user_id"`
+	SessionID     *uuid.UUID `json:"session_id"`
+	MessageID     *uuid.UUID `json:"message_id"`
+	ToolCallID    *string    `json:"tool_call_id"`
+	ToolName      string     `json:"tool_name"`
+	ArgumentsHash string     `json:"arguments_hash"`
+	Arguments     []byte     `json:"arguments"`
+	Outcome       string     `json:"outcome"`
+	Success       bool       `json:"success"`
+	Error         *string    `json:"error"`
+	DurationMs    int32      `json:"duration_ms"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+type PasswordResetToken struct {
+	ID        uuid.UUID `json:"id"`
+	UserID    uuid.UUID `json:"user_id"`
+	TokenHash string    `json:"token_hash"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type RevokedAccessToken struct {
+	Jti       uuid.UUID `json:"jti"`
+	UserID    uuid.UUID `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type RevokedSession struct {
+	FamilyID  uuid.UUID `json:"family_id"`
+	UserID    uuid.UUID `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type UserIdentity struct {
+	ID         uuid.UUID          `json:"id"`
+	UserID     uuid.UUID          `json:"user_id"`
+	Provider   string             `json:"provider"`
+	ProviderID string             `json:"provider_id"`
+	Email      *string            `json:"email"`
+	CreatedAt  time.Time          `json:"created_at"`
+	LastUsedAt pgtype.Timestamptz `json:"last_used_at"`
+}
+
+type UserTotp struct {
+	UserID          uuid.UUID          `json:"user_id"`
+	SecretEncrypted []byte             `json:"secret_encrypted"`
+	EnabledAt       pgtype.Timestamptz `json:"enabled_at"`
+	LastUsedStep    int64              `json:"last_used_step"`
+	CreatedAt       time.Time          `json:"created_at"`
+}
+
+type MfaChallenge struct {
+	TokenHash string    `json:"token_hash"`
+	UserID    uuid.UUID `json:"user_id"`
+	Attempts  int32     `json:"attempts"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type MfaRecoveryCode struct {
+	ID        uuid.UUID          `json:"id"`
+	UserID    uuid.UUID          `json:"user_id"`
+	CodeHash  string             `json:"code_hash"`
+	UsedAt    pgtype.Timestamptz `json:"used_at"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+type ApiKey struct {
+	ID         uuid.UUID          `json:"id"`
+	UserID     uuid.UUID          `json:"user_id"`
+	Name       string             `json:"name"`
+	Prefix     string             `json:"prefix"`
+	KeyHash    string             `json:"key_hash"`
+	Scopes     []string           `json:"scopes"`
+	ExpiresAt  pgtype.Timestamptz `json:"expires_at"`
+	LastUsedAt pgtype.Timestamptz `json:"last_used_at"`
+	CreatedAt  time.Time          `json:"created_at"`
 }
 
 // Referral tracking models
@@ -136,3 +319,19 @@ type ReferralSignup struct {
 	CreatedAt             pgtype.Timestamptz `json:"created_at"`
 	UpdatedAt             pgtype.Timestamptz `json:"updated_at"`
 }
+
+type OrganizationInvitation struct {
+	ID             uuid.UUID  `json:"id"`
+	OrganizationID uuid.UUID  `json:"organization_id"`
+	Email          string     `json:"email"`
+	Role           string     `json:"role"`
+	InvitedBy      *uuid.UUID `json:"invited_by"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type MfaCodeAttempt struct {
+	UserID       uuid.UUID `json:"user_id"`
+	Attempts     int32     `json:"attempts"`
+	WindowEndsAt time.Time `json:"window_ends_at"`
+}
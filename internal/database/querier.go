@@ -6,43 +6,161 @@ package database
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 type Querier interface {
+	// Locks every member row of the organization so owner checks and the change that depends on them run one at a time
+	LockOrganizationMembers(ctx context.Context, organizationID uuid.UUID) error
+	// Locks the owning user row so writers of one understanding run one at a time, including the first write when no row exists yet
+	// Only verifies the address the token was issued for
 	CleanExpiredCache(ctx context.Context) (int64, error)
+	// Rotated tokens are kept until they expire so their reuse can be detected
 	CleanExpiredTokens(ctx context.Context) (int64, error)
+	// Deleting the token as it is read makes it single-use
+	ConsumePasswordResetToken(ctx context.Context, tokenHash string) (PasswordResetToken, error)
+	CountAPIKeys(ctx context.Context, userID uuid.UUID) (int64, error)
+	CountActiveMFAChallenges(ctx context.Context, userID uuid.UUID) (int64, error)
+	CountOrganizationOwners(ctx context.Context, organizationID uuid.UUID) (int64, error)
 	CountSessionMessages(ctx context.Context, sessionID uuid.UUID) (int64, error)
+	CountUnderstandingChanges(ctx context.Context, userID uuid.UUID) (int64, error)
+	CountUnusedRecoveryCodes(ctx context.Context, userID uuid.UUID) (int64, error)
+	CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error)
+	// Uploading a file with the same name as an existing one replaces it
+	CreateChatAttachment(ctx context.Context, arg CreateChatAttachmentParams) (CreateChatAttachmentRow, error)
 	CreateChatMessage(ctx context.Context, arg CreateChatMessageParams) (ChatMessage, error)
+	CreateChatMessageWithTools(ctx context.Context, arg CreateChatMessageWithToolsParams) (ChatMessage, error)
 	CreateChatSession(ctx context.Context, arg CreateChatSessionParams) (ChatSession, error)
+	CreateMFAChallenge(ctx context.Context, arg CreateMFAChallengeParams) error
+	CreateOrganization(ctx context.Context, arg CreateOrganizationParams) (Organization, error)
+	CreateOrganizationMember(ctx context.Context, arg CreateOrganizationMemberParams) (OrganizationMember, error)
+	CreatePasswordResetToken(ctx context.Context, arg CreatePasswordResetTokenParams) error
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error
 	CreateRefreshToken(ctx context.Context, arg CreateRefreshTokenParams) (RefreshToken, error)
+	CreateToolCallConfirmation(ctx context.Context, arg CreateToolCallConfirmationParams) (ToolCallConfirmation, error)
+	CreateToolExecution(ctx context.Context, arg CreateToolExecutionParams) error
+	CreateUnderstandingChange(ctx context.Context, arg CreateUnderstandingChangeParams) (BusinessUnderstandingChange, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	CreateUserIdentity(ctx context.Context, arg CreateUserIdentityParams) (UserIdentity, error)
+	CreateWebhookTool(ctx context.Context, arg CreateWebhookToolParams) (WebhookTool, error)
+	DeleteAPIKey(ctx context.Context, arg DeleteAPIKeyParams) (int64, error)
 	DeleteBusinessUnderstanding(ctx context.Context, userID uuid.UUID) error
 	DeleteCache(ctx context.Context, key string) error
 	DeleteCacheByPrefix(ctx context.Context, dollar_1 *string) (int64, error)
+	DeleteChatAttachment(ctx context.Context, arg DeleteChatAttachmentParams) (int64, error)
 	DeleteChatMessage(ctx context.Context, id uuid.UUID) error
 	DeleteChatSession(ctx context.Context, arg DeleteChatSessionParams) error
+	DeleteExpiredMFAChallenges(ctx context.Context) (int64, error)
+	DeleteExpiredRevokedAccessTokens(ctx context.Context) (int64, error)
+	DeleteExpiredRevokedSessions(ctx context.Context) (int64, error)
+	DeleteInvitationByEmail(ctx context.Context, arg DeleteInvitationByEmailParams) (int64, error)
+	DeleteMFAChallenge(ctx context.Context, tokenHash string) (int64, error)
+	DeleteOrganizationInvitation(ctx context.Context, arg DeleteOrganizationInvitationParams) (int64, error)
+	DeleteOrganizationMember(ctx context.Context, arg DeleteOrganizationMemberParams) error
+	DeleteToolExecutionsBefore(ctx context.Context, createdAt time.Time) (int64, error)
 	DeleteUser(ctx context.Context, id uuid.UUID) error
+	DeleteUserAPIKeys(ctx context.Context, userID uuid.UUID) error
+	DeleteUserIdentity(ctx context.Context, arg DeleteUserIdentityParams) (int64, error)
+	DeleteUserPasswordResetTokens(ctx context.Context, userID uuid.UUID) error
+	DeleteUserRecoveryCodes(ctx context.Context, userID uuid.UUID) error
+	DeleteUserTOTP(ctx context.Context, userID uuid.UUID) error
+	DeleteWebhookTool(ctx context.Context, id uuid.UUID) (int64, error)
+	EnableUserTOTP(ctx context.Context, userID uuid.UUID) (int64, error)
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (GetAPIKeyByHashRow, error)
 	GetBusinessUnderstanding(ctx context.Context, userID uuid.UUID) (BusinessUnderstanding, error)
 	GetCache(ctx context.Context, key string) (Cache, error)
+	// Files in the session other than the one being uploaded, which it would replace
+	GetChatAttachmentUsage(ctx context.Context, arg GetChatAttachmentUsageParams) (GetChatAttachmentUsageRow, error)
 	GetChatMessages(ctx context.Context, sessionID uuid.UUID) ([]ChatMessage, error)
 	GetChatSession(ctx context.Context, id uuid.UUID) (ChatSession, error)
 	GetChatSessionByUser(ctx context.Context, arg GetChatSessionByUserParams) (ChatSession, error)
+	GetInvitationByEmail(ctx context.Context, arg GetInvitationByEmailParams) (OrganizationInvitation, error)
+	GetOrganization(ctx context.Context, id uuid.UUID) (Organization, error)
+	GetOrganizationMemberByUserID(ctx context.Context, userID uuid.UUID) (OrganizationMember, error)
+	GetOrganizationUnderstanding(ctx context.Context, organizationID uuid.UUID) (OrganizationUnderstanding, error)
 	GetRecentChatMessages(ctx context.Context, arg GetRecentChatMessagesParams) ([]ChatMessage, error)
 	GetRefreshToken(ctx context.Context, tokenHash string) (RefreshToken, error)
+	// Unlike GetRefreshToken this also finds revoked and expired tokens
+	GetRefreshTokenByHash(ctx context.Context, tokenHash string) (RefreshToken, error)
 	GetSessionTokenCount(ctx context.Context, sessionID uuid.UUID) (int32, error)
+	GetToolCallConfirmation(ctx context.Context, arg GetToolCallConfirmationParams) (ToolCallConfirmation, error)
+	GetToolExecution(ctx context.Context, id uuid.UUID) (ToolExecution, error)
+	GetUnderstandingChange(ctx context.Context, arg GetUnderstandingChangeParams) (BusinessUnderstandingChange, error)
+	GetUnderstandingInterview(ctx context.Context, arg GetUnderstandingInterviewParams) (UnderstandingInterview, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
 	GetUserByID(ctx context.Context, id uuid.UUID) (User, error)
 	GetUserByProvider(ctx context.Context, arg GetUserByProviderParams) (User, error)
+	GetUserIdentity(ctx context.Context, arg GetUserIdentityParams) (UserIdentity, error)
+	GetUserMFAState(ctx context.Context, id uuid.UUID) (GetUserMFAStateRow, error)
+	GetUserTOTP(ctx context.Context, userID uuid.UUID) (UserTotp, error)
+	GetUserTokensValidAfter(ctx context.Context, id uuid.UUID) (pgtype.Timestamptz, error)
+	GetWebhookTool(ctx context.Context, id uuid.UUID) (WebhookTool, error)
+	ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]ApiKey, error)
+	ListChatAttachmentFiles(ctx context.Context, sessionID uuid.UUID) ([]ListChatAttachmentFilesRow, error)
+	ListChatAttachments(ctx context.Context, sessionID uuid.UUID) ([]ListChatAttachmentsRow, error)
 	ListChatSessions(ctx context.Context, arg ListChatSessionsParams) ([]ChatSession, error)
+	ListInvitationsByEmail(ctx context.Context, email string) ([]ListInvitationsByEmailRow, error)
+	ListOrganizationInvitations(ctx context.Context, organizationID uuid.UUID) ([]OrganizationInvitation, error)
+	ListOrganizationMembers(ctx context.Context, organizationID uuid.UUID) ([]ListOrganizationMembersRow, error)
+	ListPendingToolCallConfirmations(ctx context.Context, arg ListPendingToolCallConfirmationsParams) ([]ToolCallConfirmation, error)
+	// Newest first; each filter applies only when set. Page with created_before.
+	ListToolExecutions(ctx context.Context, arg ListToolExecutionsParams) ([]ToolExecution, error)
+	ListUnderstandingChanges(ctx context.Context, arg ListUnderstandingChangesParams) ([]BusinessUnderstandingChange, error)
+	ListUnderstandingProvenance(ctx context.Context, understandingID uuid.UUID) ([]BusinessUnderstandingProvenance, error)
+	// A device is a token family; only its newest token is still usable
+	ListUserDevices(ctx context.Context, userID uuid.UUID) ([]RefreshToken, error)
+	ListUserIdentities(ctx context.Context, userID uuid.UUID) ([]UserIdentity, error)
+	ListUserRevokedAccessTokens(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
+	ListUserRevokedSessions(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
+	ListWebhookTools(ctx context.Context) ([]WebhookTool, error)
+	// Locks the owning user row so writers of one understanding run one at a time, including the first write when no row exists yet
+	LockUserUnderstanding(ctx context.Context, id uuid.UUID) error
+	// Only verifies the address the token was issued for
+	MarkUserEmailVerified(ctx context.Context, arg MarkUserEmailVerifiedParams) (User, error)
+	ReplaceBusinessUnderstanding(ctx context.Context, arg ReplaceBusinessUnderstandingParams) (BusinessUnderstanding, error)
+	ReplaceOrganizationUnderstanding(ctx context.Context, arg ReplaceOrganizationUnderstandingParams) (OrganizationUnderstanding, error)
+	ResolveToolCallConfirmation(ctx context.Context, arg ResolveToolCallConfirmationParams) (ToolCallConfirmation, error)
+	RevokeAccessToken(ctx context.Context, arg RevokeAccessTokenParams) error
 	RevokeAllUserTokens(ctx context.Context, userID uuid.UUID) error
 	RevokeRefreshToken(ctx context.Context, tokenHash string) error
+	RevokeRefreshTokenFamily(ctx context.Context, familyID uuid.UUID) (int64, error)
+	RevokeSession(ctx context.Context, arg RevokeSessionParams) error
+	// Token times have one-second precision, so tokens issued in the same
+	// second as the revocation stay valid
+	RevokeUserAccessTokens(ctx context.Context, id uuid.UUID) error
+	RevokeUserRefreshTokenFamily(ctx context.Context, arg RevokeUserRefreshTokenFamilyParams) (int64, error)
+	// Revokes a usable token and marks it rotated in one statement, so only one
+	// of several concurrent refreshes with the same token succeeds
+	RotateRefreshToken(ctx context.Context, tokenHash string) (RefreshToken, error)
 	SetCache(ctx context.Context, arg SetCacheParams) error
+	SetToolCallConfirmationResult(ctx context.Context, arg SetToolCallConfirmationResultParams) error
+	SetUserMFARequired(ctx context.Context, arg SetUserMFARequiredParams) (int64, error)
+	// Records use at most once a minute, so busy keys don't write on every request
+	TouchAPIKey(ctx context.Context, id uuid.UUID) error
+	TouchUserIdentity(ctx context.Context, id uuid.UUID) error
 	UpdateChatSession(ctx context.Context, arg UpdateChatSessionParams) (ChatSession, error)
+	UpdateOrganizationMemberRole(ctx context.Context, arg UpdateOrganizationMemberRoleParams) (OrganizationMember, error)
+	// Replaces the placeholder result saved for a tool call that was waiting for confirmation
+	UpdateToolResultMessage(ctx context.Context, arg UpdateToolResultMessageParams) error
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
 	UpdateUserPassword(ctx context.Context, arg UpdateUserPasswordParams) error
-	UpsertBusinessUnderstanding(ctx context.Context, arg UpsertBusinessUnderstandingParams) (BusinessUnderstanding, error)
+	UpdateWebhookTool(ctx context.Context, arg UpdateWebhookToolParams) (WebhookTool, error)
+	UpsertOrganizationInvitation(ctx context.Context, arg UpsertOrganizationInvitationParams) (OrganizationInvitation, error)
+	UpsertUnderstandingInterview(ctx context.Context, arg UpsertUnderstandingInterviewParams) (UnderstandingInterview, error)
+	UpsertUnderstandingProvenance(ctx context.Context, arg UpsertUnderstandingProvenanceParams) error
+	// Replaces an unconfirmed enrollment; an enabled one is left as it is
+	UpsertUserTOTP(ctx context.Context, arg UpsertUserTOTPParams) (int64, error)
+	// Counts an attempt; returns no row once the challenge expired or ran out of attempts
+	UseMFAChallengeAttempt(ctx context.Context, arg UseMFAChallengeAttemptParams) (UseMFAChallengeAttemptRow, error)
+	// Counts an attempt, starting a new window once the last one ended; affects no row while the window's attempts are used up
+	UseMFACodeAttempt(ctx context.Context, arg UseMFACodeAttemptParams) (int64, error)
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error)
+	// Fails for a step at or before the last one used, so each code works once
+	UseTOTPStep(ctx context.Context, arg UseTOTPStepParams) (int64, error)
+
 	// Referral tracking methods
 	CountReferralSharesByReferrer(ctx context.Context, referrerID uuid.UUID) (int64, error)
 	CountReferralSignupsByReferrer(ctx context.Context, referrerID uuid.UUID) (int64, error)
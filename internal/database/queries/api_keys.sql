@@ -0,0 +1,27 @@
+-- name: CreateAPIKey :one
+INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetAPIKeyByHash :one
+SELECT k.id, k.user_id, k.scopes, k.expires_at, u.email
+FROM api_keys k
+JOIN users u ON u.id = k.user_id
+WHERE k.key_hash = $1;
+
+-- name: ListAPIKeys :many
+SELECT * FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC;
+
+-- name: CountAPIKeys :one
+SELECT COUNT(*) FROM api_keys WHERE user_id = $1;
+
+-- name: TouchAPIKey :exec
+-- Records use at most once a minute, so busy keys don't write on every request
+UPDATE api_keys SET last_used_at = NOW()
+WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute');
+
+-- name: DeleteAPIKey :execrows
+DELETE FROM api_keys WHERE id = $1 AND user_id = $2;
+
+-- name: DeleteUserAPIKeys :exec
+DELETE FROM api_keys WHERE user_id = $1;
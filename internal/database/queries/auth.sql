@@ -1,17 +1,81 @@
 -- name: CreateRefreshToken :one
-INSERT INTO refresh_tokens (user_id, token_hash, expires_at)
-VALUES ($1, $2, $3)
+INSERT INTO refresh_tokens (user_id, token_hash, expires_at, family_id, user_agent, ip_hash, signed_in_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
 RETURNING *;
 
 -- name: GetRefreshToken :one
 SELECT * FROM refresh_tokens
 WHERE token_hash = $1 AND revoked = FALSE AND expires_at > NOW();
 
+-- name: GetRefreshTokenByHash :one
+-- Unlike GetRefreshToken this also finds revoked and expired tokens
+SELECT * FROM refresh_tokens WHERE token_hash = $1;
+
+-- name: RotateRefreshToken :one
+-- Revokes a usable token and marks it rotated in one statement, so only one
+-- of several concurrent refreshes with the same token succeeds
+UPDATE refresh_tokens SET revoked = TRUE, rotated_at = NOW()
+WHERE token_hash = $1 AND revoked = FALSE AND expires_at > NOW()
+RETURNING *;
+
+-- name: RevokeUserRefreshTokenFamily :execrows
+UPDATE refresh_tokens SET revoked = TRUE
+WHERE user_id = $1 AND family_id = $2 AND revoked = FALSE;
+
+-- name: RevokeRefreshTokenFamily :execrows
+UPDATE refresh_tokens SET revoked = TRUE
+WHERE family_id = $1 AND revoked = FALSE;
+
 -- name: RevokeRefreshToken :exec
 UPDATE refresh_tokens SET revoked = TRUE WHERE token_hash = $1;
 
+-- name: ListUserDevices :many
+-- A device is a token family; only its newest token is still usable
+SELECT * FROM refresh_tokens
+WHERE user_id = $1 AND revoked = FALSE AND expires_at > NOW()
+ORDER BY created_at DESC;
+
 -- name: RevokeAllUserTokens :exec
 UPDATE refresh_tokens SET revoked = TRUE WHERE user_id = $1;
 
 -- name: CleanExpiredTokens :execrows
-DELETE FROM refresh_tokens WHERE expires_at < NOW() OR revoked = TRUE;
+-- Rotated tokens are kept until they expire so their reuse can be detected
+DELETE FROM refresh_tokens
+WHERE expires_at < NOW() OR (revoked = TRUE AND rotated_at IS NULL);
+
+-- name: CreatePasswordResetToken :exec
+INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+VALUES ($1, $2, $3);
+
+-- name: ConsumePasswordResetToken :one
+-- Deleting the token as it is read makes it single-use
+DELETE FROM password_reset_tokens
+WHERE token_hash = $1
+RETURNING *;
+
+-- name: DeleteUserPasswordResetTokens :exec
+DELETE FROM password_reset_tokens WHERE user_id = $1;
+
+-- name: RevokeAccessToken :exec
+INSERT INTO revoked_access_tokens (jti, user_id, expires_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (jti) DO NOTHING;
+
+-- name: ListUserRevokedAccessTokens :many
+SELECT jti FROM revoked_access_tokens
+WHERE user_id = $1 AND expires_at > NOW();
+
+-- name: DeleteExpiredRevokedAccessTokens :execrows
+DELETE FROM revoked_access_tokens WHERE expires_at <= NOW();
+
+-- name: RevokeSession :exec
+INSERT INTO revoked_sessions (family_id, user_id, expires_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (family_id) DO UPDATE SET expires_at = GREATEST(revoked_sessions.expires_at, EXCLUDED.expires_at);
+
+-- name: ListUserRevokedSessions :many
+SELECT family_id FROM revoked_sessions
+WHERE user_id = $1 AND expires_at > NOW();
+
+-- name: DeleteExpiredRevokedSessions :execrows
+DELETE FROM revoked_sessions WHERE expires_at <= NOW();
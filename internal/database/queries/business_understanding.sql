@@ -1,45 +1,74 @@
 -- name: GetBusinessUnderstanding :one
 SELECT * FROM business_understanding WHERE user_id = $1;
 
--- name: UpsertBusinessUnderstanding :one
-INSERT INTO business_understanding (
-    user_id,
-    user_name,
-    job_title,
-    business_name,
-    industry,
-    business_size,
-    user_role,
-    key_workflows,
-    daily_activities,
-    pain_points,
-    bottlenecks,
-    manual_tasks,
-    automation_goals,
-    current_software,
-    existing_automation,
-    additional_notes
-) VALUES (
-    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16
-)
+-- Locks the owning user row so writers of one understanding run one at a time, including the first write when no row exists yet
+-- name: LockUserUnderstanding :exec
+SELECT id FROM users WHERE id = $1 FOR NO KEY UPDATE;
+
+-- name: DeleteBusinessUnderstanding :exec
+DELETE FROM business_understanding WHERE user_id = $1;
+
+-- name: ReplaceBusinessUnderstanding :one
+-- Writes the whole document; callers merge in Go so every write path shares one set of rules
+INSERT INTO business_understanding (user_id, data)
+VALUES ($1, $2)
 ON CONFLICT (user_id) DO UPDATE SET
-    user_name = COALESCE(NULLIF(EXCLUDED.user_name, ''), business_understanding.user_name),
-    job_title = COALESCE(NULLIF(EXCLUDED.job_title, ''), business_understanding.job_title),
-    business_name = COALESCE(NULLIF(EXCLUDED.business_name, ''), business_understanding.business_name),
-    industry = COALESCE(NULLIF(EXCLUDED.industry, ''), business_understanding.industry),
-    business_size = COALESCE(NULLIF(EXCLUDED.business_size, ''), business_understanding.business_size),
-    user_role = COALESCE(NULLIF(EXCLUDED.user_role, ''), business_understanding.user_role),
-    key_workflows = EXCLUDED.key_workflows,
-    daily_activities = EXCLUDED.daily_activities,
-    pain_points = EXCLUDED.pain_points,
-    bottlenecks = EXCLUDED.bottlenecks,
-    manual_tasks = EXCLUDED.manual_tasks,
-    automation_goals = EXCLUDED.automation_goals,
-    current_software = EXCLUDED.current_software,
-    existing_automation = EXCLUDED.existing_automation,
-    additional_notes = COALESCE(NULLIF(EXCLUDED.additional_notes, ''), business_understanding.additional_notes),
+    data = EXCLUDED.data,
     updated_at = NOW()
 RETURNING *;
 
--- name: DeleteBusinessUnderstanding :exec
-DELETE FROM business_understanding WHERE user_id = $1;
+-- Provenance
+
+-- name: UpsertUnderstandingProvenance :exec
+INSERT INTO business_understanding_provenance (
+    understanding_id, field_name, source, session_id, message_id, tool_name, tool_call_id
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (understanding_id, field_name) DO UPDATE SET
+    source = EXCLUDED.source,
+    session_id = EXCLUDED.session_id,
+    message_id = EXCLUDED.message_id,
+    tool_name = EXCLUDED.tool_name,
+    tool_call_id = EXCLUDED.tool_call_id,
+    set_at = NOW();
+
+-- name: ListUnderstandingProvenance :many
+SELECT * FROM business_understanding_provenance
+WHERE understanding_id = $1
+ORDER BY field_name ASC;
+
+-- Change Log
+
+-- name: CreateUnderstandingChange :one
+INSERT INTO business_understanding_changes (
+    user_id, source, session_id, message_id, tool_name, tool_call_id,
+    changed_fields, before_snapshot, after_snapshot, reverted_change_id
+)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING *;
+
+-- name: GetUnderstandingChange :one
+SELECT * FROM business_understanding_changes WHERE id = $1 AND user_id = $2;
+
+-- name: ListUnderstandingChanges :many
+SELECT * FROM business_understanding_changes
+WHERE user_id = $1
+ORDER BY created_at DESC
+LIMIT $2 OFFSET $3;
+
+-- name: CountUnderstandingChanges :one
+SELECT COUNT(*) FROM business_understanding_changes WHERE user_id = $1;
+
+-- Interview State
+
+-- name: GetUnderstandingInterview :one
+SELECT * FROM understanding_interviews WHERE session_id = $1 AND user_id = $2;
+
+-- name: UpsertUnderstandingInterview :one
+INSERT INTO understanding_interviews (session_id, user_id, report_type, asked_fields)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (session_id) DO UPDATE SET
+    report_type = EXCLUDED.report_type,
+    asked_fields = EXCLUDED.asked_fields,
+    updated_at = NOW()
+RETURNING *;
@@ -1,6 +1,6 @@
 -- name: CreateChatSession :one
-INSERT INTO chat_sessions (user_id, title, model, system_prompt)
-VALUES ($1, $2, $3, $4)
+INSERT INTO chat_sessions (user_id, title, model, system_prompt, profile, tools, tool_choice)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
 RETURNING *;
 
 -- name: GetChatSession :one
@@ -30,6 +30,16 @@ INSERT INTO chat_messages (session_id, role, content, tokens_used)
 VALUES ($1, $2, $3, $4)
 RETURNING *;
 
+-- name: CreateChatMessageWithTools :one
+INSERT INTO chat_messages (session_id, role, content, tokens_used, tool_calls, tool_call_id, tool_name)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- Replaces the placeholder result saved for a tool call that was waiting for confirmation
+-- name: UpdateToolResultMessage :exec
+UPDATE chat_messages SET content = $3, tokens_used = $4
+WHERE session_id = $1 AND tool_call_id = $2 AND role = 'tool';
+
 -- name: GetChatMessages :many
 SELECT * FROM chat_messages
 WHERE session_id = $1
@@ -0,0 +1,31 @@
+-- Chat Attachments
+
+-- name: CreateChatAttachment :one
+-- Uploading a file with the same name as an existing one replaces it
+INSERT INTO chat_attachments (session_id, user_id, filename, content_type, size_bytes, data)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (session_id, filename) DO UPDATE SET
+    user_id = EXCLUDED.user_id,
+    content_type = EXCLUDED.content_type,
+    size_bytes = EXCLUDED.size_bytes,
+    data = EXCLUDED.data,
+    created_at = NOW()
+RETURNING id, session_id, user_id, filename, content_type, size_bytes, created_at;
+
+-- name: ListChatAttachments :many
+SELECT id, session_id, user_id, filename, content_type, size_bytes, created_at
+FROM chat_attachments
+WHERE session_id = $1
+ORDER BY created_at ASC;
+
+-- name: ListChatAttachmentFiles :many
+SELECT filename, data FROM chat_attachments WHERE session_id = $1 ORDER BY filename ASC;
+
+-- name: GetChatAttachmentUsage :one
+-- Files in the session other than the one being uploaded, which it would replace
+SELECT COUNT(*) AS file_count, COALESCE(SUM(size_bytes), 0)::bigint AS total_bytes
+FROM chat_attachments
+WHERE session_id = $1 AND filename <> $2;
+
+-- name: DeleteChatAttachment :execrows
+DELETE FROM chat_attachments WHERE id = $1 AND session_id = $2;
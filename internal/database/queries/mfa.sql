@@ -0,0 +1,60 @@
+-- name: UpsertUserTOTP :execrows
+-- Replaces an unconfirmed enrollment; an enabled one is left as it is
+INSERT INTO user_totp (user_id, secret_encrypted)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET secret_encrypted = EXCLUDED.secret_encrypted, last_used_step = 0, created_at = NOW()
+WHERE user_totp.enabled_at IS NULL;
+
+-- name: GetUserTOTP :one
+SELECT * FROM user_totp WHERE user_id = $1;
+
+-- name: EnableUserTOTP :execrows
+UPDATE user_totp SET enabled_at = NOW() WHERE user_id = $1 AND enabled_at IS NULL;
+
+-- name: UseTOTPStep :execrows
+-- Fails for a step at or before the last one used, so each code works once
+UPDATE user_totp SET last_used_step = $2 WHERE user_id = $1 AND last_used_step < $2;
+
+-- name: DeleteUserTOTP :exec
+DELETE FROM user_totp WHERE user_id = $1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO mfa_recovery_codes (user_id, code_hash) VALUES ($1, $2);
+
+-- name: UseRecoveryCode :execrows
+UPDATE mfa_recovery_codes SET used_at = NOW()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: CountUnusedRecoveryCodes :one
+SELECT COUNT(*) FROM mfa_recovery_codes WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: DeleteUserRecoveryCodes :exec
+DELETE FROM mfa_recovery_codes WHERE user_id = $1;
+
+-- name: CreateMFAChallenge :exec
+INSERT INTO mfa_challenges (token_hash, user_id, expires_at) VALUES ($1, $2, $3);
+
+-- name: CountActiveMFAChallenges :one
+SELECT COUNT(*) FROM mfa_challenges WHERE user_id = $1 AND expires_at > NOW();
+
+-- name: UseMFAChallengeAttempt :one
+-- Counts an attempt; returns no row once the challenge expired or ran out of attempts
+UPDATE mfa_challenges SET attempts = attempts + 1
+WHERE token_hash = $1 AND expires_at > NOW() AND attempts < sqlc.arg(max_attempts)::int
+RETURNING user_id, attempts;
+
+-- name: UseMFACodeAttempt :execrows
+-- Counts an attempt, starting a new window once the last one ended; affects no row while the window's attempts are used up
+INSERT INTO mfa_code_attempts (user_id, attempts, window_ends_at)
+VALUES ($1, 1, sqlc.arg(window_ends_at))
+ON CONFLICT (user_id) DO UPDATE SET
+    attempts = CASE WHEN mfa_code_attempts.window_ends_at <= NOW() THEN 1 ELSE mfa_code_attempts.attempts + 1 END,
+    window_ends_at = CASE WHEN mfa_code_attempts.window_ends_at <= NOW() THEN EXCLUDED.window_ends_at ELSE mfa_code_attempts.window_ends_at END
+WHERE mfa_code_attempts.window_ends_at <= NOW() OR mfa_code_attempts.attempts < sqlc.arg(max_attempts)::int;
+
+-- name: DeleteMFAChallenge :execrows
+DELETE FROM mfa_challenges WHERE token_hash = $1;
+
+-- name: DeleteExpiredMFAChallenges :execrows
+DELETE FROM mfa_challenges WHERE expires_at <= NOW();
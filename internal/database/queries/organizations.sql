@@ -0,0 +1,88 @@
+-- Organizations
+
+-- name: CreateOrganization :one
+INSERT INTO organizations (name, created_by)
+VALUES ($1, $2)
+RETURNING *;
+
+-- name: GetOrganization :one
+SELECT * FROM organizations WHERE id = $1;
+
+-- Organization Members
+
+-- name: CreateOrganizationMember :one
+INSERT INTO organization_members (organization_id, user_id, role)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetOrganizationMemberByUserID :one
+SELECT * FROM organization_members WHERE user_id = $1;
+
+-- name: ListOrganizationMembers :many
+SELECT m.id, m.organization_id, m.user_id, m.role, m.created_at, u.email, u.name
+FROM organization_members m
+JOIN users u ON m.user_id = u.id
+WHERE m.organization_id = $1
+ORDER BY m.created_at ASC;
+
+-- Locks every member row of the organization so owner checks and the change that depends on them run one at a time
+-- name: LockOrganizationMembers :exec
+SELECT id FROM organization_members WHERE organization_id = $1 FOR UPDATE;
+
+-- name: UpdateOrganizationMemberRole :one
+UPDATE organization_members SET role = $3
+WHERE organization_id = $1 AND user_id = $2
+RETURNING *;
+
+-- name: DeleteOrganizationMember :exec
+DELETE FROM organization_members WHERE organization_id = $1 AND user_id = $2;
+
+-- name: CountOrganizationOwners :one
+SELECT COUNT(*) FROM organization_members WHERE organization_id = $1 AND role = 'owner';
+
+-- Organization Invitations
+
+-- name: UpsertOrganizationInvitation :one
+INSERT INTO organization_invitations (organization_id, email, role, invited_by, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (organization_id, email) DO UPDATE SET
+    role = EXCLUDED.role,
+    invited_by = EXCLUDED.invited_by,
+    expires_at = EXCLUDED.expires_at,
+    created_at = NOW()
+RETURNING *;
+
+-- name: ListOrganizationInvitations :many
+SELECT * FROM organization_invitations
+WHERE organization_id = $1 AND expires_at > NOW()
+ORDER BY created_at DESC;
+
+-- name: ListInvitationsByEmail :many
+SELECT i.id, i.organization_id, i.email, i.role, i.invited_by, i.expires_at, i.created_at, o.name AS organization_name
+FROM organization_invitations i
+JOIN organizations o ON i.organization_id = o.id
+WHERE i.email = $1 AND i.expires_at > NOW()
+ORDER BY i.created_at DESC;
+
+-- name: GetInvitationByEmail :one
+SELECT * FROM organization_invitations
+WHERE id = $1 AND email = $2 AND expires_at > NOW();
+
+-- name: DeleteOrganizationInvitation :execrows
+DELETE FROM organization_invitations WHERE id = $1 AND organization_id = $2;
+
+-- name: DeleteInvitationByEmail :execrows
+DELETE FROM organization_invitations WHERE id = $1 AND email = $2;
+
+-- Organization Understanding
+
+-- name: GetOrganizationUnderstanding :one
+SELECT * FROM organization_understanding WHERE organization_id = $1;
+
+-- name: ReplaceOrganizationUnderstanding :one
+INSERT INTO organization_understanding (organization_id, data)
+VALUES ($1, $2)
+ON CONFLICT (organization_id) DO UPDATE SET
+    data = EXCLUDED.data,
+    updated_at = NOW()
+RETURNING *;
@@ -0,0 +1,24 @@
+-- Tool Call Confirmations
+
+-- name: CreateToolCallConfirmation :one
+INSERT INTO tool_call_confirmations (session_id, user_id, message_id, tool_call_id, tool_name, arguments)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: GetToolCallConfirmation :one
+SELECT * FROM tool_call_confirmations
+WHERE session_id = $1 AND tool_call_id = $2 AND user_id = $3;
+
+-- name: ListPendingToolCallConfirmations :many
+SELECT * FROM tool_call_confirmations
+WHERE session_id = $1 AND user_id = $2 AND status = 'pending'
+ORDER BY created_at ASC;
+
+-- name: ResolveToolCallConfirmation :one
+UPDATE tool_call_confirmations SET status = $2, resolved_at = NOW()
+WHERE id = $1 AND status = 'pending'
+RETURNING *;
+
+-- name: SetToolCallConfirmationResult :exec
+UPDATE tool_call_confirmations SET result = $2
+WHERE id = $1;
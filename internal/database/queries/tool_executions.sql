@@ -0,0 +1,24 @@
+-- Tool Executions
+
+-- name: CreateToolExecution :exec
+INSERT INTO tool_executions (
+    user_id, session_id, message_id, tool_call_id, tool_name,
+    arguments_hash, arguments, outcome, success, error, duration_ms
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11);
+
+-- name: GetToolExecution :one
+SELECT * FROM tool_executions WHERE id = $1;
+
+-- name: ListToolExecutions :many
+-- Newest first; each filter applies only when set. Page with created_before.
+SELECT * FROM tool_executions
+WHERE (sqlc.narg('tool_name')::text IS NULL OR tool_name = sqlc.narg('tool_name'))
+  AND (sqlc.narg('user_id')::uuid IS NULL OR user_id = sqlc.narg('user_id'))
+  AND (sqlc.narg('session_id')::uuid IS NULL OR session_id = sqlc.narg('session_id'))
+  AND (sqlc.narg('success')::boolean IS NULL OR success = sqlc.narg('success'))
+  AND (sqlc.narg('created_before')::timestamptz IS NULL OR created_at < sqlc.narg('created_before'))
+ORDER BY created_at DESC
+LIMIT sqlc.arg('page_size');
+
+-- name: DeleteToolExecutionsBefore :execrows
+DELETE FROM tool_executions WHERE created_at < $1;
@@ -0,0 +1,16 @@
+-- name: CreateUserIdentity :one
+INSERT INTO user_identities (user_id, provider, provider_id, email, last_used_at)
+VALUES ($1, $2, $3, $4, NOW())
+RETURNING *;
+
+-- name: GetUserIdentity :one
+SELECT * FROM user_identities WHERE provider = $1 AND provider_id = $2;
+
+-- name: ListUserIdentities :many
+SELECT * FROM user_identities WHERE user_id = $1 ORDER BY created_at;
+
+-- name: TouchUserIdentity :exec
+UPDATE user_identities SET last_used_at = NOW() WHERE id = $1;
+
+-- name: DeleteUserIdentity :execrows
+DELETE FROM user_identities WHERE id = $1 AND user_id = $2;
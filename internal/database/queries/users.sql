@@ -23,5 +23,28 @@ RETURNING *;
 -- name: UpdateUserPassword :exec
 UPDATE users SET password_hash = $2 WHERE id = $1;
 
+-- name: MarkUserEmailVerified :one
+-- Only verifies the address the token was issued for
+UPDATE users SET email_verified = TRUE
+WHERE id = $1 AND email = $2
+RETURNING *;
+
+-- name: GetUserTokensValidAfter :one
+SELECT tokens_valid_after FROM users WHERE id = $1;
+
+-- name: RevokeUserAccessTokens :exec
+-- Token times have one-second precision, so tokens issued in the same
+-- second as the revocation stay valid
+UPDATE users SET tokens_valid_after = date_trunc('second', NOW()) WHERE id = $1;
+
+-- name: SetUserMFARequired :execrows
+UPDATE users SET mfa_required = $2 WHERE id = $1;
+
+-- name: GetUserMFAState :one
+SELECT u.mfa_required, (t.enabled_at IS NOT NULL)::boolean AS mfa_enabled
+FROM users u
+LEFT JOIN user_totp t ON t.user_id = u.id
+WHERE u.id = $1;
+
 -- name: DeleteUser :exec
 DELETE FROM users WHERE id = $1;
@@ -0,0 +1,29 @@
+-- Webhook Tools
+
+-- name: CreateWebhookTool :one
+INSERT INTO webhook_tools (name, description, parameters, url, method, headers, timeout_seconds, response_mapping, enabled, created_by)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING *;
+
+-- name: GetWebhookTool :one
+SELECT * FROM webhook_tools WHERE id = $1;
+
+-- name: ListWebhookTools :many
+SELECT * FROM webhook_tools ORDER BY name ASC;
+
+-- name: UpdateWebhookTool :one
+UPDATE webhook_tools SET
+    name = $2,
+    description = $3,
+    parameters = $4,
+    url = $5,
+    method = $6,
+    headers = $7,
+    timeout_seconds = $8,
+    response_mapping = $9,
+    enabled = $10
+WHERE id = $1
+RETURNING *;
+
+-- name: DeleteWebhookTool :execrows
+DELETE FROM webhook_tools WHERE id = $1;
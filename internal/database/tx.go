@@ -0,0 +1,37 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// txBeginner is a DBTX that can start a transaction, such as a *pgxpool.Pool or a pgx.Tx
+type txBeginner interface {
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// InTx runs fn with queries bound to one transaction, committing if fn
+// succeeds and rolling back otherwise. Called inside a transaction, it uses a savepoint.
+func (q *Queries) InTx(ctx context.Context, fn func(q *Queries) error) error {
+	db, ok := q.db.(txBeginner)
+	if !ok {
+		return errors.New("database does not support transactions")
+	}
+
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }() // No-op once committed
+
+	if err := fn(q.WithTx(tx)); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
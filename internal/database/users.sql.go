@@ -9,12 +9,13 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (email, password_hash, name, provider, provider_id, email_verified)
 VALUES ($1, $2, $3, $4, $5, $6)
-RETURNING id, email, password_hash, name, avatar_url, provider, provider_id, email_verified, created_at, updated_at
+RETURNING id, email, password_hash, name, avatar_url, provider, provider_id, email_verified, created_at, updated_at, tokens_valid_after, mfa_required
 `
 
 type CreateUserParams struct {
@@ -47,6 +48,8 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.EmailVerified,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.TokensValidAfter,
+		&i.MfaRequired,
 	)
 	return i, err
 }
@@ -61,7 +64,7 @@ func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, email, password_hash, name, avatar_url, provider, provider_id, email_verified, created_at, updated_at FROM users WHERE email = $1
+SELECT id, email, password_hash, name, avatar_url, provider, provider_id, email_verified, created_at, updated_at, tokens_valid_after, mfa_required FROM users WHERE email = $1
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -78,12 +81,14 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.EmailVerified,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.TokensValidAfter,
+		&i.MfaRequired,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, email, password_hash, name, avatar_url, provider, provider_id, email_verified, created_at, updated_at FROM users WHERE id = $1
+SELECT id, email, password_hash, name, avatar_url, provider, provider_id, email_verified, created_at, updated_at, tokens_valid_after, mfa_required FROM users WHERE id = $1
 `
 
 func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
@@ -100,12 +105,14 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.EmailVerified,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.TokensValidAfter,
+		&i.MfaRequired,
 	)
 	return i, err
 }
 
 const getUserByProvider = `-- name: GetUserByProvider :one
-SELECT id, email, password_hash, name, avatar_url, provider, provider_id, email_verified, created_at, updated_at FROM users WHERE provider = $1 AND provider_id = $2
+SELECT id, email, password_hash, name, avatar_url, provider, provider_id, email_verified, created_at, updated_at, tokens_valid_after, mfa_required FROM users WHERE provider = $1 AND provider_id = $2
 `
 
 type GetUserByProviderParams struct {
@@ -127,17 +134,109 @@ func (q *Queries) GetUserByProvider(ctx context.Context, arg GetUserByProviderPa
 		&i.EmailVerified,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.TokensValidAfter,
+		&i.MfaRequired,
 	)
 	return i, err
 }
 
+const getUserMFAState = `-- name: GetUserMFAState :one
+SELECT u.mfa_required, (t.enabled_at IS NOT NULL)::boolean AS mfa_enabled
+FROM users u
+LEFT JOIN user_totp t ON t.user_id = u.id
+WHERE u.id = $1
+`
+
+type GetUserMFAStateRow struct {
+	MfaRequired bool `json:"mfa_required"`
+	MfaEnabled  bool `json:"mfa_enabled"`
+}
+
+func (q *Queries) GetUserMFAState(ctx context.Context, id uuid.UUID) (GetUserMFAStateRow, error) {
+	row := q.db.QueryRow(ctx, getUserMFAState, id)
+	var i GetUserMFAStateRow
+	err := row.Scan(&i.MfaRequired, &i.MfaEnabled)
+	return i, err
+}
+
+const getUserTokensValidAfter = `-- name: GetUserTokensValidAfter :one
+SELECT tokens_valid_after FROM users WHERE id = $1
+`
+
+func (q *Queries) GetUserTokensValidAfter(ctx context.Context, id uuid.UUID) (pgtype.Timestamptz, error) {
+	row := q.db.QueryRow(ctx, getUserTokensValidAfter, id)
+	var tokens_valid_after pgtype.Timestamptz
+	err := row.Scan(&tokens_valid_after)
+	return tokens_valid_after, err
+}
+
+const markUserEmailVerified = `-- name: MarkUserEmailVerified :one
+UPDATE users SET email_verified = TRUE
+WHERE id = $1 AND email = $2
+RETURNING id, email, password_hash, name, avatar_url, provider, provider_id, email_verified, created_at, updated_at, tokens_valid_after, mfa_required
+`
+
+type MarkUserEmailVerifiedParams struct {
+	ID    uuid.UUID `json:"id"`
+	Email string    `json:"email"`
+}
+
+// Only verifies the address the token was issued for
+func (q *Queries) MarkUserEmailVerified(ctx context.Context, arg MarkUserEmailVerifiedParams) (User, error) {
+	row := q.db.QueryRow(ctx, markUserEmailVerified, arg.ID, arg.Email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.PasswordHash,
+		&i.Name,
+		&i.AvatarUrl,
+		&i.Provider,
+		&i.ProviderID,
+		&i.EmailVerified,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.TokensValidAfter,
+		&i.MfaRequired,
+	)
+	return i, err
+}
+
+const revokeUserAccessTokens = `-- name: RevokeUserAccessTokens :exec
+UPDATE users SET tokens_valid_after = date_trunc('second', NOW()) WHERE id = $1
+`
+
+// Token times have one-second precision, so tokens issued in the same
+// second as the revocation stay valid
+func (q *Queries) RevokeUserAccessTokens(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, revokeUserAccessTokens, id)
+	return err
+}
+
+const setUserMFARequired = `-- name: SetUserMFARequired :execrows
+UPDATE users SET mfa_required = $2 WHERE id = $1
+`
+
+type SetUserMFARequiredParams struct {
+	ID          uuid.UUID `json:"id"`
+	MfaRequired bool      `json:"mfa_required"`
+}
+
+func (q *Queries) SetUserMFARequired(ctx context.Context, arg SetUserMFARequiredParams) (int64, error) {
+	result, err := q.db.Exec(ctx, setUserMFARequired, arg.ID, arg.MfaRequired)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
 const updateUser = `-- name: UpdateUser :one
 UPDATE users
 SET name = COALESCE($2, name),
     avatar_url = COALESCE($3, avatar_url),
     email_verified = COALESCE($4, email_verified)
 WHERE id = $1
-RETURNING id, email, password_hash, name, avatar_url, provider, provider_id, email_verified, created_at, updated_at
+RETURNING id, email, password_hash, name, avatar_url, provider, provider_id, email_verified, created_at, updated_at, tokens_valid_after, mfa_required
 `
 
 type UpdateUserParams struct {
@@ -166,6 +265,8 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.EmailVerified,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.TokensValidAfter,
+		&i.MfaRequired,
 	)
 	return i, err
 }
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/logging"
+	"github.com/agpt-go/chatbot-api/internal/middleware"
+	"github.com/agpt-go/chatbot-api/internal/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" validate:"required,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1"` // chat:read, chat:write, reports:read, reports:write, mcp
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`             // Omit for a key that doesn't expire
+}
+
+type APIKeyResponse struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Prefix     string   `json:"prefix"`
+	Scopes     []string `json:"scopes"`
+	ExpiresAt  string   `json:"expires_at,omitempty"`
+	LastUsedAt string   `json:"last_used_at,omitempty"`
+	CreatedAt  string   `json:"created_at"`
+}
+
+// CreateAPIKeyResponse carries the key itself, which is only shown once
+type CreateAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+func apiKeyToResponse(key services.APIKey) APIKeyResponse {
+	resp := APIKeyResponse{
+		ID:        key.ID.String(),
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Scopes:    key.Scopes,
+		CreatedAt: key.CreatedAt.Format(time.RFC3339),
+	}
+	if !key.ExpiresAt.IsZero() {
+		resp.ExpiresAt = key.ExpiresAt.Format(time.RFC3339)
+	}
+	if !key.LastUsedAt.IsZero() {
+		resp.LastUsedAt = key.LastUsedAt.Format(time.RFC3339)
+	}
+	return resp
+}
+
+// ListAPIKeys godoc
+// @Summary List API keys
+// @Description List the authenticated user's API keys, newest first. Keys are identified by their prefix; the keys themselves are not stored.
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} APIKeyResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /me/api-keys [get]
+func (h *AuthHandler) ListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	keys, err := h.authService.ListAPIKeys(r.Context(), userID)
+	if err != nil {
+		logging.Error("failed to list api keys", err, "userID", userID.String())
+		writeError(w, http.StatusInternalServerError, "Failed to list API keys")
+		return
+	}
+
+	response := make([]APIKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		response = append(response, apiKeyToResponse(key))
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// CreateAPIKey godoc
+// @Summary Create an API key
+// @Description Create a key that acts as the authenticated user with the given scopes, sent as "Authorization: Bearer agpt_...". The key is only in this response.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateAPIKeyRequest true "Key name, scopes and optional expiry"
+// @Success 201 {object} CreateAPIKeyResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /me/api-keys [post]
+func (h *AuthHandler) CreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	var expiresAt time.Time
+	if req.ExpiresAt != nil {
+		expiresAt = *req.ExpiresAt
+	}
+
+	key, secret, err := h.authService.CreateAPIKey(r.Context(), userID, req.Name, req.Scopes, expiresAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidAPIKeyScope):
+			writeError(w, http.StatusBadRequest, "Invalid scope; use chat:read, chat:write, reports:read, reports:write or mcp")
+		case errors.Is(err, services.ErrInvalidAPIKeyExpiry):
+			writeError(w, http.StatusBadRequest, "expires_at must be in the future")
+		case errors.Is(err, services.ErrTooManyAPIKeys):
+			writeError(w, http.StatusConflict, "Too many API keys; revoke one first")
+		default:
+			logging.Error("failed to create api key", err, "userID", userID.String())
+			writeError(w, http.StatusInternalServerError, "Failed to create API key")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, CreateAPIKeyResponse{
+		APIKeyResponse: apiKeyToResponse(*key),
+		Key:            secret,
+	})
+}
+
+// RevokeAPIKey godoc
+// @Summary Revoke an API key
+// @Description Delete one of the authenticated user's API keys. It stops working at once.
+// @Tags User
+// @Security BearerAuth
+// @Param keyID path string true "API key UUID"
+// @Success 204 "API key revoked"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /me/api-keys/{keyID} [delete]
+func (h *AuthHandler) RevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	keyID, err := uuid.Parse(chi.URLParam(r, "keyID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid API key ID")
+		return
+	}
+
+	if err := h.authService.RevokeAPIKey(r.Context(), userID, keyID); err != nil {
+		if errors.Is(err, services.ErrAPIKeyNotFound) {
+			writeError(w, http.StatusNotFound, "API key not found")
+			return
+		}
+		logging.Error("failed to revoke api key", err, "userID", userID.String())
+		writeError(w, http.StatusInternalServerError, "Failed to revoke API key")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func TestCreateAPIKey(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		err  error
+		want int
+	}{
+		{"created", `{"name":"CI","scopes":["chat:read"]}`, nil, http.StatusCreated},
+		{"with expiry", `{"name":"CI","scopes":["chat:read"],"expires_at":"2099-01-01T00:00:00Z"}`, nil, http.StatusCreated},
+		{"missing scopes", `{"name":"CI"}`, nil, http.StatusBadRequest},
+		{"missing name", `{"scopes":["chat:read"]}`, nil, http.StatusBadRequest},
+		{"unknown scope", `{"name":"CI","scopes":["chat:read"]}`, services.ErrInvalidAPIKeyScope, http.StatusBadRequest},
+		{"expired", `{"name":"CI","scopes":["chat:read"]}`, services.ErrInvalidAPIKeyExpiry, http.StatusBadRequest},
+		{"too many keys", `{"name":"CI","scopes":["chat:read"]}`, services.ErrTooManyAPIKeys, http.StatusConflict},
+		{"database error", `{"name":"CI","scopes":["chat:read"]}`, errors.New("db down"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAuthHandler(&mockAuthService{
+				createAPIKey: func(ctx context.Context, userID uuid.UUID, name string, scopes []string, expiresAt time.Time) (*services.APIKey, string, error) {
+					if tt.err != nil {
+						return nil, "", tt.err
+					}
+					return &services.APIKey{ID: uuid.New(), Name: name, Prefix: "agpt_1a2b3c4d", Scopes: scopes, ExpiresAt: expiresAt, CreatedAt: time.Now()}, "agpt_secret", nil
+				},
+			}, nil, nil)
+
+			req := withTestUser(httptest.NewRequest(http.MethodPost, "/api/v1/me/api-keys", bytes.NewBufferString(tt.body)))
+			rec := httptest.NewRecorder()
+
+			handler.CreateAPIKey(rec, req)
+
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want != http.StatusCreated {
+				return
+			}
+			var resp CreateAPIKeyResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Key != "agpt_secret" || resp.Prefix != "agpt_1a2b3c4d" || resp.Name != "CI" {
+				t.Errorf("response = %+v", resp)
+			}
+		})
+	}
+}
+
+func TestListAPIKeys(t *testing.T) {
+	now := time.Now()
+	handler := NewAuthHandler(&mockAuthService{
+		listAPIKeys: func(ctx context.Context, userID uuid.UUID) ([]services.APIKey, error) {
+			return []services.APIKey{
+				{ID: uuid.New(), Name: "CI", Prefix: "agpt_1a2b3c4d", Scopes: []string{"chat:write"}, ExpiresAt: now.Add(time.Hour), LastUsedAt: now, CreatedAt: now},
+				{ID: uuid.New(), Name: "Reports", Prefix: "agpt_5e6f7a8b", Scopes: []string{"reports:read"}, CreatedAt: now},
+			}, nil
+		},
+	}, nil, nil)
+
+	req := withTestUser(httptest.NewRequest(http.MethodGet, "/api/v1/me/api-keys", nil))
+	rec := httptest.NewRecorder()
+
+	handler.ListAPIKeys(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var keys []map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&keys); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("keys = %+v", keys)
+	}
+	if _, ok := keys[0]["key"]; ok {
+		t.Error("listed key includes the key itself")
+	}
+	if _, ok := keys[1]["expires_at"]; ok {
+		t.Error("key without expiry has expires_at")
+	}
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	tests := []struct {
+		name  string
+		keyID string
+		err   error
+		want  int
+	}{
+		{"revoked", uuid.NewString(), nil, http.StatusNoContent},
+		{"invalid ID", "not-a-uuid", nil, http.StatusBadRequest},
+		{"not found", uuid.NewString(), services.ErrAPIKeyNotFound, http.StatusNotFound},
+		{"database error", uuid.NewString(), errors.New("db down"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAuthHandler(&mockAuthService{
+				revokeAPIKey: func(ctx context.Context, userID, keyID uuid.UUID) error {
+					if keyID.String() != tt.keyID {
+						t.Errorf("keyID = %s, want %s", keyID, tt.keyID)
+					}
+					return tt.err
+				},
+			}, nil, nil)
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/v1/me/api-keys/"+tt.keyID, nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("keyID", tt.keyID)
+			req = withTestUser(req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx)))
+			rec := httptest.NewRecorder()
+
+			handler.RevokeAPIKey(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/agpt-go/chatbot-api/internal/logging"
+	"github.com/agpt-go/chatbot-api/internal/middleware"
+	"github.com/agpt-go/chatbot-api/internal/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// multipartOverhead allows for the multipart framing around an uploaded file
+const multipartOverhead = 64 << 10
+
+// AttachmentHandler manages files uploaded to chat sessions for analysis
+type AttachmentHandler struct {
+	attachments AttachmentServicer
+}
+
+// NewAttachmentHandler creates a new attachment handler
+func NewAttachmentHandler(attachments AttachmentServicer) *AttachmentHandler {
+	return &AttachmentHandler{
+		attachments: attachments,
+	}
+}
+
+// writeAttachmentError maps attachment service errors to responses
+func writeAttachmentError(w http.ResponseWriter, err error, action string) {
+	switch {
+	case errors.Is(err, services.ErrSessionNotFound):
+		writeError(w, http.StatusNotFound, "Session not found")
+	case errors.Is(err, services.ErrAttachmentNotFound):
+		writeError(w, http.StatusNotFound, "Attachment not found")
+	case errors.Is(err, services.ErrAttachmentTooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, err.Error())
+	case errors.Is(err, services.ErrAttachmentLimit):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, services.ErrAttachmentType):
+		writeError(w, http.StatusUnsupportedMediaType, err.Error())
+	case errors.Is(err, services.ErrAttachmentInvalidName):
+		writeError(w, http.StatusBadRequest, "Invalid file name")
+	default:
+		logging.Error("failed to "+action, err)
+		writeError(w, http.StatusInternalServerError, "Failed to "+action)
+	}
+}
+
+// UploadAttachment godoc
+// @Summary Upload attachment
+// @Description Upload a file to a chat session so the assistant can analyze it with the run_analysis tool. Supported types are .csv, .tsv, .xlsx, .json, .txt and .md. A file with the same name as an existing one replaces it.
+// @Tags Attachments
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param sessionID path string true "Session UUID"
+// @Param file formData file true "The file to upload"
+// @Success 201 {object} services.AttachmentView
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 413 {object} ErrorResponse
+// @Failure 415 {object} ErrorResponse
+// @Router /sessions/{sessionID}/attachments [post]
+func (h *AttachmentHandler) UploadAttachment(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	sessionID, err := uuid.Parse(chi.URLParam(r, "sessionID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+
+	maxBytes := h.attachments.MaxFileBytes()
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes+multipartOverhead)
+	reader, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Expected a multipart/form-data upload")
+		return
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeAttachmentError(w, services.ErrAttachmentTooLarge, "upload attachment")
+				return
+			}
+			writeError(w, http.StatusBadRequest, "Missing file field")
+			return
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+
+		data, err := io.ReadAll(io.LimitReader(part, maxBytes+1))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				writeAttachmentError(w, services.ErrAttachmentTooLarge, "upload attachment")
+				return
+			}
+			writeError(w, http.StatusBadRequest, "Failed to read file")
+			return
+		}
+		if int64(len(data)) > maxBytes {
+			writeAttachmentError(w, fmt.Errorf("%w: the limit is %d MB", services.ErrAttachmentTooLarge, maxBytes>>20), "upload attachment")
+			return
+		}
+
+		attachment, err := h.attachments.Upload(r.Context(), userID, sessionID, part.FileName(), data)
+		if err != nil {
+			writeAttachmentError(w, err, "upload attachment")
+			return
+		}
+		writeJSON(w, http.StatusCreated, attachment)
+		return
+	}
+}
+
+// ListAttachments godoc
+// @Summary List attachments
+// @Description List the files uploaded to a chat session
+// @Tags Attachments
+// @Produce json
+// @Security BearerAuth
+// @Param sessionID path string true "Session UUID"
+// @Success 200 {array} services.AttachmentView
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /sessions/{sessionID}/attachments [get]
+func (h *AttachmentHandler) ListAttachments(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	sessionID, err := uuid.Parse(chi.URLParam(r, "sessionID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+
+	attachments, err := h.attachments.List(r.Context(), userID, sessionID)
+	if err != nil {
+		writeAttachmentError(w, err, "list attachments")
+		return
+	}
+	writeJSON(w, http.StatusOK, attachments)
+}
+
+// DeleteAttachment godoc
+// @Summary Delete attachment
+// @Description Delete a file from a chat session
+// @Tags Attachments
+// @Security BearerAuth
+// @Param sessionID path string true "Session UUID"
+// @Param attachmentID path string true "Attachment UUID"
+// @Success 204 "No Content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /sessions/{sessionID}/attachments/{attachmentID} [delete]
+func (h *AttachmentHandler) DeleteAttachment(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	sessionID, err := uuid.Parse(chi.URLParam(r, "sessionID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+	attachmentID, err := uuid.Parse(chi.URLParam(r, "attachmentID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid attachment ID")
+		return
+	}
+
+	if err := h.attachments.Delete(r.Context(), userID, sessionID, attachmentID); err != nil {
+		writeAttachmentError(w, err, "delete attachment")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/agpt-go/chatbot-api/internal/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// mockAttachmentService implements AttachmentServicer for testing
+type mockAttachmentService struct {
+	uploadFunc func(ctx context.Context, userID, sessionID uuid.UUID, filename string, data []byte) (*services.AttachmentView, error)
+	deleteFunc func(ctx context.Context, userID, sessionID, attachmentID uuid.UUID) error
+}
+
+func (m *mockAttachmentService) Upload(ctx context.Context, userID, sessionID uuid.UUID, filename string, data []byte) (*services.AttachmentView, error) {
+	return m.uploadFunc(ctx, userID, sessionID, filename, data)
+}
+
+func (m *mockAttachmentService) List(ctx context.Context, userID, sessionID uuid.UUID) ([]services.AttachmentView, error) {
+	return []services.AttachmentView{}, nil
+}
+
+func (m *mockAttachmentService) Delete(ctx context.Context, userID, sessionID, attachmentID uuid.UUID) error {
+	return m.deleteFunc(ctx, userID, sessionID, attachmentID)
+}
+
+func (m *mockAttachmentService) MaxFileBytes() int64 {
+	return 1024
+}
+
+func withAttachmentParams(r *http.Request, sessionID, attachmentID string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("sessionID", sessionID)
+	if attachmentID != "" {
+		rctx.URLParams.Add("attachmentID", attachmentID)
+	}
+	return withTestUser(r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx)))
+}
+
+func newUploadRequest(t *testing.T, sessionID, filename string, data []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(data)
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/sessions/"+sessionID+"/attachments", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return withAttachmentParams(req, sessionID, "")
+}
+
+func TestUploadAttachment(t *testing.T) {
+	sessionID := uuid.New()
+
+	t.Run("created", func(t *testing.T) {
+		var gotName string
+		var gotData []byte
+		handler := NewAttachmentHandler(&mockAttachmentService{
+			uploadFunc: func(ctx context.Context, userID, sid uuid.UUID, filename string, data []byte) (*services.AttachmentView, error) {
+				gotName, gotData = filename, data
+				return &services.AttachmentView{ID: uuid.New(), Filename: filename, SizeBytes: int64(len(data))}, nil
+			},
+		})
+		rec := httptest.NewRecorder()
+
+		handler.UploadAttachment(rec, newUploadRequest(t, sessionID.String(), "orders.csv", []byte("a,b\n1,2\n")))
+
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+		}
+		if gotName != "orders.csv" || string(gotData) != "a,b\n1,2\n" {
+			t.Errorf("unexpected upload: %q %q", gotName, gotData)
+		}
+	})
+
+	t.Run("service errors", func(t *testing.T) {
+		tests := map[error]int{
+			services.ErrSessionNotFound:    http.StatusNotFound,
+			services.ErrAttachmentTooLarge: http.StatusRequestEntityTooLarge,
+			services.ErrAttachmentLimit:    http.StatusConflict,
+			services.ErrAttachmentType:     http.StatusUnsupportedMediaType,
+		}
+		for serviceErr, want := range tests {
+			handler := NewAttachmentHandler(&mockAttachmentService{
+				uploadFunc: func(ctx context.Context, userID, sid uuid.UUID, filename string, data []byte) (*services.AttachmentView, error) {
+					return nil, fmt.Errorf("%w: detail", serviceErr)
+				},
+			})
+			rec := httptest.NewRecorder()
+
+			handler.UploadAttachment(rec, newUploadRequest(t, sessionID.String(), "report.exe", []byte("x")))
+
+			if rec.Code != want {
+				t.Errorf("%v: status = %d, want %d", serviceErr, rec.Code, want)
+			}
+		}
+	})
+
+	t.Run("body larger than the limit", func(t *testing.T) {
+		handler := NewAttachmentHandler(&mockAttachmentService{
+			uploadFunc: func(ctx context.Context, userID, sid uuid.UUID, filename string, data []byte) (*services.AttachmentView, error) {
+				t.Fatal("upload should not be called")
+				return nil, nil
+			},
+		})
+		rec := httptest.NewRecorder()
+
+		handler.UploadAttachment(rec, newUploadRequest(t, sessionID.String(), "big.csv", bytes.Repeat([]byte("a"), 128<<10)))
+
+		if rec.Code != http.StatusRequestEntityTooLarge {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusRequestEntityTooLarge)
+		}
+	})
+
+	t.Run("not multipart", func(t *testing.T) {
+		handler := NewAttachmentHandler(&mockAttachmentService{})
+		req := withAttachmentParams(httptest.NewRequest(http.MethodPost, "/", bytes.NewReader([]byte("{}"))), sessionID.String(), "")
+		rec := httptest.NewRecorder()
+
+		handler.UploadAttachment(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestDeleteAttachment(t *testing.T) {
+	handler := NewAttachmentHandler(&mockAttachmentService{
+		deleteFunc: func(ctx context.Context, userID, sessionID, attachmentID uuid.UUID) error {
+			return services.ErrAttachmentNotFound
+		},
+	})
+	req := withAttachmentParams(httptest.NewRequest(http.MethodDelete, "/", nil), uuid.New().String(), uuid.New().String())
+	rec := httptest.NewRecorder()
+
+	handler.DeleteAttachment(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
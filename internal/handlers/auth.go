@@ -6,8 +6,11 @@ import (
 	"net/http"
 
 	"github.com/agpt-go/chatbot-api/internal/logging"
+	"github.com/agpt-go/chatbot-api/internal/middleware"
 	"github.com/agpt-go/chatbot-api/internal/services"
+	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
 )
 
 type AuthHandler struct {
@@ -43,6 +46,28 @@ type RefreshRequest struct {
 	RefreshToken string `json:"refresh_token" validate:"required"`
 }
 
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=8"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=8"`
+}
+
 type AuthResponse struct {
 	User   *UserResponse       `json:"user"`
 	Tokens *services.TokenPair `json:"tokens"`
@@ -81,7 +106,7 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, tokens, err := h.authService.Register(r.Context(), req.Email, req.Password, req.Name)
+	user, tokens, err := h.authService.Register(clientContext(r), req.Email, req.Password, req.Name)
 	if err != nil {
 		if errors.Is(err, services.ErrUserExists) {
 			writeError(w, http.StatusConflict, "User already exists")
@@ -116,14 +141,16 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 // Login godoc
 // @Summary Login user
-// @Description Authenticate user with email and password
+// @Description Authenticate user with email and password. Users with MFA enabled get an MFA challenge instead of tokens, completed at /auth/mfa/verify.
 // @Tags Authentication
 // @Accept json
 // @Produce json
 // @Param request body LoginRequest true "Login credentials"
 // @Success 200 {object} AuthResponse
+// @Success 200 {object} MFAChallengeResponse
 // @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /auth/login [post]
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
@@ -138,13 +165,19 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, tokens, err := h.authService.Login(r.Context(), req.Email, req.Password)
+	user, tokens, err := h.authService.Login(clientContext(r), req.Email, req.Password)
 	if err != nil {
-		if errors.Is(err, services.ErrInvalidCredentials) {
+		var challenge *services.MFAChallengeError
+		switch {
+		case errors.As(err, &challenge):
+			writeJSON(w, http.StatusOK, mfaChallengeToResponse(challenge))
+		case errors.Is(err, services.ErrTooManyMFAChallenges):
+			writeError(w, http.StatusTooManyRequests, "Too many sign-ins are waiting for an MFA code, try again in a few minutes")
+		case errors.Is(err, services.ErrInvalidCredentials):
 			writeError(w, http.StatusUnauthorized, "Invalid email or password")
-			return
+		default:
+			writeError(w, http.StatusInternalServerError, "Failed to login")
 		}
-		writeError(w, http.StatusInternalServerError, "Failed to login")
 		return
 	}
 
@@ -161,7 +194,7 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 
 // Refresh godoc
 // @Summary Refresh access token
-// @Description Get a new access token using a valid refresh token
+// @Description Get a new access token using a valid refresh token. Each refresh token works once; presenting a used one signs out every device of that sign-in.
 // @Tags Authentication
 // @Accept json
 // @Produce json
@@ -183,8 +216,12 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tokens, err := h.authService.RefreshTokens(r.Context(), req.RefreshToken)
+	tokens, err := h.authService.RefreshTokens(clientContext(r), req.RefreshToken)
 	if err != nil {
+		if errors.Is(err, services.ErrTokenReused) {
+			writeError(w, http.StatusUnauthorized, "Refresh token already used; sign in again")
+			return
+		}
 		if errors.Is(err, services.ErrInvalidToken) || errors.Is(err, services.ErrTokenExpired) {
 			writeError(w, http.StatusUnauthorized, "Invalid or expired refresh token")
 			return
@@ -198,7 +235,7 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 
 // Logout godoc
 // @Summary Logout user
-// @Description Revoke the refresh token to log out
+// @Description Revoke the refresh token to log out. An access token sent in the Authorization header is revoked too, ending the session at once.
 // @Tags Authentication
 // @Accept json
 // @Produce json
@@ -216,73 +253,100 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	if err := h.authService.Logout(r.Context(), req.RefreshToken); err != nil {
 		logging.Warn("failed to revoke refresh token during logout", "error", err)
 	}
+	if token := bearerToken(r); token != "" {
+		if err := h.authService.RevokeAccessToken(r.Context(), token); err != nil {
+			logging.Warn("failed to revoke access token during logout", "error", err)
+		}
+	}
 	writeJSON(w, http.StatusOK, map[string]string{"message": "Logged out successfully"})
 }
 
-// GoogleLogin godoc
-// @Summary Initiate Google OAuth
-// @Description Redirect to Google OAuth consent screen
+// OAuthLogin godoc
+// @Summary Initiate OAuth sign-in
+// @Description Redirect to the consent screen of an OAuth or OIDC provider, such as google, or one configured in OAUTH_PROVIDERS_PATH
 // @Tags Authentication
-// @Success 307 "Redirect to Google OAuth"
+// @Param provider path string true "Provider name"
+// @Success 307 "Redirect to the provider"
+// @Failure 404 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
-// @Router /auth/google [get]
-func (h *AuthHandler) GoogleLogin(w http.ResponseWriter, r *http.Request) {
-	// Generate and store OAuth state for CSRF protection
-	state, err := h.authService.GenerateOAuthState(r.Context())
+// @Router /auth/{provider} [get]
+func (h *AuthHandler) OAuthLogin(w http.ResponseWriter, r *http.Request) {
+	provider := chi.URLParam(r, "provider")
+	url, err := h.authService.OAuthAuthURL(r.Context(), provider)
 	if err != nil {
-		logging.Error("failed to generate oauth state", err)
+		if errors.Is(err, services.ErrUnknownOAuthProvider) {
+			writeError(w, http.StatusNotFound, "Unknown OAuth provider")
+			return
+		}
+		logging.Error("failed to initiate oauth flow", err, "provider", provider)
 		writeError(w, http.StatusInternalServerError, "Failed to initiate OAuth flow")
 		return
 	}
-
-	url := h.authService.GetGoogleAuthURL(state)
-	if url == "" {
-		writeError(w, http.StatusNotImplemented, "Google OAuth not configured")
-		return
-	}
 	http.Redirect(w, r, url, http.StatusTemporaryRedirect)
 }
 
-// GoogleCallback godoc
-// @Summary Google OAuth callback
-// @Description Handle Google OAuth callback and create/login user
+// OAuthCallback godoc
+// @Summary OAuth callback
+// @Description Handle the callback of an OAuth or OIDC provider and create/login user. If the provider's verified email address belongs to an account the provider isn't linked to, nothing is signed in and the 409 response carries a link token to confirm the link with. Users with MFA enabled get an MFA challenge instead of tokens.
 // @Tags Authentication
 // @Produce json
-// @Param code query string true "Authorization code from Google"
+// @Param provider path string true "Provider name"
+// @Param code query string true "Authorization code from the provider"
 // @Param state query string true "CSRF protection state parameter"
 // @Success 200 {object} AuthResponse
+// @Success 200 {object} MFAChallengeResponse
 // @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} AccountLinkRequiredResponse
+// @Failure 429 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
-// @Router /auth/google/callback [get]
-func (h *AuthHandler) GoogleCallback(w http.ResponseWriter, r *http.Request) {
+// @Router /auth/{provider}/callback [get]
+func (h *AuthHandler) OAuthCallback(w http.ResponseWriter, r *http.Request) {
+	provider := chi.URLParam(r, "provider")
+	if r.URL.Query().Get("error") != "" {
+		writeError(w, http.StatusBadRequest, "Sign-in was cancelled or denied by the provider")
+		return
+	}
+
 	code := r.URL.Query().Get("code")
 	if code == "" {
 		writeError(w, http.StatusBadRequest, "Missing authorization code")
 		return
 	}
 
-	// Verify state parameter for CSRF protection
 	state := r.URL.Query().Get("state")
-	if err := h.authService.ValidateOAuthState(r.Context(), state); err != nil {
-		if errors.Is(err, services.ErrInvalidOAuthState) {
+	user, tokens, err := h.authService.HandleOAuthCallback(clientContext(r), provider, code, state)
+	if err != nil {
+		var linkErr *services.AccountLinkRequiredError
+		var challenge *services.MFAChallengeError
+		switch {
+		case errors.As(err, &challenge):
+			writeJSON(w, http.StatusOK, mfaChallengeToResponse(challenge))
+		case errors.Is(err, services.ErrTooManyMFAChallenges):
+			writeError(w, http.StatusTooManyRequests, "Too many sign-ins are waiting for an MFA code, try again in a few minutes")
+		case errors.As(err, &linkErr):
+			writeJSON(w, http.StatusConflict, AccountLinkRequiredResponse{
+				Error:     "An account with this email address already exists; sign in to it to link " + provider,
+				LinkToken: linkErr.Token,
+				Provider:  linkErr.Provider,
+				Email:     linkErr.Email,
+			})
+		case errors.Is(err, services.ErrAccountExists):
+			writeError(w, http.StatusConflict, "An account with this email address already exists")
+		case errors.Is(err, services.ErrUnknownOAuthProvider):
+			writeError(w, http.StatusNotFound, "Unknown OAuth provider")
+		case errors.Is(err, services.ErrInvalidOAuthState):
 			writeError(w, http.StatusBadRequest, "Invalid or expired OAuth state")
-			return
+		default:
+			logging.Error("oauth callback failed", err, "provider", provider)
+			writeError(w, http.StatusInternalServerError, "Failed to authenticate with "+provider)
 		}
-		logging.Error("oauth state validation error", err)
-		writeError(w, http.StatusInternalServerError, "Failed to validate OAuth state")
-		return
-	}
-
-	user, tokens, err := h.authService.HandleGoogleCallback(r.Context(), code)
-	if err != nil {
-		logging.Error("google oauth callback failed", err)
-		writeError(w, http.StatusInternalServerError, "Failed to authenticate with Google")
 		return
 	}
 
-	// Track Google signup/login - we track as signup since HandleGoogleCallback creates user if not exists
+	// Track OAuth signup/login - we track as signup since HandleOAuthCallback creates user if not exists
 	if h.analytics != nil {
-		h.analytics.TrackUserSignedUp(user.ID, user.Email, user.Name, "google")
+		h.analytics.TrackUserSignedUp(user.ID, user.Email, user.Name, provider)
 	}
 
 	writeJSON(w, http.StatusOK, AuthResponse{
@@ -290,3 +354,240 @@ func (h *AuthHandler) GoogleCallback(w http.ResponseWriter, r *http.Request) {
 		Tokens: tokens,
 	})
 }
+
+// VerifyEmail godoc
+// @Summary Verify email address
+// @Description Verify the email address a verification link was sent to, using the token from the link
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body VerifyEmailRequest true "Verification token"
+// @Success 200 {object} UserResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/verify-email [post]
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req VerifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	user, err := h.authService.VerifyEmail(r.Context(), req.Token)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidToken) || errors.Is(err, services.ErrTokenExpired) {
+			writeError(w, http.StatusBadRequest, "Invalid or expired verification token")
+			return
+		}
+		logging.Error("failed to verify email", err)
+		writeError(w, http.StatusInternalServerError, "Failed to verify email")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, UserToResponse(user))
+}
+
+// ResendVerification godoc
+// @Summary Resend verification email
+// @Description Send a new verification link to an unverified account. The response is the same whether or not such an account exists.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body ResendVerificationRequest true "Account email address"
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/verify-email/resend [post]
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	var req ResendVerificationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	if err := h.authService.ResendVerificationEmail(r.Context(), req.Email); err != nil {
+		logging.Error("failed to resend verification email", err)
+		writeError(w, http.StatusInternalServerError, "Failed to send verification email")
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]string{
+		"message": "If an unverified account uses this address, a verification email is on its way",
+	})
+}
+
+// SendVerificationEmail godoc
+// @Summary Send verification email
+// @Description Send a new verification link to the authenticated user's email address
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Success 202 {object} map[string]string
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /me/verify-email/resend [post]
+func (h *AuthHandler) SendVerificationEmail(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if err := h.authService.SendVerificationEmail(r.Context(), userID); err != nil {
+		switch {
+		case errors.Is(err, services.ErrEmailAlreadyVerified):
+			writeError(w, http.StatusConflict, "Email address is already verified")
+		case errors.Is(err, services.ErrVerificationThrottled):
+			writeError(w, http.StatusTooManyRequests, "A verification email was sent recently, try again in a minute")
+		default:
+			logging.Error("failed to send verification email", err, "userID", userID.String())
+			writeError(w, http.StatusInternalServerError, "Failed to send verification email")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]string{"message": "Verification email sent"})
+}
+
+// ForgotPassword godoc
+// @Summary Request password reset
+// @Description Email a password reset link to an account. The response is the same whether or not the account exists.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body ForgotPasswordRequest true "Account email address"
+// @Success 202 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/forgot-password [post]
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req ForgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	if err := h.authService.RequestPasswordReset(r.Context(), req.Email); err != nil {
+		logging.Error("failed to request password reset", err)
+		writeError(w, http.StatusInternalServerError, "Failed to send password reset email")
+		return
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]string{
+		"message": "If an account uses this address, a password reset email is on its way",
+	})
+}
+
+// ResetPassword godoc
+// @Summary Reset password
+// @Description Set a new password using the token from a password reset link. The token works once, every device signed in to the account is signed out and its API keys are revoked.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body ResetPasswordRequest true "Reset token and new password"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/reset-password [post]
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req ResetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	if err := h.authService.ResetPassword(r.Context(), req.Token, req.NewPassword); err != nil {
+		if errors.Is(err, services.ErrInvalidToken) || errors.Is(err, services.ErrTokenExpired) {
+			writeError(w, http.StatusBadRequest, "Invalid or expired reset token")
+			return
+		}
+		logging.Error("failed to reset password", err)
+		writeError(w, http.StatusInternalServerError, "Failed to reset password")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "Password reset, please log in"})
+}
+
+// ChangePassword godoc
+// @Summary Change password
+// @Description Change the authenticated user's password. Every other device signed in to the account is signed out and its API keys are revoked; the returned tokens replace the current ones.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ChangePasswordRequest true "Current and new password"
+// @Success 200 {object} services.TokenPair
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /me/password [post]
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	tokens, err := h.authService.ChangePassword(clientContext(r), userID, req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrIncorrectPassword):
+			writeError(w, http.StatusForbidden, "Current password is incorrect")
+		case errors.Is(err, services.ErrNoPassword):
+			writeError(w, http.StatusBadRequest, "Account has no password; use forgot password to set one")
+		default:
+			logging.Error("failed to change password", err, "userID", userID.String())
+			writeError(w, http.StatusInternalServerError, "Failed to change password")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, tokens)
+}
+
+// JWKS godoc
+// @Summary JSON Web Key Set
+// @Description Public keys that verify access tokens, identified by the kid header of a token. Keys are listed before they start signing and for a grace period after they are replaced. Empty while tokens are signed with the shared secret.
+// @Tags Authentication
+// @Produce json
+// @Success 200 {object} services.JWKSet
+// @Router /.well-known/jwks.json [get]
+func (h *AuthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	writeJSON(w, http.StatusOK, h.authService.JWKS())
+}
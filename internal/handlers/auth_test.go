@@ -8,9 +8,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/agpt-go/chatbot-api/internal/database"
 	"github.com/agpt-go/chatbot-api/internal/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 )
 
 // mockAuthService implements AuthServicer for testing
@@ -20,10 +23,33 @@ type mockAuthService struct {
 	refreshTokensFunc    func(ctx context.Context, refreshToken string) (*services.TokenPair, error)
 	logoutFunc           func(ctx context.Context, refreshToken string) error
 	validateAccessToken  func(tokenString string) (*services.Claims, error)
-	generateOAuthState   func(ctx context.Context) (string, error)
-	validateOAuthState   func(ctx context.Context, state string) error
-	getGoogleAuthURL     func(state string) string
-	handleGoogleCallback func(ctx context.Context, code string) (*database.User, *services.TokenPair, error)
+	oauthAuthURL         func(ctx context.Context, provider string) (string, error)
+	handleOAuthCallback  func(ctx context.Context, provider, code, state string) (*database.User, *services.TokenPair, error)
+	verifyEmail          func(ctx context.Context, token string) (*database.User, error)
+	sendVerification     func(ctx context.Context, userID uuid.UUID) error
+	resendVerification   func(ctx context.Context, email string) error
+	requestPasswordReset func(ctx context.Context, email string) error
+	resetPassword        func(ctx context.Context, token, newPassword string) error
+	changePassword       func(ctx context.Context, userID uuid.UUID, currentPassword, newPassword string) (*services.TokenPair, error)
+	logoutAll            func(ctx context.Context, userID uuid.UUID) error
+	listDevices          func(ctx context.Context, userID uuid.UUID) ([]services.Device, error)
+	revokeDevice         func(ctx context.Context, userID, deviceID uuid.UUID) error
+	listIdentities       func(ctx context.Context, userID uuid.UUID) ([]services.Identity, error)
+	confirmAccountLink   func(ctx context.Context, userID uuid.UUID, token string) (*services.Identity, error)
+	unlinkIdentity       func(ctx context.Context, userID, identityID uuid.UUID) error
+	verifyMFAChallenge   func(ctx context.Context, token, code string) (*database.User, *services.TokenPair, error)
+	mfaStatus            func(ctx context.Context, userID uuid.UUID) (*services.MFAStatus, error)
+	beginTOTP            func(ctx context.Context, userID uuid.UUID) (*services.TOTPEnrollment, error)
+	confirmTOTP          func(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
+	disableMFA           func(ctx context.Context, userID uuid.UUID, password, code string) error
+	regenerateCodes      func(ctx context.Context, userID uuid.UUID, password, code string) ([]string, error)
+	setMFARequired       func(ctx context.Context, userID uuid.UUID, required bool) error
+	resetMFA             func(ctx context.Context, userID uuid.UUID) error
+	createAPIKey         func(ctx context.Context, userID uuid.UUID, name string, scopes []string, expiresAt time.Time) (*services.APIKey, string, error)
+	listAPIKeys          func(ctx context.Context, userID uuid.UUID) ([]services.APIKey, error)
+	revokeAPIKey         func(ctx context.Context, userID, keyID uuid.UUID) error
+	jwks                 services.JWKSet
+	revokeAccessToken    func(ctx context.Context, tokenString string) error
 }
 
 func (m *mockAuthService) Register(ctx context.Context, email, password, name string) (*database.User, *services.TokenPair, error) {
@@ -61,32 +87,190 @@ func (m *mockAuthService) ValidateAccessToken(tokenString string) (*services.Cla
 	return nil, services.ErrInvalidToken
 }
 
-func (m *mockAuthService) GenerateOAuthState(ctx context.Context) (string, error) {
-	if m.generateOAuthState != nil {
-		return m.generateOAuthState(ctx)
+func (m *mockAuthService) OAuthAuthURL(ctx context.Context, provider string) (string, error) {
+	if m.oauthAuthURL != nil {
+		return m.oauthAuthURL(ctx, provider)
 	}
-	return "mock-state-12345678901234567890123456789012", nil
+	return "", services.ErrUnknownOAuthProvider // Not configured
 }
 
-func (m *mockAuthService) ValidateOAuthState(ctx context.Context, state string) error {
-	if m.validateOAuthState != nil {
-		return m.validateOAuthState(ctx, state)
+func (m *mockAuthService) HandleOAuthCallback(ctx context.Context, provider, code, state string) (*database.User, *services.TokenPair, error) {
+	if m.handleOAuthCallback != nil {
+		return m.handleOAuthCallback(ctx, provider, code, state)
 	}
-	return services.ErrInvalidOAuthState
+	return nil, nil, services.ErrUnknownOAuthProvider
 }
 
-func (m *mockAuthService) GetGoogleAuthURL(state string) string {
-	if m.getGoogleAuthURL != nil {
-		return m.getGoogleAuthURL(state)
+func (m *mockAuthService) VerifyEmail(ctx context.Context, token string) (*database.User, error) {
+	if m.verifyEmail != nil {
+		return m.verifyEmail(ctx, token)
 	}
-	return "" // Not configured
+	return nil, services.ErrInvalidToken
+}
+
+func (m *mockAuthService) SendVerificationEmail(ctx context.Context, userID uuid.UUID) error {
+	if m.sendVerification != nil {
+		return m.sendVerification(ctx, userID)
+	}
+	return nil
+}
+
+func (m *mockAuthService) ResendVerificationEmail(ctx context.Context, email string) error {
+	if m.resendVerification != nil {
+		return m.resendVerification(ctx, email)
+	}
+	return nil
+}
+
+func (m *mockAuthService) RequestPasswordReset(ctx context.Context, email string) error {
+	if m.requestPasswordReset != nil {
+		return m.requestPasswordReset(ctx, email)
+	}
+	return nil
+}
+
+func (m *mockAuthService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	if m.resetPassword != nil {
+		return m.resetPassword(ctx, token, newPassword)
+	}
+	return services.ErrInvalidToken
+}
+
+func (m *mockAuthService) ChangePassword(ctx context.Context, userID uuid.UUID, currentPassword, newPassword string) (*services.TokenPair, error) {
+	if m.changePassword != nil {
+		return m.changePassword(ctx, userID, currentPassword, newPassword)
+	}
+	return nil, services.ErrIncorrectPassword
+}
+
+func (m *mockAuthService) LogoutAll(ctx context.Context, userID uuid.UUID) error {
+	if m.logoutAll != nil {
+		return m.logoutAll(ctx, userID)
+	}
+	return nil
+}
+
+func (m *mockAuthService) ListDevices(ctx context.Context, userID uuid.UUID) ([]services.Device, error) {
+	if m.listDevices != nil {
+		return m.listDevices(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (m *mockAuthService) RevokeDevice(ctx context.Context, userID, deviceID uuid.UUID) error {
+	if m.revokeDevice != nil {
+		return m.revokeDevice(ctx, userID, deviceID)
+	}
+	return services.ErrDeviceNotFound
+}
+
+func (m *mockAuthService) ListIdentities(ctx context.Context, userID uuid.UUID) ([]services.Identity, error) {
+	if m.listIdentities != nil {
+		return m.listIdentities(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (m *mockAuthService) ConfirmAccountLink(ctx context.Context, userID uuid.UUID, token string) (*services.Identity, error) {
+	if m.confirmAccountLink != nil {
+		return m.confirmAccountLink(ctx, userID, token)
+	}
+	return nil, services.ErrInvalidLinkToken
+}
+
+func (m *mockAuthService) UnlinkIdentity(ctx context.Context, userID, identityID uuid.UUID) error {
+	if m.unlinkIdentity != nil {
+		return m.unlinkIdentity(ctx, userID, identityID)
+	}
+	return services.ErrIdentityNotFound
+}
+
+func (m *mockAuthService) VerifyMFAChallenge(ctx context.Context, token, code string) (*database.User, *services.TokenPair, error) {
+	if m.verifyMFAChallenge != nil {
+		return m.verifyMFAChallenge(ctx, token, code)
+	}
+	return nil, nil, services.ErrInvalidMFAToken
+}
+
+func (m *mockAuthService) MFAStatus(ctx context.Context, userID uuid.UUID) (*services.MFAStatus, error) {
+	if m.mfaStatus != nil {
+		return m.mfaStatus(ctx, userID)
+	}
+	return &services.MFAStatus{}, nil
+}
+
+func (m *mockAuthService) BeginTOTPEnrollment(ctx context.Context, userID uuid.UUID) (*services.TOTPEnrollment, error) {
+	if m.beginTOTP != nil {
+		return m.beginTOTP(ctx, userID)
+	}
+	return nil, errors.New("not implemented")
 }
 
-func (m *mockAuthService) HandleGoogleCallback(ctx context.Context, code string) (*database.User, *services.TokenPair, error) {
-	if m.handleGoogleCallback != nil {
-		return m.handleGoogleCallback(ctx, code)
+func (m *mockAuthService) ConfirmTOTPEnrollment(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	if m.confirmTOTP != nil {
+		return m.confirmTOTP(ctx, userID, code)
 	}
-	return nil, nil, errors.New("google oauth not configured")
+	return nil, services.ErrMFANotStarted
+}
+
+func (m *mockAuthService) DisableMFA(ctx context.Context, userID uuid.UUID, password, code string) error {
+	if m.disableMFA != nil {
+		return m.disableMFA(ctx, userID, password, code)
+	}
+	return services.ErrMFANotEnabled
+}
+
+func (m *mockAuthService) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, password, code string) ([]string, error) {
+	if m.regenerateCodes != nil {
+		return m.regenerateCodes(ctx, userID, password, code)
+	}
+	return nil, services.ErrMFANotEnabled
+}
+
+func (m *mockAuthService) SetMFARequired(ctx context.Context, userID uuid.UUID, required bool) error {
+	if m.setMFARequired != nil {
+		return m.setMFARequired(ctx, userID, required)
+	}
+	return nil
+}
+
+func (m *mockAuthService) ResetMFA(ctx context.Context, userID uuid.UUID) error {
+	if m.resetMFA != nil {
+		return m.resetMFA(ctx, userID)
+	}
+	return nil
+}
+
+func (m *mockAuthService) CreateAPIKey(ctx context.Context, userID uuid.UUID, name string, scopes []string, expiresAt time.Time) (*services.APIKey, string, error) {
+	if m.createAPIKey != nil {
+		return m.createAPIKey(ctx, userID, name, scopes, expiresAt)
+	}
+	return nil, "", errors.New("not implemented")
+}
+
+func (m *mockAuthService) ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]services.APIKey, error) {
+	if m.listAPIKeys != nil {
+		return m.listAPIKeys(ctx, userID)
+	}
+	return nil, nil
+}
+
+func (m *mockAuthService) RevokeAPIKey(ctx context.Context, userID, keyID uuid.UUID) error {
+	if m.revokeAPIKey != nil {
+		return m.revokeAPIKey(ctx, userID, keyID)
+	}
+	return services.ErrAPIKeyNotFound
+}
+
+func (m *mockAuthService) JWKS() services.JWKSet {
+	return m.jwks
+}
+
+func (m *mockAuthService) RevokeAccessToken(ctx context.Context, tokenString string) error {
+	if m.revokeAccessToken != nil {
+		return m.revokeAccessToken(ctx, tokenString)
+	}
+	return nil
 }
 
 func createTestAuthHandler(t *testing.T) *AuthHandler {
@@ -249,6 +433,64 @@ func TestRefreshHandler_MissingToken(t *testing.T) {
 	}
 }
 
+func TestLogoutHandler_RevokesAccessToken(t *testing.T) {
+	var revoked string
+	handler := NewAuthHandler(&mockAuthService{
+		logoutFunc: func(ctx context.Context, refreshToken string) error { return nil },
+		revokeAccessToken: func(ctx context.Context, tokenString string) error {
+			revoked = tokenString
+			return nil
+		},
+	}, nil, nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/logout", bytes.NewBufferString(`{"refresh_token":"refresh"}`))
+	req.Header.Set("Authorization", "Bearer access-token")
+	rec := httptest.NewRecorder()
+
+	handler.Logout(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if revoked != "access-token" {
+		t.Errorf("revoked access token = %q, want %q", revoked, "access-token")
+	}
+}
+
+func TestRefreshHandler_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"refreshed", nil, http.StatusOK},
+		{"invalid", services.ErrInvalidToken, http.StatusUnauthorized},
+		{"expired", services.ErrTokenExpired, http.StatusUnauthorized},
+		{"reused", services.ErrTokenReused, http.StatusUnauthorized},
+		{"database error", errors.New("connection refused"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAuthHandler(&mockAuthService{
+				refreshTokensFunc: func(ctx context.Context, refreshToken string) (*services.TokenPair, error) {
+					if tt.err != nil {
+						return nil, tt.err
+					}
+					return &services.TokenPair{AccessToken: "new"}, nil
+				},
+			}, nil, nil)
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", bytes.NewBufferString(`{"refresh_token":"token"}`))
+			rec := httptest.NewRecorder()
+
+			handler.Refresh(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
 func TestLogoutHandler_InvalidJSON(t *testing.T) {
 	handler := createTestAuthHandler(t)
 
@@ -266,30 +508,84 @@ func TestLogoutHandler_InvalidJSON(t *testing.T) {
 // Note: TestLogoutHandler_Success is skipped because it requires a database connection
 // to actually revoke tokens. This would be tested in integration tests.
 
-func TestGoogleLoginHandler_NotConfigured(t *testing.T) {
-	handler := createTestAuthHandler(t)
-
-	req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/google", nil)
-	rec := httptest.NewRecorder()
-
-	handler.GoogleLogin(rec, req)
+// withProvider sets the provider URL parameter of an OAuth route
+func withProvider(r *http.Request, provider string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("provider", provider)
+	return r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx))
+}
 
-	// Should return 501 when Google OAuth is not configured
-	if rec.Code != http.StatusNotImplemented {
-		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotImplemented)
-	}
+func TestOAuthLoginHandler(t *testing.T) {
+	t.Run("unknown provider", func(t *testing.T) {
+		handler := createTestAuthHandler(t)
+		req := withProvider(httptest.NewRequest(http.MethodGet, "/api/v1/auth/okta", nil), "okta")
+		rec := httptest.NewRecorder()
+
+		handler.OAuthLogin(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("redirects to the provider", func(t *testing.T) {
+		handler := NewAuthHandler(&mockAuthService{
+			oauthAuthURL: func(ctx context.Context, provider string) (string, error) {
+				return "https://idp.example.com/authorize?provider=" + provider, nil
+			},
+		}, nil, nil)
+		req := withProvider(httptest.NewRequest(http.MethodGet, "/api/v1/auth/okta", nil), "okta")
+		rec := httptest.NewRecorder()
+
+		handler.OAuthLogin(rec, req)
+
+		if rec.Code != http.StatusTemporaryRedirect {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusTemporaryRedirect)
+		}
+		if got := rec.Header().Get("Location"); got != "https://idp.example.com/authorize?provider=okta" {
+			t.Errorf("Location = %q", got)
+		}
+	})
 }
 
-func TestGoogleCallbackHandler_MissingCode(t *testing.T) {
-	handler := createTestAuthHandler(t)
+func TestOAuthCallbackHandler(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		err   error
+		want  int
+	}{
+		{"missing code", "?state=s", nil, http.StatusBadRequest},
+		{"denied by the provider", "?error=access_denied&state=s", nil, http.StatusBadRequest},
+		{"invalid state", "?code=c&state=s", services.ErrInvalidOAuthState, http.StatusBadRequest},
+		{"unknown provider", "?code=c&state=s", services.ErrUnknownOAuthProvider, http.StatusNotFound},
+		{"unverified email of another account", "?code=c&state=s", services.ErrAccountExists, http.StatusConflict},
+		{"failed exchange", "?code=c&state=s", services.ErrInvalidIDToken, http.StatusInternalServerError},
+		{"success", "?code=c&state=s", nil, http.StatusOK},
+	}
 
-	req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/google/callback", nil)
-	rec := httptest.NewRecorder()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAuthHandler(&mockAuthService{
+				handleOAuthCallback: func(ctx context.Context, provider, code, state string) (*database.User, *services.TokenPair, error) {
+					if provider != "github" || code != "c" || state != "s" {
+						t.Errorf("HandleOAuthCallback(%q, %q, %q)", provider, code, state)
+					}
+					if tt.err != nil {
+						return nil, nil, tt.err
+					}
+					return &database.User{ID: uuid.New(), Email: "user@example.com"}, &services.TokenPair{AccessToken: "access"}, nil
+				},
+			}, nil, nil)
+			req := withProvider(httptest.NewRequest(http.MethodGet, "/api/v1/auth/github/callback"+tt.query, nil), "github")
+			rec := httptest.NewRecorder()
 
-	handler.GoogleCallback(rec, req)
+			handler.OAuthCallback(rec, req)
 
-	if rec.Code != http.StatusBadRequest {
-		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
 	}
 }
 
@@ -366,5 +662,173 @@ func TestUserResponseStruct(t *testing.T) {
 	}
 }
 
-// Note: OAuth state generation has moved to AuthService.GenerateOAuthState
-// which is tested in services/auth_test.go
+// Note: OAuth state generation and PKCE are handled by AuthService.OAuthAuthURL
+// and tested against a mock OIDC server in services/oauth_providers_test.go
+
+func TestVerifyEmail(t *testing.T) {
+	verified := true
+	handler := NewAuthHandler(&mockAuthService{
+		verifyEmail: func(ctx context.Context, token string) (*database.User, error) {
+			switch token {
+			case "good":
+				return &database.User{ID: uuid.New(), Email: "a@example.com", EmailVerified: &verified}, nil
+			case "old":
+				return nil, services.ErrTokenExpired
+			}
+			return nil, services.ErrInvalidToken
+		},
+	}, nil, nil)
+
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`{"token":"good"}`, http.StatusOK},
+		{`{"token":"old"}`, http.StatusBadRequest},
+		{`{"token":"forged"}`, http.StatusBadRequest},
+		{`{}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/verify-email", bytes.NewBufferString(tt.body))
+		rec := httptest.NewRecorder()
+
+		handler.VerifyEmail(rec, req)
+
+		if rec.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.body, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestSendVerificationEmail(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"sent", nil, http.StatusAccepted},
+		{"already verified", services.ErrEmailAlreadyVerified, http.StatusConflict},
+		{"throttled", services.ErrVerificationThrottled, http.StatusTooManyRequests},
+		{"mail failure", errors.New("smtp down"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAuthHandler(&mockAuthService{
+				sendVerification: func(ctx context.Context, userID uuid.UUID) error { return tt.err },
+			}, nil, nil)
+			req := withTestUser(httptest.NewRequest(http.MethodPost, "/api/v1/me/verify-email/resend", nil))
+			rec := httptest.NewRecorder()
+
+			handler.SendVerificationEmail(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestResetPassword(t *testing.T) {
+	var gotToken, gotPassword string
+	handler := NewAuthHandler(&mockAuthService{
+		resetPassword: func(ctx context.Context, token, newPassword string) error {
+			gotToken, gotPassword = token, newPassword
+			if token == "used" {
+				return services.ErrInvalidToken
+			}
+			return nil
+		},
+	}, nil, nil)
+
+	tests := []struct {
+		name, body string
+		want       int
+	}{
+		{"valid", `{"token":"abc","new_password":"correct horse"}`, http.StatusOK},
+		{"used token", `{"token":"used","new_password":"correct horse"}`, http.StatusBadRequest},
+		{"short password", `{"token":"abc","new_password":"short"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/reset-password", bytes.NewBufferString(tt.body))
+			rec := httptest.NewRecorder()
+
+			handler.ResetPassword(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+	if gotToken != "used" || gotPassword != "correct horse" {
+		t.Errorf("ResetPassword(%q, %q)", gotToken, gotPassword)
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"changed", nil, http.StatusOK},
+		{"wrong current password", services.ErrIncorrectPassword, http.StatusForbidden},
+		{"no password", services.ErrNoPassword, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAuthHandler(&mockAuthService{
+				changePassword: func(ctx context.Context, userID uuid.UUID, currentPassword, newPassword string) (*services.TokenPair, error) {
+					if tt.err != nil {
+						return nil, tt.err
+					}
+					return &services.TokenPair{AccessToken: "new"}, nil
+				},
+			}, nil, nil)
+			body := `{"current_password":"old password","new_password":"new password"}`
+			req := withTestUser(httptest.NewRequest(http.MethodPost, "/api/v1/me/password", bytes.NewBufferString(body)))
+			rec := httptest.NewRecorder()
+
+			handler.ChangePassword(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestForgotPasswordHidesAccounts(t *testing.T) {
+	handler := NewAuthHandler(&mockAuthService{}, nil, nil)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/forgot-password", bytes.NewBufferString(`{"email":"nobody@example.com"}`))
+	rec := httptest.NewRecorder()
+
+	handler.ForgotPassword(rec, req)
+
+	if rec.Code != http.StatusAccepted {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusAccepted)
+	}
+}
+
+func TestJWKS(t *testing.T) {
+	handler := NewAuthHandler(&mockAuthService{
+		jwks: services.JWKSet{Keys: []services.JWK{{KeyType: "OKP", Use: "sig", Algorithm: "EdDSA", KeyID: "2026-10", Curve: "Ed25519", X: "abc"}}},
+	}, nil, nil)
+	rec := httptest.NewRecorder()
+
+	handler.JWKS(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if rec.Header().Get("Cache-Control") == "" {
+		t.Error("JWKS response should be cacheable")
+	}
+	var set services.JWKSet
+	if err := json.NewDecoder(rec.Body).Decode(&set); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(set.Keys) != 1 || set.Keys[0].KeyID != "2026-10" {
+		t.Errorf("keys = %+v, want the key 2026-10", set.Keys)
+	}
+}
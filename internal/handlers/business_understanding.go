@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/logging"
+	"github.com/agpt-go/chatbot-api/internal/middleware"
+	"github.com/agpt-go/chatbot-api/internal/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// BusinessUnderstandingHandler lets users view and correct what the model recorded about their business
+type BusinessUnderstandingHandler struct {
+	understandingService BusinessUnderstandingServicer
+}
+
+// NewBusinessUnderstandingHandler creates a new business understanding handler
+func NewBusinessUnderstandingHandler(understandingService BusinessUnderstandingServicer) *BusinessUnderstandingHandler {
+	return &BusinessUnderstandingHandler{
+		understandingService: understandingService,
+	}
+}
+
+// BusinessUnderstandingResponse is the business understanding with per-field provenance
+type BusinessUnderstandingResponse struct {
+	Understanding *services.BusinessContext           `json:"understanding"`
+	Provenance    map[string]services.FieldProvenance `json:"provenance"`
+	CreatedAt     string                              `json:"created_at"`
+	UpdatedAt     string                              `json:"updated_at"`
+}
+
+// UnderstandingChangesResponse contains a page of the change log
+type UnderstandingChangesResponse struct {
+	Changes    []services.UnderstandingChange `json:"changes"`
+	TotalCount int64                          `json:"total_count"`
+	Limit      int                            `json:"limit"`
+	Offset     int                            `json:"offset"`
+}
+
+func understandingToResponse(view *services.BusinessUnderstandingView) BusinessUnderstandingResponse {
+	return BusinessUnderstandingResponse{
+		Understanding: view.Context,
+		Provenance:    view.Provenance,
+		CreatedAt:     view.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:     view.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// GetBusinessUnderstanding godoc
+// @Summary Get business understanding
+// @Description Get what the assistant has recorded about the user's business, with the source of each field
+// @Tags Business Understanding
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} BusinessUnderstandingResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /business-understanding [get]
+func (h *BusinessUnderstandingHandler) GetBusinessUnderstanding(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	view, err := h.understandingService.Get(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, services.ErrUnderstandingNotFound) {
+			writeError(w, http.StatusNotFound, "Business understanding not found")
+			return
+		}
+		logging.Error("failed to get business understanding", err, "userID", userID.String())
+		writeError(w, http.StatusInternalServerError, "Failed to get business understanding")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, understandingToResponse(view))
+}
+
+// GetBusinessUnderstandingSchema godoc
+// @Summary Get business understanding schema
+// @Description Get the fields that make up the business understanding, with their types, scopes and limits
+// @Tags Business Understanding
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} services.UnderstandingSchema
+// @Failure 401 {object} ErrorResponse
+// @Router /business-understanding/schema [get]
+func (h *BusinessUnderstandingHandler) GetBusinessUnderstandingSchema(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.understandingService.Schema())
+}
+
+// UpdateBusinessUnderstanding godoc
+// @Summary Update business understanding
+// @Description Correct fields of the business understanding, keyed by schema field name. Omitted fields are unchanged; empty values clear a field.
+// @Tags Business Understanding
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body object true "Fields to update"
+// @Success 200 {object} BusinessUnderstandingResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /business-understanding [patch]
+func (h *BusinessUnderstandingHandler) UpdateBusinessUnderstanding(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	patch := services.NewBusinessContext()
+	if err := json.NewDecoder(r.Body).Decode(patch); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	view, err := h.understandingService.Update(r.Context(), userID, patch)
+	if err != nil {
+		var validationErr *services.UnderstandingValidationError
+		if errors.As(err, &validationErr) {
+			writeValidationError(w, err)
+			return
+		}
+		logging.Error("failed to update business understanding", err, "userID", userID.String())
+		writeError(w, http.StatusInternalServerError, "Failed to update business understanding")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, understandingToResponse(view))
+}
+
+// DeleteBusinessUnderstanding godoc
+// @Summary Delete business understanding
+// @Description Delete everything the assistant has recorded about the user's business
+// @Tags Business Understanding
+// @Security BearerAuth
+// @Success 204 "Business understanding deleted"
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /business-understanding [delete]
+func (h *BusinessUnderstandingHandler) DeleteBusinessUnderstanding(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if err := h.understandingService.Delete(r.Context(), userID); err != nil {
+		logging.Error("failed to delete business understanding", err, "userID", userID.String())
+		writeError(w, http.StatusInternalServerError, "Failed to delete business understanding")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListBusinessUnderstandingChanges godoc
+// @Summary List business understanding changes
+// @Description Get the history of changes to the business understanding, newest first, with before and after snapshots
+// @Tags Business Understanding
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "Number of changes (default: 20, max: 100)"
+// @Param offset query int false "Offset for pagination (default: 0)"
+// @Success 200 {object} UnderstandingChangesResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /business-understanding/changes [get]
+func (h *BusinessUnderstandingHandler) ListBusinessUnderstandingChanges(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	limit := int32(20)
+	offset := int32(0)
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			limit = int32(parsed)
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = int32(parsed)
+		}
+	}
+
+	changes, totalCount, err := h.understandingService.ListChanges(r.Context(), userID, limit, offset)
+	if err != nil {
+		logging.Error("failed to list business understanding changes", err, "userID", userID.String())
+		writeError(w, http.StatusInternalServerError, "Failed to list changes")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, UnderstandingChangesResponse{
+		Changes:    changes,
+		TotalCount: totalCount,
+		Limit:      int(limit),
+		Offset:     int(offset),
+	})
+}
+
+// RevertBusinessUnderstandingChange godoc
+// @Summary Revert a business understanding change
+// @Description Restore the business understanding to how it was just before the given change. The revert is itself recorded in the history.
+// @Tags Business Understanding
+// @Produce json
+// @Security BearerAuth
+// @Param changeID path string true "Change ID"
+// @Success 200 {object} BusinessUnderstandingResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /business-understanding/changes/{changeID}/revert [post]
+func (h *BusinessUnderstandingHandler) RevertBusinessUnderstandingChange(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	changeID, err := uuid.Parse(chi.URLParam(r, "changeID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid change ID")
+		return
+	}
+
+	view, err := h.understandingService.Revert(r.Context(), userID, changeID)
+	if err != nil {
+		if errors.Is(err, services.ErrUnderstandingChangeNotFound) {
+			writeError(w, http.StatusNotFound, "Change not found")
+			return
+		}
+		logging.Error("failed to revert business understanding change", err, "userID", userID.String(), "changeID", changeID.String())
+		writeError(w, http.StatusInternalServerError, "Failed to revert change")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, understandingToResponse(view))
+}
@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/agpt-go/chatbot-api/internal/middleware"
+	"github.com/agpt-go/chatbot-api/internal/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// mockUnderstandingService implements BusinessUnderstandingServicer for testing
+type mockUnderstandingService struct {
+	getFunc    func(ctx context.Context, userID uuid.UUID) (*services.BusinessUnderstandingView, error)
+	updateFunc func(ctx context.Context, userID uuid.UUID, patch *services.BusinessContext) (*services.BusinessUnderstandingView, error)
+	deleteFunc func(ctx context.Context, userID uuid.UUID) error
+	listFunc   func(ctx context.Context, userID uuid.UUID, limit, offset int32) ([]services.UnderstandingChange, int64, error)
+	revertFunc func(ctx context.Context, userID, changeID uuid.UUID) (*services.BusinessUnderstandingView, error)
+}
+
+func (m *mockUnderstandingService) Get(ctx context.Context, userID uuid.UUID) (*services.BusinessUnderstandingView, error) {
+	if m.getFunc != nil {
+		return m.getFunc(ctx, userID)
+	}
+	return nil, services.ErrUnderstandingNotFound
+}
+
+func (m *mockUnderstandingService) Update(ctx context.Context, userID uuid.UUID, patch *services.BusinessContext) (*services.BusinessUnderstandingView, error) {
+	if m.updateFunc != nil {
+		return m.updateFunc(ctx, userID, patch)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockUnderstandingService) Delete(ctx context.Context, userID uuid.UUID) error {
+	if m.deleteFunc != nil {
+		return m.deleteFunc(ctx, userID)
+	}
+	return nil
+}
+
+func (m *mockUnderstandingService) ListChanges(ctx context.Context, userID uuid.UUID, limit, offset int32) ([]services.UnderstandingChange, int64, error) {
+	if m.listFunc != nil {
+		return m.listFunc(ctx, userID, limit, offset)
+	}
+	return []services.UnderstandingChange{}, 0, nil
+}
+
+func (m *mockUnderstandingService) Revert(ctx context.Context, userID, changeID uuid.UUID) (*services.BusinessUnderstandingView, error) {
+	if m.revertFunc != nil {
+		return m.revertFunc(ctx, userID, changeID)
+	}
+	return nil, services.ErrUnderstandingChangeNotFound
+}
+
+func (m *mockUnderstandingService) Schema() *services.UnderstandingSchema {
+	return services.DefaultUnderstandingSchema()
+}
+
+func withTestUser(r *http.Request) *http.Request {
+	ctx := context.WithValue(r.Context(), middleware.UserIDKey, uuid.New())
+	return r.WithContext(ctx)
+}
+
+func TestGetBusinessUnderstanding(t *testing.T) {
+	t.Run("unauthorized", func(t *testing.T) {
+		handler := NewBusinessUnderstandingHandler(&mockUnderstandingService{})
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/business-understanding", nil)
+		rec := httptest.NewRecorder()
+
+		handler.GetBusinessUnderstanding(rec, req)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		handler := NewBusinessUnderstandingHandler(&mockUnderstandingService{})
+		req := withTestUser(httptest.NewRequest(http.MethodGet, "/api/v1/business-understanding", nil))
+		rec := httptest.NewRecorder()
+
+		handler.GetBusinessUnderstanding(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+		}
+	})
+}
+
+func TestUpdateBusinessUnderstanding(t *testing.T) {
+	t.Run("schema validation error", func(t *testing.T) {
+		handler := NewBusinessUnderstandingHandler(&mockUnderstandingService{
+			updateFunc: func(ctx context.Context, userID uuid.UUID, patch *services.BusinessContext) (*services.BusinessUnderstandingView, error) {
+				return nil, services.DefaultUnderstandingSchema().Validate(patch, services.FieldScopePersonal)
+			},
+		})
+		req := withTestUser(httptest.NewRequest(http.MethodPatch, "/api/v1/business-understanding",
+			bytes.NewBufferString(`{"business_size":"huge"}`)))
+		rec := httptest.NewRecorder()
+
+		handler.UpdateBusinessUnderstanding(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+		var resp ErrorResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		if resp.Details["business_size"] == "" {
+			t.Errorf("details = %v, want business_size error", resp.Details)
+		}
+	})
+
+	t.Run("wrong value type", func(t *testing.T) {
+		handler := NewBusinessUnderstandingHandler(&mockUnderstandingService{
+			updateFunc: func(ctx context.Context, userID uuid.UUID, patch *services.BusinessContext) (*services.BusinessUnderstandingView, error) {
+				t.Error("service should not be called")
+				return nil, nil
+			},
+		})
+		req := withTestUser(httptest.NewRequest(http.MethodPatch, "/api/v1/business-understanding",
+			bytes.NewBufferString(`{"business_size":10}`)))
+		rec := httptest.NewRecorder()
+
+		handler.UpdateBusinessUnderstanding(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("passes patch to service", func(t *testing.T) {
+		var got *services.BusinessContext
+		handler := NewBusinessUnderstandingHandler(&mockUnderstandingService{
+			updateFunc: func(ctx context.Context, userID uuid.UUID, patch *services.BusinessContext) (*services.BusinessUnderstandingView, error) {
+				got = patch
+				return &services.BusinessUnderstandingView{Context: &services.BusinessContext{}}, nil
+			},
+		})
+		req := withTestUser(httptest.NewRequest(http.MethodPatch, "/api/v1/business-understanding",
+			bytes.NewBufferString(`{"industry":"retail","pain_points":[]}`)))
+		rec := httptest.NewRecorder()
+
+		handler.UpdateBusinessUnderstanding(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+		}
+		if got.Get("industry") != "retail" {
+			t.Errorf("industry = %q, want retail", got.Get("industry"))
+		}
+		if items, ok := got.Lists["pain_points"]; !ok || len(items) != 0 {
+			t.Errorf("pain_points = %v, want empty list", items)
+		}
+		if _, ok := got.Strings["user_name"]; ok {
+			t.Error("user_name should be omitted")
+		}
+	})
+}
+
+func TestGetBusinessUnderstandingSchema(t *testing.T) {
+	handler := NewBusinessUnderstandingHandler(&mockUnderstandingService{})
+	req := withTestUser(httptest.NewRequest(http.MethodGet, "/api/v1/business-understanding/schema", nil))
+	rec := httptest.NewRecorder()
+
+	handler.GetBusinessUnderstandingSchema(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var resp services.UnderstandingSchema
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Fields) == 0 {
+		t.Error("schema has no fields")
+	}
+}
+
+func TestListBusinessUnderstandingChanges(t *testing.T) {
+	t.Run("clamps invalid pagination to defaults", func(t *testing.T) {
+		var gotLimit, gotOffset int32
+		handler := NewBusinessUnderstandingHandler(&mockUnderstandingService{
+			listFunc: func(ctx context.Context, userID uuid.UUID, limit, offset int32) ([]services.UnderstandingChange, int64, error) {
+				gotLimit, gotOffset = limit, offset
+				return []services.UnderstandingChange{}, 0, nil
+			},
+		})
+		req := withTestUser(httptest.NewRequest(http.MethodGet, "/api/v1/business-understanding/changes?limit=500&offset=-1", nil))
+		rec := httptest.NewRecorder()
+
+		handler.ListBusinessUnderstandingChanges(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+		}
+		if gotLimit != 20 || gotOffset != 0 {
+			t.Errorf("limit, offset = %d, %d, want 20, 0", gotLimit, gotOffset)
+		}
+	})
+}
+
+func TestRevertBusinessUnderstandingChange(t *testing.T) {
+	revertRequest := func(changeID string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/business-understanding/changes/"+changeID+"/revert", nil)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("changeID", changeID)
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		return withTestUser(req)
+	}
+
+	t.Run("invalid change ID", func(t *testing.T) {
+		handler := NewBusinessUnderstandingHandler(&mockUnderstandingService{})
+		rec := httptest.NewRecorder()
+
+		handler.RevertBusinessUnderstandingChange(rec, revertRequest("not-a-uuid"))
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("change not found", func(t *testing.T) {
+		handler := NewBusinessUnderstandingHandler(&mockUnderstandingService{})
+		rec := httptest.NewRecorder()
+
+		handler.RevertBusinessUnderstandingChange(rec, revertRequest(uuid.New().String()))
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("reverts change", func(t *testing.T) {
+		changeID := uuid.New()
+		var got uuid.UUID
+		handler := NewBusinessUnderstandingHandler(&mockUnderstandingService{
+			revertFunc: func(ctx context.Context, userID, id uuid.UUID) (*services.BusinessUnderstandingView, error) {
+				got = id
+				return &services.BusinessUnderstandingView{Context: &services.BusinessContext{}}, nil
+			},
+		})
+		rec := httptest.NewRecorder()
+
+		handler.RevertBusinessUnderstandingChange(rec, revertRequest(changeID.String()))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+		}
+		if got != changeID {
+			t.Errorf("changeID = %s, want %s", got, changeID)
+		}
+	})
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -33,9 +34,12 @@ func NewChatHandler(chatService ChatServicer, analytics AnalyticsServicer) *Chat
 }
 
 type CreateSessionRequest struct {
-	Title        string `json:"title"`
-	Model        string `json:"model"`
-	SystemPrompt string `json:"system_prompt"`
+	Title        string    `json:"title"`
+	Model        string    `json:"model"`
+	SystemPrompt string    `json:"system_prompt"`
+	Profile      string    `json:"profile"`
+	Tools        *[]string `json:"tools"`       // Omit to offer every tool; [] offers none
+	ToolChoice   string    `json:"tool_choice"` // auto, required, none or a tool name
 }
 
 type UpdateSessionRequest struct {
@@ -48,19 +52,30 @@ type SendMessageRequest struct {
 }
 
 type SessionResponse struct {
-	ID           string  `json:"id"`
-	Title        string  `json:"title"`
-	Model        string  `json:"model"`
-	SystemPrompt *string `json:"system_prompt,omitempty"`
-	CreatedAt    string  `json:"created_at"`
-	UpdatedAt    string  `json:"updated_at"`
+	ID           string   `json:"id"`
+	Title        string   `json:"title"`
+	Model        string   `json:"model"`
+	SystemPrompt *string  `json:"system_prompt,omitempty"`
+	Profile      *string  `json:"profile,omitempty"`
+	Tools        []string `json:"tools"` // null offers every tool
+	ToolChoice   string   `json:"tool_choice"`
+	CreatedAt    string   `json:"created_at"`
+	UpdatedAt    string   `json:"updated_at"`
+}
+
+// ToolResponse describes a tool that sessions can offer
+type ToolResponse struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
 }
 
 type MessageResponse struct {
-	ID        string `json:"id"`
-	Role      string `json:"role"`
-	Content   string `json:"content"`
-	CreatedAt string `json:"created_at"`
+	ID         string `json:"id"`
+	Role       string `json:"role"`
+	Content    string `json:"content"`
+	ToolCallID string `json:"tool_call_id,omitempty"` // For tool messages: the call this is the result of
+	ToolName   string `json:"tool_name,omitempty"`
+	CreatedAt  string `json:"created_at"`
 }
 
 type ChatResponse struct {
@@ -84,34 +99,44 @@ func formatTimestamp(ts pgtype.Timestamptz) string {
 }
 
 func sessionToResponse(session *database.ChatSession) SessionResponse {
-	return SessionResponse{
+	resp := SessionResponse{
 		ID:           session.ID.String(),
 		Title:        derefString(session.Title),
 		Model:        derefString(session.Model),
 		SystemPrompt: session.SystemPrompt,
+		Profile:      session.Profile,
+		Tools:        session.Tools,
+		ToolChoice:   services.ToolChoiceAuto,
 		CreatedAt:    formatTimestamp(session.CreatedAt),
 		UpdatedAt:    formatTimestamp(session.UpdatedAt),
 	}
+	if session.ToolChoice != nil && *session.ToolChoice != "" {
+		resp.ToolChoice = *session.ToolChoice
+	}
+	return resp
 }
 
 func messageToResponse(msg *database.ChatMessage) MessageResponse {
 	return MessageResponse{
-		ID:        msg.ID.String(),
-		Role:      msg.Role,
-		Content:   msg.Content,
-		CreatedAt: formatTimestamp(msg.CreatedAt),
+		ID:         msg.ID.String(),
+		Role:       msg.Role,
+		Content:    msg.Content,
+		ToolCallID: derefString(msg.ToolCallID),
+		ToolName:   derefString(msg.ToolName),
+		CreatedAt:  formatTimestamp(msg.CreatedAt),
 	}
 }
 
 // CreateSession godoc
 // @Summary Create a new chat session
-// @Description Create a new chat session for the authenticated user
+// @Description Create a new chat session for the authenticated user. A profile supplies the system prompt, model, tools and tool choice; explicit fields override it. tools limits the tools offered in the session ([] offers none), and tool_choice is auto, required, none or the name of a tool the model must call.
 // @Tags Sessions
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param request body CreateSessionRequest false "Session options"
 // @Success 201 {object} SessionResponse
+// @Failure 400 {object} ErrorResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 500 {object} ErrorResponse
 // @Router /sessions [post]
@@ -132,9 +157,19 @@ func (h *ChatHandler) CreateSession(w http.ResponseWriter, r *http.Request) {
 		Title:        req.Title,
 		Model:        req.Model,
 		SystemPrompt: req.SystemPrompt,
+		Profile:      req.Profile,
+		Tools:        req.Tools,
+		ToolChoice:   req.ToolChoice,
 	})
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "Failed to create session")
+		switch {
+		case errors.Is(err, services.ErrAgentProfileNotFound), errors.Is(err, services.ErrUnknownSessionTool),
+			errors.Is(err, services.ErrInvalidToolChoice), errors.Is(err, services.ErrModelNotAllowed):
+			writeError(w, http.StatusBadRequest, err.Error())
+		default:
+			logging.Error("failed to create session", err)
+			writeError(w, http.StatusInternalServerError, "Failed to create session")
+		}
 		return
 	}
 
@@ -150,6 +185,43 @@ func (h *ChatHandler) CreateSession(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusCreated, sessionToResponse(session))
 }
 
+// ListProfiles godoc
+// @Summary List agent profiles
+// @Description List the agent profiles a session can be created from. tools is null for profiles offering every tool.
+// @Tags Sessions
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} services.AgentProfile
+// @Failure 401 {object} ErrorResponse
+// @Router /profiles [get]
+func (h *ChatHandler) ListProfiles(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.chatService.ListProfiles())
+}
+
+// ListTools godoc
+// @Summary List tools
+// @Description List the tools the authenticated user can select for a session. Tools the user's policies deny are left out.
+// @Tags Sessions
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} ToolResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /tools [get]
+func (h *ChatHandler) ListTools(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	tools := h.chatService.AvailableTools(r.Context(), userID)
+	resp := make([]ToolResponse, len(tools))
+	for i, def := range tools {
+		resp[i] = ToolResponse{Name: def.Name, Description: def.Description}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
 // ListSessions godoc
 // @Summary List chat sessions
 // @Description List all chat sessions for the authenticated user
@@ -514,8 +586,10 @@ func (h *ChatHandler) SendMessageStream(w http.ResponseWriter, r *http.Request)
 	// Track tool call streaming state
 	streamedToolCalls := make(map[int]bool) // Track which tool calls have had their start written
 
-	// Stream response
+	// Stream response. Steps that call tools are saved as they finish; the
+	// content after the last one is saved when the stream ends.
 	var fullContent strings.Builder
+	savedToolStep := false
 	for chunk := range chunks {
 		// Handle text content
 		if chunk.Content != "" {
@@ -543,45 +617,54 @@ func (h *ChatHandler) SendMessageStream(w http.ResponseWriter, r *http.Request)
 			}
 		}
 
-		// Handle completed tool calls - execute them and write results
-		for _, tc := range chunk.ToolCalls {
-			// Parse arguments to interface{} for proper JSON encoding
-			var args interface{}
-			if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err != nil {
-				args = tc.Function.Arguments // Fallback to string
-			}
+		// Handle completed tool calls - announce them all, then execute them and
+		// write each result as soon as it completes
+		if len(chunk.ToolCalls) > 0 {
+			for _, tc := range chunk.ToolCalls {
+				// Parse arguments to interface{} for proper JSON encoding
+				var args interface{}
+				if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err != nil {
+					args = tc.Function.Arguments // Fallback to string
+				}
 
-			if err := sw.WriteToolCall(tc.ID, tc.Function.Name, args); err != nil {
-				return
+				if err := sw.WriteToolCall(tc.ID, tc.Function.Name, args); err != nil {
+					return
+				}
 			}
 
-			// Execute the tool and write result
-			toolExecutor := h.chatService.GetToolExecutor()
-			result, err := toolExecutor.ExecuteToolCall(r.Context(), userID, services.ToolCall{
-				ID:   tc.ID,
-				Type: tc.Type,
-				Function: struct {
-					Name      string `json:"name"`
-					Arguments string `json:"arguments"`
-				}{
-					Name:      tc.Function.Name,
-					Arguments: tc.Function.Arguments,
-				},
+			// Tag the calls with their chat context for provenance
+			toolCtx := services.WithToolInvocation(r.Context(), services.ToolInvocation{
+				SessionID: sessionID,
+				MessageID: userMsg.ID,
 			})
-			if err != nil {
-				logging.Error("failed to execute tool", err, "tool", tc.Function.Name)
-				// Write error result
-				if err := sw.WriteToolResult(tc.ID, map[string]interface{}{
-					"success": false,
-					"error":   "Failed to execute tool: " + err.Error(),
-				}); err != nil {
+
+			var writeErr error
+			results := h.chatService.GetToolExecutor().ExecuteToolCalls(toolCtx, userID, chunk.ToolCalls, func(res services.ToolCallResult) {
+				if writeErr != nil {
+					return
+				}
+
+				// A call that needs confirmation has no result yet; the client approves
+				// or rejects it through the tool-calls endpoints, which return the result
+				if res.Result.Pending {
+					writeErr = sw.WriteData([]interface{}{map[string]interface{}{
+						"type":       "tool-call-pending",
+						"toolCallId": res.Call.ID,
+						"toolName":   res.Call.Function.Name,
+					}})
 					return
 				}
-				continue
-			}
 
-			// Write successful tool result
-			if err := sw.WriteToolResult(tc.ID, result); err != nil {
+				writeErr = sw.WriteToolResult(res.Call.ID, res.Result)
+			})
+
+			// Save the step even if the client went away, since the tools ran
+			if err := h.chatService.SaveToolCallStep(r.Context(), sessionID, fullContent.String(), results); err != nil {
+				logging.Error("failed to save tool call step", err, "sessionID", sessionID.String())
+			}
+			fullContent.Reset()
+			savedToolStep = true
+			if writeErr != nil {
 				return
 			}
 		}
@@ -633,8 +716,10 @@ func (h *ChatHandler) SendMessageStream(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Save the complete response to database
-	if _, err := h.chatService.SaveStreamedResponse(r.Context(), sessionID, fullContent.String()); err != nil {
-		logging.Error("failed to save streamed response", err, "sessionID", sessionID.String())
+	if !savedToolStep || fullContent.Len() > 0 {
+		if _, err := h.chatService.SaveStreamedResponse(r.Context(), sessionID, fullContent.String()); err != nil {
+			logging.Error("failed to save streamed response", err, "sessionID", sessionID.String())
+		}
 	}
 
 	// Include user message ID in annotations
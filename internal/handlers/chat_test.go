@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/agpt-go/chatbot-api/internal/database"
+	"github.com/agpt-go/chatbot-api/internal/services"
+	"github.com/google/uuid"
+)
+
+// mockChatService implements the ChatServicer methods the session tests use;
+// the others panic through the nil embedded interface
+type mockChatService struct {
+	ChatServicer
+	createFunc func(ctx context.Context, userID uuid.UUID, input services.CreateSessionInput) (*database.ChatSession, error)
+	tools      []services.ToolDefinition
+}
+
+func (m *mockChatService) CreateSession(ctx context.Context, userID uuid.UUID, input services.CreateSessionInput) (*database.ChatSession, error) {
+	return m.createFunc(ctx, userID, input)
+}
+
+func (m *mockChatService) AvailableTools(ctx context.Context, userID uuid.UUID) []services.ToolDefinition {
+	return m.tools
+}
+
+func (m *mockChatService) ListProfiles() []services.AgentProfile {
+	return services.DefaultAgentProfiles().List()
+}
+
+func TestCreateSessionToolSelection(t *testing.T) {
+	t.Run("passes the selection", func(t *testing.T) {
+		var got services.CreateSessionInput
+		handler := NewChatHandler(&mockChatService{
+			createFunc: func(ctx context.Context, userID uuid.UUID, input services.CreateSessionInput) (*database.ChatSession, error) {
+				got = input
+				choice := input.ToolChoice
+				return &database.ChatSession{ID: uuid.New(), Profile: &input.Profile, Tools: *input.Tools, ToolChoice: &choice}, nil
+			},
+		}, nil)
+		body := `{"profile":"analyst","tools":[],"tool_choice":"none"}`
+		req := withTestUser(httptest.NewRequest(http.MethodPost, "/api/v1/sessions", strings.NewReader(body)))
+		rec := httptest.NewRecorder()
+
+		handler.CreateSession(rec, req)
+
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+		}
+		if got.Profile != "analyst" || got.Tools == nil || len(*got.Tools) != 0 || got.ToolChoice != "none" {
+			t.Errorf("input = %+v", got)
+		}
+		var resp SessionResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Tools == nil || resp.ToolChoice != "none" || resp.Profile == nil || *resp.Profile != "analyst" {
+			t.Errorf("response = %+v", resp)
+		}
+	})
+
+	t.Run("every tool by default", func(t *testing.T) {
+		var got services.CreateSessionInput
+		handler := NewChatHandler(&mockChatService{
+			createFunc: func(ctx context.Context, userID uuid.UUID, input services.CreateSessionInput) (*database.ChatSession, error) {
+				got = input
+				return &database.ChatSession{ID: uuid.New()}, nil
+			},
+		}, nil)
+		req := withTestUser(httptest.NewRequest(http.MethodPost, "/api/v1/sessions", strings.NewReader(`{}`)))
+		rec := httptest.NewRecorder()
+
+		handler.CreateSession(rec, req)
+
+		if got.Tools != nil {
+			t.Errorf("tools = %v, want nil", *got.Tools)
+		}
+		if !strings.Contains(rec.Body.String(), `"tools":null`) || !strings.Contains(rec.Body.String(), `"tool_choice":"auto"`) {
+			t.Errorf("body = %s", rec.Body.String())
+		}
+	})
+
+	for name, err := range map[string]error{
+		"unknown profile": services.ErrAgentProfileNotFound,
+		"unknown tool":    services.ErrUnknownSessionTool,
+		"bad tool choice": services.ErrInvalidToolChoice,
+		"model":           services.ErrModelNotAllowed,
+	} {
+		t.Run(name, func(t *testing.T) {
+			handler := NewChatHandler(&mockChatService{
+				createFunc: func(ctx context.Context, userID uuid.UUID, input services.CreateSessionInput) (*database.ChatSession, error) {
+					return nil, fmt.Errorf("%w: x", err)
+				},
+			}, nil)
+			req := withTestUser(httptest.NewRequest(http.MethodPost, "/api/v1/sessions", strings.NewReader(`{}`)))
+			rec := httptest.NewRecorder()
+
+			handler.CreateSession(rec, req)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func TestListTools(t *testing.T) {
+	handler := NewChatHandler(&mockChatService{
+		tools: []services.ToolDefinition{{Name: "search", Description: "Search the web"}},
+	}, nil)
+	req := withTestUser(httptest.NewRequest(http.MethodGet, "/api/v1/tools", nil))
+	rec := httptest.NewRecorder()
+
+	handler.ListTools(rec, req)
+
+	var resp []ToolResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp) != 1 || resp[0].Name != "search" || resp[0].Description != "Search the web" {
+		t.Errorf("tools = %+v", resp)
+	}
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/logging"
+	"github.com/agpt-go/chatbot-api/internal/middleware"
+	"github.com/agpt-go/chatbot-api/internal/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type DeviceResponse struct {
+	ID         string `json:"id"`
+	UserAgent  string `json:"user_agent"`
+	Current    bool   `json:"current"` // The device making the request
+	SignedInAt string `json:"signed_in_at"`
+	LastUsedAt string `json:"last_used_at"`
+	ExpiresAt  string `json:"expires_at"`
+}
+
+// ListDevices godoc
+// @Summary List signed-in devices
+// @Description List the devices the authenticated user is signed in on, most recently used first. A device is one sign-in; it stays listed until it signs out or its refresh token expires.
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} DeviceResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /me/devices [get]
+func (h *AuthHandler) ListDevices(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	devices, err := h.authService.ListDevices(r.Context(), userID)
+	if err != nil {
+		logging.Error("failed to list devices", err, "userID", userID.String())
+		writeError(w, http.StatusInternalServerError, "Failed to list devices")
+		return
+	}
+
+	current := middleware.GetSessionID(r.Context())
+	response := make([]DeviceResponse, 0, len(devices))
+	for _, device := range devices {
+		response = append(response, DeviceResponse{
+			ID:         device.ID.String(),
+			UserAgent:  device.UserAgent,
+			Current:    current != uuid.Nil && device.ID == current,
+			SignedInAt: device.SignedInAt.Format(time.RFC3339),
+			LastUsedAt: device.LastUsedAt.Format(time.RFC3339),
+			ExpiresAt:  device.ExpiresAt.Format(time.RFC3339),
+		})
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// RevokeDevice godoc
+// @Summary Sign out a device
+// @Description Sign out one of the authenticated user's devices, revoking its refresh tokens and the access tokens issued to it at once.
+// @Tags User
+// @Security BearerAuth
+// @Param deviceID path string true "Device UUID"
+// @Success 204 "Device signed out"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /me/devices/{deviceID} [delete]
+func (h *AuthHandler) RevokeDevice(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	deviceID, err := uuid.Parse(chi.URLParam(r, "deviceID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid device ID")
+		return
+	}
+
+	if err := h.authService.RevokeDevice(r.Context(), userID, deviceID); err != nil {
+		if errors.Is(err, services.ErrDeviceNotFound) {
+			writeError(w, http.StatusNotFound, "Device not found")
+			return
+		}
+		logging.Error("failed to revoke device", err, "userID", userID.String())
+		writeError(w, http.StatusInternalServerError, "Failed to sign out device")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LogoutAll godoc
+// @Summary Logout everywhere
+// @Description Revoke every refresh and access token and every API key of the authenticated user, signing out all devices including this one at once
+// @Tags Authentication
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/logout-all [post]
+func (h *AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	if err := h.authService.LogoutAll(r.Context(), userID); err != nil {
+		logging.Error("failed to log out all devices", err, "userID", userID.String())
+		writeError(w, http.StatusInternalServerError, "Failed to log out")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "Logged out of all devices"})
+}
+
+// AdminLogoutUser godoc
+// @Summary Sign a user out everywhere
+// @Description Revoke every refresh and access token and every API key of a user, for example to lock out a compromised account. Tokens issued afterwards are not affected. Requires an admin.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param userID path string true "User UUID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/users/{userID}/logout [post]
+func (h *AuthHandler) AdminLogoutUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	if err := h.authService.LogoutAll(r.Context(), userID); err != nil {
+		logging.Error("failed to log out user", err, "userID", userID.String())
+		writeError(w, http.StatusInternalServerError, "Failed to log out user")
+		return
+	}
+
+	logging.Info("admin logged out user", "adminID", middleware.GetUserID(r.Context()).String(), "userID", userID.String())
+	writeJSON(w, http.StatusOK, map[string]string{"message": "User logged out of all devices"})
+}
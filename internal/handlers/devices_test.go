@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/middleware"
+	"github.com/agpt-go/chatbot-api/internal/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func TestListDevices(t *testing.T) {
+	current, other := uuid.New(), uuid.New()
+	now := time.Now()
+	handler := NewAuthHandler(&mockAuthService{
+		listDevices: func(ctx context.Context, userID uuid.UUID) ([]services.Device, error) {
+			return []services.Device{
+				{ID: current, UserAgent: "Firefox", SignedInAt: now, LastUsedAt: now, ExpiresAt: now},
+				{ID: other, UserAgent: "curl", SignedInAt: now, LastUsedAt: now, ExpiresAt: now},
+			}, nil
+		},
+	}, nil, nil)
+
+	req := withTestUser(httptest.NewRequest(http.MethodGet, "/api/v1/me/devices", nil))
+	req = req.WithContext(context.WithValue(req.Context(), middleware.SessionIDKey, current))
+	rec := httptest.NewRecorder()
+
+	handler.ListDevices(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var devices []DeviceResponse
+	if err := json.NewDecoder(rec.Body).Decode(&devices); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(devices) != 2 {
+		t.Fatalf("got %d devices, want 2", len(devices))
+	}
+	if !devices[0].Current || devices[1].Current {
+		t.Errorf("current = %v, %v; want only the first device current", devices[0].Current, devices[1].Current)
+	}
+	if devices[1].UserAgent != "curl" {
+		t.Errorf("user agent = %q, want %q", devices[1].UserAgent, "curl")
+	}
+}
+
+func TestRevokeDevice(t *testing.T) {
+	tests := []struct {
+		name     string
+		deviceID string
+		err      error
+		want     int
+	}{
+		{"revoked", uuid.New().String(), nil, http.StatusNoContent},
+		{"invalid id", "not-a-uuid", nil, http.StatusBadRequest},
+		{"not found", uuid.New().String(), services.ErrDeviceNotFound, http.StatusNotFound},
+		{"database error", uuid.New().String(), errors.New("connection refused"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAuthHandler(&mockAuthService{
+				revokeDevice: func(ctx context.Context, userID, deviceID uuid.UUID) error {
+					if deviceID.String() != tt.deviceID {
+						t.Errorf("deviceID = %s, want %s", deviceID, tt.deviceID)
+					}
+					return tt.err
+				},
+			}, nil, nil)
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/v1/me/devices/"+tt.deviceID, nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("deviceID", tt.deviceID)
+			req = withTestUser(req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx)))
+			rec := httptest.NewRecorder()
+
+			handler.RevokeDevice(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestLogoutAll(t *testing.T) {
+	t.Run("unauthorized", func(t *testing.T) {
+		handler := NewAuthHandler(&mockAuthService{}, nil, nil)
+		rec := httptest.NewRecorder()
+
+		handler.LogoutAll(rec, httptest.NewRequest(http.MethodPost, "/api/v1/auth/logout-all", nil))
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("logs out", func(t *testing.T) {
+		called := false
+		handler := NewAuthHandler(&mockAuthService{
+			logoutAll: func(ctx context.Context, userID uuid.UUID) error {
+				called = true
+				return nil
+			},
+		}, nil, nil)
+		rec := httptest.NewRecorder()
+
+		handler.LogoutAll(rec, withTestUser(httptest.NewRequest(http.MethodPost, "/api/v1/auth/logout-all", nil)))
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+		}
+		if !called {
+			t.Error("LogoutAll was not called")
+		}
+	})
+}
+
+func TestAdminLogoutUser(t *testing.T) {
+	tests := []struct {
+		name   string
+		userID string
+		err    error
+		want   int
+	}{
+		{"logged out", uuid.New().String(), nil, http.StatusOK},
+		{"invalid id", "not-a-uuid", nil, http.StatusBadRequest},
+		{"database error", uuid.New().String(), errors.New("connection refused"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAuthHandler(&mockAuthService{
+				logoutAll: func(ctx context.Context, userID uuid.UUID) error {
+					if userID.String() != tt.userID {
+						t.Errorf("userID = %s, want %s", userID, tt.userID)
+					}
+					return tt.err
+				},
+			}, nil, nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/users/"+tt.userID+"/logout", nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("userID", tt.userID)
+			req = withTestUser(req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx)))
+			rec := httptest.NewRecorder()
+
+			handler.AdminLogoutUser(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
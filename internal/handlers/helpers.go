@@ -1,11 +1,16 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"net"
 	"net/http"
+	"strings"
 
 	"github.com/agpt-go/chatbot-api/internal/database"
 	"github.com/agpt-go/chatbot-api/internal/logging"
+	"github.com/agpt-go/chatbot-api/internal/services"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -29,7 +34,13 @@ func writeError(w http.ResponseWriter, status int, message string) {
 func writeValidationError(w http.ResponseWriter, err error) {
 	details := make(map[string]string)
 
-	if validationErrors, ok := err.(validator.ValidationErrors); ok {
+	var understandingErr *services.UnderstandingValidationError
+	var webhookToolErr *services.WebhookToolValidationError
+	if errors.As(err, &understandingErr) {
+		details = understandingErr.Fields
+	} else if errors.As(err, &webhookToolErr) {
+		details = webhookToolErr.Fields
+	} else if validationErrors, ok := err.(validator.ValidationErrors); ok {
 		for _, e := range validationErrors {
 			field := e.Field()
 			switch e.Tag() {
@@ -53,6 +64,28 @@ func writeValidationError(w http.ResponseWriter, err error) {
 	})
 }
 
+// clientContext returns the request context carrying the client's user agent
+// and IP address, which are recorded with the refresh tokens issued for it
+func clientContext(r *http.Request) context.Context {
+	ip := r.RemoteAddr
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	return services.WithClientInfo(r.Context(), services.ClientInfo{
+		UserAgent: r.UserAgent(),
+		IP:        ip,
+	})
+}
+
+// bearerToken returns the token of a Bearer Authorization header, if any
+func bearerToken(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return token
+}
+
 func UserToResponse(user *database.User) *UserResponse {
 	if user == nil {
 		return nil
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/logging"
+	"github.com/agpt-go/chatbot-api/internal/middleware"
+	"github.com/agpt-go/chatbot-api/internal/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type IdentityResponse struct {
+	ID         string `json:"id"`
+	Provider   string `json:"provider"`
+	Email      string `json:"email"`
+	CreatedAt  string `json:"created_at"`
+	LastUsedAt string `json:"last_used_at,omitempty"`
+}
+
+// AccountLinkRequiredResponse is returned when an OAuth sign-in matches an
+// existing account. The client signs in to that account and posts the
+// link token to /me/identities/link to link the provider.
+type AccountLinkRequiredResponse struct {
+	Error     string `json:"error"`
+	LinkToken string `json:"link_token"`
+	Provider  string `json:"provider"`
+	Email     string `json:"email"`
+}
+
+type LinkIdentityRequest struct {
+	LinkToken string `json:"link_token" validate:"required"`
+}
+
+func identityToResponse(identity services.Identity) IdentityResponse {
+	resp := IdentityResponse{
+		ID:        identity.ID.String(),
+		Provider:  identity.Provider,
+		Email:     identity.Email,
+		CreatedAt: identity.CreatedAt.Format(time.RFC3339),
+	}
+	if !identity.LastUsedAt.IsZero() {
+		resp.LastUsedAt = identity.LastUsedAt.Format(time.RFC3339)
+	}
+	return resp
+}
+
+// ListIdentities godoc
+// @Summary List linked sign-in methods
+// @Description List the OAuth accounts the authenticated user can sign in with, oldest first
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} IdentityResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /me/identities [get]
+func (h *AuthHandler) ListIdentities(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	identities, err := h.authService.ListIdentities(r.Context(), userID)
+	if err != nil {
+		logging.Error("failed to list identities", err, "userID", userID.String())
+		writeError(w, http.StatusInternalServerError, "Failed to list identities")
+		return
+	}
+
+	response := make([]IdentityResponse, 0, len(identities))
+	for _, identity := range identities {
+		response = append(response, identityToResponse(identity))
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// LinkIdentity godoc
+// @Summary Link an OAuth account
+// @Description Confirm linking the OAuth account of a sign-in that matched the authenticated user's email address, using the link token from that sign-in's 409 response. The token expires after 10 minutes and works once.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body LinkIdentityRequest true "Link token"
+// @Success 201 {object} IdentityResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /me/identities/link [post]
+func (h *AuthHandler) LinkIdentity(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req LinkIdentityRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	identity, err := h.authService.ConfirmAccountLink(r.Context(), userID, req.LinkToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidLinkToken):
+			writeError(w, http.StatusBadRequest, "Invalid or expired link token")
+		case errors.Is(err, services.ErrIdentityInUse):
+			writeError(w, http.StatusConflict, "This account is already linked to another user")
+		default:
+			logging.Error("failed to link identity", err, "userID", userID.String())
+			writeError(w, http.StatusInternalServerError, "Failed to link account")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, identityToResponse(*identity))
+}
+
+// UnlinkIdentity godoc
+// @Summary Unlink an OAuth account
+// @Description Remove one of the authenticated user's sign-in methods. An account without a password keeps at least one.
+// @Tags User
+// @Security BearerAuth
+// @Param identityID path string true "Identity UUID"
+// @Success 204 "Identity unlinked"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /me/identities/{identityID} [delete]
+func (h *AuthHandler) UnlinkIdentity(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	identityID, err := uuid.Parse(chi.URLParam(r, "identityID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid identity ID")
+		return
+	}
+
+	if err := h.authService.UnlinkIdentity(r.Context(), userID, identityID); err != nil {
+		switch {
+		case errors.Is(err, services.ErrIdentityNotFound):
+			writeError(w, http.StatusNotFound, "Identity not found")
+		case errors.Is(err, services.ErrLastLoginMethod):
+			writeError(w, http.StatusConflict, "Cannot unlink the only way to sign in; set a password first")
+		default:
+			logging.Error("failed to unlink identity", err, "userID", userID.String())
+			writeError(w, http.StatusInternalServerError, "Failed to unlink identity")
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/database"
+	"github.com/agpt-go/chatbot-api/internal/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func TestListIdentities(t *testing.T) {
+	now := time.Now()
+	handler := NewAuthHandler(&mockAuthService{
+		listIdentities: func(ctx context.Context, userID uuid.UUID) ([]services.Identity, error) {
+			return []services.Identity{
+				{ID: uuid.New(), Provider: "google", Email: "user@example.com", CreatedAt: now, LastUsedAt: now},
+				{ID: uuid.New(), Provider: "github", Email: "user@example.com", CreatedAt: now},
+			}, nil
+		},
+	}, nil, nil)
+
+	req := withTestUser(httptest.NewRequest(http.MethodGet, "/api/v1/me/identities", nil))
+	rec := httptest.NewRecorder()
+
+	handler.ListIdentities(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var identities []IdentityResponse
+	if err := json.NewDecoder(rec.Body).Decode(&identities); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(identities) != 2 || identities[1].Provider != "github" {
+		t.Fatalf("identities = %+v", identities)
+	}
+	if identities[0].LastUsedAt == "" || identities[1].LastUsedAt != "" {
+		t.Errorf("last_used_at = %q, %q; want only the first set", identities[0].LastUsedAt, identities[1].LastUsedAt)
+	}
+}
+
+func TestLinkIdentity(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		err  error
+		want int
+	}{
+		{"linked", `{"link_token":"token"}`, nil, http.StatusCreated},
+		{"missing token", `{}`, nil, http.StatusBadRequest},
+		{"invalid token", `{"link_token":"token"}`, services.ErrInvalidLinkToken, http.StatusBadRequest},
+		{"linked to another user", `{"link_token":"token"}`, services.ErrIdentityInUse, http.StatusConflict},
+		{"database error", `{"link_token":"token"}`, errors.New("db down"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAuthHandler(&mockAuthService{
+				confirmAccountLink: func(ctx context.Context, userID uuid.UUID, token string) (*services.Identity, error) {
+					if token != "token" {
+						t.Errorf("token = %q, want %q", token, "token")
+					}
+					if tt.err != nil {
+						return nil, tt.err
+					}
+					return &services.Identity{ID: uuid.New(), Provider: "okta", CreatedAt: time.Now()}, nil
+				},
+			}, nil, nil)
+
+			req := withTestUser(httptest.NewRequest(http.MethodPost, "/api/v1/me/identities/link", bytes.NewBufferString(tt.body)))
+			rec := httptest.NewRecorder()
+
+			handler.LinkIdentity(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnlinkIdentity(t *testing.T) {
+	tests := []struct {
+		name       string
+		identityID string
+		err        error
+		want       int
+	}{
+		{"unlinked", uuid.NewString(), nil, http.StatusNoContent},
+		{"invalid ID", "not-a-uuid", nil, http.StatusBadRequest},
+		{"not found", uuid.NewString(), services.ErrIdentityNotFound, http.StatusNotFound},
+		{"last login method", uuid.NewString(), services.ErrLastLoginMethod, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAuthHandler(&mockAuthService{
+				unlinkIdentity: func(ctx context.Context, userID, identityID uuid.UUID) error {
+					if identityID.String() != tt.identityID {
+						t.Errorf("identityID = %s, want %s", identityID, tt.identityID)
+					}
+					return tt.err
+				},
+			}, nil, nil)
+
+			req := httptest.NewRequest(http.MethodDelete, "/api/v1/me/identities/"+tt.identityID, nil)
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("identityID", tt.identityID)
+			req = withTestUser(req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx)))
+			rec := httptest.NewRecorder()
+
+			handler.UnlinkIdentity(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestOAuthCallbackHandler_AccountLinkRequired(t *testing.T) {
+	handler := NewAuthHandler(&mockAuthService{
+		handleOAuthCallback: func(ctx context.Context, provider, code, state string) (*database.User, *services.TokenPair, error) {
+			return nil, nil, &services.AccountLinkRequiredError{Token: "link-token", Provider: provider, Email: "user@example.com"}
+		},
+	}, nil, nil)
+
+	req := withProvider(httptest.NewRequest(http.MethodGet, "/api/v1/auth/okta/callback?code=c&state=s", nil), "okta")
+	rec := httptest.NewRecorder()
+
+	handler.OAuthCallback(rec, req)
+
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	var resp AccountLinkRequiredResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.LinkToken != "link-token" || resp.Provider != "okta" || resp.Email != "user@example.com" {
+		t.Errorf("response = %+v", resp)
+	}
+}
@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"context"
+	"io"
+	"time"
 
 	"github.com/agpt-go/chatbot-api/internal/database"
 	"github.com/agpt-go/chatbot-api/internal/services"
@@ -20,8 +22,47 @@ type ChatServicer interface {
 	SendMessage(ctx context.Context, sessionID, userID uuid.UUID, content string) (*database.ChatMessage, *database.ChatMessage, error)
 	SendMessageStream(ctx context.Context, sessionID, userID uuid.UUID, content string) (*database.ChatMessage, <-chan services.StreamChunk, error)
 	SaveStreamedResponse(ctx context.Context, sessionID uuid.UUID, content string) (*database.ChatMessage, error)
+	SaveToolCallStep(ctx context.Context, sessionID uuid.UUID, content string, results []services.ToolCallResult) error
 	GetToolExecutor() *services.ToolExecutor
-	GetAvailableTools() []services.ToolDefinition
+	AvailableTools(ctx context.Context, userID uuid.UUID) []services.ToolDefinition
+	ListProfiles() []services.AgentProfile
+}
+
+// ToolCallServicer defines the interface for confirming tool calls
+type ToolCallServicer interface {
+	ListPendingToolCalls(ctx context.Context, userID, sessionID uuid.UUID) ([]services.PendingToolCall, error)
+	ResolveToolCall(ctx context.Context, userID, sessionID uuid.UUID, toolCallID string, approve bool) (*services.ToolExecutionResult, error)
+}
+
+// MCPToolServicer defines the interface for serving tools over MCP
+type MCPToolServicer interface {
+	AutomaticTools(ctx context.Context, userID uuid.UUID) []services.ToolDefinition
+	ExecuteToolCalls(ctx context.Context, userID uuid.UUID, calls []services.ToolCall, onResult func(services.ToolCallResult)) []services.ToolCallResult
+}
+
+// WebhookToolServicer defines the interface for managing webhook tools
+type WebhookToolServicer interface {
+	List(ctx context.Context) ([]services.WebhookToolView, error)
+	Get(ctx context.Context, id uuid.UUID) (*services.WebhookToolView, error)
+	Create(ctx context.Context, userID uuid.UUID, input services.WebhookToolInput) (*services.WebhookToolView, error)
+	Update(ctx context.Context, id uuid.UUID, input services.WebhookToolInput) (*services.WebhookToolView, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// ToolAuditServicer defines the interface for the tool execution audit log and metrics
+type ToolAuditServicer interface {
+	ListExecutions(ctx context.Context, filter services.ToolExecutionFilter) ([]services.ToolExecutionView, error)
+	GetExecution(ctx context.Context, id uuid.UUID) (*services.ToolExecutionView, error)
+	Metrics() services.ToolMetrics
+	WritePrometheus(w io.Writer) error
+}
+
+// AttachmentServicer defines the interface for managing files uploaded to chat sessions
+type AttachmentServicer interface {
+	Upload(ctx context.Context, userID, sessionID uuid.UUID, filename string, data []byte) (*services.AttachmentView, error)
+	List(ctx context.Context, userID, sessionID uuid.UUID) ([]services.AttachmentView, error)
+	Delete(ctx context.Context, userID, sessionID, attachmentID uuid.UUID) error
+	MaxFileBytes() int64
 }
 
 // AuthServicer defines the interface for auth service operations
@@ -32,10 +73,33 @@ type AuthServicer interface {
 	RefreshTokens(ctx context.Context, refreshToken string) (*services.TokenPair, error)
 	Logout(ctx context.Context, refreshToken string) error
 	ValidateAccessToken(tokenString string) (*services.Claims, error)
-	GenerateOAuthState(ctx context.Context) (string, error)
-	ValidateOAuthState(ctx context.Context, state string) error
-	GetGoogleAuthURL(state string) string
-	HandleGoogleCallback(ctx context.Context, code string) (*database.User, *services.TokenPair, error)
+	OAuthAuthURL(ctx context.Context, provider string) (string, error)
+	HandleOAuthCallback(ctx context.Context, provider, code, state string) (*database.User, *services.TokenPair, error)
+	VerifyEmail(ctx context.Context, token string) (*database.User, error)
+	SendVerificationEmail(ctx context.Context, userID uuid.UUID) error
+	ResendVerificationEmail(ctx context.Context, email string) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
+	ChangePassword(ctx context.Context, userID uuid.UUID, currentPassword, newPassword string) (*services.TokenPair, error)
+	LogoutAll(ctx context.Context, userID uuid.UUID) error
+	ListDevices(ctx context.Context, userID uuid.UUID) ([]services.Device, error)
+	RevokeDevice(ctx context.Context, userID, deviceID uuid.UUID) error
+	ListIdentities(ctx context.Context, userID uuid.UUID) ([]services.Identity, error)
+	ConfirmAccountLink(ctx context.Context, userID uuid.UUID, token string) (*services.Identity, error)
+	UnlinkIdentity(ctx context.Context, userID, identityID uuid.UUID) error
+	VerifyMFAChallenge(ctx context.Context, token, code string) (*database.User, *services.TokenPair, error)
+	MFAStatus(ctx context.Context, userID uuid.UUID) (*services.MFAStatus, error)
+	BeginTOTPEnrollment(ctx context.Context, userID uuid.UUID) (*services.TOTPEnrollment, error)
+	ConfirmTOTPEnrollment(ctx context.Context, userID uuid.UUID, code string) ([]string, error)
+	DisableMFA(ctx context.Context, userID uuid.UUID, password, code string) error
+	RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, password, code string) ([]string, error)
+	SetMFARequired(ctx context.Context, userID uuid.UUID, required bool) error
+	ResetMFA(ctx context.Context, userID uuid.UUID) error
+	CreateAPIKey(ctx context.Context, userID uuid.UUID, name string, scopes []string, expiresAt time.Time) (*services.APIKey, string, error)
+	ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]services.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, keyID uuid.UUID) error
+	JWKS() services.JWKSet
+	RevokeAccessToken(ctx context.Context, tokenString string) error
 }
 
 // Validator defines the interface for request validation
@@ -59,3 +123,29 @@ type ReferralServicer interface {
 	HashIP(ip string) string
 	HashVisitorID(visitorID string) string
 }
+
+// BusinessUnderstandingServicer defines the interface for business understanding operations
+type BusinessUnderstandingServicer interface {
+	Get(ctx context.Context, userID uuid.UUID) (*services.BusinessUnderstandingView, error)
+	Update(ctx context.Context, userID uuid.UUID, patch *services.BusinessContext) (*services.BusinessUnderstandingView, error)
+	Delete(ctx context.Context, userID uuid.UUID) error
+	ListChanges(ctx context.Context, userID uuid.UUID, limit, offset int32) ([]services.UnderstandingChange, int64, error)
+	Revert(ctx context.Context, userID, changeID uuid.UUID) (*services.BusinessUnderstandingView, error)
+	Schema() *services.UnderstandingSchema
+}
+
+// OrganizationServicer defines the interface for organization operations
+type OrganizationServicer interface {
+	Create(ctx context.Context, userID uuid.UUID, name string) (*services.OrganizationView, error)
+	Get(ctx context.Context, userID uuid.UUID) (*services.OrganizationView, error)
+	Invite(ctx context.Context, userID uuid.UUID, email, role string) (*services.OrganizationInvitationView, error)
+	ListInvitations(ctx context.Context, userID uuid.UUID) ([]services.OrganizationInvitationView, error)
+	RevokeInvitation(ctx context.Context, userID, invitationID uuid.UUID) error
+	ListMyInvitations(ctx context.Context, userID uuid.UUID) ([]services.OrganizationInvitationView, error)
+	AcceptInvitation(ctx context.Context, userID, invitationID uuid.UUID) (*services.OrganizationView, error)
+	DeclineInvitation(ctx context.Context, userID, invitationID uuid.UUID) error
+	UpdateMemberRole(ctx context.Context, userID, memberID uuid.UUID, role string) error
+	RemoveMember(ctx context.Context, userID, memberID uuid.UUID) error
+	GetUnderstanding(ctx context.Context, userID uuid.UUID) (*services.OrganizationUnderstandingView, error)
+	UpdateUnderstanding(ctx context.Context, userID uuid.UUID, patch *services.BusinessContext) (*services.OrganizationUnderstandingView, error)
+}
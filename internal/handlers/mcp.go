@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/agpt-go/chatbot-api/internal/mcp"
+	"github.com/agpt-go/chatbot-api/internal/middleware"
+	"github.com/agpt-go/chatbot-api/internal/services"
+	"github.com/google/uuid"
+)
+
+// maxMCPRequestSize caps the body of an MCP request
+const maxMCPRequestSize = 1 << 20
+
+// MCPHandler serves the chatbot's tools to MCP clients over the streamable
+// HTTP transport. It answers with JSON only and keeps no session, so every
+// request stands alone and is authenticated like any other API call.
+type MCPHandler struct {
+	tools  MCPToolServicer
+	server *mcp.Server
+}
+
+// NewMCPHandler creates a new MCP handler
+func NewMCPHandler(tools MCPToolServicer) *MCPHandler {
+	return &MCPHandler{
+		tools: tools,
+		server: mcp.NewServer(
+			mcp.Implementation{Name: "chatbot-api", Version: "1.0"},
+			"Tools for recording and reporting on the user's business understanding. Calls run as the authenticated user.",
+		),
+	}
+}
+
+// ServeMCP godoc
+// @Summary MCP endpoint
+// @Description Model Context Protocol endpoint (streamable HTTP, JSON responses). Lists and calls the tools the user's policies allow without confirmation, as the authenticated user.
+// @Tags MCP
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body object true "JSON-RPC message or batch"
+// @Success 200 {object} object "JSON-RPC response or batch"
+// @Success 202 "Notifications accepted"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /mcp [post]
+func (h *MCPHandler) ServeMCP(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxMCPRequestSize))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Request body too large")
+		return
+	}
+	body = bytes.TrimSpace(body)
+
+	tools := &mcpUserTools{tools: h.tools, userID: userID}
+
+	if len(body) > 0 && body[0] == '[' {
+		var batch []*mcp.Message
+		if err := json.Unmarshal(body, &batch); err != nil || len(batch) == 0 {
+			writeJSON(w, http.StatusOK, mcp.NewErrorResponse(json.RawMessage("null"), mcp.CodeParseError, "invalid JSON-RPC batch"))
+			return
+		}
+
+		var responses []*mcp.Message
+		for _, msg := range batch {
+			if resp := h.server.Handle(r.Context(), tools, msg); resp != nil {
+				responses = append(responses, resp)
+			}
+		}
+		if len(responses) == 0 {
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		writeJSON(w, http.StatusOK, responses)
+		return
+	}
+
+	var msg mcp.Message
+	if err := json.Unmarshal(body, &msg); err != nil {
+		writeJSON(w, http.StatusOK, mcp.NewErrorResponse(json.RawMessage("null"), mcp.CodeParseError, "invalid JSON-RPC message"))
+		return
+	}
+
+	resp := h.server.Handle(r.Context(), tools, &msg)
+	if resp == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// RejectMCPStream godoc
+// @Summary MCP stream
+// @Description The MCP endpoint keeps no session and sends no server-initiated messages, so there is no stream to open or session to end
+// @Tags MCP
+// @Security BearerAuth
+// @Failure 405 {object} ErrorResponse
+// @Router /mcp [get]
+// @Router /mcp [delete]
+func (h *MCPHandler) RejectMCPStream(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Allow", http.MethodPost)
+	writeError(w, http.StatusMethodNotAllowed, "Method not allowed")
+}
+
+// mcpUserTools offers the tools to one authenticated user
+type mcpUserTools struct {
+	tools  MCPToolServicer
+	userID uuid.UUID
+}
+
+func (t *mcpUserTools) ListTools(ctx context.Context) ([]mcp.Tool, error) {
+	defs := t.tools.AutomaticTools(ctx, t.userID)
+	tools := make([]mcp.Tool, len(defs))
+	for i, def := range defs {
+		tools[i] = mcp.Tool{Name: def.Name, Description: def.Description, InputSchema: def.Parameters}
+	}
+	return tools, nil
+}
+
+func (t *mcpUserTools) CallTool(ctx context.Context, name string, arguments json.RawMessage) (*mcp.CallToolResult, error) {
+	available := false
+	for _, def := range t.tools.AutomaticTools(ctx, t.userID) {
+		if def.Name == name {
+			available = true
+			break
+		}
+	}
+	if !available {
+		return nil, mcp.ErrUnknownTool
+	}
+
+	call := services.ToolCall{ID: "mcp_" + uuid.NewString(), Type: "function"}
+	call.Function.Name = name
+	call.Function.Arguments = string(arguments)
+	if len(bytes.TrimSpace(arguments)) == 0 {
+		call.Function.Arguments = "{}"
+	}
+
+	// Run through the executor like chat tool calls, so policies, validation,
+	// timeouts and panic recovery all apply
+	result := t.tools.ExecuteToolCalls(ctx, t.userID, []services.ToolCall{call}, nil)[0].Result
+
+	// The text is what the chat model would see for the same call
+	content, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	return &mcp.CallToolResult{
+		Content: mcp.TextContent(string(content)),
+		IsError: !result.Success,
+	}, nil
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/agpt-go/chatbot-api/internal/mcp"
+	"github.com/agpt-go/chatbot-api/internal/services"
+	"github.com/google/uuid"
+)
+
+// mockMCPToolService implements MCPToolServicer for testing
+type mockMCPToolService struct {
+	tools       []services.ToolDefinition
+	executeFunc func(ctx context.Context, userID uuid.UUID, calls []services.ToolCall) []services.ToolCallResult
+}
+
+func (m *mockMCPToolService) AutomaticTools(ctx context.Context, userID uuid.UUID) []services.ToolDefinition {
+	return m.tools
+}
+
+func (m *mockMCPToolService) ExecuteToolCalls(ctx context.Context, userID uuid.UUID, calls []services.ToolCall, onResult func(services.ToolCallResult)) []services.ToolCallResult {
+	return m.executeFunc(ctx, userID, calls)
+}
+
+func serveMCP(t *testing.T, handler *MCPHandler, body string) (*httptest.ResponseRecorder, mcp.Message) {
+	t.Helper()
+	req := withTestUser(httptest.NewRequest(http.MethodPost, "/api/v1/mcp", strings.NewReader(body)))
+	rec := httptest.NewRecorder()
+	handler.ServeMCP(rec, req)
+
+	var resp mcp.Message
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("failed to decode response %q: %v", rec.Body.String(), err)
+		}
+	}
+	return rec, resp
+}
+
+func TestServeMCPListsTools(t *testing.T) {
+	handler := NewMCPHandler(&mockMCPToolService{tools: []services.ToolDefinition{{
+		Name:        "add_understanding",
+		Description: "Record business context",
+		Parameters:  map[string]interface{}{"type": "object"},
+	}}})
+
+	rec, resp := serveMCP(t, handler, `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`)
+	if rec.Code != http.StatusOK || resp.Error != nil {
+		t.Fatalf("status = %d, error = %v", rec.Code, resp.Error)
+	}
+
+	var result mcp.ListToolsResult
+	json.Unmarshal(resp.Result, &result)
+	if len(result.Tools) != 1 || result.Tools[0].Name != "add_understanding" || result.Tools[0].InputSchema["type"] != "object" {
+		t.Fatalf("unexpected tools: %+v", result.Tools)
+	}
+}
+
+func TestServeMCPCallsToolAsUser(t *testing.T) {
+	var gotUser uuid.UUID
+	var gotCall services.ToolCall
+	handler := NewMCPHandler(&mockMCPToolService{
+		tools: []services.ToolDefinition{{Name: "generate_business_report"}},
+		executeFunc: func(ctx context.Context, userID uuid.UUID, calls []services.ToolCall) []services.ToolCallResult {
+			gotUser, gotCall = userID, calls[0]
+			return []services.ToolCallResult{{Call: calls[0], Result: &services.ToolExecutionResult{Success: false, Error: "not enough context"}}}
+		},
+	})
+
+	rec, resp := serveMCP(t, handler, `{"jsonrpc":"2.0","id":"a","method":"tools/call","params":{"name":"generate_business_report"}}`)
+	if rec.Code != http.StatusOK || resp.Error != nil {
+		t.Fatalf("status = %d, error = %v", rec.Code, resp.Error)
+	}
+	if gotUser == uuid.Nil {
+		t.Error("expected the call to run as the authenticated user")
+	}
+	if gotCall.Function.Name != "generate_business_report" || gotCall.Function.Arguments != "{}" {
+		t.Errorf("unexpected call: %+v", gotCall)
+	}
+
+	var result mcp.CallToolResult
+	json.Unmarshal(resp.Result, &result)
+	if !result.IsError || len(result.Content) != 1 || !strings.Contains(result.Content[0].Text, "not enough context") {
+		t.Fatalf("expected the failed result as error content, got %+v", result)
+	}
+}
+
+func TestServeMCPRejectsUnavailableTool(t *testing.T) {
+	handler := NewMCPHandler(&mockMCPToolService{
+		tools: []services.ToolDefinition{{Name: "add_understanding"}},
+		executeFunc: func(ctx context.Context, userID uuid.UUID, calls []services.ToolCall) []services.ToolCallResult {
+			t.Fatal("tool should not run")
+			return nil
+		},
+	})
+
+	_, resp := serveMCP(t, handler, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"update_understanding"}}`)
+	if resp.Error == nil || resp.Error.Code != mcp.CodeInvalidParams {
+		t.Fatalf("expected invalid params error, got %+v", resp)
+	}
+}
+
+func TestServeMCPNotificationsAndBatches(t *testing.T) {
+	handler := NewMCPHandler(&mockMCPToolService{})
+
+	rec, _ := serveMCP(t, handler, `{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("notification: status = %d, want %d", rec.Code, http.StatusAccepted)
+	}
+
+	req := withTestUser(httptest.NewRequest(http.MethodPost, "/api/v1/mcp", strings.NewReader(
+		`[{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}},{"jsonrpc":"2.0","method":"notifications/initialized"},{"jsonrpc":"2.0","id":2,"method":"resources/list"}]`,
+	)))
+	rec = httptest.NewRecorder()
+	handler.ServeMCP(rec, req)
+
+	var responses []mcp.Message
+	if err := json.Unmarshal(rec.Body.Bytes(), &responses); err != nil {
+		t.Fatalf("failed to decode batch: %v", err)
+	}
+	if len(responses) != 2 {
+		t.Fatalf("expected a response per request, got %d", len(responses))
+	}
+	var init mcp.InitializeResult
+	json.Unmarshal(responses[0].Result, &init)
+	if init.ProtocolVersion != mcp.ProtocolVersion || init.Capabilities.Tools == nil {
+		t.Errorf("unexpected initialize result: %+v", init)
+	}
+	if responses[1].Error == nil || responses[1].Error.Code != mcp.CodeMethodNotFound {
+		t.Errorf("expected method not found, got %+v", responses[1])
+	}
+}
+
+func TestServeMCPRequiresAuth(t *testing.T) {
+	handler := NewMCPHandler(&mockMCPToolService{})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/mcp", strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"ping"}`))
+	rec := httptest.NewRecorder()
+
+	handler.ServeMCP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,419 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/agpt-go/chatbot-api/internal/logging"
+	"github.com/agpt-go/chatbot-api/internal/middleware"
+	"github.com/agpt-go/chatbot-api/internal/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// MFAChallengeResponse is returned by sign-ins of users with MFA enabled in
+// place of an AuthResponse. The client posts the token with a code to
+// /auth/mfa/verify to get the tokens.
+type MFAChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+type VerifyMFARequest struct {
+	MFAToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"` // Authenticator code or recovery code
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" validate:"required"` // Authenticator code, or a recovery code where MFA is enabled
+}
+
+type MFAChangeRequest struct {
+	Password string `json:"password"`                 // Current password; users who only sign in through an identity provider have none
+	Code     string `json:"code" validate:"required"` // Authenticator code or recovery code
+}
+
+type SetMFARequiredRequest struct {
+	Required *bool `json:"required" validate:"required"`
+}
+
+type MFAStatusResponse struct {
+	Enabled                bool  `json:"enabled"`
+	Required               bool  `json:"required"`
+	RecoveryCodesRemaining int64 `json:"recovery_codes_remaining"`
+}
+
+type TOTPEnrollmentResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+func mfaChallengeToResponse(challenge *services.MFAChallengeError) MFAChallengeResponse {
+	return MFAChallengeResponse{
+		MFARequired: true,
+		MFAToken:    challenge.Token,
+		ExpiresIn:   challenge.ExpiresIn,
+	}
+}
+
+// VerifyMFA godoc
+// @Summary Complete an MFA sign-in
+// @Description Exchange the MFA token from a login or OAuth callback and a code from the user's authenticator app, or one of their recovery codes, for tokens. The MFA token expires after 5 minutes or 5 wrong codes.
+// @Tags Authentication
+// @Accept json
+// @Produce json
+// @Param request body VerifyMFARequest true "MFA token and code"
+// @Success 200 {object} AuthResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /auth/mfa/verify [post]
+func (h *AuthHandler) VerifyMFA(w http.ResponseWriter, r *http.Request) {
+	var req VerifyMFARequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	user, tokens, err := h.authService.VerifyMFAChallenge(clientContext(r), req.MFAToken, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidMFACode):
+			writeError(w, http.StatusUnauthorized, "Invalid code")
+		case errors.Is(err, services.ErrInvalidMFAToken), errors.Is(err, services.ErrMFANotEnabled):
+			writeError(w, http.StatusUnauthorized, "Invalid or expired MFA token")
+		default:
+			logging.Error("failed to verify mfa challenge", err)
+			writeError(w, http.StatusInternalServerError, "Failed to login")
+		}
+		return
+	}
+
+	if h.analytics != nil {
+		h.analytics.TrackUserLoggedIn(user.ID, "mfa")
+	}
+
+	writeJSON(w, http.StatusOK, AuthResponse{
+		User:   UserToResponse(user),
+		Tokens: tokens,
+	})
+}
+
+// GetMFAStatus godoc
+// @Summary Get MFA status
+// @Description Whether the authenticated user has MFA enabled, whether it is required of them, and how many recovery codes they have left
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} MFAStatusResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /me/mfa [get]
+func (h *AuthHandler) GetMFAStatus(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	status, err := h.authService.MFAStatus(r.Context(), userID)
+	if err != nil {
+		logging.Error("failed to get mfa status", err, "userID", userID.String())
+		writeError(w, http.StatusInternalServerError, "Failed to get MFA status")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, MFAStatusResponse{
+		Enabled:                status.Enabled,
+		Required:               status.Required,
+		RecoveryCodesRemaining: status.RecoveryCodesRemaining,
+	})
+}
+
+// BeginTOTPEnrollment godoc
+// @Summary Start authenticator app enrollment
+// @Description Generate an authenticator secret for the authenticated user. Show the provisioning URI as a QR code, then confirm with a code from the app. Starting again replaces an unconfirmed secret.
+// @Tags User
+// @Produce json
+// @Security BearerAuth
+// @Success 201 {object} TOTPEnrollmentResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /me/mfa/totp [post]
+func (h *AuthHandler) BeginTOTPEnrollment(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	enrollment, err := h.authService.BeginTOTPEnrollment(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, services.ErrMFAAlreadyEnabled) {
+			writeError(w, http.StatusConflict, "MFA is already enabled")
+			return
+		}
+		logging.Error("failed to start totp enrollment", err, "userID", userID.String())
+		writeError(w, http.StatusInternalServerError, "Failed to start MFA enrollment")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, TOTPEnrollmentResponse{
+		Secret:          enrollment.Secret,
+		ProvisioningURI: enrollment.ProvisioningURI,
+	})
+}
+
+// ConfirmTOTPEnrollment godoc
+// @Summary Enable MFA
+// @Description Enable MFA with a code from the authenticator app enrolled at /me/mfa/totp. The response holds the user's recovery codes, which are not shown again.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body MFACodeRequest true "Authenticator code"
+// @Success 200 {object} RecoveryCodesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /me/mfa/totp/confirm [post]
+func (h *AuthHandler) ConfirmTOTPEnrollment(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	codes, err := h.authService.ConfirmTOTPEnrollment(r.Context(), userID, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidMFACode):
+			writeError(w, http.StatusBadRequest, "Invalid code")
+		case errors.Is(err, services.ErrMFANotStarted):
+			writeError(w, http.StatusConflict, "Start MFA enrollment first")
+		case errors.Is(err, services.ErrMFAAlreadyEnabled):
+			writeError(w, http.StatusConflict, "MFA is already enabled")
+		default:
+			logging.Error("failed to confirm totp enrollment", err, "userID", userID.String())
+			writeError(w, http.StatusInternalServerError, "Failed to enable MFA")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// DisableMFA godoc
+// @Summary Disable MFA
+// @Description Remove the authenticated user's authenticator app and recovery codes. Requires the current password and a code, with 5 attempts per 15 minutes. Users MFA is required of can't disable it.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body MFAChangeRequest true "Current password and an authenticator or recovery code"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /me/mfa/disable [post]
+func (h *AuthHandler) DisableMFA(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req MFAChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	if err := h.authService.DisableMFA(r.Context(), userID, req.Password, req.Code); err != nil {
+		switch {
+		case errors.Is(err, services.ErrMFARequired):
+			writeError(w, http.StatusForbidden, "MFA is required for this account")
+		default:
+			writeMFACodeError(w, err, userID, "Failed to disable MFA")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"message": "MFA disabled"})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary Regenerate recovery codes
+// @Description Replace the authenticated user's recovery codes, used or not. Requires the current password and a code, with 5 attempts per 15 minutes. The response holds the new codes, which are not shown again.
+// @Tags User
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body MFAChangeRequest true "Current password and an authenticator or recovery code"
+// @Success 200 {object} RecoveryCodesResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /me/mfa/recovery-codes [post]
+func (h *AuthHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req MFAChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	codes, err := h.authService.RegenerateRecoveryCodes(r.Context(), userID, req.Password, req.Code)
+	if err != nil {
+		writeMFACodeError(w, err, userID, "Failed to regenerate recovery codes")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// writeMFACodeError writes the response for a failed check of a user's MFA code
+func writeMFACodeError(w http.ResponseWriter, err error, userID uuid.UUID, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidMFACode):
+		writeError(w, http.StatusBadRequest, "Invalid code")
+	case errors.Is(err, services.ErrIncorrectPassword):
+		writeError(w, http.StatusForbidden, "Current password is incorrect")
+	case errors.Is(err, services.ErrMFANotEnabled):
+		writeError(w, http.StatusConflict, "MFA is not enabled")
+	case errors.Is(err, services.ErrTooManyMFAAttempts):
+		writeError(w, http.StatusTooManyRequests, "Too many attempts, try again in 15 minutes")
+	default:
+		logging.Error("mfa request failed", err, "userID", userID.String())
+		writeError(w, http.StatusInternalServerError, message)
+	}
+}
+
+// AdminSetMFARequired godoc
+// @Summary Require MFA of a user
+// @Description Set whether a user must enable MFA. Until they do, they can only reach their account settings. Requires an admin.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param userID path string true "User UUID"
+// @Param request body SetMFARequiredRequest true "Whether MFA is required"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/users/{userID}/mfa [put]
+func (h *AuthHandler) AdminSetMFARequired(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req SetMFARequiredRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	if err := h.authService.SetMFARequired(r.Context(), userID, *req.Required); err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			writeError(w, http.StatusNotFound, "User not found")
+			return
+		}
+		logging.Error("failed to set mfa requirement", err, "userID", userID.String())
+		writeError(w, http.StatusInternalServerError, "Failed to update user")
+		return
+	}
+
+	logging.Info("admin set mfa requirement", "adminID", middleware.GetUserID(r.Context()).String(), "userID", userID.String(), "required", *req.Required)
+	writeJSON(w, http.StatusOK, map[string]string{"message": "MFA requirement updated"})
+}
+
+// AdminResetMFA godoc
+// @Summary Reset a user's MFA
+// @Description Remove a user's authenticator app and recovery codes, for example when they lost both. If MFA is required of them they must enroll again. Requires an admin.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param userID path string true "User UUID"
+// @Success 200 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/users/{userID}/mfa [delete]
+func (h *AuthHandler) AdminResetMFA(w http.ResponseWriter, r *http.Request) {
+	userID, err := uuid.Parse(chi.URLParam(r, "userID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	if err := h.authService.ResetMFA(r.Context(), userID); err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			writeError(w, http.StatusNotFound, "User not found")
+			return
+		}
+		logging.Error("failed to reset mfa", err, "userID", userID.String())
+		writeError(w, http.StatusInternalServerError, "Failed to reset MFA")
+		return
+	}
+
+	logging.Info("admin reset mfa", "adminID", middleware.GetUserID(r.Context()).String(), "userID", userID.String())
+	writeJSON(w, http.StatusOK, map[string]string{"message": "MFA reset"})
+}
@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/agpt-go/chatbot-api/internal/database"
+	"github.com/agpt-go/chatbot-api/internal/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func TestLoginHandler_MFAChallenge(t *testing.T) {
+	handler := NewAuthHandler(&mockAuthService{
+		loginFunc: func(ctx context.Context, email, password string) (*database.User, *services.TokenPair, error) {
+			return nil, nil, &services.MFAChallengeError{Token: "mfa-token", ExpiresIn: 300}
+		},
+	}, nil, nil)
+
+	body := `{"email":"user@example.com","password":"password123"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+
+	handler.Login(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var resp MFAChallengeResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if !resp.MFARequired || resp.MFAToken != "mfa-token" || resp.ExpiresIn != 300 {
+		t.Errorf("response = %+v", resp)
+	}
+}
+
+func TestLoginHandler_TooManyMFAChallenges(t *testing.T) {
+	handler := NewAuthHandler(&mockAuthService{
+		loginFunc: func(ctx context.Context, email, password string) (*database.User, *services.TokenPair, error) {
+			return nil, nil, services.ErrTooManyMFAChallenges
+		},
+	}, nil, nil)
+
+	body := `{"email":"user@example.com","password":"password123"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewBufferString(body))
+	rec := httptest.NewRecorder()
+
+	handler.Login(rec, req)
+
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusTooManyRequests)
+	}
+}
+
+func TestVerifyMFA(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		err  error
+		want int
+	}{
+		{"signed in", `{"mfa_token":"token","code":"123456"}`, nil, http.StatusOK},
+		{"missing code", `{"mfa_token":"token"}`, nil, http.StatusBadRequest},
+		{"wrong code", `{"mfa_token":"token","code":"123456"}`, services.ErrInvalidMFACode, http.StatusUnauthorized},
+		{"expired token", `{"mfa_token":"token","code":"123456"}`, services.ErrInvalidMFAToken, http.StatusUnauthorized},
+		{"database error", `{"mfa_token":"token","code":"123456"}`, errors.New("db down"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAuthHandler(&mockAuthService{
+				verifyMFAChallenge: func(ctx context.Context, token, code string) (*database.User, *services.TokenPair, error) {
+					if token != "token" || code != "123456" {
+						t.Errorf("token, code = %q, %q", token, code)
+					}
+					if tt.err != nil {
+						return nil, nil, tt.err
+					}
+					return &database.User{ID: uuid.New(), Email: "user@example.com"}, &services.TokenPair{AccessToken: "access"}, nil
+				},
+			}, nil, nil)
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/mfa/verify", bytes.NewBufferString(tt.body))
+			rec := httptest.NewRecorder()
+
+			handler.VerifyMFA(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfirmTOTPEnrollment(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		err  error
+		want int
+	}{
+		{"enabled", `{"code":"123456"}`, nil, http.StatusOK},
+		{"missing code", `{}`, nil, http.StatusBadRequest},
+		{"wrong code", `{"code":"123456"}`, services.ErrInvalidMFACode, http.StatusBadRequest},
+		{"not started", `{"code":"123456"}`, services.ErrMFANotStarted, http.StatusConflict},
+		{"already enabled", `{"code":"123456"}`, services.ErrMFAAlreadyEnabled, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAuthHandler(&mockAuthService{
+				confirmTOTP: func(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+					if tt.err != nil {
+						return nil, tt.err
+					}
+					return []string{"abcde-fghij"}, nil
+				},
+			}, nil, nil)
+
+			req := withTestUser(httptest.NewRequest(http.MethodPost, "/api/v1/me/mfa/totp/confirm", bytes.NewBufferString(tt.body)))
+			rec := httptest.NewRecorder()
+
+			handler.ConfirmTOTPEnrollment(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusOK {
+				var resp RecoveryCodesResponse
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if len(resp.RecoveryCodes) != 1 {
+					t.Errorf("recovery_codes = %v", resp.RecoveryCodes)
+				}
+			}
+		})
+	}
+}
+
+func TestDisableMFA(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"disabled", nil, http.StatusOK},
+		{"required by policy", services.ErrMFARequired, http.StatusForbidden},
+		{"wrong code", services.ErrInvalidMFACode, http.StatusBadRequest},
+		{"not enabled", services.ErrMFANotEnabled, http.StatusConflict},
+		{"wrong password", services.ErrIncorrectPassword, http.StatusForbidden},
+		{"too many attempts", services.ErrTooManyMFAAttempts, http.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAuthHandler(&mockAuthService{
+				disableMFA: func(ctx context.Context, userID uuid.UUID, password, code string) error {
+					if password != "secret-password" || code != "123456" {
+						t.Errorf("password, code = %q, %q", password, code)
+					}
+					return tt.err
+				},
+			}, nil, nil)
+
+			req := withTestUser(httptest.NewRequest(http.MethodPost, "/api/v1/me/mfa/disable", bytes.NewBufferString(`{"password":"secret-password","code":"123456"}`)))
+			rec := httptest.NewRecorder()
+
+			handler.DisableMFA(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdminSetMFARequired(t *testing.T) {
+	tests := []struct {
+		name   string
+		userID string
+		body   string
+		err    error
+		want   int
+	}{
+		{"required", uuid.NewString(), `{"required":true}`, nil, http.StatusOK},
+		{"not required", uuid.NewString(), `{"required":false}`, nil, http.StatusOK},
+		{"missing field", uuid.NewString(), `{}`, nil, http.StatusBadRequest},
+		{"invalid id", "not-a-uuid", `{"required":true}`, nil, http.StatusBadRequest},
+		{"unknown user", uuid.NewString(), `{"required":true}`, services.ErrUserNotFound, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewAuthHandler(&mockAuthService{
+				setMFARequired: func(ctx context.Context, userID uuid.UUID, required bool) error {
+					if userID.String() != tt.userID {
+						t.Errorf("userID = %s, want %s", userID, tt.userID)
+					}
+					return tt.err
+				},
+			}, nil, nil)
+
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/admin/users/"+tt.userID+"/mfa", bytes.NewBufferString(tt.body))
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("userID", tt.userID)
+			req = withTestUser(req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx)))
+			rec := httptest.NewRecorder()
+
+			handler.AdminSetMFARequired(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
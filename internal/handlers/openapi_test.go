@@ -73,6 +73,12 @@ func TestOpenAPIHandler_ServeSpec(t *testing.T) {
 			"/api/v1/sessions/{sessionID}",
 			"/api/v1/sessions/{sessionID}/messages",
 			"/api/v1/sessions/{sessionID}/messages/stream",
+			"/api/v1/auth/{provider}",
+			"/api/v1/auth/mfa/verify",
+			"/api/v1/me/api-keys",
+			"/api/v1/business-understanding",
+			"/api/v1/organizations/me/invitations",
+			"/api/v1/sessions/{sessionID}/tool-calls/{toolCallID}/approve",
 		}
 
 		for _, path := range expectedPaths {
@@ -0,0 +1,510 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/logging"
+	"github.com/agpt-go/chatbot-api/internal/middleware"
+	"github.com/agpt-go/chatbot-api/internal/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// OrganizationHandler manages organizations and their shared business understanding
+type OrganizationHandler struct {
+	organizationService OrganizationServicer
+	validate            Validator
+}
+
+// NewOrganizationHandler creates a new organization handler
+func NewOrganizationHandler(organizationService OrganizationServicer) *OrganizationHandler {
+	return &OrganizationHandler{
+		organizationService: organizationService,
+		validate:            validator.New(),
+	}
+}
+
+// CreateOrganizationRequest represents the create organization request body
+type CreateOrganizationRequest struct {
+	Name string `json:"name" validate:"required,min=1,max=255"`
+}
+
+// InviteOrganizationMemberRequest represents the invite member request body
+type InviteOrganizationMemberRequest struct {
+	Email string `json:"email" validate:"required,email"`
+	Role  string `json:"role" validate:"omitempty,oneof=owner admin member"`
+}
+
+// UpdateOrganizationMemberRequest represents the update member request body
+type UpdateOrganizationMemberRequest struct {
+	Role string `json:"role" validate:"required,oneof=owner admin member"`
+}
+
+// OrganizationUnderstandingResponse is the company-level business understanding
+type OrganizationUnderstandingResponse struct {
+	Understanding *services.BusinessContext `json:"understanding"`
+	CreatedAt     string                    `json:"created_at"`
+	UpdatedAt     string                    `json:"updated_at"`
+}
+
+func orgUnderstandingToResponse(view *services.OrganizationUnderstandingView) OrganizationUnderstandingResponse {
+	return OrganizationUnderstandingResponse{
+		Understanding: view.Context,
+		CreatedAt:     view.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:     view.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+// writeOrganizationError maps organization service errors to responses
+func writeOrganizationError(w http.ResponseWriter, err error, action string, userID uuid.UUID) {
+	var validationErr *services.UnderstandingValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		writeValidationError(w, err)
+	case errors.Is(err, services.ErrNotInOrganization):
+		writeError(w, http.StatusNotFound, "You are not in an organization")
+	case errors.Is(err, services.ErrAlreadyInOrganization):
+		writeError(w, http.StatusConflict, "You already belong to an organization")
+	case errors.Is(err, services.ErrOrganizationForbidden):
+		writeError(w, http.StatusForbidden, "Your organization role does not allow this")
+	case errors.Is(err, services.ErrOrganizationMemberNotFound):
+		writeError(w, http.StatusNotFound, "User not found")
+	case errors.Is(err, services.ErrLastOrganizationOwner):
+		writeError(w, http.StatusConflict, "Organization must keep at least one owner")
+	case errors.Is(err, services.ErrInvitationNotFound):
+		writeError(w, http.StatusNotFound, "Invitation not found")
+	case errors.Is(err, services.ErrInvitationEmailUnverified):
+		writeError(w, http.StatusForbidden, "Verify your email address to use invitations")
+	case errors.Is(err, services.ErrUnderstandingNotFound):
+		writeError(w, http.StatusNotFound, "Organization understanding not found")
+	default:
+		logging.Error("failed to "+action, err, "userID", userID.String())
+		writeError(w, http.StatusInternalServerError, "Failed to "+action)
+	}
+}
+
+// CreateOrganization godoc
+// @Summary Create organization
+// @Description Create an organization with the current user as its owner. A user can belong to one organization.
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateOrganizationRequest true "Organization details"
+// @Success 201 {object} services.OrganizationView
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /organizations [post]
+func (h *OrganizationHandler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req CreateOrganizationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	org, err := h.organizationService.Create(r.Context(), userID, req.Name)
+	if err != nil {
+		writeOrganizationError(w, err, "create organization", userID)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, org)
+}
+
+// GetOrganization godoc
+// @Summary Get current organization
+// @Description Get the current user's organization, their role and its members
+// @Tags Organizations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} services.OrganizationView
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /organizations/me [get]
+func (h *OrganizationHandler) GetOrganization(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	org, err := h.organizationService.Get(r.Context(), userID)
+	if err != nil {
+		writeOrganizationError(w, err, "get organization", userID)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, org)
+}
+
+// InviteOrganizationMember godoc
+// @Summary Invite organization member
+// @Description Invite someone to the organization by email. They join only once they accept. Requires the owner or admin role; only owners can invite owners. The response is the same whether or not the address belongs to an account. Inviting an address again replaces its invitation.
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body InviteOrganizationMemberRequest true "Invitation details"
+// @Success 201 {object} services.OrganizationInvitationView
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /organizations/me/invitations [post]
+func (h *OrganizationHandler) InviteOrganizationMember(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req InviteOrganizationMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	role := req.Role
+	if role == "" {
+		role = services.OrgRoleMember
+	}
+
+	invitation, err := h.organizationService.Invite(r.Context(), userID, req.Email, role)
+	if err != nil {
+		writeOrganizationError(w, err, "invite organization member", userID)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, invitation)
+}
+
+// ListOrganizationInvitations godoc
+// @Summary List organization invitations
+// @Description List the organization's pending invitations, newest first. Requires the owner or admin role.
+// @Tags Organizations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} services.OrganizationInvitationView
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /organizations/me/invitations [get]
+func (h *OrganizationHandler) ListOrganizationInvitations(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	invitations, err := h.organizationService.ListInvitations(r.Context(), userID)
+	if err != nil {
+		writeOrganizationError(w, err, "list organization invitations", userID)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, invitations)
+}
+
+// RevokeOrganizationInvitation godoc
+// @Summary Revoke organization invitation
+// @Description Withdraw a pending invitation. Requires the owner or admin role.
+// @Tags Organizations
+// @Security BearerAuth
+// @Param invitationID path string true "Invitation ID"
+// @Success 204 "Invitation revoked"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /organizations/me/invitations/{invitationID} [delete]
+func (h *OrganizationHandler) RevokeOrganizationInvitation(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	invitationID, err := uuid.Parse(chi.URLParam(r, "invitationID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid invitation ID")
+		return
+	}
+
+	if err := h.organizationService.RevokeInvitation(r.Context(), userID, invitationID); err != nil {
+		writeOrganizationError(w, err, "revoke organization invitation", userID)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListMyInvitations godoc
+// @Summary List my invitations
+// @Description List the pending organization invitations addressed to the current user's email, newest first. Requires a verified email address.
+// @Tags Organizations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} services.OrganizationInvitationView
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /organizations/invitations [get]
+func (h *OrganizationHandler) ListMyInvitations(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	invitations, err := h.organizationService.ListMyInvitations(r.Context(), userID)
+	if err != nil {
+		writeOrganizationError(w, err, "list invitations", userID)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, invitations)
+}
+
+// AcceptInvitation godoc
+// @Summary Accept invitation
+// @Description Join the organization an invitation is for, with the invited role. Requires a verified email address and no current organization.
+// @Tags Organizations
+// @Produce json
+// @Security BearerAuth
+// @Param invitationID path string true "Invitation ID"
+// @Success 200 {object} services.OrganizationView
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /organizations/invitations/{invitationID}/accept [post]
+func (h *OrganizationHandler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	invitationID, err := uuid.Parse(chi.URLParam(r, "invitationID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid invitation ID")
+		return
+	}
+
+	org, err := h.organizationService.AcceptInvitation(r.Context(), userID, invitationID)
+	if err != nil {
+		writeOrganizationError(w, err, "accept invitation", userID)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, org)
+}
+
+// DeclineInvitation godoc
+// @Summary Decline invitation
+// @Description Discard an invitation addressed to the current user. Requires a verified email address.
+// @Tags Organizations
+// @Security BearerAuth
+// @Param invitationID path string true "Invitation ID"
+// @Success 204 "Invitation declined"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /organizations/invitations/{invitationID}/decline [post]
+func (h *OrganizationHandler) DeclineInvitation(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	invitationID, err := uuid.Parse(chi.URLParam(r, "invitationID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid invitation ID")
+		return
+	}
+
+	if err := h.organizationService.DeclineInvitation(r.Context(), userID, invitationID); err != nil {
+		writeOrganizationError(w, err, "decline invitation", userID)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UpdateOrganizationMember godoc
+// @Summary Change a member's role
+// @Description Change an organization member's role. Requires the owner role.
+// @Tags Organizations
+// @Accept json
+// @Security BearerAuth
+// @Param userID path string true "Member user ID"
+// @Param request body UpdateOrganizationMemberRequest true "New role"
+// @Success 204 "Role updated"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /organizations/me/members/{userID} [patch]
+func (h *OrganizationHandler) UpdateOrganizationMember(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	memberID, err := uuid.Parse(chi.URLParam(r, "userID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	var req UpdateOrganizationMemberRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	if err := h.organizationService.UpdateMemberRole(r.Context(), userID, memberID, req.Role); err != nil {
+		writeOrganizationError(w, err, "update organization member", userID)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RemoveOrganizationMember godoc
+// @Summary Remove organization member
+// @Description Remove a member from the organization. Members can remove themselves; removing others requires the owner or admin role.
+// @Tags Organizations
+// @Security BearerAuth
+// @Param userID path string true "Member user ID"
+// @Success 204 "Member removed"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /organizations/me/members/{userID} [delete]
+func (h *OrganizationHandler) RemoveOrganizationMember(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	memberID, err := uuid.Parse(chi.URLParam(r, "userID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	if err := h.organizationService.RemoveMember(r.Context(), userID, memberID); err != nil {
+		writeOrganizationError(w, err, "remove organization member", userID)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetOrganizationUnderstanding godoc
+// @Summary Get organization business understanding
+// @Description Get the company-level business understanding shared by all members
+// @Tags Organizations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} OrganizationUnderstandingResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /organizations/me/understanding [get]
+func (h *OrganizationHandler) GetOrganizationUnderstanding(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	view, err := h.organizationService.GetUnderstanding(r.Context(), userID)
+	if err != nil {
+		writeOrganizationError(w, err, "get organization understanding", userID)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, orgUnderstandingToResponse(view))
+}
+
+// UpdateOrganizationUnderstanding godoc
+// @Summary Update organization business understanding
+// @Description Edit the company-level business understanding. Requires the owner or admin role. Omitted fields are unchanged; empty values clear a field.
+// @Tags Organizations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body object true "Fields to update"
+// @Success 200 {object} OrganizationUnderstandingResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /organizations/me/understanding [patch]
+func (h *OrganizationHandler) UpdateOrganizationUnderstanding(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	patch := services.NewBusinessContext()
+	if err := json.NewDecoder(r.Body).Decode(patch); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	view, err := h.organizationService.UpdateUnderstanding(r.Context(), userID, patch)
+	if err != nil {
+		writeOrganizationError(w, err, "update organization understanding", userID)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, orgUnderstandingToResponse(view))
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/agpt-go/chatbot-api/internal/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// mockOrganizationService implements OrganizationServicer for testing
+type mockOrganizationService struct {
+	createFunc              func(ctx context.Context, userID uuid.UUID, name string) (*services.OrganizationView, error)
+	getFunc                 func(ctx context.Context, userID uuid.UUID) (*services.OrganizationView, error)
+	inviteFunc              func(ctx context.Context, userID uuid.UUID, email, role string) (*services.OrganizationInvitationView, error)
+	acceptInvitationFunc    func(ctx context.Context, userID, invitationID uuid.UUID) (*services.OrganizationView, error)
+	updateUnderstandingFunc func(ctx context.Context, userID uuid.UUID, patch *services.BusinessContext) (*services.OrganizationUnderstandingView, error)
+}
+
+func (m *mockOrganizationService) Create(ctx context.Context, userID uuid.UUID, name string) (*services.OrganizationView, error) {
+	if m.createFunc != nil {
+		return m.createFunc(ctx, userID, name)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockOrganizationService) Get(ctx context.Context, userID uuid.UUID) (*services.OrganizationView, error) {
+	if m.getFunc != nil {
+		return m.getFunc(ctx, userID)
+	}
+	return nil, services.ErrNotInOrganization
+}
+
+func (m *mockOrganizationService) Invite(ctx context.Context, userID uuid.UUID, email, role string) (*services.OrganizationInvitationView, error) {
+	if m.inviteFunc != nil {
+		return m.inviteFunc(ctx, userID, email, role)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockOrganizationService) ListInvitations(ctx context.Context, userID uuid.UUID) ([]services.OrganizationInvitationView, error) {
+	return []services.OrganizationInvitationView{}, nil
+}
+
+func (m *mockOrganizationService) RevokeInvitation(ctx context.Context, userID, invitationID uuid.UUID) error {
+	return nil
+}
+
+func (m *mockOrganizationService) ListMyInvitations(ctx context.Context, userID uuid.UUID) ([]services.OrganizationInvitationView, error) {
+	return []services.OrganizationInvitationView{}, nil
+}
+
+func (m *mockOrganizationService) AcceptInvitation(ctx context.Context, userID, invitationID uuid.UUID) (*services.OrganizationView, error) {
+	if m.acceptInvitationFunc != nil {
+		return m.acceptInvitationFunc(ctx, userID, invitationID)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *mockOrganizationService) DeclineInvitation(ctx context.Context, userID, invitationID uuid.UUID) error {
+	return nil
+}
+
+func (m *mockOrganizationService) UpdateMemberRole(ctx context.Context, userID, memberID uuid.UUID, role string) error {
+	return nil
+}
+
+func (m *mockOrganizationService) RemoveMember(ctx context.Context, userID, memberID uuid.UUID) error {
+	return nil
+}
+
+func (m *mockOrganizationService) GetUnderstanding(ctx context.Context, userID uuid.UUID) (*services.OrganizationUnderstandingView, error) {
+	return nil, services.ErrUnderstandingNotFound
+}
+
+func (m *mockOrganizationService) UpdateUnderstanding(ctx context.Context, userID uuid.UUID, patch *services.BusinessContext) (*services.OrganizationUnderstandingView, error) {
+	if m.updateUnderstandingFunc != nil {
+		return m.updateUnderstandingFunc(ctx, userID, patch)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func TestCreateOrganization(t *testing.T) {
+	t.Run("missing name", func(t *testing.T) {
+		handler := NewOrganizationHandler(&mockOrganizationService{})
+		req := withTestUser(httptest.NewRequest(http.MethodPost, "/api/v1/organizations", bytes.NewBufferString(`{}`)))
+		rec := httptest.NewRecorder()
+
+		handler.CreateOrganization(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("already in organization", func(t *testing.T) {
+		handler := NewOrganizationHandler(&mockOrganizationService{
+			createFunc: func(ctx context.Context, userID uuid.UUID, name string) (*services.OrganizationView, error) {
+				return nil, services.ErrAlreadyInOrganization
+			},
+		})
+		req := withTestUser(httptest.NewRequest(http.MethodPost, "/api/v1/organizations", bytes.NewBufferString(`{"name":"Acme"}`)))
+		rec := httptest.NewRecorder()
+
+		handler.CreateOrganization(rec, req)
+
+		if rec.Code != http.StatusConflict {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusConflict)
+		}
+	})
+}
+
+func TestGetOrganization(t *testing.T) {
+	handler := NewOrganizationHandler(&mockOrganizationService{})
+	req := withTestUser(httptest.NewRequest(http.MethodGet, "/api/v1/organizations/me", nil))
+	rec := httptest.NewRecorder()
+
+	handler.GetOrganization(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestInviteOrganizationMember(t *testing.T) {
+	t.Run("defaults to member role", func(t *testing.T) {
+		var gotRole string
+		handler := NewOrganizationHandler(&mockOrganizationService{
+			inviteFunc: func(ctx context.Context, userID uuid.UUID, email, role string) (*services.OrganizationInvitationView, error) {
+				gotRole = role
+				return &services.OrganizationInvitationView{Email: email, Role: role}, nil
+			},
+		})
+		req := withTestUser(httptest.NewRequest(http.MethodPost, "/api/v1/organizations/me/invitations",
+			bytes.NewBufferString(`{"email":"bob@example.com"}`)))
+		rec := httptest.NewRecorder()
+
+		handler.InviteOrganizationMember(rec, req)
+
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusCreated)
+		}
+		if gotRole != services.OrgRoleMember {
+			t.Errorf("role = %q, want %q", gotRole, services.OrgRoleMember)
+		}
+	})
+
+	t.Run("invalid role", func(t *testing.T) {
+		handler := NewOrganizationHandler(&mockOrganizationService{})
+		req := withTestUser(httptest.NewRequest(http.MethodPost, "/api/v1/organizations/me/invitations",
+			bytes.NewBufferString(`{"email":"bob@example.com","role":"superuser"}`)))
+		rec := httptest.NewRecorder()
+
+		handler.InviteOrganizationMember(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("insufficient role", func(t *testing.T) {
+		handler := NewOrganizationHandler(&mockOrganizationService{
+			inviteFunc: func(ctx context.Context, userID uuid.UUID, email, role string) (*services.OrganizationInvitationView, error) {
+				return nil, services.ErrOrganizationForbidden
+			},
+		})
+		req := withTestUser(httptest.NewRequest(http.MethodPost, "/api/v1/organizations/me/invitations",
+			bytes.NewBufferString(`{"email":"bob@example.com"}`)))
+		rec := httptest.NewRecorder()
+
+		handler.InviteOrganizationMember(rec, req)
+
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+		}
+	})
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	t.Run("invalid invitation ID", func(t *testing.T) {
+		handler := NewOrganizationHandler(&mockOrganizationService{})
+		req := withTestUser(httptest.NewRequest(http.MethodPost, "/api/v1/organizations/invitations/nope/accept", nil))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("invitationID", "nope")
+		req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+		rec := httptest.NewRecorder()
+
+		handler.AcceptInvitation(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"unverified email", services.ErrInvitationEmailUnverified, http.StatusForbidden},
+		{"not invited", services.ErrInvitationNotFound, http.StatusNotFound},
+		{"already in an organization", services.ErrAlreadyInOrganization, http.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewOrganizationHandler(&mockOrganizationService{
+				acceptInvitationFunc: func(ctx context.Context, userID, invitationID uuid.UUID) (*services.OrganizationView, error) {
+					return nil, tt.err
+				},
+			})
+			invitationID := uuid.New().String()
+			req := withTestUser(httptest.NewRequest(http.MethodPost, "/api/v1/organizations/invitations/"+invitationID+"/accept", nil))
+			rctx := chi.NewRouteContext()
+			rctx.URLParams.Add("invitationID", invitationID)
+			req = req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, rctx))
+			rec := httptest.NewRecorder()
+
+			handler.AcceptInvitation(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/logging"
+	"github.com/agpt-go/chatbot-api/internal/middleware"
+	"github.com/agpt-go/chatbot-api/internal/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// ToolCallHandler lets users approve or reject tool calls whose policy requires confirmation
+type ToolCallHandler struct {
+	toolCalls ToolCallServicer
+}
+
+// NewToolCallHandler creates a new tool call handler
+func NewToolCallHandler(toolCalls ToolCallServicer) *ToolCallHandler {
+	return &ToolCallHandler{
+		toolCalls: toolCalls,
+	}
+}
+
+// PendingToolCallResponse is a tool call waiting for confirmation
+type PendingToolCallResponse struct {
+	ToolCallID string      `json:"tool_call_id"`
+	ToolName   string      `json:"tool_name"`
+	Args       interface{} `json:"args"`
+	CreatedAt  string      `json:"created_at"`
+}
+
+// ToolCallResolutionResponse is the outcome of approving or rejecting a tool call
+type ToolCallResolutionResponse struct {
+	ToolCallID string                        `json:"tool_call_id"`
+	Status     string                        `json:"status"`
+	Result     *services.ToolExecutionResult `json:"result"`
+}
+
+// ListPendingToolCalls godoc
+// @Summary List pending tool calls
+// @Description List the tool calls in a session that wait for the user's approval
+// @Tags Tool Calls
+// @Produce json
+// @Security BearerAuth
+// @Param sessionID path string true "Session UUID"
+// @Success 200 {array} PendingToolCallResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /sessions/{sessionID}/tool-calls [get]
+func (h *ToolCallHandler) ListPendingToolCalls(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	sessionID, err := uuid.Parse(chi.URLParam(r, "sessionID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+
+	pending, err := h.toolCalls.ListPendingToolCalls(r.Context(), userID, sessionID)
+	if err != nil {
+		logging.Error("failed to list pending tool calls", err, "sessionID", sessionID.String())
+		writeError(w, http.StatusInternalServerError, "Failed to list pending tool calls")
+		return
+	}
+
+	response := make([]PendingToolCallResponse, len(pending))
+	for i, tc := range pending {
+		// Parse arguments to interface{} for proper JSON encoding
+		var args interface{}
+		if err := json.Unmarshal([]byte(tc.Arguments), &args); err != nil {
+			args = tc.Arguments // Fallback to string
+		}
+		response[i] = PendingToolCallResponse{
+			ToolCallID: tc.ToolCallID,
+			ToolName:   tc.ToolName,
+			Args:       args,
+			CreatedAt:  tc.CreatedAt.Format(time.RFC3339),
+		}
+	}
+
+	writeJSON(w, http.StatusOK, response)
+}
+
+// ApproveToolCall godoc
+// @Summary Approve a tool call
+// @Description Run a pending tool call and return its result. The result is also saved to the chat history, so the model sees it on the next message.
+// @Tags Tool Calls
+// @Produce json
+// @Security BearerAuth
+// @Param sessionID path string true "Session UUID"
+// @Param toolCallID path string true "Tool call ID from the stream"
+// @Success 200 {object} ToolCallResolutionResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /sessions/{sessionID}/tool-calls/{toolCallID}/approve [post]
+func (h *ToolCallHandler) ApproveToolCall(w http.ResponseWriter, r *http.Request) {
+	h.resolveToolCall(w, r, true)
+}
+
+// RejectToolCall godoc
+// @Summary Reject a tool call
+// @Description Decline a pending tool call without running it. The chat history records that the user declined, so the model sees it on the next message.
+// @Tags Tool Calls
+// @Produce json
+// @Security BearerAuth
+// @Param sessionID path string true "Session UUID"
+// @Param toolCallID path string true "Tool call ID from the stream"
+// @Success 200 {object} ToolCallResolutionResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /sessions/{sessionID}/tool-calls/{toolCallID}/reject [post]
+func (h *ToolCallHandler) RejectToolCall(w http.ResponseWriter, r *http.Request) {
+	h.resolveToolCall(w, r, false)
+}
+
+func (h *ToolCallHandler) resolveToolCall(w http.ResponseWriter, r *http.Request, approve bool) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	sessionID, err := uuid.Parse(chi.URLParam(r, "sessionID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+
+	toolCallID := chi.URLParam(r, "toolCallID")
+	if toolCallID == "" {
+		writeError(w, http.StatusBadRequest, "Invalid tool call ID")
+		return
+	}
+
+	result, err := h.toolCalls.ResolveToolCall(r.Context(), userID, sessionID, toolCallID, approve)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrToolCallNotFound):
+			writeError(w, http.StatusNotFound, "Tool call not found")
+		case errors.Is(err, services.ErrToolCallResolved):
+			writeError(w, http.StatusConflict, "Tool call has already been approved or rejected")
+		default:
+			logging.Error("failed to resolve tool call", err, "sessionID", sessionID.String(), "toolCallID", toolCallID)
+			writeError(w, http.StatusInternalServerError, "Failed to resolve tool call")
+		}
+		return
+	}
+
+	status := services.ToolCallStatusRejected
+	if approve {
+		status = services.ToolCallStatusApproved
+	}
+
+	writeJSON(w, http.StatusOK, ToolCallResolutionResponse{
+		ToolCallID: toolCallID,
+		Status:     status,
+		Result:     result,
+	})
+}
@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// mockToolCallService implements ToolCallServicer for testing
+type mockToolCallService struct {
+	listFunc    func(ctx context.Context, userID, sessionID uuid.UUID) ([]services.PendingToolCall, error)
+	resolveFunc func(ctx context.Context, userID, sessionID uuid.UUID, toolCallID string, approve bool) (*services.ToolExecutionResult, error)
+}
+
+func (m *mockToolCallService) ListPendingToolCalls(ctx context.Context, userID, sessionID uuid.UUID) ([]services.PendingToolCall, error) {
+	if m.listFunc != nil {
+		return m.listFunc(ctx, userID, sessionID)
+	}
+	return []services.PendingToolCall{}, nil
+}
+
+func (m *mockToolCallService) ResolveToolCall(ctx context.Context, userID, sessionID uuid.UUID, toolCallID string, approve bool) (*services.ToolExecutionResult, error) {
+	if m.resolveFunc != nil {
+		return m.resolveFunc(ctx, userID, sessionID, toolCallID, approve)
+	}
+	return nil, services.ErrToolCallNotFound
+}
+
+func withToolCallParams(r *http.Request, sessionID, toolCallID string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("sessionID", sessionID)
+	rctx.URLParams.Add("toolCallID", toolCallID)
+	return withTestUser(r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx)))
+}
+
+func TestListPendingToolCalls(t *testing.T) {
+	handler := NewToolCallHandler(&mockToolCallService{
+		listFunc: func(ctx context.Context, userID, sessionID uuid.UUID) ([]services.PendingToolCall, error) {
+			return []services.PendingToolCall{{
+				ToolCallID: "call_1",
+				ToolName:   "update_understanding",
+				Arguments:  `{"field":"pain_points"}`,
+				CreatedAt:  time.Now(),
+			}}, nil
+		},
+	})
+	req := withToolCallParams(httptest.NewRequest(http.MethodGet, "/api/v1/sessions/x/tool-calls", nil), uuid.New().String(), "")
+	rec := httptest.NewRecorder()
+
+	handler.ListPendingToolCalls(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var resp []PendingToolCallResponse
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp) != 1 || resp[0].ToolCallID != "call_1" {
+		t.Fatalf("response = %+v", resp)
+	}
+	if args, ok := resp[0].Args.(map[string]interface{}); !ok || args["field"] != "pain_points" {
+		t.Errorf("args = %v, want parsed JSON", resp[0].Args)
+	}
+}
+
+func TestResolveToolCall(t *testing.T) {
+	tests := []struct {
+		name       string
+		approve    bool
+		err        error
+		wantStatus int
+	}{
+		{name: "approve", approve: true, wantStatus: http.StatusOK},
+		{name: "reject", approve: false, wantStatus: http.StatusOK},
+		{name: "not found", approve: true, err: services.ErrToolCallNotFound, wantStatus: http.StatusNotFound},
+		{name: "already resolved", approve: false, err: services.ErrToolCallResolved, wantStatus: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotApprove bool
+			handler := NewToolCallHandler(&mockToolCallService{
+				resolveFunc: func(ctx context.Context, userID, sessionID uuid.UUID, toolCallID string, approve bool) (*services.ToolExecutionResult, error) {
+					gotApprove = approve
+					if tt.err != nil {
+						return nil, tt.err
+					}
+					return &services.ToolExecutionResult{Success: approve}, nil
+				},
+			})
+			req := withToolCallParams(httptest.NewRequest(http.MethodPost, "/api/v1/sessions/x/tool-calls/call_1", nil), uuid.New().String(), "call_1")
+			rec := httptest.NewRecorder()
+
+			if tt.approve {
+				handler.ApproveToolCall(rec, req)
+			} else {
+				handler.RejectToolCall(rec, req)
+			}
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if gotApprove != tt.approve {
+				t.Errorf("approve = %v, want %v", gotApprove, tt.approve)
+			}
+			if tt.err != nil {
+				return
+			}
+
+			var resp ToolCallResolutionResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			wantStatus := services.ToolCallStatusRejected
+			if tt.approve {
+				wantStatus = services.ToolCallStatusApproved
+			}
+			if resp.Status != wantStatus || resp.ToolCallID != "call_1" || resp.Result == nil {
+				t.Errorf("response = %+v", resp)
+			}
+		})
+	}
+
+	t.Run("invalid session", func(t *testing.T) {
+		handler := NewToolCallHandler(&mockToolCallService{})
+		req := withToolCallParams(httptest.NewRequest(http.MethodPost, "/", nil), "not-a-uuid", "call_1")
+		rec := httptest.NewRecorder()
+
+		handler.ApproveToolCall(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+}
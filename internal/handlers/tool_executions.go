@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/logging"
+	"github.com/agpt-go/chatbot-api/internal/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// ToolAuditHandler exposes the tool execution audit log and metrics
+type ToolAuditHandler struct {
+	audit        ToolAuditServicer
+	metricsToken string
+}
+
+// NewToolAuditHandler creates a new tool audit handler. The Prometheus
+// endpoint is only served when metricsToken is set.
+func NewToolAuditHandler(audit ToolAuditServicer, metricsToken string) *ToolAuditHandler {
+	return &ToolAuditHandler{
+		audit:        audit,
+		metricsToken: metricsToken,
+	}
+}
+
+// ListToolExecutions godoc
+// @Summary List tool executions
+// @Description List recorded tool calls, newest first. Arguments are stored redacted and truncated; arguments_hash is the SHA-256 of the arguments as sent. Page by passing the created_at of the last entry as before. Requires an admin.
+// @Tags Tool Audit
+// @Produce json
+// @Security BearerAuth
+// @Param tool query string false "Tool name"
+// @Param user_id query string false "User UUID"
+// @Param session_id query string false "Session UUID"
+// @Param success query bool false "Only successful or only failed calls"
+// @Param before query string false "Only calls before this RFC 3339 time"
+// @Param limit query int false "Maximum entries (default 50, max 200)"
+// @Success 200 {array} services.ToolExecutionView
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/tool-executions [get]
+func (h *ToolAuditHandler) ListToolExecutions(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := services.ToolExecutionFilter{ToolName: query.Get("tool")}
+
+	if v := query.Get("user_id"); v != "" {
+		userID, err := uuid.Parse(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid user ID")
+			return
+		}
+		filter.UserID = &userID
+	}
+	if v := query.Get("session_id"); v != "" {
+		sessionID, err := uuid.Parse(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid session ID")
+			return
+		}
+		filter.SessionID = &sessionID
+	}
+	if v := query.Get("success"); v != "" {
+		success, err := strconv.ParseBool(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid success filter")
+			return
+		}
+		filter.Success = &success
+	}
+	if v := query.Get("before"); v != "" {
+		before, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid before time, expected RFC 3339")
+			return
+		}
+		filter.Before = &before
+	}
+	if l := query.Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= services.MaxToolExecutionPageSize {
+			filter.Limit = parsed
+		}
+	}
+
+	executions, err := h.audit.ListExecutions(r.Context(), filter)
+	if err != nil {
+		logging.Error("failed to list tool executions", err)
+		writeError(w, http.StatusInternalServerError, "Failed to list tool executions")
+		return
+	}
+	writeJSON(w, http.StatusOK, executions)
+}
+
+// GetToolExecution godoc
+// @Summary Get tool execution
+// @Description Get one recorded tool call. Requires an admin.
+// @Tags Tool Audit
+// @Produce json
+// @Security BearerAuth
+// @Param executionID path string true "Tool execution UUID"
+// @Success 200 {object} services.ToolExecutionView
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/tool-executions/{executionID} [get]
+func (h *ToolAuditHandler) GetToolExecution(w http.ResponseWriter, r *http.Request) {
+	executionID, err := uuid.Parse(chi.URLParam(r, "executionID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid tool execution ID")
+		return
+	}
+
+	execution, err := h.audit.GetExecution(r.Context(), executionID)
+	if err != nil {
+		if errors.Is(err, services.ErrToolExecutionNotFound) {
+			writeError(w, http.StatusNotFound, "Tool execution not found")
+			return
+		}
+		logging.Error("failed to get tool execution", err)
+		writeError(w, http.StatusInternalServerError, "Failed to get tool execution")
+		return
+	}
+	writeJSON(w, http.StatusOK, execution)
+}
+
+// GetToolMetrics godoc
+// @Summary Get tool metrics
+// @Description Get call counts by outcome and call durations for each tool since the server started. Requires an admin.
+// @Tags Tool Audit
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} services.ToolMetrics
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /admin/tool-metrics [get]
+func (h *ToolAuditHandler) GetToolMetrics(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.audit.Metrics())
+}
+
+// ServeMetrics serves the tool metrics in the Prometheus text format to
+// scrapers presenting METRICS_TOKEN as a bearer token
+func (h *ToolAuditHandler) ServeMetrics(w http.ResponseWriter, r *http.Request) {
+	if h.metricsToken == "" {
+		http.NotFound(w, r)
+		return
+	}
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(h.metricsToken)) != 1 {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := h.audit.WritePrometheus(w); err != nil {
+		logging.Error("failed to write metrics", err)
+	}
+}
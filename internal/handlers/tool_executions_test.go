@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/agpt-go/chatbot-api/internal/services"
+	"github.com/google/uuid"
+)
+
+// mockToolAuditService implements ToolAuditServicer for testing
+type mockToolAuditService struct {
+	filter services.ToolExecutionFilter
+}
+
+func (m *mockToolAuditService) ListExecutions(ctx context.Context, filter services.ToolExecutionFilter) ([]services.ToolExecutionView, error) {
+	m.filter = filter
+	return []services.ToolExecutionView{}, nil
+}
+
+func (m *mockToolAuditService) GetExecution(ctx context.Context, id uuid.UUID) (*services.ToolExecutionView, error) {
+	return nil, services.ErrToolExecutionNotFound
+}
+
+func (m *mockToolAuditService) Metrics() services.ToolMetrics {
+	return services.ToolMetrics{}
+}
+
+func (m *mockToolAuditService) WritePrometheus(w io.Writer) error {
+	_, err := io.WriteString(w, "tool_audit_dropped_total 0\n")
+	return err
+}
+
+func TestListToolExecutions(t *testing.T) {
+	sessionID := uuid.New()
+
+	t.Run("filters", func(t *testing.T) {
+		audit := &mockToolAuditService{}
+		handler := NewToolAuditHandler(audit, "")
+		req := withTestUser(httptest.NewRequest(http.MethodGet,
+			"/api/v1/admin/tool-executions?tool=add_understanding&session_id="+sessionID.String()+"&success=false&before=2026-01-02T15:04:05Z&limit=10", nil))
+		rec := httptest.NewRecorder()
+
+		handler.ListToolExecutions(rec, req)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+		}
+		f := audit.filter
+		if f.ToolName != "add_understanding" || f.SessionID == nil || *f.SessionID != sessionID || f.UserID != nil {
+			t.Errorf("unexpected filter: %+v", f)
+		}
+		if f.Success == nil || *f.Success || f.Before == nil || f.Before.Year() != 2026 || f.Limit != 10 {
+			t.Errorf("unexpected filter: %+v", f)
+		}
+	})
+
+	for name, query := range map[string]string{
+		"user id": "user_id=nope",
+		"success": "success=maybe",
+		"before":  "before=yesterday",
+	} {
+		t.Run("invalid "+name, func(t *testing.T) {
+			handler := NewToolAuditHandler(&mockToolAuditService{}, "")
+			req := withTestUser(httptest.NewRequest(http.MethodGet, "/api/v1/admin/tool-executions?"+query, nil))
+			rec := httptest.NewRecorder()
+
+			handler.ListToolExecutions(rec, req)
+
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func TestServeMetrics(t *testing.T) {
+	tests := []struct {
+		name, token, header string
+		want                int
+	}{
+		{"disabled", "", "Bearer anything", http.StatusNotFound},
+		{"missing token", "s3cret", "", http.StatusUnauthorized},
+		{"wrong token", "s3cret", "Bearer guess", http.StatusUnauthorized},
+		{"valid token", "s3cret", "Bearer s3cret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewToolAuditHandler(&mockToolAuditService{}, tt.token)
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeMetrics(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/agpt-go/chatbot-api/internal/logging"
+	"github.com/agpt-go/chatbot-api/internal/middleware"
+	"github.com/agpt-go/chatbot-api/internal/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+)
+
+// WebhookToolHandler lets admins manage tools that call external HTTP endpoints
+type WebhookToolHandler struct {
+	webhookTools WebhookToolServicer
+	validate     Validator
+}
+
+// NewWebhookToolHandler creates a new webhook tool handler
+func NewWebhookToolHandler(webhookTools WebhookToolServicer) *WebhookToolHandler {
+	return &WebhookToolHandler{
+		webhookTools: webhookTools,
+		validate:     validator.New(),
+	}
+}
+
+// WebhookToolRequest defines a webhook tool
+type WebhookToolRequest struct {
+	Name            string                 `json:"name" validate:"required,max=64"`
+	Description     string                 `json:"description" validate:"required,max=1024"`
+	Parameters      map[string]interface{} `json:"parameters"`
+	URL             string                 `json:"url" validate:"required,url"`
+	Method          string                 `json:"method" validate:"omitempty,oneof=GET POST PUT PATCH DELETE"`
+	Headers         map[string]string      `json:"headers"`
+	TimeoutSeconds  int                    `json:"timeout_seconds" validate:"omitempty,min=1,max=60"`
+	ResponseMapping map[string]string      `json:"response_mapping"`
+	Enabled         *bool                  `json:"enabled"`
+}
+
+func (req WebhookToolRequest) toInput() services.WebhookToolInput {
+	enabled := true
+	if req.Enabled != nil {
+		enabled = *req.Enabled
+	}
+	return services.WebhookToolInput{
+		Name:            req.Name,
+		Description:     req.Description,
+		Parameters:      req.Parameters,
+		URL:             req.URL,
+		Method:          req.Method,
+		Headers:         req.Headers,
+		TimeoutSeconds:  req.TimeoutSeconds,
+		ResponseMapping: req.ResponseMapping,
+		Enabled:         enabled,
+	}
+}
+
+// writeWebhookToolError maps webhook tool service errors to responses
+func writeWebhookToolError(w http.ResponseWriter, err error, action string) {
+	var validationErr *services.WebhookToolValidationError
+	switch {
+	case errors.As(err, &validationErr):
+		writeValidationError(w, err)
+	case errors.Is(err, services.ErrWebhookToolNotFound):
+		writeError(w, http.StatusNotFound, "Webhook tool not found")
+	case errors.Is(err, services.ErrWebhookToolNameTaken):
+		writeError(w, http.StatusConflict, "A webhook tool with this name already exists")
+	default:
+		logging.Error("failed to "+action, err)
+		writeError(w, http.StatusInternalServerError, "Failed to "+action)
+	}
+}
+
+// ListWebhookTools godoc
+// @Summary List webhook tools
+// @Description List the admin-defined tools that call external HTTP endpoints. Requires an admin.
+// @Tags Webhook Tools
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} services.WebhookToolView
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/webhook-tools [get]
+func (h *WebhookToolHandler) ListWebhookTools(w http.ResponseWriter, r *http.Request) {
+	tools, err := h.webhookTools.List(r.Context())
+	if err != nil {
+		writeWebhookToolError(w, err, "list webhook tools")
+		return
+	}
+	writeJSON(w, http.StatusOK, tools)
+}
+
+// GetWebhookTool godoc
+// @Summary Get webhook tool
+// @Description Get one webhook tool definition. Requires an admin.
+// @Tags Webhook Tools
+// @Produce json
+// @Security BearerAuth
+// @Param toolID path string true "Webhook tool UUID"
+// @Success 200 {object} services.WebhookToolView
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/webhook-tools/{toolID} [get]
+func (h *WebhookToolHandler) GetWebhookTool(w http.ResponseWriter, r *http.Request) {
+	toolID, err := uuid.Parse(chi.URLParam(r, "toolID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid webhook tool ID")
+		return
+	}
+
+	tool, err := h.webhookTools.Get(r.Context(), toolID)
+	if err != nil {
+		writeWebhookToolError(w, err, "get webhook tool")
+		return
+	}
+	writeJSON(w, http.StatusOK, tool)
+}
+
+// CreateWebhookTool godoc
+// @Summary Create webhook tool
+// @Description Define a tool that calls an external HTTP endpoint. It is available to the assistant immediately. Header values can reference secrets as {{secret.NAME}}, read from the TOOL_SECRET_NAME environment variable. Requires an admin.
+// @Tags Webhook Tools
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body WebhookToolRequest true "Webhook tool definition"
+// @Success 201 {object} services.WebhookToolView
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /admin/webhook-tools [post]
+func (h *WebhookToolHandler) CreateWebhookTool(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	if userID == uuid.Nil {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	var req WebhookToolRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	tool, err := h.webhookTools.Create(r.Context(), userID, req.toInput())
+	if err != nil {
+		writeWebhookToolError(w, err, "create webhook tool")
+		return
+	}
+	writeJSON(w, http.StatusCreated, tool)
+}
+
+// UpdateWebhookTool godoc
+// @Summary Update webhook tool
+// @Description Replace a webhook tool definition. Requires an admin.
+// @Tags Webhook Tools
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param toolID path string true "Webhook tool UUID"
+// @Param request body WebhookToolRequest true "Webhook tool definition"
+// @Success 200 {object} services.WebhookToolView
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 409 {object} ErrorResponse
+// @Router /admin/webhook-tools/{toolID} [put]
+func (h *WebhookToolHandler) UpdateWebhookTool(w http.ResponseWriter, r *http.Request) {
+	toolID, err := uuid.Parse(chi.URLParam(r, "toolID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid webhook tool ID")
+		return
+	}
+
+	var req WebhookToolRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.validate.Struct(req); err != nil {
+		writeValidationError(w, err)
+		return
+	}
+
+	tool, err := h.webhookTools.Update(r.Context(), toolID, req.toInput())
+	if err != nil {
+		writeWebhookToolError(w, err, "update webhook tool")
+		return
+	}
+	writeJSON(w, http.StatusOK, tool)
+}
+
+// DeleteWebhookTool godoc
+// @Summary Delete webhook tool
+// @Description Delete a webhook tool; the assistant can no longer call it. Requires an admin.
+// @Tags Webhook Tools
+// @Security BearerAuth
+// @Param toolID path string true "Webhook tool UUID"
+// @Success 204 "No Content"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /admin/webhook-tools/{toolID} [delete]
+func (h *WebhookToolHandler) DeleteWebhookTool(w http.ResponseWriter, r *http.Request) {
+	toolID, err := uuid.Parse(chi.URLParam(r, "toolID"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid webhook tool ID")
+		return
+	}
+
+	if err := h.webhookTools.Delete(r.Context(), toolID); err != nil {
+		writeWebhookToolError(w, err, "delete webhook tool")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
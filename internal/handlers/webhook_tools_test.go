@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/agpt-go/chatbot-api/internal/services"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+// mockWebhookToolService implements WebhookToolServicer for testing
+type mockWebhookToolService struct {
+	createFunc func(ctx context.Context, userID uuid.UUID, input services.WebhookToolInput) (*services.WebhookToolView, error)
+	deleteFunc func(ctx context.Context, id uuid.UUID) error
+}
+
+func (m *mockWebhookToolService) List(ctx context.Context) ([]services.WebhookToolView, error) {
+	return []services.WebhookToolView{}, nil
+}
+
+func (m *mockWebhookToolService) Get(ctx context.Context, id uuid.UUID) (*services.WebhookToolView, error) {
+	return nil, services.ErrWebhookToolNotFound
+}
+
+func (m *mockWebhookToolService) Create(ctx context.Context, userID uuid.UUID, input services.WebhookToolInput) (*services.WebhookToolView, error) {
+	return m.createFunc(ctx, userID, input)
+}
+
+func (m *mockWebhookToolService) Update(ctx context.Context, id uuid.UUID, input services.WebhookToolInput) (*services.WebhookToolView, error) {
+	return nil, services.ErrWebhookToolNotFound
+}
+
+func (m *mockWebhookToolService) Delete(ctx context.Context, id uuid.UUID) error {
+	if m.deleteFunc != nil {
+		return m.deleteFunc(ctx, id)
+	}
+	return nil
+}
+
+func withWebhookToolID(r *http.Request, toolID string) *http.Request {
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("toolID", toolID)
+	return withTestUser(r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, rctx)))
+}
+
+func TestCreateWebhookTool(t *testing.T) {
+	body := `{"name":"lookup_order","description":"Look up an order","url":"https://crm.example.com/orders","method":"GET"}`
+
+	t.Run("created enabled by default", func(t *testing.T) {
+		var got services.WebhookToolInput
+		handler := NewWebhookToolHandler(&mockWebhookToolService{
+			createFunc: func(ctx context.Context, userID uuid.UUID, input services.WebhookToolInput) (*services.WebhookToolView, error) {
+				got = input
+				return &services.WebhookToolView{ID: uuid.New(), Name: input.Name}, nil
+			},
+		})
+		req := withTestUser(httptest.NewRequest(http.MethodPost, "/api/v1/admin/webhook-tools", strings.NewReader(body)))
+		rec := httptest.NewRecorder()
+
+		handler.CreateWebhookTool(rec, req)
+
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusCreated, rec.Body.String())
+		}
+		if !got.Enabled || got.Method != http.MethodGet || got.Name != "lookup_order" {
+			t.Errorf("unexpected input: %+v", got)
+		}
+	})
+
+	t.Run("service validation errors", func(t *testing.T) {
+		handler := NewWebhookToolHandler(&mockWebhookToolService{
+			createFunc: func(ctx context.Context, userID uuid.UUID, input services.WebhookToolInput) (*services.WebhookToolView, error) {
+				return nil, &services.WebhookToolValidationError{Fields: map[string]string{"url": "url must not point to a private network address"}}
+			},
+		})
+		req := withTestUser(httptest.NewRequest(http.MethodPost, "/api/v1/admin/webhook-tools", strings.NewReader(body)))
+		rec := httptest.NewRecorder()
+
+		handler.CreateWebhookTool(rec, req)
+
+		var resp ErrorResponse
+		json.Unmarshal(rec.Body.Bytes(), &resp)
+		if rec.Code != http.StatusBadRequest || resp.Details["url"] == "" {
+			t.Fatalf("status = %d, details = %v", rec.Code, resp.Details)
+		}
+	})
+
+	t.Run("name taken", func(t *testing.T) {
+		handler := NewWebhookToolHandler(&mockWebhookToolService{
+			createFunc: func(ctx context.Context, userID uuid.UUID, input services.WebhookToolInput) (*services.WebhookToolView, error) {
+				return nil, services.ErrWebhookToolNameTaken
+			},
+		})
+		req := withTestUser(httptest.NewRequest(http.MethodPost, "/api/v1/admin/webhook-tools", strings.NewReader(body)))
+		rec := httptest.NewRecorder()
+
+		handler.CreateWebhookTool(rec, req)
+
+		if rec.Code != http.StatusConflict {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusConflict)
+		}
+	})
+
+	t.Run("invalid request", func(t *testing.T) {
+		handler := NewWebhookToolHandler(&mockWebhookToolService{})
+		req := withTestUser(httptest.NewRequest(http.MethodPost, "/api/v1/admin/webhook-tools", strings.NewReader(`{"name":"x","url":"not a url"}`)))
+		rec := httptest.NewRecorder()
+
+		handler.CreateWebhookTool(rec, req)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestDeleteWebhookTool(t *testing.T) {
+	handler := NewWebhookToolHandler(&mockWebhookToolService{
+		deleteFunc: func(ctx context.Context, id uuid.UUID) error {
+			return services.ErrWebhookToolNotFound
+		},
+	})
+
+	rec := httptest.NewRecorder()
+	handler.DeleteWebhookTool(rec, withWebhookToolID(httptest.NewRequest(http.MethodDelete, "/", nil), uuid.New().String()))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.DeleteWebhookTool(rec, withWebhookToolID(httptest.NewRequest(http.MethodDelete, "/", nil), "not-a-uuid"))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,233 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	"github.com/agpt-go/chatbot-api/internal/logging"
+)
+
+// ErrClientClosed is returned for calls on a closed client or a server that went away
+var ErrClientClosed = errors.New("mcp client closed")
+
+// Transport carries JSON-RPC messages between a client and an MCP server
+type Transport interface {
+	// Start connects the transport. Incoming messages are passed to handle;
+	// closed is called once if the connection ends.
+	Start(ctx context.Context, handle func(*Message), closed func(error)) error
+	// Send delivers a message to the server
+	Send(ctx context.Context, msg *Message) error
+	// Close ends the connection
+	Close() error
+}
+
+// Client is an MCP client for a single server
+type Client struct {
+	transport      Transport
+	onNotification func(method string, params json.RawMessage)
+
+	nextID  atomic.Int64
+	mu      sync.Mutex
+	pending map[string]chan *Message
+	closed  bool
+}
+
+// NewClient creates a client. onNotification, if set, receives server notifications.
+func NewClient(transport Transport, onNotification func(method string, params json.RawMessage)) *Client {
+	return &Client{
+		transport:      transport,
+		onNotification: onNotification,
+		pending:        make(map[string]chan *Message),
+	}
+}
+
+// Connect starts the transport and performs the initialize handshake
+func (c *Client) Connect(ctx context.Context, clientInfo Implementation) (*InitializeResult, error) {
+	if err := c.transport.Start(ctx, c.dispatch, c.fail); err != nil {
+		return nil, err
+	}
+
+	var result InitializeResult
+	err := c.call(ctx, MethodInitialize, InitializeParams{
+		ProtocolVersion: ProtocolVersion,
+		Capabilities:    map[string]interface{}{},
+		ClientInfo:      clientInfo,
+	}, &result)
+	if err != nil {
+		return nil, fmt.Errorf("initialize: %w", err)
+	}
+
+	if err := c.notify(ctx, MethodInitialized, nil); err != nil {
+		return nil, fmt.Errorf("initialized: %w", err)
+	}
+	return &result, nil
+}
+
+// ListTools returns all tools of the server, following pagination
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var tools []Tool
+	cursor := ""
+	for {
+		var page ListToolsResult
+		if err := c.call(ctx, MethodToolsList, ListToolsParams{Cursor: cursor}, &page); err != nil {
+			return nil, err
+		}
+		tools = append(tools, page.Tools...)
+		if page.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// CallTool invokes a tool on the server
+func (c *Client) CallTool(ctx context.Context, name string, arguments json.RawMessage) (*CallToolResult, error) {
+	var result CallToolResult
+	if err := c.call(ctx, MethodToolsCall, CallToolParams{Name: name, Arguments: arguments}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Close shuts the client down and fails calls still waiting for a response
+func (c *Client) Close() error {
+	c.fail(ErrClientClosed)
+	return c.transport.Close()
+}
+
+func (c *Client) call(ctx context.Context, method string, params, result interface{}) error {
+	id := strconv.FormatInt(c.nextID.Add(1), 10)
+	msg, err := newRequest(id, method, params)
+	if err != nil {
+		return err
+	}
+
+	// Registered before sending: some transports deliver the response from within Send
+	ch := make(chan *Message, 1)
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return ErrClientClosed
+	}
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	if err := c.transport.Send(ctx, msg); err != nil {
+		return err
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return ErrClientClosed
+		}
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil {
+			return nil
+		}
+		return json.Unmarshal(resp.Result, result)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *Client) notify(ctx context.Context, method string, params interface{}) error {
+	msg := &Message{JSONRPC: jsonRPCVersion, Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		msg.Params = data
+	}
+	return c.transport.Send(ctx, msg)
+}
+
+// dispatch routes an incoming message to the call waiting for it
+func (c *Client) dispatch(msg *Message) {
+	switch {
+	case msg.IsResponse():
+		// Sent under the lock so fail can't close the channel in between;
+		// the channel is buffered and a duplicate response is dropped
+		c.mu.Lock()
+		if ch, ok := c.pending[responseKey(msg.ID)]; ok {
+			select {
+			case ch <- msg:
+			default:
+			}
+		}
+		c.mu.Unlock()
+
+	case msg.IsNotification():
+		if c.onNotification != nil {
+			c.onNotification(msg.Method, msg.Params)
+		}
+
+	case msg.IsRequest():
+		// Answered asynchronously so a transport's read loop is never blocked on its own write
+		go c.answer(msg)
+	}
+}
+
+// answer responds to requests from the server. Only ping is supported.
+func (c *Client) answer(req *Message) {
+	resp := NewErrorResponse(req.ID, CodeMethodNotFound, "method not found: "+req.Method)
+	if req.Method == MethodPing {
+		resp, _ = NewResponse(req.ID, struct{}{})
+	}
+	if err := c.transport.Send(context.Background(), resp); err != nil {
+		logging.Warn("failed to answer mcp server request", "error", err, "method", req.Method)
+	}
+}
+
+// fail closes the client, ending all pending calls
+func (c *Client) fail(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return
+	}
+	c.closed = true
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+	if err != nil && !errors.Is(err, ErrClientClosed) {
+		logging.Warn("mcp connection closed", "error", err)
+	}
+}
+
+func newRequest(id, method string, params interface{}) (*Message, error) {
+	msg := &Message{JSONRPC: jsonRPCVersion, ID: json.RawMessage(id), Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		msg.Params = data
+	}
+	return msg, nil
+}
+
+// responseKey normalizes a response ID; requests use numeric IDs but a server
+// may echo them back as strings
+func responseKey(id json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(id, &s); err == nil {
+		return s
+	}
+	return string(id)
+}
@@ -0,0 +1,176 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testServer is a minimal streamable HTTP MCP server. tools/list is answered
+// over SSE and everything else with JSON, so both response kinds are exercised.
+type testServer struct {
+	mu       sync.Mutex
+	sessions []string // Session header of each POST after initialize
+}
+
+func (s *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	case http.MethodDelete:
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var msg Message
+	if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if msg.Method != MethodInitialize {
+		s.mu.Lock()
+		s.sessions = append(s.sessions, r.Header.Get(SessionHeader))
+		s.mu.Unlock()
+	}
+
+	if msg.IsNotification() {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	var resp *Message
+	switch msg.Method {
+	case MethodInitialize:
+		w.Header().Set(SessionHeader, "session-1")
+		resp, _ = NewResponse(msg.ID, InitializeResult{
+			ProtocolVersion: ProtocolVersion,
+			Capabilities:    ServerCapabilities{Tools: &ToolsCapability{ListChanged: true}},
+			ServerInfo:      Implementation{Name: "test", Version: "1"},
+		})
+	case MethodToolsList:
+		var params ListToolsParams
+		json.Unmarshal(msg.Params, &params)
+		page := ListToolsResult{Tools: []Tool{{Name: "echo", InputSchema: map[string]interface{}{"type": "object"}}}, NextCursor: "2"}
+		if params.Cursor == "2" {
+			page = ListToolsResult{Tools: []Tool{{Name: "fail"}}}
+		}
+		resp, _ = NewResponse(msg.ID, page)
+		data, _ := json.Marshal(resp)
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+		return
+	case MethodToolsCall:
+		var params CallToolParams
+		json.Unmarshal(msg.Params, &params)
+		if params.Name != "echo" {
+			resp = NewErrorResponse(msg.ID, CodeInvalidParams, "unknown tool")
+			break
+		}
+		resp, _ = NewResponse(msg.ID, CallToolResult{Content: TextContent(string(params.Arguments))})
+	default:
+		resp = NewErrorResponse(msg.ID, CodeMethodNotFound, "method not found")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+func connectTestClient(t *testing.T) (*Client, *testServer) {
+	t.Helper()
+	server := &testServer{}
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+
+	client := NewClient(NewHTTPTransport(ts.URL, nil, ts.Client()), nil)
+	t.Cleanup(func() { client.Close() })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	info, err := client.Connect(ctx, Implementation{Name: "client", Version: "1"})
+	if err != nil {
+		t.Fatalf("Connect: %v", err)
+	}
+	if info.ServerInfo.Name != "test" || info.Capabilities.Tools == nil || !info.Capabilities.Tools.ListChanged {
+		t.Fatalf("unexpected initialize result: %+v", info)
+	}
+	return client, server
+}
+
+func TestHTTPClientListsAndCallsTools(t *testing.T) {
+	client, server := connectTestClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	if len(tools) != 2 || tools[0].Name != "echo" || tools[1].Name != "fail" {
+		t.Fatalf("expected both pages of tools, got %+v", tools)
+	}
+
+	result, err := client.CallTool(ctx, "echo", json.RawMessage(`{"text":"hi"}`))
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if len(result.Content) != 1 || result.Content[0].Text != `{"text":"hi"}` {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	_, err = client.CallTool(ctx, "missing", nil)
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) || rpcErr.Code != CodeInvalidParams {
+		t.Fatalf("expected invalid params error, got %v", err)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	for _, session := range server.sessions {
+		if session != "session-1" {
+			t.Fatalf("expected every request after initialize to carry the session, got %q", session)
+		}
+	}
+}
+
+func TestClientCloseFailsCalls(t *testing.T) {
+	client, _ := connectTestClient(t)
+	client.Close()
+
+	if _, err := client.ListTools(context.Background()); !errors.Is(err, ErrClientClosed) {
+		t.Fatalf("expected ErrClientClosed, got %v", err)
+	}
+}
+
+func TestReadEventStream(t *testing.T) {
+	stream := ": comment\n" +
+		"event: message\n" +
+		"data: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/tools/list_changed\"}\n\n" +
+		"data: [{\"jsonrpc\":\"2.0\",\"id\":1,\"result\":{}},\n" +
+		"data: {\"jsonrpc\":\"2.0\",\"id\":2,\"result\":{}}]\n\n" +
+		"data: not json\n\n"
+
+	var got []*Message
+	if err := readEventStream(strings.NewReader(stream), func(msg *Message) {
+		got = append(got, msg)
+	}); err != nil {
+		t.Fatalf("readEventStream: %v", err)
+	}
+
+	if len(got) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(got))
+	}
+	if !got[0].IsNotification() || got[0].Method != MethodToolsListChanged {
+		t.Fatalf("expected list_changed notification, got %+v", got[0])
+	}
+	if !got[1].IsResponse() || string(got[2].ID) != "2" {
+		t.Fatalf("expected batched responses, got %+v %+v", got[1], got[2])
+	}
+}
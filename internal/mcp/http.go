@@ -0,0 +1,293 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/logging"
+)
+
+// SessionHeader carries the session ID of the streamable HTTP transport
+const SessionHeader = "Mcp-Session-Id"
+
+// listenRetryDelay is the pause before reopening a dropped notification stream
+const listenRetryDelay = 5 * time.Second
+
+// HTTPTransport talks to an MCP server over the streamable HTTP transport:
+// every message is POSTed to one endpoint and answered with JSON or an SSE
+// stream, and server notifications arrive on a long-lived GET stream.
+type HTTPTransport struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+
+	mu        sync.Mutex
+	sessionID string
+	handle    func(*Message)
+	listening bool
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewHTTPTransport creates a transport for the endpoint. headers are sent with every request.
+func NewHTTPTransport(url string, headers map[string]string, client *http.Client) *HTTPTransport {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return &HTTPTransport{url: url, headers: headers, client: client, done: make(chan struct{})}
+}
+
+// Start prepares the transport; the connection is made by the first Send
+func (t *HTTPTransport) Start(ctx context.Context, handle func(*Message), closed func(error)) error {
+	t.mu.Lock()
+	t.handle = handle
+	t.mu.Unlock()
+
+	go func() {
+		<-t.done
+		closed(nil)
+	}()
+	return nil
+}
+
+// Send POSTs a message. Responses in the reply are passed to the message handler.
+func (t *HTTPTransport) Send(ctx context.Context, msg *Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	t.setHeaders(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	if sessionID := resp.Header.Get(SessionHeader); sessionID != "" {
+		t.mu.Lock()
+		t.sessionID = sessionID
+		t.mu.Unlock()
+	}
+
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("mcp server returned %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	// Once the session is initialized, open the stream for server notifications
+	if msg.Method == MethodInitialized {
+		t.startListening()
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	switch {
+	case resp.StatusCode == http.StatusAccepted || resp.ContentLength == 0:
+		resp.Body.Close()
+	case mediaType == "text/event-stream":
+		// The stream may stay open after the response, so it is read in the background
+		go func() {
+			defer resp.Body.Close()
+			if err := readEventStream(resp.Body, t.dispatch); err != nil && ctx.Err() == nil {
+				logging.Warn("mcp response stream failed", "error", err, "url", t.url)
+			}
+		}()
+	default:
+		defer resp.Body.Close()
+		return readJSONMessages(io.LimitReader(resp.Body, maxMessageSize), t.dispatch)
+	}
+	return nil
+}
+
+// Close ends the notification stream and the server session
+func (t *HTTPTransport) Close() error {
+	alreadyClosed := true
+	t.closeOnce.Do(func() {
+		alreadyClosed = false
+		close(t.done)
+	})
+
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+	if alreadyClosed || sessionID == "" {
+		return nil
+	}
+
+	ctx, done := context.WithTimeout(context.Background(), 5*time.Second)
+	defer done()
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, t.url, nil)
+	if err != nil {
+		return err
+	}
+	t.setHeaders(req)
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (t *HTTPTransport) setHeaders(req *http.Request) {
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	t.mu.Lock()
+	if t.sessionID != "" {
+		req.Header.Set(SessionHeader, t.sessionID)
+	}
+	t.mu.Unlock()
+}
+
+func (t *HTTPTransport) dispatch(msg *Message) {
+	t.mu.Lock()
+	handle := t.handle
+	t.mu.Unlock()
+	if handle != nil {
+		handle(msg)
+	}
+}
+
+// startListening opens the GET stream once; servers without one answer 405
+func (t *HTTPTransport) startListening() {
+	t.mu.Lock()
+	if t.listening {
+		t.mu.Unlock()
+		return
+	}
+	t.listening = true
+	t.mu.Unlock()
+
+	go func() {
+		for {
+			retry := t.listen()
+			if !retry {
+				return
+			}
+			select {
+			case <-time.After(listenRetryDelay):
+			case <-t.done:
+				return
+			}
+		}
+	}()
+}
+
+// listen reads server notifications until the stream ends. It reports whether to reconnect.
+func (t *HTTPTransport) listen() bool {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-t.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, t.url, nil)
+	if err != nil {
+		return false
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	t.setHeaders(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return ctx.Err() == nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusMethodNotAllowed {
+		return false
+	}
+	if resp.StatusCode >= 300 {
+		logging.Warn("mcp notification stream rejected", "status", resp.StatusCode, "url", t.url)
+		return false
+	}
+
+	if err := readEventStream(resp.Body, t.dispatch); err != nil && ctx.Err() == nil {
+		logging.Warn("mcp notification stream failed", "error", err, "url", t.url)
+	}
+	return ctx.Err() == nil
+}
+
+// readJSONMessages reads a single message or a batch
+func readJSONMessages(r io.Reader, handle func(*Message)) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil
+	}
+
+	if data[0] == '[' {
+		var batch []*Message
+		if err := json.Unmarshal(data, &batch); err != nil {
+			return err
+		}
+		for _, msg := range batch {
+			handle(msg)
+		}
+		return nil
+	}
+
+	var msg Message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return err
+	}
+	handle(&msg)
+	return nil
+}
+
+// readEventStream reads server-sent events, passing each data payload on as a message
+func readEventStream(r io.Reader, handle func(*Message)) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+
+	var data strings.Builder
+	flush := func() {
+		if data.Len() == 0 {
+			return
+		}
+		if err := readJSONMessages(strings.NewReader(data.String()), handle); err != nil {
+			logging.Warn("ignoring malformed mcp event", "error", err)
+		}
+		data.Reset()
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			flush()
+		case strings.HasPrefix(line, "data:"):
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.WriteString(strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+	}
+	flush()
+	return scanner.Err()
+}
@@ -0,0 +1,87 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+)
+
+// ErrUnknownTool is returned by a ToolProvider for a tool it doesn't offer
+var ErrUnknownTool = errors.New("unknown tool")
+
+// ToolProvider supplies the tools a server offers to one client
+type ToolProvider interface {
+	ListTools(ctx context.Context) ([]Tool, error)
+	// CallTool runs a tool. Tool failures belong in the result's IsError;
+	// an error fails the request itself.
+	CallTool(ctx context.Context, name string, arguments json.RawMessage) (*CallToolResult, error)
+}
+
+// Server answers MCP requests with the tools of a ToolProvider. It keeps no
+// session state, so one Server can serve every client.
+type Server struct {
+	info         Implementation
+	instructions string
+}
+
+// NewServer creates a server that identifies itself with info
+func NewServer(info Implementation, instructions string) *Server {
+	return &Server{info: info, instructions: instructions}
+}
+
+// Handle answers one message. Notifications and responses get no answer and return nil.
+func (s *Server) Handle(ctx context.Context, tools ToolProvider, msg *Message) *Message {
+	if msg.JSONRPC != jsonRPCVersion {
+		if len(msg.ID) == 0 {
+			return nil
+		}
+		return NewErrorResponse(msg.ID, CodeInvalidRequest, "jsonrpc must be \"2.0\"")
+	}
+	if !msg.IsRequest() {
+		return nil
+	}
+
+	var result interface{}
+	switch msg.Method {
+	case MethodInitialize:
+		result = InitializeResult{
+			ProtocolVersion: ProtocolVersion,
+			Capabilities:    ServerCapabilities{Tools: &ToolsCapability{}},
+			ServerInfo:      s.info,
+			Instructions:    s.instructions,
+		}
+
+	case MethodPing:
+		result = struct{}{}
+
+	case MethodToolsList:
+		list, err := tools.ListTools(ctx)
+		if err != nil {
+			return NewErrorResponse(msg.ID, CodeInternalError, "failed to list tools")
+		}
+		result = ListToolsResult{Tools: list}
+
+	case MethodToolsCall:
+		var params CallToolParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil || params.Name == "" {
+			return NewErrorResponse(msg.ID, CodeInvalidParams, "params must name a tool")
+		}
+		callResult, err := tools.CallTool(ctx, params.Name, params.Arguments)
+		if errors.Is(err, ErrUnknownTool) {
+			return NewErrorResponse(msg.ID, CodeInvalidParams, "unknown tool: "+params.Name)
+		}
+		if err != nil {
+			return NewErrorResponse(msg.ID, CodeInternalError, "failed to call tool")
+		}
+		result = callResult
+
+	default:
+		return NewErrorResponse(msg.ID, CodeMethodNotFound, "method not found: "+msg.Method)
+	}
+
+	resp, err := NewResponse(msg.ID, result)
+	if err != nil {
+		return NewErrorResponse(msg.ID, CodeInternalError, "failed to encode result")
+	}
+	return resp
+}
@@ -0,0 +1,128 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/logging"
+)
+
+// maxMessageSize caps a single message read from a server
+const maxMessageSize = 10 << 20
+
+// stdioShutdownGrace is how long a server gets to exit after its stdin is closed
+const stdioShutdownGrace = 5 * time.Second
+
+// StdioTransport runs an MCP server as a subprocess and exchanges
+// newline-delimited JSON-RPC messages over its stdin and stdout
+type StdioTransport struct {
+	command string
+	args    []string
+	env     []string
+
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	writeMu sync.Mutex
+	exited  chan struct{}
+}
+
+// NewStdioTransport creates a transport for the given command. env entries
+// (KEY=value) are added to the server's environment.
+func NewStdioTransport(command string, args, env []string) *StdioTransport {
+	return &StdioTransport{command: command, args: args, env: env}
+}
+
+// Start launches the server process. The process lives until Close, not until ctx ends.
+func (t *StdioTransport) Start(ctx context.Context, handle func(*Message), closed func(error)) error {
+	cmd := exec.Command(t.command, t.args...)
+	cmd.Env = append(os.Environ(), t.env...)
+	cmd.Stderr = os.Stderr
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %s: %w", t.command, err)
+	}
+
+	t.cmd = cmd
+	t.stdin = stdin
+	t.exited = make(chan struct{})
+
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+		for scanner.Scan() {
+			var msg Message
+			if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+				logging.Warn("ignoring malformed mcp message", "error", err, "command", t.command)
+				continue
+			}
+			handle(&msg)
+		}
+
+		err := scanner.Err()
+		if waitErr := cmd.Wait(); err == nil {
+			err = waitErr
+		}
+		if err == nil {
+			err = errors.New("server exited")
+		}
+		close(t.exited)
+		closed(fmt.Errorf("%s: %w", t.command, err))
+	}()
+
+	return nil
+}
+
+// Send writes one message as a line to the server's stdin
+func (t *StdioTransport) Send(ctx context.Context, msg *Message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	if t.stdin == nil {
+		return ErrClientClosed
+	}
+	_, err = t.stdin.Write(append(data, '\n'))
+	return err
+}
+
+// Close closes the server's stdin and kills it if it doesn't exit in time
+func (t *StdioTransport) Close() error {
+	t.writeMu.Lock()
+	stdin := t.stdin
+	t.stdin = nil
+	t.writeMu.Unlock()
+
+	if stdin == nil {
+		return nil
+	}
+	stdin.Close()
+
+	select {
+	case <-t.exited:
+	case <-time.After(stdioShutdownGrace):
+		if err := t.cmd.Process.Kill(); err != nil {
+			return err
+		}
+		<-t.exited
+	}
+	return nil
+}
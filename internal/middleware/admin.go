@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+// RequireAdmin only lets the given admin users through. It must run after
+// RequireAuth, which puts the user ID in the context.
+func RequireAdmin(adminIDs []uuid.UUID) func(http.Handler) http.Handler {
+	admins := make(map[uuid.UUID]bool, len(adminIDs))
+	for _, id := range adminIDs {
+		admins[id] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID := GetUserID(r.Context())
+			if userID == uuid.Nil {
+				http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+				return
+			}
+			if !admins[userID] {
+				http.Error(w, `{"error":"Admin access required"}`, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -2,9 +2,11 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strings"
 
+	"github.com/agpt-go/chatbot-api/internal/logging"
 	"github.com/agpt-go/chatbot-api/internal/services"
 	"github.com/google/uuid"
 )
@@ -12,8 +14,12 @@ import (
 type contextKey string
 
 const (
-	UserIDKey contextKey = "userID"
-	EmailKey  contextKey = "email"
+	UserIDKey    contextKey = "userID"
+	EmailKey     contextKey = "email"
+	SessionIDKey contextKey = "sessionID"
+	// APIKeyScopesKey holds the scopes of the API key a request was made
+	// with. It is unset for requests made with an access token.
+	APIKeyScopesKey contextKey = "apiKeyScopes"
 )
 
 type AuthMiddleware struct {
@@ -26,7 +32,8 @@ func NewAuthMiddleware(authService *services.AuthService) *AuthMiddleware {
 	}
 }
 
-// RequireAuth validates the JWT token and adds user info to context
+// RequireAuth validates the JWT token or API key, rejects revoked tokens and
+// adds user info to context
 func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
@@ -42,21 +49,46 @@ func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 		}
 
 		token := parts[1]
+		if strings.HasPrefix(token, services.APIKeyPrefix) {
+			principal, err := m.authService.ValidateAPIKey(r.Context(), token)
+			if err != nil {
+				if errors.Is(err, services.ErrInvalidAPIKey) {
+					http.Error(w, `{"error":"Invalid or expired API key"}`, http.StatusUnauthorized)
+					return
+				}
+				logging.Error("failed to check api key", err)
+				http.Error(w, `{"error":"Failed to check API key"}`, http.StatusServiceUnavailable)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(withAPIKey(r.Context(), principal)))
+			return
+		}
+
 		claims, err := m.authService.ValidateAccessToken(token)
 		if err != nil {
 			http.Error(w, `{"error":"Invalid or expired token"}`, http.StatusUnauthorized)
 			return
 		}
+		if err := m.authService.CheckAccessToken(r.Context(), claims); err != nil {
+			if errors.Is(err, services.ErrTokenRevoked) {
+				http.Error(w, `{"error":"Token has been revoked"}`, http.StatusUnauthorized)
+				return
+			}
+			logging.Error("failed to check access token revocation", err, "userID", claims.UserID.String())
+			http.Error(w, `{"error":"Failed to check token"}`, http.StatusServiceUnavailable)
+			return
+		}
 
 		// Add user info to context
 		ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
 		ctx = context.WithValue(ctx, EmailKey, claims.Email)
+		ctx = context.WithValue(ctx, SessionIDKey, claims.SessionID)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-// OptionalAuth validates the JWT token if present, but doesn't require it
+// OptionalAuth validates the JWT token or API key if present, but doesn't require it
 func (m *AuthMiddleware) OptionalAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
@@ -72,8 +104,16 @@ func (m *AuthMiddleware) OptionalAuth(next http.Handler) http.Handler {
 		}
 
 		token := parts[1]
+		if strings.HasPrefix(token, services.APIKeyPrefix) {
+			if principal, err := m.authService.ValidateAPIKey(r.Context(), token); err == nil {
+				r = r.WithContext(withAPIKey(r.Context(), principal))
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		claims, err := m.authService.ValidateAccessToken(token)
-		if err != nil {
+		if err != nil || m.authService.CheckAccessToken(r.Context(), claims) != nil {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -81,11 +121,20 @@ func (m *AuthMiddleware) OptionalAuth(next http.Handler) http.Handler {
 		// Add user info to context
 		ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
 		ctx = context.WithValue(ctx, EmailKey, claims.Email)
+		ctx = context.WithValue(ctx, SessionIDKey, claims.SessionID)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// withAPIKey adds the user an API key acts as to the context. Requests made
+// with a key belong to no device.
+func withAPIKey(ctx context.Context, principal *services.APIKeyPrincipal) context.Context {
+	ctx = context.WithValue(ctx, UserIDKey, principal.UserID)
+	ctx = context.WithValue(ctx, EmailKey, principal.Email)
+	return context.WithValue(ctx, APIKeyScopesKey, principal.Scopes)
+}
+
 // GetUserID retrieves the user ID from context
 func GetUserID(ctx context.Context) uuid.UUID {
 	userID, ok := ctx.Value(UserIDKey).(uuid.UUID)
@@ -103,3 +152,13 @@ func GetEmail(ctx context.Context) string {
 	}
 	return email
 }
+
+// GetSessionID retrieves the ID of the device the access token was issued to.
+// It is uuid.Nil for tokens issued before devices were tracked.
+func GetSessionID(ctx context.Context) uuid.UUID {
+	sessionID, ok := ctx.Value(SessionIDKey).(uuid.UUID)
+	if !ok {
+		return uuid.Nil
+	}
+	return sessionID
+}
@@ -175,6 +175,17 @@ func TestGetUserID(t *testing.T) {
 	})
 }
 
+func TestGetSessionID(t *testing.T) {
+	expectedID := uuid.New()
+	ctx := context.WithValue(context.Background(), SessionIDKey, expectedID)
+	if got := GetSessionID(ctx); got != expectedID {
+		t.Errorf("GetSessionID() = %v, want %v", got, expectedID)
+	}
+	if got := GetSessionID(context.Background()); got != uuid.Nil {
+		t.Errorf("GetSessionID() = %v, want uuid.Nil", got)
+	}
+}
+
 func TestGetEmail(t *testing.T) {
 	t.Run("with email in context", func(t *testing.T) {
 		expectedEmail := "test@example.com"
@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/agpt-go/chatbot-api/internal/logging"
+	"github.com/google/uuid"
+)
+
+// RequireMFA blocks users who must enable MFA until they have. It must run
+// after RequireAuth, which puts the user ID in the context.
+func RequireMFA(isSatisfied func(ctx context.Context, userID uuid.UUID) (bool, error)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID := GetUserID(r.Context())
+			if userID == uuid.Nil {
+				http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+				return
+			}
+
+			satisfied, err := isSatisfied(r.Context(), userID)
+			if err != nil {
+				logging.Error("failed to check mfa enrollment", err, "userID", userID.String())
+				http.Error(w, `{"error":"Failed to check MFA enrollment"}`, http.StatusInternalServerError)
+				return
+			}
+			if !satisfied {
+				http.Error(w, `{"error":"MFA enrollment required"}`, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"slices"
+)
+
+// GetAPIKeyScopes retrieves the scopes of the API key the request was made
+// with. ok is false for requests made with an access token, which may do
+// anything the user can.
+func GetAPIKeyScopes(ctx context.Context) (scopes []string, ok bool) {
+	scopes, ok = ctx.Value(APIKeyScopesKey).([]string)
+	return scopes, ok
+}
+
+// RequireScopes lets requests made with an API key through only if the key
+// has readScope, for GET and HEAD requests, or writeScope for the others.
+// Requests made with an access token always pass. It must run after
+// RequireAuth.
+func RequireScopes(readScope, writeScope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scopes, ok := GetAPIKeyScopes(r.Context())
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			scope := writeScope
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				scope = readScope
+			}
+			if !slices.Contains(scopes, scope) {
+				http.Error(w, `{"error":"API key is missing the `+scope+` scope"}`, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// DenyAPIKeys rejects requests made with an API key, for endpoints that need
+// a signed-in user such as account settings. It must run after RequireAuth.
+func DenyAPIKeys(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := GetAPIKeyScopes(r.Context()); ok {
+			http.Error(w, `{"error":"API keys can't be used for this endpoint"}`, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
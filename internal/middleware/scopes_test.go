@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequireScopes(t *testing.T) {
+	handler := RequireScopes("chat:read", "chat:write")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name   string
+		method string
+		scopes []string // nil for an access token
+		want   int
+	}{
+		{"access token", http.MethodPost, nil, http.StatusOK},
+		{"read with read scope", http.MethodGet, []string{"chat:read"}, http.StatusOK},
+		{"write with write scope", http.MethodPost, []string{"chat:write"}, http.StatusOK},
+		{"write with read scope", http.MethodPost, []string{"chat:read"}, http.StatusForbidden},
+		{"read with write scope", http.MethodGet, []string{"chat:write"}, http.StatusForbidden},
+		{"other scope", http.MethodDelete, []string{"reports:write"}, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/v1/sessions", nil)
+			if tt.scopes != nil {
+				req = req.WithContext(context.WithValue(req.Context(), APIKeyScopesKey, tt.scopes))
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestDenyAPIKeys(t *testing.T) {
+	handler := DenyAPIKeys(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/me", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("access token: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	req = req.WithContext(context.WithValue(req.Context(), APIKeyScopesKey, []string{"chat:read"}))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("api key: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
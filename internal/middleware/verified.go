@@ -0,0 +1,35 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/agpt-go/chatbot-api/internal/logging"
+	"github.com/google/uuid"
+)
+
+// RequireVerifiedEmail only lets users who have verified their email address
+// through. It must run after RequireAuth, which puts the user ID in the context.
+func RequireVerifiedEmail(isVerified func(ctx context.Context, userID uuid.UUID) (bool, error)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID := GetUserID(r.Context())
+			if userID == uuid.Nil {
+				http.Error(w, `{"error":"Unauthorized"}`, http.StatusUnauthorized)
+				return
+			}
+
+			verified, err := isVerified(r.Context(), userID)
+			if err != nil {
+				logging.Error("failed to check email verification", err, "userID", userID.String())
+				http.Error(w, `{"error":"Failed to check email verification"}`, http.StatusInternalServerError)
+				return
+			}
+			if !verified {
+				http.Error(w, `{"error":"Email address not verified"}`, http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRequireVerifiedEmail(t *testing.T) {
+	verifiedID, unverifiedID, missingID := uuid.New(), uuid.New(), uuid.New()
+	handler := RequireVerifiedEmail(func(ctx context.Context, userID uuid.UUID) (bool, error) {
+		if userID == missingID {
+			return false, errors.New("user not found")
+		}
+		return userID == verifiedID, nil
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name   string
+		userID uuid.UUID
+		want   int
+	}{
+		{"verified", verifiedID, http.StatusOK},
+		{"unverified", unverifiedID, http.StatusForbidden},
+		{"lookup fails", missingID, http.StatusInternalServerError},
+		{"anonymous", uuid.Nil, http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/sessions", nil)
+			if tt.userID != uuid.Nil {
+				req = req.WithContext(context.WithValue(req.Context(), UserIDKey, tt.userID))
+			}
+			rec := httptest.NewRecorder()
+
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
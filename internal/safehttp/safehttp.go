@@ -0,0 +1,136 @@
+// Package safehttp provides an HTTP client for requests to URLs that users or
+// admins supply. It refuses to connect to loopback, private, link-local and
+// other non-public addresses, so such URLs can't be used to reach internal
+// services or cloud metadata endpoints (SSRF).
+package safehttp
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrBlockedAddress is returned when a request would connect to a non-public address
+var ErrBlockedAddress = errors.New("destination address is not allowed")
+
+// maxRedirects is the number of redirects a client follows
+const maxRedirects = 3
+
+// Ranges that aren't covered by the net.IP predicates but are not publicly routable
+var blockedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",       // "This" network
+	"100.64.0.0/10",   // Carrier-grade NAT
+	"192.0.0.0/24",    // IETF protocol assignments
+	"192.0.2.0/24",    // Documentation
+	"198.18.0.0/15",   // Benchmarking
+	"198.51.100.0/24", // Documentation
+	"203.0.113.0/24",  // Documentation
+	"240.0.0.0/4",     // Reserved
+	"64:ff9b::/96",    // NAT64, can embed private IPv4 addresses
+	"2001:db8::/32",   // Documentation
+)
+
+// Options configures a client
+type Options struct {
+	// AllowPrivate permits non-public destinations, for development against local services
+	AllowPrivate bool
+	// Timeout limits a whole request including reading the body; 0 means none
+	Timeout time.Duration
+}
+
+// NewClient returns a client that only connects to public addresses. The
+// check runs on the resolved address of every connection, including after
+// redirects, so DNS names that resolve to internal addresses are refused too.
+func NewClient(opts Options) *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	if !opts.AllowPrivate {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !IsPublicIP(ip) {
+				return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+			}
+			return nil
+		}
+	}
+
+	transport := &http.Transport{
+		// No proxy: a proxy would make the connection on our behalf, bypassing the check
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   opts.Timeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return CheckURL(req.URL)
+		},
+	}
+}
+
+// CheckURL validates the parts of a URL that can be checked without
+// connecting: the scheme must be http or https and it needs a host but no
+// credentials. Destination addresses are checked when the client connects.
+func CheckURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return errors.New("URL has no host")
+	}
+	if u.User != nil {
+		return errors.New("URL must not contain credentials")
+	}
+	return nil
+}
+
+// IsPublicIP reports whether ip is a publicly routable unicast address
+func IsPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		if ip.Equal(net.IPv4bcast) {
+			return false
+		}
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
@@ -0,0 +1,68 @@
+package safehttp
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestIsPublicIP(t *testing.T) {
+	tests := map[string]bool{
+		"8.8.8.8":         true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"10.1.2.3":        false,
+		"172.16.0.1":      false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false, // Cloud metadata
+		"100.64.0.1":      false,
+		"0.0.0.0":         false,
+		"::1":             false,
+		"fd00::1":         false,
+		"fe80::1":         false,
+		"::ffff:10.0.0.1": false,
+		"64:ff9b::a00:1":  false,
+	}
+	for addr, want := range tests {
+		if got := IsPublicIP(net.ParseIP(addr)); got != want {
+			t.Errorf("IsPublicIP(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	for raw, ok := range map[string]bool{
+		"https://example.com/hook":      true,
+		"http://example.com":            true,
+		"file:///etc/passwd":            false,
+		"gopher://example.com":          false,
+		"https://user:pw@example.com/x": false,
+		"https:///path":                 false,
+	} {
+		u, _ := url.Parse(raw)
+		if err := CheckURL(u); (err == nil) != ok {
+			t.Errorf("CheckURL(%s) error = %v, want ok = %v", raw, err, ok)
+		}
+	}
+}
+
+func TestClientBlocksPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	_, err := NewClient(Options{}).Get(server.URL)
+	if !errors.Is(err, ErrBlockedAddress) {
+		t.Fatalf("expected loopback request to be blocked, got %v", err)
+	}
+
+	resp, err := NewClient(Options{AllowPrivate: true}).Get(server.URL)
+	if err != nil {
+		t.Fatalf("expected request to be allowed with AllowPrivate, got %v", err)
+	}
+	resp.Body.Close()
+}
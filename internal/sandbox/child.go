@@ -0,0 +1,82 @@
+package sandbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+	"sync"
+	"time"
+)
+
+const (
+	maxRequestBytes     = 256 << 20
+	memoryCheckInterval = 5 * time.Millisecond
+	heapMetric          = "/memory/classes/heap/objects:bytes"
+)
+
+// RunIfChild runs the sandboxed script and exits when the process was started
+// by a Runner. Call it at the start of main, before any other initialization,
+// so the child never touches configuration, the database or the network.
+func RunIfChild() {
+	if os.Getenv(childEnv) != "1" {
+		return
+	}
+	runtime.GOMAXPROCS(2)
+
+	reporter := &childReporter{out: os.Stdout}
+
+	var req request
+	if err := json.NewDecoder(io.LimitReader(os.Stdin, maxRequestBytes)).Decode(&req); err != nil {
+		reporter.finish(&Result{Error: fmt.Sprintf("invalid analysis request: %v", err)})
+	}
+	limits := req.Limits.withDefaults()
+
+	applyProcessLimits(limits)
+	debug.SetMemoryLimit(limits.MemoryBytes)
+	go watchMemory(limits.MemoryBytes, reporter)
+
+	reporter.finish(execute(req.Code, req.Files, limits))
+}
+
+// childReporter writes the one result the parent reads and exits. Whichever of
+// the script and the memory watchdog finishes first wins.
+type childReporter struct {
+	out  io.Writer
+	once sync.Once
+}
+
+func (r *childReporter) finish(result *Result) {
+	r.once.Do(func() {
+		json.NewEncoder(r.out).Encode(result)
+		os.Exit(0)
+	})
+	// The other goroutine is exiting the process
+	select {}
+}
+
+// watchMemory stops the run once the live heap exceeds limit. The Go memory
+// limit makes the collector work harder as usage nears it, so only a script
+// that really holds that much data is stopped.
+func watchMemory(limit int64, reporter *childReporter) {
+	sample := []metrics.Sample{{Name: heapMetric}}
+	ticker := time.NewTicker(memoryCheckInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		metrics.Read(sample)
+		if sample[0].Value.Kind() != metrics.KindUint64 {
+			return
+		}
+		if used := sample[0].Value.Uint64(); used > uint64(limit) {
+			runtime.GC()
+			metrics.Read(sample)
+			if sample[0].Value.Uint64() > uint64(limit) {
+				reporter.finish(&Result{Error: fmt.Sprintf("memory limit exceeded: the analysis used more than %d MB", limit>>20)})
+			}
+		}
+	}
+}
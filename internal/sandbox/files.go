@@ -0,0 +1,262 @@
+package sandbox
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+	"go.starlark.net/starlark"
+)
+
+// maxUnzippedBytes bounds how far a spreadsheet may expand when unzipped, so
+// a zip bomb fails instead of exhausting memory
+const maxUnzippedBytes = 128 << 20
+
+// numberPattern matches plain numbers, optionally with thousands separators
+var numberPattern = regexp.MustCompile(`^[-+]?(\d{1,3}(,\d{3})+|\d*)(\.\d+)?([eE][-+]?\d+)?$`)
+
+// fileSet gives a script read access to the files of one run. Files are
+// parsed on first use.
+type fileSet struct {
+	data        map[string][]byte
+	names       []string
+	spreadsheet map[string]*excelize.File
+}
+
+func newFileSet(files []File) *fileSet {
+	fs := &fileSet{
+		data:        make(map[string][]byte, len(files)),
+		spreadsheet: make(map[string]*excelize.File),
+	}
+	for _, f := range files {
+		fs.data[f.Name] = f.Data
+		fs.names = append(fs.names, f.Name)
+	}
+	sort.Strings(fs.names)
+	return fs
+}
+
+func (fs *fileSet) get(name string) ([]byte, error) {
+	data, ok := fs.data[name]
+	if !ok {
+		if len(fs.names) == 0 {
+			return nil, fmt.Errorf("no file named %q; no files are attached to this chat", name)
+		}
+		return nil, fmt.Errorf("no file named %q; available files: %s", name, strings.Join(fs.names, ", "))
+	}
+	return data, nil
+}
+
+func fileKind(name string) string {
+	return strings.ToLower(path.Ext(name))
+}
+
+func (fs *fileSet) text(name string) (string, error) {
+	data, err := fs.get(name)
+	if err != nil {
+		return "", err
+	}
+	if fileKind(name) == ".xlsx" {
+		return "", fmt.Errorf("%s is a spreadsheet; use read_table or read_rows", name)
+	}
+	if !utf8.Valid(data) {
+		return "", fmt.Errorf("%s is not a UTF-8 text file", name)
+	}
+	return string(bytes.TrimPrefix(data, []byte("\ufeff"))), nil
+}
+
+func (fs *fileSet) workbook(name string) (*excelize.File, error) {
+	if f, ok := fs.spreadsheet[name]; ok {
+		return f, nil
+	}
+	data, err := fs.get(name)
+	if err != nil {
+		return nil, err
+	}
+	f, err := excelize.OpenReader(bytes.NewReader(data), excelize.Options{
+		UnzipSizeLimit:    maxUnzippedBytes,
+		UnzipXMLSizeLimit: maxUnzippedBytes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not open spreadsheet %s: %v", name, err)
+	}
+	fs.spreadsheet[name] = f
+	return f, nil
+}
+
+// sheets lists the sheets of a spreadsheet; delimited files have one sheet
+// named after the file
+func (fs *fileSet) sheets(name string) ([]string, error) {
+	if fileKind(name) != ".xlsx" {
+		if _, err := fs.get(name); err != nil {
+			return nil, err
+		}
+		return []string{name}, nil
+	}
+	f, err := fs.workbook(name)
+	if err != nil {
+		return nil, err
+	}
+	return f.GetSheetList(), nil
+}
+
+// rows returns the cells of a CSV, TSV or spreadsheet file. sheet selects a
+// spreadsheet sheet; empty means the first.
+func (fs *fileSet) rows(name, sheet string) ([][]string, error) {
+	switch fileKind(name) {
+	case ".csv", ".tsv":
+		text, err := fs.text(name)
+		if err != nil {
+			return nil, err
+		}
+		r := csv.NewReader(strings.NewReader(text))
+		if fileKind(name) == ".tsv" {
+			r.Comma = '\t'
+		}
+		r.FieldsPerRecord = -1
+		r.LazyQuotes = true
+		rows, err := r.ReadAll()
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %s: %v", name, err)
+		}
+		return rows, nil
+
+	case ".xlsx":
+		f, err := fs.workbook(name)
+		if err != nil {
+			return nil, err
+		}
+		if sheet == "" {
+			sheet = f.GetSheetName(0)
+		}
+		rows, err := f.GetRows(sheet, excelize.Options{RawCellValue: true})
+		if err != nil {
+			return nil, fmt.Errorf("could not read sheet %q of %s: %v", sheet, name, err)
+		}
+		return rows, nil
+
+	default:
+		if _, err := fs.get(name); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s is not a table; read_table and read_rows support .csv, .tsv and .xlsx files, use read_text for other files", name)
+	}
+}
+
+// cellValue converts a cell to a number when it looks like one. Values with
+// leading zeros, such as IDs and postal codes, stay strings.
+func cellValue(cell string) starlark.Value {
+	cell = strings.TrimSpace(cell)
+	if cell == "" {
+		return starlark.None
+	}
+	if !numberPattern.MatchString(cell) {
+		return starlark.String(cell)
+	}
+	digits := strings.TrimLeft(cell, "+-")
+	if len(digits) > 1 && digits[0] == '0' && digits[1] != '.' {
+		return starlark.String(cell)
+	}
+	plain := strings.ReplaceAll(cell, ",", "")
+	if i, err := strconv.ParseInt(plain, 10, 64); err == nil {
+		return starlark.MakeInt64(i)
+	}
+	if f, err := strconv.ParseFloat(plain, 64); err == nil {
+		return starlark.Float(f)
+	}
+	return starlark.String(cell)
+}
+
+// tableRows converts rows to lists of cell values, skipping empty rows
+func tableRows(rows [][]string) []*starlark.List {
+	var out []*starlark.List
+	for _, row := range rows {
+		if isEmptyRow(row) {
+			continue
+		}
+		values := make([]starlark.Value, len(row))
+		for i, cell := range row {
+			values[i] = cellValue(cell)
+		}
+		out = append(out, starlark.NewList(values))
+	}
+	return out
+}
+
+func isEmptyRow(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// tableRecords converts rows to dicts keyed by the first non-empty row's headers
+func tableRecords(rows [][]string) (*starlark.List, error) {
+	for len(rows) > 0 && isEmptyRow(rows[0]) {
+		rows = rows[1:]
+	}
+	if len(rows) == 0 {
+		return starlark.NewList(nil), nil
+	}
+	columns := headerNames(rows[0], maxWidth(rows))
+
+	data := tableRows(rows[1:])
+	records := make([]starlark.Value, 0, len(data))
+	for _, row := range data {
+		record := starlark.NewDict(len(columns))
+		for i, column := range columns {
+			var value starlark.Value = starlark.None
+			if i < row.Len() {
+				value = row.Index(i)
+			}
+			if err := record.SetKey(starlark.String(column), value); err != nil {
+				return nil, err
+			}
+		}
+		records = append(records, record)
+	}
+	return starlark.NewList(records), nil
+}
+
+func maxWidth(rows [][]string) int {
+	width := 0
+	for _, row := range rows {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+	return width
+}
+
+// headerNames names every column, filling in blank headers and making
+// duplicates unique
+func headerNames(header []string, width int) []string {
+	names := make([]string, width)
+	seen := make(map[string]int, width)
+	for i := range names {
+		name := ""
+		if i < len(header) {
+			name = strings.TrimSpace(header[i])
+		}
+		if name == "" {
+			name = fmt.Sprintf("column_%d", i+1)
+		}
+		if n := seen[name]; n > 0 {
+			seen[name] = n + 1
+			name = fmt.Sprintf("%s_%d", name, n+1)
+		} else {
+			seen[name] = 1
+		}
+		names[i] = name
+	}
+	return names
+}
@@ -0,0 +1,383 @@
+package sandbox
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	starlarkmath "go.starlark.net/lib/math"
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkjson"
+	"go.starlark.net/syntax"
+)
+
+// scriptName is the file name scripts are reported under in errors
+const scriptName = "analysis.star"
+
+// fileOptions relaxes Starlark's configuration-language restrictions so
+// scripts read like ordinary Python
+var fileOptions = &syntax.FileOptions{
+	Set:             true,
+	While:           true,
+	TopLevelControl: true,
+	GlobalReassign:  true,
+	Recursion:       true,
+}
+
+// chartTypes are the kinds of chart chart() can produce
+var chartTypes = map[string]bool{"bar": true, "line": true, "pie": true, "scatter": true}
+
+// execute runs code in a fresh interpreter that can see only the analysis
+// builtins and files
+func execute(code string, files []File, limits Limits) (result *Result) {
+	out := &output{limit: limits.MaxOutputBytes}
+	fs := newFileSet(files)
+
+	thread := &starlark.Thread{
+		Name:  "analysis",
+		Print: func(_ *starlark.Thread, msg string) { out.print(msg) },
+		Load: func(*starlark.Thread, string) (starlark.StringDict, error) {
+			return nil, errors.New("load is not available in the sandbox")
+		},
+		OnMaxSteps: func(thread *starlark.Thread) {
+			thread.Cancel(fmt.Sprintf("CPU limit exceeded: the analysis ran for more than %d steps", limits.MaxSteps))
+		},
+	}
+	thread.SetMaxExecutionSteps(limits.MaxSteps)
+	timer := time.AfterFunc(limits.Timeout, func() {
+		thread.Cancel(fmt.Sprintf("time limit exceeded: the analysis ran for more than %s", limits.Timeout))
+	})
+	defer timer.Stop()
+
+	defer func() {
+		if r := recover(); r != nil {
+			result = out.result()
+			result.Error = fmt.Sprintf("internal error: %v", r)
+		}
+	}()
+
+	_, err := starlark.ExecFileOptions(fileOptions, thread, scriptName, code, predeclared(out, fs))
+	result = out.result()
+	if err != nil {
+		var evalErr *starlark.EvalError
+		if errors.As(err, &evalErr) {
+			result.Error = evalErr.Backtrace()
+		} else {
+			result.Error = err.Error()
+		}
+	}
+	return result
+}
+
+// predeclared returns the names a script can use besides the Starlark built-ins
+func predeclared(out *output, fs *fileSet) starlark.StringDict {
+	return starlark.StringDict{
+		"files":      starlark.NewBuiltin("files", fs.builtinFiles),
+		"sheets":     starlark.NewBuiltin("sheets", fs.builtinSheets),
+		"read_table": starlark.NewBuiltin("read_table", fs.builtinReadTable),
+		"read_rows":  starlark.NewBuiltin("read_rows", fs.builtinReadRows),
+		"read_text":  starlark.NewBuiltin("read_text", fs.builtinReadText),
+		"table":      starlark.NewBuiltin("table", out.builtinTable),
+		"chart":      starlark.NewBuiltin("chart", out.builtinChart),
+		"sum":        starlark.NewBuiltin("sum", builtinSum),
+		"round":      starlark.NewBuiltin("round", builtinRound),
+		"stats":      statsModule,
+		"math":       starlarkmath.Module,
+		"json":       starlarkjson.Module,
+	}
+}
+
+// files() returns the names of the files the script can read
+func (fs *fileSet) builtinFiles(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs); err != nil {
+		return nil, err
+	}
+	return stringList(fs.names), nil
+}
+
+// sheets(name) returns the sheet names of a spreadsheet
+func (fs *fileSet) builtinSheets(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "name", &name); err != nil {
+		return nil, err
+	}
+	sheets, err := fs.sheets(name)
+	if err != nil {
+		return nil, err
+	}
+	return stringList(sheets), nil
+}
+
+// read_table(name, sheet="") returns one dict per row, keyed by the header row
+func (fs *fileSet) builtinReadTable(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name, sheet string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "name", &name, "sheet?", &sheet); err != nil {
+		return nil, err
+	}
+	rows, err := fs.rows(name, sheet)
+	if err != nil {
+		return nil, err
+	}
+	return tableRecords(rows)
+}
+
+// read_rows(name, sheet="") returns one list per row, including the header row
+func (fs *fileSet) builtinReadRows(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name, sheet string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "name", &name, "sheet?", &sheet); err != nil {
+		return nil, err
+	}
+	rows, err := fs.rows(name, sheet)
+	if err != nil {
+		return nil, err
+	}
+	lists := tableRows(rows)
+	values := make([]starlark.Value, len(lists))
+	for i, row := range lists {
+		values[i] = row
+	}
+	return starlark.NewList(values), nil
+}
+
+// read_text(name) returns the contents of a text file
+func (fs *fileSet) builtinReadText(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var name string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "name", &name); err != nil {
+		return nil, err
+	}
+	text, err := fs.text(name)
+	if err != nil {
+		return nil, err
+	}
+	return starlark.String(text), nil
+}
+
+// output collects what a script prints and emits
+type output struct {
+	stdout    []byte
+	limit     int
+	tables    []Table
+	charts    []Chart
+	truncated bool
+}
+
+func (o *output) print(msg string) {
+	line := msg + "\n"
+	if room := o.limit - len(o.stdout); room < len(line) {
+		if room > 0 {
+			o.stdout = append(o.stdout, line[:room]...)
+		}
+		o.truncated = true
+		return
+	}
+	o.stdout = append(o.stdout, line...)
+}
+
+func (o *output) result() *Result {
+	return &Result{
+		Stdout:    string(o.stdout),
+		Tables:    o.tables,
+		Charts:    o.charts,
+		Truncated: o.truncated,
+	}
+}
+
+// table(rows, columns=None, title="") shows rows as a table. Rows are dicts
+// or lists; columns default to the keys of dict rows.
+func (o *output) builtinTable(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var rows starlark.Iterable
+	var columns starlark.Value = starlark.None
+	var title string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "rows", &rows, "columns?", &columns, "title?", &title); err != nil {
+		return nil, err
+	}
+	if len(o.tables) >= MaxTables {
+		return nil, fmt.Errorf("%s: a run can produce at most %d tables", b.Name(), MaxTables)
+	}
+
+	t := Table{Title: title, Rows: [][]interface{}{}}
+	if columns != starlark.None {
+		names, err := stringsOf(columns)
+		if err != nil {
+			return nil, fmt.Errorf("%s: columns: %v", b.Name(), err)
+		}
+		t.Columns = names
+	}
+
+	var records []starlark.Value
+	iter := rows.Iterate()
+	defer iter.Done()
+	var row starlark.Value
+	for iter.Next(&row) {
+		t.TotalRows++
+		if len(records) < MaxTableRows {
+			records = append(records, row)
+		}
+	}
+
+	// Infer columns from dict keys, in first-seen order
+	if t.Columns == nil {
+		seen := make(map[string]bool)
+		width := 0
+		for _, record := range records {
+			switch record := record.(type) {
+			case *starlark.Dict:
+				for _, key := range record.Keys() {
+					name := valueString(key)
+					if !seen[name] {
+						seen[name] = true
+						t.Columns = append(t.Columns, name)
+					}
+				}
+			case starlark.Indexable:
+				width = max(width, record.Len())
+			}
+		}
+		for i := len(t.Columns); i < width; i++ {
+			t.Columns = append(t.Columns, fmt.Sprintf("column_%d", i+1))
+		}
+	}
+
+	for i, record := range records {
+		cells := make([]interface{}, len(t.Columns))
+		switch record := record.(type) {
+		case *starlark.Dict:
+			for j, column := range t.Columns {
+				if value, found, _ := record.Get(starlark.String(column)); found {
+					cells[j] = toJSONValue(value)
+				}
+			}
+		case starlark.Indexable:
+			for j := 0; j < len(cells) && j < record.Len(); j++ {
+				cells[j] = toJSONValue(record.Index(j))
+			}
+		default:
+			return nil, fmt.Errorf("%s: row %d is a %s, not a dict or list", b.Name(), i, record.Type())
+		}
+		t.Rows = append(t.Rows, cells)
+	}
+	if t.TotalRows > len(t.Rows) {
+		o.truncated = true
+	}
+
+	o.tables = append(o.tables, t)
+	return starlark.None, nil
+}
+
+// chart(type, labels, series, title="") shows a chart. series is a list of
+// numbers, or a dict of series name to list of numbers, one per label.
+func (o *output) builtinChart(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var chartType, title string
+	var labels starlark.Iterable
+	var series starlark.Value
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "type", &chartType, "labels", &labels, "series", &series, "title?", &title); err != nil {
+		return nil, err
+	}
+	if !chartTypes[chartType] {
+		return nil, fmt.Errorf("%s: type must be bar, line, pie or scatter, not %q", b.Name(), chartType)
+	}
+	if len(o.charts) >= MaxCharts {
+		return nil, fmt.Errorf("%s: a run can produce at most %d charts", b.Name(), MaxCharts)
+	}
+
+	c := Chart{Type: chartType, Title: title, Labels: []interface{}{}}
+	iter := labels.Iterate()
+	defer iter.Done()
+	var label starlark.Value
+	for iter.Next(&label) {
+		if len(c.Labels) == MaxChartPoints {
+			return nil, fmt.Errorf("%s: at most %d points per chart", b.Name(), MaxChartPoints)
+		}
+		c.Labels = append(c.Labels, toJSONValue(label))
+	}
+
+	named := map[string]starlark.Value{}
+	var names []string
+	if dict, ok := series.(*starlark.Dict); ok {
+		for _, item := range dict.Items() {
+			name := valueString(item[0])
+			named[name] = item[1]
+			names = append(names, name)
+		}
+	} else {
+		named["value"] = series
+		names = []string{"value"}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%s: series is empty", b.Name())
+	}
+	if chartType == "pie" && len(names) > 1 {
+		return nil, fmt.Errorf("%s: a pie chart has one series", b.Name())
+	}
+
+	for _, name := range names {
+		values, err := numbers(named[name], false)
+		if err != nil {
+			return nil, fmt.Errorf("%s: series %q: %v", b.Name(), name, err)
+		}
+		if len(values) != len(c.Labels) {
+			return nil, fmt.Errorf("%s: series %q has %d values for %d labels", b.Name(), name, len(values), len(c.Labels))
+		}
+		c.Series = append(c.Series, ChartSeries{Name: name, Values: values})
+	}
+
+	o.charts = append(o.charts, c)
+	return starlark.None, nil
+}
+
+// toJSONValue converts a Starlark value to one encoding/json can encode
+func toJSONValue(v starlark.Value) interface{} {
+	switch v := v.(type) {
+	case starlark.NoneType:
+		return nil
+	case starlark.Bool:
+		return bool(v)
+	case starlark.Int:
+		if i, ok := v.Int64(); ok {
+			return i
+		}
+		return v.String()
+	case starlark.Float:
+		f := float64(v)
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil
+		}
+		return f
+	case starlark.String:
+		return string(v)
+	default:
+		return v.String()
+	}
+}
+
+// valueString returns strings unquoted and other values as Starlark prints them
+func valueString(v starlark.Value) string {
+	if s, ok := starlark.AsString(v); ok {
+		return s
+	}
+	return v.String()
+}
+
+func stringList(values []string) *starlark.List {
+	items := make([]starlark.Value, len(values))
+	for i, v := range values {
+		items[i] = starlark.String(v)
+	}
+	return starlark.NewList(items)
+}
+
+func stringsOf(v starlark.Value) ([]string, error) {
+	iterable, ok := v.(starlark.Iterable)
+	if !ok {
+		return nil, fmt.Errorf("got %s, want a list of strings", v.Type())
+	}
+	var out []string
+	iter := iterable.Iterate()
+	defer iter.Done()
+	var item starlark.Value
+	for iter.Next(&item) {
+		out = append(out, valueString(item))
+	}
+	return out, nil
+}
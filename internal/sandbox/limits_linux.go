@@ -0,0 +1,14 @@
+package sandbox
+
+import (
+	"syscall"
+)
+
+// applyProcessLimits backs up the interpreter's step budget with a kernel CPU
+// limit, so a builtin that never returns can't spin forever. Address space
+// isn't limited: the Go runtime reserves far more than it uses, and the
+// memory watchdog covers the heap.
+func applyProcessLimits(limits Limits) {
+	cpuSeconds := uint64(limits.Timeout.Seconds()) + 1
+	_ = syscall.Setrlimit(syscall.RLIMIT_CPU, &syscall.Rlimit{Cur: cpuSeconds, Max: cpuSeconds})
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package sandbox
+
+// applyProcessLimits is a no-op where kernel resource limits aren't
+// available; the step budget, memory watchdog and deadline still apply
+func applyProcessLimits(limits Limits) {}
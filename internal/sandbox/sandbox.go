@@ -0,0 +1,201 @@
+// Package sandbox runs untrusted analysis scripts written by the model.
+//
+// Scripts are Starlark, a small Python dialect with no access to the file
+// system, network, clock or environment. Each run happens in a child process
+// (this binary re-executed with a clean environment) so a script that
+// exhausts memory or hangs takes down only itself. Limits are enforced at
+// three levels: an interpreter step budget (CPU), a memory watchdog in the
+// child, and a wall-clock deadline after which the parent kills the child.
+//
+// The only data a script can read are the files passed in with the request.
+package sandbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/logging"
+)
+
+// Default limits for one run
+const (
+	DefaultTimeout        = 30 * time.Second
+	DefaultMemoryBytes    = 256 << 20
+	DefaultMaxSteps       = 100_000_000
+	DefaultMaxOutputBytes = 64 << 10
+)
+
+// Caps on what a script can emit, so results stay a reasonable size for the model
+const (
+	MaxTables       = 20
+	MaxTableRows    = 500
+	MaxCharts       = 10
+	MaxChartPoints  = 1000
+	maxResultBytes  = 16 << 20
+	maxStderrBytes  = 64 << 10
+	childWaitDelay  = 2 * time.Second
+	childEnv        = "AGPT_SANDBOX_CHILD"
+	childEnvEnabled = childEnv + "=1"
+)
+
+// ErrUnavailable is returned when the sandbox process can't be started
+var ErrUnavailable = errors.New("analysis sandbox unavailable")
+
+// Limits bounds the resources one run may use
+type Limits struct {
+	Timeout        time.Duration // Wall-clock time, including loading files
+	MemoryBytes    int64         // Heap the script may use
+	MaxSteps       uint64        // Interpreter steps; bounds CPU time independent of machine load
+	MaxOutputBytes int           // Printed output kept; the rest is dropped
+}
+
+func (l Limits) withDefaults() Limits {
+	if l.Timeout <= 0 {
+		l.Timeout = DefaultTimeout
+	}
+	if l.MemoryBytes <= 0 {
+		l.MemoryBytes = DefaultMemoryBytes
+	}
+	if l.MaxSteps == 0 {
+		l.MaxSteps = DefaultMaxSteps
+	}
+	if l.MaxOutputBytes <= 0 {
+		l.MaxOutputBytes = DefaultMaxOutputBytes
+	}
+	return l
+}
+
+// File is a file the script can read, by name
+type File struct {
+	Name string `json:"name"`
+	Data []byte `json:"data"`
+}
+
+// Table is tabular output produced with table()
+type Table struct {
+	Title     string          `json:"title,omitempty"`
+	Columns   []string        `json:"columns"`
+	Rows      [][]interface{} `json:"rows"`
+	TotalRows int             `json:"total_rows"` // Rows before truncation to MaxTableRows
+}
+
+// ChartSeries is one named series of a chart
+type ChartSeries struct {
+	Name   string    `json:"name"`
+	Values []float64 `json:"values"`
+}
+
+// Chart is a chart specification produced with chart(). Clients render it.
+type Chart struct {
+	Type   string        `json:"type"` // bar, line, pie or scatter
+	Title  string        `json:"title,omitempty"`
+	Labels []interface{} `json:"labels"`
+	Series []ChartSeries `json:"series"`
+}
+
+// Result is the outcome of one run. A script that fails (syntax error,
+// runtime error or exceeded limit) still produces a Result, with Error set
+// and whatever output was produced before the failure.
+type Result struct {
+	Stdout    string  `json:"stdout"`
+	Tables    []Table `json:"tables,omitempty"`
+	Charts    []Chart `json:"charts,omitempty"`
+	Error     string  `json:"error,omitempty"`
+	Truncated bool    `json:"truncated,omitempty"` // Output was dropped to stay within limits
+}
+
+// request is what the parent sends the child on stdin
+type request struct {
+	Code   string `json:"code"`
+	Files  []File `json:"files"`
+	Limits Limits `json:"limits"`
+}
+
+// Runner starts sandboxed runs
+type Runner struct {
+	limits  Limits
+	command string
+}
+
+// NewRunner creates a runner that re-executes the current binary for each run.
+// The binary must call RunIfChild at the start of main.
+func NewRunner(limits Limits) (*Runner, error) {
+	command, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	return &Runner{limits: limits.withDefaults(), command: command}, nil
+}
+
+// Limits returns the limits applied to each run
+func (r *Runner) Limits() Limits {
+	return r.limits
+}
+
+// Run executes code with access to files. The returned error is non-nil only
+// when the sandbox itself failed or ctx was cancelled; problems with the
+// script are reported in Result.Error.
+func (r *Runner) Run(ctx context.Context, code string, files []File) (*Result, error) {
+	input, err := json.Marshal(request{Code: code, Files: files, Limits: r.limits})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode analysis request: %w", err)
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, r.limits.Timeout)
+	defer cancel()
+
+	var stdout, stderr limitedBuffer
+	stdout.limit = maxResultBytes
+	stderr.limit = maxStderrBytes
+
+	cmd := exec.CommandContext(runCtx, r.command)
+	cmd.Env = []string{childEnvEnabled}
+	cmd.Dir = os.TempDir()
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = childWaitDelay
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnavailable, err)
+	}
+	waitErr := cmd.Wait()
+
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	if runCtx.Err() != nil {
+		return &Result{Error: fmt.Sprintf("time limit exceeded: the analysis ran for more than %s", r.limits.Timeout)}, nil
+	}
+
+	var result Result
+	if err := json.Unmarshal(stdout.Bytes(), &result); err != nil {
+		// The child died without reporting, most likely killed for using too
+		// much memory before the watchdog noticed
+		logging.Warn("analysis process failed", "error", waitErr, "stderr", stderr.String())
+		return &Result{Error: "the analysis process stopped unexpectedly; it may have used too much memory"}, nil
+	}
+	return &result, nil
+}
+
+// limitedBuffer keeps at most limit bytes and discards the rest
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room < len(p) {
+		if room > 0 {
+			b.Buffer.Write(p[:room])
+		}
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
@@ -0,0 +1,188 @@
+package sandbox
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"go.starlark.net/starlark"
+)
+
+// TestMain lets Runner re-execute the test binary as the sandbox child
+func TestMain(m *testing.M) {
+	RunIfChild()
+	os.Exit(m.Run())
+}
+
+var testLimits = Limits{}.withDefaults()
+
+const ordersCSV = "\ufeffregion,product,revenue,zip\nNorth,Widget,\"1,200.50\",02134\nSouth,Widget,800,30301\n\nNorth,Gadget,300,02134\n"
+
+func TestExecute(t *testing.T) {
+	code := `
+rows = read_table("orders.csv")
+print(len(rows), "rows")
+totals = stats.sum_by(rows, "region", "revenue")
+print("north", round(totals["North"], 2))
+print("mean", round(stats.mean([r["revenue"] for r in rows]), 1))
+print("zip", rows[0]["zip"])
+table([{"region": k, "revenue": v} for k, v in totals.items()], title="Revenue by region")
+chart("bar", list(totals.keys()), {"revenue": list(totals.values())}, title="Revenue")
+`
+	result := execute(code, []File{{Name: "orders.csv", Data: []byte(ordersCSV)}}, testLimits)
+	if result.Error != "" {
+		t.Fatalf("unexpected error: %s", result.Error)
+	}
+
+	want := "3 rows\nnorth 1500.5\nmean 766.8\nzip 02134\n"
+	if result.Stdout != want {
+		t.Errorf("stdout = %q, want %q", result.Stdout, want)
+	}
+	if len(result.Tables) != 1 || result.Tables[0].Title != "Revenue by region" {
+		t.Fatalf("unexpected tables: %+v", result.Tables)
+	}
+	if got := result.Tables[0].Columns; len(got) != 2 || got[0] != "region" || got[1] != "revenue" {
+		t.Errorf("columns = %v", got)
+	}
+	if len(result.Charts) != 1 || result.Charts[0].Series[0].Values[0] != 1500.5 {
+		t.Errorf("unexpected charts: %+v", result.Charts)
+	}
+}
+
+func TestExecuteSpreadsheet(t *testing.T) {
+	f := excelize.NewFile()
+	f.SetSheetRow("Sheet1", "A1", &[]interface{}{"month", "orders"})
+	f.SetSheetRow("Sheet1", "A2", &[]interface{}{"Jan", 12})
+	f.SetSheetRow("Sheet1", "A3", &[]interface{}{"Feb", 30})
+	f.NewSheet("Costs")
+	buf, err := f.WriteToBuffer()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code := `
+print(sheets("sales.xlsx"))
+rows = read_table("sales.xlsx")
+print(sum([r["orders"] for r in rows]))
+print(read_rows("sales.xlsx")[0])
+`
+	result := execute(code, []File{{Name: "sales.xlsx", Data: buf.Bytes()}}, testLimits)
+	if result.Error != "" {
+		t.Fatalf("unexpected error: %s", result.Error)
+	}
+	want := "[\"Sheet1\", \"Costs\"]\n42\n[\"month\", \"orders\"]\n"
+	if result.Stdout != want {
+		t.Errorf("stdout = %q, want %q", result.Stdout, want)
+	}
+}
+
+func TestExecuteErrors(t *testing.T) {
+	files := []File{{Name: "orders.csv", Data: []byte(ordersCSV)}, {Name: "notes.txt", Data: []byte("hi")}}
+	limits := testLimits
+	limits.MaxSteps = 10000
+
+	tests := map[string]struct {
+		code string
+		want string
+	}{
+		"syntax":       {"print(", "analysis.star:1"},
+		"runtime":      {"print('before')\nx = 1 // 0", "floored division by zero"},
+		"missing file": {`read_table("sales.csv")`, "available files: notes.txt, orders.csv"},
+		"not a table":  {`read_table("notes.txt")`, "use read_text"},
+		"load":         {`load("os.star", "system")`, "load is not available"},
+		"steps":        {"while True:\n    pass", "CPU limit exceeded"},
+		"chart":        {`chart("bar", ["a", "b"], [1])`, "has 1 values for 2 labels"},
+	}
+	for name, tt := range tests {
+		result := execute(tt.code, files, limits)
+		if !strings.Contains(result.Error, tt.want) {
+			t.Errorf("%s: error = %q, want it to contain %q", name, result.Error, tt.want)
+		}
+	}
+
+	// Output produced before a failure is kept
+	result := execute("print('before')\nx = 1 // 0", nil, limits)
+	if result.Stdout != "before\n" {
+		t.Errorf("stdout = %q, want output before the error", result.Stdout)
+	}
+}
+
+func TestExecuteTruncatesOutput(t *testing.T) {
+	limits := testLimits
+	limits.MaxOutputBytes = 10
+
+	result := execute("for i in range(100):\n    print(i)\ntable([[i] for i in range(1000)])", nil, limits)
+	if result.Error != "" {
+		t.Fatalf("unexpected error: %s", result.Error)
+	}
+	if len(result.Stdout) != 10 || !result.Truncated {
+		t.Errorf("expected output truncated to 10 bytes, got %q", result.Stdout)
+	}
+	if len(result.Tables[0].Rows) != MaxTableRows || result.Tables[0].TotalRows != 1000 {
+		t.Errorf("expected table truncated to %d rows, got %d of %d", MaxTableRows, len(result.Tables[0].Rows), result.Tables[0].TotalRows)
+	}
+}
+
+func TestCellValue(t *testing.T) {
+	tests := map[string]starlark.Value{
+		"42":       starlark.MakeInt(42),
+		"-1,234.5": starlark.Float(-1234.5),
+		"0.25":     starlark.Float(0.25),
+		"00123":    starlark.String("00123"),
+		"1e3":      starlark.Float(1000),
+		"12,34":    starlark.String("12,34"),
+		"inf":      starlark.String("inf"),
+		" ":        starlark.None,
+	}
+	for cell, want := range tests {
+		if got := cellValue(cell); got != want {
+			t.Errorf("cellValue(%q) = %v (%s), want %v", cell, got, got.Type(), want)
+		}
+	}
+}
+
+func TestRunner(t *testing.T) {
+	runner, err := NewRunner(Limits{Timeout: 5 * time.Second, MemoryBytes: 64 << 20})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	result, err := runner.Run(ctx, `print(files(), read_text("a.txt"))`, []File{{Name: "a.txt", Data: []byte("hello")}})
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if result.Error != "" || result.Stdout != "[\"a.txt\"] hello\n" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+
+	result, err = runner.Run(ctx, "x = []\nwhile True:\n    x.append('y' * 100000)", nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !strings.Contains(result.Error, "memory") {
+		t.Errorf("expected the memory limit to stop the run, got %+v", result)
+	}
+}
+
+func TestRunnerTimeout(t *testing.T) {
+	runner, err := NewRunner(Limits{Timeout: 500 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	result, err := runner.Run(context.Background(), "while True:\n    pass", nil)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if !strings.Contains(result.Error, "time limit exceeded") {
+		t.Errorf("expected a time limit error, got %+v", result)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("run took %s after the time limit", elapsed)
+	}
+}
@@ -0,0 +1,280 @@
+package sandbox
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"go.starlark.net/starlark"
+	"go.starlark.net/starlarkstruct"
+)
+
+// statsModule holds summary statistics helpers. None values are skipped, so
+// columns with blank cells can be passed directly.
+var statsModule = &starlarkstruct.Module{
+	Name: "stats",
+	Members: starlark.StringDict{
+		"sum":        starlark.NewBuiltin("stats.sum", builtinSum),
+		"mean":       starlark.NewBuiltin("stats.mean", floatStat(mean)),
+		"median":     starlark.NewBuiltin("stats.median", floatStat(func(v []float64) float64 { return percentile(v, 50) })),
+		"stdev":      starlark.NewBuiltin("stats.stdev", builtinStdev),
+		"percentile": starlark.NewBuiltin("stats.percentile", builtinPercentile),
+		"count_by":   starlark.NewBuiltin("stats.count_by", builtinCountBy),
+		"sum_by":     starlark.NewBuiltin("stats.sum_by", builtinSumBy),
+		"group_by":   starlark.NewBuiltin("stats.group_by", builtinGroupBy),
+	},
+}
+
+// numbers returns the numeric values of an iterable. With skipNone, None
+// values are left out; otherwise they are an error.
+func numbers(v starlark.Value, skipNone bool) ([]float64, error) {
+	iterable, ok := v.(starlark.Iterable)
+	if !ok {
+		return nil, fmt.Errorf("got %s, want a list of numbers", v.Type())
+	}
+	var out []float64
+	iter := iterable.Iterate()
+	defer iter.Done()
+	var item starlark.Value
+	for i := 0; iter.Next(&item); i++ {
+		if item == starlark.None && skipNone {
+			continue
+		}
+		f, ok := starlark.AsFloat(item)
+		if !ok {
+			return nil, fmt.Errorf("item %d is %s, not a number", i, item.Type())
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+// sum(values) adds numbers, keeping integers exact
+func builtinSum(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var values starlark.Iterable
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &values); err != nil {
+		return nil, err
+	}
+
+	total := starlark.MakeInt(0)
+	var floatTotal float64
+	isFloat := false
+	iter := values.Iterate()
+	defer iter.Done()
+	var item starlark.Value
+	for i := 0; iter.Next(&item); i++ {
+		switch item := item.(type) {
+		case starlark.NoneType:
+		case starlark.Int:
+			total = total.Add(item)
+		case starlark.Float:
+			isFloat = true
+			floatTotal += float64(item)
+		default:
+			return nil, fmt.Errorf("%s: item %d is %s, not a number", b.Name(), i, item.Type())
+		}
+	}
+	if isFloat {
+		return starlark.Float(floatTotal + float64(total.Float())), nil
+	}
+	return total, nil
+}
+
+// round(x, digits=0) rounds half away from zero
+func builtinRound(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var x starlark.Value
+	digits := 0
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "x", &x, "digits?", &digits); err != nil {
+		return nil, err
+	}
+	f, ok := starlark.AsFloat(x)
+	if !ok {
+		return nil, fmt.Errorf("%s: got %s, want a number", b.Name(), x.Type())
+	}
+	scale := math.Pow(10, float64(digits))
+	rounded := math.Round(f*scale) / scale
+	if digits <= 0 {
+		if i := int64(rounded); float64(i) == rounded {
+			return starlark.MakeInt64(i), nil
+		}
+	}
+	return starlark.Float(rounded), nil
+}
+
+// floatStat wraps a statistic over a non-empty list of numbers
+func floatStat(stat func([]float64) float64) func(*starlark.Thread, *starlark.Builtin, starlark.Tuple, []starlark.Tuple) (starlark.Value, error) {
+	return func(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+		var values starlark.Value
+		if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &values); err != nil {
+			return nil, err
+		}
+		nums, err := numbers(values, true)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", b.Name(), err)
+		}
+		if len(nums) == 0 {
+			return nil, fmt.Errorf("%s: no numbers", b.Name())
+		}
+		return starlark.Float(stat(nums)), nil
+	}
+}
+
+func mean(values []float64) float64 {
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total / float64(len(values))
+}
+
+// percentile interpolates linearly between the closest ranks
+func percentile(values []float64, p float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	rank := p / 100 * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	upper := int(math.Ceil(rank))
+	return sorted[lower] + (sorted[upper]-sorted[lower])*(rank-float64(lower))
+}
+
+// stdev(values) is the sample standard deviation
+func builtinStdev(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var values starlark.Value
+	if err := starlark.UnpackPositionalArgs(b.Name(), args, kwargs, 1, &values); err != nil {
+		return nil, err
+	}
+	nums, err := numbers(values, true)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", b.Name(), err)
+	}
+	if len(nums) < 2 {
+		return nil, fmt.Errorf("%s: need at least two numbers", b.Name())
+	}
+	m := mean(nums)
+	total := 0.0
+	for _, v := range nums {
+		total += (v - m) * (v - m)
+	}
+	return starlark.Float(math.Sqrt(total / float64(len(nums)-1))), nil
+}
+
+// percentile(values, p) returns the p-th percentile, p from 0 to 100
+func builtinPercentile(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var values starlark.Value
+	var p float64
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "values", &values, "p", &p); err != nil {
+		return nil, err
+	}
+	if p < 0 || p > 100 {
+		return nil, fmt.Errorf("%s: p must be between 0 and 100", b.Name())
+	}
+	nums, err := numbers(values, true)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", b.Name(), err)
+	}
+	if len(nums) == 0 {
+		return nil, fmt.Errorf("%s: no numbers", b.Name())
+	}
+	return starlark.Float(percentile(nums, p)), nil
+}
+
+// groupRows calls fn with each dict row and the value of its key column
+func groupRows(name string, rows starlark.Iterable, key string, fn func(group starlark.Value, row *starlark.Dict) error) error {
+	iter := rows.Iterate()
+	defer iter.Done()
+	var row starlark.Value
+	for i := 0; iter.Next(&row); i++ {
+		dict, ok := row.(*starlark.Dict)
+		if !ok {
+			return fmt.Errorf("%s: row %d is %s, not a dict", name, i, row.Type())
+		}
+		group, found, err := dict.Get(starlark.String(key))
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		if !found {
+			group = starlark.None
+		}
+		if err := fn(group, dict); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// count_by(rows, key) counts rows per value of the key column
+func builtinCountBy(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var rows starlark.Iterable
+	var key string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "rows", &rows, "key", &key); err != nil {
+		return nil, err
+	}
+	counts := starlark.NewDict(0)
+	err := groupRows(b.Name(), rows, key, func(group starlark.Value, _ *starlark.Dict) error {
+		count, found, _ := counts.Get(group)
+		n := starlark.MakeInt(1)
+		if found {
+			n = count.(starlark.Int).Add(n)
+		}
+		return counts.SetKey(group, n)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// sum_by(rows, key, value) totals the value column per value of the key
+// column; blank values count as zero
+func builtinSumBy(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var rows starlark.Iterable
+	var key, value string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "rows", &rows, "key", &key, "value", &value); err != nil {
+		return nil, err
+	}
+	totals := starlark.NewDict(0)
+	err := groupRows(b.Name(), rows, key, func(group starlark.Value, row *starlark.Dict) error {
+		v, _, _ := row.Get(starlark.String(value))
+		amount := 0.0
+		if v != starlark.None {
+			f, ok := starlark.AsFloat(v)
+			if !ok {
+				return fmt.Errorf("%s: %s value %s is not a number", b.Name(), value, v)
+			}
+			amount = f
+		}
+		total, found, _ := totals.Get(group)
+		if found {
+			amount += float64(total.(starlark.Float))
+		}
+		return totals.SetKey(group, starlark.Float(amount))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+// group_by(rows, key) returns the rows for each value of the key column
+func builtinGroupBy(_ *starlark.Thread, b *starlark.Builtin, args starlark.Tuple, kwargs []starlark.Tuple) (starlark.Value, error) {
+	var rows starlark.Iterable
+	var key string
+	if err := starlark.UnpackArgs(b.Name(), args, kwargs, "rows", &rows, "key", &key); err != nil {
+		return nil, err
+	}
+	groups := starlark.NewDict(0)
+	err := groupRows(b.Name(), rows, key, func(group starlark.Value, row *starlark.Dict) error {
+		list, found, _ := groups.Get(group)
+		if !found {
+			list = starlark.NewList(nil)
+			if err := groups.SetKey(group, list); err != nil {
+				return err
+			}
+		}
+		return list.(*starlark.List).Append(row)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
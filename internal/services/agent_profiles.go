@@ -0,0 +1,146 @@
+package services
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+
+	"go.yaml.in/yaml/v3"
+)
+
+//go:embed agent_profiles.yaml
+var defaultAgentProfiles []byte
+
+var (
+	ErrAgentProfileNotFound = errors.New("agent profile not found")
+	ErrInvalidToolChoice    = errors.New("invalid tool choice")
+	ErrUnknownSessionTool   = errors.New("unknown tool")
+	ErrModelNotAllowed      = errors.New("model is not allowed")
+)
+
+// Tool choices for a chat session. Any other value names a tool the model must call.
+const (
+	ToolChoiceAuto     = "auto"
+	ToolChoiceRequired = "required"
+	ToolChoiceNone     = "none"
+)
+
+// agentProfileName matches valid profile names
+var agentProfileName = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+
+// AgentProfile bundles the system prompt, model and tools a chat session starts with
+type AgentProfile struct {
+	Name         string    `json:"name" yaml:"-"`
+	Title        string    `json:"title" yaml:"title"`
+	Description  string    `json:"description,omitempty" yaml:"description"`
+	SystemPrompt string    `json:"system_prompt,omitempty" yaml:"system_prompt"`
+	Model        string    `json:"model,omitempty" yaml:"model"`
+	Tools        *[]string `json:"tools" yaml:"tools"` // nil offers every tool; empty offers none
+	ToolChoice   string    `json:"tool_choice,omitempty" yaml:"tool_choice"`
+}
+
+// AgentProfiles are the profiles users can create sessions from
+type AgentProfiles struct {
+	Profiles map[string]AgentProfile `json:"profiles" yaml:"profiles"`
+}
+
+// DefaultAgentProfiles returns the built-in profiles
+func DefaultAgentProfiles() *AgentProfiles {
+	profiles, err := ParseAgentProfiles(defaultAgentProfiles)
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in agent profiles: %v", err))
+	}
+	return profiles
+}
+
+// LoadAgentProfiles reads profiles from a YAML or JSON file.
+// An empty path returns the built-in profiles.
+func LoadAgentProfiles(path string) (*AgentProfiles, error) {
+	if path == "" {
+		return DefaultAgentProfiles(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read agent profiles: %w", err)
+	}
+
+	profiles, err := ParseAgentProfiles(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid agent profiles %s: %w", path, err)
+	}
+	return profiles, nil
+}
+
+// ParseAgentProfiles parses and validates profiles. JSON is accepted as it is valid YAML.
+func ParseAgentProfiles(data []byte) (*AgentProfiles, error) {
+	var profiles AgentProfiles
+	if err := yaml.Unmarshal(data, &profiles); err != nil {
+		return nil, err
+	}
+
+	for name, profile := range profiles.Profiles {
+		if !agentProfileName.MatchString(name) {
+			return nil, fmt.Errorf("profile %q: names use lowercase letters, digits, - and _", name)
+		}
+		if err := checkToolChoice(profile.ToolChoice, profile.Tools); err != nil {
+			return nil, fmt.Errorf("profile %q: %w", name, err)
+		}
+		profile.Name = name
+		if profile.Title == "" {
+			profile.Title = name
+		}
+		profiles.Profiles[name] = profile
+	}
+
+	return &profiles, nil
+}
+
+// Get returns a profile by name
+func (p *AgentProfiles) Get(name string) (AgentProfile, error) {
+	profile, ok := p.Profiles[name]
+	if !ok {
+		return AgentProfile{}, fmt.Errorf("%w: %s", ErrAgentProfileNotFound, name)
+	}
+	return profile, nil
+}
+
+// List returns the profiles sorted by name
+func (p *AgentProfiles) List() []AgentProfile {
+	list := make([]AgentProfile, 0, len(p.Profiles))
+	for _, profile := range p.Profiles {
+		list = append(list, profile)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// CheckModels reports a profile whose model is not allowed
+func (p *AgentProfiles) CheckModels(allowed func(model string) bool) error {
+	for _, profile := range p.List() {
+		if profile.Model != "" && !allowed(profile.Model) {
+			return fmt.Errorf("profile %q uses model %q, which is not allowed", profile.Name, profile.Model)
+		}
+	}
+	return nil
+}
+
+// checkToolChoice validates a tool choice against the tools offered; nil tools offers every tool
+func checkToolChoice(choice string, tools *[]string) error {
+	switch choice {
+	case "", ToolChoiceAuto, ToolChoiceNone:
+		return nil
+	case ToolChoiceRequired:
+		if tools != nil && len(*tools) == 0 {
+			return fmt.Errorf("%w: required needs at least one tool", ErrInvalidToolChoice)
+		}
+		return nil
+	}
+	if tools != nil && !containsString(*tools, choice) {
+		return fmt.Errorf("%w: %s is not one of the session's tools", ErrInvalidToolChoice, choice)
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/agpt-go/chatbot-api/internal/config"
+	"github.com/agpt-go/chatbot-api/internal/database"
+	"github.com/google/uuid"
+	"github.com/sashabaranov/go-openai"
+)
+
+func TestDefaultAgentProfiles(t *testing.T) {
+	profiles := DefaultAgentProfiles()
+
+	assistant, err := profiles.Get("assistant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if assistant.Tools != nil {
+		t.Errorf("assistant tools = %v, want every tool", *assistant.Tools)
+	}
+	writer, err := profiles.Get("writer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if writer.Tools == nil || len(*writer.Tools) != 0 {
+		t.Errorf("writer tools = %v, want none", writer.Tools)
+	}
+	if _, err := profiles.Get("missing"); !errors.Is(err, ErrAgentProfileNotFound) {
+		t.Errorf("Get(missing) error = %v, want ErrAgentProfileNotFound", err)
+	}
+
+	list := profiles.List()
+	for i := 1; i < len(list); i++ {
+		if list[i-1].Name >= list[i].Name {
+			t.Errorf("List() is not sorted: %s before %s", list[i-1].Name, list[i].Name)
+		}
+	}
+}
+
+func TestParseAgentProfiles(t *testing.T) {
+	profiles, err := ParseAgentProfiles([]byte(`
+profiles:
+  support:
+    system_prompt: Help customers.
+    tools: [search]
+    tool_choice: search
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	support, _ := profiles.Get("support")
+	if support.Name != "support" || support.Title != "support" || support.ToolChoice != "search" {
+		t.Errorf("support = %+v", support)
+	}
+
+	for name, data := range map[string]string{
+		"bad name":            "profiles: {Support: {}}",
+		"required, no tools":  "profiles: {quiet: {tools: [], tool_choice: required}}",
+		"choice not in tools": "profiles: {p: {tools: [search], tool_choice: export}}",
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseAgentProfiles([]byte(data)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestAgentProfilesCheckModels(t *testing.T) {
+	profiles, err := ParseAgentProfiles([]byte(`profiles: {fast: {model: gpt-4o-mini}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := profiles.CheckModels(func(model string) bool { return model == "gpt-4o" }); err == nil {
+		t.Error("expected an error for a model that is not allowed")
+	}
+	if err := profiles.CheckModels(func(model string) bool { return true }); err != nil {
+		t.Errorf("CheckModels() error = %v", err)
+	}
+}
+
+// newTestChatService creates a chat service with the named tools registered
+func newTestChatService(cfg ToolExecutorConfig, tools ...string) *ChatService {
+	toolService := &ToolService{registry: NewToolRegistry()}
+	for _, name := range tools {
+		toolService.registry.Register(name, ToolDefinition{Name: name}, func(ctx context.Context, userID uuid.UUID, arguments string) (*ToolResult, error) {
+			return &ToolResult{Success: true}, nil
+		})
+	}
+	return &ChatService{
+		llmService:   NewLLMService(&config.OpenAIConfig{APIKey: "test", Model: "gpt-4o", AllowedModels: []string{"gpt-4o-mini"}}),
+		toolService:  toolService,
+		toolExecutor: NewToolExecutor(toolService, cfg),
+		profiles:     DefaultAgentProfiles(),
+	}
+}
+
+func TestSessionChatOptions(t *testing.T) {
+	policies, err := ParseToolPolicies([]byte(`tools: {export: {policy: deny}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := newTestChatService(ToolExecutorConfig{Policies: policies}, "search", "export", "report")
+	userID := uuid.New()
+	str := func(s string) *string { return &s }
+
+	tests := []struct {
+		name       string
+		session    database.ChatSession
+		tools      []string
+		model      string
+		toolChoice string
+	}{
+		{"all tools", database.ChatSession{Model: str("gpt-4o-mini")}, []string{"search", "report"}, "gpt-4o-mini", ""},
+		{"selected tools", database.ChatSession{Tools: []string{"report", "export"}}, []string{"report"}, "gpt-4o", ""},
+		{"no tools", database.ChatSession{Tools: []string{}}, nil, "gpt-4o", ""},
+		{"tool choice none", database.ChatSession{ToolChoice: str(ToolChoiceNone)}, nil, "gpt-4o", ""},
+		{"required", database.ChatSession{ToolChoice: str(ToolChoiceRequired)}, []string{"search", "report"}, "gpt-4o", ToolChoiceRequired},
+		{"specific tool", database.ChatSession{ToolChoice: str("report")}, []string{"search", "report"}, "gpt-4o", "report"},
+		{"denied tool falls back to auto", database.ChatSession{ToolChoice: str("export")}, []string{"search", "report"}, "gpt-4o", ""},
+		{"disallowed model uses default", database.ChatSession{Model: str("gpt-3.5-turbo")}, []string{"search", "report"}, "gpt-4o", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tools, opts := svc.sessionChatOptions(context.Background(), userID, &tt.session)
+
+			var names []string
+			for _, def := range tools {
+				names = append(names, def.Name)
+			}
+			if !slices.Equal(names, tt.tools) {
+				t.Errorf("tools = %v, want %v", names, tt.tools)
+			}
+
+			req := svc.llmService.newRequest(nil, "", tools, opts)
+			if req.Model != tt.model {
+				t.Errorf("model = %q, want %q", req.Model, tt.model)
+			}
+			choice := ""
+			switch c := req.ToolChoice.(type) {
+			case string:
+				choice = c
+			case openai.ToolChoice:
+				choice = c.Function.Name
+			}
+			if choice != tt.toolChoice {
+				t.Errorf("tool choice = %v, want %q", req.ToolChoice, tt.toolChoice)
+			}
+		})
+	}
+}
+
+func TestExecuteToolEnforcesSessionTools(t *testing.T) {
+	svc := newTestChatService(ToolExecutorConfig{}, "search", "report")
+	sessionID := uuid.New()
+	svc.toolExecutor.sessionTools = func(ctx context.Context, userID, id uuid.UUID) ([]string, bool, error) {
+		if id != sessionID {
+			return nil, false, errors.New("session not found")
+		}
+		return []string{"search"}, true, nil
+	}
+	userID := uuid.New()
+
+	tests := []struct {
+		name    string
+		ctx     context.Context
+		tool    string
+		success bool
+	}{
+		{"selected tool", WithToolInvocation(context.Background(), ToolInvocation{SessionID: sessionID}), "search", true},
+		{"other tool", WithToolInvocation(context.Background(), ToolInvocation{SessionID: sessionID}), "report", false},
+		{"session lookup fails", WithToolInvocation(context.Background(), ToolInvocation{SessionID: uuid.New()}), "search", false},
+		{"outside a session", context.Background(), "report", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := svc.toolExecutor.ExecuteTool(tt.ctx, userID, tt.tool, "{}")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Success != tt.success {
+				t.Errorf("success = %v, want %v (%s)", result.Success, tt.success, result.Error)
+			}
+		})
+	}
+}
+
+func TestCheckSessionTools(t *testing.T) {
+	svc := newTestChatService(ToolExecutorConfig{}, "search", "report")
+
+	tools, err := svc.checkSessionTools([]string{"search", "report", "search"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(*tools, []string{"search", "report"}) {
+		t.Errorf("tools = %v, want duplicates removed", *tools)
+	}
+	if _, err := svc.checkSessionTools([]string{"made_up"}); !errors.Is(err, ErrUnknownSessionTool) {
+		t.Errorf("error = %v, want ErrUnknownSessionTool", err)
+	}
+}
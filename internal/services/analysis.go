@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/logging"
+	"github.com/agpt-go/chatbot-api/internal/sandbox"
+	"github.com/google/uuid"
+)
+
+// maxAnalysisCodeLength bounds the scripts the model can send to run_analysis
+const maxAnalysisCodeLength = 20000
+
+// analysisToolOverhead is time the tool allows beyond the sandbox's own limit,
+// for loading files and starting the sandbox process
+const analysisToolOverhead = 10 * time.Second
+
+// analysisFiles loads the files a session's scripts can read
+type analysisFiles interface {
+	SessionFiles(ctx context.Context, userID, sessionID uuid.UUID) ([]sandbox.File, error)
+}
+
+// analysisRunner runs a script in the sandbox
+type analysisRunner interface {
+	Run(ctx context.Context, code string, files []sandbox.File) (*sandbox.Result, error)
+	Limits() sandbox.Limits
+}
+
+// AnalysisService provides the run_analysis tool, which runs scripts the model
+// writes against the files uploaded to the chat session
+type AnalysisService struct {
+	files  analysisFiles
+	runner analysisRunner
+}
+
+// NewAnalysisService creates a new analysis service
+func NewAnalysisService(files analysisFiles, runner analysisRunner) *AnalysisService {
+	return &AnalysisService{files: files, runner: runner}
+}
+
+// Register adds run_analysis to the registry
+func (s *AnalysisService) Register(registry *ToolRegistry) {
+	registry.Register("run_analysis", GetRunAnalysisToolDefinition(), s.handleRunAnalysis,
+		WithToolTimeout(s.runner.Limits().Timeout+analysisToolOverhead))
+}
+
+// RunAnalysisInput represents the input for the run_analysis tool
+type RunAnalysisInput struct {
+	Code string `json:"code" jsonschema:"required" description:"The Starlark script to run"`
+}
+
+// GetRunAnalysisToolDefinition returns the tool definition for run_analysis.
+// Its parameters are derived from RunAnalysisInput.
+func GetRunAnalysisToolDefinition() ToolDefinition {
+	params := ToolParametersFor[RunAnalysisInput]()
+	setPropertyKeyword(params, "code", "maxLength", maxAnalysisCodeLength)
+
+	return ToolDefinition{
+		Name: "run_analysis",
+		Description: `Run a script to analyze the files the user uploaded to this chat, such as
+spreadsheets of their operations. Use it whenever the user asks for numbers,
+totals, trends or comparisons from their data; never guess figures.
+
+Scripts are Starlark, a Python dialect: no imports, classes, exceptions or
+f-strings (use "%s" % x or str()). Available besides the usual builtins:
+- files(): names of the uploaded files
+- read_table(name, sheet=""): rows of a .csv, .tsv or .xlsx file as dicts keyed
+  by the header row; numeric cells are numbers, blank cells are None
+- read_rows(name, sheet=""): rows as lists, including the header
+- sheets(name): sheet names of an .xlsx file
+- read_text(name): contents of a text file; json.decode() parses JSON
+- sum(values), round(x, digits=0)
+- stats.mean/median/stdev/sum(values), stats.percentile(values, p),
+  stats.count_by(rows, key), stats.sum_by(rows, key, value), stats.group_by(rows, key)
+- math module
+- print(...): output returned to you
+- table(rows, columns=None, title=""): show rows (dicts or lists) to the user as a table
+- chart(type, labels, series, title=""): show a bar, line, pie or scatter chart;
+  series is a list of numbers or a dict of series name to list of numbers
+
+Print the figures you need to answer. Use table() and chart() for results the
+user should see. The script can't access the network or anything but these files.`,
+		Parameters: params,
+	}
+}
+
+// handleRunAnalysis is the registry handler for run_analysis
+func (s *AnalysisService) handleRunAnalysis(ctx context.Context, userID uuid.UUID, arguments string) (*ToolResult, error) {
+	input, err := ParseArgs[RunAnalysisInput](arguments)
+	if err != nil {
+		return &ToolResult{Success: false, Error: fmt.Sprintf("invalid arguments: %v", err)}, nil
+	}
+
+	inv, ok := ToolInvocationFromContext(ctx)
+	if !ok || inv.SessionID == uuid.Nil {
+		return &ToolResult{Success: false, Error: "run_analysis can only be used in a chat session"}, nil
+	}
+
+	files, err := s.files.SessionFiles(ctx, userID, inv.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.runner.Run(ctx, input.Code, files)
+	if err != nil {
+		logging.Error("analysis sandbox failed", err, "sessionID", inv.SessionID)
+		return &ToolResult{Success: false, Error: "The analysis sandbox is unavailable"}, nil
+	}
+
+	data := map[string]interface{}{
+		"stdout": result.Stdout,
+	}
+	if len(result.Tables) > 0 {
+		data["tables"] = result.Tables
+	}
+	if len(result.Charts) > 0 {
+		data["charts"] = result.Charts
+	}
+	if result.Truncated {
+		data["truncated"] = true
+	}
+
+	if result.Error != "" {
+		return &ToolResult{Success: false, Error: result.Error, Data: data}, nil
+	}
+	return &ToolResult{
+		Success: true,
+		Message: fmt.Sprintf("Analysis ran over %d file(s)", len(files)),
+		Data:    data,
+	}, nil
+}
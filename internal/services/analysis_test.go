@@ -0,0 +1,99 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/agpt-go/chatbot-api/internal/sandbox"
+	"github.com/google/uuid"
+)
+
+type mockAnalysisFiles struct {
+	files     []sandbox.File
+	sessionID uuid.UUID
+}
+
+func (m *mockAnalysisFiles) SessionFiles(ctx context.Context, userID, sessionID uuid.UUID) ([]sandbox.File, error) {
+	m.sessionID = sessionID
+	return m.files, nil
+}
+
+type mockAnalysisRunner struct {
+	result *sandbox.Result
+	code   string
+	files  []sandbox.File
+}
+
+func (m *mockAnalysisRunner) Run(ctx context.Context, code string, files []sandbox.File) (*sandbox.Result, error) {
+	m.code = code
+	m.files = files
+	return m.result, nil
+}
+
+func (m *mockAnalysisRunner) Limits() sandbox.Limits {
+	return sandbox.Limits{}
+}
+
+func TestRunAnalysisTool(t *testing.T) {
+	files := &mockAnalysisFiles{files: []sandbox.File{{Name: "orders.csv", Data: []byte("a,b\n1,2\n")}}}
+	runner := &mockAnalysisRunner{result: &sandbox.Result{
+		Stdout: "3\n",
+		Tables: []sandbox.Table{{Columns: []string{"a"}, Rows: [][]interface{}{{1}}, TotalRows: 1}},
+	}}
+	service := NewAnalysisService(files, runner)
+	sessionID := uuid.New()
+	ctx := WithToolInvocation(context.Background(), ToolInvocation{SessionID: sessionID})
+
+	result, err := service.handleRunAnalysis(ctx, uuid.New(), `{"code":"print(1 + 2)"}`)
+	if err != nil {
+		t.Fatalf("handleRunAnalysis() error = %v", err)
+	}
+	if !result.Success || result.Data["stdout"] != "3\n" || result.Data["tables"] == nil {
+		t.Errorf("unexpected result: %+v", result)
+	}
+	if files.sessionID != sessionID || runner.code != "print(1 + 2)" || len(runner.files) != 1 {
+		t.Errorf("expected the session's files to be passed to the runner")
+	}
+
+	// Script errors fail the call but keep the output so the model can fix the script
+	runner.result = &sandbox.Result{Stdout: "partial\n", Error: "analysis.star:2:1: undefined: x"}
+	result, _ = service.handleRunAnalysis(ctx, uuid.New(), `{"code":"print('partial')\nx"}`)
+	if result.Success || result.Error != runner.result.Error || result.Data["stdout"] != "partial\n" {
+		t.Errorf("unexpected result for a failing script: %+v", result)
+	}
+
+	// Outside a chat session there are no files to analyze
+	result, _ = service.handleRunAnalysis(context.Background(), uuid.New(), `{"code":"print(1)"}`)
+	if result.Success {
+		t.Error("expected run_analysis to fail without a chat session")
+	}
+}
+
+func TestRunAnalysisToolDefinition(t *testing.T) {
+	registry := NewToolRegistry()
+	NewAnalysisService(&mockAnalysisFiles{}, &mockAnalysisRunner{}).Register(registry)
+
+	tool, ok := registry.Get("run_analysis")
+	if !ok {
+		t.Fatal("expected run_analysis to be registered")
+	}
+	if tool.Timeout <= 0 {
+		t.Error("expected run_analysis to have its own timeout")
+	}
+	if err := ValidateToolArguments(tool.Definition.Parameters, `{}`); err == nil {
+		t.Error("expected code to be required")
+	}
+}
+
+func TestCheckAttachmentName(t *testing.T) {
+	name, contentType, err := checkAttachmentName(`C:\Users\me\Q3 Sales.XLSX`)
+	if err != nil || name != "Q3 Sales.XLSX" || contentType != attachmentTypes[".xlsx"] {
+		t.Errorf("checkAttachmentName() = %q, %q, %v", name, contentType, err)
+	}
+
+	for _, filename := range []string{"", "../", "report.exe", "data\x00.csv"} {
+		if _, _, err := checkAttachmentName(filename); err == nil {
+			t.Errorf("expected %q to be rejected", filename)
+		}
+	}
+}
@@ -43,6 +43,9 @@ const (
 
 	// Error tracking
 	EventError = "error_occurred"
+
+	// Security events
+	EventRefreshTokenReused = "refresh_token_reused" // A rotated refresh token was presented again
 )
 
 // NewAnalyticsService creates a new analytics service with PostHog client
@@ -286,60 +289,6 @@ func (s *AnalyticsService) TrackBusinessReportRequested(userID uuid.UUID, report
 	}
 }
 
-// CalculateCompletenessPercentage calculates how complete the business understanding is
-func CalculateCompletenessPercentage(status UnderstandingStatus) float64 {
-	total := 15 // Total number of trackable fields
-	filled := 0
-
-	if status.UserName {
-		filled++
-	}
-	if status.JobTitle {
-		filled++
-	}
-	if status.BusinessName {
-		filled++
-	}
-	if status.Industry {
-		filled++
-	}
-	if status.BusinessSize {
-		filled++
-	}
-	if status.UserRole {
-		filled++
-	}
-	if status.KeyWorkflowsCount > 0 {
-		filled++
-	}
-	if status.DailyActivitiesCount > 0 {
-		filled++
-	}
-	if status.PainPointsCount > 0 {
-		filled++
-	}
-	if status.BottlenecksCount > 0 {
-		filled++
-	}
-	if status.ManualTasksCount > 0 {
-		filled++
-	}
-	if status.AutomationGoalsCount > 0 {
-		filled++
-	}
-	if status.CurrentSoftwareCount > 0 {
-		filled++
-	}
-	if status.ExistingAutomationCount > 0 {
-		filled++
-	}
-	if status.AdditionalNotes {
-		filled++
-	}
-
-	return float64(filled) / float64(total) * 100
-}
-
 // TrackError tracks error events for debugging and monitoring
 func (s *AnalyticsService) TrackError(userID uuid.UUID, errorType, errorMessage, context string) {
 	s.Track(userID, EventError, map[string]interface{}{
@@ -349,6 +298,15 @@ func (s *AnalyticsService) TrackError(userID uuid.UUID, errorType, errorMessage,
 	})
 }
 
+// TrackRefreshTokenReused tracks replay of an already rotated refresh token,
+// which revoked the token family it belongs to
+func (s *AnalyticsService) TrackRefreshTokenReused(userID, familyID uuid.UUID, revokedTokens int64) {
+	s.Track(userID, EventRefreshTokenReused, map[string]interface{}{
+		"family_id":      familyID.String(),
+		"revoked_tokens": revokedTokens,
+	})
+}
+
 // IdentifyCompany identifies a company/organization for B2B group analytics
 // This enables tracking metrics at the company level, not just user level
 func (s *AnalyticsService) IdentifyCompany(userID uuid.UUID, companyName, industry, size string) {
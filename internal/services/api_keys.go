@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/database"
+	"github.com/agpt-go/chatbot-api/internal/logging"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	ErrInvalidAPIKey       = errors.New("invalid or expired api key")
+	ErrAPIKeyNotFound      = errors.New("api key not found")
+	ErrInvalidAPIKeyScope  = errors.New("invalid api key scope")
+	ErrInvalidAPIKeyExpiry = errors.New("api key expiry must be in the future")
+	ErrTooManyAPIKeys      = errors.New("too many api keys")
+)
+
+// APIKeyPrefix starts every API key, telling them apart from access tokens
+const APIKeyPrefix = "agpt_"
+
+const (
+	apiKeyDisplayLength = len(APIKeyPrefix) + 8
+	maxAPIKeysPerUser   = 25
+)
+
+// Scopes an API key can be given. Keys can only reach endpoints that need
+// one of them; account settings and admin endpoints need a signed-in user.
+const (
+	ScopeChatRead     = "chat:read"     // List and read chat sessions and messages
+	ScopeChatWrite    = "chat:write"    // Create sessions, send messages and handle tool calls
+	ScopeReportsRead  = "reports:read"  // Read the business understanding reports are built from
+	ScopeReportsWrite = "reports:write" // Correct the business understanding
+	ScopeMCP          = "mcp"           // Call the chatbot's tools over MCP
+)
+
+// APIKeyScopes lists every scope, in the order they are documented
+var APIKeyScopes = []string{ScopeChatRead, ScopeChatWrite, ScopeReportsRead, ScopeReportsWrite, ScopeMCP}
+
+// APIKey describes a user's API key. The key itself is only shown when it
+// is created.
+type APIKey struct {
+	ID         uuid.UUID
+	Name       string
+	Prefix     string
+	Scopes     []string
+	ExpiresAt  time.Time // Zero for keys that don't expire
+	LastUsedAt time.Time
+	CreatedAt  time.Time
+}
+
+// APIKeyPrincipal is the user a valid API key acts as
+type APIKeyPrincipal struct {
+	KeyID  uuid.UUID
+	UserID uuid.UUID
+	Email  string
+	Scopes []string
+}
+
+// CreateAPIKey creates a key acting as the user with the given scopes and
+// returns it with the key itself, which isn't stored. A zero expiresAt
+// creates a key that doesn't expire.
+func (s *AuthService) CreateAPIKey(ctx context.Context, userID uuid.UUID, name string, scopes []string, expiresAt time.Time) (*APIKey, string, error) {
+	if len(scopes) == 0 {
+		return nil, "", ErrInvalidAPIKeyScope
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(APIKeyScopes, scope) {
+			return nil, "", fmt.Errorf("%w: %s", ErrInvalidAPIKeyScope, scope)
+		}
+	}
+	if !expiresAt.IsZero() && !expiresAt.After(time.Now()) {
+		return nil, "", ErrInvalidAPIKeyExpiry
+	}
+
+	count, err := s.queries.CountAPIKeys(ctx, userID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to count api keys: %w", err)
+	}
+	if count >= maxAPIKeysPerUser {
+		return nil, "", ErrTooManyAPIKeys
+	}
+
+	keyBytes := make([]byte, 32)
+	if _, err := rand.Read(keyBytes); err != nil {
+		return nil, "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	key := APIKeyPrefix + hex.EncodeToString(keyBytes)
+
+	scopes = slices.Compact(slices.Sorted(slices.Values(scopes)))
+	row, err := s.queries.CreateAPIKey(ctx, database.CreateAPIKeyParams{
+		UserID:    userID,
+		Name:      name,
+		Prefix:    key[:apiKeyDisplayLength],
+		KeyHash:   hashToken(key),
+		Scopes:    scopes,
+		ExpiresAt: pgtype.Timestamptz{Time: expiresAt, Valid: !expiresAt.IsZero()},
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	logging.Info("api key created", "userID", userID.String(), "keyID", row.ID.String(), "scopes", row.Scopes)
+	apiKey := apiKeyFromRow(row)
+	return &apiKey, key, nil
+}
+
+// ListAPIKeys returns the user's API keys, newest first
+func (s *AuthService) ListAPIKeys(ctx context.Context, userID uuid.UUID) ([]APIKey, error) {
+	rows, err := s.queries.ListAPIKeys(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list api keys: %w", err)
+	}
+
+	keys := make([]APIKey, 0, len(rows))
+	for _, row := range rows {
+		keys = append(keys, apiKeyFromRow(row))
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey deletes one of the user's API keys; it stops working at once
+func (s *AuthService) RevokeAPIKey(ctx context.Context, userID, keyID uuid.UUID) error {
+	deleted, err := s.queries.DeleteAPIKey(ctx, database.DeleteAPIKeyParams{
+		ID:     keyID,
+		UserID: userID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	if deleted == 0 {
+		return ErrAPIKeyNotFound
+	}
+	logging.Info("api key revoked", "userID", userID.String(), "keyID", keyID.String())
+	return nil
+}
+
+// ValidateAPIKey returns the user an API key acts as and records its use
+func (s *AuthService) ValidateAPIKey(ctx context.Context, key string) (*APIKeyPrincipal, error) {
+	if !strings.HasPrefix(key, APIKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+	row, err := s.queries.GetAPIKeyByHash(ctx, hashToken(key))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+	if row.ExpiresAt.Valid && !row.ExpiresAt.Time.After(time.Now()) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if err := s.queries.TouchAPIKey(ctx, row.ID); err != nil {
+		logging.Warn("failed to update api key last use", "keyID", row.ID.String(), "error", err)
+	}
+
+	return &APIKeyPrincipal{
+		KeyID:  row.ID,
+		UserID: row.UserID,
+		Email:  row.Email,
+		Scopes: row.Scopes,
+	}, nil
+}
+
+func apiKeyFromRow(row database.ApiKey) APIKey {
+	key := APIKey{
+		ID:        row.ID,
+		Name:      row.Name,
+		Prefix:    row.Prefix,
+		Scopes:    row.Scopes,
+		CreatedAt: row.CreatedAt,
+	}
+	if row.ExpiresAt.Valid {
+		key.ExpiresAt = row.ExpiresAt.Time
+	}
+	if row.LastUsedAt.Valid {
+		key.LastUsedAt = row.LastUsedAt.Time
+	}
+	return key
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestCreateAPIKeyValidation(t *testing.T) {
+	// Invalid requests are rejected before the database is used
+	s := &AuthService{}
+	tests := []struct {
+		name      string
+		scopes    []string
+		expiresAt time.Time
+		want      error
+	}{
+		{"no scopes", nil, time.Time{}, ErrInvalidAPIKeyScope},
+		{"unknown scope", []string{ScopeChatRead, "admin"}, time.Time{}, ErrInvalidAPIKeyScope},
+		{"expired", []string{ScopeChatRead}, time.Now().Add(-time.Minute), ErrInvalidAPIKeyExpiry},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := s.CreateAPIKey(context.Background(), uuid.New(), "CI", tt.scopes, tt.expiresAt)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateAPIKeyRequiresPrefix(t *testing.T) {
+	s := &AuthService{}
+	if _, err := s.ValidateAPIKey(context.Background(), "not-an-api-key"); !errors.Is(err, ErrInvalidAPIKey) {
+		t.Errorf("err = %v, want %v", err, ErrInvalidAPIKey)
+	}
+}
+
+// fakeAPIKeyDB stores API keys in memory and accepts the other writes that
+// signing a user out makes
+type fakeAPIKeyDB struct {
+	keys map[string]uuid.UUID // Key hash to user ID
+}
+
+func (db *fakeAPIKeyDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	if strings.Contains(sql, "-- name: DeleteUserAPIKeys ") {
+		for hash, userID := range db.keys {
+			if userID == args[0].(uuid.UUID) {
+				delete(db.keys, hash)
+			}
+		}
+	}
+	return pgconn.CommandTag{}, nil
+}
+
+func (db *fakeAPIKeyDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	return nil, errors.New("unexpected query")
+}
+
+func (db *fakeAPIKeyDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	if !strings.Contains(sql, "-- name: GetAPIKeyByHash ") {
+		return fakeRow{err: errors.New("unexpected query")}
+	}
+	userID, ok := db.keys[args[0].(string)]
+	if !ok {
+		return fakeRow{err: pgx.ErrNoRows}
+	}
+	return fakeRow{values: []any{uuid.New(), userID, []string{ScopeChatRead}, pgtype.Timestamptz{}, "user@example.com"}}
+}
+
+type fakeRow struct {
+	values []any
+	err    error
+}
+
+func (r fakeRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+	for i, value := range r.values {
+		reflect.ValueOf(dest[i]).Elem().Set(reflect.ValueOf(value))
+	}
+	return nil
+}
+
+func TestAPIKeysRevokedWithSessions(t *testing.T) {
+	tests := []struct {
+		name   string
+		revoke func(s *AuthService, userID uuid.UUID) error
+	}{
+		{"logout everywhere", func(s *AuthService, userID uuid.UUID) error {
+			return s.LogoutAll(context.Background(), userID)
+		}},
+		{"password reset or change", func(s *AuthService, userID uuid.UUID) error {
+			return s.setPassword(context.Background(), userID, "new-password")
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userID := uuid.New()
+			key := APIKeyPrefix + "0123456789abcdef"
+			db := &fakeAPIKeyDB{keys: map[string]uuid.UUID{hashToken(key): userID}}
+			s := &AuthService{
+				queries:     database.New(db),
+				revocations: newAccessTokenRevocations(nil, time.Minute),
+			}
+
+			if _, err := s.ValidateAPIKey(context.Background(), key); err != nil {
+				t.Fatalf("ValidateAPIKey before revoking: %v", err)
+			}
+			if err := tt.revoke(s, userID); err != nil {
+				t.Fatalf("revoke: %v", err)
+			}
+			if _, err := s.ValidateAPIKey(context.Background(), key); !errors.Is(err, ErrInvalidAPIKey) {
+				t.Errorf("ValidateAPIKey after revoking: err = %v, want %v", err, ErrInvalidAPIKey)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/agpt-go/chatbot-api/internal/database"
+	"github.com/agpt-go/chatbot-api/internal/sandbox"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrSessionNotFound       = errors.New("session not found")
+	ErrAttachmentNotFound    = errors.New("attachment not found")
+	ErrAttachmentTooLarge    = errors.New("attachment is too large")
+	ErrAttachmentLimit       = errors.New("session attachment limit reached")
+	ErrAttachmentType        = errors.New("unsupported attachment type")
+	ErrAttachmentInvalidName = errors.New("invalid attachment file name")
+)
+
+// Defaults for attachment limits
+const (
+	DefaultAttachmentMaxFileBytes    = 10 << 20
+	DefaultAttachmentMaxSessionBytes = 25 << 20
+	DefaultAttachmentMaxFiles        = 20
+	maxAttachmentNameLength          = 255
+)
+
+// attachmentTypes are the files run_analysis can read, by extension
+var attachmentTypes = map[string]string{
+	".csv":  "text/csv",
+	".tsv":  "text/tab-separated-values",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".json": "application/json",
+	".txt":  "text/plain",
+	".md":   "text/markdown",
+}
+
+// AttachmentsConfig limits what can be uploaded to a chat session
+type AttachmentsConfig struct {
+	MaxFileBytes    int64 // 0 uses DefaultAttachmentMaxFileBytes
+	MaxSessionBytes int64 // Total across a session's files; 0 uses DefaultAttachmentMaxSessionBytes
+	MaxFiles        int   // 0 uses DefaultAttachmentMaxFiles
+}
+
+// AttachmentView is an uploaded file, without its contents
+type AttachmentView struct {
+	ID          uuid.UUID `json:"id"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	SizeBytes   int64     `json:"size_bytes"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// AttachmentService stores files uploaded to chat sessions for analysis
+type AttachmentService struct {
+	queries *database.Queries
+	config  AttachmentsConfig
+}
+
+// NewAttachmentService creates a new attachment service
+func NewAttachmentService(queries *database.Queries, cfg AttachmentsConfig) *AttachmentService {
+	if cfg.MaxFileBytes <= 0 {
+		cfg.MaxFileBytes = DefaultAttachmentMaxFileBytes
+	}
+	if cfg.MaxSessionBytes <= 0 {
+		cfg.MaxSessionBytes = DefaultAttachmentMaxSessionBytes
+	}
+	if cfg.MaxFiles <= 0 {
+		cfg.MaxFiles = DefaultAttachmentMaxFiles
+	}
+	return &AttachmentService{queries: queries, config: cfg}
+}
+
+// MaxFileBytes returns the largest file that can be uploaded
+func (s *AttachmentService) MaxFileBytes() int64 {
+	return s.config.MaxFileBytes
+}
+
+// checkSession ensures the session exists and belongs to the user
+func (s *AttachmentService) checkSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	_, err := s.queries.GetChatSessionByUser(ctx, database.GetChatSessionByUserParams{
+		ID:     sessionID,
+		UserID: userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrSessionNotFound
+		}
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+	return nil
+}
+
+// Upload stores a file in a session. A file with the same name replaces the
+// existing one.
+func (s *AttachmentService) Upload(ctx context.Context, userID, sessionID uuid.UUID, filename string, data []byte) (*AttachmentView, error) {
+	filename, contentType, err := checkAttachmentName(filename)
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.config.MaxFileBytes {
+		return nil, fmt.Errorf("%w: the limit is %d MB", ErrAttachmentTooLarge, s.config.MaxFileBytes>>20)
+	}
+	if err := s.checkSession(ctx, userID, sessionID); err != nil {
+		return nil, err
+	}
+
+	usage, err := s.queries.GetChatAttachmentUsage(ctx, database.GetChatAttachmentUsageParams{
+		SessionID: sessionID,
+		Filename:  filename,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachment usage: %w", err)
+	}
+	if usage.FileCount >= int64(s.config.MaxFiles) {
+		return nil, fmt.Errorf("%w: a session can have at most %d files", ErrAttachmentLimit, s.config.MaxFiles)
+	}
+	if usage.TotalBytes+int64(len(data)) > s.config.MaxSessionBytes {
+		return nil, fmt.Errorf("%w: a session's files can total at most %d MB", ErrAttachmentLimit, s.config.MaxSessionBytes>>20)
+	}
+
+	row, err := s.queries.CreateChatAttachment(ctx, database.CreateChatAttachmentParams{
+		SessionID:   sessionID,
+		UserID:      userID,
+		Filename:    filename,
+		ContentType: contentType,
+		SizeBytes:   int32(len(data)),
+		Data:        data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store attachment: %w", err)
+	}
+
+	return &AttachmentView{
+		ID:          row.ID,
+		Filename:    row.Filename,
+		ContentType: row.ContentType,
+		SizeBytes:   int64(row.SizeBytes),
+		CreatedAt:   row.CreatedAt,
+	}, nil
+}
+
+// List returns the files uploaded to a session
+func (s *AttachmentService) List(ctx context.Context, userID, sessionID uuid.UUID) ([]AttachmentView, error) {
+	if err := s.checkSession(ctx, userID, sessionID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.queries.ListChatAttachments(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list attachments: %w", err)
+	}
+
+	views := make([]AttachmentView, 0, len(rows))
+	for _, row := range rows {
+		views = append(views, AttachmentView{
+			ID:          row.ID,
+			Filename:    row.Filename,
+			ContentType: row.ContentType,
+			SizeBytes:   int64(row.SizeBytes),
+			CreatedAt:   row.CreatedAt,
+		})
+	}
+	return views, nil
+}
+
+// Delete removes a file from a session
+func (s *AttachmentService) Delete(ctx context.Context, userID, sessionID, attachmentID uuid.UUID) error {
+	if err := s.checkSession(ctx, userID, sessionID); err != nil {
+		return err
+	}
+
+	rows, err := s.queries.DeleteChatAttachment(ctx, database.DeleteChatAttachmentParams{
+		ID:        attachmentID,
+		SessionID: sessionID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete attachment: %w", err)
+	}
+	if rows == 0 {
+		return ErrAttachmentNotFound
+	}
+	return nil
+}
+
+// SessionFiles returns the contents of a session's files for the sandbox
+func (s *AttachmentService) SessionFiles(ctx context.Context, userID, sessionID uuid.UUID) ([]sandbox.File, error) {
+	if err := s.checkSession(ctx, userID, sessionID); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.queries.ListChatAttachmentFiles(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load attachments: %w", err)
+	}
+
+	files := make([]sandbox.File, 0, len(rows))
+	for _, row := range rows {
+		files = append(files, sandbox.File{Name: row.Filename, Data: row.Data})
+	}
+	return files, nil
+}
+
+// checkAttachmentName cleans an uploaded file name and returns its content
+// type. Scripts refer to files by this name, so it must be plain.
+func checkAttachmentName(filename string) (string, string, error) {
+	filename = strings.TrimSpace(path.Base(strings.ReplaceAll(filename, "\\", "/")))
+	if filename == "" || filename == "." || filename == "/" || len(filename) > maxAttachmentNameLength {
+		return "", "", ErrAttachmentInvalidName
+	}
+	for _, r := range filename {
+		if unicode.IsControl(r) {
+			return "", "", ErrAttachmentInvalidName
+		}
+	}
+
+	contentType, ok := attachmentTypes[strings.ToLower(path.Ext(filename))]
+	if !ok {
+		return "", "", fmt.Errorf("%w: upload .csv, .tsv, .xlsx, .json, .txt or .md files", ErrAttachmentType)
+	}
+	return filename, contentType, nil
+}
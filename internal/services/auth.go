@@ -5,6 +5,7 @@ import (
 	"crypto/rand"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"time"
@@ -17,7 +18,6 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 	"golang.org/x/crypto/bcrypt"
 	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
 )
 
 var (
@@ -26,6 +26,7 @@ var (
 	ErrInvalidToken       = errors.New("invalid token")
 	ErrTokenExpired       = errors.New("token expired")
 	ErrInvalidOAuthState  = errors.New("invalid oauth state")
+	ErrTokenReused        = errors.New("refresh token reused")
 )
 
 const (
@@ -34,9 +35,13 @@ const (
 )
 
 type AuthService struct {
-	queries     *database.Queries
-	config      *config.Config
-	googleOAuth *oauth2.Config
+	queries        *database.Queries
+	config         *config.Config
+	oauthProviders map[string]*OAuthProvider
+	mailer         Mailer
+	analytics      *AnalyticsService
+	signingKeys    *SigningKeys
+	revocations    *accessTokenRevocations
 }
 
 type TokenPair struct {
@@ -49,25 +54,37 @@ type TokenPair struct {
 type Claims struct {
 	UserID uuid.UUID `json:"user_id"`
 	Email  string    `json:"email"`
+	// SessionID is the refresh token family, and so the device, the token was issued to
+	SessionID uuid.UUID `json:"sid"`
 	jwt.RegisteredClaims
 }
 
 func NewAuthService(queries *database.Queries, cfg *config.Config) *AuthService {
-	var googleOAuth *oauth2.Config
+	oauthProviders := make(map[string]*OAuthProvider)
 	if cfg.OAuth.GoogleClientID != "" {
-		googleOAuth = &oauth2.Config{
+		oauthProviders["google"] = NewOAuthProvider(OAuthProviderConfig{
+			Name:         "google",
+			Type:         OAuthProviderOIDC,
+			Issuer:       googleIssuer,
 			ClientID:     cfg.OAuth.GoogleClientID,
 			ClientSecret: cfg.OAuth.GoogleClientSecret,
 			RedirectURL:  cfg.OAuth.GoogleRedirectURL,
-			Scopes:       []string{"openid", "email", "profile"},
-			Endpoint:     google.Endpoint,
-		}
+		}, cfg.OAuth.RedirectBaseURL)
 	}
 
 	return &AuthService{
-		queries:     queries,
-		config:      cfg,
-		googleOAuth: googleOAuth,
+		queries:        queries,
+		config:         cfg,
+		oauthProviders: oauthProviders,
+		revocations:    newAccessTokenRevocations(queries, cfg.Auth.RevocationCacheTTL),
+	}
+}
+
+// SetOAuthProviders adds OAuth providers users can sign in with. A provider
+// named google replaces the one configured through GOOGLE_CLIENT_ID.
+func (s *AuthService) SetOAuthProviders(providers *OAuthProvidersConfig) {
+	for _, p := range providers.Providers {
+		s.oauthProviders[p.Name] = NewOAuthProvider(p, s.config.OAuth.RedirectBaseURL)
 	}
 }
 
@@ -103,6 +120,11 @@ func (s *AuthService) Register(ctx context.Context, email, password, name string
 		return nil, nil, fmt.Errorf("failed to create user: %w", err)
 	}
 
+	// The account works without a verified address; the user can ask for another email
+	if err := s.sendVerificationEmail(ctx, &user); err != nil {
+		logging.Warn("failed to send verification email", "error", err, "userID", user.ID.String())
+	}
+
 	tokens, err := s.generateTokenPair(ctx, &user)
 	if err != nil {
 		return nil, nil, err
@@ -111,6 +133,8 @@ func (s *AuthService) Register(ctx context.Context, email, password, name string
 	return &user, tokens, nil
 }
 
+// Login checks a user's password. Users with MFA enabled get an
+// MFAChallengeError instead of tokens; see VerifyMFAChallenge.
 func (s *AuthService) Login(ctx context.Context, email, password string) (*database.User, *TokenPair, error) {
 	user, err := s.queries.GetUserByEmail(ctx, email)
 	if err != nil {
@@ -125,7 +149,7 @@ func (s *AuthService) Login(ctx context.Context, email, password string) (*datab
 		return nil, nil, ErrInvalidCredentials
 	}
 
-	tokens, err := s.generateTokenPair(ctx, &user)
+	tokens, err := s.signIn(ctx, &user)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -133,29 +157,72 @@ func (s *AuthService) Login(ctx context.Context, email, password string) (*datab
 	return &user, tokens, nil
 }
 
+// SetAnalytics sets the analytics service used to report security events
+func (s *AuthService) SetAnalytics(analytics *AnalyticsService) {
+	s.analytics = analytics
+}
+
+// SetSigningKeys sets the asymmetric keys access tokens are signed with. Without
+// them tokens are signed with the JWT secret.
+func (s *AuthService) SetSigningKeys(keys *SigningKeys) {
+	s.signingKeys = keys
+}
+
+// JWKS returns the public keys that verify access tokens. It is empty while
+// tokens are signed with the JWT secret.
+func (s *AuthService) JWKS() JWKSet {
+	return s.signingKeys.JWKS(time.Now())
+}
+
+// RefreshTokens exchanges a refresh token for a new token pair. The new refresh
+// token joins the family of the old one. Presenting a token that was already
+// exchanged means it has been copied, so every token of its family is revoked
+// and ErrTokenReused is returned.
 func (s *AuthService) RefreshTokens(ctx context.Context, refreshToken string) (*TokenPair, error) {
 	tokenHash := hashToken(refreshToken)
 
-	storedToken, err := s.queries.GetRefreshToken(ctx, tokenHash)
+	storedToken, err := s.queries.RotateRefreshToken(ctx, tokenHash)
 	if err != nil {
-		return nil, ErrInvalidToken
+		return nil, s.rejectRefreshToken(ctx, tokenHash)
 	}
 
-	if storedToken.ExpiresAt.Before(time.Now()) {
-		return nil, ErrTokenExpired
+	user, err := s.queries.GetUserByID(ctx, storedToken.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
-	// Revoke old token
-	if err := s.queries.RevokeRefreshToken(ctx, tokenHash); err != nil {
-		logging.Warn("failed to revoke old refresh token", "error", err)
-	}
+	return s.issueTokenPair(ctx, &user, &storedToken)
+}
 
-	user, err := s.queries.GetUserByID(ctx, storedToken.UserID)
+// rejectRefreshToken explains why a refresh token can't be rotated, revoking
+// its family when the token was rotated before
+func (s *AuthService) rejectRefreshToken(ctx context.Context, tokenHash string) error {
+	storedToken, err := s.queries.GetRefreshTokenByHash(ctx, tokenHash)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user: %w", err)
+		return ErrInvalidToken
+	}
+
+	if storedToken.RotatedAt.Valid {
+		revoked, err := s.queries.RevokeRefreshTokenFamily(ctx, storedToken.FamilyID)
+		if err != nil {
+			logging.Error("failed to revoke refresh token family", err,
+				"userID", storedToken.UserID.String(), "familyID", storedToken.FamilyID.String())
+		}
+		logging.Warn("refresh token reused, revoked its family",
+			"userID", storedToken.UserID.String(),
+			"familyID", storedToken.FamilyID.String(),
+			"rotatedAt", storedToken.RotatedAt.Time,
+			"revokedTokens", revoked)
+		if s.analytics != nil {
+			s.analytics.TrackRefreshTokenReused(storedToken.UserID, storedToken.FamilyID, revoked)
+		}
+		return ErrTokenReused
 	}
 
-	return s.generateTokenPair(ctx, &user)
+	if storedToken.ExpiresAt.Before(time.Now()) {
+		return ErrTokenExpired
+	}
+	return ErrInvalidToken
 }
 
 func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
@@ -163,17 +230,43 @@ func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
 	return s.queries.RevokeRefreshToken(ctx, tokenHash)
 }
 
+// LogoutAll signs the user out everywhere, revoking their refresh tokens,
+// every access token issued so far and their API keys
 func (s *AuthService) LogoutAll(ctx context.Context, userID uuid.UUID) error {
-	return s.queries.RevokeAllUserTokens(ctx, userID)
+	if err := s.queries.RevokeAllUserTokens(ctx, userID); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	if err := s.queries.DeleteUserAPIKeys(ctx, userID); err != nil {
+		return fmt.Errorf("failed to revoke api keys: %w", err)
+	}
+	return s.RevokeUserAccessTokens(ctx, userID)
 }
 
-func (s *AuthService) ValidateAccessToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+// accessTokenKey returns the key that verifies an access token: the signing
+// key named by its kid header, or the JWT secret for tokens without one
+func (s *AuthService) accessTokenKey(token *jwt.Token) (interface{}, error) {
+	now := time.Now()
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || !s.signingKeys.acceptsSecret(now) {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return []byte(s.config.JWT.Secret), nil
-	})
+	}
+
+	key := s.signingKeys.verificationKey(kid, now)
+	if key == nil {
+		return nil, fmt.Errorf("unknown or retired key %q", kid)
+	}
+	if token.Method.Alg() != key.Algorithm {
+		return nil, fmt.Errorf("key %q does not sign with %v", kid, token.Header["alg"])
+	}
+	return key.publicKey, nil
+}
+
+func (s *AuthService) ValidateAccessToken(tokenString string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, s.accessTokenKey,
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), SigningAlgorithmRS256, SigningAlgorithmEdDSA}))
 
 	if err != nil {
 		return nil, ErrInvalidToken
@@ -187,22 +280,34 @@ func (s *AuthService) ValidateAccessToken(tokenString string) (*Claims, error) {
 	return claims, nil
 }
 
-// GenerateOAuthState creates a new OAuth state and stores it in the cache
-func (s *AuthService) GenerateOAuthState(ctx context.Context) (string, error) {
+// oauthState is kept in the cache between the redirect to a provider and its callback
+type oauthState struct {
+	Provider string `json:"provider"`
+	Verifier string `json:"verifier"` // PKCE code verifier
+	Nonce    string `json:"nonce"`
+}
+
+// generateOAuthState stores an OAuth flow in the cache under a new random state
+func (s *AuthService) generateOAuthState(ctx context.Context, flow oauthState) (string, error) {
 	stateBytes := make([]byte, 32)
 	if _, err := rand.Read(stateBytes); err != nil {
 		return "", fmt.Errorf("failed to generate state: %w", err)
 	}
 	state := hex.EncodeToString(stateBytes)
 
+	value, err := json.Marshal(flow)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode state: %w", err)
+	}
+
 	// Store state in cache with expiration
 	expiresAt := pgtype.Timestamptz{
 		Time:  time.Now().Add(oauthStateExpiration),
 		Valid: true,
 	}
-	err := s.queries.SetCache(ctx, database.SetCacheParams{
+	err = s.queries.SetCache(ctx, database.SetCacheParams{
 		Key:       oauthStateCachePrefix + state,
-		Value:     []byte("valid"),
+		Value:     value,
 		ExpiresAt: expiresAt,
 	})
 	if err != nil {
@@ -213,17 +318,17 @@ func (s *AuthService) GenerateOAuthState(ctx context.Context) (string, error) {
 	return state, nil
 }
 
-// ValidateOAuthState checks if the provided state is valid and removes it from cache
-func (s *AuthService) ValidateOAuthState(ctx context.Context, state string) error {
+// consumeOAuthState returns the OAuth flow stored under state and removes it from cache
+func (s *AuthService) consumeOAuthState(ctx context.Context, state string) (*oauthState, error) {
 	if state == "" {
-		return ErrInvalidOAuthState
+		return nil, ErrInvalidOAuthState
 	}
 
 	cacheKey := oauthStateCachePrefix + state
-	_, err := s.queries.GetCache(ctx, cacheKey)
+	cached, err := s.queries.GetCache(ctx, cacheKey)
 	if err != nil {
 		logging.Warn("oauth state validation failed", "state", truncateState(state), "error", err)
-		return ErrInvalidOAuthState
+		return nil, ErrInvalidOAuthState
 	}
 
 	// Delete the state after validation (one-time use)
@@ -231,104 +336,106 @@ func (s *AuthService) ValidateOAuthState(ctx context.Context, state string) erro
 		logging.Warn("failed to delete oauth state from cache", "error", err)
 	}
 
-	return nil
+	var flow oauthState
+	if err := json.Unmarshal(cached.Value, &flow); err != nil {
+		return nil, ErrInvalidOAuthState
+	}
+	return &flow, nil
 }
 
-func (s *AuthService) GetGoogleAuthURL(state string) string {
-	if s.googleOAuth == nil {
-		return ""
+// OAuthAuthURL starts signing in with a provider and returns its consent page URL
+func (s *AuthService) OAuthAuthURL(ctx context.Context, providerName string) (string, error) {
+	provider, ok := s.oauthProviders[providerName]
+	if !ok {
+		return "", ErrUnknownOAuthProvider
 	}
-	return s.googleOAuth.AuthCodeURL(state, oauth2.AccessTypeOffline)
+
+	flow := oauthState{
+		Provider: providerName,
+		Verifier: oauth2.GenerateVerifier(),
+		Nonce:    oauth2.GenerateVerifier(),
+	}
+	state, err := s.generateOAuthState(ctx, flow)
+	if err != nil {
+		return "", err
+	}
+	return provider.AuthCodeURL(ctx, state, flow.Verifier, flow.Nonce)
 }
 
-func (s *AuthService) HandleGoogleCallback(ctx context.Context, code string) (*database.User, *TokenPair, error) {
-	if s.googleOAuth == nil {
-		return nil, nil, errors.New("google oauth not configured")
+// HandleOAuthCallback completes signing in with a provider, creating the user
+// on their first sign-in. See oauthUser for accounts that exist already, and
+// Login for users with MFA enabled.
+func (s *AuthService) HandleOAuthCallback(ctx context.Context, providerName, code, state string) (*database.User, *TokenPair, error) {
+	provider, ok := s.oauthProviders[providerName]
+	if !ok {
+		return nil, nil, ErrUnknownOAuthProvider
 	}
 
-	token, err := s.googleOAuth.Exchange(ctx, code)
+	// Verify state parameter for CSRF protection
+	flow, err := s.consumeOAuthState(ctx, state)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to exchange code: %w", err)
+		return nil, nil, err
+	}
+	if flow.Provider != providerName {
+		return nil, nil, ErrInvalidOAuthState
 	}
 
-	// Get user info from Google
-	userInfo, err := s.getGoogleUserInfo(ctx, token)
+	identity, err := provider.Exchange(ctx, code, flow.Verifier, flow.Nonce)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	// Check if user exists by provider
-	googleProvider := "google"
-	user, err := s.queries.GetUserByProvider(ctx, database.GetUserByProviderParams{
-		Provider:   &googleProvider,
-		ProviderID: &userInfo.ID,
-	})
-
+	user, err := s.oauthUser(ctx, identity)
 	if err != nil {
-		// Create new user
-		user, err = s.queries.CreateUser(ctx, database.CreateUserParams{
-			Email:         userInfo.Email,
-			PasswordHash:  nil,
-			Name:          userInfo.Name,
-			Provider:      &googleProvider,
-			ProviderID:    &userInfo.ID,
-			EmailVerified: &userInfo.EmailVerified,
-		})
-		if err != nil {
-			return nil, nil, fmt.Errorf("failed to create user: %w", err)
-		}
+		return nil, nil, err
 	}
 
-	tokens, err := s.generateTokenPair(ctx, &user)
+	tokens, err := s.signIn(ctx, user)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	return &user, tokens, nil
+	return user, tokens, nil
 }
 
-type GoogleUserInfo struct {
-	ID            string `json:"id"`
-	Email         string `json:"email"`
-	Name          string `json:"name"`
-	Picture       string `json:"picture"`
-	EmailVerified bool   `json:"verified_email"`
+// generateTokenPair issues tokens for a new sign-in, starting a refresh token family
+func (s *AuthService) generateTokenPair(ctx context.Context, user *database.User) (*TokenPair, error) {
+	return s.issueTokenPair(ctx, user, nil)
 }
 
-func (s *AuthService) getGoogleUserInfo(ctx context.Context, token *oauth2.Token) (*GoogleUserInfo, error) {
-	client := s.googleOAuth.Client(ctx, token)
-	resp, err := client.Get("https://www.googleapis.com/oauth2/v2/userinfo")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get user info: %w", err)
-	}
-	defer func() { _ = resp.Body.Close() }()
+// issueTokenPair issues an access token and a refresh token. The refresh token
+// replaces prev in its family, or starts a new family when prev is nil.
+func (s *AuthService) issueTokenPair(ctx context.Context, user *database.User, prev *database.RefreshToken) (*TokenPair, error) {
+	now := time.Now()
 
-	var userInfo GoogleUserInfo
-	if err := decodeJSON(resp.Body, &userInfo); err != nil {
-		return nil, fmt.Errorf("failed to decode user info: %w", err)
+	familyID, signedInAt := uuid.New(), now
+	userAgent, ipHash := s.clientDetails(ctx)
+	if prev != nil {
+		familyID, signedInAt = prev.FamilyID, prev.SignedInAt
+		if userAgent == nil {
+			userAgent = prev.UserAgent
+		}
+		if ipHash == nil {
+			ipHash = prev.IpHash
+		}
 	}
 
-	return &userInfo, nil
-}
-
-func (s *AuthService) generateTokenPair(ctx context.Context, user *database.User) (*TokenPair, error) {
-	now := time.Now()
-
 	// Generate access token
 	accessClaims := &Claims{
-		UserID: user.ID,
-		Email:  user.Email,
+		UserID:    user.ID,
+		Email:     user.Email,
+		SessionID: familyID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(now.Add(s.config.JWT.AccessExpiresIn)),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
 			Issuer:    s.config.JWT.Issuer,
 			Subject:   user.ID.String(),
+			ID:        uuid.NewString(),
 		},
 	}
 
-	accessToken := jwt.NewWithClaims(jwt.SigningMethodHS256, accessClaims)
-	accessTokenString, err := accessToken.SignedString([]byte(s.config.JWT.Secret))
+	accessTokenString, err := s.signAccessToken(accessClaims, now)
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign access token: %w", err)
 	}
@@ -342,9 +449,13 @@ func (s *AuthService) generateTokenPair(ctx context.Context, user *database.User
 
 	// Store refresh token hash
 	_, err = s.queries.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
-		UserID:    user.ID,
-		TokenHash: hashToken(refreshToken),
-		ExpiresAt: now.Add(s.config.JWT.RefreshExpiresIn),
+		UserID:     user.ID,
+		TokenHash:  hashToken(refreshToken),
+		ExpiresAt:  now.Add(s.config.JWT.RefreshExpiresIn),
+		FamilyID:   familyID,
+		UserAgent:  userAgent,
+		IpHash:     ipHash,
+		SignedInAt: signedInAt,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to store refresh token: %w", err)
@@ -358,6 +469,18 @@ func (s *AuthService) generateTokenPair(ctx context.Context, user *database.User
 	}, nil
 }
 
+// signAccessToken signs claims with the signing key active at now, falling
+// back to the JWT secret when no key is
+func (s *AuthService) signAccessToken(claims *Claims, now time.Time) (string, error) {
+	key := s.signingKeys.active(now)
+	if key == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.config.JWT.Secret))
+	}
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.signer)
+}
+
 func hashToken(token string) string {
 	hash := sha256.Sum256([]byte(token))
 	return hex.EncodeToString(hash[:])
@@ -1,10 +1,14 @@
 package services
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/agpt-go/chatbot-api/internal/config"
+	"github.com/agpt-go/chatbot-api/internal/database"
 	"github.com/google/uuid"
 )
 
@@ -47,8 +51,12 @@ func TestNewAuthServiceWithGoogleOAuth(t *testing.T) {
 	if svc == nil {
 		t.Fatal("NewAuthService() returned nil")
 	}
-	if svc.googleOAuth == nil {
-		t.Error("NewAuthService() should set googleOAuth when credentials are provided")
+	google, ok := svc.oauthProviders["google"]
+	if !ok {
+		t.Fatal("NewAuthService() should configure the google provider when credentials are provided")
+	}
+	if google.issuer != googleIssuer || google.oauth.RedirectURL != "http://localhost:8080/callback" {
+		t.Errorf("google provider = %q redirecting to %q", google.issuer, google.oauth.RedirectURL)
 	}
 }
 
@@ -80,30 +88,15 @@ func TestValidateAccessToken(t *testing.T) {
 	})
 }
 
-func TestGetGoogleAuthURL(t *testing.T) {
-	t.Run("without google oauth configured", func(t *testing.T) {
-		cfg := createTestConfig()
-		svc := NewAuthService(nil, cfg)
-
-		url := svc.GetGoogleAuthURL("test-state")
-		if url != "" {
-			t.Errorf("GetGoogleAuthURL() = %q, want empty string", url)
-		}
-	})
-
-	t.Run("with google oauth configured", func(t *testing.T) {
-		cfg := createTestConfig()
-		cfg.OAuth.GoogleClientID = "test-client-id"
-		cfg.OAuth.GoogleClientSecret = "test-client-secret"
-		cfg.OAuth.GoogleRedirectURL = "http://localhost:8080/callback"
+func TestOAuthUnknownProvider(t *testing.T) {
+	svc := NewAuthService(nil, createTestConfig())
 
-		svc := NewAuthService(nil, cfg)
-
-		url := svc.GetGoogleAuthURL("test-state")
-		if url == "" {
-			t.Error("GetGoogleAuthURL() returned empty string")
-		}
-	})
+	if _, err := svc.OAuthAuthURL(context.Background(), "google"); err != ErrUnknownOAuthProvider {
+		t.Errorf("OAuthAuthURL() error = %v, want %v", err, ErrUnknownOAuthProvider)
+	}
+	if _, _, err := svc.HandleOAuthCallback(context.Background(), "okta", "code", "state"); err != ErrUnknownOAuthProvider {
+		t.Errorf("HandleOAuthCallback() error = %v, want %v", err, ErrUnknownOAuthProvider)
+	}
 }
 
 func TestHashToken(t *testing.T) {
@@ -189,28 +182,91 @@ func TestErrorVariables(t *testing.T) {
 	}
 }
 
-func TestGoogleUserInfoStruct(t *testing.T) {
-	userInfo := GoogleUserInfo{
-		ID:            "google-id-123",
-		Email:         "test@gmail.com",
-		Name:          "Test User",
-		Picture:       "https://example.com/photo.jpg",
-		EmailVerified: true,
+func TestActionTokens(t *testing.T) {
+	svc := NewAuthService(nil, createTestConfig())
+	user := &database.User{ID: uuid.New(), Email: "ada@example.com"}
+
+	token, err := svc.signActionToken(tokenPurposeVerifyEmail, user, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims, err := svc.parseActionToken(tokenPurposeVerifyEmail, token)
+	if err != nil {
+		t.Fatalf("parseActionToken() error = %v", err)
+	}
+	if claims.Subject != user.ID.String() || claims.Email != user.Email {
+		t.Errorf("claims = %+v", claims)
 	}
 
-	if userInfo.ID != "google-id-123" {
-		t.Errorf("GoogleUserInfo.ID = %q, want %q", userInfo.ID, "google-id-123")
+	// Tokens only work for their own purpose, and never as access tokens
+	if _, err := svc.parseActionToken("reset_password", token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("other purpose error = %v, want ErrInvalidToken", err)
 	}
-	if userInfo.Email != "test@gmail.com" {
-		t.Errorf("GoogleUserInfo.Email = %q, want %q", userInfo.Email, "test@gmail.com")
+	if _, err := svc.ValidateAccessToken(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("ValidateAccessToken() error = %v, want ErrInvalidToken", err)
 	}
-	if userInfo.Name != "Test User" {
-		t.Errorf("GoogleUserInfo.Name = %q, want %q", userInfo.Name, "Test User")
+
+	expired, err := svc.signActionToken(tokenPurposeVerifyEmail, user, -time.Minute)
+	if err != nil {
+		t.Fatal(err)
 	}
-	if userInfo.Picture != "https://example.com/photo.jpg" {
-		t.Errorf("GoogleUserInfo.Picture = %q, want %q", userInfo.Picture, "https://example.com/photo.jpg")
+	if _, err := svc.parseActionToken(tokenPurposeVerifyEmail, expired); !errors.Is(err, ErrTokenExpired) {
+		t.Errorf("expired token error = %v, want ErrTokenExpired", err)
 	}
-	if !userInfo.EmailVerified {
-		t.Error("GoogleUserInfo.EmailVerified should be true")
+}
+
+func TestClientDetails(t *testing.T) {
+	svc := NewAuthService(nil, createTestConfig())
+
+	userAgent, ipHash := svc.clientDetails(context.Background())
+	if userAgent != nil || ipHash != nil {
+		t.Errorf("clientDetails() without client = %v, %v; want nil, nil", userAgent, ipHash)
+	}
+
+	ctx := WithClientInfo(context.Background(), ClientInfo{
+		UserAgent: strings.Repeat("a", maxUserAgentLength+10),
+		IP:        "203.0.113.7",
+	})
+	userAgent, ipHash = svc.clientDetails(ctx)
+	if userAgent == nil || len(*userAgent) != maxUserAgentLength {
+		t.Errorf("user agent not truncated to %d characters", maxUserAgentLength)
+	}
+	if ipHash == nil || *ipHash != svc.hashIP("203.0.113.7") {
+		t.Fatalf("ipHash = %v, want the hash of the client IP", ipHash)
+	}
+	if strings.Contains(*ipHash, "203.0.113.7") || *ipHash == svc.hashIP("203.0.113.8") {
+		t.Error("ipHash should be a distinct hash of the address")
+	}
+}
+
+// failingMailer fails every send and reports each attempt on sent
+type failingMailer struct {
+	sent chan Email
+}
+
+func (m *failingMailer) Send(ctx context.Context, email Email) error {
+	m.sent <- email
+	return errors.New("smtp unavailable")
+}
+
+func TestRequestPasswordResetHidesSendFailures(t *testing.T) {
+	var none *string
+	db := &fakeQueryDB{rows: map[string][]any{
+		"GetUserByEmail": {uuid.New(), "ada@example.com", none, "Ada"},
+	}}
+	mailer := &failingMailer{sent: make(chan Email, 1)}
+	s := &AuthService{queries: database.New(db), config: createTestConfig(), mailer: mailer}
+
+	if err := s.RequestPasswordReset(context.Background(), "ada@example.com"); err != nil {
+		t.Fatalf("RequestPasswordReset() error = %v, want nil as for an unknown address", err)
+	}
+
+	select {
+	case email := <-mailer.sent:
+		if email.To != "ada@example.com" {
+			t.Errorf("sent to %q, want ada@example.com", email.To)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no password reset email was sent")
 	}
 }
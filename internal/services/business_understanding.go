@@ -0,0 +1,551 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrUnderstandingNotFound       = errors.New("business understanding not found")
+	ErrUnderstandingChangeNotFound = errors.New("business understanding change not found")
+)
+
+// Sources record who made a change, both in field provenance and the change log
+const (
+	UnderstandingSourceTool   = "tool"
+	UnderstandingSourceUser   = "user"
+	UnderstandingSourceRevert = "revert"
+)
+
+// BusinessUnderstandingService exposes business understanding to users
+// so they can review and correct what the model recorded
+type BusinessUnderstandingService struct {
+	queries *database.Queries
+	schema  *UnderstandingSchema
+}
+
+// NewBusinessUnderstandingService creates a new business understanding service
+func NewBusinessUnderstandingService(queries *database.Queries, schema *UnderstandingSchema) *BusinessUnderstandingService {
+	return &BusinessUnderstandingService{
+		queries: queries,
+		schema:  schema,
+	}
+}
+
+// FieldProvenance describes where a field's current value came from
+type FieldProvenance struct {
+	Source     string     `json:"source"`
+	SessionID  *uuid.UUID `json:"session_id,omitempty"`
+	MessageID  *uuid.UUID `json:"message_id,omitempty"`
+	ToolName   string     `json:"tool_name,omitempty"`
+	ToolCallID string     `json:"tool_call_id,omitempty"`
+	SetAt      time.Time  `json:"set_at"`
+}
+
+// BusinessUnderstandingView is the user-facing business understanding with per-field provenance
+type BusinessUnderstandingView struct {
+	Context    *BusinessContext           `json:"understanding"`
+	Provenance map[string]FieldProvenance `json:"provenance"`
+	CreatedAt  time.Time                  `json:"created_at"`
+	UpdatedAt  time.Time                  `json:"updated_at"`
+}
+
+// UnderstandingChange is one entry in the business understanding change log
+type UnderstandingChange struct {
+	ID               uuid.UUID        `json:"id"`
+	Source           string           `json:"source"`
+	SessionID        *uuid.UUID       `json:"session_id,omitempty"`
+	MessageID        *uuid.UUID       `json:"message_id,omitempty"`
+	ToolName         string           `json:"tool_name,omitempty"`
+	ToolCallID       string           `json:"tool_call_id,omitempty"`
+	ChangedFields    []string         `json:"changed_fields"`
+	Before           *BusinessContext `json:"before"`
+	After            *BusinessContext `json:"after"`
+	RevertedChangeID *uuid.UUID       `json:"reverted_change_id,omitempty"`
+	CreatedAt        time.Time        `json:"created_at"`
+}
+
+// changeOrigin describes who made a change, for provenance and the change log
+type changeOrigin struct {
+	source           string
+	invocation       *ToolInvocation
+	revertedChangeID *uuid.UUID
+}
+
+// Get returns the user's business understanding with provenance
+func (s *BusinessUnderstandingService) Get(ctx context.Context, userID uuid.UUID) (*BusinessUnderstandingView, error) {
+	understanding, err := s.queries.GetBusinessUnderstanding(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUnderstandingNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get business understanding: %w", err)
+	}
+
+	return s.buildView(ctx, understanding)
+}
+
+// Schema returns the schema that defines the understanding's fields
+func (s *BusinessUnderstandingService) Schema() *UnderstandingSchema {
+	return s.schema
+}
+
+// Update applies a user edit and marks the changed fields as user-sourced.
+// Fields missing from the patch are unchanged; an empty string or empty list clears the field.
+// Returns an *UnderstandingValidationError if the patch doesn't match the schema.
+func (s *BusinessUnderstandingService) Update(ctx context.Context, userID uuid.UUID, patch *BusinessContext) (*BusinessUnderstandingView, error) {
+	if err := s.schema.Validate(patch, FieldScopePersonal); err != nil {
+		return nil, err
+	}
+
+	understanding, err := s.write(ctx, userID, changeOrigin{source: UnderstandingSourceUser}, func(before *BusinessContext, _ bool) (*BusinessContext, error) {
+		return applyUnderstandingPatch(before, patch), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.buildView(ctx, understanding)
+}
+
+// Delete removes the user's business understanding. The deletion is recorded
+// in the change log so it can be reverted like any other change.
+func (s *BusinessUnderstandingService) Delete(ctx context.Context, userID uuid.UUID) error {
+	_, err := s.write(ctx, userID, changeOrigin{source: UnderstandingSourceUser}, func(*BusinessContext, bool) (*BusinessContext, error) {
+		return nil, nil
+	})
+	return err
+}
+
+// ListChanges returns the user's change log, newest first, with the total number of changes
+func (s *BusinessUnderstandingService) ListChanges(ctx context.Context, userID uuid.UUID, limit, offset int32) ([]UnderstandingChange, int64, error) {
+	rows, err := s.queries.ListUnderstandingChanges(ctx, database.ListUnderstandingChangesParams{
+		UserID: userID,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list changes: %w", err)
+	}
+
+	total, err := s.queries.CountUnderstandingChanges(ctx, userID)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count changes: %w", err)
+	}
+
+	changes := make([]UnderstandingChange, len(rows))
+	for i, row := range rows {
+		changes[i] = changeFromRow(row)
+	}
+	return changes, total, nil
+}
+
+// Revert restores the understanding to the snapshot taken just before the given change.
+// The revert is itself recorded, so it can be undone too.
+func (s *BusinessUnderstandingService) Revert(ctx context.Context, userID, changeID uuid.UUID) (*BusinessUnderstandingView, error) {
+	change, err := s.queries.GetUnderstandingChange(ctx, database.GetUnderstandingChangeParams{
+		ID:     changeID,
+		UserID: userID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUnderstandingChangeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get change: %w", err)
+	}
+
+	origin := changeOrigin{source: UnderstandingSourceRevert, revertedChangeID: &change.ID}
+	understanding, err := s.write(ctx, userID, origin, func(*BusinessContext, bool) (*BusinessContext, error) {
+		return snapshotToBusinessContext(change.BeforeSnapshot), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.buildView(ctx, understanding)
+}
+
+// WriteFromTool applies a write made by the tool call described in ctx, recording
+// provenance and a change log entry for it. See write for how apply is used.
+func (s *BusinessUnderstandingService) WriteFromTool(ctx context.Context, userID uuid.UUID, apply understandingWriteFunc) (database.BusinessUnderstanding, error) {
+	origin := changeOrigin{source: UnderstandingSourceTool}
+	if inv, ok := ToolInvocationFromContext(ctx); ok {
+		origin.invocation = &inv
+	}
+	return s.write(ctx, userID, origin, apply)
+}
+
+// understandingWriteFunc computes the new understanding from the current one.
+// found is false when the user has none yet; returning nil deletes the understanding.
+type understandingWriteFunc func(before *BusinessContext, found bool) (*BusinessContext, error)
+
+// write locks and reads the user's understanding, then saves what apply returns together
+// with its provenance and change log entry in one transaction, so concurrent writers can't
+// lose each other's changes and no change goes unlogged.
+// The returned row is zero-valued when the understanding was deleted.
+func (s *BusinessUnderstandingService) write(ctx context.Context, userID uuid.UUID, origin changeOrigin, apply understandingWriteFunc) (database.BusinessUnderstanding, error) {
+	var understanding database.BusinessUnderstanding
+	err := s.queries.InTx(ctx, func(q *database.Queries) error {
+		if err := q.LockUserUnderstanding(ctx, userID); err != nil {
+			return fmt.Errorf("failed to lock business understanding: %w", err)
+		}
+
+		existing, err := q.GetBusinessUnderstanding(ctx, userID)
+		found := err == nil
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("failed to get business understanding: %w", err)
+		}
+
+		before := understandingToBusinessContext(existing)
+		after, err := apply(before, found)
+		if err != nil {
+			return err
+		}
+
+		if after == nil {
+			if !found {
+				return nil
+			}
+			if err := q.DeleteBusinessUnderstanding(ctx, userID); err != nil {
+				return fmt.Errorf("failed to delete business understanding: %w", err)
+			}
+			return s.recordChange(ctx, q, userID, uuid.Nil, before, NewBusinessContext(), origin)
+		}
+
+		understanding, err = q.ReplaceBusinessUnderstanding(ctx, businessContextToReplaceParams(userID, after))
+		if err != nil {
+			return fmt.Errorf("failed to save business understanding: %w", err)
+		}
+		return s.recordChange(ctx, q, userID, understanding.ID, before, after, origin)
+	})
+	if err != nil {
+		return database.BusinessUnderstanding{}, err
+	}
+	return understanding, nil
+}
+
+// recordChange updates field provenance and appends to the change log using q.
+// understandingID may be nil when the understanding was deleted.
+func (s *BusinessUnderstandingService) recordChange(ctx context.Context, q *database.Queries, userID, understandingID uuid.UUID, before, after *BusinessContext, origin changeOrigin) error {
+	changed := changedUnderstandingFields(before, after)
+	if len(changed) == 0 {
+		return nil
+	}
+
+	var sessionID, messageID *uuid.UUID
+	var toolName, toolCallID *string
+	if inv := origin.invocation; inv != nil {
+		if inv.SessionID != uuid.Nil {
+			sessionID = &inv.SessionID
+		}
+		if inv.MessageID != uuid.Nil {
+			messageID = &inv.MessageID
+		}
+		toolName = stringPtrOrNil(inv.ToolName)
+		toolCallID = stringPtrOrNil(inv.ToolCallID)
+	}
+
+	if understandingID != uuid.Nil {
+		// Reverts are a user action, so the restored fields are attributed to the user
+		source := origin.source
+		if source == UnderstandingSourceRevert {
+			source = UnderstandingSourceUser
+		}
+		for _, field := range changed {
+			err := q.UpsertUnderstandingProvenance(ctx, database.UpsertUnderstandingProvenanceParams{
+				UnderstandingID: understandingID,
+				FieldName:       field,
+				Source:          source,
+				SessionID:       sessionID,
+				MessageID:       messageID,
+				ToolName:        toolName,
+				ToolCallID:      toolCallID,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to record provenance: %w", err)
+			}
+		}
+	}
+
+	beforeJSON, err := json.Marshal(before)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+	afterJSON, err := json.Marshal(after)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	_, err = q.CreateUnderstandingChange(ctx, database.CreateUnderstandingChangeParams{
+		UserID:           userID,
+		Source:           origin.source,
+		SessionID:        sessionID,
+		MessageID:        messageID,
+		ToolName:         toolName,
+		ToolCallID:       toolCallID,
+		ChangedFields:    changed,
+		BeforeSnapshot:   beforeJSON,
+		AfterSnapshot:    afterJSON,
+		RevertedChangeID: origin.revertedChangeID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record change: %w", err)
+	}
+	return nil
+}
+
+func (s *BusinessUnderstandingService) buildView(ctx context.Context, understanding database.BusinessUnderstanding) (*BusinessUnderstandingView, error) {
+	rows, err := s.queries.ListUnderstandingProvenance(ctx, understanding.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provenance: %w", err)
+	}
+
+	provenance := make(map[string]FieldProvenance, len(rows))
+	for _, row := range rows {
+		provenance[row.FieldName] = FieldProvenance{
+			Source:     row.Source,
+			SessionID:  row.SessionID,
+			MessageID:  row.MessageID,
+			ToolName:   derefStringPtr(row.ToolName),
+			ToolCallID: derefStringPtr(row.ToolCallID),
+			SetAt:      row.SetAt,
+		}
+	}
+
+	return &BusinessUnderstandingView{
+		Context:    understandingToBusinessContext(understanding),
+		Provenance: provenance,
+		CreatedAt:  understanding.CreatedAt.Time,
+		UpdatedAt:  understanding.UpdatedAt.Time,
+	}, nil
+}
+
+// understandingToBusinessContext decodes a database row's document.
+// A zero-value row yields an empty context.
+func understandingToBusinessContext(u database.BusinessUnderstanding) *BusinessContext {
+	return snapshotToBusinessContext(u.Data)
+}
+
+// changeFromRow converts a change log row to its API representation
+func changeFromRow(row database.BusinessUnderstandingChange) UnderstandingChange {
+	return UnderstandingChange{
+		ID:               row.ID,
+		Source:           row.Source,
+		SessionID:        row.SessionID,
+		MessageID:        row.MessageID,
+		ToolName:         derefStringPtr(row.ToolName),
+		ToolCallID:       derefStringPtr(row.ToolCallID),
+		ChangedFields:    row.ChangedFields,
+		Before:           snapshotToBusinessContext(row.BeforeSnapshot),
+		After:            snapshotToBusinessContext(row.AfterSnapshot),
+		RevertedChangeID: row.RevertedChangeID,
+		CreatedAt:        row.CreatedAt,
+	}
+}
+
+// snapshotToBusinessContext decodes a stored document; invalid data yields an empty context
+func snapshotToBusinessContext(data []byte) *BusinessContext {
+	bc := NewBusinessContext()
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, bc); err != nil {
+			return NewBusinessContext()
+		}
+	}
+	return bc
+}
+
+// businessContextToReplaceParams converts a BusinessContext to exact-write parameters
+func businessContextToReplaceParams(userID uuid.UUID, bc *BusinessContext) database.ReplaceBusinessUnderstandingParams {
+	data, _ := json.Marshal(bc)
+	return database.ReplaceBusinessUnderstandingParams{
+		UserID: userID,
+		Data:   data,
+	}
+}
+
+// applyUnderstandingPatch returns a copy of bc with the patch applied.
+// Lists are deduplicated; empty values remove the field.
+func applyUnderstandingPatch(bc, patch *BusinessContext) *BusinessContext {
+	result := bc.Clone()
+	for name, value := range patch.Strings {
+		result.Set(name, value)
+	}
+	for name, items := range patch.Lists {
+		result.SetList(name, mergeStringArrays(nil, items)) // dedupe, keep order
+	}
+	return result
+}
+
+// changedUnderstandingFields returns the names of fields that differ between two contexts, sorted
+func changedUnderstandingFields(before, after *BusinessContext) []string {
+	names := make(map[string]bool)
+	for _, bc := range []*BusinessContext{before, after} {
+		for _, name := range bc.FieldNames() {
+			names[name] = true
+		}
+	}
+
+	var changed []string
+	for name := range names {
+		if before.Get(name) != after.Get(name) || !reflect.DeepEqual(before.List(name), after.List(name)) {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// BusinessContext is a business understanding document keyed by schema field name.
+// It encodes as a flat JSON object of strings and string lists, e.g.
+// {"industry": "retail", "pain_points": ["returns"]}.
+type BusinessContext struct {
+	Strings map[string]string
+	Lists   map[string][]string
+}
+
+// NewBusinessContext creates an empty business context
+func NewBusinessContext() *BusinessContext {
+	return &BusinessContext{
+		Strings: map[string]string{},
+		Lists:   map[string][]string{},
+	}
+}
+
+// Get returns a string field, or "" if it isn't set
+func (bc *BusinessContext) Get(name string) string {
+	if bc == nil {
+		return ""
+	}
+	return bc.Strings[name]
+}
+
+// List returns a list field, or nil if it isn't set
+func (bc *BusinessContext) List(name string) []string {
+	if bc == nil || len(bc.Lists[name]) == 0 {
+		return nil
+	}
+	return bc.Lists[name]
+}
+
+// Set sets a string field; an empty value removes it
+func (bc *BusinessContext) Set(name, value string) {
+	if value == "" {
+		delete(bc.Strings, name)
+		return
+	}
+	if bc.Strings == nil {
+		bc.Strings = map[string]string{}
+	}
+	bc.Strings[name] = value
+}
+
+// SetList sets a list field; an empty list removes it
+func (bc *BusinessContext) SetList(name string, items []string) {
+	if len(items) == 0 {
+		delete(bc.Lists, name)
+		return
+	}
+	if bc.Lists == nil {
+		bc.Lists = map[string][]string{}
+	}
+	bc.Lists[name] = items
+}
+
+// FieldNames returns the names of all fields present, sorted
+func (bc *BusinessContext) FieldNames() []string {
+	if bc == nil {
+		return nil
+	}
+	names := make([]string, 0, len(bc.Strings)+len(bc.Lists))
+	for name := range bc.Strings {
+		names = append(names, name)
+	}
+	for name := range bc.Lists {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsEmpty reports whether no field has a value
+func (bc *BusinessContext) IsEmpty() bool {
+	if bc == nil {
+		return true
+	}
+	for _, v := range bc.Strings {
+		if v != "" {
+			return false
+		}
+	}
+	for _, v := range bc.Lists {
+		if len(v) > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Clone returns a deep copy
+func (bc *BusinessContext) Clone() *BusinessContext {
+	result := NewBusinessContext()
+	if bc == nil {
+		return result
+	}
+	for name, value := range bc.Strings {
+		result.Strings[name] = value
+	}
+	for name, items := range bc.Lists {
+		result.Lists[name] = append([]string(nil), items...)
+	}
+	return result
+}
+
+// MarshalJSON encodes the context as a flat JSON object
+func (bc BusinessContext) MarshalJSON() ([]byte, error) {
+	fields := make(map[string]interface{}, len(bc.Strings)+len(bc.Lists))
+	for name, value := range bc.Strings {
+		fields[name] = value
+	}
+	for name, items := range bc.Lists {
+		fields[name] = items
+	}
+	return json.Marshal(fields)
+}
+
+// UnmarshalJSON decodes a flat JSON object of strings and string lists.
+// Empty values are kept so a decoded patch can clear fields; nulls are skipped.
+func (bc *BusinessContext) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	bc.Strings = make(map[string]string)
+	bc.Lists = make(map[string][]string)
+	for name, raw := range fields {
+		var str string
+		var list []string
+		switch {
+		case string(raw) == "null":
+		case json.Unmarshal(raw, &str) == nil:
+			bc.Strings[name] = str
+		case json.Unmarshal(raw, &list) == nil:
+			if list == nil {
+				list = []string{}
+			}
+			bc.Lists[name] = list
+		default:
+			return fmt.Errorf("field %q must be a string or a list of strings", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,237 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/agpt-go/chatbot-api/internal/database"
+	"github.com/google/uuid"
+)
+
+// testContext builds a business context from string and list fields
+func testContext(strs map[string]string, lists map[string][]string) *BusinessContext {
+	bc := NewBusinessContext()
+	for name, value := range strs {
+		bc.Set(name, value)
+	}
+	for name, items := range lists {
+		bc.SetList(name, items)
+	}
+	return bc
+}
+
+func TestApplyUnderstandingPatch(t *testing.T) {
+	base := testContext(
+		map[string]string{"user_name": "Alice", "industry": "retail"},
+		map[string][]string{"pain_points": {"manual invoicing", "slow support"}},
+	)
+
+	t.Run("omitted fields are unchanged", func(t *testing.T) {
+		result := applyUnderstandingPatch(base, NewBusinessContext())
+		if !reflect.DeepEqual(result, base) {
+			t.Errorf("applyUnderstandingPatch() = %+v, want %+v", result, base)
+		}
+	})
+
+	t.Run("sets and clears fields", func(t *testing.T) {
+		patch := NewBusinessContext()
+		if err := json.Unmarshal([]byte(`{"industry":"e-commerce","user_name":"","pain_points":["returns","returns","shipping"]}`), patch); err != nil {
+			t.Fatalf("json.Unmarshal() error = %v", err)
+		}
+
+		result := applyUnderstandingPatch(base, patch)
+
+		if got := result.Get("industry"); got != "e-commerce" {
+			t.Errorf("industry = %q, want %q", got, "e-commerce")
+		}
+		if got := result.Get("user_name"); got != "" {
+			t.Errorf("user_name = %q, want empty", got)
+		}
+		if want := []string{"returns", "shipping"}; !reflect.DeepEqual(result.List("pain_points"), want) {
+			t.Errorf("pain_points = %v, want %v", result.List("pain_points"), want)
+		}
+	})
+
+	t.Run("does not modify the original", func(t *testing.T) {
+		_ = applyUnderstandingPatch(base, testContext(map[string]string{"industry": "finance"}, nil))
+		if got := base.Get("industry"); got != "retail" {
+			t.Errorf("original industry = %q, want %q", got, "retail")
+		}
+	})
+}
+
+func TestChangedUnderstandingFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		before *BusinessContext
+		after  *BusinessContext
+		want   []string
+	}{
+		{
+			name:   "no changes",
+			before: testContext(map[string]string{"industry": "retail"}, nil),
+			after:  testContext(map[string]string{"industry": "retail"}, nil),
+			want:   nil,
+		},
+		{
+			name:   "field set",
+			before: NewBusinessContext(),
+			after:  testContext(map[string]string{"industry": "retail"}, map[string][]string{"pain_points": {"a"}}),
+			want:   []string{"industry", "pain_points"},
+		},
+		{
+			name:   "field cleared",
+			before: testContext(map[string]string{"user_name": "Alice", "industry": "retail"}, nil),
+			after:  testContext(map[string]string{"industry": "retail"}, nil),
+			want:   []string{"user_name"},
+		},
+		{
+			name:   "list changed",
+			before: testContext(nil, map[string][]string{"pain_points": {"a"}}),
+			after:  testContext(nil, map[string][]string{"pain_points": {"a", "b"}}),
+			want:   []string{"pain_points"},
+		},
+		{
+			name:   "empty list equals missing list",
+			before: NewBusinessContext(),
+			after:  &BusinessContext{Lists: map[string][]string{"pain_points": {}}},
+			want:   nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := changedUnderstandingFields(tt.before, tt.after)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("changedUnderstandingFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSnapshotToBusinessContext(t *testing.T) {
+	t.Run("round trips a snapshot", func(t *testing.T) {
+		want := testContext(map[string]string{"industry": "retail"}, map[string][]string{"pain_points": {"returns"}})
+		data, err := json.Marshal(want)
+		if err != nil {
+			t.Fatalf("json.Marshal() error = %v", err)
+		}
+		if got := snapshotToBusinessContext(data); !reflect.DeepEqual(got, want) {
+			t.Errorf("snapshotToBusinessContext() = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("invalid data yields empty context", func(t *testing.T) {
+		got := snapshotToBusinessContext([]byte("not json"))
+		if !got.IsEmpty() {
+			t.Errorf("snapshotToBusinessContext() = %+v, want empty", got)
+		}
+	})
+}
+
+func TestBusinessContextJSON(t *testing.T) {
+	t.Run("decodes strings and lists and skips nulls", func(t *testing.T) {
+		var bc BusinessContext
+		if err := json.Unmarshal([]byte(`{"industry":"retail","pain_points":["returns"],"user_name":null}`), &bc); err != nil {
+			t.Fatalf("json.Unmarshal() error = %v", err)
+		}
+		if bc.Get("industry") != "retail" || !reflect.DeepEqual(bc.List("pain_points"), []string{"returns"}) {
+			t.Errorf("decoded = %+v", bc)
+		}
+		if _, ok := bc.Strings["user_name"]; ok {
+			t.Error("null user_name should be skipped")
+		}
+	})
+
+	t.Run("rejects other types", func(t *testing.T) {
+		var bc BusinessContext
+		if err := json.Unmarshal([]byte(`{"business_size":10}`), &bc); err == nil {
+			t.Error("json.Unmarshal() error = nil, want error for a number")
+		}
+	})
+
+	t.Run("encodes a flat object", func(t *testing.T) {
+		data, err := json.Marshal(testContext(map[string]string{"industry": "retail"}, map[string][]string{"pain_points": {"returns"}}))
+		if err != nil {
+			t.Fatalf("json.Marshal() error = %v", err)
+		}
+		if want := `{"industry":"retail","pain_points":["returns"]}`; string(data) != want {
+			t.Errorf("json.Marshal() = %s, want %s", data, want)
+		}
+	})
+}
+
+func TestToolInvocationContext(t *testing.T) {
+	t.Run("missing invocation", func(t *testing.T) {
+		if _, ok := ToolInvocationFromContext(context.Background()); ok {
+			t.Error("ToolInvocationFromContext() ok = true, want false")
+		}
+	})
+
+	t.Run("registry fills in tool name", func(t *testing.T) {
+		registry := NewToolRegistry()
+		var got ToolInvocation
+		registry.Register("echo", ToolDefinition{Name: "echo"}, func(ctx context.Context, userID uuid.UUID, arguments string) (*ToolResult, error) {
+			got, _ = ToolInvocationFromContext(ctx)
+			return &ToolResult{Success: true}, nil
+		})
+
+		sessionID := uuid.New()
+		ctx := WithToolInvocation(context.Background(), ToolInvocation{SessionID: sessionID, ToolCallID: "call_1"})
+		if _, err := registry.Execute(ctx, uuid.New(), "echo", "{}"); err != nil {
+			t.Fatalf("Execute() error = %v", err)
+		}
+
+		if got.SessionID != sessionID || got.ToolCallID != "call_1" || got.ToolName != "echo" {
+			t.Errorf("invocation = %+v, want session %s, call_1, echo", got, sessionID)
+		}
+	})
+}
+
+func TestUnderstandingWriteIsTransactional(t *testing.T) {
+	patch := testContext(map[string]string{"industry": "Retail"}, nil)
+	apply := func(before *BusinessContext, _ bool) (*BusinessContext, error) {
+		return applyUnderstandingPatch(before, patch), nil
+	}
+
+	t.Run("commits the write with its change log", func(t *testing.T) {
+		db := &fakeQueryDB{rows: map[string][]any{
+			"ReplaceBusinessUnderstanding": {uuid.New()},
+			"CreateUnderstandingChange":    {uuid.New()},
+		}}
+		s := &BusinessUnderstandingService{queries: database.New(db)}
+
+		if _, err := s.write(context.Background(), uuid.New(), changeOrigin{source: UnderstandingSourceUser}, apply); err != nil {
+			t.Fatalf("write() error = %v", err)
+		}
+
+		want := []string{"LockUserUnderstanding", "GetBusinessUnderstanding", "ReplaceBusinessUnderstanding", "UpsertUnderstandingProvenance", "CreateUnderstandingChange"}
+		if !slices.Equal(db.ran, want) {
+			t.Errorf("ran %v, want %v", db.ran, want)
+		}
+		if !db.committed {
+			t.Error("transaction was not committed")
+		}
+	})
+
+	t.Run("fails without committing when the change log can't be written", func(t *testing.T) {
+		logErr := errors.New("insert failed")
+		db := &fakeQueryDB{
+			rows: map[string][]any{"ReplaceBusinessUnderstanding": {uuid.New()}},
+			fail: map[string]error{"CreateUnderstandingChange": logErr},
+		}
+		s := &BusinessUnderstandingService{queries: database.New(db)}
+
+		_, err := s.write(context.Background(), uuid.New(), changeOrigin{source: UnderstandingSourceUser}, apply)
+		if !errors.Is(err, logErr) {
+			t.Fatalf("write() error = %v, want %v", err, logErr)
+		}
+		if db.committed {
+			t.Error("transaction was committed")
+		}
+	})
+}
@@ -2,9 +2,12 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"slices"
 
 	"github.com/agpt-go/chatbot-api/internal/database"
+	"github.com/agpt-go/chatbot-api/internal/logging"
 	"github.com/google/uuid"
 )
 
@@ -13,17 +16,25 @@ type ChatService struct {
 	llmService   *LLMService
 	toolService  *ToolService
 	toolExecutor *ToolExecutor
+	profiles     *AgentProfiles
 }
 
-func NewChatService(queries *database.Queries, llmService *LLMService, analytics *AnalyticsService) *ChatService {
-	toolService := NewToolService(queries, analytics)
-	toolExecutor := NewToolExecutor(toolService)
-	return &ChatService{
+// NewChatService creates a chat service. A nil profiles uses the built-in agent profiles.
+func NewChatService(queries *database.Queries, llmService *LLMService, analytics *AnalyticsService, schema *UnderstandingSchema, profiles *AgentProfiles, toolConfig ToolExecutorConfig) *ChatService {
+	if profiles == nil {
+		profiles = DefaultAgentProfiles()
+	}
+	toolService := NewToolService(queries, analytics, schema)
+	toolExecutor := NewToolExecutor(toolService, toolConfig)
+	s := &ChatService{
 		queries:      queries,
 		llmService:   llmService,
 		toolService:  toolService,
 		toolExecutor: toolExecutor,
+		profiles:     profiles,
 	}
+	toolExecutor.sessionTools = s.sessionTools
+	return s
 }
 
 // GetToolExecutor returns the tool executor for handling tool calls
@@ -36,15 +47,33 @@ func (s *ChatService) GetToolService() *ToolService {
 	return s.toolService
 }
 
-// GetAvailableTools returns all available tool definitions
-func (s *ChatService) GetAvailableTools() []ToolDefinition {
-	return GetAllToolDefinitions()
+// AvailableTools returns the definitions of the tools the user can use,
+// including tools imported from MCP servers. Tools the user's policies deny
+// are left out.
+func (s *ChatService) AvailableTools(ctx context.Context, userID uuid.UUID) []ToolDefinition {
+	var tools []ToolDefinition
+	for _, def := range s.toolService.GetRegistry().GetDefinitions() {
+		if s.toolExecutor.policyFor(ctx, userID, def.Name) != ToolPolicyDeny {
+			tools = append(tools, def)
+		}
+	}
+	return tools
+}
+
+// ListProfiles returns the agent profiles users can create sessions from
+func (s *ChatService) ListProfiles() []AgentProfile {
+	return s.profiles.List()
 }
 
+// CreateSessionInput holds the options for a new session. Settings left
+// empty come from the profile, if one is given, and then from the defaults.
 type CreateSessionInput struct {
 	Title        string
 	Model        string
 	SystemPrompt string
+	Profile      string
+	Tools        *[]string // nil offers every tool; empty offers none
+	ToolChoice   string    // ToolChoiceAuto, ToolChoiceRequired, ToolChoiceNone or a tool name
 }
 
 type SendMessageInput struct {
@@ -58,17 +87,59 @@ func (s *ChatService) CreateSession(ctx context.Context, userID uuid.UUID, input
 		title = "New Chat"
 	}
 
-	model := input.Model
-	if model == "" {
-		model = "gpt-4o"
+	var profile AgentProfile
+	var profileName *string
+	if input.Profile != "" {
+		var err error
+		if profile, err = s.profiles.Get(input.Profile); err != nil {
+			return nil, err
+		}
+		profileName = &profile.Name
+	}
+
+	model := firstNonEmpty(input.Model, profile.Model, s.llmService.DefaultModel())
+	if !s.llmService.IsModelAllowed(model) {
+		return nil, fmt.Errorf("%w: %s", ErrModelNotAllowed, model)
+	}
+	systemPrompt := firstNonEmpty(input.SystemPrompt, profile.SystemPrompt)
+
+	tools := profile.Tools
+	if input.Tools != nil {
+		var err error
+		if tools, err = s.checkSessionTools(*input.Tools); err != nil {
+			return nil, err
+		}
+	}
+
+	// A profile's tool choice only applies to the profile's tools
+	toolChoice := input.ToolChoice
+	if toolChoice == "" && input.Tools == nil {
+		toolChoice = profile.ToolChoice
+	}
+	if err := checkToolChoice(toolChoice, tools); err != nil {
+		return nil, err
+	}
+	if toolChoice != "" && toolChoice != ToolChoiceAuto && toolChoice != ToolChoiceRequired && toolChoice != ToolChoiceNone {
+		if _, ok := s.toolService.GetRegistry().Get(toolChoice); !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownSessionTool, toolChoice)
+		}
 	}
 
-	session, err := s.queries.CreateChatSession(ctx, database.CreateChatSessionParams{
+	params := database.CreateChatSessionParams{
 		UserID:       userID,
 		Title:        &title,
 		Model:        &model,
-		SystemPrompt: &input.SystemPrompt,
-	})
+		SystemPrompt: &systemPrompt,
+		Profile:      profileName,
+	}
+	if tools != nil {
+		params.Tools = *tools
+	}
+	if toolChoice != "" {
+		params.ToolChoice = &toolChoice
+	}
+
+	session, err := s.queries.CreateChatSession(ctx, params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
@@ -186,22 +257,16 @@ func (s *ChatService) SendMessage(ctx context.Context, sessionID, userID uuid.UU
 	}
 
 	// Convert to LLM format
-	llmMessages := make([]ChatMessage, len(messages))
-	for i, msg := range messages {
-		llmMessages[i] = ChatMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-		}
-	}
+	llmMessages := toLLMMessages(messages)
 
 	// Build system prompt with business context
 	systemPrompt := s.buildEnhancedSystemPrompt(ctx, userID, session.SystemPrompt)
 
-	// Get available tools
-	tools := s.GetAvailableTools()
+	// Get the tools and model for this session
+	tools, opts := s.sessionChatOptions(ctx, userID, session)
 
 	// Generate response with tools
-	chatResp, err := s.llmService.ChatWithTools(ctx, llmMessages, systemPrompt, tools)
+	chatResp, err := s.llmService.ChatWithTools(ctx, llmMessages, systemPrompt, tools, opts...)
 	if err != nil {
 		return userMsg, nil, fmt.Errorf("failed to generate response: %w", err)
 	}
@@ -241,22 +306,16 @@ func (s *ChatService) SendMessageStream(ctx context.Context, sessionID, userID u
 	}
 
 	// Convert to LLM format
-	llmMessages := make([]ChatMessage, len(messages))
-	for i, msg := range messages {
-		llmMessages[i] = ChatMessage{
-			Role:    msg.Role,
-			Content: msg.Content,
-		}
-	}
+	llmMessages := toLLMMessages(messages)
 
 	// Build system prompt with business context
 	systemPrompt := s.buildEnhancedSystemPrompt(ctx, userID, session.SystemPrompt)
 
-	// Get available tools
-	tools := s.GetAvailableTools()
+	// Get the tools and model for this session
+	tools, opts := s.sessionChatOptions(ctx, userID, session)
 
 	// Start streaming with tools
-	chunks, err := s.llmService.ChatStreamWithTools(ctx, llmMessages, systemPrompt, tools)
+	chunks, err := s.llmService.ChatStreamWithTools(ctx, llmMessages, systemPrompt, tools, opts...)
 	if err != nil {
 		return userMsg, nil, err
 	}
@@ -264,6 +323,99 @@ func (s *ChatService) SendMessageStream(ctx context.Context, sessionID, userID u
 	return userMsg, chunks, nil
 }
 
+// sessionChatOptions returns the tools offered in a session and the options
+// selecting its model and tool choice. Sessions whose model is no longer
+// allowed use the default model.
+func (s *ChatService) sessionChatOptions(ctx context.Context, userID uuid.UUID, session *database.ChatSession) ([]ToolDefinition, []ChatOption) {
+	var opts []ChatOption
+	if session.Model != nil && s.llmService.IsModelAllowed(*session.Model) {
+		opts = append(opts, WithModel(*session.Model))
+	}
+
+	choice := derefToolChoice(session.ToolChoice)
+	if choice == ToolChoiceNone {
+		return nil, opts
+	}
+
+	tools := s.AvailableTools(ctx, userID)
+	if session.Tools != nil {
+		tools = filterTools(tools, session.Tools)
+	}
+
+	switch choice {
+	case ToolChoiceAuto:
+	case ToolChoiceRequired:
+		if len(tools) > 0 {
+			opts = append(opts, WithToolChoice(choice))
+		}
+	default:
+		// A tool that was removed or denied since the session was created falls back to auto
+		for _, def := range tools {
+			if def.Name == choice {
+				opts = append(opts, WithToolChoice(choice))
+				break
+			}
+		}
+	}
+
+	return tools, opts
+}
+
+// sessionTools returns the tools a session is restricted to; ok is false when
+// the session offers every tool
+func (s *ChatService) sessionTools(ctx context.Context, userID, sessionID uuid.UUID) (tools []string, ok bool, err error) {
+	session, err := s.GetSession(ctx, sessionID, userID)
+	if err != nil {
+		return nil, false, err
+	}
+	if derefToolChoice(session.ToolChoice) == ToolChoiceNone {
+		return []string{}, true, nil
+	}
+	return session.Tools, session.Tools != nil, nil
+}
+
+// checkSessionTools validates a session's tool selection and removes duplicates
+func (s *ChatService) checkSessionTools(names []string) (*[]string, error) {
+	registry := s.toolService.GetRegistry()
+	tools := make([]string, 0, len(names))
+	for _, name := range names {
+		if _, ok := registry.Get(name); !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownSessionTool, name)
+		}
+		if !containsString(tools, name) {
+			tools = append(tools, name)
+		}
+	}
+	return &tools, nil
+}
+
+// filterTools keeps the definitions of the named tools
+func filterTools(tools []ToolDefinition, names []string) []ToolDefinition {
+	var filtered []ToolDefinition
+	for _, def := range tools {
+		if containsString(names, def.Name) {
+			filtered = append(filtered, def)
+		}
+	}
+	return filtered
+}
+
+func derefToolChoice(choice *string) string {
+	if choice == nil || *choice == "" {
+		return ToolChoiceAuto
+	}
+	return *choice
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
 // buildEnhancedSystemPrompt builds a system prompt enhanced with business context
 func (s *ChatService) buildEnhancedSystemPrompt(ctx context.Context, userID uuid.UUID, basePrompt *string) string {
 	var prompt string
@@ -289,3 +441,103 @@ func (s *ChatService) SaveStreamedResponse(ctx context.Context, sessionID uuid.U
 	tokens := s.llmService.EstimateTokens(content)
 	return s.SaveMessage(ctx, sessionID, "assistant", content, tokens)
 }
+
+// SaveToolCallStep saves a streamed model step that called tools: the
+// assistant message with the calls, then one tool message per result in call
+// order, so the next request shows the model what its tools returned
+func (s *ChatService) SaveToolCallStep(ctx context.Context, sessionID uuid.UUID, content string, results []ToolCallResult) error {
+	results = slices.Clone(results)
+	slices.SortFunc(results, func(a, b ToolCallResult) int { return a.Index - b.Index })
+
+	calls := make([]ToolCall, len(results))
+	for i, res := range results {
+		calls[i] = res.Call
+	}
+	callsJSON, err := json.Marshal(calls)
+	if err != nil {
+		return fmt.Errorf("failed to encode tool calls: %w", err)
+	}
+
+	tokens := int32(s.llmService.EstimateTokens(content))
+	_, err = s.queries.CreateChatMessageWithTools(ctx, database.CreateChatMessageWithToolsParams{
+		SessionID:  sessionID,
+		Role:       "assistant",
+		Content:    content,
+		TokensUsed: &tokens,
+		ToolCalls:  callsJSON,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save tool call message: %w", err)
+	}
+
+	for _, res := range results {
+		resultJSON, err := json.Marshal(res.Result)
+		if err != nil {
+			return fmt.Errorf("failed to encode tool result: %w", err)
+		}
+		callID, toolName := res.Call.ID, res.Call.Function.Name
+		tokens := int32(s.llmService.EstimateTokens(string(resultJSON)))
+		_, err = s.queries.CreateChatMessageWithTools(ctx, database.CreateChatMessageWithToolsParams{
+			SessionID:  sessionID,
+			Role:       "tool",
+			Content:    string(resultJSON),
+			TokensUsed: &tokens,
+			ToolCallID: &callID,
+			ToolName:   &toolName,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to save tool result message: %w", err)
+		}
+	}
+	return nil
+}
+
+// toLLMMessages converts stored messages to the LLM format. The model only
+// accepts tool results right after the assistant message that made the
+// calls, so a result whose call was cut off by the history limit is dropped,
+// as are calls left without a result.
+func toLLMMessages(messages []database.ChatMessage) []ChatMessage {
+	llmMessages := make([]ChatMessage, 0, len(messages))
+	for i := 0; i < len(messages); i++ {
+		msg := messages[i]
+		if msg.Role == "tool" {
+			continue // Not preceded by its call
+		}
+		llmMsg := ChatMessage{Role: msg.Role, Content: msg.Content}
+
+		var calls []ToolCall
+		if len(msg.ToolCalls) > 0 {
+			if err := json.Unmarshal(msg.ToolCalls, &calls); err != nil {
+				logging.Warn("skipping invalid stored tool calls", "messageID", msg.ID.String(), "error", err)
+			}
+		}
+		if len(calls) == 0 {
+			llmMessages = append(llmMessages, llmMsg)
+			continue
+		}
+
+		// Pair the calls with the results that follow them
+		results := make(map[string]ChatMessage)
+		for i+1 < len(messages) && messages[i+1].Role == "tool" {
+			i++
+			result := messages[i]
+			callID := derefString(result.ToolCallID)
+			results[callID] = ChatMessage{
+				Role:       "tool",
+				Content:    result.Content,
+				ToolCallID: callID,
+				Name:       derefString(result.ToolName),
+			}
+		}
+		var answers []ChatMessage
+		for _, call := range calls {
+			if result, ok := results[call.ID]; ok {
+				llmMsg.ToolCalls = append(llmMsg.ToolCalls, call)
+				answers = append(answers, result)
+			}
+		}
+		llmMessages = append(llmMessages, llmMsg)
+		llmMessages = append(llmMessages, answers...)
+	}
+	return llmMessages
+}
@@ -1,10 +1,16 @@
 package services
 
 import (
+	"context"
+	"encoding/json"
+	"slices"
 	"testing"
 
 	"github.com/agpt-go/chatbot-api/internal/config"
+	"github.com/agpt-go/chatbot-api/internal/database"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
 func TestNewChatService(t *testing.T) {
@@ -14,7 +20,7 @@ func TestNewChatService(t *testing.T) {
 	}
 	llmService := NewLLMService(llmCfg)
 
-	svc := NewChatService(nil, llmService, nil)
+	svc := NewChatService(nil, llmService, nil, DefaultUnderstandingSchema(), nil, ToolExecutorConfig{Policies: DefaultToolPolicies()})
 
 	if svc == nil {
 		t.Fatal("NewChatService() returned nil")
@@ -73,3 +79,87 @@ func TestCreateSessionInputDefaults(t *testing.T) {
 		}
 	})
 }
+
+func TestToLLMMessages(t *testing.T) {
+	str := func(s string) *string { return &s }
+	calls := []ToolCall{testToolCall("call_1", "a"), testToolCall("call_2", "b")}
+	callsJSON, err := json.Marshal(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	messages := []database.ChatMessage{
+		// Cut off from its call by the history limit
+		{Role: "tool", Content: `{"success":true}`, ToolCallID: str("call_0"), ToolName: str("a")},
+		{Role: "user", Content: "hi"},
+		{Role: "assistant", ToolCalls: callsJSON},
+		{Role: "tool", Content: `{"success":true}`, ToolCallID: str("call_1"), ToolName: str("a")},
+		// call_2 has no result, so it is dropped
+		{Role: "assistant", Content: "done"},
+	}
+
+	got := toLLMMessages(messages)
+	var roles []string
+	for _, msg := range got {
+		roles = append(roles, msg.Role)
+	}
+	if want := []string{"user", "assistant", "tool", "assistant"}; !slices.Equal(roles, want) {
+		t.Fatalf("roles = %v, want %v", roles, want)
+	}
+	if len(got[1].ToolCalls) != 1 || got[1].ToolCalls[0].ID != "call_1" {
+		t.Errorf("tool calls = %+v, want only call_1", got[1].ToolCalls)
+	}
+	if got[2].ToolCallID != "call_1" || got[2].Name != "a" {
+		t.Errorf("tool message = %+v, want the result of call_1", got[2])
+	}
+}
+
+// recordingMessageDB records the chat messages inserted through it
+type recordingMessageDB struct {
+	fakeQueryDB
+	inserted []database.CreateChatMessageWithToolsParams
+}
+
+func (db *recordingMessageDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	db.inserted = append(db.inserted, database.CreateChatMessageWithToolsParams{
+		Role:       args[1].(string),
+		Content:    args[2].(string),
+		ToolCalls:  args[4].([]byte),
+		ToolCallID: args[5].(*string),
+	})
+	return fakeRow{values: []any{uuid.New(), uuid.New(), args[1], args[2], args[3], pgtype.Timestamptz{}, args[4], args[5], args[6]}}
+}
+
+func TestSaveToolCallStepKeepsCallOrder(t *testing.T) {
+	db := &recordingMessageDB{}
+	svc := &ChatService{
+		queries:    database.New(db),
+		llmService: NewLLMService(&config.OpenAIConfig{APIKey: "test-api-key", Model: "gpt-4o"}),
+	}
+
+	// Results in completion order
+	results := []ToolCallResult{
+		{Index: 1, Call: testToolCall("call_b", "b"), Result: &ToolExecutionResult{Success: true}},
+		{Index: 0, Call: testToolCall("call_a", "a"), Result: &ToolExecutionResult{Success: false, Error: "failed"}},
+	}
+	if err := svc.SaveToolCallStep(context.Background(), uuid.New(), "Let me check", results); err != nil {
+		t.Fatalf("SaveToolCallStep() error = %v", err)
+	}
+
+	if len(db.inserted) != 3 {
+		t.Fatalf("inserted %d messages, want 3", len(db.inserted))
+	}
+	var calls []ToolCall
+	if err := json.Unmarshal(db.inserted[0].ToolCalls, &calls); err != nil {
+		t.Fatal(err)
+	}
+	if db.inserted[0].Role != "assistant" || len(calls) != 2 || calls[0].ID != "call_a" || calls[1].ID != "call_b" {
+		t.Errorf("assistant message = %+v with calls %+v, want call_a then call_b", db.inserted[0], calls)
+	}
+	for i, want := range []string{"call_a", "call_b"} {
+		msg := db.inserted[i+1]
+		if msg.Role != "tool" || msg.ToolCallID == nil || *msg.ToolCallID != want {
+			t.Errorf("message %d = %+v, want the result of %s", i+1, msg, want)
+		}
+	}
+}
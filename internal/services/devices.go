@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/database"
+	"github.com/agpt-go/chatbot-api/internal/logging"
+	"github.com/google/uuid"
+)
+
+var ErrDeviceNotFound = errors.New("device not found")
+
+// maxUserAgentLength bounds the user agent stored with a refresh token
+const maxUserAgentLength = 512
+
+// ClientInfo describes the client a request came from
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+}
+
+type clientInfoKey struct{}
+
+// WithClientInfo returns a context carrying the client of the request. Refresh
+// tokens issued with it record the client, so the user can recognise the device.
+func WithClientInfo(ctx context.Context, client ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, client)
+}
+
+func clientInfoFromContext(ctx context.Context) ClientInfo {
+	client, _ := ctx.Value(clientInfoKey{}).(ClientInfo)
+	return client
+}
+
+// Device is a sign-in that can still refresh its tokens. All refresh tokens of
+// one family belong to the same device, so the family ID identifies it.
+type Device struct {
+	ID         uuid.UUID
+	UserAgent  string
+	SignedInAt time.Time
+	LastUsedAt time.Time
+	ExpiresAt  time.Time
+}
+
+// ListDevices returns the devices the user is signed in on, most recently used first
+func (s *AuthService) ListDevices(ctx context.Context, userID uuid.UUID) ([]Device, error) {
+	tokens, err := s.queries.ListUserDevices(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list devices: %w", err)
+	}
+
+	devices := make([]Device, 0, len(tokens))
+	for _, token := range tokens {
+		device := Device{
+			ID:         token.FamilyID,
+			SignedInAt: token.SignedInAt,
+			ExpiresAt:  token.ExpiresAt,
+		}
+		if token.UserAgent != nil {
+			device.UserAgent = *token.UserAgent
+		}
+		// A token is issued when the device signs in or refreshes
+		if token.CreatedAt.Valid {
+			device.LastUsedAt = token.CreatedAt.Time
+		}
+		devices = append(devices, device)
+	}
+	return devices, nil
+}
+
+// RevokeDevice signs the user out of one device, rejecting the access tokens
+// already issued to it as well as its refresh token
+func (s *AuthService) RevokeDevice(ctx context.Context, userID, deviceID uuid.UUID) error {
+	revoked, err := s.queries.RevokeUserRefreshTokenFamily(ctx, database.RevokeUserRefreshTokenFamilyParams{
+		UserID:   userID,
+		FamilyID: deviceID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revoke device: %w", err)
+	}
+	if revoked == 0 {
+		return ErrDeviceNotFound
+	}
+
+	// Access tokens carry the family as sid; reject them until the last one expires
+	err = s.queries.RevokeSession(ctx, database.RevokeSessionParams{
+		FamilyID:  deviceID,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(s.config.JWT.AccessExpiresIn),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revoke device access tokens: %w", err)
+	}
+	s.revocations.forget(userID)
+
+	if _, err := s.queries.DeleteExpiredRevokedSessions(ctx); err != nil {
+		logging.Warn("failed to delete expired revoked sessions", "error", err)
+	}
+	return nil
+}
+
+// clientDetails returns the user agent and IP hash to store with a refresh token
+func (s *AuthService) clientDetails(ctx context.Context) (userAgent, ipHash *string) {
+	client := clientInfoFromContext(ctx)
+	if client.UserAgent != "" {
+		ua := client.UserAgent
+		if runes := []rune(ua); len(runes) > maxUserAgentLength {
+			ua = string(runes[:maxUserAgentLength])
+		}
+		userAgent = &ua
+	}
+	if client.IP != "" {
+		hash := s.hashIP(client.IP)
+		ipHash = &hash
+	}
+	return userAgent, ipHash
+}
+
+// hashIP hashes an IP address with a key derived from the JWT secret, so
+// stored hashes can't be reversed by hashing every address
+func (s *AuthService) hashIP(ip string) string {
+	mac := hmac.New(sha256.New, []byte(s.config.JWT.Secret))
+	mac.Write([]byte("client-ip:" + ip))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/database"
+	"github.com/agpt-go/chatbot-api/internal/logging"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	ErrEmailAlreadyVerified  = errors.New("email already verified")
+	ErrVerificationThrottled = errors.New("verification email sent recently")
+	ErrMailerNotConfigured   = errors.New("mailer not configured")
+)
+
+const (
+	// Token purposes. Each purpose signs with its own key, so a token issued
+	// for one can't be used for another or as an access token.
+	tokenPurposeVerifyEmail = "verify_email"
+
+	verificationSentCachePrefix = "email_verification_sent:"
+	verificationResendInterval  = time.Minute
+	defaultVerificationExpiry   = 24 * time.Hour
+)
+
+// actionClaims are the claims of a single-purpose token sent to a user by email
+type actionClaims struct {
+	Email   string `json:"email"`
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+// SetMailer sets the mailer used for account emails
+func (s *AuthService) SetMailer(mailer Mailer) {
+	s.mailer = mailer
+}
+
+// SendVerificationEmail emails the user a link to verify their address
+func (s *AuthService) SendVerificationEmail(ctx context.Context, userID uuid.UUID) error {
+	user, err := s.queries.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	return s.sendVerificationEmail(ctx, &user)
+}
+
+// ResendVerificationEmail emails a new verification link to the account with
+// the given address. It reports success whether or not such an unverified
+// account exists, so callers can't use it to discover accounts.
+func (s *AuthService) ResendVerificationEmail(ctx context.Context, email string) error {
+	user, err := s.queries.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil
+	}
+	err = s.sendVerificationEmail(ctx, &user)
+	if errors.Is(err, ErrEmailAlreadyVerified) || errors.Is(err, ErrVerificationThrottled) {
+		return nil
+	}
+	return err
+}
+
+// VerifyEmail marks the address a verification token was issued for as verified.
+// Verifying an already verified address succeeds.
+func (s *AuthService) VerifyEmail(ctx context.Context, token string) (*database.User, error) {
+	claims, err := s.parseActionToken(tokenPurposeVerifyEmail, token)
+	if err != nil {
+		return nil, err
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	user, err := s.queries.MarkUserEmailVerified(ctx, database.MarkUserEmailVerifiedParams{
+		ID:    userID,
+		Email: claims.Email,
+	})
+	if err != nil {
+		// The account is gone or its address changed since the token was issued
+		return nil, ErrInvalidToken
+	}
+	return &user, nil
+}
+
+// IsEmailVerified reports whether the user has verified their email address
+func (s *AuthService) IsEmailVerified(ctx context.Context, userID uuid.UUID) (bool, error) {
+	user, err := s.queries.GetUserByID(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get user: %w", err)
+	}
+	return user.EmailVerified != nil && *user.EmailVerified, nil
+}
+
+func (s *AuthService) sendVerificationEmail(ctx context.Context, user *database.User) error {
+	if user.EmailVerified != nil && *user.EmailVerified {
+		return ErrEmailAlreadyVerified
+	}
+	if s.mailer == nil {
+		return ErrMailerNotConfigured
+	}
+
+	cacheKey := verificationSentCachePrefix + user.ID.String()
+	if _, err := s.queries.GetCache(ctx, cacheKey); err == nil {
+		return ErrVerificationThrottled
+	}
+
+	expiresIn := s.config.Auth.EmailVerificationExpiresIn
+	if expiresIn <= 0 {
+		expiresIn = defaultVerificationExpiry
+	}
+	token, err := s.signActionToken(tokenPurposeVerifyEmail, user, expiresIn)
+	if err != nil {
+		return err
+	}
+
+	err = s.mailer.Send(ctx, Email{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Text: fmt.Sprintf("Hi %s,\n\nConfirm your email address by opening this link:\n\n%s\n\nThe link expires in %s. If you didn't create an account, you can ignore this email.\n",
+			user.Name, withQuery(s.config.Auth.EmailVerificationURL, "token", token), formatDuration(expiresIn)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
+
+	err = s.queries.SetCache(ctx, database.SetCacheParams{
+		Key:       cacheKey,
+		Value:     []byte("sent"),
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(verificationResendInterval), Valid: true},
+	})
+	if err != nil {
+		logging.Warn("failed to record verification email", "error", err, "userID", user.ID.String())
+	}
+	return nil
+}
+
+// signActionToken issues a token for purpose bound to the user's current email address
+func (s *AuthService) signActionToken(purpose string, user *database.User, expiresIn time.Duration) (string, error) {
+	now := time.Now()
+	claims := &actionClaims{
+		Email:   user.Email,
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiresIn)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    s.config.JWT.Issuer,
+			Subject:   user.ID.String(),
+		},
+	}
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.actionKey(purpose))
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return token, nil
+}
+
+// parseActionToken validates a token issued by signActionToken for purpose
+func (s *AuthService) parseActionToken(purpose, tokenString string) (*actionClaims, error) {
+	claims := &actionClaims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return s.actionKey(purpose), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrTokenExpired
+		}
+		return nil, ErrInvalidToken
+	}
+	if claims.Purpose != purpose {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// actionKey derives the signing key for a token purpose from the JWT secret
+func (s *AuthService) actionKey(purpose string) []byte {
+	mac := hmac.New(sha256.New, []byte(s.config.JWT.Secret))
+	mac.Write([]byte("action-token:" + purpose))
+	return mac.Sum(nil)
+}
+
+// withQuery adds a query parameter to a URL
+func withQuery(rawURL, key, value string) string {
+	sep := "?"
+	if strings.Contains(rawURL, "?") {
+		sep = "&"
+	}
+	return rawURL + sep + url.QueryEscape(key) + "=" + url.QueryEscape(value)
+}
+
+// formatDuration formats a duration as whole hours or minutes for emails
+func formatDuration(d time.Duration) string {
+	if d >= time.Hour && d%time.Hour == 0 {
+		if h := int(d / time.Hour); h != 1 {
+			return fmt.Sprintf("%d hours", h)
+		}
+		return "1 hour"
+	}
+	if m := int(d.Round(time.Minute) / time.Minute); m != 1 {
+		return fmt.Sprintf("%d minutes", m)
+	}
+	return "1 minute"
+}
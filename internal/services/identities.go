@@ -0,0 +1,255 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/database"
+	"github.com/agpt-go/chatbot-api/internal/logging"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	ErrAccountExists    = errors.New("an account with this email address already exists")
+	ErrInvalidLinkToken = errors.New("invalid or expired link token")
+	ErrIdentityInUse    = errors.New("identity is linked to another account")
+	ErrIdentityNotFound = errors.New("identity not found")
+	ErrLastLoginMethod  = errors.New("cannot remove the last login method")
+)
+
+const (
+	accountLinkCachePrefix = "account_link:"
+	accountLinkExpiration  = 10 * time.Minute
+)
+
+// AccountLinkRequiredError is returned when an OAuth sign-in matches an
+// existing account by verified email address. Nothing is linked until the
+// account's owner signs in to it and confirms with Token.
+type AccountLinkRequiredError struct {
+	Token    string
+	Provider string
+	Email    string
+}
+
+func (e *AccountLinkRequiredError) Error() string {
+	return fmt.Sprintf("signing in with %s requires linking it to the account of %s", e.Provider, e.Email)
+}
+
+// pendingAccountLink is kept in the cache until the account's owner confirms it
+type pendingAccountLink struct {
+	UserID     uuid.UUID `json:"user_id"`
+	Provider   string    `json:"provider"`
+	ProviderID string    `json:"provider_id"`
+	Email      string    `json:"email"`
+}
+
+// Identity is an OAuth account a user can sign in with
+type Identity struct {
+	ID         uuid.UUID
+	Provider   string
+	Email      string
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+}
+
+// oauthUser returns the user an OAuth identity signs in, creating the user on
+// their first sign-in. An identity whose email address belongs to another
+// account is not linked to it; a verified address returns an
+// AccountLinkRequiredError so the account's owner can confirm the link.
+func (s *AuthService) oauthUser(ctx context.Context, identity *OAuthIdentity) (*database.User, error) {
+	linked, err := s.queries.GetUserIdentity(ctx, database.GetUserIdentityParams{
+		Provider:   identity.Provider,
+		ProviderID: identity.Subject,
+	})
+	if err == nil {
+		if err := s.queries.TouchUserIdentity(ctx, linked.ID); err != nil {
+			logging.Warn("failed to update identity last use", "identityID", linked.ID.String(), "error", err)
+		}
+		user, err := s.queries.GetUserByID(ctx, linked.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+		return &user, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get identity: %w", err)
+	}
+
+	existing, err := s.queries.GetUserByEmail(ctx, identity.Email)
+	if err == nil {
+		if !identity.EmailVerified {
+			return nil, ErrAccountExists
+		}
+		return nil, s.requestAccountLink(ctx, existing.ID, identity)
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	user, err := s.queries.CreateUser(ctx, database.CreateUserParams{
+		Email:         identity.Email,
+		PasswordHash:  nil,
+		Name:          identity.Name,
+		Provider:      &identity.Provider,
+		ProviderID:    &identity.Subject,
+		EmailVerified: &identity.EmailVerified,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+	_, err = s.queries.CreateUserIdentity(ctx, database.CreateUserIdentityParams{
+		UserID:     user.ID,
+		Provider:   identity.Provider,
+		ProviderID: identity.Subject,
+		Email:      &identity.Email,
+	})
+	if err != nil {
+		// Don't leave behind an account nobody can sign in to
+		if err := s.queries.DeleteUser(ctx, user.ID); err != nil {
+			logging.Error("failed to delete user without identity", err, "userID", user.ID.String())
+		}
+		return nil, fmt.Errorf("failed to create identity: %w", err)
+	}
+	return &user, nil
+}
+
+// requestAccountLink stores a pending link of identity to a user and returns
+// the AccountLinkRequiredError carrying its token
+func (s *AuthService) requestAccountLink(ctx context.Context, userID uuid.UUID, identity *OAuthIdentity) error {
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return fmt.Errorf("failed to generate link token: %w", err)
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	value, err := json.Marshal(pendingAccountLink{
+		UserID:     userID,
+		Provider:   identity.Provider,
+		ProviderID: identity.Subject,
+		Email:      identity.Email,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode account link: %w", err)
+	}
+	err = s.queries.SetCache(ctx, database.SetCacheParams{
+		Key:       accountLinkCachePrefix + hashToken(token),
+		Value:     value,
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(accountLinkExpiration), Valid: true},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store account link: %w", err)
+	}
+
+	return &AccountLinkRequiredError{Token: token, Provider: identity.Provider, Email: identity.Email}
+}
+
+// ConfirmAccountLink links the identity of a pending account link to the
+// user, who must be the owner of the account it was requested for
+func (s *AuthService) ConfirmAccountLink(ctx context.Context, userID uuid.UUID, token string) (*Identity, error) {
+	if token == "" {
+		return nil, ErrInvalidLinkToken
+	}
+	cacheKey := accountLinkCachePrefix + hashToken(token)
+	cached, err := s.queries.GetCache(ctx, cacheKey)
+	if err != nil {
+		return nil, ErrInvalidLinkToken
+	}
+	var link pendingAccountLink
+	if err := json.Unmarshal(cached.Value, &link); err != nil || link.UserID != userID {
+		return nil, ErrInvalidLinkToken
+	}
+
+	// Links are single-use
+	if err := s.queries.DeleteCache(ctx, cacheKey); err != nil {
+		logging.Warn("failed to delete account link from cache", "error", err)
+	}
+
+	created, err := s.queries.CreateUserIdentity(ctx, database.CreateUserIdentityParams{
+		UserID:     userID,
+		Provider:   link.Provider,
+		ProviderID: link.ProviderID,
+		Email:      &link.Email,
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrIdentityInUse
+		}
+		return nil, fmt.Errorf("failed to link identity: %w", err)
+	}
+
+	logging.Info("linked identity", "userID", userID.String(), "provider", link.Provider)
+	identity := identityFromRow(created)
+	return &identity, nil
+}
+
+// ListIdentities returns the OAuth accounts the user can sign in with, oldest first
+func (s *AuthService) ListIdentities(ctx context.Context, userID uuid.UUID) ([]Identity, error) {
+	rows, err := s.queries.ListUserIdentities(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+
+	identities := make([]Identity, 0, len(rows))
+	for _, row := range rows {
+		identities = append(identities, identityFromRow(row))
+	}
+	return identities, nil
+}
+
+// UnlinkIdentity removes one of the user's identities. The last one can't be
+// removed from an account without a password, which couldn't sign in then.
+func (s *AuthService) UnlinkIdentity(ctx context.Context, userID, identityID uuid.UUID) error {
+	user, err := s.queries.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	rows, err := s.queries.ListUserIdentities(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to list identities: %w", err)
+	}
+
+	found := false
+	for _, row := range rows {
+		found = found || row.ID == identityID
+	}
+	if !found {
+		return ErrIdentityNotFound
+	}
+	if user.PasswordHash == nil && len(rows) == 1 {
+		return ErrLastLoginMethod
+	}
+
+	deleted, err := s.queries.DeleteUserIdentity(ctx, database.DeleteUserIdentityParams{
+		ID:     identityID,
+		UserID: userID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to unlink identity: %w", err)
+	}
+	if deleted == 0 {
+		return ErrIdentityNotFound
+	}
+	return nil
+}
+
+func identityFromRow(row database.UserIdentity) Identity {
+	identity := Identity{
+		ID:        row.ID,
+		Provider:  row.Provider,
+		CreatedAt: row.CreatedAt,
+	}
+	if row.Email != nil {
+		identity.Email = *row.Email
+	}
+	if row.LastUsedAt.Valid {
+		identity.LastUsedAt = row.LastUsedAt.Time
+	}
+	return identity
+}
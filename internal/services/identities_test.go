@@ -0,0 +1,54 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func TestIdentityFromRow(t *testing.T) {
+	email := "user@example.com"
+	now := time.Now()
+	row := database.UserIdentity{
+		ID:         uuid.New(),
+		UserID:     uuid.New(),
+		Provider:   "okta",
+		ProviderID: "00u1",
+		Email:      &email,
+		CreatedAt:  now,
+		LastUsedAt: pgtype.Timestamptz{Time: now, Valid: true},
+	}
+
+	identity := identityFromRow(row)
+	if identity.ID != row.ID || identity.Provider != "okta" || identity.Email != email || !identity.LastUsedAt.Equal(now) {
+		t.Errorf("identityFromRow() = %+v", identity)
+	}
+
+	row.Email, row.LastUsedAt = nil, pgtype.Timestamptz{}
+	if identity := identityFromRow(row); identity.Email != "" || !identity.LastUsedAt.IsZero() {
+		t.Errorf("identityFromRow() without email and last use = %+v", identity)
+	}
+}
+
+func TestAccountLinkRequiredError(t *testing.T) {
+	var err error = &AccountLinkRequiredError{Token: "t", Provider: "github", Email: "user@example.com"}
+	var linkErr *AccountLinkRequiredError
+	if !errors.As(err, &linkErr) || linkErr.Token != "t" {
+		t.Fatalf("errors.As() = %v", linkErr)
+	}
+	if got := err.Error(); got != "signing in with github requires linking it to the account of user@example.com" {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestConfirmAccountLink_EmptyToken(t *testing.T) {
+	svc := NewAuthService(nil, createTestConfig())
+	if _, err := svc.ConfirmAccountLink(context.Background(), uuid.New(), ""); err != ErrInvalidLinkToken {
+		t.Errorf("ConfirmAccountLink() error = %v, want %v", err, ErrInvalidLinkToken)
+	}
+}
@@ -11,8 +11,33 @@ import (
 )
 
 type LLMService struct {
-	client *openai.Client
-	model  string
+	client        *openai.Client
+	model         string
+	allowedModels map[string]bool
+}
+
+// ChatOption configures one chat completion
+type ChatOption func(*chatOptions)
+
+type chatOptions struct {
+	model      string
+	toolChoice string
+}
+
+// WithModel uses model instead of the default model
+func WithModel(model string) ChatOption {
+	return func(o *chatOptions) {
+		o.model = model
+	}
+}
+
+// WithToolChoice controls whether the model calls a tool: ToolChoiceAuto,
+// ToolChoiceRequired, ToolChoiceNone or the name of a tool it must call.
+// It only applies when tools are offered.
+func WithToolChoice(choice string) ChatOption {
+	return func(o *chatOptions) {
+		o.toolChoice = choice
+	}
 }
 
 type ChatMessage struct {
@@ -106,12 +131,27 @@ type CompletionUsage struct {
 
 func NewLLMService(cfg *config.OpenAIConfig) *LLMService {
 	client := openai.NewClient(cfg.APIKey)
+	allowed := map[string]bool{cfg.Model: true}
+	for _, model := range cfg.AllowedModels {
+		allowed[model] = true
+	}
 	return &LLMService{
-		client: client,
-		model:  cfg.Model,
+		client:        client,
+		model:         cfg.Model,
+		allowedModels: allowed,
 	}
 }
 
+// DefaultModel returns the model used when a session doesn't choose one
+func (s *LLMService) DefaultModel() string {
+	return s.model
+}
+
+// IsModelAllowed reports whether sessions can use model
+func (s *LLMService) IsModelAllowed(model string) bool {
+	return s.allowedModels[model]
+}
+
 // ChatResponse contains the full response from a chat completion
 type ChatResponse struct {
 	Content   string
@@ -129,18 +169,8 @@ func (s *LLMService) Chat(ctx context.Context, messages []ChatMessage, systemPro
 }
 
 // ChatWithTools performs a non-streaming chat completion with tool support
-func (s *LLMService) ChatWithTools(ctx context.Context, messages []ChatMessage, systemPrompt string, tools []ToolDefinition) (*ChatResponse, error) {
-	openaiMessages := s.toOpenAIMessages(messages, systemPrompt)
-	openaiTools := s.toOpenAITools(tools)
-
-	req := openai.ChatCompletionRequest{
-		Model:    s.model,
-		Messages: openaiMessages,
-	}
-
-	if len(openaiTools) > 0 {
-		req.Tools = openaiTools
-	}
+func (s *LLMService) ChatWithTools(ctx context.Context, messages []ChatMessage, systemPrompt string, tools []ToolDefinition, opts ...ChatOption) (*ChatResponse, error) {
+	req := s.newRequest(messages, systemPrompt, tools, opts)
 
 	resp, err := s.client.CreateChatCompletion(ctx, req)
 	if err != nil {
@@ -191,19 +221,9 @@ type toolCallAccumulator struct {
 }
 
 // ChatStreamWithTools performs a streaming chat completion with tool support
-func (s *LLMService) ChatStreamWithTools(ctx context.Context, messages []ChatMessage, systemPrompt string, tools []ToolDefinition) (<-chan StreamChunk, error) {
-	openaiMessages := s.toOpenAIMessages(messages, systemPrompt)
-	openaiTools := s.toOpenAITools(tools)
-
-	req := openai.ChatCompletionRequest{
-		Model:    s.model,
-		Messages: openaiMessages,
-		Stream:   true,
-	}
-
-	if len(openaiTools) > 0 {
-		req.Tools = openaiTools
-	}
+func (s *LLMService) ChatStreamWithTools(ctx context.Context, messages []ChatMessage, systemPrompt string, tools []ToolDefinition, opts ...ChatOption) (<-chan StreamChunk, error) {
+	req := s.newRequest(messages, systemPrompt, tools, opts)
+	req.Stream = true
 
 	stream, err := s.client.CreateChatCompletionStream(ctx, req)
 	if err != nil {
@@ -328,6 +348,35 @@ func (s *LLMService) ChatStreamWithTools(ctx context.Context, messages []ChatMes
 	return chunks, nil
 }
 
+// newRequest builds a chat completion request
+func (s *LLMService) newRequest(messages []ChatMessage, systemPrompt string, tools []ToolDefinition, opts []ChatOption) openai.ChatCompletionRequest {
+	options := chatOptions{model: s.model}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	req := openai.ChatCompletionRequest{
+		Model:    options.model,
+		Messages: s.toOpenAIMessages(messages, systemPrompt),
+	}
+
+	if openaiTools := s.toOpenAITools(tools); len(openaiTools) > 0 {
+		req.Tools = openaiTools
+		switch options.toolChoice {
+		case "", ToolChoiceAuto:
+		case ToolChoiceRequired, ToolChoiceNone:
+			req.ToolChoice = options.toolChoice
+		default:
+			req.ToolChoice = openai.ToolChoice{
+				Type:     openai.ToolTypeFunction,
+				Function: openai.ToolFunction{Name: options.toolChoice},
+			}
+		}
+	}
+
+	return req
+}
+
 func (s *LLMService) toOpenAIMessages(messages []ChatMessage, systemPrompt string) []openai.ChatCompletionMessage {
 	var openaiMessages []openai.ChatCompletionMessage
 
@@ -418,6 +467,10 @@ func ParseToolArguments(arguments string) (map[string]interface{}, error) {
 // EstimateTokens provides a rough token count estimate
 // For accurate counts, use tiktoken library
 func (s *LLMService) EstimateTokens(text string) int {
+	return estimateTokens(text)
+}
+
+func estimateTokens(text string) int {
 	// Rough estimate: ~4 characters per token for English
 	return len(text) / 4
 }
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/agpt-go/chatbot-api/internal/config"
+	"github.com/sashabaranov/go-openai"
 )
 
 func TestNewLLMService(t *testing.T) {
@@ -210,3 +211,38 @@ func TestCompletionUsageStruct(t *testing.T) {
 		t.Errorf("CompletionUsage.TotalTokens = %d, want %d", usage.TotalTokens, 30)
 	}
 }
+
+func TestNewRequestToolChoice(t *testing.T) {
+	svc := NewLLMService(&config.OpenAIConfig{APIKey: "test-api-key", Model: "gpt-4o"})
+	tools := []ToolDefinition{{Name: "search"}}
+
+	req := svc.newRequest(nil, "", tools, []ChatOption{WithModel("gpt-4o-mini"), WithToolChoice("search")})
+	if req.Model != "gpt-4o-mini" {
+		t.Errorf("Model = %q, want %q", req.Model, "gpt-4o-mini")
+	}
+	choice, ok := req.ToolChoice.(openai.ToolChoice)
+	if !ok || choice.Type != openai.ToolTypeFunction || choice.Function.Name != "search" {
+		t.Errorf("ToolChoice = %#v, want the search function", req.ToolChoice)
+	}
+
+	if req := svc.newRequest(nil, "", tools, []ChatOption{WithToolChoice(ToolChoiceRequired)}); req.ToolChoice != ToolChoiceRequired {
+		t.Errorf("ToolChoice = %#v, want %q", req.ToolChoice, ToolChoiceRequired)
+	}
+	if req := svc.newRequest(nil, "", tools, []ChatOption{WithToolChoice(ToolChoiceAuto)}); req.ToolChoice != nil {
+		t.Errorf("ToolChoice = %#v, want it unset for auto", req.ToolChoice)
+	}
+	// Without tools there is nothing to choose
+	if req := svc.newRequest(nil, "", nil, []ChatOption{WithToolChoice(ToolChoiceRequired)}); req.ToolChoice != nil || req.Tools != nil {
+		t.Errorf("request = %+v, want no tools", req)
+	}
+}
+
+func TestIsModelAllowed(t *testing.T) {
+	svc := NewLLMService(&config.OpenAIConfig{APIKey: "test-api-key", Model: "gpt-4o", AllowedModels: []string{"gpt-4o-mini"}})
+
+	for model, want := range map[string]bool{"gpt-4o": true, "gpt-4o-mini": true, "gpt-3.5-turbo": false} {
+		if got := svc.IsModelAllowed(model); got != want {
+			t.Errorf("IsModelAllowed(%q) = %v, want %v", model, got, want)
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/config"
+	"github.com/agpt-go/chatbot-api/internal/logging"
+)
+
+// smtpTimeout bounds one SMTP delivery when the context has no deadline
+const smtpTimeout = 30 * time.Second
+
+var ErrInvalidEmail = errors.New("invalid email")
+
+// Email is a plain text message to one recipient
+type Email struct {
+	To      string
+	Subject string
+	Text    string
+}
+
+// Mailer delivers emails
+type Mailer interface {
+	Send(ctx context.Context, email Email) error
+}
+
+// NewMailer creates the mailer selected by cfg.Transport
+func NewMailer(cfg config.MailConfig) (Mailer, error) {
+	switch cfg.Transport {
+	case "smtp":
+		return NewSMTPMailer(cfg), nil
+	case "file":
+		if err := os.MkdirAll(cfg.Dir, 0o750); err != nil {
+			return nil, fmt.Errorf("failed to create mail directory: %w", err)
+		}
+		return &FileMailer{dir: cfg.Dir, from: cfg.From}, nil
+	case "", "log":
+		return &LogMailer{from: cfg.From}, nil
+	}
+	return nil, fmt.Errorf("unknown mail transport %q", cfg.Transport)
+}
+
+// SMTPMailer sends emails through an SMTP server, upgrading to TLS with
+// STARTTLS when the server offers it
+type SMTPMailer struct {
+	host string
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPMailer creates an SMTP mailer. It authenticates only when a username is set.
+func NewSMTPMailer(cfg config.MailConfig) *SMTPMailer {
+	m := &SMTPMailer{
+		host: cfg.SMTPHost,
+		addr: net.JoinHostPort(cfg.SMTPHost, cfg.SMTPPort),
+		from: cfg.From,
+	}
+	if cfg.SMTPUsername != "" {
+		m.auth = smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)
+	}
+	return m
+}
+
+// Send delivers the email
+func (m *SMTPMailer) Send(ctx context.Context, email Email) error {
+	msg, err := buildEmail(m.from, email)
+	if err != nil {
+		return err
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, smtpTimeout)
+		defer cancel()
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	deadline, _ := ctx.Deadline()
+	_ = conn.SetDeadline(deadline)
+
+	c, err := smtp.NewClient(conn, m.host)
+	if err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer func() { _ = c.Close() }()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: m.host}); err != nil {
+			return fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	if m.auth != nil {
+		if err := c.Auth(m.auth); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+	if err := c.Mail(addressOnly(m.from)); err != nil {
+		return fmt.Errorf("SMTP MAIL FROM failed: %w", err)
+	}
+	if err := c.Rcpt(addressOnly(email.To)); err != nil {
+		return fmt.Errorf("SMTP RCPT TO failed: %w", err)
+	}
+	w, err := c.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA failed: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("SMTP server rejected the email: %w", err)
+	}
+	return c.Quit()
+}
+
+// FileMailer writes each email to a .eml file, for development
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+// Send writes the email to the mail directory
+func (m *FileMailer) Send(ctx context.Context, email Email) error {
+	msg, err := buildEmail(m.from, email)
+	if err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000"), hex.EncodeToString(suffix))
+	if err := os.WriteFile(filepath.Join(m.dir, name), msg, 0o640); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+	return nil
+}
+
+// LogMailer logs emails instead of sending them, for development
+type LogMailer struct {
+	from string
+}
+
+// tokenQueryParam matches a link's query parameter carrying a token, such as ?token= in reset links
+var tokenQueryParam = regexp.MustCompile(`(?i)([?&][\w-]*token=)[^&#\s]+`)
+
+// Send logs the email with the tokens in its links redacted, so the log can't
+// be used to reset a password or verify an address
+func (m *LogMailer) Send(ctx context.Context, email Email) error {
+	if _, err := buildEmail(m.from, email); err != nil {
+		return err
+	}
+	text := tokenQueryParam.ReplaceAllString(email.Text, "${1}REDACTED")
+	logging.Info("email", "to", email.To, "subject", email.Subject, "text", text)
+	return nil
+}
+
+// buildEmail formats an RFC 5322 message with a quoted-printable UTF-8 body
+func buildEmail(from string, email Email) ([]byte, error) {
+	if _, err := mail.ParseAddress(email.To); err != nil || strings.ContainsAny(email.To, "\r\n") {
+		return nil, fmt.Errorf("%w: recipient %q", ErrInvalidEmail, email.To)
+	}
+	if strings.ContainsAny(email.Subject, "\r\n") {
+		return nil, fmt.Errorf("%w: subject contains a line break", ErrInvalidEmail)
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", email.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", email.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	qp := quotedprintable.NewWriter(&b)
+	if _, err := qp.Write([]byte(strings.ReplaceAll(strings.ReplaceAll(email.Text, "\r\n", "\n"), "\n", "\r\n"))); err != nil {
+		return nil, err
+	}
+	if err := qp.Close(); err != nil {
+		return nil, err
+	}
+	b.WriteString("\r\n")
+	return b.Bytes(), nil
+}
+
+// addressOnly returns the bare address of "Name <addr>"
+func addressOnly(address string) string {
+	if parsed, err := mail.ParseAddress(address); err == nil {
+		return parsed.Address
+	}
+	return address
+}
@@ -0,0 +1,173 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/agpt-go/chatbot-api/internal/config"
+	"github.com/agpt-go/chatbot-api/internal/logging"
+)
+
+// smtpStandIn is a minimal SMTP server that accepts one message
+type smtpStandIn struct {
+	addr     string
+	commands []string
+	auth     string
+	data     string
+	done     chan struct{}
+}
+
+func startSMTPStandIn(t *testing.T) *smtpStandIn {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	s := &smtpStandIn{addr: ln.Addr().String(), done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { _ = conn.Close() }()
+
+		r := bufio.NewReader(conn)
+		reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+		reply("220 localhost ESMTP stand-in")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+			s.commands = append(s.commands, verb)
+			switch verb {
+			case "EHLO":
+				reply("250-localhost")
+				reply("250 AUTH PLAIN")
+			case "AUTH":
+				s.auth = line
+				reply("235 Authenticated")
+			case "DATA":
+				reply("354 Go ahead")
+				var b strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil || l == ".\r\n" {
+						break
+					}
+					b.WriteString(l)
+				}
+				s.data = b.String()
+				reply("250 Queued")
+			case "QUIT":
+				reply("221 Bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+	return s
+}
+
+func TestSMTPMailer(t *testing.T) {
+	server := startSMTPStandIn(t)
+	host, port, _ := net.SplitHostPort(server.addr)
+	mailer := NewSMTPMailer(config.MailConfig{
+		From:         "Chatbot <no-reply@example.com>",
+		SMTPHost:     host,
+		SMTPPort:     port,
+		SMTPUsername: "user",
+		SMTPPassword: "secret",
+	})
+
+	err := mailer.Send(context.Background(), Email{To: "ada@example.com", Subject: "Vérifiez", Text: "Open this link:\nhttps://example.com/verify?token=abc"})
+	if err != nil {
+		t.Fatalf("Send() error = %v", err)
+	}
+	<-server.done
+
+	want := []string{"EHLO", "AUTH", "MAIL", "RCPT", "DATA", "QUIT"}
+	if strings.Join(server.commands, " ") != strings.Join(want, " ") {
+		t.Errorf("commands = %v, want %v", server.commands, want)
+	}
+	credentials, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(server.auth, "AUTH PLAIN "))
+	if string(credentials) != "\x00user\x00secret" {
+		t.Errorf("auth = %q", credentials)
+	}
+	for _, part := range []string{"To: ada@example.com\r\n", "Subject: =?utf-8?q?V=C3=A9rifiez?=\r\n", "https://example.com/verify?token=3Dabc"} {
+		if !strings.Contains(server.data, part) {
+			t.Errorf("message missing %q:\n%s", part, server.data)
+		}
+	}
+}
+
+func TestFileMailer(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	mailer, err := NewMailer(config.MailConfig{Transport: "file", Dir: dir, From: "no-reply@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mailer.Send(context.Background(), Email{To: "ada@example.com", Subject: "Hello", Text: "Hi"}); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("wrote %d files, want 1", len(files))
+	}
+	data, _ := os.ReadFile(files[0])
+	if !strings.Contains(string(data), "Subject: Hello\r\n") {
+		t.Errorf("message = %s", data)
+	}
+}
+
+func TestLogMailerRedactsTokens(t *testing.T) {
+	var out bytes.Buffer
+	previous := logging.Logger
+	logging.Logger = slog.New(slog.NewTextHandler(&out, nil))
+	t.Cleanup(func() { logging.Logger = previous })
+
+	mailer, err := NewMailer(config.MailConfig{Transport: "log", From: "no-reply@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := "Open https://app.example.com/reset-password?token=abc123&lang=en or https://app.example.com/verify?email_token=def456"
+	if err := mailer.Send(context.Background(), Email{To: "ada@example.com", Subject: "Reset", Text: text}); err != nil {
+		t.Fatal(err)
+	}
+
+	logged := out.String()
+	if strings.Contains(logged, "abc123") || strings.Contains(logged, "def456") {
+		t.Errorf("log = %s, want tokens redacted", logged)
+	}
+	if !strings.Contains(logged, "reset-password?token=REDACTED&lang=en") {
+		t.Errorf("log = %s, want the link kept with its token redacted", logged)
+	}
+}
+
+func TestBuildEmailRejectsHeaderInjection(t *testing.T) {
+	for name, email := range map[string]Email{
+		"recipient": {To: "ada@example.com\r\nBcc: eve@example.com", Subject: "Hi"},
+		"subject":   {To: "ada@example.com", Subject: "Hi\r\nBcc: eve@example.com"},
+		"invalid":   {To: "not an address", Subject: "Hi"},
+	} {
+		if _, err := buildEmail("no-reply@example.com", email); !errors.Is(err, ErrInvalidEmail) {
+			t.Errorf("%s: error = %v, want ErrInvalidEmail", name, err)
+		}
+	}
+}
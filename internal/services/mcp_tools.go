@@ -0,0 +1,360 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/logging"
+	"github.com/agpt-go/chatbot-api/internal/mcp"
+	"github.com/google/uuid"
+	"go.yaml.in/yaml/v3"
+)
+
+// MCP server transports
+const (
+	MCPTransportStdio = "stdio"
+	MCPTransportHTTP  = "http"
+)
+
+// mcpToolSeparator joins the server name and the tool name of an imported tool
+const mcpToolSeparator = "__"
+
+// maxToolNameLength is the longest tool name LLM providers accept
+const maxToolNameLength = 64
+
+// mcpConnectTimeout bounds the handshake and first tool listing of one server
+const mcpConnectTimeout = 30 * time.Second
+
+var (
+	invalidToolNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+	mcpServerNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+)
+
+// MCPServerConfig describes one MCP server whose tools are imported.
+// Env values and headers are expanded from the environment, so secrets can
+// be referenced as ${VAR} instead of written into the file.
+type MCPServerConfig struct {
+	Name      string `json:"name" yaml:"name"`
+	Transport string `json:"transport" yaml:"transport"`
+
+	// stdio
+	Command string            `json:"command,omitempty" yaml:"command"`
+	Args    []string          `json:"args,omitempty" yaml:"args"`
+	Env     map[string]string `json:"env,omitempty" yaml:"env"`
+
+	// http
+	URL     string            `json:"url,omitempty" yaml:"url"`
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers"`
+
+	// TimeoutSeconds overrides the default time limit for calls to this server's tools
+	TimeoutSeconds int `json:"timeout_seconds,omitempty" yaml:"timeout_seconds"`
+}
+
+// MCPConfig lists the MCP servers to import tools from
+type MCPConfig struct {
+	Servers []MCPServerConfig `json:"servers" yaml:"servers"`
+}
+
+// LoadMCPConfig reads the MCP server list from a YAML or JSON file.
+// An empty path configures no servers.
+func LoadMCPConfig(path string) (*MCPConfig, error) {
+	if path == "" {
+		return &MCPConfig{}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mcp config: %w", err)
+	}
+
+	config, err := ParseMCPConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mcp config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// ParseMCPConfig parses and validates an MCP server list
+func ParseMCPConfig(data []byte) (*MCPConfig, error) {
+	var config MCPConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for i, server := range config.Servers {
+		if !mcpServerNamePattern.MatchString(server.Name) {
+			return nil, fmt.Errorf("server %d: name must only contain letters, digits, _ and -", i)
+		}
+		if strings.Contains(server.Name, mcpToolSeparator) {
+			return nil, fmt.Errorf("server %s: name must not contain %q", server.Name, mcpToolSeparator)
+		}
+		if seen[server.Name] {
+			return nil, fmt.Errorf("server %s: duplicate name", server.Name)
+		}
+		seen[server.Name] = true
+
+		switch server.Transport {
+		case MCPTransportStdio:
+			if server.Command == "" {
+				return nil, fmt.Errorf("server %s: command is required for the stdio transport", server.Name)
+			}
+		case MCPTransportHTTP:
+			if server.URL == "" {
+				return nil, fmt.Errorf("server %s: url is required for the http transport", server.Name)
+			}
+		default:
+			return nil, fmt.Errorf("server %s: transport must be %s or %s", server.Name, MCPTransportStdio, MCPTransportHTTP)
+		}
+		if server.TimeoutSeconds < 0 {
+			return nil, fmt.Errorf("server %s: timeout_seconds must not be negative", server.Name)
+		}
+	}
+
+	return &config, nil
+}
+
+// transport builds the client transport for the server
+func (c MCPServerConfig) transport() mcp.Transport {
+	if c.Transport == MCPTransportHTTP {
+		headers := make(map[string]string, len(c.Headers))
+		for k, v := range c.Headers {
+			headers[k] = os.ExpandEnv(v)
+		}
+		return mcp.NewHTTPTransport(c.URL, headers, nil)
+	}
+
+	env := make([]string, 0, len(c.Env))
+	for k, v := range c.Env {
+		env = append(env, k+"="+os.ExpandEnv(v))
+	}
+	return mcp.NewStdioTransport(c.Command, c.Args, env)
+}
+
+// MCPToolName is the registry name of a tool imported from an MCP server:
+// the server and tool names joined by "__", limited to the characters and
+// length LLM providers accept for tool names
+func MCPToolName(server, tool string) string {
+	name := invalidToolNameChars.ReplaceAllString(server+mcpToolSeparator+tool, "_")
+	if len(name) > maxToolNameLength {
+		name = name[:maxToolNameLength]
+	}
+	return name
+}
+
+// mcpClient is the part of mcp.Client the manager uses
+type mcpClient interface {
+	ListTools(ctx context.Context) ([]mcp.Tool, error)
+	CallTool(ctx context.Context, name string, arguments json.RawMessage) (*mcp.CallToolResult, error)
+	Close() error
+}
+
+// mcpServer is a connected MCP server and the tools imported from it
+type mcpServer struct {
+	config MCPServerConfig
+	client mcpClient
+
+	mu    sync.Mutex        // Serializes refreshes
+	tools map[string]string // Registry name -> server tool name
+}
+
+// MCPManager imports the tools of external MCP servers into a ToolRegistry.
+// Imported tools are namespaced by server, proxied to the server when called,
+// and re-imported when the server reports that its tool list changed.
+type MCPManager struct {
+	registry   *ToolRegistry
+	clientInfo mcp.Implementation
+
+	mu      sync.Mutex
+	servers map[string]*mcpServer
+}
+
+// NewMCPManager creates a manager that registers tools in registry
+func NewMCPManager(registry *ToolRegistry) *MCPManager {
+	return &MCPManager{
+		registry:   registry,
+		clientInfo: mcp.Implementation{Name: "chatbot-api", Version: "1.0"},
+		servers:    make(map[string]*mcpServer),
+	}
+}
+
+// Connect connects to the servers and imports their tools. A server that
+// can't be reached is logged and skipped so it doesn't keep the API from starting.
+func (m *MCPManager) Connect(ctx context.Context, servers []MCPServerConfig) {
+	for _, config := range servers {
+		if err := m.connect(ctx, config); err != nil {
+			logging.Error("failed to connect to mcp server", err, "server", config.Name)
+		}
+	}
+}
+
+func (m *MCPManager) connect(ctx context.Context, config MCPServerConfig) error {
+	ctx, cancel := context.WithTimeout(ctx, mcpConnectTimeout)
+	defer cancel()
+
+	server := &mcpServer{config: config, tools: make(map[string]string)}
+	client := mcp.NewClient(config.transport(), func(method string, params json.RawMessage) {
+		if method == mcp.MethodToolsListChanged {
+			// Refreshed off the transport's read loop, which has to deliver the tools/list response
+			go m.refresh(server)
+		}
+	})
+	server.client = client
+
+	info, err := client.Connect(ctx, m.clientInfo)
+	if err != nil {
+		client.Close()
+		return err
+	}
+
+	m.mu.Lock()
+	m.servers[config.Name] = server
+	m.mu.Unlock()
+
+	count, err := m.syncTools(ctx, server)
+	if err != nil {
+		return err
+	}
+
+	logging.Info("connected to mcp server", "server", config.Name, "server_name", info.ServerInfo.Name, "tools", count)
+	return nil
+}
+
+// refresh re-imports a server's tools after it reported a change
+func (m *MCPManager) refresh(server *mcpServer) {
+	ctx, cancel := context.WithTimeout(context.Background(), mcpConnectTimeout)
+	defer cancel()
+
+	count, err := m.syncTools(ctx, server)
+	if err != nil {
+		logging.Error("failed to refresh mcp tools", err, "server", server.config.Name)
+		return
+	}
+	logging.Info("refreshed mcp tools", "server", server.config.Name, "tools", count)
+}
+
+// syncTools lists the server's tools, registering new and changed ones and
+// removing the ones the server no longer offers. It returns the number of imported tools.
+func (m *MCPManager) syncTools(ctx context.Context, server *mcpServer) (int, error) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+
+	tools, err := server.client.ListTools(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list tools: %w", err)
+	}
+
+	var opts []ToolOption
+	if server.config.TimeoutSeconds > 0 {
+		opts = append(opts, WithToolTimeout(time.Duration(server.config.TimeoutSeconds)*time.Second))
+	}
+
+	current := make(map[string]string, len(tools))
+	for _, tool := range tools {
+		name := MCPToolName(server.config.Name, tool.Name)
+		if _, taken := current[name]; taken {
+			logging.Warn("skipping mcp tool with conflicting name", "server", server.config.Name, "tool", tool.Name)
+			continue
+		}
+		_, owned := server.tools[name]
+		if _, registered := m.registry.Get(name); registered && !owned {
+			logging.Warn("skipping mcp tool that shadows a registered tool", "server", server.config.Name, "tool", tool.Name)
+			continue
+		}
+		current[name] = tool.Name
+		m.registry.Register(name, mcpToolDefinition(name, server.config.Name, tool), m.proxy(server, tool.Name), opts...)
+	}
+
+	for name := range server.tools {
+		if _, ok := current[name]; !ok {
+			m.registry.Unregister(name)
+		}
+	}
+	server.tools = current
+	return len(current), nil
+}
+
+// proxy returns a handler that forwards calls to the server's tool
+func (m *MCPManager) proxy(server *mcpServer, toolName string) ToolHandler {
+	return func(ctx context.Context, userID uuid.UUID, arguments string) (*ToolResult, error) {
+		args := json.RawMessage(arguments)
+		if strings.TrimSpace(arguments) == "" {
+			args = json.RawMessage("{}")
+		}
+
+		result, err := server.client.CallTool(ctx, toolName, args)
+		if err != nil {
+			return &ToolResult{
+				Success: false,
+				Error:   fmt.Sprintf("mcp server %s: %v", server.config.Name, err),
+			}, nil
+		}
+		return mcpToolResult(result), nil
+	}
+}
+
+// Close disconnects from all servers and removes their tools
+func (m *MCPManager) Close() {
+	m.mu.Lock()
+	servers := m.servers
+	m.servers = make(map[string]*mcpServer)
+	m.mu.Unlock()
+
+	for _, server := range servers {
+		server.mu.Lock()
+		for name := range server.tools {
+			m.registry.Unregister(name)
+		}
+		server.tools = nil
+		server.mu.Unlock()
+
+		if err := server.client.Close(); err != nil {
+			logging.Warn("failed to close mcp server", "error", err, "server", server.config.Name)
+		}
+	}
+}
+
+func mcpToolDefinition(name, server string, tool mcp.Tool) ToolDefinition {
+	params := tool.InputSchema
+	if len(params) == 0 {
+		params = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	}
+
+	description := tool.Description
+	if description == "" {
+		description = tool.Name
+	}
+
+	return ToolDefinition{
+		Name:        name,
+		Description: fmt.Sprintf("[%s] %s", server, description),
+		Parameters:  params,
+	}
+}
+
+// mcpToolResult converts an MCP tool result; text content becomes the message
+func mcpToolResult(result *mcp.CallToolResult) *ToolResult {
+	var texts []string
+	for _, content := range result.Content {
+		if content.Type == "text" && content.Text != "" {
+			texts = append(texts, content.Text)
+		}
+	}
+	text := strings.Join(texts, "\n")
+
+	data := map[string]interface{}{"content": result.Content}
+	if result.StructuredContent != nil {
+		data["structured_content"] = result.StructuredContent
+	}
+
+	if result.IsError {
+		return &ToolResult{Success: false, Error: text, Data: data}
+	}
+	return &ToolResult{Success: true, Message: text, Data: data}
+}
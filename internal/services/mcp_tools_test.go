@@ -0,0 +1,173 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/agpt-go/chatbot-api/internal/mcp"
+	"github.com/google/uuid"
+)
+
+type fakeMCPClient struct {
+	tools []mcp.Tool
+	calls []string
+}
+
+func (c *fakeMCPClient) ListTools(ctx context.Context) ([]mcp.Tool, error) {
+	return c.tools, nil
+}
+
+func (c *fakeMCPClient) CallTool(ctx context.Context, name string, arguments json.RawMessage) (*mcp.CallToolResult, error) {
+	c.calls = append(c.calls, name+" "+string(arguments))
+	if name == "broken" {
+		return &mcp.CallToolResult{Content: mcp.TextContent("it broke"), IsError: true}, nil
+	}
+	return &mcp.CallToolResult{
+		Content:           mcp.TextContent("done"),
+		StructuredContent: map[string]interface{}{"ok": true},
+	}, nil
+}
+
+func (c *fakeMCPClient) Close() error { return nil }
+
+func newTestMCPServer(name string, client *fakeMCPClient) *mcpServer {
+	return &mcpServer{config: MCPServerConfig{Name: name}, client: client, tools: make(map[string]string)}
+}
+
+func TestMCPManagerImportsNamespacedTools(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register("add_understanding", ToolDefinition{Name: "add_understanding"}, nil)
+	manager := NewMCPManager(registry)
+
+	client := &fakeMCPClient{tools: []mcp.Tool{
+		{Name: "search", Description: "Search the web", InputSchema: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"query": map[string]interface{}{"type": "string"}},
+			"required":   []interface{}{"query"},
+		}},
+		{Name: "broken"},
+	}}
+	server := newTestMCPServer("web", client)
+
+	count, err := manager.syncTools(context.Background(), server)
+	if err != nil {
+		t.Fatalf("syncTools: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 tools, got %d", count)
+	}
+
+	defs := registry.GetDefinitions()
+	if len(defs) != 3 || defs[0].Name != "add_understanding" || defs[1].Name != "web__search" || defs[2].Name != "web__broken" {
+		t.Fatalf("unexpected definitions: %+v", defs)
+	}
+	if defs[1].Description != "[web] Search the web" {
+		t.Errorf("unexpected description %q", defs[1].Description)
+	}
+
+	// Imported tools are validated against their input schema like built-in ones
+	result, _ := registry.Execute(context.Background(), uuid.New(), "web__search", `{}`)
+	if result.Success || len(client.calls) != 0 {
+		t.Fatalf("expected invalid arguments to be rejected before the server is called, got %+v", result)
+	}
+
+	result, _ = registry.Execute(context.Background(), uuid.New(), "web__search", `{"query":"go"}`)
+	if !result.Success || result.Message != "done" || result.Data["structured_content"] == nil {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if client.calls[0] != `search {"query":"go"}` {
+		t.Errorf("expected the call to be proxied under the server's tool name, got %q", client.calls[0])
+	}
+
+	result, _ = registry.Execute(context.Background(), uuid.New(), "web__broken", "")
+	if result.Success || result.Error != "it broke" {
+		t.Fatalf("expected tool error to be reported, got %+v", result)
+	}
+	if client.calls[1] != "broken {}" {
+		t.Errorf("expected empty arguments to be sent as an empty object, got %q", client.calls[1])
+	}
+}
+
+func TestMCPManagerRefreshRemovesTools(t *testing.T) {
+	registry := NewToolRegistry()
+	manager := NewMCPManager(registry)
+	client := &fakeMCPClient{tools: []mcp.Tool{{Name: "a"}, {Name: "b"}}}
+	server := newTestMCPServer("srv", client)
+
+	if _, err := manager.syncTools(context.Background(), server); err != nil {
+		t.Fatalf("syncTools: %v", err)
+	}
+
+	client.tools = []mcp.Tool{{Name: "b", Description: "new"}, {Name: "c"}}
+	manager.refresh(server)
+
+	defs := registry.GetDefinitions()
+	if len(defs) != 2 || defs[0].Name != "srv__b" || defs[1].Name != "srv__c" {
+		t.Fatalf("unexpected definitions after refresh: %+v", defs)
+	}
+	if defs[0].Description != "[srv] new" {
+		t.Errorf("expected changed tool to be updated, got %q", defs[0].Description)
+	}
+	if _, ok := registry.Get("srv__a"); ok {
+		t.Error("expected removed tool to be unregistered")
+	}
+}
+
+func TestMCPManagerDoesNotShadowTools(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register("srv__a", ToolDefinition{Name: "srv__a", Description: "built-in"}, nil)
+	manager := NewMCPManager(registry)
+	server := newTestMCPServer("srv", &fakeMCPClient{tools: []mcp.Tool{{Name: "a"}}})
+
+	count, _ := manager.syncTools(context.Background(), server)
+	if count != 0 {
+		t.Fatalf("expected the conflicting tool to be skipped, got %d", count)
+	}
+	if tool, _ := registry.Get("srv__a"); tool.Definition.Description != "built-in" {
+		t.Fatalf("expected the registered tool to be kept, got %+v", tool.Definition)
+	}
+}
+
+func TestMCPToolName(t *testing.T) {
+	if got := MCPToolName("github", "create.issue"); got != "github__create_issue" {
+		t.Errorf("expected invalid characters to be replaced, got %q", got)
+	}
+	if got := MCPToolName("srv", strings.Repeat("x", 100)); len(got) != maxToolNameLength {
+		t.Errorf("expected name to be truncated to %d characters, got %d", maxToolNameLength, len(got))
+	}
+}
+
+func TestParseMCPConfig(t *testing.T) {
+	config, err := ParseMCPConfig([]byte(`
+servers:
+  - name: local
+    transport: stdio
+    command: ./server
+  - name: remote
+    transport: http
+    url: https://mcp.example.com/mcp
+    timeout_seconds: 60
+`))
+	if err != nil {
+		t.Fatalf("ParseMCPConfig: %v", err)
+	}
+	if len(config.Servers) != 2 || config.Servers[1].TimeoutSeconds != 60 {
+		t.Fatalf("unexpected config: %+v", config)
+	}
+
+	invalid := map[string]string{
+		"bad name":          "servers: [{name: 'a b', transport: stdio, command: x}]",
+		"separator in name": "servers: [{name: a__b, transport: stdio, command: x}]",
+		"duplicate":         "servers: [{name: a, transport: stdio, command: x}, {name: a, transport: stdio, command: x}]",
+		"no command":        "servers: [{name: a, transport: stdio}]",
+		"no url":            "servers: [{name: a, transport: http}]",
+		"unknown transport": "servers: [{name: a, transport: sse, url: x}]",
+	}
+	for name, data := range invalid {
+		if _, err := ParseMCPConfig([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
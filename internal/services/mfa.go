@@ -0,0 +1,497 @@
+package services
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/database"
+	"github.com/agpt-go/chatbot-api/internal/logging"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrInvalidMFAToken      = errors.New("invalid or expired mfa token")
+	ErrInvalidMFACode       = errors.New("invalid mfa code")
+	ErrMFAAlreadyEnabled    = errors.New("mfa is already enabled")
+	ErrMFANotEnabled        = errors.New("mfa is not enabled")
+	ErrMFARequired          = errors.New("mfa is required for this account")
+	ErrMFANotStarted        = errors.New("mfa enrollment has not been started")
+	ErrUserNotFound         = errors.New("user not found")
+	ErrTooManyMFAChallenges = errors.New("too many pending mfa sign-ins")
+	ErrTooManyMFAAttempts   = errors.New("too many mfa code attempts")
+)
+
+const (
+	mfaChallengeExpiration  = 5 * time.Minute
+	mfaChallengeMaxAttempts = 5
+	// maxActiveMFAChallenges bounds the codes that can be guessed per user
+	// to this many times mfaChallengeMaxAttempts per mfaChallengeExpiration
+	maxActiveMFAChallenges = 5
+	// Codes a signed-in user can try per window to disable MFA or replace recovery codes
+	mfaCodeMaxAttempts   = 5
+	mfaCodeAttemptWindow = 15 * time.Minute
+	recoveryCodeCount    = 10
+)
+
+// MFAChallengeError is returned instead of tokens when a user with MFA
+// enabled signs in. The client completes the sign-in by posting Token with a
+// code from the user's authenticator app, or a recovery code.
+type MFAChallengeError struct {
+	Token     string
+	ExpiresIn int64 // Seconds
+}
+
+func (e *MFAChallengeError) Error() string {
+	return "mfa code required to complete sign-in"
+}
+
+// MFAStatus describes a user's second factor
+type MFAStatus struct {
+	Enabled                bool
+	Required               bool // By an administrator or MFA_REQUIRED
+	RecoveryCodesRemaining int64
+}
+
+// TOTPEnrollment is a new authenticator secret awaiting confirmation
+type TOTPEnrollment struct {
+	Secret          string // Base32, for typing into an authenticator app
+	ProvisioningURI string // otpauth:// URI, for rendering as a QR code
+}
+
+// signIn issues tokens for a user who proved their first factor, or returns
+// an MFAChallengeError when they have a second one. ErrTooManyMFAChallenges
+// is returned while too many of their sign-ins are waiting for a code.
+func (s *AuthService) signIn(ctx context.Context, user *database.User) (*TokenPair, error) {
+	state, err := s.queries.GetUserMFAState(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mfa state: %w", err)
+	}
+	if !state.MfaEnabled {
+		return s.generateTokenPair(ctx, user)
+	}
+
+	active, err := s.queries.CountActiveMFAChallenges(ctx, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count mfa challenges: %w", err)
+	}
+	if active >= maxActiveMFAChallenges {
+		logging.Warn("mfa sign-in refused, too many open challenges", "userID", user.ID.String())
+		return nil, ErrTooManyMFAChallenges
+	}
+
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return nil, fmt.Errorf("failed to generate mfa token: %w", err)
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	err = s.queries.CreateMFAChallenge(ctx, database.CreateMFAChallengeParams{
+		TokenHash: hashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(mfaChallengeExpiration),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store mfa challenge: %w", err)
+	}
+	if _, err := s.queries.DeleteExpiredMFAChallenges(ctx); err != nil {
+		logging.Warn("failed to delete expired mfa challenges", "error", err)
+	}
+
+	return nil, &MFAChallengeError{Token: token, ExpiresIn: int64(mfaChallengeExpiration.Seconds())}
+}
+
+// VerifyMFAChallenge completes a sign-in that returned an MFAChallengeError.
+// A challenge allows a few wrong codes before it has to be started again.
+func (s *AuthService) VerifyMFAChallenge(ctx context.Context, token, code string) (*database.User, *TokenPair, error) {
+	if token == "" {
+		return nil, nil, ErrInvalidMFAToken
+	}
+	tokenHash := hashToken(token)
+
+	// The attempt is counted before the code is checked, so parallel
+	// requests can't try more codes than the challenge allows
+	challenge, err := s.queries.UseMFAChallengeAttempt(ctx, database.UseMFAChallengeAttemptParams{
+		TokenHash:   tokenHash,
+		MaxAttempts: mfaChallengeMaxAttempts,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil, ErrInvalidMFAToken
+		}
+		return nil, nil, fmt.Errorf("failed to check mfa challenge: %w", err)
+	}
+
+	if err := s.verifyMFACode(ctx, challenge.UserID, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) && challenge.Attempts >= mfaChallengeMaxAttempts {
+			logging.Warn("mfa challenge abandoned after too many attempts", "userID", challenge.UserID.String())
+		}
+		return nil, nil, err
+	}
+
+	// Challenges are single-use
+	deleted, err := s.queries.DeleteMFAChallenge(ctx, tokenHash)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to complete mfa challenge: %w", err)
+	}
+	if deleted == 0 {
+		return nil, nil, ErrInvalidMFAToken
+	}
+
+	user, err := s.queries.GetUserByID(ctx, challenge.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	tokens, err := s.generateTokenPair(ctx, &user)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &user, tokens, nil
+}
+
+// verifyMFACode checks an authenticator code or an unused recovery code.
+// Each code works once.
+func (s *AuthService) verifyMFACode(ctx context.Context, userID uuid.UUID, code string) error {
+	totp, err := s.queries.GetUserTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrMFANotEnabled
+		}
+		return fmt.Errorf("failed to get totp: %w", err)
+	}
+	if !totp.EnabledAt.Valid {
+		return ErrMFANotEnabled
+	}
+
+	code = normalizeMFACode(code)
+	if len(code) == totpDigits {
+		return s.useTOTPCode(ctx, totp, code)
+	}
+
+	used, err := s.queries.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+		UserID:   userID,
+		CodeHash: hashToken(code),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to use recovery code: %w", err)
+	}
+	if used == 0 {
+		return ErrInvalidMFACode
+	}
+	logging.Info("recovery code used", "userID", userID.String())
+	return nil
+}
+
+// useTOTPCode checks an authenticator code and records its time step, so a
+// code seen by someone else can't be replayed
+func (s *AuthService) useTOTPCode(ctx context.Context, totp database.UserTotp, code string) error {
+	secret, err := s.decryptMFASecret(totp.SecretEncrypted)
+	if err != nil {
+		return err
+	}
+	step, ok := matchTOTP(secret, code, time.Now())
+	if !ok {
+		return ErrInvalidMFACode
+	}
+	used, err := s.queries.UseTOTPStep(ctx, database.UseTOTPStepParams{
+		UserID:       totp.UserID,
+		LastUsedStep: step,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record totp step: %w", err)
+	}
+	if used == 0 {
+		return ErrInvalidMFACode
+	}
+	return nil
+}
+
+// MFAStatus returns whether the user has MFA enabled and must have it
+func (s *AuthService) MFAStatus(ctx context.Context, userID uuid.UUID) (*MFAStatus, error) {
+	state, err := s.queries.GetUserMFAState(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mfa state: %w", err)
+	}
+	status := &MFAStatus{
+		Enabled:  state.MfaEnabled,
+		Required: state.MfaRequired || s.config.Auth.MFARequired,
+	}
+	if status.Enabled {
+		status.RecoveryCodesRemaining, err = s.queries.CountUnusedRecoveryCodes(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count recovery codes: %w", err)
+		}
+	}
+	return status, nil
+}
+
+// MFASatisfied reports whether the user may use endpoints that need MFA:
+// either it isn't required of them or they have enabled it
+func (s *AuthService) MFASatisfied(ctx context.Context, userID uuid.UUID) (bool, error) {
+	state, err := s.queries.GetUserMFAState(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get mfa state: %w", err)
+	}
+	required := state.MfaRequired || s.config.Auth.MFARequired
+	return !required || state.MfaEnabled, nil
+}
+
+// BeginTOTPEnrollment generates an authenticator secret for the user. It
+// isn't used to sign in until ConfirmTOTPEnrollment; starting again replaces
+// an unconfirmed secret.
+func (s *AuthService) BeginTOTPEnrollment(ctx context.Context, userID uuid.UUID) (*TOTPEnrollment, error) {
+	user, err := s.queries.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	secret := make([]byte, totpSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate totp secret: %w", err)
+	}
+	encrypted, err := s.encryptMFASecret(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.queries.UpsertUserTOTP(ctx, database.UpsertUserTOTPParams{
+		UserID:          userID,
+		SecretEncrypted: encrypted,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store totp secret: %w", err)
+	}
+	if stored == 0 {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	return &TOTPEnrollment{
+		Secret:          totpEncoding.EncodeToString(secret),
+		ProvisioningURI: totpProvisioningURI(s.config.Auth.MFAIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTOTPEnrollment enables MFA once the user proves their authenticator
+// app has the secret, and returns their recovery codes. They are only shown
+// this once.
+func (s *AuthService) ConfirmTOTPEnrollment(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	totp, err := s.queries.GetUserTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrMFANotStarted
+		}
+		return nil, fmt.Errorf("failed to get totp: %w", err)
+	}
+	if totp.EnabledAt.Valid {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	if err := s.useTOTPCode(ctx, totp, normalizeMFACode(code)); err != nil {
+		return nil, err
+	}
+	enabled, err := s.queries.EnableUserTOTP(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable totp: %w", err)
+	}
+	if enabled == 0 {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	codes, err := s.replaceRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	logging.Info("mfa enabled", "userID", userID.String())
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces the user's recovery codes, used or not.
+// See verifyMFAChange for what the user must prove.
+func (s *AuthService) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, password, code string) ([]string, error) {
+	if err := s.verifyMFAChange(ctx, userID, password, code); err != nil {
+		return nil, err
+	}
+	return s.replaceRecoveryCodes(ctx, userID)
+}
+
+// DisableMFA removes the user's second factor. Users MFA is required of
+// can't disable it. See verifyMFAChange for what the user must prove.
+func (s *AuthService) DisableMFA(ctx context.Context, userID uuid.UUID, password, code string) error {
+	state, err := s.queries.GetUserMFAState(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get mfa state: %w", err)
+	}
+	if state.MfaRequired || s.config.Auth.MFARequired {
+		return ErrMFARequired
+	}
+	if err := s.verifyMFAChange(ctx, userID, password, code); err != nil {
+		return err
+	}
+	return s.removeMFA(ctx, userID)
+}
+
+// verifyMFAChange checks the current password and an MFA code before a
+// signed-in user changes their second factor, so a stolen access token isn't
+// enough. Users signing in only through an identity provider have no password
+// and give just the code. Attempts are limited per user to
+// mfaCodeMaxAttempts per mfaCodeAttemptWindow; ErrTooManyMFAAttempts is
+// returned after that.
+func (s *AuthService) verifyMFAChange(ctx context.Context, userID uuid.UUID, password, code string) error {
+	counted, err := s.queries.UseMFACodeAttempt(ctx, database.UseMFACodeAttemptParams{
+		UserID:       userID,
+		WindowEndsAt: time.Now().Add(mfaCodeAttemptWindow),
+		MaxAttempts:  mfaCodeMaxAttempts,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to count mfa attempt: %w", err)
+	}
+	if counted == 0 {
+		return ErrTooManyMFAAttempts
+	}
+
+	user, err := s.queries.GetUserByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.PasswordHash != nil {
+		if err := bcrypt.CompareHashAndPassword([]byte(*user.PasswordHash), []byte(password)); err != nil {
+			return ErrIncorrectPassword
+		}
+	}
+	return s.verifyMFACode(ctx, userID, code)
+}
+
+// SetMFARequired sets whether the user must enable MFA before using the API
+func (s *AuthService) SetMFARequired(ctx context.Context, userID uuid.UUID, required bool) error {
+	updated, err := s.queries.SetUserMFARequired(ctx, database.SetUserMFARequiredParams{
+		ID:          userID,
+		MfaRequired: required,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	if updated == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// ResetMFA removes a user's second factor for them, e.g. when they lost both
+// their authenticator and their recovery codes
+func (s *AuthService) ResetMFA(ctx context.Context, userID uuid.UUID) error {
+	if _, err := s.queries.GetUserByID(ctx, userID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	return s.removeMFA(ctx, userID)
+}
+
+func (s *AuthService) removeMFA(ctx context.Context, userID uuid.UUID) error {
+	if err := s.queries.DeleteUserTOTP(ctx, userID); err != nil {
+		return fmt.Errorf("failed to delete totp: %w", err)
+	}
+	if err := s.queries.DeleteUserRecoveryCodes(ctx, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+	logging.Info("mfa disabled", "userID", userID.String())
+	return nil
+}
+
+// replaceRecoveryCodes generates a new set of recovery codes, storing only their hashes
+func (s *AuthService) replaceRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	if err := s.queries.DeleteUserRecoveryCodes(ctx, userID); err != nil {
+		return nil, fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	for len(codes) < recoveryCodeCount {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		err = s.queries.CreateRecoveryCode(ctx, database.CreateRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: hashToken(normalizeMFACode(code)),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to store recovery code: %w", err)
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// generateRecoveryCode returns a random code formatted like "abcde-fghij"
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 7)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate recovery code: %w", err)
+	}
+	code := strings.ToLower(totpEncoding.EncodeToString(b))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+// normalizeMFACode accepts codes as users type them, with spaces, dashes or capitals
+func normalizeMFACode(code string) string {
+	return strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(strings.TrimSpace(code)))
+}
+
+// mfaKey derives the key authenticator secrets are encrypted with, from
+// MFA_ENCRYPTION_KEY or else the JWT secret
+func (s *AuthService) mfaKey() []byte {
+	material := s.config.Auth.MFAEncryptionKey
+	if material == "" {
+		material = s.config.JWT.Secret
+	}
+	mac := hmac.New(sha256.New, []byte(material))
+	mac.Write([]byte("mfa-secret-key"))
+	return mac.Sum(nil)
+}
+
+// encryptMFASecret seals a secret with AES-256-GCM, prefixed with its nonce
+func (s *AuthService) encryptMFASecret(secret []byte) ([]byte, error) {
+	gcm, err := s.mfaCipher()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, secret, nil), nil
+}
+
+func (s *AuthService) decryptMFASecret(data []byte) ([]byte, error) {
+	gcm, err := s.mfaCipher()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("encrypted totp secret is too short")
+	}
+	secret, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt totp secret: %w", err)
+	}
+	return secret, nil
+}
+
+func (s *AuthService) mfaCipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(s.mfaKey())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,315 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/config"
+	"github.com/agpt-go/chatbot-api/internal/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B test vectors for SHA-1, truncated to 6 digits
+	secret := []byte("12345678901234567890")
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tt := range tests {
+		if got := totpCode(secret, totpStep(time.Unix(tt.unix, 0))); got != tt.want {
+			t.Errorf("totpCode at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestMatchTOTP(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	now := time.Unix(1111111109, 0)
+	current := totpStep(now)
+
+	tests := []struct {
+		name   string
+		code   string
+		want   bool
+		wantAt int64
+	}{
+		{"current step", totpCode(secret, current), true, current},
+		{"previous step", totpCode(secret, current-1), true, current - 1},
+		{"next step", totpCode(secret, current+1), true, current + 1},
+		{"too old", totpCode(secret, current-2), false, 0},
+		{"wrong length", "12345", false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := matchTOTP(secret, tt.code, now)
+			if ok != tt.want || step != tt.wantAt {
+				t.Errorf("matchTOTP = %d, %v; want %d, %v", step, ok, tt.wantAt, tt.want)
+			}
+		})
+	}
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := totpProvisioningURI("Chatbot", "user@example.com", []byte("12345678901234567890"))
+
+	parsed, err := url.Parse(uri)
+	if err != nil {
+		t.Fatalf("failed to parse %q: %v", uri, err)
+	}
+	if parsed.Scheme != "otpauth" || parsed.Host != "totp" || parsed.Path != "/Chatbot:user@example.com" {
+		t.Errorf("uri = %q", uri)
+	}
+	if got := parsed.Query().Get("secret"); got != "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" {
+		t.Errorf("secret = %q", got)
+	}
+	if got := parsed.Query().Get("issuer"); got != "Chatbot" {
+		t.Errorf("issuer = %q", got)
+	}
+}
+
+func TestMFASecretEncryption(t *testing.T) {
+	s := &AuthService{config: &config.Config{JWT: config.JWTConfig{Secret: "jwt-secret"}}}
+	secret := []byte("12345678901234567890")
+
+	encrypted, err := s.encryptMFASecret(secret)
+	if err != nil {
+		t.Fatalf("encryptMFASecret: %v", err)
+	}
+	if bytes.Contains(encrypted, secret) {
+		t.Fatal("encrypted secret contains the plaintext")
+	}
+	decrypted, err := s.decryptMFASecret(encrypted)
+	if err != nil {
+		t.Fatalf("decryptMFASecret: %v", err)
+	}
+	if !bytes.Equal(decrypted, secret) {
+		t.Errorf("decrypted = %q, want %q", decrypted, secret)
+	}
+
+	// A different key can't read it
+	other := &AuthService{config: &config.Config{
+		JWT:  config.JWTConfig{Secret: "jwt-secret"},
+		Auth: config.AuthConfig{MFAEncryptionKey: "other-key"},
+	}}
+	if _, err := other.decryptMFASecret(encrypted); err == nil {
+		t.Error("decrypting with another key succeeded")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			t.Fatalf("generateRecoveryCode: %v", err)
+		}
+		if len(code) != 11 || code[5] != '-' || code != strings.ToLower(code) {
+			t.Errorf("code = %q, want xxxxx-xxxxx", code)
+		}
+		if seen[code] {
+			t.Errorf("duplicate code %q", code)
+		}
+		seen[code] = true
+	}
+
+	if got := normalizeMFACode(" ABCDE-fghij "); got != "abcdefghij" {
+		t.Errorf("normalizeMFACode = %q", got)
+	}
+	if got := normalizeMFACode("123 456"); got != "123456" {
+		t.Errorf("normalizeMFACode = %q", got)
+	}
+}
+
+// fakeQueryDB answers single-row queries by name and records the statements run
+type fakeQueryDB struct {
+	rows      map[string][]any // Values returned by each query; missing queries return no row
+	fail      map[string]error // Errors returned by each query instead of a result
+	affected  map[string]int64 // Rows affected by each statement; missing statements affect one
+	args      map[string][]any
+	ran       []string
+	committed bool
+}
+
+func queryName(sql string) string {
+	name, _, _ := strings.Cut(strings.TrimPrefix(sql, "-- name: "), " ")
+	return name
+}
+
+func (db *fakeQueryDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	name := queryName(sql)
+	db.ran = append(db.ran, name)
+	if err := db.fail[name]; err != nil {
+		return pgconn.CommandTag{}, err
+	}
+	if n, ok := db.affected[name]; ok {
+		return pgconn.NewCommandTag(fmt.Sprintf("DELETE %d", n)), nil
+	}
+	return pgconn.NewCommandTag("DELETE 1"), nil
+}
+
+func (db *fakeQueryDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	return nil, errors.New("unexpected query")
+}
+
+func (db *fakeQueryDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	name := queryName(sql)
+	db.ran = append(db.ran, name)
+	if db.args == nil {
+		db.args = make(map[string][]any)
+	}
+	db.args[name] = args
+	if err := db.fail[name]; err != nil {
+		return fakeRow{err: err}
+	}
+	values, ok := db.rows[name]
+	if !ok {
+		return fakeRow{err: pgx.ErrNoRows}
+	}
+	return fakeRow{values: values}
+}
+
+func (db *fakeQueryDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	return &fakeTx{db: db, committed: &db.committed}, nil
+}
+
+// fakeTx runs statements against the fake it was started from and records whether it committed
+type fakeTx struct {
+	pgx.Tx
+	db        database.DBTX
+	committed *bool
+}
+
+func (tx *fakeTx) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return tx.db.Exec(ctx, sql, args...)
+}
+
+func (tx *fakeTx) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	return tx.db.Query(ctx, sql, args...)
+}
+
+func (tx *fakeTx) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return tx.db.QueryRow(ctx, sql, args...)
+}
+
+func (tx *fakeTx) Commit(ctx context.Context) error {
+	*tx.committed = true
+	return nil
+}
+
+func (tx *fakeTx) Rollback(ctx context.Context) error {
+	return nil
+}
+
+func TestSignInLimitsOpenMFAChallenges(t *testing.T) {
+	tests := []struct {
+		name   string
+		active int64
+		want   error
+	}{
+		{"below the limit", maxActiveMFAChallenges - 1, nil},
+		{"at the limit", maxActiveMFAChallenges, ErrTooManyMFAChallenges},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeQueryDB{rows: map[string][]any{
+				"GetUserMFAState":          {false, true},
+				"CountActiveMFAChallenges": {tt.active},
+			}}
+			s := &AuthService{queries: database.New(db)}
+
+			_, err := s.signIn(context.Background(), &database.User{ID: uuid.New()})
+			created := slices.Contains(db.ran, "CreateMFAChallenge")
+			if tt.want != nil {
+				if !errors.Is(err, tt.want) || created {
+					t.Errorf("err = %v, challenge created = %v; want %v and none created", err, created, tt.want)
+				}
+				return
+			}
+			var challenge *MFAChallengeError
+			if !errors.As(err, &challenge) || !created {
+				t.Errorf("err = %v, challenge created = %v; want an MFA challenge", err, created)
+			}
+		})
+	}
+}
+
+func TestVerifyMFAChallengeCountsAttemptFirst(t *testing.T) {
+	// UseMFAChallengeAttempt returns no row for an expired challenge or one
+	// out of attempts; the code must not be checked then
+	db := &fakeQueryDB{}
+	s := &AuthService{queries: database.New(db)}
+
+	_, _, err := s.VerifyMFAChallenge(context.Background(), "token", "123456")
+	if !errors.Is(err, ErrInvalidMFAToken) {
+		t.Errorf("err = %v, want %v", err, ErrInvalidMFAToken)
+	}
+	if want := []string{"UseMFAChallengeAttempt"}; !slices.Equal(db.ran, want) {
+		t.Errorf("queries = %v, want %v", db.ran, want)
+	}
+	if args := db.args["UseMFAChallengeAttempt"]; len(args) != 2 || args[1] != int32(mfaChallengeMaxAttempts) {
+		t.Errorf("UseMFAChallengeAttempt args = %v, want max attempts %d", args, mfaChallengeMaxAttempts)
+	}
+}
+
+func TestVerifyMFAChange(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("right-password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	passwordHash := string(hash)
+	userRow := func(passwordHash *string) []any {
+		return []any{uuid.New(), "ada@example.com", passwordHash}
+	}
+
+	tests := []struct {
+		name      string
+		db        *fakeQueryDB
+		password  string
+		want      error
+		checkCode bool
+	}{
+		{"attempts used up", &fakeQueryDB{
+			rows:     map[string][]any{"GetUserByID": userRow(&passwordHash)},
+			affected: map[string]int64{"UseMFACodeAttempt": 0},
+		}, "right-password", ErrTooManyMFAAttempts, false},
+		{"wrong password", &fakeQueryDB{
+			rows: map[string][]any{"GetUserByID": userRow(&passwordHash)},
+		}, "wrong-password", ErrIncorrectPassword, false},
+		{"right password", &fakeQueryDB{
+			rows: map[string][]any{"GetUserByID": userRow(&passwordHash)},
+		}, "right-password", ErrMFANotEnabled, true},
+		{"account without a password", &fakeQueryDB{
+			rows: map[string][]any{"GetUserByID": userRow(nil)},
+		}, "", ErrMFANotEnabled, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &AuthService{queries: database.New(tt.db)}
+
+			err := s.verifyMFAChange(context.Background(), uuid.New(), tt.password, "123456")
+			if !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+			if got := slices.Contains(tt.db.ran, "GetUserTOTP"); got != tt.checkCode {
+				t.Errorf("queries = %v, code checked = %v, want %v", tt.db.ran, got, tt.checkCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,497 @@
+package services
+
+import (
+	"context"
+	"crypto"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.yaml.in/yaml/v3"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/github"
+)
+
+var (
+	ErrUnknownOAuthProvider = errors.New("unknown oauth provider")
+	ErrInvalidIDToken       = errors.New("invalid id token")
+)
+
+// Kinds of OAuth providers
+const (
+	OAuthProviderOIDC   = "oidc"   // OpenID Connect, configured through issuer discovery
+	OAuthProviderGitHub = "github" // GitHub, which has no ID tokens and is read through its API
+)
+
+const (
+	googleIssuer     = "https://accounts.google.com"
+	githubAPIURL     = "https://api.github.com"
+	oauthHTTPTimeout = 10 * time.Second
+
+	// providerKeysTTL is how long a provider's JWKS is used before it is fetched again
+	providerKeysTTL = time.Hour
+	// minProviderKeysRefresh limits refetching the JWKS for tokens signed with an unknown key
+	minProviderKeysRefresh = time.Minute
+)
+
+var oauthProviderNamePattern = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// idTokenAlgorithms are the signing algorithms accepted on ID tokens
+var idTokenAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// OAuthProviderConfig describes a provider users can sign in with. Values are
+// expanded from the environment, so secrets can be referenced as ${VAR}
+// instead of written into the file.
+type OAuthProviderConfig struct {
+	Name         string   `yaml:"name"` // Used in the /auth/{name} routes and stored as the user's provider
+	Type         string   `yaml:"type"` // oidc (default) or github
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	Scopes       []string `yaml:"scopes"`       // Defaults to openid, email and profile; read:user and user:email for GitHub
+	RedirectURL  string   `yaml:"redirect_url"` // Defaults to the provider's callback route under OAUTH_REDIRECT_BASE_URL
+
+	// oidc
+	Issuer string `yaml:"issuer"` // Must match the issuer in the provider's discovery document
+
+	// github, overridden for GitHub Enterprise
+	AuthURL  string `yaml:"auth_url"`
+	TokenURL string `yaml:"token_url"`
+	APIURL   string `yaml:"api_url"`
+}
+
+// OAuthProvidersConfig is the list of OAuth providers
+type OAuthProvidersConfig struct {
+	Providers []OAuthProviderConfig `yaml:"providers"`
+}
+
+// LoadOAuthProviders reads OAuth providers from a YAML or JSON file. An empty
+// path configures none.
+func LoadOAuthProviders(path string) (*OAuthProvidersConfig, error) {
+	if path == "" {
+		return &OAuthProvidersConfig{}, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read oauth providers: %w", err)
+	}
+
+	config, err := ParseOAuthProviders(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid oauth providers %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// ParseOAuthProviders parses, expands and validates a provider list
+func ParseOAuthProviders(data []byte) (*OAuthProvidersConfig, error) {
+	var config OAuthProvidersConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for i := range config.Providers {
+		p := &config.Providers[i]
+		p.expandEnv()
+
+		if !oauthProviderNamePattern.MatchString(p.Name) {
+			return nil, fmt.Errorf("provider %d: name must only contain lowercase letters, digits, _ and -", i)
+		}
+		if seen[p.Name] {
+			return nil, fmt.Errorf("provider %s: duplicate name", p.Name)
+		}
+		seen[p.Name] = true
+
+		if p.ClientID == "" {
+			return nil, fmt.Errorf("provider %s: client_id is required", p.Name)
+		}
+		switch p.Type {
+		case "", OAuthProviderOIDC:
+			p.Type = OAuthProviderOIDC
+			if !strings.HasPrefix(p.Issuer, "https://") && !strings.HasPrefix(p.Issuer, "http://") {
+				return nil, fmt.Errorf("provider %s: issuer must be an http(s) URL", p.Name)
+			}
+		case OAuthProviderGitHub:
+		default:
+			return nil, fmt.Errorf("provider %s: type must be %s or %s", p.Name, OAuthProviderOIDC, OAuthProviderGitHub)
+		}
+	}
+	return &config, nil
+}
+
+func (c *OAuthProviderConfig) expandEnv() {
+	for _, v := range []*string{&c.Name, &c.Type, &c.ClientID, &c.ClientSecret, &c.RedirectURL, &c.Issuer, &c.AuthURL, &c.TokenURL, &c.APIURL} {
+		*v = os.ExpandEnv(*v)
+	}
+	for i := range c.Scopes {
+		c.Scopes[i] = os.ExpandEnv(c.Scopes[i])
+	}
+}
+
+// OAuthIdentity is the account a user signed in with at a provider
+type OAuthIdentity struct {
+	Provider      string
+	Subject       string // The user's ID at the provider
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// OAuthProvider signs users in with an OAuth 2.0 provider, using PKCE. OIDC
+// providers are discovered on first use and identify the user with an ID
+// token, which is validated against the provider's JWKS.
+type OAuthProvider struct {
+	name   string
+	kind   string
+	issuer string
+	apiURL string
+	oauth  oauth2.Config
+	client *http.Client
+
+	mu            sync.Mutex
+	discovered    bool
+	jwksURI       string
+	userinfoURL   string
+	keys          map[string]crypto.PublicKey
+	keysFetchedAt time.Time
+}
+
+// NewOAuthProvider creates a provider from a validated config. A missing
+// redirect URL defaults to the provider's callback route under redirectBaseURL.
+func NewOAuthProvider(cfg OAuthProviderConfig, redirectBaseURL string) *OAuthProvider {
+	p := &OAuthProvider{
+		name:   cfg.Name,
+		kind:   cfg.Type,
+		issuer: cfg.Issuer,
+		oauth: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       cfg.Scopes,
+		},
+		client: &http.Client{Timeout: oauthHTTPTimeout},
+	}
+	if p.oauth.RedirectURL == "" {
+		p.oauth.RedirectURL = strings.TrimSuffix(redirectBaseURL, "/") + "/" + cfg.Name + "/callback"
+	}
+
+	if p.kind == OAuthProviderGitHub {
+		p.oauth.Endpoint = github.Endpoint
+		if cfg.AuthURL != "" {
+			p.oauth.Endpoint.AuthURL = cfg.AuthURL
+		}
+		if cfg.TokenURL != "" {
+			p.oauth.Endpoint.TokenURL = cfg.TokenURL
+		}
+		p.apiURL = strings.TrimSuffix(cfg.APIURL, "/")
+		if p.apiURL == "" {
+			p.apiURL = githubAPIURL
+		}
+		if len(p.oauth.Scopes) == 0 {
+			p.oauth.Scopes = []string{"read:user", "user:email"}
+		}
+	} else if len(p.oauth.Scopes) == 0 {
+		p.oauth.Scopes = []string{"openid", "email", "profile"}
+	}
+	return p
+}
+
+// AuthCodeURL returns the provider's consent page URL. verifier is the PKCE
+// code verifier; nonce is checked against the ID token of OIDC providers.
+func (p *OAuthProvider) AuthCodeURL(ctx context.Context, state, verifier, nonce string) (string, error) {
+	if err := p.discover(ctx); err != nil {
+		return "", err
+	}
+	opts := []oauth2.AuthCodeOption{oauth2.S256ChallengeOption(verifier)}
+	if p.kind == OAuthProviderOIDC {
+		opts = append(opts, oauth2.SetAuthURLParam("nonce", nonce))
+	}
+	return p.oauth.AuthCodeURL(state, opts...), nil
+}
+
+// Exchange redeems an authorization code and returns the user it identifies
+func (p *OAuthProvider) Exchange(ctx context.Context, code, verifier, nonce string) (*OAuthIdentity, error) {
+	if err := p.discover(ctx); err != nil {
+		return nil, err
+	}
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, p.client)
+
+	token, err := p.oauth.Exchange(ctx, code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code: %w", err)
+	}
+
+	var identity *OAuthIdentity
+	if p.kind == OAuthProviderGitHub {
+		identity, err = p.githubIdentity(ctx, token)
+	} else {
+		identity, err = p.oidcIdentity(ctx, token, nonce)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if identity.Email == "" {
+		return nil, fmt.Errorf("%s returned no email address", p.name)
+	}
+	if identity.Name == "" {
+		identity.Name = identity.Email
+	}
+	identity.Provider = p.name
+	return identity, nil
+}
+
+// oidcDiscovery is the part of an OpenID Provider's metadata that is used
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+// discover fetches the endpoints of an OIDC provider, once it succeeds
+func (p *OAuthProvider) discover(ctx context.Context) error {
+	if p.kind != OAuthProviderOIDC {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.discovered {
+		return nil
+	}
+
+	var doc oidcDiscovery
+	if err := p.getJSON(ctx, strings.TrimSuffix(p.issuer, "/")+"/.well-known/openid-configuration", nil, &doc); err != nil {
+		return fmt.Errorf("failed to discover %s: %w", p.name, err)
+	}
+	if doc.Issuer != p.issuer {
+		return fmt.Errorf("failed to discover %s: issuer is %q, want %q", p.name, doc.Issuer, p.issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return fmt.Errorf("failed to discover %s: authorization, token and jwks endpoints are required", p.name)
+	}
+
+	p.oauth.Endpoint = oauth2.Endpoint{AuthURL: doc.AuthorizationEndpoint, TokenURL: doc.TokenEndpoint}
+	p.jwksURI = doc.JWKSURI
+	p.userinfoURL = doc.UserinfoEndpoint
+	p.discovered = true
+	return nil
+}
+
+// idTokenClaims are the ID token claims that identify the user
+type idTokenClaims struct {
+	Nonce             string       `json:"nonce"`
+	AuthorizedParty   string       `json:"azp"`
+	Email             string       `json:"email"`
+	EmailVerified     flexibleBool `json:"email_verified"`
+	Name              string       `json:"name"`
+	PreferredUsername string       `json:"preferred_username"`
+	jwt.RegisteredClaims
+}
+
+// flexibleBool decodes booleans some providers send as strings
+type flexibleBool bool
+
+func (b *flexibleBool) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("invalid boolean %s", data)
+	}
+	*b = flexibleBool(v)
+	return nil
+}
+
+// oidcIdentity validates the ID token returned with token. Claims it lacks
+// are read from the userinfo endpoint.
+func (p *OAuthProvider) oidcIdentity(ctx context.Context, token *oauth2.Token, nonce string) (*OAuthIdentity, error) {
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		return nil, fmt.Errorf("%w: %s returned no id token", ErrInvalidIDToken, p.name)
+	}
+	claims, err := p.validateIDToken(ctx, rawIDToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	identity := &OAuthIdentity{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          claims.Name,
+	}
+	if (identity.Email == "" || identity.Name == "") && p.userinfoURL != "" {
+		var info idTokenClaims
+		if err := p.getJSON(ctx, p.userinfoURL, token, &info); err != nil {
+			return nil, fmt.Errorf("failed to get user info: %w", err)
+		}
+		// Userinfo must describe the user the ID token identifies
+		if info.Subject == claims.Subject {
+			if identity.Email == "" {
+				identity.Email, identity.EmailVerified = info.Email, bool(info.EmailVerified)
+			}
+			if identity.Name == "" {
+				identity.Name = info.Name
+			}
+		}
+	}
+	if identity.Name == "" {
+		identity.Name = claims.PreferredUsername
+	}
+	return identity, nil
+}
+
+// validateIDToken checks the signature, issuer, audience, expiry and nonce of an ID token
+func (p *OAuthProvider) validateIDToken(ctx context.Context, rawIDToken, nonce string) (*idTokenClaims, error) {
+	var claims idTokenClaims
+	_, err := jwt.ParseWithClaims(rawIDToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.providerKey(ctx, kid)
+	},
+		jwt.WithValidMethods(idTokenAlgorithms),
+		jwt.WithIssuer(p.issuer),
+		jwt.WithAudience(p.oauth.ClientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing sub", ErrInvalidIDToken)
+	}
+	if len(claims.Audience) > 1 && claims.AuthorizedParty != p.oauth.ClientID {
+		return nil, fmt.Errorf("%w: azp is not the client", ErrInvalidIDToken)
+	}
+	if nonce == "" || subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+	}
+	return &claims, nil
+}
+
+// providerKey returns the provider's public key with the given kid, fetching
+// the JWKS when it is stale or lacks the key, as after the provider rotated
+// keys. An empty kid selects the only key.
+func (p *OAuthProvider) providerKey(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	key, ok := p.lookupKey(kid)
+	stale := now.Sub(p.keysFetchedAt) >= providerKeysTTL
+	if ok && !stale {
+		return key, nil
+	}
+	if !stale && now.Sub(p.keysFetchedAt) < minProviderKeysRefresh {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	var set JWKSet
+	if err := p.getJSON(ctx, p.jwksURI, nil, &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch %s keys: %w", p.name, err)
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		// Keys of types that can't be decoded are skipped, so one doesn't break sign-in
+		if pub, err := jwk.PublicKey(); err == nil {
+			keys[jwk.KeyID] = pub
+		}
+	}
+	p.keys, p.keysFetchedAt = keys, now
+
+	if key, ok := p.lookupKey(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (p *OAuthProvider) lookupKey(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(p.keys) == 1 {
+		for _, key := range p.keys {
+			return key, true
+		}
+	}
+	key, ok := p.keys[kid]
+	return key, ok
+}
+
+// githubUser is the part of a GitHub user that is used
+type githubUser struct {
+	ID    int64  `json:"id"`
+	Login string `json:"login"`
+	Name  string `json:"name"`
+}
+
+type githubEmail struct {
+	Email    string `json:"email"`
+	Primary  bool   `json:"primary"`
+	Verified bool   `json:"verified"`
+}
+
+// githubIdentity reads the user and their primary email address from the GitHub API
+func (p *OAuthProvider) githubIdentity(ctx context.Context, token *oauth2.Token) (*OAuthIdentity, error) {
+	var user githubUser
+	if err := p.getJSON(ctx, p.apiURL+"/user", token, &user); err != nil {
+		return nil, fmt.Errorf("failed to get user info: %w", err)
+	}
+	if user.ID == 0 {
+		return nil, fmt.Errorf("failed to get user info: missing id")
+	}
+
+	var emails []githubEmail
+	if err := p.getJSON(ctx, p.apiURL+"/user/emails", token, &emails); err != nil {
+		return nil, fmt.Errorf("failed to get user emails: %w", err)
+	}
+
+	identity := &OAuthIdentity{Subject: strconv.FormatInt(user.ID, 10), Name: user.Name}
+	if identity.Name == "" {
+		identity.Name = user.Login
+	}
+	for _, email := range emails {
+		if email.Primary {
+			identity.Email, identity.EmailVerified = email.Email, email.Verified
+			break
+		}
+	}
+	return identity, nil
+}
+
+// getJSON decodes the JSON response to a GET request, authorized by token if not nil
+func (p *OAuthProvider) getJSON(ctx context.Context, url string, token *oauth2.Token, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if token != nil {
+		token.SetAuthHeader(req)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s returned %d: %s", url, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
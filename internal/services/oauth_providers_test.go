@@ -0,0 +1,394 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/oauth2"
+)
+
+// mockOIDCServer is an OpenID Provider that authorizes every request
+type mockOIDCServer struct {
+	*httptest.Server
+	t        *testing.T
+	clientID string
+
+	mu     sync.Mutex
+	key    *rsa.PrivateKey
+	kid    string
+	codes  map[string]mockAuthorization
+	claims func(claims jwt.MapClaims) // Changes the claims of issued ID tokens
+}
+
+// mockAuthorization is what an authorization code was issued for
+type mockAuthorization struct {
+	challenge string
+	nonce     string
+}
+
+func newMockOIDCServer(t *testing.T) *mockOIDCServer {
+	t.Helper()
+	s := &mockOIDCServer{t: t, clientID: "test-client", codes: make(map[string]mockAuthorization)}
+	s.rotateKey("key-1")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(w, oidcDiscovery{
+			Issuer:                s.URL,
+			AuthorizationEndpoint: s.URL + "/authorize",
+			TokenEndpoint:         s.URL + "/token",
+			JWKSURI:               s.URL + "/jwks",
+			UserinfoEndpoint:      s.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("GET /jwks", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		writeTestJSON(w, JWKSet{Keys: []JWK{{
+			KeyType:   "RSA",
+			Use:       "sig",
+			Algorithm: "RS256",
+			KeyID:     s.kid,
+			N:         base64.RawURLEncoding.EncodeToString(s.key.N.Bytes()),
+			E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(s.key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("POST /token", s.token)
+	mux.HandleFunc("GET /userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer mock-access-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		writeTestJSON(w, map[string]any{"sub": "user-123", "email": "info@example.com", "email_verified": "true"})
+	})
+	s.Server = httptest.NewServer(mux)
+	t.Cleanup(s.Close)
+	return s
+}
+
+// rotateKey replaces the key ID tokens are signed with
+func (s *mockOIDCServer) rotateKey(kid string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.key, s.kid = key, kid
+}
+
+func (s *mockOIDCServer) provider() *OAuthProvider {
+	return NewOAuthProvider(OAuthProviderConfig{
+		Name:         "mock",
+		Type:         OAuthProviderOIDC,
+		Issuer:       s.URL,
+		ClientID:     s.clientID,
+		ClientSecret: "test-secret",
+	}, "http://localhost:8080/api/v1/auth")
+}
+
+// authorize plays the user consenting on the page at authURL and returns the code
+func (s *mockOIDCServer) authorize(authURL string) string {
+	s.t.Helper()
+	u, err := url.Parse(authURL)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	q := u.Query()
+	if u.Path != "/authorize" || q.Get("client_id") != s.clientID || q.Get("response_type") != "code" {
+		s.t.Fatalf("unexpected authorization request %s", authURL)
+	}
+	if q.Get("code_challenge_method") != "S256" || q.Get("code_challenge") == "" {
+		s.t.Fatalf("authorization request without PKCE: %s", authURL)
+	}
+	if q.Get("redirect_uri") != "http://localhost:8080/api/v1/auth/mock/callback" {
+		s.t.Errorf("redirect_uri = %q", q.Get("redirect_uri"))
+	}
+
+	code := oauth2.GenerateVerifier()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.codes[code] = mockAuthorization{challenge: q.Get("code_challenge"), nonce: q.Get("nonce")}
+	return code
+}
+
+func (s *mockOIDCServer) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	clientID, secret, ok := r.BasicAuth()
+	if !ok || clientID != s.clientID || secret != "test-secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	auth, ok := s.codes[r.PostForm.Get("code")]
+	delete(s.codes, r.PostForm.Get("code"))
+	challenge := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(challenge[:]) != auth.challenge {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"error":"invalid_grant"}`))
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            s.URL,
+		"sub":            "user-123",
+		"aud":            s.clientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(time.Hour).Unix(),
+		"nonce":          auth.nonce,
+		"email":          "user@example.com",
+		"email_verified": true,
+		"name":           "Test User",
+	}
+	if s.claims != nil {
+		s.claims(claims)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = s.kid
+	idToken, err := token.SignedString(s.key)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	writeTestJSON(w, map[string]any{
+		"access_token": "mock-access-token",
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func writeTestJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// signIn runs an authorization code flow against provider
+func signIn(t *testing.T, idp *mockOIDCServer, provider *OAuthProvider, nonce string) (*OAuthIdentity, error) {
+	t.Helper()
+	ctx := context.Background()
+	verifier := oauth2.GenerateVerifier()
+	authURL, err := provider.AuthCodeURL(ctx, "state", verifier, "nonce-1")
+	if err != nil {
+		t.Fatalf("AuthCodeURL() error = %v", err)
+	}
+	return provider.Exchange(ctx, idp.authorize(authURL), verifier, nonce)
+}
+
+func TestParseOAuthProviders(t *testing.T) {
+	t.Setenv("TEST_OKTA_SECRET", "s3cret")
+	config, err := ParseOAuthProviders([]byte(`
+providers:
+  - name: okta
+    issuer: https://example.okta.com
+    client_id: okta-client
+    client_secret: ${TEST_OKTA_SECRET}
+  - name: github
+    type: github
+    client_id: gh-client
+`))
+	if err != nil {
+		t.Fatalf("ParseOAuthProviders() error = %v", err)
+	}
+	okta := config.Providers[0]
+	if okta.Type != OAuthProviderOIDC || okta.ClientSecret != "s3cret" {
+		t.Errorf("okta = %+v, want type oidc with the expanded secret", okta)
+	}
+
+	github := NewOAuthProvider(config.Providers[1], "https://api.example.com/api/v1/auth/")
+	if github.oauth.RedirectURL != "https://api.example.com/api/v1/auth/github/callback" {
+		t.Errorf("RedirectURL = %q", github.oauth.RedirectURL)
+	}
+	if strings.Join(github.oauth.Scopes, " ") != "read:user user:email" {
+		t.Errorf("Scopes = %v", github.oauth.Scopes)
+	}
+
+	invalid := map[string]string{
+		"invalid name":   "providers:\n  - name: Okta\n    issuer: https://example.okta.com\n    client_id: c\n",
+		"duplicate name": "providers:\n  - name: a\n    type: github\n    client_id: c\n  - name: a\n    type: github\n    client_id: c\n",
+		"no client_id":   "providers:\n  - name: a\n    type: github\n",
+		"no issuer":      "providers:\n  - name: a\n    client_id: c\n",
+		"unknown type":   "providers:\n  - name: a\n    type: saml\n    client_id: c\n",
+	}
+	for name, data := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseOAuthProviders([]byte(data)); err == nil {
+				t.Error("ParseOAuthProviders() should fail")
+			}
+		})
+	}
+}
+
+func TestOAuthProviderOIDC(t *testing.T) {
+	idp := newMockOIDCServer(t)
+
+	identity, err := signIn(t, idp, idp.provider(), "nonce-1")
+	if err != nil {
+		t.Fatalf("sign-in failed: %v", err)
+	}
+	want := OAuthIdentity{Provider: "mock", Subject: "user-123", Email: "user@example.com", EmailVerified: true, Name: "Test User"}
+	if *identity != want {
+		t.Errorf("identity = %+v, want %+v", *identity, want)
+	}
+
+	t.Run("claims missing from the ID token are read from userinfo", func(t *testing.T) {
+		idp.claims = func(claims jwt.MapClaims) {
+			delete(claims, "email")
+			delete(claims, "email_verified")
+		}
+		defer func() { idp.claims = nil }()
+
+		identity, err := signIn(t, idp, idp.provider(), "nonce-1")
+		if err != nil {
+			t.Fatalf("sign-in failed: %v", err)
+		}
+		if identity.Email != "info@example.com" || !identity.EmailVerified {
+			t.Errorf("identity = %+v, want the userinfo email", *identity)
+		}
+	})
+
+	t.Run("rotated key", func(t *testing.T) {
+		provider := idp.provider()
+		if _, err := signIn(t, idp, provider, "nonce-1"); err != nil {
+			t.Fatal(err)
+		}
+		idp.rotateKey("key-2")
+		defer idp.rotateKey("key-1")
+
+		// The JWKS was just fetched, so it isn't fetched again for an unknown key yet
+		if _, err := signIn(t, idp, provider, "nonce-1"); !errors.Is(err, ErrInvalidIDToken) {
+			t.Errorf("error = %v, want %v", err, ErrInvalidIDToken)
+		}
+		provider.keysFetchedAt = provider.keysFetchedAt.Add(-minProviderKeysRefresh)
+		if _, err := signIn(t, idp, provider, "nonce-1"); err != nil {
+			t.Errorf("token signed with the new key: %v", err)
+		}
+	})
+}
+
+func TestOAuthProviderIDTokenValidation(t *testing.T) {
+	idp := newMockOIDCServer(t)
+
+	tests := []struct {
+		name   string
+		claims func(claims jwt.MapClaims)
+		nonce  string
+	}{
+		{"wrong nonce", nil, "nonce-2"},
+		{"wrong audience", func(c jwt.MapClaims) { c["aud"] = "other-client" }, "nonce-1"},
+		{"wrong issuer", func(c jwt.MapClaims) { c["iss"] = "https://evil.example.com" }, "nonce-1"},
+		{"expired", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }, "nonce-1"},
+		{"no expiry", func(c jwt.MapClaims) { delete(c, "exp") }, "nonce-1"},
+		{"no subject", func(c jwt.MapClaims) { delete(c, "sub") }, "nonce-1"},
+		{"other authorized party", func(c jwt.MapClaims) {
+			c["aud"] = []string{"test-client", "other-client"}
+			c["azp"] = "other-client"
+		}, "nonce-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			idp.claims = tt.claims
+			defer func() { idp.claims = nil }()
+
+			if _, err := signIn(t, idp, idp.provider(), tt.nonce); !errors.Is(err, ErrInvalidIDToken) {
+				t.Errorf("error = %v, want %v", err, ErrInvalidIDToken)
+			}
+		})
+	}
+
+	t.Run("wrong code verifier", func(t *testing.T) {
+		provider := idp.provider()
+		authURL, err := provider.AuthCodeURL(context.Background(), "state", oauth2.GenerateVerifier(), "nonce-1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := provider.Exchange(context.Background(), idp.authorize(authURL), oauth2.GenerateVerifier(), "nonce-1"); err == nil {
+			t.Error("Exchange() should fail")
+		}
+	})
+
+	t.Run("issuer mismatch", func(t *testing.T) {
+		provider := idp.provider()
+		provider.issuer = idp.URL + "/"
+		if _, err := provider.AuthCodeURL(context.Background(), "state", oauth2.GenerateVerifier(), "nonce-1"); err == nil {
+			t.Error("AuthCodeURL() should fail for a discovery document of another issuer")
+		}
+	})
+}
+
+func TestOAuthProviderGitHub(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /token", func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.PostForm.Get("code") != "gh-code" || r.PostForm.Get("code_verifier") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		writeTestJSON(w, map[string]any{"access_token": "gho_token", "token_type": "bearer"})
+	})
+	authorized := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Bearer gho_token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next(w, r)
+		}
+	}
+	mux.HandleFunc("GET /user", authorized(func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(w, map[string]any{"id": 42, "login": "octocat"})
+	}))
+	mux.HandleFunc("GET /user/emails", authorized(func(w http.ResponseWriter, r *http.Request) {
+		writeTestJSON(w, []githubEmail{
+			{Email: "old@example.com", Verified: true},
+			{Email: "octocat@example.com", Primary: true, Verified: true},
+		})
+	}))
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	provider := NewOAuthProvider(OAuthProviderConfig{
+		Name:     "github",
+		Type:     OAuthProviderGitHub,
+		ClientID: "gh-client",
+		AuthURL:  srv.URL + "/authorize",
+		TokenURL: srv.URL + "/token",
+		APIURL:   srv.URL,
+	}, "http://localhost:8080/api/v1/auth")
+
+	authURL, err := provider.AuthCodeURL(context.Background(), "state", oauth2.GenerateVerifier(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(authURL, srv.URL+"/authorize?") || !strings.Contains(authURL, "code_challenge=") {
+		t.Errorf("AuthCodeURL() = %q", authURL)
+	}
+
+	identity, err := provider.Exchange(context.Background(), "gh-code", oauth2.GenerateVerifier(), "")
+	if err != nil {
+		t.Fatalf("Exchange() error = %v", err)
+	}
+	want := OAuthIdentity{Provider: "github", Subject: "42", Email: "octocat@example.com", EmailVerified: true, Name: "octocat"}
+	if *identity != want {
+		t.Errorf("identity = %+v, want %+v", *identity, want)
+	}
+}
@@ -0,0 +1,624 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrNotInOrganization          = errors.New("user is not in an organization")
+	ErrAlreadyInOrganization      = errors.New("user already belongs to an organization")
+	ErrOrganizationForbidden      = errors.New("insufficient organization role")
+	ErrOrganizationMemberNotFound = errors.New("organization member not found")
+	ErrLastOrganizationOwner      = errors.New("organization must keep at least one owner")
+	ErrInvitationNotFound         = errors.New("organization invitation not found")
+	ErrInvitationEmailUnverified  = errors.New("email address must be verified to use invitations")
+)
+
+// Organization roles, from most to least privileged
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+)
+
+// organizationInvitationExpiry is how long an invitation can be accepted
+const organizationInvitationExpiry = 7 * 24 * time.Hour
+
+// OrganizationService manages organizations, their members and the
+// company-level business understanding shared by all members
+type OrganizationService struct {
+	queries *database.Queries
+	schema  *UnderstandingSchema
+}
+
+// NewOrganizationService creates a new organization service
+func NewOrganizationService(queries *database.Queries, schema *UnderstandingSchema) *OrganizationService {
+	return &OrganizationService{
+		queries: queries,
+		schema:  schema,
+	}
+}
+
+// OrganizationMemberView is a member of an organization
+type OrganizationMemberView struct {
+	UserID   uuid.UUID `json:"user_id"`
+	Email    string    `json:"email"`
+	Name     string    `json:"name"`
+	Role     string    `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// OrganizationView is an organization as seen by one of its members
+type OrganizationView struct {
+	ID        uuid.UUID                `json:"id"`
+	Name      string                   `json:"name"`
+	Role      string                   `json:"role"`
+	Members   []OrganizationMemberView `json:"members"`
+	CreatedAt time.Time                `json:"created_at"`
+}
+
+// OrganizationInvitationView is a pending invitation to join an organization
+type OrganizationInvitationView struct {
+	ID               uuid.UUID `json:"id"`
+	OrganizationID   uuid.UUID `json:"organization_id"`
+	OrganizationName string    `json:"organization_name,omitempty"`
+	Email            string    `json:"email"`
+	Role             string    `json:"role"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// OrganizationUnderstandingView is the company-level business understanding
+type OrganizationUnderstandingView struct {
+	Context   *BusinessContext `json:"understanding"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+// Create creates an organization with the user as its owner
+func (s *OrganizationService) Create(ctx context.Context, userID uuid.UUID, name string) (*OrganizationView, error) {
+	if _, err := s.membership(ctx, userID); err == nil {
+		return nil, ErrAlreadyInOrganization
+	} else if !errors.Is(err, ErrNotInOrganization) {
+		return nil, err
+	}
+
+	org, err := s.queries.CreateOrganization(ctx, database.CreateOrganizationParams{
+		Name:      name,
+		CreatedBy: &userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create organization: %w", err)
+	}
+
+	_, err = s.queries.CreateOrganizationMember(ctx, database.CreateOrganizationMemberParams{
+		OrganizationID: org.ID,
+		UserID:         userID,
+		Role:           OrgRoleOwner,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to add organization owner: %w", err)
+	}
+
+	return s.buildView(ctx, org, OrgRoleOwner)
+}
+
+// Get returns the user's organization with its members
+func (s *OrganizationService) Get(ctx context.Context, userID uuid.UUID) (*OrganizationView, error) {
+	member, err := s.membership(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	org, err := s.queries.GetOrganization(ctx, member.OrganizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+
+	return s.buildView(ctx, org, member.Role)
+}
+
+// Invite invites someone to the caller's organization by email. Owners and
+// admins can invite; only owners can invite owners. Inviting the same address
+// again replaces the earlier invitation. The address is not looked up, so the
+// result is the same whether or not it belongs to an account.
+func (s *OrganizationService) Invite(ctx context.Context, userID uuid.UUID, email, role string) (*OrganizationInvitationView, error) {
+	member, err := s.membership(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !canManageMembers(member.Role) || (role == OrgRoleOwner && member.Role != OrgRoleOwner) {
+		return nil, ErrOrganizationForbidden
+	}
+
+	invitation, err := s.queries.UpsertOrganizationInvitation(ctx, database.UpsertOrganizationInvitationParams{
+		OrganizationID: member.OrganizationID,
+		Email:          normalizeInvitationEmail(email),
+		Role:           role,
+		InvitedBy:      &userID,
+		ExpiresAt:      time.Now().Add(organizationInvitationExpiry),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create organization invitation: %w", err)
+	}
+
+	return invitationToView(invitation, ""), nil
+}
+
+// ListInvitations returns the pending invitations of the caller's
+// organization. Requires the owner or admin role.
+func (s *OrganizationService) ListInvitations(ctx context.Context, userID uuid.UUID) ([]OrganizationInvitationView, error) {
+	member, err := s.membership(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !canManageMembers(member.Role) {
+		return nil, ErrOrganizationForbidden
+	}
+
+	invitations, err := s.queries.ListOrganizationInvitations(ctx, member.OrganizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organization invitations: %w", err)
+	}
+
+	views := make([]OrganizationInvitationView, len(invitations))
+	for i, invitation := range invitations {
+		views[i] = *invitationToView(invitation, "")
+	}
+	return views, nil
+}
+
+// RevokeInvitation withdraws a pending invitation of the caller's
+// organization. Requires the owner or admin role.
+func (s *OrganizationService) RevokeInvitation(ctx context.Context, userID, invitationID uuid.UUID) error {
+	member, err := s.membership(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !canManageMembers(member.Role) {
+		return ErrOrganizationForbidden
+	}
+
+	deleted, err := s.queries.DeleteOrganizationInvitation(ctx, database.DeleteOrganizationInvitationParams{
+		ID:             invitationID,
+		OrganizationID: member.OrganizationID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revoke organization invitation: %w", err)
+	}
+	if deleted == 0 {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+// ListMyInvitations returns the pending invitations addressed to the user.
+// Invitations are matched by email, so only users who verified their address can see them.
+func (s *OrganizationService) ListMyInvitations(ctx context.Context, userID uuid.UUID) ([]OrganizationInvitationView, error) {
+	email, err := s.verifiedEmail(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.queries.ListInvitationsByEmail(ctx, email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list invitations: %w", err)
+	}
+
+	views := make([]OrganizationInvitationView, len(rows))
+	for i, row := range rows {
+		views[i] = *invitationToView(database.OrganizationInvitation{
+			ID:             row.ID,
+			OrganizationID: row.OrganizationID,
+			Email:          row.Email,
+			Role:           row.Role,
+			InvitedBy:      row.InvitedBy,
+			ExpiresAt:      row.ExpiresAt,
+			CreatedAt:      row.CreatedAt,
+		}, row.OrganizationName)
+	}
+	return views, nil
+}
+
+// AcceptInvitation joins the organization the user was invited to, with the
+// invited role. A user can belong to one organization, so they must leave
+// their current one first.
+func (s *OrganizationService) AcceptInvitation(ctx context.Context, userID, invitationID uuid.UUID) (*OrganizationView, error) {
+	email, err := s.verifiedEmail(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation, err := s.queries.GetInvitationByEmail(ctx, database.GetInvitationByEmailParams{
+		ID:    invitationID,
+		Email: email,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInvitationNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get invitation: %w", err)
+	}
+
+	// Joining and using up the invitation happen together, so a revoked or already
+	// accepted invitation can't add a member
+	err = s.inTx(ctx, func(tx *OrganizationService) error {
+		if _, err := tx.membership(ctx, userID); err == nil {
+			return ErrAlreadyInOrganization
+		} else if !errors.Is(err, ErrNotInOrganization) {
+			return err
+		}
+
+		// The unique user_id constraint rejects a concurrent accept of another invitation
+		_, err := tx.queries.CreateOrganizationMember(ctx, database.CreateOrganizationMemberParams{
+			OrganizationID: invitation.OrganizationID,
+			UserID:         userID,
+			Role:           invitation.Role,
+		})
+		if isUniqueViolation(err) {
+			return ErrAlreadyInOrganization
+		}
+		if err != nil {
+			return fmt.Errorf("failed to add organization member: %w", err)
+		}
+
+		deleted, err := tx.queries.DeleteInvitationByEmail(ctx, database.DeleteInvitationByEmailParams{
+			ID:    invitationID,
+			Email: email,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to delete invitation: %w", err)
+		}
+		if deleted == 0 {
+			return ErrInvitationNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	org, err := s.queries.GetOrganization(ctx, invitation.OrganizationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+
+	return s.buildView(ctx, org, invitation.Role)
+}
+
+// DeclineInvitation discards an invitation addressed to the user
+func (s *OrganizationService) DeclineInvitation(ctx context.Context, userID, invitationID uuid.UUID) error {
+	email, err := s.verifiedEmail(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := s.queries.DeleteInvitationByEmail(ctx, database.DeleteInvitationByEmailParams{
+		ID:    invitationID,
+		Email: email,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to decline invitation: %w", err)
+	}
+	if deleted == 0 {
+		return ErrInvitationNotFound
+	}
+	return nil
+}
+
+// UpdateMemberRole changes a member's role. Only owners can change roles.
+func (s *OrganizationService) UpdateMemberRole(ctx context.Context, userID, memberID uuid.UUID, role string) error {
+	return s.inTx(ctx, func(tx *OrganizationService) error {
+		member, err := tx.lockedMembership(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if member.Role != OrgRoleOwner {
+			return ErrOrganizationForbidden
+		}
+
+		target, err := tx.memberOf(ctx, member.OrganizationID, memberID)
+		if err != nil {
+			return err
+		}
+		if target.Role == OrgRoleOwner && role != OrgRoleOwner {
+			if err := tx.ensureAnotherOwner(ctx, member.OrganizationID); err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.queries.UpdateOrganizationMemberRole(ctx, database.UpdateOrganizationMemberRoleParams{
+			OrganizationID: member.OrganizationID,
+			UserID:         memberID,
+			Role:           role,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to update member role: %w", err)
+		}
+		return nil
+	})
+}
+
+// RemoveMember removes a member from the caller's organization. Any member can
+// remove themselves; owners and admins can remove others, but only owners can remove an owner.
+func (s *OrganizationService) RemoveMember(ctx context.Context, userID, memberID uuid.UUID) error {
+	return s.inTx(ctx, func(tx *OrganizationService) error {
+		member, err := tx.lockedMembership(ctx, userID)
+		if err != nil {
+			return err
+		}
+
+		target, err := tx.memberOf(ctx, member.OrganizationID, memberID)
+		if err != nil {
+			return err
+		}
+
+		if memberID != userID {
+			if !canManageMembers(member.Role) || (target.Role == OrgRoleOwner && member.Role != OrgRoleOwner) {
+				return ErrOrganizationForbidden
+			}
+		}
+		if target.Role == OrgRoleOwner {
+			if err := tx.ensureAnotherOwner(ctx, member.OrganizationID); err != nil {
+				return err
+			}
+		}
+
+		err = tx.queries.DeleteOrganizationMember(ctx, database.DeleteOrganizationMemberParams{
+			OrganizationID: member.OrganizationID,
+			UserID:         memberID,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to remove organization member: %w", err)
+		}
+		return nil
+	})
+}
+
+// GetUnderstanding returns the organization's business understanding
+func (s *OrganizationService) GetUnderstanding(ctx context.Context, userID uuid.UUID) (*OrganizationUnderstandingView, error) {
+	member, err := s.membership(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	understanding, err := s.queries.GetOrganizationUnderstanding(ctx, member.OrganizationID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUnderstandingNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization understanding: %w", err)
+	}
+
+	return orgUnderstandingToView(understanding), nil
+}
+
+// UpdateUnderstanding applies an edit to the organization's business understanding.
+// Only fields with organization scope can be set. Only owners and admins can edit.
+func (s *OrganizationService) UpdateUnderstanding(ctx context.Context, userID uuid.UUID, patch *BusinessContext) (*OrganizationUnderstandingView, error) {
+	if err := s.schema.Validate(patch, FieldScopeOrganization); err != nil {
+		return nil, err
+	}
+
+	member, err := s.membership(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !canManageMembers(member.Role) {
+		return nil, ErrOrganizationForbidden
+	}
+
+	existing, err := s.queries.GetOrganizationUnderstanding(ctx, member.OrganizationID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get organization understanding: %w", err)
+	}
+
+	after := applyUnderstandingPatch(orgUnderstandingToBusinessContext(existing), patch)
+
+	understanding, err := s.queries.ReplaceOrganizationUnderstanding(ctx, businessContextToOrgReplaceParams(member.OrganizationID, after))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update organization understanding: %w", err)
+	}
+
+	return orgUnderstandingToView(understanding), nil
+}
+
+// ContextForUser returns the company-level business context of the user's
+// organization, or nil if the user has no organization or it has no understanding yet
+func (s *OrganizationService) ContextForUser(ctx context.Context, userID uuid.UUID) (*BusinessContext, error) {
+	member, err := s.membership(ctx, userID)
+	if errors.Is(err, ErrNotInOrganization) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	understanding, err := s.queries.GetOrganizationUnderstanding(ctx, member.OrganizationID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organization understanding: %w", err)
+	}
+
+	return orgUnderstandingToBusinessContext(understanding), nil
+}
+
+func (s *OrganizationService) membership(ctx context.Context, userID uuid.UUID) (database.OrganizationMember, error) {
+	member, err := s.queries.GetOrganizationMemberByUserID(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return member, ErrNotInOrganization
+	}
+	if err != nil {
+		return member, fmt.Errorf("failed to get organization membership: %w", err)
+	}
+	return member, nil
+}
+
+// lockedMembership returns the user's membership after locking every member row of
+// their organization. Use it inside inTx before checks on other members' roles, so
+// two owners acting at once can't both pass the last-owner check.
+func (s *OrganizationService) lockedMembership(ctx context.Context, userID uuid.UUID) (database.OrganizationMember, error) {
+	member, err := s.membership(ctx, userID)
+	if err != nil {
+		return member, err
+	}
+	if err := s.queries.LockOrganizationMembers(ctx, member.OrganizationID); err != nil {
+		return member, fmt.Errorf("failed to lock organization members: %w", err)
+	}
+
+	// Read again, since the membership may have changed while waiting for the lock
+	locked, err := s.membership(ctx, userID)
+	if err == nil && locked.OrganizationID != member.OrganizationID {
+		return locked, ErrNotInOrganization
+	}
+	return locked, err
+}
+
+// inTx runs fn with a copy of the service whose queries share one transaction
+func (s *OrganizationService) inTx(ctx context.Context, fn func(tx *OrganizationService) error) error {
+	return s.queries.InTx(ctx, func(q *database.Queries) error {
+		tx := *s
+		tx.queries = q
+		return fn(&tx)
+	})
+}
+
+// verifiedEmail returns the user's email address for matching invitations,
+// failing unless they have verified it, so nobody can claim an invitation by
+// signing up with someone else's address
+func (s *OrganizationService) verifiedEmail(ctx context.Context, userID uuid.UUID) (string, error) {
+	user, err := s.queries.GetUserByID(ctx, userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.EmailVerified == nil || !*user.EmailVerified {
+		return "", ErrInvitationEmailUnverified
+	}
+	return normalizeInvitationEmail(user.Email), nil
+}
+
+// memberOf returns memberID's membership, requiring it to be in orgID
+func (s *OrganizationService) memberOf(ctx context.Context, orgID, memberID uuid.UUID) (database.OrganizationMember, error) {
+	target, err := s.membership(ctx, memberID)
+	if errors.Is(err, ErrNotInOrganization) || (err == nil && target.OrganizationID != orgID) {
+		return target, ErrOrganizationMemberNotFound
+	}
+	return target, err
+}
+
+// ensureAnotherOwner fails if removing or demoting one owner would leave the organization without any
+func (s *OrganizationService) ensureAnotherOwner(ctx context.Context, orgID uuid.UUID) error {
+	owners, err := s.queries.CountOrganizationOwners(ctx, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to count owners: %w", err)
+	}
+	if owners <= 1 {
+		return ErrLastOrganizationOwner
+	}
+	return nil
+}
+
+func (s *OrganizationService) buildView(ctx context.Context, org database.Organization, role string) (*OrganizationView, error) {
+	rows, err := s.queries.ListOrganizationMembers(ctx, org.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list organization members: %w", err)
+	}
+
+	members := make([]OrganizationMemberView, len(rows))
+	for i, row := range rows {
+		members[i] = OrganizationMemberView{
+			UserID:   row.UserID,
+			Email:    row.Email,
+			Name:     row.Name,
+			Role:     row.Role,
+			JoinedAt: row.CreatedAt,
+		}
+	}
+
+	return &OrganizationView{
+		ID:        org.ID,
+		Name:      org.Name,
+		Role:      role,
+		Members:   members,
+		CreatedAt: org.CreatedAt,
+	}, nil
+}
+
+func normalizeInvitationEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func invitationToView(invitation database.OrganizationInvitation, orgName string) *OrganizationInvitationView {
+	return &OrganizationInvitationView{
+		ID:               invitation.ID,
+		OrganizationID:   invitation.OrganizationID,
+		OrganizationName: orgName,
+		Email:            invitation.Email,
+		Role:             invitation.Role,
+		ExpiresAt:        invitation.ExpiresAt,
+		CreatedAt:        invitation.CreatedAt,
+	}
+}
+
+func canManageMembers(role string) bool {
+	return role == OrgRoleOwner || role == OrgRoleAdmin
+}
+
+func orgUnderstandingToView(u database.OrganizationUnderstanding) *OrganizationUnderstandingView {
+	return &OrganizationUnderstandingView{
+		Context:   orgUnderstandingToBusinessContext(u),
+		CreatedAt: u.CreatedAt,
+		UpdatedAt: u.UpdatedAt,
+	}
+}
+
+// orgUnderstandingToBusinessContext converts an organization understanding row
+func orgUnderstandingToBusinessContext(u database.OrganizationUnderstanding) *BusinessContext {
+	return snapshotToBusinessContext(u.Data)
+}
+
+func businessContextToOrgReplaceParams(orgID uuid.UUID, bc *BusinessContext) database.ReplaceOrganizationUnderstandingParams {
+	data, _ := json.Marshal(bc)
+	return database.ReplaceOrganizationUnderstandingParams{
+		OrganizationID: orgID,
+		Data:           data,
+	}
+}
+
+// notesField is combined rather than overridden when merging contexts
+const notesField = "additional_notes"
+
+// mergeBusinessContexts layers a user's personal understanding on top of their
+// organization's. Personal values win for text fields, except notes which are
+// concatenated; lists are combined.
+func mergeBusinessContexts(org, personal *BusinessContext) *BusinessContext {
+	if org == nil {
+		return personal
+	}
+	if personal == nil {
+		return org
+	}
+
+	merged := org.Clone()
+	for name, value := range personal.Strings {
+		if name == notesField && value != "" && merged.Get(name) != "" {
+			value = merged.Get(name) + "\n" + value
+		}
+		merged.Set(name, value)
+	}
+	for name, items := range personal.Lists {
+		merged.SetList(name, mergeStringArrays(merged.List(name), items))
+	}
+	return merged
+}
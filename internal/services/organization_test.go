@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/database"
+	"github.com/google/uuid"
+)
+
+func TestInviteDoesNotLookUpInvitee(t *testing.T) {
+	orgID := uuid.New()
+	db := &fakeQueryDB{rows: map[string][]any{
+		"GetOrganizationMemberByUserID": {uuid.New(), orgID, uuid.New(), OrgRoleAdmin},
+		"UpsertOrganizationInvitation":  {uuid.New(), orgID, "bob@example.com", OrgRoleMember},
+	}}
+	s := &OrganizationService{queries: database.New(db)}
+
+	invitation, err := s.Invite(context.Background(), uuid.New(), " Bob@Example.com ", OrgRoleMember)
+	if err != nil {
+		t.Fatalf("Invite() error = %v", err)
+	}
+	if invitation.Email != "bob@example.com" || invitation.OrganizationID != orgID {
+		t.Errorf("invitation = %+v, want one for bob@example.com in the caller's organization", invitation)
+	}
+	// The response must not depend on whether the address has an account
+	if slices.Contains(db.ran, "GetUserByEmail") || slices.Contains(db.ran, "CreateOrganizationMember") {
+		t.Errorf("queries = %v, want no user lookup and no member added", db.ran)
+	}
+	if got := db.args["UpsertOrganizationInvitation"][1]; got != "bob@example.com" {
+		t.Errorf("invited email = %v, want normalized address", got)
+	}
+}
+
+func TestInviteOwnerRequiresOwner(t *testing.T) {
+	db := &fakeQueryDB{rows: map[string][]any{
+		"GetOrganizationMemberByUserID": {uuid.New(), uuid.New(), uuid.New(), OrgRoleAdmin},
+	}}
+	s := &OrganizationService{queries: database.New(db)}
+
+	_, err := s.Invite(context.Background(), uuid.New(), "bob@example.com", OrgRoleOwner)
+	if !errors.Is(err, ErrOrganizationForbidden) {
+		t.Errorf("err = %v, want %v", err, ErrOrganizationForbidden)
+	}
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	verified, unverified := true, false
+	userRow := func(emailVerified *bool) []any {
+		var none *string
+		return []any{uuid.New(), "Bob@Example.com", none, "Bob", none, none, none, emailVerified}
+	}
+	invitationRow := []any{uuid.New(), uuid.New(), "bob@example.com", OrgRoleMember, (*uuid.UUID)(nil), time.Now().Add(time.Hour)}
+
+	t.Run("unverified email", func(t *testing.T) {
+		db := &fakeQueryDB{rows: map[string][]any{
+			"GetUserByID":          userRow(&unverified),
+			"GetInvitationByEmail": invitationRow,
+		}}
+		s := &OrganizationService{queries: database.New(db)}
+
+		_, err := s.AcceptInvitation(context.Background(), uuid.New(), uuid.New())
+		if !errors.Is(err, ErrInvitationEmailUnverified) {
+			t.Errorf("err = %v, want %v", err, ErrInvitationEmailUnverified)
+		}
+		if slices.Contains(db.ran, "GetInvitationByEmail") {
+			t.Errorf("queries = %v, want the invitation not looked up", db.ran)
+		}
+	})
+
+	t.Run("not invited", func(t *testing.T) {
+		db := &fakeQueryDB{rows: map[string][]any{
+			"GetUserByID": userRow(&verified),
+		}}
+		s := &OrganizationService{queries: database.New(db)}
+
+		_, err := s.AcceptInvitation(context.Background(), uuid.New(), uuid.New())
+		if !errors.Is(err, ErrInvitationNotFound) {
+			t.Errorf("err = %v, want %v", err, ErrInvitationNotFound)
+		}
+		if got := db.args["GetInvitationByEmail"][1]; got != "bob@example.com" {
+			t.Errorf("matched email = %v, want normalized address", got)
+		}
+	})
+
+	t.Run("already in an organization", func(t *testing.T) {
+		db := &fakeQueryDB{rows: map[string][]any{
+			"GetUserByID":                   userRow(&verified),
+			"GetInvitationByEmail":          invitationRow,
+			"GetOrganizationMemberByUserID": {uuid.New(), uuid.New(), uuid.New(), OrgRoleMember},
+		}}
+		s := &OrganizationService{queries: database.New(db)}
+
+		_, err := s.AcceptInvitation(context.Background(), uuid.New(), uuid.New())
+		if !errors.Is(err, ErrAlreadyInOrganization) {
+			t.Errorf("err = %v, want %v", err, ErrAlreadyInOrganization)
+		}
+		if slices.Contains(db.ran, "CreateOrganizationMember") {
+			t.Errorf("queries = %v, want no member added", db.ran)
+		}
+	})
+}
+
+func TestMergeBusinessContexts(t *testing.T) {
+	org := testContext(
+		map[string]string{"business_name": "Acme", "industry": "retail", "additional_notes": "Family business"},
+		map[string][]string{"pain_points": {"returns"}, "current_software": {"Shopify"}},
+	)
+	personal := testContext(
+		map[string]string{"user_name": "Alice", "industry": "e-commerce", "additional_notes": "Works part time"},
+		map[string][]string{"pain_points": {"returns", "manual invoicing"}, "daily_activities": {"answer support tickets"}},
+	)
+
+	t.Run("personal layered over organization", func(t *testing.T) {
+		got := mergeBusinessContexts(org, personal)
+		want := testContext(
+			map[string]string{
+				"user_name":        "Alice",
+				"business_name":    "Acme",
+				"industry":         "e-commerce",
+				"additional_notes": "Family business\nWorks part time",
+			},
+			map[string][]string{
+				"pain_points":      {"returns", "manual invoicing"},
+				"daily_activities": {"answer support tickets"},
+				"current_software": {"Shopify"},
+			},
+		)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("mergeBusinessContexts() = %+v, want %+v", got, want)
+		}
+	})
+
+	t.Run("missing organization", func(t *testing.T) {
+		if got := mergeBusinessContexts(nil, personal); got != personal {
+			t.Errorf("mergeBusinessContexts(nil, personal) = %+v, want personal", got)
+		}
+	})
+
+	t.Run("missing personal", func(t *testing.T) {
+		if got := mergeBusinessContexts(org, nil); got != org {
+			t.Errorf("mergeBusinessContexts(org, nil) = %+v, want org", got)
+		}
+	})
+
+	t.Run("both missing", func(t *testing.T) {
+		if got := mergeBusinessContexts(nil, nil); got != nil {
+			t.Errorf("mergeBusinessContexts(nil, nil) = %+v, want nil", got)
+		}
+	})
+}
+
+func TestRemoveMemberLocksBeforeCountingOwners(t *testing.T) {
+	userID, orgID := uuid.New(), uuid.New()
+	tests := []struct {
+		name   string
+		owners int64
+		want   error
+	}{
+		{"another owner remains", 2, nil},
+		{"last owner", 1, ErrLastOrganizationOwner},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := &fakeQueryDB{rows: map[string][]any{
+				"GetOrganizationMemberByUserID": {uuid.New(), orgID, userID, OrgRoleOwner},
+				"CountOrganizationOwners":       {tt.owners},
+			}}
+			s := &OrganizationService{queries: database.New(db)}
+
+			err := s.RemoveMember(context.Background(), userID, userID)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+
+			lock, count := slices.Index(db.ran, "LockOrganizationMembers"), slices.Index(db.ran, "CountOrganizationOwners")
+			if lock < 0 || lock > count {
+				t.Errorf("queries = %v, want the members locked before counting owners", db.ran)
+			}
+			if db.committed != (tt.want == nil) {
+				t.Errorf("committed = %v, want %v", db.committed, tt.want == nil)
+			}
+		})
+	}
+}
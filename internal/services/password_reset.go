@@ -0,0 +1,174 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/database"
+	"github.com/agpt-go/chatbot-api/internal/logging"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var (
+	ErrIncorrectPassword = errors.New("incorrect password")
+	ErrNoPassword        = errors.New("account has no password")
+)
+
+const (
+	passwordResetSentCachePrefix = "password_reset_sent:"
+	passwordResetResendInterval  = time.Minute
+	passwordResetSendTimeout     = time.Minute
+	defaultPasswordResetExpiry   = time.Hour
+)
+
+// RequestPasswordReset emails a password reset link to the account with the
+// given address, replacing any earlier link. It reports success whether or
+// not the account exists, so callers can't use it to discover accounts: the
+// email is sent in the background and failures to send it are only logged.
+func (s *AuthService) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.queries.GetUserByEmail(ctx, email)
+	if err != nil {
+		return nil
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), passwordResetSendTimeout)
+		defer cancel()
+		if err := s.sendPasswordReset(ctx, user); err != nil {
+			logging.Error("failed to send password reset email", err, "userID", user.ID.String())
+		}
+	}()
+	return nil
+}
+
+func (s *AuthService) sendPasswordReset(ctx context.Context, user database.User) error {
+	if s.mailer == nil {
+		return ErrMailerNotConfigured
+	}
+
+	// One email a minute per account, so the endpoint can't flood an inbox
+	cacheKey := passwordResetSentCachePrefix + user.ID.String()
+	if _, err := s.queries.GetCache(ctx, cacheKey); err == nil {
+		return nil
+	}
+
+	tokenBytes := make([]byte, 32)
+	if _, err := rand.Read(tokenBytes); err != nil {
+		return fmt.Errorf("failed to generate reset token: %w", err)
+	}
+	token := hex.EncodeToString(tokenBytes)
+
+	expiresIn := s.config.Auth.PasswordResetExpiresIn
+	if expiresIn <= 0 {
+		expiresIn = defaultPasswordResetExpiry
+	}
+
+	if err := s.queries.DeleteUserPasswordResetTokens(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to replace reset tokens: %w", err)
+	}
+	err := s.queries.CreatePasswordResetToken(ctx, database.CreatePasswordResetTokenParams{
+		UserID:    user.ID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(expiresIn),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store reset token: %w", err)
+	}
+
+	err = s.mailer.Send(ctx, Email{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Text: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password for your account. To choose a new password, open this link:\n\n%s\n\nThe link expires in %s and works once. If you didn't ask for this, you can ignore this email; your password hasn't changed.\n",
+			user.Name, withQuery(s.config.Auth.PasswordResetURL, "token", token), formatDuration(expiresIn)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send password reset email: %w", err)
+	}
+
+	err = s.queries.SetCache(ctx, database.SetCacheParams{
+		Key:       cacheKey,
+		Value:     []byte("sent"),
+		ExpiresAt: pgtype.Timestamptz{Time: time.Now().Add(passwordResetResendInterval), Valid: true},
+	})
+	if err != nil {
+		logging.Warn("failed to record password reset email", "error", err, "userID", user.ID.String())
+	}
+	return nil
+}
+
+// ResetPassword sets a new password using a token from a reset link. The token
+// can only be used once. Every refresh token of the user is revoked, signing
+// out all their devices.
+func (s *AuthService) ResetPassword(ctx context.Context, token, newPassword string) error {
+	stored, err := s.queries.ConsumePasswordResetToken(ctx, hashToken(token))
+	if err != nil {
+		return ErrInvalidToken
+	}
+	if stored.ExpiresAt.Before(time.Now()) {
+		return ErrTokenExpired
+	}
+
+	user, err := s.queries.GetUserByID(ctx, stored.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if err := s.setPassword(ctx, user.ID, newPassword); err != nil {
+		return err
+	}
+	if err := s.queries.DeleteUserPasswordResetTokens(ctx, user.ID); err != nil {
+		logging.Warn("failed to delete password reset tokens", "error", err, "userID", user.ID.String())
+	}
+
+	// Opening the link proved the user controls the address
+	if user.EmailVerified == nil || !*user.EmailVerified {
+		_, err := s.queries.MarkUserEmailVerified(ctx, database.MarkUserEmailVerifiedParams{ID: user.ID, Email: user.Email})
+		if err != nil {
+			logging.Warn("failed to verify email after password reset", "error", err, "userID", user.ID.String())
+		}
+	}
+	return nil
+}
+
+// ChangePassword replaces the password of a signed-in user after checking the
+// current one. Every refresh token of the user is revoked; the returned tokens
+// keep the current device signed in.
+func (s *AuthService) ChangePassword(ctx context.Context, userID uuid.UUID, currentPassword, newPassword string) (*TokenPair, error) {
+	user, err := s.queries.GetUserByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.PasswordHash == nil {
+		return nil, ErrNoPassword
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(*user.PasswordHash), []byte(currentPassword)); err != nil {
+		return nil, ErrIncorrectPassword
+	}
+
+	if err := s.setPassword(ctx, user.ID, newPassword); err != nil {
+		return nil, err
+	}
+	return s.generateTokenPair(ctx, &user)
+}
+
+// setPassword stores a new password hash and signs the user out of every device
+func (s *AuthService) setPassword(ctx context.Context, userID uuid.UUID, password string) error {
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	passwordHash := string(hashedPassword)
+	if err := s.queries.UpdateUserPassword(ctx, database.UpdateUserPasswordParams{
+		ID:           userID,
+		PasswordHash: &passwordHash,
+	}); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	return s.LogoutAll(ctx, userID)
+}
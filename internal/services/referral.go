@@ -134,8 +134,9 @@ func (s *ReferralService) GetOrCreateReferralCode(ctx context.Context, userID uu
 	var industry, businessSize *string
 	bu, err := s.queries.GetBusinessUnderstanding(ctx, userID)
 	if err == nil {
-		industry = bu.Industry
-		businessSize = bu.BusinessSize
+		bc := understandingToBusinessContext(bu)
+		industry = stringPtrOrNil(bc.Get("industry"))
+		businessSize = stringPtrOrNil(bc.Get("business_size"))
 	}
 
 	// Create the code
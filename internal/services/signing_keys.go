@@ -0,0 +1,328 @@
+package services
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.yaml.in/yaml/v3"
+)
+
+// Algorithms signing keys can use
+const (
+	SigningAlgorithmRS256 = "RS256"
+	SigningAlgorithmEdDSA = "EdDSA"
+)
+
+// minRSAKeyBits is the smallest RSA signing key accepted
+const minRSAKeyBits = 2048
+
+// SigningKey is an asymmetric key that signs access tokens from ActiveFrom
+// until the next key becomes active
+type SigningKey struct {
+	ID             string    `yaml:"kid"`
+	Algorithm      string    `yaml:"algorithm"`
+	ActiveFrom     time.Time `yaml:"active_from"`
+	PrivateKeyFile string    `yaml:"private_key_file"` // PEM file, relative to the keys file
+	PrivateKey     string    `yaml:"private_key"`      // Inline PEM, instead of PrivateKeyFile
+
+	method    jwt.SigningMethod
+	signer    crypto.Signer
+	publicKey crypto.PublicKey
+}
+
+// SigningKeys rotate the key access tokens are signed with. The newest key
+// whose ActiveFrom has passed signs; keys are published in the JWKS as soon as
+// they are configured, so verifiers can fetch one before it is used. A key
+// that was replaced keeps verifying tokens for the grace period, which should
+// be at least the access token lifetime.
+//
+// Before the first key becomes active, tokens are signed with the JWT secret
+// (HS256); those tokens too are accepted until the grace period has passed.
+// Every key needs an ActiveFrom, so that period ends at a fixed time however
+// often the keys are reloaded.
+type SigningKeys struct {
+	Keys  []*SigningKey `yaml:"keys"`
+	grace time.Duration
+}
+
+// LoadSigningKeys reads signing keys from a YAML or JSON file. An empty path
+// returns nil, which signs every token with the JWT secret.
+func LoadSigningKeys(path string, grace time.Duration) (*SigningKeys, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing keys: %w", err)
+	}
+
+	keys, err := ParseSigningKeys(data, filepath.Dir(path), grace)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing keys %s: %w", path, err)
+	}
+	return keys, nil
+}
+
+// ParseSigningKeys parses and validates signing keys. Key files are resolved
+// relative to dir. JSON is accepted as it is valid YAML.
+func ParseSigningKeys(data []byte, dir string, grace time.Duration) (*SigningKeys, error) {
+	var keys SigningKeys
+	if err := yaml.Unmarshal(data, &keys); err != nil {
+		return nil, err
+	}
+	if len(keys.Keys) == 0 {
+		return nil, fmt.Errorf("no keys")
+	}
+
+	ids := make(map[string]bool)
+	activeFrom := make(map[time.Time]string)
+	for _, key := range keys.Keys {
+		if key == nil || key.ID == "" {
+			return nil, fmt.Errorf("every key needs a kid")
+		}
+		if ids[key.ID] {
+			return nil, fmt.Errorf("duplicate kid %q", key.ID)
+		}
+		ids[key.ID] = true
+		if key.ActiveFrom.IsZero() {
+			return nil, fmt.Errorf("key %q needs an active_from", key.ID)
+		}
+		if other, ok := activeFrom[key.ActiveFrom]; ok {
+			return nil, fmt.Errorf("keys %q and %q become active at the same time", other, key.ID)
+		}
+		activeFrom[key.ActiveFrom] = key.ID
+
+		if err := key.load(dir); err != nil {
+			return nil, fmt.Errorf("key %q: %w", key.ID, err)
+		}
+	}
+
+	sort.Slice(keys.Keys, func(i, j int) bool {
+		return keys.Keys[i].ActiveFrom.Before(keys.Keys[j].ActiveFrom)
+	})
+	keys.grace = grace
+	return &keys, nil
+}
+
+// load parses the private key of k
+func (k *SigningKey) load(dir string) error {
+	pemData := []byte(k.PrivateKey)
+	switch {
+	case k.PrivateKey != "" && k.PrivateKeyFile != "":
+		return fmt.Errorf("set private_key or private_key_file, not both")
+	case k.PrivateKeyFile != "":
+		path := k.PrivateKeyFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read private key: %w", err)
+		}
+		pemData = data
+	case k.PrivateKey == "":
+		return fmt.Errorf("private_key or private_key_file is required")
+	}
+
+	switch k.Algorithm {
+	case SigningAlgorithmRS256:
+		key, err := jwt.ParseRSAPrivateKeyFromPEM(pemData)
+		if err != nil {
+			return fmt.Errorf("invalid RSA private key: %w", err)
+		}
+		if key.N.BitLen() < minRSAKeyBits {
+			return fmt.Errorf("RSA keys need at least %d bits", minRSAKeyBits)
+		}
+		k.method, k.signer, k.publicKey = jwt.SigningMethodRS256, key, &key.PublicKey
+	case SigningAlgorithmEdDSA:
+		parsed, err := jwt.ParseEdPrivateKeyFromPEM(pemData)
+		if err != nil {
+			return fmt.Errorf("invalid Ed25519 private key: %w", err)
+		}
+		key, ok := parsed.(ed25519.PrivateKey)
+		if !ok {
+			return fmt.Errorf("invalid Ed25519 private key")
+		}
+		k.method, k.signer, k.publicKey = jwt.SigningMethodEdDSA, key, key.Public()
+	default:
+		return fmt.Errorf("algorithm must be %s or %s", SigningAlgorithmRS256, SigningAlgorithmEdDSA)
+	}
+	return nil
+}
+
+// active returns the key that signs tokens at now, or nil to sign with the JWT secret
+func (s *SigningKeys) active(now time.Time) *SigningKey {
+	if s == nil {
+		return nil
+	}
+	var active *SigningKey
+	for _, key := range s.Keys {
+		if key.ActiveFrom.After(now) {
+			break
+		}
+		active = key
+	}
+	return active
+}
+
+// verifying returns the keys whose tokens are accepted at now: the active key,
+// keys not active yet, and keys replaced less than the grace period ago
+func (s *SigningKeys) verifying(now time.Time) []*SigningKey {
+	if s == nil {
+		return nil
+	}
+	var keys []*SigningKey
+	for i, key := range s.Keys {
+		if i+1 < len(s.Keys) && !now.Before(s.Keys[i+1].ActiveFrom.Add(s.grace)) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// verificationKey returns the key with the given kid if it is accepted at now
+func (s *SigningKeys) verificationKey(kid string, now time.Time) *SigningKey {
+	for _, key := range s.verifying(now) {
+		if key.ID == kid {
+			return key
+		}
+	}
+	return nil
+}
+
+// acceptsSecret reports whether tokens signed with the JWT secret are accepted at now
+func (s *SigningKeys) acceptsSecret(now time.Time) bool {
+	if s == nil {
+		return true
+	}
+	return now.Before(s.Keys[0].ActiveFrom.Add(s.grace))
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	N         string `json:"n,omitempty"`   // RSA modulus
+	E         string `json:"e,omitempty"`   // RSA exponent
+	Curve     string `json:"crv,omitempty"` // EC or OKP curve
+	X         string `json:"x,omitempty"`   // OKP public key, or EC x coordinate
+	Y         string `json:"y,omitempty"`   // EC y coordinate
+}
+
+// PublicKey decodes the public key of an RSA, EC or Ed25519 JWK
+func (k JWK) PublicKey() (crypto.PublicKey, error) {
+	decode := func(name, value string) ([]byte, error) {
+		b, err := base64.RawURLEncoding.DecodeString(value)
+		if err != nil || len(b) == 0 {
+			return nil, fmt.Errorf("invalid %s", name)
+		}
+		return b, nil
+	}
+
+	switch k.KeyType {
+	case "RSA":
+		n, err := decode("n", k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode("e", k.E)
+		if err != nil {
+			return nil, err
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > math.MaxInt32 {
+			return nil, fmt.Errorf("invalid e")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		var checker ecdh.Curve
+		switch k.Curve {
+		case "P-256":
+			curve, checker = elliptic.P256(), ecdh.P256()
+		case "P-384":
+			curve, checker = elliptic.P384(), ecdh.P384()
+		case "P-521":
+			curve, checker = elliptic.P521(), ecdh.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := decode("x", k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode("y", k.Y)
+		if err != nil {
+			return nil, err
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) > size || len(y) > size {
+			return nil, fmt.Errorf("invalid point")
+		}
+		// ecdh rejects points that are not on the curve
+		point := make([]byte, 1+2*size)
+		point[0] = 4
+		copy(point[1+size-len(x):1+size], x)
+		copy(point[1+2*size-len(y):], y)
+		if _, err := checker.NewPublicKey(point); err != nil {
+			return nil, fmt.Errorf("invalid point")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := decode("x", k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid x")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+	}
+}
+
+// JWKSet is a JSON Web Key Set
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys that verify tokens at now
+func (s *SigningKeys) JWKS(now time.Time) JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range s.verifying(now) {
+		jwk := JWK{Use: "sig", Algorithm: key.Algorithm, KeyID: key.ID}
+		switch pub := key.publicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
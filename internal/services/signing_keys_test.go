@@ -0,0 +1,238 @@
+package services
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+func testPrivateKeyPEM(t *testing.T, algorithm string) string {
+	t.Helper()
+	var key any
+	switch algorithm {
+	case SigningAlgorithmRS256:
+		rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key = rsaKey
+	case SigningAlgorithmEdDSA:
+		_, edKey, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		key = edKey
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+}
+
+// testSigningKeysYAML describes keys that become active at the given times
+func testSigningKeysYAML(t *testing.T, keys map[string]time.Time) []byte {
+	t.Helper()
+	var b strings.Builder
+	b.WriteString("keys:\n")
+	algorithms := []string{SigningAlgorithmRS256, SigningAlgorithmEdDSA}
+	i := 0
+	for kid, activeFrom := range keys {
+		algorithm := algorithms[i%2]
+		i++
+		pemData := strings.ReplaceAll(strings.TrimSpace(testPrivateKeyPEM(t, algorithm)), "\n", "\n      ")
+		fmt.Fprintf(&b, "  - kid: %s\n    algorithm: %s\n    active_from: %s\n    private_key: |\n      %s\n",
+			kid, algorithm, activeFrom.UTC().Format(time.RFC3339), pemData)
+	}
+	return []byte(b.String())
+}
+
+func TestParseSigningKeys(t *testing.T) {
+	pemData := testPrivateKeyPEM(t, SigningAlgorithmEdDSA)
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "ed.pem"), []byte(pemData), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := ParseSigningKeys([]byte("keys:\n  - kid: a\n    algorithm: EdDSA\n    active_from: 2026-01-01T00:00:00Z\n    private_key_file: ed.pem\n"), dir, time.Minute)
+	if err != nil {
+		t.Fatalf("ParseSigningKeys() error = %v", err)
+	}
+	if key := keys.active(time.Now()); key == nil || key.ID != "a" {
+		t.Errorf("active key = %v, want a", key)
+	}
+
+	invalid := map[string]string{
+		"no keys":             "keys: []",
+		"missing kid":         "keys:\n  - algorithm: EdDSA\n    private_key_file: ed.pem\n",
+		"unknown algorithm":   "keys:\n  - kid: a\n    algorithm: HS256\n    private_key_file: ed.pem\n",
+		"wrong key type":      "keys:\n  - kid: a\n    algorithm: RS256\n    private_key_file: ed.pem\n",
+		"missing key":         "keys:\n  - kid: a\n    algorithm: EdDSA\n",
+		"duplicate kid":       "keys:\n  - kid: a\n    algorithm: EdDSA\n    active_from: 2026-01-01T00:00:00Z\n    private_key_file: ed.pem\n  - kid: a\n    algorithm: EdDSA\n    active_from: 2030-01-01T00:00:00Z\n    private_key_file: ed.pem\n",
+		"same active_from":    "keys:\n  - kid: a\n    algorithm: EdDSA\n    active_from: 2030-01-01T00:00:00Z\n    private_key_file: ed.pem\n  - kid: b\n    algorithm: EdDSA\n    active_from: 2030-01-01T00:00:00Z\n    private_key_file: ed.pem\n",
+		"missing active_from": "keys:\n  - kid: a\n    algorithm: EdDSA\n    private_key_file: ed.pem\n",
+	}
+	for name, data := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseSigningKeys([]byte(data), dir, time.Minute); err == nil {
+				t.Error("ParseSigningKeys() should fail")
+			}
+		})
+	}
+}
+
+func TestSigningKeyRotation(t *testing.T) {
+	start := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	grace := 15 * time.Minute
+	keys, err := ParseSigningKeys(testSigningKeysYAML(t, map[string]time.Time{
+		"old": start,
+		"new": start.Add(24 * time.Hour),
+	}), "", grace)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kids := func(now time.Time) []string {
+		var ids []string
+		for _, key := range keys.JWKS(now).Keys {
+			ids = append(ids, key.KeyID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name   string
+		now    time.Time
+		active string // "" signs with the secret
+		jwks   []string
+		secret bool
+	}{
+		{"before the first key", start.Add(-time.Hour), "", []string{"old", "new"}, true},
+		{"first key active", start.Add(time.Hour), "old", []string{"old", "new"}, false},
+		{"rotated, in grace", start.Add(24*time.Hour + grace/2), "new", []string{"old", "new"}, false},
+		{"rotated, after grace", start.Add(24*time.Hour + grace), "new", []string{"new"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			active := ""
+			if key := keys.active(tt.now); key != nil {
+				active = key.ID
+			}
+			if active != tt.active {
+				t.Errorf("active = %q, want %q", active, tt.active)
+			}
+			if got := kids(tt.now); strings.Join(got, ",") != strings.Join(tt.jwks, ",") {
+				t.Errorf("JWKS kids = %v, want %v", got, tt.jwks)
+			}
+			if got := keys.acceptsSecret(tt.now); got != tt.secret {
+				t.Errorf("acceptsSecret = %v, want %v", got, tt.secret)
+			}
+		})
+	}
+}
+
+func TestAccessTokenSigningKeys(t *testing.T) {
+	now := time.Now()
+	grace := time.Hour
+	keys, err := ParseSigningKeys(testSigningKeysYAML(t, map[string]time.Time{
+		"retired":  now.Add(-3 * time.Hour),
+		"replaced": now.Add(-2 * time.Hour),
+		"current":  now.Add(-30 * time.Minute),
+	}), "", grace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc := NewAuthService(nil, createTestConfig())
+	legacy, err := svc.signAccessToken(testClaims(now), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc.SetSigningKeys(keys)
+
+	token, err := svc.signAccessToken(testClaims(now), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.ValidateAccessToken(token); err != nil {
+		t.Errorf("token signed with the current key: %v", err)
+	}
+
+	signWith := func(kid string) string {
+		t.Helper()
+		key := keys.verificationKey(kid, now.Add(-3*time.Hour))
+		signer := &AuthService{config: svc.config, signingKeys: &SigningKeys{Keys: []*SigningKey{key}}}
+		token, err := signer.signAccessToken(testClaims(now), now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+	// "replaced" was replaced 30 minutes ago, within the grace period
+	if _, err := svc.ValidateAccessToken(signWith("replaced")); err != nil {
+		t.Errorf("token signed with a key in its grace period: %v", err)
+	}
+	if _, err := svc.ValidateAccessToken(signWith("retired")); err != ErrInvalidToken {
+		t.Errorf("token signed with a retired key: error = %v, want %v", err, ErrInvalidToken)
+	}
+	if _, err := svc.ValidateAccessToken(legacy); err != ErrInvalidToken {
+		t.Errorf("token signed with the secret after the grace period: error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestJWKPublicKey(t *testing.T) {
+	edPub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encode := base64.RawURLEncoding.EncodeToString
+
+	ed, err := JWK{KeyType: "OKP", Curve: "Ed25519", X: encode(edPub)}.PublicKey()
+	if err != nil || !edPub.Equal(ed) {
+		t.Errorf("Ed25519 key = %v, %v", ed, err)
+	}
+	ec, err := JWK{KeyType: "EC", Curve: "P-256", X: encode(ecKey.X.Bytes()), Y: encode(ecKey.Y.Bytes())}.PublicKey()
+	if err != nil || !ecKey.PublicKey.Equal(ec) {
+		t.Errorf("EC key = %v, %v", ec, err)
+	}
+
+	invalid := map[string]JWK{
+		"EC point off the curve": {KeyType: "EC", Curve: "P-256", X: encode(ecKey.X.Bytes()), Y: encode(ecKey.X.Bytes())},
+		"unknown curve":          {KeyType: "EC", Curve: "secp256k1", X: encode(ecKey.X.Bytes()), Y: encode(ecKey.Y.Bytes())},
+		"short Ed25519 key":      {KeyType: "OKP", Curve: "Ed25519", X: encode(edPub[:16])},
+		"RSA without modulus":    {KeyType: "RSA", E: "AQAB"},
+		"symmetric key":          {KeyType: "oct"},
+	}
+	for name, jwk := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := jwk.PublicKey(); err == nil {
+				t.Error("PublicKey() should fail")
+			}
+		})
+	}
+}
+
+func testClaims(now time.Time) *Claims {
+	userID := uuid.New()
+	claims := &Claims{UserID: userID, Email: "user@example.com", SessionID: uuid.New()}
+	claims.Subject = userID.String()
+	claims.ExpiresAt = jwt.NewNumericDate(now.Add(15 * time.Minute))
+	return claims
+}
@@ -0,0 +1,190 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/database"
+	"github.com/agpt-go/chatbot-api/internal/logging"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var ErrTokenRevoked = errors.New("token revoked")
+
+// maxCachedRevocations bounds the users whose revocations are cached
+const maxCachedRevocations = 10000
+
+// revocationQueries are the queries access token revocation checks need
+type revocationQueries interface {
+	GetUserTokensValidAfter(ctx context.Context, id uuid.UUID) (pgtype.Timestamptz, error)
+	ListUserRevokedAccessTokens(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
+	ListUserRevokedSessions(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
+}
+
+// accessTokenRevocations caches the revoked access tokens of each user, so
+// checking a token costs no query while its user's entry is fresh. Revocations
+// made by this instance apply at once; those made by other instances apply
+// once the entry is older than ttl.
+type accessTokenRevocations struct {
+	queries revocationQueries
+	ttl     time.Duration
+
+	mu         sync.Mutex
+	users      map[uuid.UUID]*userRevocations
+	generation uint64 // Incremented by forget, so loads racing a revocation aren't cached
+}
+
+// userRevocations are the revoked access tokens of one user
+type userRevocations struct {
+	deleted    bool               // The user no longer exists
+	validAfter time.Time          // Tokens issued before are revoked; zero revokes none
+	revoked    map[uuid.UUID]bool // Revoked token IDs
+	sessions   map[uuid.UUID]bool // Revoked sessions (refresh token families)
+	loadedAt   time.Time
+}
+
+func newAccessTokenRevocations(queries revocationQueries, ttl time.Duration) *accessTokenRevocations {
+	return &accessTokenRevocations{
+		queries: queries,
+		ttl:     ttl,
+		users:   make(map[uuid.UUID]*userRevocations),
+	}
+}
+
+// check returns ErrTokenRevoked if the token was revoked
+func (r *accessTokenRevocations) check(ctx context.Context, claims *Claims) error {
+	entry, err := r.lookup(ctx, claims.UserID)
+	if err != nil {
+		return err
+	}
+
+	if entry.deleted {
+		return ErrTokenRevoked
+	}
+	if !entry.validAfter.IsZero() && (claims.IssuedAt == nil || claims.IssuedAt.Before(entry.validAfter)) {
+		return ErrTokenRevoked
+	}
+	if jti, err := uuid.Parse(claims.ID); err == nil && entry.revoked[jti] {
+		return ErrTokenRevoked
+	}
+	if entry.sessions[claims.SessionID] {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// lookup returns the user's revocations, loading them when the cached entry is stale
+func (r *accessTokenRevocations) lookup(ctx context.Context, userID uuid.UUID) (*userRevocations, error) {
+	now := time.Now()
+	r.mu.Lock()
+	entry, ok := r.users[userID]
+	generation := r.generation
+	r.mu.Unlock()
+	if ok && now.Sub(entry.loadedAt) < r.ttl {
+		return entry, nil
+	}
+
+	entry = &userRevocations{loadedAt: now}
+	validAfter, err := r.queries.GetUserTokensValidAfter(ctx, userID)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		entry.deleted = true
+	case err != nil:
+		return nil, fmt.Errorf("failed to check token revocation: %w", err)
+	default:
+		if validAfter.Valid {
+			entry.validAfter = validAfter.Time
+		}
+		jtis, err := r.queries.ListUserRevokedAccessTokens(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check token revocation: %w", err)
+		}
+		entry.revoked = make(map[uuid.UUID]bool, len(jtis))
+		for _, jti := range jtis {
+			entry.revoked[jti] = true
+		}
+		sessions, err := r.queries.ListUserRevokedSessions(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check token revocation: %w", err)
+		}
+		entry.sessions = make(map[uuid.UUID]bool, len(sessions))
+		for _, session := range sessions {
+			entry.sessions[session] = true
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.generation != generation {
+		return entry, nil
+	}
+	if len(r.users) >= maxCachedRevocations {
+		for id, cached := range r.users {
+			if now.Sub(cached.loadedAt) >= r.ttl {
+				delete(r.users, id)
+			}
+		}
+		if len(r.users) >= maxCachedRevocations {
+			r.users = make(map[uuid.UUID]*userRevocations)
+		}
+	}
+	r.users[userID] = entry
+	return entry, nil
+}
+
+// forget drops the cached revocations of a user after they changed
+func (r *accessTokenRevocations) forget(userID uuid.UUID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.users, userID)
+	r.generation++
+}
+
+// CheckAccessToken returns ErrTokenRevoked if a validated access token was
+// revoked since it was issued, or if its user no longer exists
+func (s *AuthService) CheckAccessToken(ctx context.Context, claims *Claims) error {
+	return s.revocations.check(ctx, claims)
+}
+
+// RevokeAccessToken revokes a single access token until it expires. Invalid
+// and expired tokens are ignored.
+func (s *AuthService) RevokeAccessToken(ctx context.Context, tokenString string) error {
+	claims, err := s.ValidateAccessToken(tokenString)
+	if err != nil {
+		return nil
+	}
+	jti, err := uuid.Parse(claims.ID)
+	if err != nil || claims.ExpiresAt == nil {
+		// Issued before tokens had IDs; only RevokeUserAccessTokens can revoke it
+		return nil
+	}
+
+	err = s.queries.RevokeAccessToken(ctx, database.RevokeAccessTokenParams{
+		Jti:       jti,
+		UserID:    claims.UserID,
+		ExpiresAt: claims.ExpiresAt.Time,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+	s.revocations.forget(claims.UserID)
+
+	if _, err := s.queries.DeleteExpiredRevokedAccessTokens(ctx); err != nil {
+		logging.Warn("failed to delete expired revoked access tokens", "error", err)
+	}
+	return nil
+}
+
+// RevokeUserAccessTokens revokes every access token issued to the user so far
+func (s *AuthService) RevokeUserAccessTokens(ctx context.Context, userID uuid.UUID) error {
+	if err := s.queries.RevokeUserAccessTokens(ctx, userID); err != nil {
+		return fmt.Errorf("failed to revoke access tokens: %w", err)
+	}
+	s.revocations.forget(userID)
+	return nil
+}
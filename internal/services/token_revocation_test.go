@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+type fakeRevocationQueries struct {
+	validAfter pgtype.Timestamptz
+	revoked    []uuid.UUID
+	sessions   []uuid.UUID
+	err        error
+	loads      int
+}
+
+func (f *fakeRevocationQueries) GetUserTokensValidAfter(ctx context.Context, id uuid.UUID) (pgtype.Timestamptz, error) {
+	f.loads++
+	return f.validAfter, f.err
+}
+
+func (f *fakeRevocationQueries) ListUserRevokedAccessTokens(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	return f.revoked, nil
+}
+
+func (f *fakeRevocationQueries) ListUserRevokedSessions(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	return f.sessions, nil
+}
+
+func TestAccessTokenRevocations(t *testing.T) {
+	issued := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	revokedJTI := uuid.New()
+	revokedSession := uuid.New()
+	claims := func(jti uuid.UUID) *Claims {
+		return &Claims{UserID: uuid.New(), SessionID: uuid.New(), RegisteredClaims: jwt.RegisteredClaims{
+			ID:       jti.String(),
+			IssuedAt: jwt.NewNumericDate(issued),
+		}}
+	}
+	sessionClaims := func(sessionID uuid.UUID) *Claims {
+		c := claims(uuid.New())
+		c.SessionID = sessionID
+		return c
+	}
+
+	tests := []struct {
+		name    string
+		queries *fakeRevocationQueries
+		claims  *Claims
+		want    error
+	}{
+		{"nothing revoked", &fakeRevocationQueries{}, claims(uuid.New()), nil},
+		{"issued before watermark", &fakeRevocationQueries{validAfter: pgtype.Timestamptz{Time: issued.Add(time.Second), Valid: true}}, claims(uuid.New()), ErrTokenRevoked},
+		{"issued at watermark", &fakeRevocationQueries{validAfter: pgtype.Timestamptz{Time: issued, Valid: true}}, claims(uuid.New()), nil},
+		{"revoked jti", &fakeRevocationQueries{revoked: []uuid.UUID{revokedJTI}}, claims(revokedJTI), ErrTokenRevoked},
+		{"other jti revoked", &fakeRevocationQueries{revoked: []uuid.UUID{revokedJTI}}, claims(uuid.New()), nil},
+		{"revoked session", &fakeRevocationQueries{sessions: []uuid.UUID{revokedSession}}, sessionClaims(revokedSession), ErrTokenRevoked},
+		{"other session revoked", &fakeRevocationQueries{sessions: []uuid.UUID{revokedSession}}, sessionClaims(uuid.New()), nil},
+		{"user deleted", &fakeRevocationQueries{err: pgx.ErrNoRows}, claims(uuid.New()), ErrTokenRevoked},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newAccessTokenRevocations(tt.queries, time.Minute)
+			if err := r.check(context.Background(), tt.claims); !errors.Is(err, tt.want) {
+				t.Errorf("check() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	t.Run("database error", func(t *testing.T) {
+		r := newAccessTokenRevocations(&fakeRevocationQueries{err: errors.New("connection refused")}, time.Minute)
+		err := r.check(context.Background(), claims(uuid.New()))
+		if err == nil || errors.Is(err, ErrTokenRevoked) {
+			t.Errorf("check() error = %v, want a lookup error", err)
+		}
+	})
+}
+
+func TestAccessTokenRevocationsCache(t *testing.T) {
+	queries := &fakeRevocationQueries{}
+	r := newAccessTokenRevocations(queries, time.Minute)
+	claims := &Claims{UserID: uuid.New(), RegisteredClaims: jwt.RegisteredClaims{
+		ID:       uuid.NewString(),
+		IssuedAt: jwt.NewNumericDate(time.Now()),
+	}}
+
+	for i := 0; i < 3; i++ {
+		if err := r.check(context.Background(), claims); err != nil {
+			t.Fatalf("check() error = %v", err)
+		}
+	}
+	if queries.loads != 1 {
+		t.Errorf("loads = %d, want 1 while the entry is fresh", queries.loads)
+	}
+
+	// A revocation on this instance applies at once
+	jti, _ := uuid.Parse(claims.ID)
+	queries.revoked = []uuid.UUID{jti}
+	r.forget(claims.UserID)
+	if err := r.check(context.Background(), claims); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("check() after forget error = %v, want %v", err, ErrTokenRevoked)
+	}
+
+	// Entries older than the TTL are reloaded
+	uncached := newAccessTokenRevocations(queries, 0)
+	queries.loads = 0
+	_ = uncached.check(context.Background(), claims)
+	_ = uncached.check(context.Background(), claims)
+	if queries.loads != 2 {
+		t.Errorf("loads = %d, want 2 without caching", queries.loads)
+	}
+}
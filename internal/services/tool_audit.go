@@ -0,0 +1,459 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/agpt-go/chatbot-api/internal/database"
+	"github.com/agpt-go/chatbot-api/internal/logging"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var ErrToolExecutionNotFound = errors.New("tool execution not found")
+
+// Defaults for ToolAuditConfig
+const (
+	DefaultToolAuditQueueSize = 1024
+	DefaultToolAuditRetention = 30 * 24 * time.Hour
+
+	DefaultToolExecutionPageSize = 50
+	MaxToolExecutionPageSize     = 200
+)
+
+// Limits on what is stored of a call's arguments
+const (
+	maxAuditStringLength    = 500
+	maxAuditArgumentsLength = 8 << 10
+	maxAuditErrorLength     = 2000
+	maxAuditNameLength      = 200 // Leaves room for the truncation note in a VARCHAR(255)
+	auditRedacted           = "[REDACTED]"
+)
+
+// auditSensitiveKey matches argument names whose values are never stored
+var auditSensitiveKey = regexp.MustCompile(`(?i)(password|passwd|secret|token|api[_-]?key|authorization|credential|private[_-]?key|cookie)`)
+
+// toolDurationBuckets are the upper bounds, in seconds, of the duration histogram
+var toolDurationBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// ToolAuditConfig configures the tool execution audit log
+type ToolAuditConfig struct {
+	Retention time.Duration // How long executions are kept; negative keeps them forever, 0 uses DefaultToolAuditRetention
+	QueueSize int           // Executions waiting to be written; 0 uses DefaultToolAuditQueueSize
+}
+
+// ToolExecutionFilter selects executions from the audit log. Unset fields match everything.
+type ToolExecutionFilter struct {
+	ToolName  string
+	UserID    *uuid.UUID
+	SessionID *uuid.UUID
+	Success   *bool
+	Before    *time.Time // Only executions created before this time, for paging
+	Limit     int        // 0 uses DefaultToolExecutionPageSize
+}
+
+// ToolExecutionView is an audit log entry
+type ToolExecutionView struct {
+	ID            uuid.UUID       `json:"id"`
+	UserID        uuid.UUID       `json:"user_id"`
+	SessionID     *uuid.UUID      `json:"session_id,omitempty"`
+	MessageID     *uuid.UUID      `json:"message_id,omitempty"`
+	ToolCallID    string          `json:"tool_call_id,omitempty"`
+	ToolName      string          `json:"tool_name"`
+	ArgumentsHash string          `json:"arguments_hash"`
+	Arguments     json.RawMessage `json:"arguments" swaggertype:"object"`
+	Outcome       string          `json:"outcome"`
+	Success       bool            `json:"success"`
+	Error         string          `json:"error,omitempty"`
+	DurationMs    int32           `json:"duration_ms"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// ToolMetrics is a snapshot of tool execution counts and durations since the server started
+type ToolMetrics struct {
+	Since        time.Time         `json:"since"`
+	Tools        []ToolMetricsItem `json:"tools"`
+	AuditDropped int64             `json:"audit_dropped"` // Executions not written to the audit log because the queue was full
+}
+
+// ToolMetricsItem holds the metrics of one tool
+type ToolMetricsItem struct {
+	ToolName      string           `json:"tool_name"`
+	Calls         int64            `json:"calls"`
+	Outcomes      map[string]int64 `json:"outcomes"`
+	AvgDurationMs float64          `json:"avg_duration_ms"`
+	MaxDurationMs float64          `json:"max_duration_ms"`
+}
+
+// toolStats accumulates the metrics of one tool
+type toolStats struct {
+	outcomes map[string]int64
+	calls    int64
+	total    time.Duration
+	max      time.Duration
+	buckets  []int64 // Calls at or under each of toolDurationBuckets
+}
+
+// ToolAuditService records every tool execution in the audit log and keeps
+// per-tool metrics. It observes the ToolRegistry; writes happen in the
+// background so tool calls never wait on the database.
+type ToolAuditService struct {
+	queries *database.Queries
+	cfg     ToolAuditConfig
+	queue   chan database.CreateToolExecutionParams
+
+	mu      sync.Mutex
+	since   time.Time
+	stats   map[string]*toolStats
+	dropped int64
+}
+
+// NewToolAuditService creates a new tool audit service
+func NewToolAuditService(queries *database.Queries, cfg ToolAuditConfig) *ToolAuditService {
+	if cfg.Retention == 0 {
+		cfg.Retention = DefaultToolAuditRetention
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = DefaultToolAuditQueueSize
+	}
+	return &ToolAuditService{
+		queries: queries,
+		cfg:     cfg,
+		queue:   make(chan database.CreateToolExecutionParams, cfg.QueueSize),
+		since:   time.Now(),
+		stats:   make(map[string]*toolStats),
+	}
+}
+
+// ObserveToolExecution implements ToolObserver. It updates the metrics and
+// queues the execution for the audit log, dropping it if the queue is full.
+func (s *ToolAuditService) ObserveToolExecution(ctx context.Context, execution ToolExecution) {
+	s.record(execution)
+
+	params := toolExecutionParams(execution)
+	select {
+	case s.queue <- params:
+	default:
+		s.mu.Lock()
+		s.dropped++
+		s.mu.Unlock()
+		logging.Warn("tool audit queue full, dropping execution", "tool", execution.ToolName, "userID", execution.UserID)
+	}
+}
+
+// Start writes queued executions to the database and prunes old ones in the
+// background. When ctx ends, executions still queued are written before the
+// worker stops.
+func (s *ToolAuditService) Start(ctx context.Context) {
+	go func() {
+		var prune <-chan time.Time
+		if s.cfg.Retention > 0 {
+			s.prune(ctx)
+			ticker := time.NewTicker(time.Hour)
+			defer ticker.Stop()
+			prune = ticker.C
+		}
+
+		for {
+			select {
+			case params := <-s.queue:
+				s.write(context.Background(), params)
+			case <-prune:
+				s.prune(ctx)
+			case <-ctx.Done():
+				for {
+					select {
+					case params := <-s.queue:
+						s.write(context.Background(), params)
+					default:
+						return
+					}
+				}
+			}
+		}
+	}()
+}
+
+// write stores one execution in the audit log
+func (s *ToolAuditService) write(ctx context.Context, params database.CreateToolExecutionParams) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := s.queries.CreateToolExecution(ctx, params); err != nil {
+		logging.Error("failed to record tool execution", err, "tool", params.ToolName)
+	}
+}
+
+// prune deletes executions older than the retention period
+func (s *ToolAuditService) prune(ctx context.Context) {
+	deleted, err := s.queries.DeleteToolExecutionsBefore(ctx, time.Now().Add(-s.cfg.Retention))
+	if err != nil {
+		logging.Error("failed to prune tool executions", err)
+		return
+	}
+	if deleted > 0 {
+		logging.Info("pruned tool executions", "count", deleted)
+	}
+}
+
+// record adds an execution to the metrics
+func (s *ToolAuditService) record(execution ToolExecution) {
+	// Unknown names come from the model, so they share one entry
+	name := execution.ToolName
+	if execution.Outcome == ToolOutcomeUnknownTool {
+		name = "unknown"
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats, ok := s.stats[name]
+	if !ok {
+		stats = &toolStats{
+			outcomes: make(map[string]int64),
+			buckets:  make([]int64, len(toolDurationBuckets)),
+		}
+		s.stats[name] = stats
+	}
+	stats.calls++
+	stats.outcomes[execution.Outcome]++
+	stats.total += execution.Duration
+	stats.max = max(stats.max, execution.Duration)
+	seconds := execution.Duration.Seconds()
+	for i, bound := range toolDurationBuckets {
+		if seconds <= bound {
+			stats.buckets[i]++
+		}
+	}
+}
+
+// Metrics returns the per-tool metrics since the server started
+func (s *ToolAuditService) Metrics() ToolMetrics {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	metrics := ToolMetrics{
+		Since:        s.since,
+		Tools:        make([]ToolMetricsItem, 0, len(s.stats)),
+		AuditDropped: s.dropped,
+	}
+	for name, stats := range s.stats {
+		outcomes := make(map[string]int64, len(stats.outcomes))
+		for outcome, count := range stats.outcomes {
+			outcomes[outcome] = count
+		}
+		metrics.Tools = append(metrics.Tools, ToolMetricsItem{
+			ToolName:      name,
+			Calls:         stats.calls,
+			Outcomes:      outcomes,
+			AvgDurationMs: float64(stats.total.Microseconds()) / 1000 / float64(stats.calls),
+			MaxDurationMs: float64(stats.max.Microseconds()) / 1000,
+		})
+	}
+	sort.Slice(metrics.Tools, func(i, j int) bool {
+		return metrics.Tools[i].ToolName < metrics.Tools[j].ToolName
+	})
+	return metrics
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition format
+func (s *ToolAuditService) WritePrometheus(w io.Writer) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := make([]string, 0, len(s.stats))
+	for name := range s.stats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("# HELP tool_executions_total Tool calls by tool and outcome.\n")
+	b.WriteString("# TYPE tool_executions_total counter\n")
+	for _, name := range names {
+		outcomes := make([]string, 0, len(s.stats[name].outcomes))
+		for outcome := range s.stats[name].outcomes {
+			outcomes = append(outcomes, outcome)
+		}
+		sort.Strings(outcomes)
+		for _, outcome := range outcomes {
+			fmt.Fprintf(&b, "tool_executions_total{tool=%q,outcome=%q} %d\n", name, outcome, s.stats[name].outcomes[outcome])
+		}
+	}
+
+	b.WriteString("# HELP tool_execution_duration_seconds Time taken by tool calls.\n")
+	b.WriteString("# TYPE tool_execution_duration_seconds histogram\n")
+	for _, name := range names {
+		stats := s.stats[name]
+		for i, bound := range toolDurationBuckets {
+			fmt.Fprintf(&b, "tool_execution_duration_seconds_bucket{tool=%q,le=\"%g\"} %d\n", name, bound, stats.buckets[i])
+		}
+		fmt.Fprintf(&b, "tool_execution_duration_seconds_bucket{tool=%q,le=\"+Inf\"} %d\n", name, stats.calls)
+		fmt.Fprintf(&b, "tool_execution_duration_seconds_sum{tool=%q} %g\n", name, stats.total.Seconds())
+		fmt.Fprintf(&b, "tool_execution_duration_seconds_count{tool=%q} %d\n", name, stats.calls)
+	}
+
+	b.WriteString("# HELP tool_audit_dropped_total Tool calls not written to the audit log because its queue was full.\n")
+	b.WriteString("# TYPE tool_audit_dropped_total counter\n")
+	fmt.Fprintf(&b, "tool_audit_dropped_total %d\n", s.dropped)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ListExecutions returns audit log entries, newest first
+func (s *ToolAuditService) ListExecutions(ctx context.Context, filter ToolExecutionFilter) ([]ToolExecutionView, error) {
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultToolExecutionPageSize
+	}
+	limit = min(limit, MaxToolExecutionPageSize)
+
+	params := database.ListToolExecutionsParams{
+		UserID:    filter.UserID,
+		SessionID: filter.SessionID,
+		Success:   filter.Success,
+		PageSize:  int32(limit),
+	}
+	if filter.ToolName != "" {
+		params.ToolName = &filter.ToolName
+	}
+	if filter.Before != nil {
+		params.CreatedBefore = pgtype.Timestamptz{Time: *filter.Before, Valid: true}
+	}
+
+	rows, err := s.queries.ListToolExecutions(ctx, params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tool executions: %w", err)
+	}
+
+	views := make([]ToolExecutionView, 0, len(rows))
+	for _, row := range rows {
+		views = append(views, toolExecutionToView(row))
+	}
+	return views, nil
+}
+
+// GetExecution returns one audit log entry
+func (s *ToolAuditService) GetExecution(ctx context.Context, id uuid.UUID) (*ToolExecutionView, error) {
+	row, err := s.queries.GetToolExecution(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrToolExecutionNotFound
+		}
+		return nil, fmt.Errorf("failed to get tool execution: %w", err)
+	}
+	view := toolExecutionToView(row)
+	return &view, nil
+}
+
+// toolExecutionParams converts an execution to an audit log row
+func toolExecutionParams(execution ToolExecution) database.CreateToolExecutionParams {
+	hash := sha256.Sum256([]byte(execution.Arguments))
+	params := database.CreateToolExecutionParams{
+		UserID:        execution.UserID,
+		ToolName:      truncateRunes(execution.ToolName, maxAuditNameLength),
+		ArgumentsHash: hex.EncodeToString(hash[:]),
+		Arguments:     redactToolArguments(execution.Arguments),
+		Outcome:       execution.Outcome,
+		Success:       execution.Outcome == ToolOutcomeOK,
+		DurationMs:    int32(execution.Duration.Milliseconds()),
+	}
+	if inv := execution.Invocation; inv.SessionID != uuid.Nil {
+		params.SessionID = &inv.SessionID
+		if inv.MessageID != uuid.Nil {
+			params.MessageID = &inv.MessageID
+		}
+	}
+	if execution.Invocation.ToolCallID != "" {
+		toolCallID := truncateRunes(execution.Invocation.ToolCallID, maxAuditNameLength)
+		params.ToolCallID = &toolCallID
+	}
+	if execution.Error != "" {
+		errMsg := truncateRunes(execution.Error, maxAuditErrorLength)
+		params.Error = &errMsg
+	}
+	return params
+}
+
+func toolExecutionToView(row database.ToolExecution) ToolExecutionView {
+	view := ToolExecutionView{
+		ID:            row.ID,
+		UserID:        row.UserID,
+		SessionID:     row.SessionID,
+		MessageID:     row.MessageID,
+		ToolName:      row.ToolName,
+		ArgumentsHash: row.ArgumentsHash,
+		Arguments:     json.RawMessage(row.Arguments),
+		Outcome:       row.Outcome,
+		Success:       row.Success,
+		DurationMs:    row.DurationMs,
+		CreatedAt:     row.CreatedAt,
+	}
+	if row.ToolCallID != nil {
+		view.ToolCallID = *row.ToolCallID
+	}
+	if row.Error != nil {
+		view.Error = *row.Error
+	}
+	return view
+}
+
+// redactToolArguments returns the arguments as JSON for the audit log. Values
+// of sensitive-looking keys are replaced and long strings are truncated.
+// Arguments that aren't valid JSON are stored as a truncated string, since
+// those are the calls most worth debugging.
+func redactToolArguments(arguments string) []byte {
+	var value interface{}
+	if err := json.Unmarshal([]byte(arguments), &value); err == nil {
+		if data, err := json.Marshal(redactAuditValue(value)); err == nil && len(data) <= maxAuditArgumentsLength {
+			return data
+		}
+	}
+	data, _ := json.Marshal(truncateRunes(arguments, maxAuditStringLength))
+	return data
+}
+
+func redactAuditValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if auditSensitiveKey.MatchString(key) {
+				v[key] = auditRedacted
+				continue
+			}
+			v[key] = redactAuditValue(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactAuditValue(item)
+		}
+		return v
+	case string:
+		return truncateRunes(v, maxAuditStringLength)
+	default:
+		return v
+	}
+}
+
+// truncateRunes shortens s to at most n runes, noting how much was cut
+func truncateRunes(s string, n int) string {
+	count := utf8.RuneCountInString(s)
+	if count <= n {
+		return s
+	}
+	runes := []rune(s)
+	return fmt.Sprintf("%s…(%d more characters)", string(runes[:n]), count-n)
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// recordingObserver collects the executions it observes
+type recordingObserver struct {
+	mu         sync.Mutex
+	executions []ToolExecution
+}
+
+func (o *recordingObserver) ObserveToolExecution(ctx context.Context, execution ToolExecution) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.executions = append(o.executions, execution)
+}
+
+func TestToolRegistryObservesExecutions(t *testing.T) {
+	executor, registry := newTestExecutor(ToolExecutorConfig{})
+	observer := &recordingObserver{}
+	registry.SetObserver(observer)
+
+	registry.Register("greet", ToolDefinition{Name: "greet", Parameters: ToolParametersFor[testToolArgs]()},
+		func(ctx context.Context, userID uuid.UUID, arguments string) (*ToolResult, error) {
+			return &ToolResult{Success: true}, nil
+		})
+	registry.Register("refuses", ToolDefinition{}, func(ctx context.Context, userID uuid.UUID, arguments string) (*ToolResult, error) {
+		return &ToolResult{Success: false, Error: "no such order"}, nil
+	})
+	registry.Register("breaks", ToolDefinition{}, func(ctx context.Context, userID uuid.UUID, arguments string) (*ToolResult, error) {
+		return nil, errors.New("database down")
+	})
+	registry.Register("panics", ToolDefinition{}, func(ctx context.Context, userID uuid.UUID, arguments string) (*ToolResult, error) {
+		panic("boom")
+	})
+
+	userID, sessionID := uuid.New(), uuid.New()
+	ctx := WithToolInvocation(context.Background(), ToolInvocation{SessionID: sessionID, ToolCallID: "call_1"})
+
+	tests := []struct {
+		tool, arguments, outcome, err string
+	}{
+		{"greet", `{"name":"a"}`, ToolOutcomeOK, ""},
+		{"greet", `{"mode":"medium"}`, ToolOutcomeInvalidArguments, "name"},
+		{"refuses", `{}`, ToolOutcomeFailed, "no such order"},
+		{"breaks", `{}`, ToolOutcomeError, "database down"},
+		{"missing", `{}`, ToolOutcomeUnknownTool, "unknown tool"},
+	}
+	for _, tt := range tests {
+		registry.Execute(ctx, userID, tt.tool, tt.arguments)
+	}
+	// The executor recovers the panic; the observer still sees the call
+	call := testToolCall("call_2", "panics")
+	executor.runToolCall(ctx, call, func(ctx context.Context) (*ToolExecutionResult, error) {
+		return executor.ExecuteToolCall(ctx, userID, call)
+	})
+
+	if len(observer.executions) != len(tests)+1 {
+		t.Fatalf("observed %d executions, want %d", len(observer.executions), len(tests)+1)
+	}
+	for i, tt := range tests {
+		got := observer.executions[i]
+		if got.ToolName != tt.tool || got.Outcome != tt.outcome || !strings.Contains(got.Error, tt.err) {
+			t.Errorf("%s %s: observed %+v, want outcome %s", tt.tool, tt.arguments, got, tt.outcome)
+		}
+		if got.UserID != userID || got.Invocation.SessionID != sessionID || got.Arguments != tt.arguments {
+			t.Errorf("%s: observed %+v, want the call's context", tt.tool, got)
+		}
+	}
+	if got := observer.executions[len(tests)]; got.Outcome != ToolOutcomeError || got.Error != "tool panicked" {
+		t.Errorf("panics: observed %+v, want error outcome", got)
+	}
+}
+
+func TestRedactToolArguments(t *testing.T) {
+	long := strings.Repeat("x", maxAuditStringLength+10)
+	arguments := `{"query":"orders","api_key":"sk-123","nested":{"Password":"hunter2","items":["` + long + `"]}}`
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(redactToolArguments(arguments), &got); err != nil {
+		t.Fatalf("redacted arguments are not JSON: %v", err)
+	}
+	nested := got["nested"].(map[string]interface{})
+	if got["query"] != "orders" || got["api_key"] != auditRedacted || nested["Password"] != auditRedacted {
+		t.Errorf("redacted = %v", got)
+	}
+	if item := nested["items"].([]interface{})[0].(string); !strings.HasSuffix(item, "…(10 more characters)") {
+		t.Errorf("expected long strings to be truncated, got %d characters", len(item))
+	}
+
+	// Malformed arguments are kept as a string
+	var raw string
+	if err := json.Unmarshal(redactToolArguments(`{"name": "a"`), &raw); err != nil || raw != `{"name": "a"` {
+		t.Errorf("redacted malformed arguments = %q, %v", raw, err)
+	}
+}
+
+func TestToolAuditServiceMetrics(t *testing.T) {
+	service := NewToolAuditService(nil, ToolAuditConfig{QueueSize: 2})
+	ctx := context.Background()
+	userID := uuid.New()
+
+	service.ObserveToolExecution(ctx, ToolExecution{UserID: userID, ToolName: "greet", Arguments: `{"token":"t"}`, Outcome: ToolOutcomeOK, Duration: 20 * time.Millisecond})
+	service.ObserveToolExecution(ctx, ToolExecution{UserID: userID, ToolName: "greet", Outcome: ToolOutcomeInvalidArguments, Error: "name: is required", Duration: time.Second})
+	service.ObserveToolExecution(ctx, ToolExecution{UserID: userID, ToolName: "made_up_tool", Outcome: ToolOutcomeUnknownTool})
+
+	metrics := service.Metrics()
+	if len(metrics.Tools) != 2 || metrics.Tools[0].ToolName != "greet" || metrics.Tools[1].ToolName != "unknown" {
+		t.Fatalf("tools = %+v, want greet and unknown", metrics.Tools)
+	}
+	greet := metrics.Tools[0]
+	if greet.Calls != 2 || greet.Outcomes[ToolOutcomeOK] != 1 || greet.Outcomes[ToolOutcomeInvalidArguments] != 1 {
+		t.Errorf("greet = %+v", greet)
+	}
+	if greet.AvgDurationMs != 510 || greet.MaxDurationMs != 1000 {
+		t.Errorf("greet durations = %v avg, %v max", greet.AvgDurationMs, greet.MaxDurationMs)
+	}
+	// The queue holds two executions; the third is dropped
+	if metrics.AuditDropped != 1 {
+		t.Errorf("audit_dropped = %d, want 1", metrics.AuditDropped)
+	}
+
+	params := <-service.queue
+	if params.UserID != userID || params.ToolName != "greet" || !params.Success || len(params.ArgumentsHash) != 64 {
+		t.Errorf("queued %+v", params)
+	}
+	if string(params.Arguments) != `{"token":"[REDACTED]"}` {
+		t.Errorf("queued arguments = %s, want them redacted", params.Arguments)
+	}
+
+	var b strings.Builder
+	if err := service.WritePrometheus(&b); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`tool_executions_total{tool="greet",outcome="invalid_arguments"} 1`,
+		`tool_execution_duration_seconds_bucket{tool="greet",le="0.05"} 1`,
+		`tool_execution_duration_seconds_bucket{tool="greet",le="+Inf"} 2`,
+		`tool_execution_duration_seconds_count{tool="unknown"} 1`,
+		`tool_audit_dropped_total 1`,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("metrics missing %q:\n%s", want, b.String())
+		}
+	}
+}
@@ -3,20 +3,67 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
 
+	"github.com/agpt-go/chatbot-api/internal/database"
+	"github.com/agpt-go/chatbot-api/internal/logging"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 )
 
+var (
+	ErrToolCallNotFound = errors.New("tool call not found")
+	ErrToolCallResolved = errors.New("tool call already resolved")
+)
+
+// Tool call confirmation statuses
+const (
+	ToolCallStatusPending  = "pending"
+	ToolCallStatusApproved = "approved"
+	ToolCallStatusRejected = "rejected"
+)
+
+// Defaults for ToolExecutorConfig
+const (
+	DefaultToolMaxParallel = 4
+	DefaultToolTimeout     = 30 * time.Second
+)
+
+// ToolExecutorConfig configures how tool calls are executed
+type ToolExecutorConfig struct {
+	Policies    *ToolPolicies // nil runs every tool automatically
+	MaxParallel int           // Calls running at once in one step; 0 uses DefaultToolMaxParallel
+	Timeout     time.Duration // Per-call limit unless the tool sets its own; 0 uses DefaultToolTimeout
+}
+
 // ToolExecutor handles the execution of tool calls from the LLM
-// It delegates to the ToolRegistry for actual execution
+// It applies the tool policies and delegates to the ToolRegistry for actual execution
 type ToolExecutor struct {
 	toolService *ToolService
+	policies    *ToolPolicies
+	maxParallel int
+	timeout     time.Duration
+
+	// sessionTools returns the tools a chat session is restricted to; ok is
+	// false when the session offers every tool
+	sessionTools func(ctx context.Context, userID, sessionID uuid.UUID) (tools []string, ok bool, err error)
 }
 
 // NewToolExecutor creates a new tool executor
-func NewToolExecutor(toolService *ToolService) *ToolExecutor {
+func NewToolExecutor(toolService *ToolService, cfg ToolExecutorConfig) *ToolExecutor {
+	if cfg.MaxParallel <= 0 {
+		cfg.MaxParallel = DefaultToolMaxParallel
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultToolTimeout
+	}
 	return &ToolExecutor{
 		toolService: toolService,
+		policies:    cfg.Policies,
+		maxParallel: cfg.MaxParallel,
+		timeout:     cfg.Timeout,
 	}
 }
 
@@ -26,26 +73,355 @@ type ToolExecutionResult struct {
 	Success bool        `json:"success"`
 	Result  interface{} `json:"result,omitempty"`
 	Error   string      `json:"error,omitempty"`
+
+	// Pending is set when the call waits for the user's confirmation and has not run yet
+	Pending bool `json:"pending,omitempty"`
 }
 
-// ExecuteTool executes a tool call via the registry
+// PendingToolCall is a tool call waiting for the user to approve or reject it
+type PendingToolCall struct {
+	ToolCallID string    `json:"tool_call_id"`
+	ToolName   string    `json:"tool_name"`
+	Arguments  string    `json:"arguments"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ExecuteTool executes a tool call according to the user's policy for the tool.
+// Denied calls fail without running. Calls that need confirmation are stored
+// as pending and return a Pending result; they run once ResolveToolCall approves them.
 func (e *ToolExecutor) ExecuteTool(ctx context.Context, userID uuid.UUID, toolName string, arguments string) (*ToolExecutionResult, error) {
+	if !e.allowedInSession(ctx, userID, toolName) {
+		return &ToolExecutionResult{
+			Success: false,
+			Error:   fmt.Sprintf("Tool %s is not available in this chat session", toolName),
+		}, nil
+	}
+
+	switch e.policyFor(ctx, userID, toolName) {
+	case ToolPolicyDeny:
+		return deniedToolResult(toolName), nil
+	case ToolPolicyConfirm:
+		return e.requestConfirmation(ctx, userID, toolName, arguments)
+	}
+	return e.execute(ctx, userID, toolName, arguments), nil
+}
+
+// ExecuteToolCall is a convenience method that takes a ToolCall directly
+func (e *ToolExecutor) ExecuteToolCall(ctx context.Context, userID uuid.UUID, toolCall ToolCall) (*ToolExecutionResult, error) {
+	return e.ExecuteTool(ctx, userID, toolCall.Function.Name, toolCall.Function.Arguments)
+}
+
+// AutomaticTools returns the definitions of the tools the user's policies run
+// without confirmation. Callers outside a chat session, where no one can
+// approve a call, can only use these.
+func (e *ToolExecutor) AutomaticTools(ctx context.Context, userID uuid.UUID) []ToolDefinition {
+	var tools []ToolDefinition
+	for _, def := range e.toolService.GetRegistry().GetDefinitions() {
+		if e.policyFor(ctx, userID, def.Name) == ToolPolicyAuto {
+			tools = append(tools, def)
+		}
+	}
+	return tools
+}
+
+// ToolCallResult pairs a tool call from one model step with its result
+type ToolCallResult struct {
+	Index  int // Position of the call in the step
+	Call   ToolCall
+	Result *ToolExecutionResult
+}
+
+// ExecuteToolCalls runs the tool calls of one model step. Independent calls
+// run concurrently, at most maxParallel at a time; calls to tools sharing a
+// group run one after another in call order. Each call gets its own timeout and
+// a panicking tool fails only its own call.
+//
+// onResult, if set, is called as each call completes, always from the calling
+// goroutine. The returned results are in call order regardless of completion order.
+// ctx should carry the step's ToolInvocation; each call gets its own ToolCallID.
+func (e *ToolExecutor) ExecuteToolCalls(ctx context.Context, userID uuid.UUID, calls []ToolCall, onResult func(ToolCallResult)) []ToolCallResult {
+	results := make([]ToolCallResult, len(calls))
+	if len(calls) == 0 {
+		return results
+	}
+
+	// Calls in the same group form one unit that runs sequentially
+	var units [][]int
+	unitByGroup := make(map[string]int)
+	for i, call := range calls {
+		tool, _ := e.toolService.GetRegistry().Get(call.Function.Name)
+		if tool.Group == "" {
+			units = append(units, []int{i})
+			continue
+		}
+		if u, ok := unitByGroup[tool.Group]; ok {
+			units[u] = append(units[u], i)
+			continue
+		}
+		unitByGroup[tool.Group] = len(units)
+		units = append(units, []int{i})
+	}
+
+	done := make(chan ToolCallResult, len(calls))
+	sem := make(chan struct{}, e.maxParallel)
+	for _, unit := range units {
+		go func(unit []int) {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			for _, i := range unit {
+				result := e.runToolCall(ctx, calls[i], func(ctx context.Context) (*ToolExecutionResult, error) {
+					return e.ExecuteToolCall(ctx, userID, calls[i])
+				})
+				done <- ToolCallResult{Index: i, Call: calls[i], Result: result}
+			}
+		}(unit)
+	}
+
+	for range calls {
+		result := <-done
+		results[result.Index] = result
+		if onResult != nil {
+			onResult(result)
+		}
+	}
+	return results
+}
+
+// runToolCall executes one call with run, under the call's timeout, turning
+// errors, panics and timeouts into failed results the model can see
+func (e *ToolExecutor) runToolCall(ctx context.Context, call ToolCall, run func(ctx context.Context) (*ToolExecutionResult, error)) *ToolExecutionResult {
+	timeout := e.timeout
+	if tool, ok := e.toolService.GetRegistry().Get(call.Function.Name); ok && tool.Timeout > 0 {
+		timeout = tool.Timeout
+	}
+
+	inv, _ := ToolInvocationFromContext(ctx)
+	inv.ToolCallID = call.ID
+	callCtx, cancel := context.WithTimeout(WithToolInvocation(ctx, inv), timeout)
+	defer cancel()
+
+	// Buffered so a tool that ignores its context can still finish after we stop waiting
+	done := make(chan *ToolExecutionResult, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				logging.Error("tool panicked", fmt.Errorf("%v", r), "tool", call.Function.Name, "toolCallID", call.ID)
+				done <- &ToolExecutionResult{Success: false, Error: "Tool failed unexpectedly"}
+			}
+		}()
+
+		result, err := run(callCtx)
+		if err != nil {
+			logging.Error("failed to execute tool", err, "tool", call.Function.Name)
+			result = &ToolExecutionResult{Success: false, Error: "Failed to execute tool: " + err.Error()}
+		}
+		done <- result
+	}()
+
+	select {
+	case result := <-done:
+		return result
+	case <-callCtx.Done():
+		logging.Warn("tool call timed out", "tool", call.Function.Name, "toolCallID", call.ID, "timeout", timeout.String())
+		return &ToolExecutionResult{Success: false, Error: fmt.Sprintf("Tool %s did not finish within %s", call.Function.Name, timeout)}
+	}
+}
+
+// ListPendingToolCalls returns the session's tool calls that wait for confirmation, oldest first
+func (e *ToolExecutor) ListPendingToolCalls(ctx context.Context, userID, sessionID uuid.UUID) ([]PendingToolCall, error) {
+	rows, err := e.toolService.queries.ListPendingToolCallConfirmations(ctx, database.ListPendingToolCallConfirmationsParams{
+		SessionID: sessionID,
+		UserID:    userID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pending tool calls: %w", err)
+	}
+
+	pending := make([]PendingToolCall, len(rows))
+	for i, row := range rows {
+		pending[i] = PendingToolCall{
+			ToolCallID: row.ToolCallID,
+			ToolName:   row.ToolName,
+			Arguments:  row.Arguments,
+			CreatedAt:  row.CreatedAt,
+		}
+	}
+	return pending, nil
+}
+
+// ResolveToolCall approves or rejects a pending tool call. An approved call runs
+// now, unless the user's policy has changed to deny in the meantime; a rejected
+// call returns a failed result telling the model the user declined. The result
+// replaces the pending one in the chat history. Each call can only be resolved once.
+func (e *ToolExecutor) ResolveToolCall(ctx context.Context, userID, sessionID uuid.UUID, toolCallID string, approve bool) (*ToolExecutionResult, error) {
+	queries := e.toolService.queries
+
+	confirmation, err := queries.GetToolCallConfirmation(ctx, database.GetToolCallConfirmationParams{
+		SessionID:  sessionID,
+		ToolCallID: toolCallID,
+		UserID:     userID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrToolCallNotFound
+		}
+		return nil, fmt.Errorf("failed to get tool call: %w", err)
+	}
+
+	status := ToolCallStatusRejected
+	if approve {
+		status = ToolCallStatusApproved
+	}
+
+	// Claiming the row first guarantees the tool runs at most once
+	confirmation, err = queries.ResolveToolCallConfirmation(ctx, database.ResolveToolCallConfirmationParams{
+		ID:     confirmation.ID,
+		Status: status,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrToolCallResolved
+		}
+		return nil, fmt.Errorf("failed to resolve tool call: %w", err)
+	}
+
+	var result *ToolExecutionResult
+	switch {
+	case !approve:
+		result = &ToolExecutionResult{Success: false, Error: "The user declined to run this tool"}
+	case e.policyFor(ctx, userID, confirmation.ToolName) == ToolPolicyDeny:
+		result = deniedToolResult(confirmation.ToolName)
+	default:
+		inv := ToolInvocation{SessionID: sessionID}
+		if confirmation.MessageID != nil {
+			inv.MessageID = *confirmation.MessageID
+		}
+		call := ToolCall{ID: toolCallID, Type: "function"}
+		call.Function.Name = confirmation.ToolName
+		call.Function.Arguments = confirmation.Arguments
+		// The policy was checked above, so the call runs without asking again
+		result = e.runToolCall(WithToolInvocation(ctx, inv), call, func(ctx context.Context) (*ToolExecutionResult, error) {
+			return e.execute(ctx, userID, call.Function.Name, call.Function.Arguments), nil
+		})
+	}
+
+	// The chat history held a pending placeholder for this call; replace it with the
+	// result so the model sees what happened on the next turn
+	data, err := json.Marshal(result)
+	if err == nil {
+		err = queries.InTx(ctx, func(q *database.Queries) error {
+			if err := q.SetToolCallConfirmationResult(ctx, database.SetToolCallConfirmationResultParams{
+				ID:     confirmation.ID,
+				Result: data,
+			}); err != nil {
+				return err
+			}
+			tokens := int32(estimateTokens(string(data)))
+			return q.UpdateToolResultMessage(ctx, database.UpdateToolResultMessageParams{
+				SessionID:  sessionID,
+				ToolCallID: &toolCallID,
+				Content:    string(data),
+				TokensUsed: &tokens,
+			})
+		})
+	}
+	if err != nil {
+		logging.Warn("failed to save tool call result", "error", err, "toolCallID", toolCallID)
+	}
+
+	return result, nil
+}
+
+// execute runs a tool without checking its policy
+func (e *ToolExecutor) execute(ctx context.Context, userID uuid.UUID, toolName string, arguments string) *ToolExecutionResult {
 	// Delegate to the registry - no switch statement needed!
 	result, err := e.toolService.GetRegistry().Execute(ctx, userID, toolName, arguments)
 	if err != nil {
-		return &ToolExecutionResult{Success: false, Error: err.Error()}, nil
+		return &ToolExecutionResult{Success: false, Error: err.Error()}
 	}
 
 	return &ToolExecutionResult{
 		Success: result.Success,
 		Result:  result,
 		Error:   result.Error,
-	}, nil
+	}
 }
 
-// ExecuteToolCall is a convenience method that takes a ToolCall directly
-func (e *ToolExecutor) ExecuteToolCall(ctx context.Context, userID uuid.UUID, toolCall ToolCall) (*ToolExecutionResult, error) {
-	return e.ExecuteTool(ctx, userID, toolCall.Function.Name, toolCall.Function.Arguments)
+// requestConfirmation stores the call as pending for the chat session it was made in
+func (e *ToolExecutor) requestConfirmation(ctx context.Context, userID uuid.UUID, toolName string, arguments string) (*ToolExecutionResult, error) {
+	inv, ok := ToolInvocationFromContext(ctx)
+	if !ok || inv.SessionID == uuid.Nil || inv.ToolCallID == "" {
+		return &ToolExecutionResult{
+			Success: false,
+			Error:   fmt.Sprintf("Tool %s requires confirmation, which is only available in a chat session", toolName),
+		}, nil
+	}
+
+	var messageID *uuid.UUID
+	if inv.MessageID != uuid.Nil {
+		messageID = &inv.MessageID
+	}
+
+	_, err := e.toolService.queries.CreateToolCallConfirmation(ctx, database.CreateToolCallConfirmationParams{
+		SessionID:  inv.SessionID,
+		UserID:     userID,
+		MessageID:  messageID,
+		ToolCallID: inv.ToolCallID,
+		ToolName:   toolName,
+		Arguments:  arguments,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store tool call for confirmation: %w", err)
+	}
+
+	return &ToolExecutionResult{Pending: true}, nil
+}
+
+// policyFor resolves the policy for a tool from the user's organization role.
+// The role is only looked up for tools with role overrides; if it can't be
+// loaded the tool's role-independent policy applies.
+func (e *ToolExecutor) policyFor(ctx context.Context, userID uuid.UUID, toolName string) string {
+	if e.policies == nil {
+		return ToolPolicyAuto
+	}
+	if len(e.policies.Tools[toolName].Roles) == 0 {
+		return e.policies.For(toolName, "")
+	}
+
+	role := ToolRoleIndividual
+	member, err := e.toolService.queries.GetOrganizationMemberByUserID(ctx, userID)
+	switch {
+	case err == nil:
+		role = member.Role
+	case !errors.Is(err, pgx.ErrNoRows):
+		logging.Warn("failed to get organization role for tool policy", "error", err, "userID", userID.String())
+		role = ""
+	}
+
+	return e.policies.For(toolName, role)
+}
+
+// allowedInSession reports whether the chat session the call was made in, if
+// any, offers the tool. It fails closed when the session can't be loaded.
+func (e *ToolExecutor) allowedInSession(ctx context.Context, userID uuid.UUID, toolName string) bool {
+	inv, ok := ToolInvocationFromContext(ctx)
+	if e.sessionTools == nil || !ok || inv.SessionID == uuid.Nil {
+		return true
+	}
+
+	tools, restricted, err := e.sessionTools(ctx, userID, inv.SessionID)
+	if err != nil {
+		logging.Warn("failed to get session tools", "error", err, "sessionID", inv.SessionID.String())
+		return false
+	}
+	return !restricted || containsString(tools, toolName)
+}
+
+func deniedToolResult(toolName string) *ToolExecutionResult {
+	return &ToolExecutionResult{
+		Success: false,
+		Error:   fmt.Sprintf("Tool %s is not permitted for this user", toolName),
+	}
 }
 
 // ToToolResultMessage converts a tool execution result to a chat message
@@ -0,0 +1,254 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/database"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+func testToolCall(id, name string) ToolCall {
+	tc := ToolCall{ID: id, Type: "function"}
+	tc.Function.Name = name
+	tc.Function.Arguments = "{}"
+	return tc
+}
+
+func newTestExecutor(cfg ToolExecutorConfig) (*ToolExecutor, *ToolRegistry) {
+	toolService := &ToolService{registry: NewToolRegistry()}
+	return NewToolExecutor(toolService, cfg), toolService.registry
+}
+
+func TestExecuteToolCallsRunsConcurrently(t *testing.T) {
+	executor, registry := newTestExecutor(ToolExecutorConfig{MaxParallel: 2})
+
+	var running, peak int32
+	release := make(chan struct{})
+	registry.Register("slow", ToolDefinition{}, func(ctx context.Context, userID uuid.UUID, arguments string) (*ToolResult, error) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		<-release
+		atomic.AddInt32(&running, -1)
+		inv, _ := ToolInvocationFromContext(ctx)
+		return &ToolResult{Success: true, Message: inv.ToolCallID}, nil
+	})
+
+	calls := []ToolCall{testToolCall("a", "slow"), testToolCall("b", "slow"), testToolCall("c", "slow")}
+	go func() {
+		// Wait until the parallelism limit is reached before letting calls finish
+		for atomic.LoadInt32(&running) < 2 {
+			time.Sleep(time.Millisecond)
+		}
+		close(release)
+	}()
+
+	var completed []string
+	results := executor.ExecuteToolCalls(context.Background(), uuid.New(), calls, func(res ToolCallResult) {
+		completed = append(completed, res.Call.ID)
+	})
+
+	if peak != 2 {
+		t.Errorf("peak concurrency = %d, want 2", peak)
+	}
+	if len(completed) != 3 {
+		t.Errorf("onResult called %d times, want 3", len(completed))
+	}
+	for i, res := range results {
+		if res.Index != i || res.Call.ID != calls[i].ID {
+			t.Errorf("results[%d] = %+v, want call %s", i, res, calls[i].ID)
+		}
+		// Each call sees its own ToolCallID
+		if data := res.Result.Result.(*ToolResult); data.Message != calls[i].ID {
+			t.Errorf("results[%d] ran with ToolCallID %q", i, data.Message)
+		}
+	}
+}
+
+func TestExecuteToolCallsSerializesGroups(t *testing.T) {
+	executor, registry := newTestExecutor(ToolExecutorConfig{})
+
+	var mu sync.Mutex
+	var order []string
+	handler := func(ctx context.Context, userID uuid.UUID, arguments string) (*ToolResult, error) {
+		inv, _ := ToolInvocationFromContext(ctx)
+		if inv.ToolCallID == "first" {
+			// Give a concurrently scheduled call a chance to overtake
+			time.Sleep(20 * time.Millisecond)
+		}
+		mu.Lock()
+		order = append(order, inv.ToolCallID)
+		mu.Unlock()
+		return &ToolResult{Success: true}, nil
+	}
+	registry.Register("write", ToolDefinition{}, handler, WithToolGroup("state"))
+	registry.Register("read", ToolDefinition{}, handler, WithToolGroup("state"))
+
+	executor.ExecuteToolCalls(context.Background(), uuid.New(), []ToolCall{
+		testToolCall("first", "write"),
+		testToolCall("second", "read"),
+	}, nil)
+
+	if want := []string{"first", "second"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("order = %v, want %v", order, want)
+	}
+}
+
+func TestExecuteToolCallsFailures(t *testing.T) {
+	executor, registry := newTestExecutor(ToolExecutorConfig{Timeout: 20 * time.Millisecond})
+
+	registry.Register("hangs", ToolDefinition{}, func(ctx context.Context, userID uuid.UUID, arguments string) (*ToolResult, error) {
+		time.Sleep(time.Second)
+		return &ToolResult{Success: true}, nil
+	})
+	registry.Register("patient", ToolDefinition{}, func(ctx context.Context, userID uuid.UUID, arguments string) (*ToolResult, error) {
+		time.Sleep(40 * time.Millisecond)
+		return &ToolResult{Success: true}, nil
+	}, WithToolTimeout(time.Second))
+	registry.Register("panics", ToolDefinition{}, func(ctx context.Context, userID uuid.UUID, arguments string) (*ToolResult, error) {
+		panic("boom")
+	})
+	registry.Register("works", ToolDefinition{}, func(ctx context.Context, userID uuid.UUID, arguments string) (*ToolResult, error) {
+		return &ToolResult{Success: true}, nil
+	})
+
+	results := executor.ExecuteToolCalls(context.Background(), uuid.New(), []ToolCall{
+		testToolCall("1", "hangs"),
+		testToolCall("2", "patient"),
+		testToolCall("3", "panics"),
+		testToolCall("4", "works"),
+	}, nil)
+
+	if r := results[0].Result; r.Success || !strings.Contains(r.Error, "did not finish") {
+		t.Errorf("hangs = %+v, want timeout", r)
+	}
+	if r := results[1].Result; !r.Success {
+		t.Errorf("patient = %+v, want its own longer timeout to apply", r)
+	}
+	if r := results[2].Result; r.Success || r.Error == "" {
+		t.Errorf("panics = %+v, want failure", r)
+	}
+	if r := results[3].Result; !r.Success {
+		t.Errorf("works = %+v, want success", r)
+	}
+}
+
+func TestResolveToolCallAppliesTimeoutAndRecovery(t *testing.T) {
+	executor, registry := newTestExecutor(ToolExecutorConfig{Timeout: 20 * time.Millisecond})
+	registry.Register("hangs", ToolDefinition{}, func(ctx context.Context, userID uuid.UUID, arguments string) (*ToolResult, error) {
+		time.Sleep(time.Second)
+		return &ToolResult{Success: true}, nil
+	})
+	registry.Register("panics", ToolDefinition{}, func(ctx context.Context, userID uuid.UUID, arguments string) (*ToolResult, error) {
+		panic("boom")
+	})
+
+	tests := []struct {
+		tool string
+		want string
+	}{
+		{"hangs", "did not finish"},
+		{"panics", "failed unexpectedly"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.tool, func(t *testing.T) {
+			userID, sessionID := uuid.New(), uuid.New()
+			confirmation := []any{uuid.New(), sessionID, userID, (*uuid.UUID)(nil), "call_1", tt.tool, "{}",
+				ToolCallStatusApproved, []byte(nil), pgtype.Timestamptz{}, time.Now(), time.Now()}
+			executor.toolService.queries = database.New(&fakeQueryDB{rows: map[string][]any{
+				"GetToolCallConfirmation":     confirmation,
+				"ResolveToolCallConfirmation": confirmation,
+			}})
+
+			result, err := executor.ResolveToolCall(context.Background(), userID, sessionID, "call_1", true)
+			if err != nil {
+				t.Fatalf("ResolveToolCall() error = %v", err)
+			}
+			if result.Success || !strings.Contains(result.Error, tt.want) {
+				t.Errorf("result = %+v, want failure containing %q", result, tt.want)
+			}
+		})
+	}
+}
+
+// chatHistoryDB keeps a session's messages so rewrites of tool results can be checked
+type chatHistoryDB struct {
+	fakeQueryDB
+	messages []database.ChatMessage
+}
+
+func (db *chatHistoryDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	if queryName(sql) == "UpdateToolResultMessage" {
+		for i, msg := range db.messages {
+			if msg.Role == "tool" && *msg.ToolCallID == *args[1].(*string) {
+				db.messages[i].Content = args[2].(string)
+			}
+		}
+	}
+	return db.fakeQueryDB.Exec(ctx, sql, args...)
+}
+
+func (db *chatHistoryDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	return &fakeTx{db: db, committed: &db.committed}, nil
+}
+
+func TestResolveToolCallUpdatesChatHistory(t *testing.T) {
+	executor, registry := newTestExecutor(ToolExecutorConfig{})
+	registry.Register("lookup", ToolDefinition{}, func(ctx context.Context, userID uuid.UUID, arguments string) (*ToolResult, error) {
+		return &ToolResult{Success: true, Message: "found it"}, nil
+	})
+
+	userID, sessionID := uuid.New(), uuid.New()
+	callID, toolName := "call_1", "lookup"
+	callsJSON, err := json.Marshal([]ToolCall{testToolCall(callID, toolName)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	confirmation := []any{uuid.New(), sessionID, userID, (*uuid.UUID)(nil), callID, toolName, "{}",
+		ToolCallStatusApproved, []byte(nil), pgtype.Timestamptz{}, time.Now(), time.Now()}
+	db := &chatHistoryDB{
+		fakeQueryDB: fakeQueryDB{rows: map[string][]any{
+			"GetToolCallConfirmation":     confirmation,
+			"ResolveToolCallConfirmation": confirmation,
+		}},
+		messages: []database.ChatMessage{
+			{Role: "user", Content: "Look it up"},
+			{Role: "assistant", ToolCalls: callsJSON},
+			{Role: "tool", Content: `{"success":false,"pending":true}`, ToolCallID: &callID, ToolName: &toolName},
+		},
+	}
+	executor.toolService.queries = database.New(db)
+
+	if _, err := executor.ResolveToolCall(context.Background(), userID, sessionID, callID, true); err != nil {
+		t.Fatalf("ResolveToolCall() error = %v", err)
+	}
+	if !db.committed {
+		t.Error("result was not saved in a committed transaction")
+	}
+
+	history := toLLMMessages(db.messages)
+	if len(history) != 3 {
+		t.Fatalf("history = %+v, want the call and its result", history)
+	}
+	var result ToolExecutionResult
+	if err := json.Unmarshal([]byte(history[2].Content), &result); err != nil {
+		t.Fatal(err)
+	}
+	if history[2].ToolCallID != callID || !result.Success || result.Pending {
+		t.Errorf("tool message = %+v, want the approved call's result", history[2])
+	}
+}
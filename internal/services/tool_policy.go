@@ -0,0 +1,122 @@
+package services
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+
+	"go.yaml.in/yaml/v3"
+)
+
+//go:embed tool_policies.yaml
+var defaultToolPolicies []byte
+
+// Tool permission policies
+const (
+	ToolPolicyAuto    = "auto"
+	ToolPolicyConfirm = "confirm"
+	ToolPolicyDeny    = "deny"
+)
+
+// ToolRoleIndividual is the policy role of users who are not in an organization
+const ToolRoleIndividual = "individual"
+
+// ToolPolicy is the policy of one tool, optionally overridden per role
+type ToolPolicy struct {
+	Policy string            `json:"policy" yaml:"policy"`
+	Roles  map[string]string `json:"roles,omitempty" yaml:"roles"`
+}
+
+// ToolPolicies decides whether a tool call runs automatically, needs the
+// user's confirmation or is denied
+type ToolPolicies struct {
+	Default string                `json:"default" yaml:"default"`
+	Tools   map[string]ToolPolicy `json:"tools" yaml:"tools"`
+}
+
+// DefaultToolPolicies returns the built-in policies
+func DefaultToolPolicies() *ToolPolicies {
+	policies, err := ParseToolPolicies(defaultToolPolicies)
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in tool policies: %v", err))
+	}
+	return policies
+}
+
+// LoadToolPolicies reads policies from a YAML or JSON file.
+// An empty path returns the built-in policies.
+func LoadToolPolicies(path string) (*ToolPolicies, error) {
+	if path == "" {
+		return DefaultToolPolicies(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tool policies: %w", err)
+	}
+
+	policies, err := ParseToolPolicies(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tool policies %s: %w", path, err)
+	}
+	return policies, nil
+}
+
+// ParseToolPolicies parses and validates policies. JSON is accepted as it is valid YAML.
+func ParseToolPolicies(data []byte) (*ToolPolicies, error) {
+	var policies ToolPolicies
+	if err := yaml.Unmarshal(data, &policies); err != nil {
+		return nil, err
+	}
+
+	if policies.Default == "" {
+		policies.Default = ToolPolicyAuto
+	}
+	if !isToolPolicy(policies.Default) {
+		return nil, fmt.Errorf("default policy %q must be %s, %s or %s", policies.Default, ToolPolicyAuto, ToolPolicyConfirm, ToolPolicyDeny)
+	}
+
+	for name, tool := range policies.Tools {
+		if tool.Policy != "" && !isToolPolicy(tool.Policy) {
+			return nil, fmt.Errorf("tool %q: unknown policy %q", name, tool.Policy)
+		}
+		for role, policy := range tool.Roles {
+			if !isToolRole(role) {
+				return nil, fmt.Errorf("tool %q: unknown role %q", name, role)
+			}
+			if !isToolPolicy(policy) {
+				return nil, fmt.Errorf("tool %q: unknown policy %q for role %q", name, policy, role)
+			}
+		}
+	}
+
+	return &policies, nil
+}
+
+// For returns the policy for a tool called by a user with the given role.
+// A role override wins over the tool's policy, which wins over the default.
+func (p *ToolPolicies) For(toolName, role string) string {
+	tool, ok := p.Tools[toolName]
+	if !ok {
+		return p.Default
+	}
+	if policy, ok := tool.Roles[role]; ok {
+		return policy
+	}
+	if tool.Policy != "" {
+		return tool.Policy
+	}
+	return p.Default
+}
+
+func isToolPolicy(policy string) bool {
+	return policy == ToolPolicyAuto || policy == ToolPolicyConfirm || policy == ToolPolicyDeny
+}
+
+func isToolRole(role string) bool {
+	switch role {
+	case ToolRoleIndividual, OrgRoleOwner, OrgRoleAdmin, OrgRoleMember:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestDefaultToolPolicies(t *testing.T) {
+	policies := DefaultToolPolicies()
+
+	if got := policies.For("add_understanding", OrgRoleMember); got != ToolPolicyAuto {
+		t.Errorf("For(add_understanding) = %q, want %q", got, ToolPolicyAuto)
+	}
+	if got := policies.For("update_understanding", ToolRoleIndividual); got != ToolPolicyConfirm {
+		t.Errorf("For(update_understanding) = %q, want %q", got, ToolPolicyConfirm)
+	}
+}
+
+func TestParseToolPolicies(t *testing.T) {
+	policies, err := ParseToolPolicies([]byte(`
+default: confirm
+tools:
+  search: {policy: auto}
+  delete_data:
+    policy: deny
+    roles: {owner: confirm}
+  export:
+    roles: {member: deny}
+`))
+	if err != nil {
+		t.Fatalf("ParseToolPolicies() error = %v", err)
+	}
+
+	tests := []struct {
+		tool, role, want string
+	}{
+		{"search", OrgRoleMember, ToolPolicyAuto},
+		{"delete_data", OrgRoleOwner, ToolPolicyConfirm},
+		{"delete_data", OrgRoleAdmin, ToolPolicyDeny},
+		{"export", OrgRoleMember, ToolPolicyDeny},
+		{"export", OrgRoleOwner, ToolPolicyConfirm},
+		{"unlisted", OrgRoleOwner, ToolPolicyConfirm},
+	}
+	for _, tt := range tests {
+		if got := policies.For(tt.tool, tt.role); got != tt.want {
+			t.Errorf("For(%s, %s) = %q, want %q", tt.tool, tt.role, got, tt.want)
+		}
+	}
+
+	t.Run("default defaults to auto", func(t *testing.T) {
+		policies, err := ParseToolPolicies([]byte(`tools: {}`))
+		if err != nil {
+			t.Fatalf("ParseToolPolicies() error = %v", err)
+		}
+		if policies.Default != ToolPolicyAuto {
+			t.Errorf("Default = %q, want %q", policies.Default, ToolPolicyAuto)
+		}
+	})
+
+	invalid := map[string]string{
+		"bad default":     `default: ask`,
+		"bad tool policy": `tools: {search: {policy: maybe}}`,
+		"bad role":        `tools: {search: {roles: {guest: auto}}}`,
+		"bad role policy": `tools: {search: {roles: {owner: always}}}`,
+	}
+	for name, data := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseToolPolicies([]byte(data)); err == nil {
+				t.Error("ParseToolPolicies() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestToolExecutorPolicies(t *testing.T) {
+	policies, err := ParseToolPolicies([]byte(`
+tools:
+  echo: {policy: auto}
+  forbidden: {policy: deny}
+  guarded: {policy: confirm}
+`))
+	if err != nil {
+		t.Fatalf("ParseToolPolicies() error = %v", err)
+	}
+
+	toolService := &ToolService{registry: NewToolRegistry()}
+	ran := map[string]bool{}
+	for _, name := range []string{"echo", "forbidden", "guarded"} {
+		toolService.registry.Register(name, ToolDefinition{}, func(ctx context.Context, userID uuid.UUID, arguments string) (*ToolResult, error) {
+			inv, _ := ToolInvocationFromContext(ctx)
+			ran[inv.ToolName] = true
+			return &ToolResult{Success: true}, nil
+		})
+	}
+	executor := NewToolExecutor(toolService, ToolExecutorConfig{Policies: policies})
+	ctx := WithToolInvocation(context.Background(), ToolInvocation{})
+
+	result, err := executor.ExecuteTool(ctx, uuid.New(), "echo", "{}")
+	if err != nil || !result.Success || !ran["echo"] {
+		t.Errorf("ExecuteTool(echo) = %+v, %v, want success", result, err)
+	}
+
+	result, err = executor.ExecuteTool(ctx, uuid.New(), "forbidden", "{}")
+	if err != nil || result.Success || result.Error == "" || ran["forbidden"] {
+		t.Errorf("ExecuteTool(forbidden) = %+v, %v, want denied without running", result, err)
+	}
+
+	// Outside a chat session there is nobody to confirm the call
+	result, err = executor.ExecuteTool(ctx, uuid.New(), "guarded", "{}")
+	if err != nil || result.Success || result.Pending || ran["guarded"] {
+		t.Errorf("ExecuteTool(guarded) = %+v, %v, want failure without running", result, err)
+	}
+}
+
+func TestAutomaticTools(t *testing.T) {
+	policies, err := ParseToolPolicies([]byte(`
+tools:
+  forbidden: {policy: deny}
+  guarded: {policy: confirm}
+`))
+	if err != nil {
+		t.Fatalf("ParseToolPolicies() error = %v", err)
+	}
+
+	toolService := &ToolService{registry: NewToolRegistry()}
+	for _, name := range []string{"echo", "forbidden", "guarded"} {
+		toolService.registry.Register(name, ToolDefinition{Name: name}, nil)
+	}
+	executor := NewToolExecutor(toolService, ToolExecutorConfig{Policies: policies})
+
+	tools := executor.AutomaticTools(context.Background(), uuid.New())
+	if len(tools) != 1 || tools[0].Name != "echo" {
+		t.Errorf("AutomaticTools() = %+v, want only echo", tools)
+	}
+}
@@ -3,8 +3,10 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -13,6 +15,30 @@ import (
 type Tool struct {
 	Definition ToolDefinition
 	Handler    ToolHandler
+
+	// Timeout overrides the executor's default time limit for one call
+	Timeout time.Duration
+
+	// Group names state the tool shares with other tools. Calls to tools in the
+	// same group run one at a time in call order; all other calls can run concurrently.
+	Group string
+}
+
+// ToolOption configures how a registered tool is executed
+type ToolOption func(*Tool)
+
+// WithToolTimeout sets the time limit for one call of the tool
+func WithToolTimeout(timeout time.Duration) ToolOption {
+	return func(t *Tool) {
+		t.Timeout = timeout
+	}
+}
+
+// WithToolGroup serializes the tool with other tools in the same group
+func WithToolGroup(group string) ToolOption {
+	return func(t *Tool) {
+		t.Group = group
+	}
 }
 
 // ToolHandler is the function signature for tool execution
@@ -26,10 +52,61 @@ type ToolResult struct {
 	Error   string                 `json:"error,omitempty"`
 }
 
+// ToolInvocation describes the chat context a tool call was made in.
+// It travels with the request context so handlers can record provenance
+// without changing the ToolHandler signature.
+type ToolInvocation struct {
+	SessionID  uuid.UUID
+	MessageID  uuid.UUID // The user message that triggered the call
+	ToolName   string
+	ToolCallID string
+}
+
+type toolInvocationKey struct{}
+
+// WithToolInvocation attaches tool call context to ctx
+func WithToolInvocation(ctx context.Context, inv ToolInvocation) context.Context {
+	return context.WithValue(ctx, toolInvocationKey{}, inv)
+}
+
+// ToolInvocationFromContext returns the tool call context attached to ctx, if any
+func ToolInvocationFromContext(ctx context.Context) (ToolInvocation, bool) {
+	inv, ok := ctx.Value(toolInvocationKey{}).(ToolInvocation)
+	return inv, ok
+}
+
+// Outcomes of a ToolRegistry.Execute call
+const (
+	ToolOutcomeOK               = "ok"                // The handler ran and reported success
+	ToolOutcomeFailed           = "failed"            // The handler ran and reported failure
+	ToolOutcomeInvalidArguments = "invalid_arguments" // The arguments did not match the schema; the handler never ran
+	ToolOutcomeUnknownTool      = "unknown_tool"      // No tool is registered under the name
+	ToolOutcomeError            = "error"             // The handler returned an error or panicked
+)
+
+// ToolExecution describes one finished ToolRegistry.Execute call
+type ToolExecution struct {
+	UserID     uuid.UUID
+	Invocation ToolInvocation // Chat context, if the call came from a chat
+	ToolName   string
+	Arguments  string // As sent by the caller, unredacted
+	Outcome    string
+	Error      string
+	Duration   time.Duration
+}
+
+// ToolObserver is notified after every ToolRegistry.Execute call. It runs on
+// the calling goroutine, so it must not block.
+type ToolObserver interface {
+	ObserveToolExecution(ctx context.Context, execution ToolExecution)
+}
+
 // ToolRegistry manages tool registration and execution
 type ToolRegistry struct {
-	tools map[string]Tool
-	mu    sync.RWMutex
+	tools    map[string]Tool
+	order    []string // Registration order, so the LLM sees tools in a stable order
+	observer ToolObserver
+	mu       sync.RWMutex
 }
 
 // NewToolRegistry creates a new tool registry
@@ -39,30 +116,108 @@ func NewToolRegistry() *ToolRegistry {
 	}
 }
 
-// Register adds a tool to the registry
-func (r *ToolRegistry) Register(name string, definition ToolDefinition, handler ToolHandler) {
+// SetObserver sets the observer notified of every execution; nil removes it
+func (r *ToolRegistry) SetObserver(observer ToolObserver) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.tools[name] = Tool{
+	r.observer = observer
+}
+
+// Register adds a tool to the registry
+func (r *ToolRegistry) Register(name string, definition ToolDefinition, handler ToolHandler, opts ...ToolOption) {
+	tool := Tool{
 		Definition: definition,
 		Handler:    handler,
 	}
+	for _, opt := range opts {
+		opt(&tool)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.tools[name]; !exists {
+		r.order = append(r.order, name)
+	}
+	r.tools[name] = tool
+}
+
+// Unregister removes a tool from the registry
+func (r *ToolRegistry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.tools[name]; !exists {
+		return
+	}
+	delete(r.tools, name)
+	for i, n := range r.order {
+		if n == name {
+			r.order = append(r.order[:i], r.order[i+1:]...)
+			break
+		}
+	}
 }
 
-// GetDefinitions returns all tool definitions for the LLM
+// Get returns a registered tool by name
+func (r *ToolRegistry) Get(name string) (Tool, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tool, ok := r.tools[name]
+	return tool, ok
+}
+
+// GetDefinitions returns all tool definitions for the LLM in registration order
 func (r *ToolRegistry) GetDefinitions() []ToolDefinition {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
-	definitions := make([]ToolDefinition, 0, len(r.tools))
-	for _, tool := range r.tools {
-		definitions = append(definitions, tool.Definition)
+	definitions := make([]ToolDefinition, 0, len(r.order))
+	for _, name := range r.order {
+		definitions = append(definitions, r.tools[name].Definition)
 	}
 	return definitions
 }
 
-// Execute runs a tool by name
+// Execute runs a tool by name. Arguments are validated against the tool's
+// parameter schema first; invalid calls return the validation errors as a
+// failed result so the model can correct them, and the handler never runs.
+// Every call is reported to the observer, if one is set.
 func (r *ToolRegistry) Execute(ctx context.Context, userID uuid.UUID, toolName, arguments string) (*ToolResult, error) {
+	r.mu.RLock()
+	observer := r.observer
+	r.mu.RUnlock()
+
+	if observer == nil {
+		result, _, err := r.execute(ctx, userID, toolName, arguments)
+		return result, err
+	}
+
+	// Reported even if the handler panics; the executor recovers the panic
+	execution := ToolExecution{
+		UserID:    userID,
+		ToolName:  toolName,
+		Arguments: arguments,
+		Outcome:   ToolOutcomeError,
+		Error:     "tool panicked",
+	}
+	execution.Invocation, _ = ToolInvocationFromContext(ctx)
+	start := time.Now()
+	defer func() {
+		execution.Duration = time.Since(start)
+		observer.ObserveToolExecution(ctx, execution)
+	}()
+
+	result, outcome, err := r.execute(ctx, userID, toolName, arguments)
+	execution.Outcome, execution.Error = outcome, ""
+	if err != nil {
+		execution.Error = err.Error()
+	} else if result != nil {
+		execution.Error = result.Error
+	}
+	return result, err
+}
+
+// execute runs a tool and reports the outcome of the call
+func (r *ToolRegistry) execute(ctx context.Context, userID uuid.UUID, toolName, arguments string) (*ToolResult, string, error) {
 	r.mu.RLock()
 	tool, exists := r.tools[toolName]
 	r.mu.RUnlock()
@@ -71,10 +226,37 @@ func (r *ToolRegistry) Execute(ctx context.Context, userID uuid.UUID, toolName,
 		return &ToolResult{
 			Success: false,
 			Error:   fmt.Sprintf("unknown tool: %s", toolName),
-		}, nil
+		}, ToolOutcomeUnknownTool, nil
+	}
+
+	if err := ValidateToolArguments(tool.Definition.Parameters, arguments); err != nil {
+		result := &ToolResult{
+			Success: false,
+			Message: "The arguments did not match the tool's parameters. Fix the listed problems and call the tool again.",
+			Error:   err.Error(),
+		}
+		var argsErr *ToolArgumentsError
+		if errors.As(err, &argsErr) {
+			result.Data = map[string]interface{}{"validation_errors": argsErr.Errors}
+		}
+		return result, ToolOutcomeInvalidArguments, nil
 	}
 
-	return tool.Handler(ctx, userID, arguments)
+	// Fill in the tool name so handlers don't need to know their registered name
+	if inv, ok := ToolInvocationFromContext(ctx); ok {
+		inv.ToolName = toolName
+		ctx = WithToolInvocation(ctx, inv)
+	}
+
+	result, err := tool.Handler(ctx, userID, arguments)
+	switch {
+	case err != nil:
+		return result, ToolOutcomeError, err
+	case result != nil && result.Success:
+		return result, ToolOutcomeOK, nil
+	default:
+		return result, ToolOutcomeFailed, nil
+	}
 }
 
 // ExecuteToolCall is a convenience method for ToolCall structs
@@ -82,7 +264,8 @@ func (r *ToolRegistry) ExecuteToolCall(ctx context.Context, userID uuid.UUID, tc
 	return r.Execute(ctx, userID, tc.Function.Name, tc.Function.Arguments)
 }
 
-// Helper to parse JSON arguments into a struct
+// Helper to parse JSON arguments into a struct. Pair it with ToolParametersFor[T]
+// so the registry validates the arguments against the same type first.
 func ParseArgs[T any](arguments string) (T, error) {
 	var args T
 	err := json.Unmarshal([]byte(arguments), &args)
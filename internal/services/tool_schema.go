@@ -0,0 +1,305 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ToolArgumentError describes one problem with a tool call's arguments
+type ToolArgumentError struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// ToolArgumentsError is returned when tool arguments don't match the tool's
+// parameter schema. The model gets the individual errors so it can fix the call.
+type ToolArgumentsError struct {
+	Errors []ToolArgumentError
+}
+
+func (e *ToolArgumentsError) Error() string {
+	parts := make([]string, len(e.Errors))
+	for i, ae := range e.Errors {
+		parts[i] = ae.Path + ": " + ae.Message
+	}
+	return "invalid arguments: " + strings.Join(parts, "; ")
+}
+
+// ToolParametersFor derives the JSON schema of a tool's parameters from the
+// struct its handler parses the arguments into with ParseArgs, so the
+// definition the model sees can't drift from the Go type.
+//
+// Properties are named by their json tag. A `description` tag documents the
+// property, and a `jsonschema` tag holds comma separated options: "required"
+// and "enum=a|b|c". Enums and descriptions known only at runtime can be set
+// with setPropertyKeyword.
+func ToolParametersFor[T any]() map[string]interface{} {
+	return schemaForType(reflect.TypeOf((*T)(nil)).Elem())
+}
+
+func schemaForType(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaForType(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaForType(t.Elem())}
+	case reflect.Struct:
+		return schemaForStruct(t)
+	}
+	return map[string]interface{}{}
+}
+
+func schemaForStruct(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := []string{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := field.Name
+		if tag := field.Tag.Get("json"); tag != "" {
+			tagName, _, _ := strings.Cut(tag, ",")
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+
+		prop := schemaForType(field.Type)
+		if description := field.Tag.Get("description"); description != "" {
+			prop["description"] = description
+		}
+		for _, opt := range strings.Split(field.Tag.Get("jsonschema"), ",") {
+			switch {
+			case opt == "required":
+				required = append(required, name)
+			case strings.HasPrefix(opt, "enum="):
+				prop["enum"] = strings.Split(strings.TrimPrefix(opt, "enum="), "|")
+			}
+		}
+		properties[name] = prop
+	}
+
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"required":             required,
+		"additionalProperties": false,
+	}
+}
+
+// setPropertyKeyword sets a keyword such as enum or description on a
+// top-level property of a parameter schema
+func setPropertyKeyword(params map[string]interface{}, property, keyword string, value interface{}) {
+	properties, _ := params["properties"].(map[string]interface{})
+	if prop, ok := properties[property].(map[string]interface{}); ok {
+		prop[keyword] = value
+	}
+}
+
+// ValidateToolArguments checks raw tool arguments against a tool's parameter
+// schema. It supports the subset of JSON Schema tool definitions use: type,
+// properties, required, additionalProperties, items, enum, maxLength and
+// maxItems. Empty arguments are treated as an empty object.
+func ValidateToolArguments(schema map[string]interface{}, arguments string) error {
+	if len(schema) == 0 {
+		return nil
+	}
+	if strings.TrimSpace(arguments) == "" {
+		arguments = "{}"
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(arguments), &value); err != nil {
+		return &ToolArgumentsError{Errors: []ToolArgumentError{{Path: "arguments", Message: "must be valid JSON: " + err.Error()}}}
+	}
+
+	var errs []ToolArgumentError
+	validateSchemaValue("", schema, value, &errs)
+	if len(errs) > 0 {
+		return &ToolArgumentsError{Errors: errs}
+	}
+	return nil
+}
+
+func validateSchemaValue(path string, schema map[string]interface{}, value interface{}, errs *[]ToolArgumentError) {
+	fail := func(format string, args ...interface{}) {
+		p := path
+		if p == "" {
+			p = "arguments"
+		}
+		*errs = append(*errs, ToolArgumentError{Path: p, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if types := schemaStrings(schema["type"]); len(types) > 0 && !matchesAnyType(value, types) {
+		fail("must be %s, got %s", strings.Join(types, " or "), jsonTypeName(value))
+		return
+	}
+
+	if enum := schemaStrings(schema["enum"]); len(enum) > 0 {
+		s, ok := value.(string)
+		if !ok || !containsString(enum, s) {
+			fail("must be one of: %s", strings.Join(enum, ", "))
+		}
+	}
+
+	switch v := value.(type) {
+	case string:
+		if max, ok := schemaInt(schema["maxLength"]); ok && len([]rune(v)) > max {
+			fail("must be at most %d characters", max)
+		}
+
+	case []interface{}:
+		if max, ok := schemaInt(schema["maxItems"]); ok && len(v) > max {
+			fail("must have at most %d items", max)
+		}
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, item := range v {
+				validateSchemaValue(fmt.Sprintf("%s[%d]", path, i), items, item, errs)
+			}
+		}
+
+	case map[string]interface{}:
+		properties, _ := schema["properties"].(map[string]interface{})
+		for _, name := range schemaStrings(schema["required"]) {
+			if _, ok := v[name]; !ok {
+				*errs = append(*errs, ToolArgumentError{Path: joinArgumentPath(path, name), Message: "is required"})
+			}
+		}
+
+		// Sorted so the model sees errors in a stable order
+		names := make([]string, 0, len(v))
+		for name := range v {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if prop, ok := properties[name].(map[string]interface{}); ok {
+				validateSchemaValue(joinArgumentPath(path, name), prop, v[name], errs)
+				continue
+			}
+			switch extra := schema["additionalProperties"].(type) {
+			case bool:
+				if !extra {
+					*errs = append(*errs, ToolArgumentError{Path: joinArgumentPath(path, name), Message: "is not a known parameter"})
+				}
+			case map[string]interface{}:
+				validateSchemaValue(joinArgumentPath(path, name), extra, v[name], errs)
+			}
+		}
+	}
+}
+
+func joinArgumentPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func matchesAnyType(value interface{}, types []string) bool {
+	for _, t := range types {
+		switch t {
+		case "string":
+			if _, ok := value.(string); ok {
+				return true
+			}
+		case "boolean":
+			if _, ok := value.(bool); ok {
+				return true
+			}
+		case "number":
+			if _, ok := value.(float64); ok {
+				return true
+			}
+		case "integer":
+			if n, ok := value.(float64); ok && n == math.Trunc(n) {
+				return true
+			}
+		case "array":
+			if _, ok := value.([]interface{}); ok {
+				return true
+			}
+		case "object":
+			if _, ok := value.(map[string]interface{}); ok {
+				return true
+			}
+		case "null":
+			if value == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// schemaStrings reads a string or list of strings from a schema keyword, which
+// is []string in schemas built in Go and []interface{} in schemas parsed from JSON
+func schemaStrings(v interface{}) []string {
+	switch s := v.(type) {
+	case string:
+		return []string{s}
+	case []string:
+		return s
+	case []interface{}:
+		out := make([]string, 0, len(s))
+		for _, item := range s {
+			if str, ok := item.(string); ok {
+				out = append(out, str)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func schemaInt(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case float64:
+		return int(n), true
+	}
+	return 0, false
+}
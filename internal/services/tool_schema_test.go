@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+type testToolArgs struct {
+	Name    string            `json:"name" jsonschema:"required" description:"Who to greet"`
+	Mode    string            `json:"mode,omitempty" jsonschema:"enum=short|long"`
+	Count   int               `json:"count,omitempty"`
+	Tags    []string          `json:"tags,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Ignored string            `json:"-"`
+	private string
+}
+
+func TestToolParametersFor(t *testing.T) {
+	params := ToolParametersFor[testToolArgs]()
+
+	if params["type"] != "object" || params["additionalProperties"] != false {
+		t.Errorf("params = %v, want closed object", params)
+	}
+	if got := params["required"]; !reflect.DeepEqual(got, []string{"name"}) {
+		t.Errorf("required = %v, want [name]", got)
+	}
+
+	properties := params["properties"].(map[string]interface{})
+	if len(properties) != 5 {
+		t.Errorf("len(properties) = %d, want 5: %v", len(properties), properties)
+	}
+
+	want := map[string]interface{}{
+		"name":   map[string]interface{}{"type": "string", "description": "Who to greet"},
+		"mode":   map[string]interface{}{"type": "string", "enum": []string{"short", "long"}},
+		"count":  map[string]interface{}{"type": "integer"},
+		"tags":   map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		"labels": map[string]interface{}{"type": "object", "additionalProperties": map[string]interface{}{"type": "string"}},
+	}
+	for name, prop := range want {
+		if !reflect.DeepEqual(properties[name], prop) {
+			t.Errorf("properties[%s] = %v, want %v", name, properties[name], prop)
+		}
+	}
+
+	setPropertyKeyword(params, "name", "enum", []string{"alice"})
+	if properties["name"].(map[string]interface{})["enum"] == nil {
+		t.Error("setPropertyKeyword() did not set enum")
+	}
+}
+
+func TestValidateToolArguments(t *testing.T) {
+	params := ToolParametersFor[testToolArgs]()
+
+	tests := []struct {
+		name      string
+		arguments string
+		wantPaths []string
+	}{
+		{name: "valid", arguments: `{"name":"a","mode":"short","count":2,"tags":["x"],"labels":{"k":"v"}}`},
+		{name: "missing required", arguments: `{}`, wantPaths: []string{"name"}},
+		{name: "empty arguments", arguments: ``, wantPaths: []string{"name"}},
+		{name: "bad enum", arguments: `{"name":"a","mode":"medium"}`, wantPaths: []string{"mode"}},
+		{name: "wrong type", arguments: `{"name":1}`, wantPaths: []string{"name"}},
+		{name: "not an integer", arguments: `{"name":"a","count":1.5}`, wantPaths: []string{"count"}},
+		{name: "bad item", arguments: `{"name":"a","tags":["x",2]}`, wantPaths: []string{"tags[1]"}},
+		{name: "bad map value", arguments: `{"name":"a","labels":{"k":1}}`, wantPaths: []string{"labels.k"}},
+		{name: "unknown parameter", arguments: `{"name":"a","colour":"red"}`, wantPaths: []string{"colour"}},
+		{name: "malformed JSON", arguments: `{"name":`, wantPaths: []string{"arguments"}},
+		{name: "not an object", arguments: `[]`, wantPaths: []string{"arguments"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateToolArguments(params, tt.arguments)
+			if tt.wantPaths == nil {
+				if err != nil {
+					t.Errorf("ValidateToolArguments() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var argsErr *ToolArgumentsError
+			if !errors.As(err, &argsErr) {
+				t.Fatalf("ValidateToolArguments() error = %v, want ToolArgumentsError", err)
+			}
+			var paths []string
+			for _, e := range argsErr.Errors {
+				paths = append(paths, e.Path)
+			}
+			if !reflect.DeepEqual(paths, tt.wantPaths) {
+				t.Errorf("paths = %v, want %v (%v)", paths, tt.wantPaths, err)
+			}
+		})
+	}
+
+	t.Run("limits from understanding schema", func(t *testing.T) {
+		schema, err := ParseUnderstandingSchema([]byte(testReportTypes + `
+fields:
+  - {name: size, type: string, enum: [small, large]}
+  - {name: tools, type: list, max_items: 1, max_length: 3}
+`))
+		if err != nil {
+			t.Fatalf("ParseUnderstandingSchema() error = %v", err)
+		}
+		err = ValidateToolArguments(schema.ToolParameters(), `{"size":"about 30","tools":["abcd","b"]}`)
+		var argsErr *ToolArgumentsError
+		if !errors.As(err, &argsErr) || len(argsErr.Errors) != 3 {
+			t.Errorf("ValidateToolArguments() error = %v, want 3 errors", err)
+		}
+	})
+}
+
+func TestToolRegistryExecuteValidatesArguments(t *testing.T) {
+	registry := NewToolRegistry()
+	called := false
+	registry.Register("greet", ToolDefinition{Name: "greet", Parameters: ToolParametersFor[testToolArgs]()},
+		func(ctx context.Context, userID uuid.UUID, arguments string) (*ToolResult, error) {
+			called = true
+			return &ToolResult{Success: true}, nil
+		})
+
+	result, err := registry.Execute(context.Background(), uuid.New(), "greet", `{"mode":"medium"}`)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+	if called {
+		t.Error("handler ran with invalid arguments")
+	}
+	if result.Success || result.Data["validation_errors"] == nil {
+		t.Errorf("result = %+v, want validation errors", result)
+	}
+	if errs := result.Data["validation_errors"].([]ToolArgumentError); len(errs) != 2 {
+		t.Errorf("validation_errors = %v, want 2", errs)
+	}
+
+	result, err = registry.Execute(context.Background(), uuid.New(), "greet", `{"name":"a"}`)
+	if err != nil || !result.Success || !called {
+		t.Errorf("Execute() = %+v, %v, want handler to run", result, err)
+	}
+}
+
+func TestToolDefinitionsMatchInputs(t *testing.T) {
+	schema := DefaultUnderstandingSchema()
+
+	valid := map[string]string{
+		"update_understanding":     `{"field":"pain_points","operation":"remove","items":["returns"]}`,
+		"generate_business_report": `{"report_type":"quick_wins","focus_areas":["cost"]}`,
+	}
+	invalid := map[string]string{
+		"add_understanding":        `{"business_size":"about 30"}`,
+		"update_understanding":     `{"field":"industry","operation":"remove"}`,
+		"generate_business_report": `{"report_type":"weekly"}`,
+	}
+
+	for _, def := range GetAllToolDefinitions(schema) {
+		if args, ok := valid[def.Name]; ok {
+			if err := ValidateToolArguments(def.Parameters, args); err != nil {
+				t.Errorf("%s: ValidateToolArguments(%s) error = %v", def.Name, args, err)
+			}
+		}
+		if args, ok := invalid[def.Name]; ok {
+			if err := ValidateToolArguments(def.Parameters, args); err == nil {
+				t.Errorf("%s: ValidateToolArguments(%s) error = nil, want error", def.Name, args)
+			}
+		}
+	}
+}
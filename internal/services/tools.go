@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -13,17 +14,23 @@ import (
 
 // ToolService handles AI tool execution
 type ToolService struct {
-	queries   *database.Queries
-	registry  *ToolRegistry
-	analytics *AnalyticsService
+	queries       *database.Queries
+	registry      *ToolRegistry
+	analytics     *AnalyticsService
+	understanding *BusinessUnderstandingService
+	organizations *OrganizationService
+	schema        *UnderstandingSchema
 }
 
 // NewToolService creates a new tool service with registered tools
-func NewToolService(queries *database.Queries, analytics *AnalyticsService) *ToolService {
+func NewToolService(queries *database.Queries, analytics *AnalyticsService, schema *UnderstandingSchema) *ToolService {
 	ts := &ToolService{
-		queries:   queries,
-		registry:  NewToolRegistry(),
-		analytics: analytics,
+		queries:       queries,
+		registry:      NewToolRegistry(),
+		analytics:     analytics,
+		understanding: NewBusinessUnderstandingService(queries, schema),
+		organizations: NewOrganizationService(queries, schema),
+		schema:        schema,
 	}
 
 	// Register all tools - adding a new tool is just one line here
@@ -32,11 +39,16 @@ func NewToolService(queries *database.Queries, analytics *AnalyticsService) *Too
 	return ts
 }
 
+// understandingToolGroup serializes the tools that read and write the business
+// understanding, so calls in one step apply in order and reports see earlier updates
+const understandingToolGroup = "business_understanding"
+
 // registerTools registers all available tools
 // To add a new tool: add one line here + implement the handler
 func (s *ToolService) registerTools() {
-	s.registry.Register("add_understanding", GetAddUnderstandingToolDefinition(), s.handleAddUnderstanding)
-	s.registry.Register("generate_business_report", GetGenerateBusinessReportToolDefinition(), s.handleGenerateBusinessReport)
+	s.registry.Register("add_understanding", GetAddUnderstandingToolDefinition(s.schema), s.handleAddUnderstanding, WithToolGroup(understandingToolGroup))
+	s.registry.Register("update_understanding", GetUpdateUnderstandingToolDefinition(s.schema), s.handleUpdateUnderstanding, WithToolGroup(understandingToolGroup))
+	s.registry.Register("generate_business_report", GetGenerateBusinessReportToolDefinition(s.schema), s.handleGenerateBusinessReport, WithToolGroup(understandingToolGroup))
 }
 
 // GetRegistry returns the tool registry for external use
@@ -46,8 +58,8 @@ func (s *ToolService) GetRegistry() *ToolRegistry {
 
 // handleAddUnderstanding is the registry handler for add_understanding
 func (s *ToolService) handleAddUnderstanding(ctx context.Context, userID uuid.UUID, arguments string) (*ToolResult, error) {
-	var input AddUnderstandingInput
-	if err := json.Unmarshal([]byte(arguments), &input); err != nil {
+	input := NewBusinessContext()
+	if err := json.Unmarshal([]byte(arguments), input); err != nil {
 		return &ToolResult{Success: false, Error: fmt.Sprintf("invalid arguments: %v", err)}, nil
 	}
 
@@ -63,14 +75,38 @@ func (s *ToolService) handleAddUnderstanding(ctx context.Context, userID uuid.UU
 			"updated_fields": response.UpdatedFields,
 			"status":         response.Status,
 			"next_steps":     response.NextSteps,
+			"next_questions": response.NextQuestions,
+		},
+	}, nil
+}
+
+// handleUpdateUnderstanding is the registry handler for update_understanding
+func (s *ToolService) handleUpdateUnderstanding(ctx context.Context, userID uuid.UUID, arguments string) (*ToolResult, error) {
+	input, err := ParseArgs[UpdateUnderstandingInput](arguments)
+	if err != nil {
+		return &ToolResult{Success: false, Error: fmt.Sprintf("invalid arguments: %v", err)}, nil
+	}
+
+	response, err := s.ExecuteUpdateUnderstanding(ctx, userID, input)
+	if err != nil {
+		return &ToolResult{Success: false, Error: err.Error()}, nil
+	}
+
+	return &ToolResult{
+		Success: true,
+		Message: response.Message,
+		Data: map[string]interface{}{
+			"field":  response.Field,
+			"items":  response.Items,
+			"status": response.Status,
 		},
 	}, nil
 }
 
 // handleGenerateBusinessReport is the registry handler for generate_business_report
 func (s *ToolService) handleGenerateBusinessReport(ctx context.Context, userID uuid.UUID, arguments string) (*ToolResult, error) {
-	var input GenerateBusinessReportInput
-	if err := json.Unmarshal([]byte(arguments), &input); err != nil {
+	input, err := ParseArgs[GenerateBusinessReportInput](arguments)
+	if err != nil {
 		return &ToolResult{Success: false, Error: fmt.Sprintf("invalid arguments: %v", err)}, nil
 	}
 
@@ -86,46 +122,34 @@ func (s *ToolService) handleGenerateBusinessReport(ctx context.Context, userID u
 			"business_context": response.BusinessContext,
 			"report_type":      response.ReportType,
 			"status":           response.Status,
+			"next_questions":   response.NextQuestions,
 		},
 	}, nil
 }
 
-// AddUnderstandingInput represents the input for the add_understanding tool
-type AddUnderstandingInput struct {
-	UserName           string   `json:"user_name,omitempty"`
-	JobTitle           string   `json:"job_title,omitempty"`
-	BusinessName       string   `json:"business_name,omitempty"`
-	Industry           string   `json:"industry,omitempty"`
-	BusinessSize       string   `json:"business_size,omitempty"`
-	UserRole           string   `json:"user_role,omitempty"`
-	KeyWorkflows       []string `json:"key_workflows,omitempty"`
-	DailyActivities    []string `json:"daily_activities,omitempty"`
-	PainPoints         []string `json:"pain_points,omitempty"`
-	Bottlenecks        []string `json:"bottlenecks,omitempty"`
-	ManualTasks        []string `json:"manual_tasks,omitempty"`
-	AutomationGoals    []string `json:"automation_goals,omitempty"`
-	CurrentSoftware    []string `json:"current_software,omitempty"`
-	ExistingAutomation []string `json:"existing_automation,omitempty"`
-	AdditionalNotes    string   `json:"additional_notes,omitempty"`
-}
-
-// UnderstandingStatus represents the current understanding status
-type UnderstandingStatus struct {
-	UserName           bool `json:"user_name"`
-	JobTitle           bool `json:"job_title"`
-	BusinessName       bool `json:"business_name"`
-	Industry           bool `json:"industry"`
-	BusinessSize       bool `json:"business_size"`
-	UserRole           bool `json:"user_role"`
-	KeyWorkflowsCount  int  `json:"key_workflows_count"`
-	DailyActivitiesCount int `json:"daily_activities_count"`
-	PainPointsCount    int  `json:"pain_points_count"`
-	BottlenecksCount   int  `json:"bottlenecks_count"`
-	ManualTasksCount   int  `json:"manual_tasks_count"`
-	AutomationGoalsCount int `json:"automation_goals_count"`
-	CurrentSoftwareCount int `json:"current_software_count"`
-	ExistingAutomationCount int `json:"existing_automation_count"`
-	AdditionalNotes    bool `json:"additional_notes"`
+// Operations supported by the update_understanding tool
+const (
+	UnderstandingOpRemove     = "remove"
+	UnderstandingOpReplaceAll = "replace_all"
+	UnderstandingOpEditItem   = "edit_item"
+)
+
+// UpdateUnderstandingInput represents the input for the update_understanding tool
+type UpdateUnderstandingInput struct {
+	Field        string   `json:"field" jsonschema:"required" description:"The list field to change"`
+	Operation    string   `json:"operation" jsonschema:"required,enum=remove|replace_all|edit_item" description:"How to change the list"`
+	Items        []string `json:"items,omitempty" description:"Items to remove (remove) or the new contents of the list (replace_all)"`
+	OldValue     string   `json:"old_value,omitempty" description:"The existing item to change (edit_item)"`
+	NewValue     string   `json:"new_value,omitempty" description:"The corrected item (edit_item)"`
+	ConfirmClear bool     `json:"confirm_clear,omitempty" description:"Must be true if the operation would leave the list empty"`
+}
+
+// UpdateUnderstandingResponse represents the response from the update_understanding tool
+type UpdateUnderstandingResponse struct {
+	Message string              `json:"message"`
+	Field   string              `json:"field"`
+	Items   []string            `json:"items"`
+	Status  UnderstandingStatus `json:"status"`
 }
 
 // AddUnderstandingResponse represents the response from the add_understanding tool
@@ -134,117 +158,62 @@ type AddUnderstandingResponse struct {
 	UpdatedFields []string            `json:"updated_fields"`
 	Status        UnderstandingStatus `json:"status"`
 	NextSteps     string              `json:"next_steps"`
+	NextQuestions []PlannedQuestion   `json:"next_questions"` // Ranked, most valuable first
 }
 
-// BusinessContext represents the full business context for a user
-type BusinessContext struct {
-	UserName           string   `json:"user_name,omitempty"`
-	JobTitle           string   `json:"job_title,omitempty"`
-	BusinessName       string   `json:"business_name,omitempty"`
-	Industry           string   `json:"industry,omitempty"`
-	BusinessSize       string   `json:"business_size,omitempty"`
-	UserRole           string   `json:"user_role,omitempty"`
-	KeyWorkflows       []string `json:"key_workflows,omitempty"`
-	DailyActivities    []string `json:"daily_activities,omitempty"`
-	PainPoints         []string `json:"pain_points,omitempty"`
-	Bottlenecks        []string `json:"bottlenecks,omitempty"`
-	ManualTasks        []string `json:"manual_tasks,omitempty"`
-	AutomationGoals    []string `json:"automation_goals,omitempty"`
-	CurrentSoftware    []string `json:"current_software,omitempty"`
-	ExistingAutomation []string `json:"existing_automation,omitempty"`
-	AdditionalNotes    string   `json:"additional_notes,omitempty"`
-}
-
-// GetAddUnderstandingToolDefinition returns the tool definition for add_understanding
-func GetAddUnderstandingToolDefinition() ToolDefinition {
+// GetAddUnderstandingToolDefinition returns the tool definition for add_understanding.
+// Its parameters are generated from the understanding schema.
+func GetAddUnderstandingToolDefinition(schema *UnderstandingSchema) ToolDefinition {
 	return ToolDefinition{
 		Name: "add_understanding",
 		Description: `Capture and store information about the user's business context,
 workflows, pain points, and automation goals. Call this tool whenever the user
 shares information about their business. Each call incrementally adds to the
 existing understanding - you don't need to provide all fields at once.
+List fields are only ever appended to; use update_understanding to remove or
+correct list entries.
 
 Use this to build a comprehensive profile that helps recommend better agents
 and automations for the user's specific needs.`,
-		Parameters: map[string]interface{}{
-			"type": "object",
-			"properties": map[string]interface{}{
-				"user_name": map[string]interface{}{
-					"type":        "string",
-					"description": "The user's name",
-				},
-				"job_title": map[string]interface{}{
-					"type":        "string",
-					"description": "The user's job title (e.g., 'Marketing Manager', 'CEO', 'Software Engineer')",
-				},
-				"business_name": map[string]interface{}{
-					"type":        "string",
-					"description": "Name of the user's business or organization",
-				},
-				"industry": map[string]interface{}{
-					"type":        "string",
-					"description": "Industry or sector (e.g., 'e-commerce', 'healthcare', 'finance')",
-				},
-				"business_size": map[string]interface{}{
-					"type":        "string",
-					"description": "Company size: '1-10', '11-50', '51-200', '201-1000', or '1000+'",
-				},
-				"user_role": map[string]interface{}{
-					"type":        "string",
-					"description": "User's role in organization context (e.g., 'decision maker', 'implementer', 'end user')",
-				},
-				"key_workflows": map[string]interface{}{
-					"type":        "array",
-					"items":       map[string]interface{}{"type": "string"},
-					"description": "Key business workflows (e.g., 'lead qualification', 'content publishing')",
-				},
-				"daily_activities": map[string]interface{}{
-					"type":        "array",
-					"items":       map[string]interface{}{"type": "string"},
-					"description": "Regular daily activities the user performs",
-				},
-				"pain_points": map[string]interface{}{
-					"type":        "array",
-					"items":       map[string]interface{}{"type": "string"},
-					"description": "Current pain points or challenges",
-				},
-				"bottlenecks": map[string]interface{}{
-					"type":        "array",
-					"items":       map[string]interface{}{"type": "string"},
-					"description": "Process bottlenecks slowing things down",
-				},
-				"manual_tasks": map[string]interface{}{
-					"type":        "array",
-					"items":       map[string]interface{}{"type": "string"},
-					"description": "Manual or repetitive tasks that could be automated",
-				},
-				"automation_goals": map[string]interface{}{
-					"type":        "array",
-					"items":       map[string]interface{}{"type": "string"},
-					"description": "Desired automation outcomes or goals",
-				},
-				"current_software": map[string]interface{}{
-					"type":        "array",
-					"items":       map[string]interface{}{"type": "string"},
-					"description": "Software and tools currently in use",
-				},
-				"existing_automation": map[string]interface{}{
-					"type":        "array",
-					"items":       map[string]interface{}{"type": "string"},
-					"description": "Any existing automations or integrations",
-				},
-				"additional_notes": map[string]interface{}{
-					"type":        "string",
-					"description": "Any other relevant context or notes",
-				},
-			},
-			"required": []string{},
-		},
+		Parameters: schema.ToolParameters(),
 	}
 }
 
-// GetGenerateBusinessReportToolDefinition returns the tool definition for generate_business_report
-func GetGenerateBusinessReportToolDefinition() ToolDefinition {
+// GetUpdateUnderstandingToolDefinition returns the tool definition for update_understanding.
+// Its parameters are derived from UpdateUnderstandingInput.
+func GetUpdateUnderstandingToolDefinition(schema *UnderstandingSchema) ToolDefinition {
+	params := ToolParametersFor[UpdateUnderstandingInput]()
+	setPropertyKeyword(params, "field", "enum", schema.ListFieldNames())
+
+	return ToolDefinition{
+		Name: "update_understanding",
+		Description: `Correct a list in the user's business understanding. Use this when the user
+says something recorded earlier is wrong or no longer true, e.g. a pain point has
+been solved or they stopped using a piece of software.
+
+Operations:
+- remove: remove the given items from the list
+- replace_all: replace the whole list with the given items
+- edit_item: change one existing item (old_value) to a new value (new_value)
+
+Emptying a list is refused unless confirm_clear is true. Only set confirm_clear
+when the user has explicitly asked for the whole list to be cleared.`,
+		Parameters: params,
+	}
+}
+
+// GetGenerateBusinessReportToolDefinition returns the tool definition for generate_business_report.
+// Its parameters are derived from GenerateBusinessReportInput.
+func GetGenerateBusinessReportToolDefinition(schema *UnderstandingSchema) ToolDefinition {
+	var typeDescriptions []string
+	for _, rt := range schema.ReportTypes {
+		typeDescriptions = append(typeDescriptions, fmt.Sprintf("'%s' for %s", rt.Name, rt.Description))
+	}
+
+	params := ToolParametersFor[GenerateBusinessReportInput]()
+	setPropertyKeyword(params, "report_type", "enum", schema.ReportTypeNames())
+	setPropertyKeyword(params, "report_type", "description", "Type of report to generate: "+strings.Join(typeDescriptions, ", "))
+
 	return ToolDefinition{
 		Name: "generate_business_report",
 		Description: `Generate a comprehensive AI integration report for the user's business.
@@ -254,148 +223,143 @@ specific workflows and pain points.
 
 Only call this tool when you have gathered sufficient understanding about the user's
 business context, workflows, and goals.`,
-		Parameters: map[string]interface{}{
-			"type": "object",
-			"properties": map[string]interface{}{
-				"report_type": map[string]interface{}{
-					"type":        "string",
-					"enum":        []string{"executive_summary", "detailed", "quick_wins"},
-					"description": "Type of report to generate: 'executive_summary' for high-level overview, 'detailed' for comprehensive analysis, 'quick_wins' for immediate action items",
-				},
-				"focus_areas": map[string]interface{}{
-					"type":        "array",
-					"items":       map[string]interface{}{"type": "string"},
-					"description": "Specific areas to focus the report on (e.g., 'automation', 'cost reduction', 'efficiency')",
-				},
-			},
-			"required": []string{"report_type"},
-		},
+		Parameters: params,
 	}
 }
 
 // GetAllToolDefinitions returns all available tool definitions
-func GetAllToolDefinitions() []ToolDefinition {
+func GetAllToolDefinitions(schema *UnderstandingSchema) []ToolDefinition {
 	return []ToolDefinition{
-		GetAddUnderstandingToolDefinition(),
-		GetGenerateBusinessReportToolDefinition(),
+		GetAddUnderstandingToolDefinition(schema),
+		GetUpdateUnderstandingToolDefinition(schema),
+		GetGenerateBusinessReportToolDefinition(schema),
 	}
 }
 
-// ExecuteAddUnderstanding executes the add_understanding tool
-func (s *ToolService) ExecuteAddUnderstanding(ctx context.Context, userID uuid.UUID, input AddUnderstandingInput) (*AddUnderstandingResponse, error) {
+// ExecuteAddUnderstanding executes the add_understanding tool. Text fields are
+// overwritten and list fields are appended to, skipping duplicates.
+func (s *ToolService) ExecuteAddUnderstanding(ctx context.Context, userID uuid.UUID, input *BusinessContext) (*AddUnderstandingResponse, error) {
 	// Check if any data was provided
-	if !hasAnyInput(input) {
+	if input.IsEmpty() {
 		return nil, fmt.Errorf("please provide at least one field to update")
 	}
+	if err := s.schema.Validate(input, FieldScopePersonal); err != nil {
+		return nil, err
+	}
 
-	// Get existing understanding or create new
-	existing, err := s.queries.GetBusinessUnderstanding(ctx, userID)
-	if err != nil && err != pgx.ErrNoRows {
-		return nil, fmt.Errorf("failed to get existing understanding: %w", err)
-	}
-
-	// Merge arrays - deduplicate and append
-	keyWorkflows := mergeStringArrays(jsonToStringArray(existing.KeyWorkflows), input.KeyWorkflows)
-	dailyActivities := mergeStringArrays(jsonToStringArray(existing.DailyActivities), input.DailyActivities)
-	painPoints := mergeStringArrays(jsonToStringArray(existing.PainPoints), input.PainPoints)
-	bottlenecks := mergeStringArrays(jsonToStringArray(existing.Bottlenecks), input.Bottlenecks)
-	manualTasks := mergeStringArrays(jsonToStringArray(existing.ManualTasks), input.ManualTasks)
-	automationGoals := mergeStringArrays(jsonToStringArray(existing.AutomationGoals), input.AutomationGoals)
-	currentSoftware := mergeStringArrays(jsonToStringArray(existing.CurrentSoftware), input.CurrentSoftware)
-	existingAutomation := mergeStringArrays(jsonToStringArray(existing.ExistingAutomation), input.ExistingAutomation)
-
-	// Upsert the understanding
-	params := database.UpsertBusinessUnderstandingParams{
-		UserID:             userID,
-		UserName:           stringPtrOrNil(input.UserName),
-		JobTitle:           stringPtrOrNil(input.JobTitle),
-		BusinessName:       stringPtrOrNil(input.BusinessName),
-		Industry:           stringPtrOrNil(input.Industry),
-		BusinessSize:       stringPtrOrNil(input.BusinessSize),
-		UserRole:           stringPtrOrNil(input.UserRole),
-		KeyWorkflows:       stringArrayToJSON(keyWorkflows),
-		DailyActivities:    stringArrayToJSON(dailyActivities),
-		PainPoints:         stringArrayToJSON(painPoints),
-		Bottlenecks:        stringArrayToJSON(bottlenecks),
-		ManualTasks:        stringArrayToJSON(manualTasks),
-		AutomationGoals:    stringArrayToJSON(automationGoals),
-		CurrentSoftware:    stringArrayToJSON(currentSoftware),
-		ExistingAutomation: stringArrayToJSON(existingAutomation),
-		AdditionalNotes:    stringPtrOrNil(input.AdditionalNotes),
-	}
-
-	understanding, err := s.queries.UpsertBusinessUnderstanding(ctx, params)
+	// Merge into the existing understanding, recording which chat and tool call set these fields
+	var after *BusinessContext
+	_, err := s.understanding.WriteFromTool(ctx, userID, func(before *BusinessContext, _ bool) (*BusinessContext, error) {
+		after = mergeUnderstandingInput(before, input)
+		return after, nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to update understanding: %w", err)
+		return nil, err
 	}
 
 	// Track which fields were updated
-	updatedFields := getUpdatedFields(input)
+	updatedFields := getUpdatedFields(s.schema, input)
 
-	// Build status
-	status := buildUnderstandingStatus(understanding)
+	// Build status and rank what to ask next, weighted for the report this session is working towards
+	reportType, asked := s.interviewState(ctx, userID)
+	status := s.schema.Status(after)
+	questions := s.schema.PlanQuestions(after, reportType, asked, maxPlannedQuestions)
+	s.saveInterviewState(ctx, userID, reportType, asked, questions)
 
 	// Track business context added event
 	if s.analytics != nil {
-		completeness := CalculateCompletenessPercentage(status)
-		s.analytics.TrackBusinessContextAdded(userID, updatedFields, completeness)
+		s.analytics.TrackBusinessContextAdded(userID, updatedFields, status.Completeness)
 
 		// Identify company for B2B group analytics (PostHog recommendation)
-		if input.BusinessName != "" || input.Industry != "" || input.BusinessSize != "" {
-			businessName := input.BusinessName
-			if businessName == "" && understanding.BusinessName != nil {
-				businessName = *understanding.BusinessName
-			}
-			industry := input.Industry
-			if industry == "" && understanding.Industry != nil {
-				industry = *understanding.Industry
-			}
-			size := input.BusinessSize
-			if size == "" && understanding.BusinessSize != nil {
-				size = *understanding.BusinessSize
-			}
-			s.analytics.IdentifyCompany(userID, businessName, industry, size)
+		if input.Get("business_name") != "" || input.Get("industry") != "" || input.Get("business_size") != "" {
+			s.analytics.IdentifyCompany(userID, after.Get("business_name"), after.Get("industry"), after.Get("business_size"))
 		}
 	}
 
 	// Generate next steps message
-	nextSteps := generateNextStepsMessage(status)
+	nextSteps := generateNextStepsMessage(questions)
 
 	return &AddUnderstandingResponse{
 		Message:       fmt.Sprintf("Updated understanding with: %s. I now have a better picture of your business context.", strings.Join(updatedFields, ", ")),
 		UpdatedFields: updatedFields,
 		Status:        status,
 		NextSteps:     nextSteps,
+		NextQuestions: questions,
+	}, nil
+}
+
+// ExecuteUpdateUnderstanding executes the update_understanding tool
+func (s *ToolService) ExecuteUpdateUnderstanding(ctx context.Context, userID uuid.UUID, input UpdateUnderstandingInput) (*UpdateUnderstandingResponse, error) {
+	if field, ok := s.schema.Field(input.Field); !ok || field.Type != FieldTypeList {
+		return nil, fmt.Errorf("unknown field %q; must be one of: %s", input.Field, strings.Join(s.schema.ListFieldNames(), ", "))
+	}
+
+	var after *BusinessContext
+	var updated []string
+	_, err := s.understanding.WriteFromTool(ctx, userID, func(before *BusinessContext, found bool) (*BusinessContext, error) {
+		if !found {
+			return nil, fmt.Errorf("there is no business understanding to update yet")
+		}
+
+		var err error
+		updated, err = applyListOperation(before.List(input.Field), input)
+		if err != nil {
+			return nil, err
+		}
+
+		after = before.Clone()
+		after.SetList(input.Field, updated)
+		if err := s.schema.Validate(after, FieldScopePersonal); err != nil {
+			return nil, err
+		}
+		return after, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &UpdateUnderstandingResponse{
+		Message: fmt.Sprintf("Updated %s (%s). It now has %d item(s).", input.Field, input.Operation, len(updated)),
+		Field:   input.Field,
+		Items:   updated,
+		Status:  s.schema.Status(after),
 	}, nil
+
 }
 
 // GenerateBusinessReportInput represents the input for the generate_business_report tool
 type GenerateBusinessReportInput struct {
-	ReportType  string   `json:"report_type"`
-	FocusAreas  []string `json:"focus_areas,omitempty"`
+	ReportType string   `json:"report_type" jsonschema:"required"`
+	FocusAreas []string `json:"focus_areas,omitempty" description:"Specific areas to focus the report on (e.g., 'automation', 'cost reduction', 'efficiency')"`
 }
 
 // BusinessReportResponse represents the response from the generate_business_report tool
 type BusinessReportResponse struct {
-	Message         string          `json:"message"`
-	BusinessContext *BusinessContext `json:"business_context"`
-	ReportType      string          `json:"report_type"`
-	Status          string          `json:"status"`
+	Message         string            `json:"message"`
+	BusinessContext *BusinessContext  `json:"business_context"`
+	ReportType      string            `json:"report_type"`
+	Status          string            `json:"status"`
+	NextQuestions   []PlannedQuestion `json:"next_questions,omitempty"`
 }
 
 // ExecuteGenerateBusinessReport executes the generate_business_report tool (stub)
 func (s *ToolService) ExecuteGenerateBusinessReport(ctx context.Context, userID uuid.UUID, input GenerateBusinessReportInput) (*BusinessReportResponse, error) {
+	_, asked := s.interviewState(ctx, userID)
+
 	// Get the current understanding
 	understanding, err := s.queries.GetBusinessUnderstanding(ctx, userID)
-	if err == pgx.ErrNoRows {
+	if errors.Is(err, pgx.ErrNoRows) {
 		// Track report request with insufficient data
 		if s.analytics != nil {
 			s.analytics.TrackBusinessReportRequested(userID, input.ReportType, "insufficient_data", 0)
 		}
+		questions := s.schema.PlanQuestions(NewBusinessContext(), input.ReportType, asked, maxPlannedQuestions)
+		s.saveInterviewState(ctx, userID, input.ReportType, asked, questions)
 		return &BusinessReportResponse{
-			Message:    "I don't have enough information about your business yet. Let's start by learning more about your company, your role, and what challenges you're facing.",
-			Status:     "insufficient_data",
-			ReportType: input.ReportType,
+			Message:       "I don't have enough information about your business yet. Let's start by learning more about your company, your role, and what challenges you're facing.",
+			Status:        "insufficient_data",
+			ReportType:    input.ReportType,
+			NextQuestions: questions,
 		}, nil
 	}
 	if err != nil {
@@ -403,41 +367,31 @@ func (s *ToolService) ExecuteGenerateBusinessReport(ctx context.Context, userID
 	}
 
 	// Build business context
-	businessContext := &BusinessContext{
-		UserName:           derefStringPtr(understanding.UserName),
-		JobTitle:           derefStringPtr(understanding.JobTitle),
-		BusinessName:       derefStringPtr(understanding.BusinessName),
-		Industry:           derefStringPtr(understanding.Industry),
-		BusinessSize:       derefStringPtr(understanding.BusinessSize),
-		UserRole:           derefStringPtr(understanding.UserRole),
-		KeyWorkflows:       jsonToStringArray(understanding.KeyWorkflows),
-		DailyActivities:    jsonToStringArray(understanding.DailyActivities),
-		PainPoints:         jsonToStringArray(understanding.PainPoints),
-		Bottlenecks:        jsonToStringArray(understanding.Bottlenecks),
-		ManualTasks:        jsonToStringArray(understanding.ManualTasks),
-		AutomationGoals:    jsonToStringArray(understanding.AutomationGoals),
-		CurrentSoftware:    jsonToStringArray(understanding.CurrentSoftware),
-		ExistingAutomation: jsonToStringArray(understanding.ExistingAutomation),
-		AdditionalNotes:    derefStringPtr(understanding.AdditionalNotes),
-	}
-
-	// Check if we have enough data for a meaningful report
-	status := buildUnderstandingStatus(understanding)
-	completeness := CalculateCompletenessPercentage(status)
-
-	if !hasMinimumDataForReport(status) {
+	businessContext := understandingToBusinessContext(understanding)
+
+	// Check if we have enough data for a meaningful report, weighting fields for the requested type
+	status := s.schema.StatusFor(businessContext, input.ReportType)
+	completeness := status.Completeness
+
+	if !hasMinimumDataForReport(s.schema, status) {
 		// Track report request with partial data
 		if s.analytics != nil {
 			s.analytics.TrackBusinessReportRequested(userID, input.ReportType, "needs_more_data", completeness)
 		}
+		questions := s.schema.PlanQuestions(businessContext, input.ReportType, asked, maxPlannedQuestions)
+		s.saveInterviewState(ctx, userID, input.ReportType, asked, questions)
 		return &BusinessReportResponse{
-			Message:         generateDataGapsMessage(status),
+			Message:         generateDataGapsMessage(s.schema, status),
 			BusinessContext: businessContext,
 			Status:          "needs_more_data",
 			ReportType:      input.ReportType,
+			NextQuestions:   questions,
 		}, nil
 	}
 
+	// Remember the report type so later questions are ranked for it
+	s.saveInterviewState(ctx, userID, input.ReportType, asked, nil)
+
 	// Track successful report generation - this is the key activation event!
 	if s.analytics != nil {
 		s.analytics.TrackBusinessReportRequested(userID, input.ReportType, "ready_for_report", completeness)
@@ -453,33 +407,24 @@ func (s *ToolService) ExecuteGenerateBusinessReport(ctx context.Context, userID
 	}, nil
 }
 
-// GetBusinessContext returns the current business context for a user
+// GetBusinessContext returns the current business context for a user, with their
+// personal understanding layered on top of their organization's
 func (s *ToolService) GetBusinessContext(ctx context.Context, userID uuid.UUID) (*BusinessContext, error) {
+	var personal *BusinessContext
 	understanding, err := s.queries.GetBusinessUnderstanding(ctx, userID)
-	if err == pgx.ErrNoRows {
-		return nil, nil
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get business understanding: %w", err)
+	}
+	if err == nil {
+		personal = understandingToBusinessContext(understanding)
 	}
+
+	org, err := s.organizations.ContextForUser(ctx, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get business understanding: %w", err)
+		return nil, err
 	}
 
-	return &BusinessContext{
-		UserName:           derefStringPtr(understanding.UserName),
-		JobTitle:           derefStringPtr(understanding.JobTitle),
-		BusinessName:       derefStringPtr(understanding.BusinessName),
-		Industry:           derefStringPtr(understanding.Industry),
-		BusinessSize:       derefStringPtr(understanding.BusinessSize),
-		UserRole:           derefStringPtr(understanding.UserRole),
-		KeyWorkflows:       jsonToStringArray(understanding.KeyWorkflows),
-		DailyActivities:    jsonToStringArray(understanding.DailyActivities),
-		PainPoints:         jsonToStringArray(understanding.PainPoints),
-		Bottlenecks:        jsonToStringArray(understanding.Bottlenecks),
-		ManualTasks:        jsonToStringArray(understanding.ManualTasks),
-		AutomationGoals:    jsonToStringArray(understanding.AutomationGoals),
-		CurrentSoftware:    jsonToStringArray(understanding.CurrentSoftware),
-		ExistingAutomation: jsonToStringArray(understanding.ExistingAutomation),
-		AdditionalNotes:    derefStringPtr(understanding.AdditionalNotes),
-	}, nil
+	return mergeBusinessContexts(org, personal), nil
 }
 
 // BuildSystemPromptContext generates a context string to include in the system prompt
@@ -492,143 +437,41 @@ func (s *ToolService) BuildSystemPromptContext(ctx context.Context, userID uuid.
 		return "", nil
 	}
 
-	return buildBusinessContextPrompt(businessContext), nil
+	return buildBusinessContextPrompt(s.schema, businessContext), nil
 }
 
 // Helper functions
 
-func hasAnyInput(input AddUnderstandingInput) bool {
-	return input.UserName != "" ||
-		input.JobTitle != "" ||
-		input.BusinessName != "" ||
-		input.Industry != "" ||
-		input.BusinessSize != "" ||
-		input.UserRole != "" ||
-		len(input.KeyWorkflows) > 0 ||
-		len(input.DailyActivities) > 0 ||
-		len(input.PainPoints) > 0 ||
-		len(input.Bottlenecks) > 0 ||
-		len(input.ManualTasks) > 0 ||
-		len(input.AutomationGoals) > 0 ||
-		len(input.CurrentSoftware) > 0 ||
-		len(input.ExistingAutomation) > 0 ||
-		input.AdditionalNotes != ""
-}
-
-func getUpdatedFields(input AddUnderstandingInput) []string {
-	var fields []string
-	if input.UserName != "" {
-		fields = append(fields, "user_name")
-	}
-	if input.JobTitle != "" {
-		fields = append(fields, "job_title")
-	}
-	if input.BusinessName != "" {
-		fields = append(fields, "business_name")
-	}
-	if input.Industry != "" {
-		fields = append(fields, "industry")
-	}
-	if input.BusinessSize != "" {
-		fields = append(fields, "business_size")
-	}
-	if input.UserRole != "" {
-		fields = append(fields, "user_role")
-	}
-	if len(input.KeyWorkflows) > 0 {
-		fields = append(fields, "key_workflows")
-	}
-	if len(input.DailyActivities) > 0 {
-		fields = append(fields, "daily_activities")
-	}
-	if len(input.PainPoints) > 0 {
-		fields = append(fields, "pain_points")
-	}
-	if len(input.Bottlenecks) > 0 {
-		fields = append(fields, "bottlenecks")
-	}
-	if len(input.ManualTasks) > 0 {
-		fields = append(fields, "manual_tasks")
-	}
-	if len(input.AutomationGoals) > 0 {
-		fields = append(fields, "automation_goals")
-	}
-	if len(input.CurrentSoftware) > 0 {
-		fields = append(fields, "current_software")
-	}
-	if len(input.ExistingAutomation) > 0 {
-		fields = append(fields, "existing_automation")
+// mergeUnderstandingInput returns a copy of existing with add_understanding input merged in
+func mergeUnderstandingInput(existing, input *BusinessContext) *BusinessContext {
+	result := existing.Clone()
+	for name, value := range input.Strings {
+		if value != "" {
+			result.Set(name, value)
+		}
 	}
-	if input.AdditionalNotes != "" {
-		fields = append(fields, "additional_notes")
+	for name, items := range input.Lists {
+		result.SetList(name, mergeStringArrays(result.List(name), items))
 	}
-	return fields
+	return result
 }
 
-func buildUnderstandingStatus(u database.BusinessUnderstanding) UnderstandingStatus {
-	return UnderstandingStatus{
-		UserName:             u.UserName != nil && *u.UserName != "",
-		JobTitle:             u.JobTitle != nil && *u.JobTitle != "",
-		BusinessName:         u.BusinessName != nil && *u.BusinessName != "",
-		Industry:             u.Industry != nil && *u.Industry != "",
-		BusinessSize:         u.BusinessSize != nil && *u.BusinessSize != "",
-		UserRole:             u.UserRole != nil && *u.UserRole != "",
-		KeyWorkflowsCount:    len(jsonToStringArray(u.KeyWorkflows)),
-		DailyActivitiesCount: len(jsonToStringArray(u.DailyActivities)),
-		PainPointsCount:      len(jsonToStringArray(u.PainPoints)),
-		BottlenecksCount:     len(jsonToStringArray(u.Bottlenecks)),
-		ManualTasksCount:     len(jsonToStringArray(u.ManualTasks)),
-		AutomationGoalsCount: len(jsonToStringArray(u.AutomationGoals)),
-		CurrentSoftwareCount: len(jsonToStringArray(u.CurrentSoftware)),
-		ExistingAutomationCount: len(jsonToStringArray(u.ExistingAutomation)),
-		AdditionalNotes:      u.AdditionalNotes != nil && *u.AdditionalNotes != "",
+// getUpdatedFields returns the fields set in input, in schema order
+func getUpdatedFields(schema *UnderstandingSchema, input *BusinessContext) []string {
+	var fields []string
+	for _, f := range schema.Fields {
+		if input.Get(f.Name) != "" || len(input.List(f.Name)) > 0 {
+			fields = append(fields, f.Name)
+		}
 	}
+	return fields
 }
 
-func generateNextStepsMessage(status UnderstandingStatus) string {
+// generateNextStepsMessage phrases the top planned questions
+func generateNextStepsMessage(questions []PlannedQuestion) string {
 	var missing []string
-
-	// Priority 1: Basic identity
-	if !status.UserName {
-		missing = append(missing, "your name")
-	}
-	if !status.BusinessName {
-		missing = append(missing, "your business name")
-	}
-	if !status.Industry {
-		missing = append(missing, "your industry")
-	}
-
-	// Priority 2: Role and context
-	if !status.JobTitle {
-		missing = append(missing, "your job title")
-	}
-	if !status.UserRole {
-		missing = append(missing, "your role in the organization (decision maker, implementer, end user)")
-	}
-	if !status.BusinessSize {
-		missing = append(missing, "your company size")
-	}
-
-	// Priority 3: Workflows and operations
-	if status.KeyWorkflowsCount == 0 {
-		missing = append(missing, "your key business workflows")
-	}
-	if status.DailyActivitiesCount == 0 {
-		missing = append(missing, "your daily activities")
-	}
-
-	// Priority 4: Pain points and goals
-	if status.PainPointsCount == 0 {
-		missing = append(missing, "your current pain points or challenges")
-	}
-	if status.AutomationGoalsCount == 0 {
-		missing = append(missing, "your automation goals")
-	}
-
-	// Priority 5: Technical context
-	if status.CurrentSoftwareCount == 0 {
-		missing = append(missing, "the software tools you currently use")
+	for _, q := range questions {
+		missing = append(missing, q.Question)
 	}
 
 	if len(missing) == 0 {
@@ -644,76 +487,69 @@ func generateNextStepsMessage(status UnderstandingStatus) string {
 		missing[len(missing)-1])
 }
 
-func hasMinimumDataForReport(status UnderstandingStatus) bool {
-	// Require at least: business name OR industry, AND some workflows or pain points
-	hasBasicContext := status.BusinessName || status.Industry
-	hasWorkContext := status.KeyWorkflowsCount > 0 || status.PainPointsCount > 0 || status.AutomationGoalsCount > 0
-	return hasBasicContext && hasWorkContext
+// hasMinimumDataForReport checks the schema's report requirements
+func hasMinimumDataForReport(schema *UnderstandingSchema, status UnderstandingStatus) bool {
+	return len(unmetReportRequirements(schema, status)) == 0
 }
 
-func generateDataGapsMessage(status UnderstandingStatus) string {
+func generateDataGapsMessage(schema *UnderstandingSchema, status UnderstandingStatus) string {
 	var gaps []string
-
-	if !status.BusinessName && !status.Industry {
-		gaps = append(gaps, "your business or industry")
-	}
-	if status.KeyWorkflowsCount == 0 && status.PainPointsCount == 0 {
-		gaps = append(gaps, "your workflows or challenges")
-	}
-	if status.AutomationGoalsCount == 0 {
-		gaps = append(gaps, "your automation goals")
+	for _, req := range unmetReportRequirements(schema, status) {
+		gaps = append(gaps, req.Description)
 	}
 
 	return fmt.Sprintf("I need more information to generate a meaningful report. Could you tell me about %s?",
 		strings.Join(gaps, ", "))
 }
 
-func buildBusinessContextPrompt(ctx *BusinessContext) string {
+func unmetReportRequirements(schema *UnderstandingSchema, status UnderstandingStatus) []ReportRequirement {
+	var unmet []ReportRequirement
+	for _, req := range schema.ReportRequirements {
+		met := false
+		for _, name := range req.AnyOf {
+			if status.Filled(name) {
+				met = true
+				break
+			}
+		}
+		if !met {
+			unmet = append(unmet, req)
+		}
+	}
+	return unmet
+}
+
+// buildBusinessContextPrompt renders every field with a prompt label. Inline fields
+// share the identity line; the rest get a line each.
+func buildBusinessContextPrompt(schema *UnderstandingSchema, ctx *BusinessContext) string {
 	if ctx == nil {
 		return ""
 	}
 
-	var parts []string
-
-	// User and business identity
-	if ctx.UserName != "" || ctx.BusinessName != "" || ctx.Industry != "" {
-		var identity []string
-		if ctx.UserName != "" {
-			identity = append(identity, fmt.Sprintf("User: %s", ctx.UserName))
-		}
-		if ctx.JobTitle != "" {
-			identity = append(identity, fmt.Sprintf("Role: %s", ctx.JobTitle))
+	var identity, parts []string
+	for _, f := range schema.Fields {
+		if f.PromptLabel == "" {
+			continue
 		}
-		if ctx.BusinessName != "" {
-			identity = append(identity, fmt.Sprintf("Company: %s", ctx.BusinessName))
-		}
-		if ctx.Industry != "" {
-			identity = append(identity, fmt.Sprintf("Industry: %s", ctx.Industry))
+
+		value := ctx.Get(f.Name)
+		if f.Type == FieldTypeList {
+			value = strings.Join(ctx.List(f.Name), ", ")
 		}
-		if ctx.BusinessSize != "" {
-			identity = append(identity, fmt.Sprintf("Size: %s employees", ctx.BusinessSize))
+		if value == "" {
+			continue
 		}
-		parts = append(parts, strings.Join(identity, " | "))
-	}
 
-	// Key workflows
-	if len(ctx.KeyWorkflows) > 0 {
-		parts = append(parts, fmt.Sprintf("Key Workflows: %s", strings.Join(ctx.KeyWorkflows, ", ")))
-	}
-
-	// Pain points
-	if len(ctx.PainPoints) > 0 {
-		parts = append(parts, fmt.Sprintf("Pain Points: %s", strings.Join(ctx.PainPoints, ", ")))
-	}
-
-	// Automation goals
-	if len(ctx.AutomationGoals) > 0 {
-		parts = append(parts, fmt.Sprintf("Automation Goals: %s", strings.Join(ctx.AutomationGoals, ", ")))
+		line := fmt.Sprintf("%s: %s", f.PromptLabel, fmt.Sprintf(f.PromptFormat, value))
+		if f.PromptInline {
+			identity = append(identity, line)
+		} else {
+			parts = append(parts, line)
+		}
 	}
 
-	// Current software
-	if len(ctx.CurrentSoftware) > 0 {
-		parts = append(parts, fmt.Sprintf("Current Tools: %s", strings.Join(ctx.CurrentSoftware, ", ")))
+	if len(identity) > 0 {
+		parts = append([]string{strings.Join(identity, " | ")}, parts...)
 	}
 
 	if len(parts) == 0 {
@@ -728,25 +564,82 @@ Use this context to provide personalized recommendations and tailor your respons
 `, strings.Join(parts, "\n"))
 }
 
-// Utility functions for JSON array handling
+// applyListOperation applies an update_understanding operation to a list and returns
+// the new list. It never modifies list. Items are matched case-insensitively so the
+// model doesn't need to reproduce the stored text exactly.
+func applyListOperation(list []string, input UpdateUnderstandingInput) ([]string, error) {
+	var result []string
 
-func jsonToStringArray(data []byte) []string {
-	if len(data) == 0 {
-		return []string{}
+	switch input.Operation {
+	case UnderstandingOpRemove:
+		if len(input.Items) == 0 {
+			return nil, fmt.Errorf("items is required for remove")
+		}
+		remove := make(map[string]bool, len(input.Items))
+		for _, item := range input.Items {
+			if indexOfItem(list, item) < 0 {
+				return nil, fmt.Errorf("%q is not in %s", item, input.Field)
+			}
+			remove[normalizeItem(item)] = true
+		}
+		result = make([]string, 0, len(list))
+		for _, item := range list {
+			if !remove[normalizeItem(item)] {
+				result = append(result, item)
+			}
+		}
+
+	case UnderstandingOpReplaceAll:
+		result = mergeStringArrays([]string{}, trimItems(input.Items))
+
+	case UnderstandingOpEditItem:
+		newValue := strings.TrimSpace(input.NewValue)
+		if input.OldValue == "" || newValue == "" {
+			return nil, fmt.Errorf("old_value and new_value are required for edit_item")
+		}
+		i := indexOfItem(list, input.OldValue)
+		if i < 0 {
+			return nil, fmt.Errorf("%q is not in %s", input.OldValue, input.Field)
+		}
+		result = make([]string, 0, len(list))
+		result = append(result, list[:i]...)
+		result = append(result, newValue)
+		result = append(result, list[i+1:]...)
+		result = mergeStringArrays([]string{}, result)
+
+	default:
+		return nil, fmt.Errorf("unknown operation %q; must be one of: remove, replace_all, edit_item", input.Operation)
+	}
+
+	if len(result) == 0 && len(list) > 0 && !input.ConfirmClear {
+		return nil, fmt.Errorf("this would remove every item from %s; set confirm_clear to true only if the user asked to clear it", input.Field)
 	}
-	var arr []string
-	if err := json.Unmarshal(data, &arr); err != nil {
-		return []string{}
+
+	return result, nil
+}
+
+func indexOfItem(list []string, item string) int {
+	target := normalizeItem(item)
+	for i, existing := range list {
+		if normalizeItem(existing) == target {
+			return i
+		}
 	}
-	return arr
+	return -1
 }
 
-func stringArrayToJSON(arr []string) []byte {
-	if arr == nil {
-		arr = []string{}
+func normalizeItem(item string) string {
+	return strings.ToLower(strings.TrimSpace(item))
+}
+
+func trimItems(items []string) []string {
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
 	}
-	data, _ := json.Marshal(arr)
-	return data
+	return result
 }
 
 func mergeStringArrays(existing, new []string) []string {
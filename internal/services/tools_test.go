@@ -0,0 +1,158 @@
+package services
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestApplyListOperation(t *testing.T) {
+	list := []string{"Manual invoicing", "slow support", "Excel"}
+
+	tests := []struct {
+		name    string
+		input   UpdateUnderstandingInput
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:  "remove matches case-insensitively",
+			input: UpdateUnderstandingInput{Field: "pain_points", Operation: UnderstandingOpRemove, Items: []string{"manual invoicing "}},
+			want:  []string{"slow support", "Excel"},
+		},
+		{
+			name:    "remove missing item",
+			input:   UpdateUnderstandingInput{Field: "pain_points", Operation: UnderstandingOpRemove, Items: []string{"hiring"}},
+			wantErr: true,
+		},
+		{
+			name:    "remove without items",
+			input:   UpdateUnderstandingInput{Field: "pain_points", Operation: UnderstandingOpRemove},
+			wantErr: true,
+		},
+		{
+			name:    "remove everything without confirmation",
+			input:   UpdateUnderstandingInput{Field: "pain_points", Operation: UnderstandingOpRemove, Items: list},
+			wantErr: true,
+		},
+		{
+			name:  "remove everything with confirmation",
+			input: UpdateUnderstandingInput{Field: "pain_points", Operation: UnderstandingOpRemove, Items: list, ConfirmClear: true},
+			want:  []string{},
+		},
+		{
+			name:  "replace all dedupes and trims",
+			input: UpdateUnderstandingInput{Field: "pain_points", Operation: UnderstandingOpReplaceAll, Items: []string{" hiring", "hiring", ""}},
+			want:  []string{"hiring"},
+		},
+		{
+			name:    "replace all with nothing without confirmation",
+			input:   UpdateUnderstandingInput{Field: "pain_points", Operation: UnderstandingOpReplaceAll},
+			wantErr: true,
+		},
+		{
+			name:  "edit item keeps position",
+			input: UpdateUnderstandingInput{Field: "pain_points", Operation: UnderstandingOpEditItem, OldValue: "slow support", NewValue: "slow email support"},
+			want:  []string{"Manual invoicing", "slow email support", "Excel"},
+		},
+		{
+			name:    "edit missing item",
+			input:   UpdateUnderstandingInput{Field: "pain_points", Operation: UnderstandingOpEditItem, OldValue: "hiring", NewValue: "recruiting"},
+			wantErr: true,
+		},
+		{
+			name:    "edit without new value",
+			input:   UpdateUnderstandingInput{Field: "pain_points", Operation: UnderstandingOpEditItem, OldValue: "Excel"},
+			wantErr: true,
+		},
+		{
+			name:    "unknown operation",
+			input:   UpdateUnderstandingInput{Field: "pain_points", Operation: "append"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := applyListOperation(list, tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("applyListOperation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("applyListOperation() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if want := []string{"Manual invoicing", "slow support", "Excel"}; !reflect.DeepEqual(list, want) {
+		t.Errorf("original list modified: %v", list)
+	}
+}
+
+func TestMergeUnderstandingInput(t *testing.T) {
+	existing := testContext(
+		map[string]string{"industry": "retail", "user_name": "Alice"},
+		map[string][]string{"pain_points": {"returns"}},
+	)
+	input := testContext(
+		map[string]string{"industry": "e-commerce"},
+		map[string][]string{"pain_points": {"returns", "shipping"}, "current_software": {"Shopify"}},
+	)
+
+	got := mergeUnderstandingInput(existing, input)
+	want := testContext(
+		map[string]string{"industry": "e-commerce", "user_name": "Alice"},
+		map[string][]string{"pain_points": {"returns", "shipping"}, "current_software": {"Shopify"}},
+	)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("mergeUnderstandingInput() = %+v, want %+v", got, want)
+	}
+	if !reflect.DeepEqual(existing.List("pain_points"), []string{"returns"}) {
+		t.Errorf("existing modified: %+v", existing)
+	}
+
+	if fields := getUpdatedFields(DefaultUnderstandingSchema(), input); !reflect.DeepEqual(fields, []string{"industry", "pain_points", "current_software"}) {
+		t.Errorf("getUpdatedFields() = %v", fields)
+	}
+}
+
+func TestBuildBusinessContextPrompt(t *testing.T) {
+	schema := DefaultUnderstandingSchema()
+
+	if got := buildBusinessContextPrompt(schema, NewBusinessContext()); got != "" {
+		t.Errorf("buildBusinessContextPrompt(empty) = %q, want empty", got)
+	}
+
+	got := buildBusinessContextPrompt(schema, testContext(
+		map[string]string{"user_name": "Alice", "business_name": "Acme", "business_size": "11-50", "user_role": "owner"},
+		map[string][]string{"pain_points": {"returns", "shipping"}, "bottlenecks": {"approvals"}},
+	))
+	for _, want := range []string{
+		"User: Alice | Company: Acme | Size: 11-50 employees\n",
+		"Pain Points: returns, shipping",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("prompt missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "approvals") || strings.Contains(got, "owner") {
+		t.Errorf("prompt includes fields without a prompt label:\n%s", got)
+	}
+}
+
+func TestReportRequirements(t *testing.T) {
+	schema := DefaultUnderstandingSchema()
+
+	status := schema.Status(testContext(map[string]string{"industry": "retail"}, nil))
+	if hasMinimumDataForReport(schema, status) {
+		t.Error("hasMinimumDataForReport() = true without workflows or challenges")
+	}
+	if msg := generateDataGapsMessage(schema, status); !strings.Contains(msg, "your workflows or challenges") || strings.Contains(msg, "your business or industry") {
+		t.Errorf("generateDataGapsMessage() = %q", msg)
+	}
+
+	status = schema.Status(testContext(map[string]string{"industry": "retail"}, map[string][]string{"automation_goals": {"invoicing"}}))
+	if !hasMinimumDataForReport(schema, status) {
+		t.Error("hasMinimumDataForReport() = false with industry and automation goals")
+	}
+}
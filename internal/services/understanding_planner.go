@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/agpt-go/chatbot-api/internal/database"
+	"github.com/agpt-go/chatbot-api/internal/logging"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const (
+	// maxPlannedQuestions is how many ranked questions a tool result carries
+	maxPlannedQuestions = 3
+
+	// requirementBoost multiplies the score of fields that would satisfy an unmet report requirement
+	requirementBoost = 2.0
+
+	// askedQuestionPenalty multiplies the score of questions already suggested in the session,
+	// so they are only repeated once nothing new is left to ask
+	askedQuestionPenalty = 0.25
+)
+
+// PlannedQuestion is a follow-up question about a missing field, ranked by how
+// much its answer is worth for the report the user is working towards
+type PlannedQuestion struct {
+	Field        string  `json:"field"`
+	Question     string  `json:"question"`
+	Score        float64 `json:"score"`
+	AlreadyAsked bool    `json:"already_asked,omitempty"`
+}
+
+// PlanQuestions ranks the missing fields of doc by importance for reportType and
+// returns at most limit questions, highest score first. Fields in asked have
+// already been suggested this session and are ranked after fresh questions of
+// similar weight. Fields without a question or with zero weight are never asked.
+func (s *UnderstandingSchema) PlanQuestions(doc *BusinessContext, reportType string, asked []string, limit int) []PlannedQuestion {
+	status := s.StatusFor(doc, reportType)
+
+	boosted := make(map[string]bool)
+	for _, req := range unmetReportRequirements(s, status) {
+		for _, name := range req.AnyOf {
+			boosted[name] = true
+		}
+	}
+
+	questions := []PlannedQuestion{}
+	for _, f := range s.Fields {
+		score := f.WeightFor(reportType)
+		if status.Filled(f.Name) || f.Question == "" || score == 0 {
+			continue
+		}
+
+		q := PlannedQuestion{Field: f.Name, Question: f.Question}
+		if boosted[f.Name] {
+			score *= requirementBoost
+		}
+		if containsString(asked, f.Name) {
+			score *= askedQuestionPenalty
+			q.AlreadyAsked = true
+		}
+		q.Score = math.Round(score*100) / 100
+		questions = append(questions, q)
+	}
+
+	// Stable so equal scores keep schema order
+	sort.SliceStable(questions, func(i, j int) bool {
+		return questions[i].Score > questions[j].Score
+	})
+
+	if limit > 0 && len(questions) > limit {
+		questions = questions[:limit]
+	}
+	return questions
+}
+
+// interviewState returns the report type and already asked fields for the chat
+// session the current tool call belongs to. Calls outside a session have no state.
+func (s *ToolService) interviewState(ctx context.Context, userID uuid.UUID) (string, []string) {
+	inv, ok := ToolInvocationFromContext(ctx)
+	if !ok || inv.SessionID == uuid.Nil {
+		return "", nil
+	}
+
+	interview, err := s.queries.GetUnderstandingInterview(ctx, database.GetUnderstandingInterviewParams{
+		SessionID: inv.SessionID,
+		UserID:    userID,
+	})
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			logging.Warn("failed to get understanding interview", "error", err, "sessionID", inv.SessionID.String())
+		}
+		return "", nil
+	}
+
+	return derefStringPtr(interview.ReportType), interview.AskedFields
+}
+
+// saveInterviewState records the session's report type and marks the planned
+// questions as asked. Failures are logged; they only affect question ranking.
+func (s *ToolService) saveInterviewState(ctx context.Context, userID uuid.UUID, reportType string, asked []string, planned []PlannedQuestion) {
+	inv, ok := ToolInvocationFromContext(ctx)
+	if !ok || inv.SessionID == uuid.Nil {
+		return
+	}
+
+	for _, q := range planned {
+		if !containsString(asked, q.Field) {
+			asked = append(asked, q.Field)
+		}
+	}
+	if asked == nil {
+		asked = []string{}
+	}
+
+	_, err := s.queries.UpsertUnderstandingInterview(ctx, database.UpsertUnderstandingInterviewParams{
+		SessionID:   inv.SessionID,
+		UserID:      userID,
+		ReportType:  stringPtrOrNil(reportType),
+		AskedFields: asked,
+	})
+	if err != nil {
+		logging.Warn("failed to save understanding interview", "error", err, "sessionID", inv.SessionID.String())
+	}
+}
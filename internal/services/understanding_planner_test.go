@@ -0,0 +1,123 @@
+package services
+
+import (
+	"reflect"
+	"testing"
+)
+
+func plannedFields(questions []PlannedQuestion) []string {
+	fields := make([]string, len(questions))
+	for i, q := range questions {
+		fields[i] = q.Field
+	}
+	return fields
+}
+
+func TestPlanQuestions(t *testing.T) {
+	schema, err := ParseUnderstandingSchema([]byte(`
+fields:
+  - {name: industry, type: string, weight: 2, question: your industry}
+  - {name: workflows, type: list, weight: 1, report_weights: {detailed: 4}, question: your workflows}
+  - {name: tools, type: list, weight: 1.5, question: your tools}
+  - {name: notes, type: string, weight: 1}
+  - {name: hobbies, type: list, weight: 0, question: your hobbies}
+report_types:
+  - {name: summary, description: overview}
+  - {name: detailed, description: analysis}
+report_requirements:
+  - {description: your work, any_of: [workflows]}
+`))
+	if err != nil {
+		t.Fatalf("ParseUnderstandingSchema() error = %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		doc        *BusinessContext
+		reportType string
+		asked      []string
+		limit      int
+		want       []string
+	}{
+		{
+			name: "unmet requirement is boosted",
+			doc:  NewBusinessContext(),
+			want: []string{"industry", "workflows", "tools"},
+		},
+		{
+			name:       "report type weights",
+			doc:        NewBusinessContext(),
+			reportType: "detailed",
+			want:       []string{"workflows", "industry", "tools"},
+		},
+		{
+			name: "filled fields are skipped",
+			doc:  testContext(map[string]string{"industry": "retail"}, map[string][]string{"workflows": {"invoicing"}}),
+			want: []string{"tools"},
+		},
+		{
+			name:  "asked questions rank last",
+			doc:   NewBusinessContext(),
+			asked: []string{"industry", "workflows"},
+			want:  []string{"tools", "industry", "workflows"},
+		},
+		{
+			name:  "limit",
+			doc:   NewBusinessContext(),
+			limit: 1,
+			want:  []string{"industry"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := schema.PlanQuestions(tt.doc, tt.reportType, tt.asked, tt.limit)
+			if fields := plannedFields(got); !reflect.DeepEqual(fields, tt.want) {
+				t.Errorf("PlanQuestions() = %v, want %v", fields, tt.want)
+			}
+		})
+	}
+
+	t.Run("scores and asked flag", func(t *testing.T) {
+		got := schema.PlanQuestions(NewBusinessContext(), "", []string{"tools"}, 0)
+		want := []PlannedQuestion{
+			{Field: "industry", Question: "your industry", Score: 2},
+			{Field: "workflows", Question: "your workflows", Score: 2},
+			{Field: "tools", Question: "your tools", Score: 0.38, AlreadyAsked: true},
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("PlanQuestions() = %+v, want %+v", got, want)
+		}
+	})
+}
+
+func TestGenerateNextStepsMessage(t *testing.T) {
+	tests := []struct {
+		name      string
+		questions []PlannedQuestion
+		want      string
+	}{
+		{
+			name: "nothing left",
+			want: "I have a comprehensive understanding of your business. We can proceed with generating personalized AI recommendations.",
+		},
+		{
+			name:      "two questions",
+			questions: []PlannedQuestion{{Question: "your industry"}, {Question: "your tools"}},
+			want:      "To complete the picture, I'd love to learn about your industry and your tools.",
+		},
+		{
+			name:      "three questions",
+			questions: []PlannedQuestion{{Question: "a"}, {Question: "b"}, {Question: "c"}},
+			want:      "To better understand your needs, could you tell me about a, b, and c?",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := generateNextStepsMessage(tt.questions); got != tt.want {
+				t.Errorf("generateNextStepsMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,452 @@
+package services
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+//go:embed understanding_schema.yaml
+var defaultUnderstandingSchema []byte
+
+// Understanding field types
+const (
+	FieldTypeString = "string"
+	FieldTypeList   = "list"
+)
+
+// Understanding field scopes
+const (
+	FieldScopePersonal     = "personal"
+	FieldScopeOrganization = "organization"
+)
+
+var fieldNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// UnderstandingField describes one field of the business understanding
+type UnderstandingField struct {
+	Name          string             `json:"name"`
+	Type          string             `json:"type"`
+	Description   string             `json:"description"`
+	Enum          []string           `json:"enum,omitempty"`
+	MaxLength     int                `json:"max_length,omitempty"`
+	MaxItems      int                `json:"max_items,omitempty"`
+	Scope         string             `json:"scope"`
+	Weight        float64            `json:"weight"`
+	ReportWeights map[string]float64 `json:"report_weights,omitempty"`
+	Question      string             `json:"question,omitempty"`
+	PromptLabel   string             `json:"prompt_label,omitempty"`
+	PromptInline  bool               `json:"prompt_inline,omitempty"`
+	PromptFormat  string             `json:"prompt_format,omitempty"`
+}
+
+// ReportType is a kind of report generate_business_report can produce
+type ReportType struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description" yaml:"description"`
+}
+
+// ReportRequirement is satisfied when any of its fields has a value
+type ReportRequirement struct {
+	Description string   `json:"description" yaml:"description"`
+	AnyOf       []string `json:"any_of" yaml:"any_of"`
+}
+
+// UnderstandingSchema defines which fields make up the business understanding.
+// It drives the add_understanding tool definition, validation, completeness
+// scoring and system prompt rendering.
+type UnderstandingSchema struct {
+	Fields             []UnderstandingField `json:"fields"`
+	ReportTypes        []ReportType         `json:"report_types"`
+	ReportRequirements []ReportRequirement  `json:"report_requirements"`
+
+	byName map[string]int
+}
+
+// schemaFile is the on-disk form of the schema. Weight is a pointer so an
+// omitted weight can default to 1 while an explicit 0 is kept.
+type schemaFile struct {
+	Fields []struct {
+		Name          string             `yaml:"name"`
+		Type          string             `yaml:"type"`
+		Description   string             `yaml:"description"`
+		Enum          []string           `yaml:"enum"`
+		MaxLength     int                `yaml:"max_length"`
+		MaxItems      int                `yaml:"max_items"`
+		Scope         string             `yaml:"scope"`
+		Weight        *float64           `yaml:"weight"`
+		ReportWeights map[string]float64 `yaml:"report_weights"`
+		Question      string             `yaml:"question"`
+		PromptLabel   string             `yaml:"prompt_label"`
+		PromptInline  bool               `yaml:"prompt_inline"`
+		PromptFormat  string             `yaml:"prompt_format"`
+	} `yaml:"fields"`
+	ReportTypes        []ReportType        `yaml:"report_types"`
+	ReportRequirements []ReportRequirement `yaml:"report_requirements"`
+}
+
+// DefaultUnderstandingSchema returns the built-in schema
+func DefaultUnderstandingSchema() *UnderstandingSchema {
+	schema, err := ParseUnderstandingSchema(defaultUnderstandingSchema)
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in understanding schema: %v", err))
+	}
+	return schema
+}
+
+// LoadUnderstandingSchema reads a schema from a YAML or JSON file.
+// An empty path returns the built-in schema.
+func LoadUnderstandingSchema(path string) (*UnderstandingSchema, error) {
+	if path == "" {
+		return DefaultUnderstandingSchema(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read understanding schema: %w", err)
+	}
+
+	schema, err := ParseUnderstandingSchema(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid understanding schema %s: %w", path, err)
+	}
+	return schema, nil
+}
+
+// ParseUnderstandingSchema parses and validates a schema. JSON is accepted as it is valid YAML.
+func ParseUnderstandingSchema(data []byte) (*UnderstandingSchema, error) {
+	var file schemaFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	if len(file.Fields) == 0 {
+		return nil, fmt.Errorf("schema has no fields")
+	}
+
+	schema := &UnderstandingSchema{
+		Fields:             make([]UnderstandingField, len(file.Fields)),
+		ReportTypes:        file.ReportTypes,
+		ReportRequirements: file.ReportRequirements,
+		byName:             make(map[string]int, len(file.Fields)),
+	}
+
+	if len(file.ReportTypes) == 0 {
+		return nil, fmt.Errorf("schema has no report types")
+	}
+
+	reportTypes := make(map[string]bool, len(file.ReportTypes))
+	for _, rt := range file.ReportTypes {
+		if !fieldNamePattern.MatchString(rt.Name) {
+			return nil, fmt.Errorf("report type %q must be lower snake case", rt.Name)
+		}
+		if reportTypes[rt.Name] {
+			return nil, fmt.Errorf("report type %q is defined twice", rt.Name)
+		}
+		reportTypes[rt.Name] = true
+	}
+
+	for i, f := range file.Fields {
+		field := UnderstandingField{
+			Name:          f.Name,
+			Type:          f.Type,
+			Description:   f.Description,
+			Enum:          f.Enum,
+			MaxLength:     f.MaxLength,
+			MaxItems:      f.MaxItems,
+			Scope:         f.Scope,
+			Weight:        1,
+			ReportWeights: f.ReportWeights,
+			Question:      f.Question,
+			PromptLabel:   f.PromptLabel,
+			PromptInline:  f.PromptInline,
+			PromptFormat:  f.PromptFormat,
+		}
+		if f.Weight != nil {
+			field.Weight = *f.Weight
+		}
+		if field.Scope == "" {
+			field.Scope = FieldScopePersonal
+		}
+		if field.PromptFormat == "" {
+			field.PromptFormat = "%s"
+		}
+
+		if !fieldNamePattern.MatchString(field.Name) {
+			return nil, fmt.Errorf("field %d: name %q must be lower snake case", i, field.Name)
+		}
+		if _, exists := schema.byName[field.Name]; exists {
+			return nil, fmt.Errorf("field %q is defined twice", field.Name)
+		}
+		if field.Type != FieldTypeString && field.Type != FieldTypeList {
+			return nil, fmt.Errorf("field %q: type must be %q or %q", field.Name, FieldTypeString, FieldTypeList)
+		}
+		if field.Scope != FieldScopePersonal && field.Scope != FieldScopeOrganization {
+			return nil, fmt.Errorf("field %q: scope must be %q or %q", field.Name, FieldScopePersonal, FieldScopeOrganization)
+		}
+		if len(field.Enum) > 0 && field.Type != FieldTypeString {
+			return nil, fmt.Errorf("field %q: enum is only supported for string fields", field.Name)
+		}
+		if field.Weight < 0 {
+			return nil, fmt.Errorf("field %q: weight must not be negative", field.Name)
+		}
+		for reportType, weight := range field.ReportWeights {
+			if !reportTypes[reportType] {
+				return nil, fmt.Errorf("field %q: report_weights has unknown report type %q", field.Name, reportType)
+			}
+			if weight < 0 {
+				return nil, fmt.Errorf("field %q: report weight for %q must not be negative", field.Name, reportType)
+			}
+		}
+		if strings.Count(field.PromptFormat, "%s") != 1 {
+			return nil, fmt.Errorf("field %q: prompt_format must contain exactly one %%s", field.Name)
+		}
+
+		schema.Fields[i] = field
+		schema.byName[field.Name] = i
+	}
+
+	for _, req := range schema.ReportRequirements {
+		if len(req.AnyOf) == 0 {
+			return nil, fmt.Errorf("report requirement %q has no fields", req.Description)
+		}
+		for _, name := range req.AnyOf {
+			if _, ok := schema.Field(name); !ok {
+				return nil, fmt.Errorf("report requirement %q: unknown field %q", req.Description, name)
+			}
+		}
+	}
+
+	return schema, nil
+}
+
+// Field returns the named field
+func (s *UnderstandingSchema) Field(name string) (UnderstandingField, bool) {
+	i, ok := s.byName[name]
+	if !ok {
+		return UnderstandingField{}, false
+	}
+	return s.Fields[i], true
+}
+
+// ReportTypeNames returns the names of all report types in schema order
+func (s *UnderstandingSchema) ReportTypeNames() []string {
+	names := make([]string, len(s.ReportTypes))
+	for i, rt := range s.ReportTypes {
+		names[i] = rt.Name
+	}
+	return names
+}
+
+// WeightFor returns how much a field matters for a report type, falling back
+// to the field's base weight when the type is empty or has no override
+func (f UnderstandingField) WeightFor(reportType string) float64 {
+	if w, ok := f.ReportWeights[reportType]; ok {
+		return w
+	}
+	return f.Weight
+}
+
+// ListFieldNames returns the names of all list fields in schema order
+func (s *UnderstandingSchema) ListFieldNames() []string {
+	var names []string
+	for _, f := range s.Fields {
+		if f.Type == FieldTypeList {
+			names = append(names, f.Name)
+		}
+	}
+	return names
+}
+
+// ForScope returns the fields that can be stored at the given scope.
+// Personal understanding can hold every field; organizations only hold organization fields.
+func (s *UnderstandingSchema) ForScope(scope string) []UnderstandingField {
+	if scope != FieldScopeOrganization {
+		return s.Fields
+	}
+	var fields []UnderstandingField
+	for _, f := range s.Fields {
+		if f.Scope == FieldScopeOrganization {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// UnderstandingValidationError lists the invalid fields of a document
+type UnderstandingValidationError struct {
+	Fields map[string]string
+}
+
+func (e *UnderstandingValidationError) Error() string {
+	names := make([]string, 0, len(e.Fields))
+	for name := range e.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	msgs := make([]string, len(names))
+	for i, name := range names {
+		msgs[i] = e.Fields[name]
+	}
+	return "invalid business understanding: " + strings.Join(msgs, "; ")
+}
+
+// Validate checks a document or patch against the schema for the given scope.
+// Empty values are always allowed since they clear a field.
+func (s *UnderstandingSchema) Validate(doc *BusinessContext, scope string) error {
+	errs := make(map[string]string)
+
+	check := func(name string, isList bool) (UnderstandingField, bool) {
+		field, ok := s.Field(name)
+		switch {
+		case !ok:
+			errs[name] = fmt.Sprintf("%s is not a known field", name)
+		case scope == FieldScopeOrganization && field.Scope != FieldScopeOrganization:
+			errs[name] = fmt.Sprintf("%s is a personal field and can't be shared by an organization", name)
+		case isList && field.Type != FieldTypeList:
+			errs[name] = fmt.Sprintf("%s must be a string", name)
+		case !isList && field.Type != FieldTypeString:
+			errs[name] = fmt.Sprintf("%s must be a list of strings", name)
+		default:
+			return field, true
+		}
+		return field, false
+	}
+
+	for name, value := range doc.Strings {
+		field, ok := check(name, false)
+		if !ok || value == "" {
+			continue
+		}
+		if len(field.Enum) > 0 && !containsString(field.Enum, value) {
+			errs[name] = fmt.Sprintf("%s must be one of: %s", name, strings.Join(field.Enum, ", "))
+		} else if field.MaxLength > 0 && len(value) > field.MaxLength {
+			errs[name] = fmt.Sprintf("%s must be at most %d characters", name, field.MaxLength)
+		}
+	}
+
+	for name, items := range doc.Lists {
+		field, ok := check(name, true)
+		if !ok {
+			continue
+		}
+		if field.MaxItems > 0 && len(items) > field.MaxItems {
+			errs[name] = fmt.Sprintf("%s must have at most %d items", name, field.MaxItems)
+			continue
+		}
+		for _, item := range items {
+			if field.MaxLength > 0 && len(item) > field.MaxLength {
+				errs[name] = fmt.Sprintf("%s items must be at most %d characters", name, field.MaxLength)
+				break
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return &UnderstandingValidationError{Fields: errs}
+	}
+	return nil
+}
+
+// ToolParameters returns the JSON schema for the add_understanding tool arguments
+func (s *UnderstandingSchema) ToolParameters() map[string]interface{} {
+	properties := make(map[string]interface{}, len(s.Fields))
+	for _, f := range s.Fields {
+		var prop map[string]interface{}
+		if f.Type == FieldTypeList {
+			items := map[string]interface{}{"type": "string"}
+			if f.MaxLength > 0 {
+				items["maxLength"] = f.MaxLength
+			}
+			prop = map[string]interface{}{
+				"type":  "array",
+				"items": items,
+			}
+			if f.MaxItems > 0 {
+				prop["maxItems"] = f.MaxItems
+			}
+		} else {
+			prop = map[string]interface{}{"type": "string"}
+			if len(f.Enum) > 0 {
+				prop["enum"] = f.Enum
+			}
+			if f.MaxLength > 0 {
+				prop["maxLength"] = f.MaxLength
+			}
+		}
+		if f.Description != "" {
+			prop["description"] = f.Description
+		}
+		properties[f.Name] = prop
+	}
+
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   []string{},
+	}
+}
+
+// UnderstandingStatus summarises how much of the business understanding has been captured
+type UnderstandingStatus struct {
+	Fields       map[string]int `json:"fields"`       // Items per field; 1 for a filled string field
+	Missing      []string       `json:"missing"`      // Unfilled fields in schema order
+	Completeness float64        `json:"completeness"` // Weighted percentage of fields filled
+}
+
+// Filled reports whether the named field has a value
+func (st UnderstandingStatus) Filled(name string) bool {
+	return st.Fields[name] > 0
+}
+
+// Status computes the status of a document, weighting each field by its base weight
+func (s *UnderstandingSchema) Status(doc *BusinessContext) UnderstandingStatus {
+	return s.StatusFor(doc, "")
+}
+
+// StatusFor computes the status of a document, weighting each field by its
+// importance for the given report type
+func (s *UnderstandingSchema) StatusFor(doc *BusinessContext, reportType string) UnderstandingStatus {
+	status := UnderstandingStatus{
+		Fields:  make(map[string]int, len(s.Fields)),
+		Missing: []string{},
+	}
+
+	var total, filled float64
+	for _, f := range s.Fields {
+		count := 0
+		if f.Type == FieldTypeList {
+			count = len(doc.List(f.Name))
+		} else if doc.Get(f.Name) != "" {
+			count = 1
+		}
+
+		weight := f.WeightFor(reportType)
+		status.Fields[f.Name] = count
+		total += weight
+		if count > 0 {
+			filled += weight
+		} else {
+			status.Missing = append(status.Missing, f.Name)
+		}
+	}
+
+	if total > 0 {
+		status.Completeness = filled / total * 100
+	}
+	return status
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
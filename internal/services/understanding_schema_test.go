@@ -0,0 +1,225 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestDefaultUnderstandingSchema(t *testing.T) {
+	schema := DefaultUnderstandingSchema()
+
+	if len(schema.Fields) != 15 {
+		t.Errorf("len(Fields) = %d, want 15", len(schema.Fields))
+	}
+
+	field, ok := schema.Field("business_size")
+	if !ok {
+		t.Fatal("Field(business_size) not found")
+	}
+	if field.Type != FieldTypeString || len(field.Enum) == 0 || field.Scope != FieldScopeOrganization {
+		t.Errorf("business_size = %+v", field)
+	}
+
+	want := []string{
+		"key_workflows", "daily_activities", "pain_points", "automation_goals",
+		"current_software", "bottlenecks", "manual_tasks", "existing_automation",
+	}
+	if got := schema.ListFieldNames(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ListFieldNames() = %v, want %v", got, want)
+	}
+
+	for _, f := range schema.ForScope(FieldScopeOrganization) {
+		if f.Name == "user_name" || f.Name == "job_title" {
+			t.Errorf("ForScope(organization) includes personal field %s", f.Name)
+		}
+	}
+}
+
+// testReportTypes is prepended to schemas in tests that are not about report types
+const testReportTypes = "report_types: [{name: detailed, description: comprehensive analysis}]\n"
+
+func TestParseUnderstandingSchema(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		schema, err := ParseUnderstandingSchema([]byte(testReportTypes + `
+fields:
+  - name: team
+    type: string
+  - name: tools
+    type: list
+    weight: 0
+    report_weights:
+      detailed: 2
+`))
+		if err != nil {
+			t.Fatalf("ParseUnderstandingSchema() error = %v", err)
+		}
+		team, _ := schema.Field("team")
+		if team.Weight != 1 || team.Scope != FieldScopePersonal || team.PromptFormat != "%s" {
+			t.Errorf("team = %+v, want weight 1, personal scope, %%s format", team)
+		}
+		tools, _ := schema.Field("tools")
+		if tools.Weight != 0 {
+			t.Errorf("tools weight = %v, want explicit 0", tools.Weight)
+		}
+		if tools.WeightFor("detailed") != 2 || tools.WeightFor("quick_wins") != 0 {
+			t.Errorf("tools WeightFor() = %v, %v, want 2, 0", tools.WeightFor("detailed"), tools.WeightFor("quick_wins"))
+		}
+	})
+
+	t.Run("accepts JSON", func(t *testing.T) {
+		if _, err := ParseUnderstandingSchema([]byte(`{"fields":[{"name":"team","type":"string"}],"report_types":[{"name":"detailed"}]}`)); err != nil {
+			t.Errorf("ParseUnderstandingSchema() error = %v", err)
+		}
+	})
+
+	invalid := map[string]string{
+		"no fields":              `fields: []`,
+		"no report types":        `fields: [{name: team, type: string}]`,
+		"bad name":               `fields: [{name: Team, type: string}]`,
+		"duplicate name":         `fields: [{name: team, type: string}, {name: team, type: list}]`,
+		"bad type":               `fields: [{name: team, type: number}]`,
+		"bad scope":              `fields: [{name: team, type: string, scope: global}]`,
+		"enum on list":           `fields: [{name: team, type: list, enum: [a]}]`,
+		"negative weight":        `fields: [{name: team, type: string, weight: -1}]`,
+		"bad prompt format":      `fields: [{name: team, type: string, prompt_format: "%d people"}]`,
+		"unknown report req":     "fields: [{name: team, type: string}]\nreport_requirements: [{description: x, any_of: [size]}]",
+		"unknown report weight":  "fields: [{name: team, type: string, report_weights: {summary: 2}}]",
+		"negative report weight": "fields: [{name: team, type: string, report_weights: {detailed: -1}}]",
+	}
+	for name, data := range invalid {
+		t.Run(name, func(t *testing.T) {
+			if name != "no report types" {
+				data = testReportTypes + data
+			}
+			if _, err := ParseUnderstandingSchema([]byte(data)); err == nil {
+				t.Error("ParseUnderstandingSchema() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestUnderstandingSchemaValidate(t *testing.T) {
+	schema := DefaultUnderstandingSchema()
+
+	tests := []struct {
+		name       string
+		doc        *BusinessContext
+		scope      string
+		wantFields []string
+	}{
+		{
+			name:  "valid",
+			doc:   testContext(map[string]string{"business_size": "11-50"}, map[string][]string{"pain_points": {"returns"}}),
+			scope: FieldScopePersonal,
+		},
+		{
+			name:  "empty values clear fields",
+			doc:   &BusinessContext{Strings: map[string]string{"business_size": ""}, Lists: map[string][]string{"pain_points": {}}},
+			scope: FieldScopeOrganization,
+		},
+		{
+			name:       "enum",
+			doc:        testContext(map[string]string{"business_size": "about 30"}, nil),
+			scope:      FieldScopePersonal,
+			wantFields: []string{"business_size"},
+		},
+		{
+			name:       "unknown field",
+			doc:        testContext(map[string]string{"favorite_color": "blue"}, nil),
+			scope:      FieldScopePersonal,
+			wantFields: []string{"favorite_color"},
+		},
+		{
+			name:       "wrong type",
+			doc:        testContext(map[string]string{"pain_points": "returns"}, map[string][]string{"industry": {"retail"}}),
+			scope:      FieldScopePersonal,
+			wantFields: []string{"industry", "pain_points"},
+		},
+		{
+			name:       "personal field on organization",
+			doc:        testContext(map[string]string{"user_name": "Alice", "industry": "retail"}, nil),
+			scope:      FieldScopeOrganization,
+			wantFields: []string{"user_name"},
+		},
+		{
+			name:       "too many items",
+			doc:        testContext(nil, map[string][]string{"pain_points": make([]string, 51)}),
+			scope:      FieldScopePersonal,
+			wantFields: []string{"pain_points"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := schema.Validate(tt.doc, tt.scope)
+			if tt.wantFields == nil {
+				if err != nil {
+					t.Errorf("Validate() error = %v, want nil", err)
+				}
+				return
+			}
+
+			var validationErr *UnderstandingValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Validate() error = %v, want UnderstandingValidationError", err)
+			}
+			for _, name := range tt.wantFields {
+				if _, ok := validationErr.Fields[name]; !ok {
+					t.Errorf("Validate() missing error for %s: %v", name, validationErr.Fields)
+				}
+			}
+			if len(validationErr.Fields) != len(tt.wantFields) {
+				t.Errorf("Validate() fields = %v, want %v", validationErr.Fields, tt.wantFields)
+			}
+		})
+	}
+}
+
+func TestUnderstandingSchemaStatus(t *testing.T) {
+	schema, err := ParseUnderstandingSchema([]byte(testReportTypes + `
+fields:
+  - {name: industry, type: string, weight: 3, report_weights: {detailed: 1}}
+  - {name: pain_points, type: list}
+  - {name: notes, type: string, weight: 0}
+`))
+	if err != nil {
+		t.Fatalf("ParseUnderstandingSchema() error = %v", err)
+	}
+
+	status := schema.Status(testContext(map[string]string{"industry": "retail"}, nil))
+	if math.Abs(status.Completeness-75) > 0.001 {
+		t.Errorf("Completeness = %v, want 75", status.Completeness)
+	}
+	if want := []string{"pain_points", "notes"}; !reflect.DeepEqual(status.Missing, want) {
+		t.Errorf("Missing = %v, want %v", status.Missing, want)
+	}
+
+	status = schema.StatusFor(testContext(map[string]string{"industry": "retail"}, nil), "detailed")
+	if math.Abs(status.Completeness-50) > 0.001 {
+		t.Errorf("Completeness for detailed = %v, want 50", status.Completeness)
+	}
+
+	status = schema.Status(testContext(nil, map[string][]string{"pain_points": {"a", "b"}}))
+	if status.Fields["pain_points"] != 2 || status.Filled("industry") {
+		t.Errorf("Fields = %v", status.Fields)
+	}
+}
+
+func TestUnderstandingSchemaToolParameters(t *testing.T) {
+	params := DefaultUnderstandingSchema().ToolParameters()
+	properties := params["properties"].(map[string]interface{})
+
+	if len(properties) != 15 {
+		t.Errorf("len(properties) = %d, want 15", len(properties))
+	}
+	size := properties["business_size"].(map[string]interface{})
+	if size["type"] != "string" || size["enum"] == nil {
+		t.Errorf("business_size = %v, want string enum", size)
+	}
+	workflows := properties["key_workflows"].(map[string]interface{})
+	if workflows["type"] != "array" {
+		t.Errorf("key_workflows type = %v, want array", workflows["type"])
+	}
+}
@@ -0,0 +1,658 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/agpt-go/chatbot-api/internal/database"
+	"github.com/agpt-go/chatbot-api/internal/logging"
+	"github.com/agpt-go/chatbot-api/internal/safehttp"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+var (
+	ErrWebhookToolNotFound  = errors.New("webhook tool not found")
+	ErrWebhookToolNameTaken = errors.New("tool name is already in use")
+)
+
+// Limits and defaults for webhook tools
+const (
+	DefaultWebhookTimeoutSeconds   = 10
+	MaxWebhookTimeoutSeconds       = 60
+	DefaultWebhookMaxResponseBytes = 1 << 20
+	maxWebhookDescriptionLength    = 1024
+)
+
+// WebhookSecretEnvPrefix prefixes the environment variables secret references
+// resolve to. Only these variables can be referenced, so a tool definition
+// can't send the API's own secrets to its URL.
+const WebhookSecretEnvPrefix = "TOOL_SECRET_"
+
+var (
+	webhookToolNamePattern   = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]{0,63}$`)
+	webhookHeaderNamePattern = regexp.MustCompile(`^[A-Za-z0-9-]+$`)
+	webhookSecretRefPattern  = regexp.MustCompile(`\{\{\s*secret\.([A-Za-z0-9_]+)\s*\}\}`)
+	webhookMethods           = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+	// Headers the HTTP client sets itself
+	webhookReservedHeaders = []string{"Host", "Content-Length", "Transfer-Encoding", "Connection", "Content-Type"}
+)
+
+// WebhookToolsConfig configures how webhook tools call out
+type WebhookToolsConfig struct {
+	AllowPrivateNetworks bool          // Permit URLs on private networks, for development
+	MaxResponseBytes     int64         // Larger responses fail the call; 0 uses DefaultWebhookMaxResponseBytes
+	RefreshInterval      time.Duration // How often definitions are reloaded from the database; 0 only loads on change
+}
+
+// WebhookToolInput defines a webhook tool
+type WebhookToolInput struct {
+	Name            string
+	Description     string
+	Parameters      map[string]interface{}
+	URL             string
+	Method          string
+	Headers         map[string]string // Values may reference secrets as {{secret.NAME}}
+	TimeoutSeconds  int
+	ResponseMapping map[string]string // Result field -> dotted path into the JSON response
+	Enabled         bool
+}
+
+// WebhookToolView is a webhook tool definition
+type WebhookToolView struct {
+	ID              uuid.UUID              `json:"id"`
+	Name            string                 `json:"name"`
+	Description     string                 `json:"description"`
+	Parameters      map[string]interface{} `json:"parameters"`
+	URL             string                 `json:"url"`
+	Method          string                 `json:"method"`
+	Headers         map[string]string      `json:"headers"`
+	TimeoutSeconds  int                    `json:"timeout_seconds"`
+	ResponseMapping map[string]string      `json:"response_mapping"`
+	Enabled         bool                   `json:"enabled"`
+	CreatedAt       time.Time              `json:"created_at"`
+	UpdatedAt       time.Time              `json:"updated_at"`
+}
+
+// WebhookToolValidationError lists the invalid fields of a webhook tool definition
+type WebhookToolValidationError struct {
+	Fields map[string]string
+}
+
+func (e *WebhookToolValidationError) Error() string {
+	names := make([]string, 0, len(e.Fields))
+	for name := range e.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	msgs := make([]string, len(names))
+	for i, name := range names {
+		msgs[i] = e.Fields[name]
+	}
+	return "invalid webhook tool: " + strings.Join(msgs, "; ")
+}
+
+// WebhookToolService manages tools that admins define in the database, each
+// of which calls an external HTTP endpoint. Definitions are registered in the
+// ToolRegistry at startup, after every change, and periodically so changes
+// made through another instance are picked up.
+type WebhookToolService struct {
+	queries  *database.Queries
+	registry *ToolRegistry
+	client   *http.Client
+	cfg      WebhookToolsConfig
+
+	mu         sync.Mutex
+	registered map[string]bool // Names of the webhook tools in the registry
+}
+
+// NewWebhookToolService creates a webhook tool service that registers tools in registry
+func NewWebhookToolService(queries *database.Queries, registry *ToolRegistry, cfg WebhookToolsConfig) *WebhookToolService {
+	if cfg.MaxResponseBytes <= 0 {
+		cfg.MaxResponseBytes = DefaultWebhookMaxResponseBytes
+	}
+	return &WebhookToolService{
+		queries:  queries,
+		registry: registry,
+		client: safehttp.NewClient(safehttp.Options{
+			AllowPrivate: cfg.AllowPrivateNetworks,
+			Timeout:      MaxWebhookTimeoutSeconds * time.Second,
+		}),
+		cfg:        cfg,
+		registered: make(map[string]bool),
+	}
+}
+
+// Start registers the stored tools and, if a refresh interval is configured,
+// keeps reloading them until ctx ends
+func (s *WebhookToolService) Start(ctx context.Context) {
+	if err := s.Sync(ctx); err != nil {
+		logging.Error("failed to load webhook tools", err)
+	}
+	if s.cfg.RefreshInterval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.cfg.RefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := s.Sync(ctx); err != nil {
+					logging.Error("failed to refresh webhook tools", err)
+				}
+			}
+		}
+	}()
+}
+
+// Sync registers the enabled tools from the database and removes the rest from the registry
+func (s *WebhookToolService) Sync(ctx context.Context) error {
+	tools, err := s.queries.ListWebhookTools(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list webhook tools: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current := make(map[string]bool, len(tools))
+	for _, tool := range tools {
+		if !tool.Enabled {
+			continue
+		}
+		if _, exists := s.registry.Get(tool.Name); exists && !s.registered[tool.Name] {
+			logging.Warn("skipping webhook tool that shadows a registered tool", "tool", tool.Name)
+			continue
+		}
+
+		view, err := webhookToolToView(tool)
+		if err != nil {
+			logging.Warn("skipping invalid webhook tool", "error", err, "tool", tool.Name)
+			continue
+		}
+		s.registry.Register(tool.Name, ToolDefinition{
+			Name:        view.Name,
+			Description: view.Description,
+			Parameters:  view.Parameters,
+		}, s.handler(view), WithToolTimeout(time.Duration(view.TimeoutSeconds)*time.Second))
+		current[tool.Name] = true
+	}
+
+	for name := range s.registered {
+		if !current[name] {
+			s.registry.Unregister(name)
+		}
+	}
+	s.registered = current
+	return nil
+}
+
+// List returns all webhook tools ordered by name
+func (s *WebhookToolService) List(ctx context.Context) ([]WebhookToolView, error) {
+	tools, err := s.queries.ListWebhookTools(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook tools: %w", err)
+	}
+
+	views := make([]WebhookToolView, 0, len(tools))
+	for _, tool := range tools {
+		view, err := webhookToolToView(tool)
+		if err != nil {
+			return nil, err
+		}
+		views = append(views, *view)
+	}
+	return views, nil
+}
+
+// Get returns one webhook tool
+func (s *WebhookToolService) Get(ctx context.Context, id uuid.UUID) (*WebhookToolView, error) {
+	tool, err := s.queries.GetWebhookTool(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrWebhookToolNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook tool: %w", err)
+	}
+	return webhookToolToView(tool)
+}
+
+// Create stores a new webhook tool and registers it
+func (s *WebhookToolService) Create(ctx context.Context, userID uuid.UUID, input WebhookToolInput) (*WebhookToolView, error) {
+	params, err := s.prepare("", &input)
+	if err != nil {
+		return nil, err
+	}
+
+	tool, err := s.queries.CreateWebhookTool(ctx, database.CreateWebhookToolParams{
+		Name:            input.Name,
+		Description:     input.Description,
+		Parameters:      params.parameters,
+		URL:             input.URL,
+		Method:          input.Method,
+		Headers:         params.headers,
+		TimeoutSeconds:  int32(input.TimeoutSeconds),
+		ResponseMapping: params.responseMapping,
+		Enabled:         input.Enabled,
+		CreatedBy:       &userID,
+	})
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil, ErrWebhookToolNameTaken
+		}
+		return nil, fmt.Errorf("failed to create webhook tool: %w", err)
+	}
+
+	s.syncAfterChange(ctx)
+	return webhookToolToView(tool)
+}
+
+// Update replaces a webhook tool's definition and re-registers it
+func (s *WebhookToolService) Update(ctx context.Context, id uuid.UUID, input WebhookToolInput) (*WebhookToolView, error) {
+	existing, err := s.queries.GetWebhookTool(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrWebhookToolNotFound
+		}
+		return nil, fmt.Errorf("failed to get webhook tool: %w", err)
+	}
+
+	params, err := s.prepare(existing.Name, &input)
+	if err != nil {
+		return nil, err
+	}
+
+	tool, err := s.queries.UpdateWebhookTool(ctx, database.UpdateWebhookToolParams{
+		ID:              id,
+		Name:            input.Name,
+		Description:     input.Description,
+		Parameters:      params.parameters,
+		URL:             input.URL,
+		Method:          input.Method,
+		Headers:         params.headers,
+		TimeoutSeconds:  int32(input.TimeoutSeconds),
+		ResponseMapping: params.responseMapping,
+		Enabled:         input.Enabled,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			return nil, ErrWebhookToolNotFound
+		case isUniqueViolation(err):
+			return nil, ErrWebhookToolNameTaken
+		}
+		return nil, fmt.Errorf("failed to update webhook tool: %w", err)
+	}
+
+	s.syncAfterChange(ctx)
+	return webhookToolToView(tool)
+}
+
+// Delete removes a webhook tool and unregisters it
+func (s *WebhookToolService) Delete(ctx context.Context, id uuid.UUID) error {
+	rows, err := s.queries.DeleteWebhookTool(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to delete webhook tool: %w", err)
+	}
+	if rows == 0 {
+		return ErrWebhookToolNotFound
+	}
+
+	s.syncAfterChange(ctx)
+	return nil
+}
+
+// syncAfterChange re-registers tools after a change. The change is already
+// stored, so a failure is only logged; the next refresh retries.
+func (s *WebhookToolService) syncAfterChange(ctx context.Context) {
+	if err := s.Sync(ctx); err != nil {
+		logging.Error("failed to reload webhook tools", err)
+	}
+}
+
+// webhookToolParams holds the JSONB columns of a validated definition
+type webhookToolParams struct {
+	parameters      []byte
+	headers         []byte
+	responseMapping []byte
+}
+
+// prepare fills in defaults, validates the input and encodes its JSON columns.
+// currentName is the tool's stored name when updating.
+func (s *WebhookToolService) prepare(currentName string, input *WebhookToolInput) (*webhookToolParams, error) {
+	input.Method = strings.ToUpper(strings.TrimSpace(input.Method))
+	if input.Method == "" {
+		input.Method = http.MethodPost
+	}
+	if input.TimeoutSeconds == 0 {
+		input.TimeoutSeconds = DefaultWebhookTimeoutSeconds
+	}
+	if input.Parameters == nil {
+		input.Parameters = map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}
+	}
+	if input.Headers == nil {
+		input.Headers = map[string]string{}
+	}
+	if input.ResponseMapping == nil {
+		input.ResponseMapping = map[string]string{}
+	}
+
+	errs := validateWebhookToolInput(input, s.cfg.AllowPrivateNetworks)
+
+	// Names of built-in and imported tools are taken; a webhook tool keeps its own
+	if _, exists := s.registry.Get(input.Name); exists && input.Name != currentName {
+		s.mu.Lock()
+		ownTool := s.registered[input.Name]
+		s.mu.Unlock()
+		if !ownTool {
+			errs["name"] = "name is used by a built-in tool"
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, &WebhookToolValidationError{Fields: errs}
+	}
+
+	var params webhookToolParams
+	var err error
+	if params.parameters, err = json.Marshal(input.Parameters); err != nil {
+		return nil, err
+	}
+	if params.headers, err = json.Marshal(input.Headers); err != nil {
+		return nil, err
+	}
+	if params.responseMapping, err = json.Marshal(input.ResponseMapping); err != nil {
+		return nil, err
+	}
+	return &params, nil
+}
+
+func validateWebhookToolInput(input *WebhookToolInput, allowPrivate bool) map[string]string {
+	errs := make(map[string]string)
+
+	switch {
+	case !webhookToolNamePattern.MatchString(input.Name):
+		errs["name"] = "name must start with a letter and contain at most 64 letters, digits, _ or -"
+	case strings.Contains(input.Name, mcpToolSeparator):
+		errs["name"] = fmt.Sprintf("name must not contain %q, which is reserved for MCP tools", mcpToolSeparator)
+	}
+
+	if strings.TrimSpace(input.Description) == "" {
+		errs["description"] = "description is required"
+	} else if len(input.Description) > maxWebhookDescriptionLength {
+		errs["description"] = fmt.Sprintf("description must be at most %d characters", maxWebhookDescriptionLength)
+	}
+
+	if t, ok := input.Parameters["type"]; ok && t != "object" {
+		errs["parameters"] = `parameters must be a JSON schema with type "object"`
+	} else if props, ok := input.Parameters["properties"]; ok {
+		if _, isObject := props.(map[string]interface{}); !isObject {
+			errs["parameters"] = "parameters.properties must be an object"
+		}
+	}
+
+	if u, err := url.Parse(input.URL); err != nil || input.URL == "" {
+		errs["url"] = "url must be a valid URL"
+	} else if err := safehttp.CheckURL(u); err != nil {
+		errs["url"] = "url is not allowed: " + err.Error()
+	} else if ip := net.ParseIP(u.Hostname()); ip != nil && !allowPrivate && !safehttp.IsPublicIP(ip) {
+		errs["url"] = "url must not point to a private network address"
+	}
+
+	if !containsString(webhookMethods, input.Method) {
+		errs["method"] = "method must be one of: " + strings.Join(webhookMethods, ", ")
+	}
+
+	for name, value := range input.Headers {
+		switch {
+		case !webhookHeaderNamePattern.MatchString(name):
+			errs["headers"] = fmt.Sprintf("header %q is not a valid header name", name)
+		case containsFold(webhookReservedHeaders, name):
+			errs["headers"] = fmt.Sprintf("header %q is set automatically", name)
+		case strings.ContainsAny(value, "\r\n"):
+			errs["headers"] = fmt.Sprintf("header %q must not contain line breaks", name)
+		}
+	}
+
+	if input.TimeoutSeconds < 1 || input.TimeoutSeconds > MaxWebhookTimeoutSeconds {
+		errs["timeout_seconds"] = fmt.Sprintf("timeout_seconds must be between 1 and %d", MaxWebhookTimeoutSeconds)
+	}
+
+	for field, path := range input.ResponseMapping {
+		if field == "" || strings.TrimSpace(path) == "" {
+			errs["response_mapping"] = "response_mapping entries need a field name and a path"
+		}
+	}
+
+	return errs
+}
+
+// handler returns the ToolHandler that calls the webhook
+func (s *WebhookToolService) handler(tool *WebhookToolView) ToolHandler {
+	return func(ctx context.Context, userID uuid.UUID, arguments string) (*ToolResult, error) {
+		req, err := buildWebhookRequest(ctx, tool, userID, arguments)
+		if err != nil {
+			return &ToolResult{Success: false, Error: err.Error()}, nil
+		}
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			if errors.Is(err, safehttp.ErrBlockedAddress) {
+				logging.Warn("blocked webhook tool request", "tool", tool.Name, "url", tool.URL)
+			}
+			return &ToolResult{Success: false, Error: fmt.Sprintf("webhook request failed: %v", err)}, nil
+		}
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(io.LimitReader(resp.Body, s.cfg.MaxResponseBytes+1))
+		if err != nil {
+			return &ToolResult{Success: false, Error: fmt.Sprintf("failed to read webhook response: %v", err)}, nil
+		}
+		if int64(len(body)) > s.cfg.MaxResponseBytes {
+			return &ToolResult{
+				Success: false,
+				Error:   fmt.Sprintf("webhook response exceeded %d bytes", s.cfg.MaxResponseBytes),
+			}, nil
+		}
+
+		return webhookToolResult(tool, resp.StatusCode, body), nil
+	}
+}
+
+// buildWebhookRequest sends the arguments as a JSON body, or as query
+// parameters for GET and DELETE, with secret references in headers resolved
+func buildWebhookRequest(ctx context.Context, tool *WebhookToolView, userID uuid.UUID, arguments string) (*http.Request, error) {
+	if strings.TrimSpace(arguments) == "" {
+		arguments = "{}"
+	}
+
+	target := tool.URL
+	var body io.Reader
+	if tool.Method == http.MethodGet || tool.Method == http.MethodDelete {
+		var args map[string]interface{}
+		if err := json.Unmarshal([]byte(arguments), &args); err != nil {
+			return nil, fmt.Errorf("invalid arguments: %w", err)
+		}
+		u, err := url.Parse(tool.URL)
+		if err != nil {
+			return nil, err
+		}
+		query := u.Query()
+		for name, value := range args {
+			addQueryValue(query, name, value)
+		}
+		u.RawQuery = query.Encode()
+		target = u.String()
+	} else {
+		body = bytes.NewReader([]byte(arguments))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, tool.Method, target, body)
+	if err != nil {
+		return nil, err
+	}
+
+	for name, value := range tool.Headers {
+		resolved, err := resolveWebhookSecrets(value)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set(name, resolved)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if req.Header.Get("Accept") == "" {
+		req.Header.Set("Accept", "application/json")
+	}
+	req.Header.Set("User-Agent", "chatbot-api-webhook/1.0")
+	req.Header.Set("X-Chatbot-User-Id", userID.String())
+	return req, nil
+}
+
+// resolveWebhookSecrets replaces {{secret.NAME}} with the TOOL_SECRET_NAME environment variable
+func resolveWebhookSecrets(value string) (string, error) {
+	var missing string
+	resolved := webhookSecretRefPattern.ReplaceAllStringFunc(value, func(ref string) string {
+		name := webhookSecretRefPattern.FindStringSubmatch(ref)[1]
+		secret, ok := os.LookupEnv(WebhookSecretEnvPrefix + strings.ToUpper(name))
+		if !ok {
+			missing = name
+		}
+		return secret
+	})
+	if missing != "" {
+		return "", fmt.Errorf("secret %s is not configured", missing)
+	}
+	return resolved, nil
+}
+
+func addQueryValue(query url.Values, name string, value interface{}) {
+	switch v := value.(type) {
+	case nil:
+	case string:
+		query.Add(name, v)
+	case float64:
+		query.Add(name, strconv.FormatFloat(v, 'f', -1, 64))
+	case bool:
+		query.Add(name, strconv.FormatBool(v))
+	case []interface{}:
+		for _, item := range v {
+			addQueryValue(query, name, item)
+		}
+	default:
+		data, _ := json.Marshal(v)
+		query.Add(name, string(data))
+	}
+}
+
+// webhookToolResult turns a webhook response into a tool result. JSON
+// responses are mapped through the tool's response mapping; other responses
+// are returned as text.
+func webhookToolResult(tool *WebhookToolView, status int, body []byte) *ToolResult {
+	var response interface{}
+	if err := json.Unmarshal(body, &response); err != nil {
+		response = string(body)
+	}
+
+	if status >= 300 {
+		return &ToolResult{
+			Success: false,
+			Error:   fmt.Sprintf("webhook returned status %d", status),
+			Data:    map[string]interface{}{"status": status, "response": response},
+		}
+	}
+
+	data := map[string]interface{}{"response": response}
+	if len(tool.ResponseMapping) > 0 {
+		data = make(map[string]interface{}, len(tool.ResponseMapping))
+		for field, path := range tool.ResponseMapping {
+			data[field] = lookupJSONPath(response, path)
+		}
+	}
+
+	return &ToolResult{
+		Success: true,
+		Message: fmt.Sprintf("%s completed", tool.Name),
+		Data:    data,
+	}
+}
+
+// lookupJSONPath follows a dotted path such as "order.items.0.id" into a
+// decoded JSON value. Missing paths yield nil.
+func lookupJSONPath(value interface{}, path string) interface{} {
+	for _, key := range strings.Split(path, ".") {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value = v[key]
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(v) {
+				return nil
+			}
+			value = v[i]
+		default:
+			return nil
+		}
+	}
+	return value
+}
+
+func webhookToolToView(tool database.WebhookTool) (*WebhookToolView, error) {
+	view := &WebhookToolView{
+		ID:             tool.ID,
+		Name:           tool.Name,
+		Description:    tool.Description,
+		URL:            tool.URL,
+		Method:         tool.Method,
+		TimeoutSeconds: int(tool.TimeoutSeconds),
+		Enabled:        tool.Enabled,
+		CreatedAt:      tool.CreatedAt,
+		UpdatedAt:      tool.UpdatedAt,
+	}
+	if err := json.Unmarshal(tool.Parameters, &view.Parameters); err != nil {
+		return nil, fmt.Errorf("invalid parameters of webhook tool %s: %w", tool.Name, err)
+	}
+	if err := json.Unmarshal(tool.Headers, &view.Headers); err != nil {
+		return nil, fmt.Errorf("invalid headers of webhook tool %s: %w", tool.Name, err)
+	}
+	if err := json.Unmarshal(tool.ResponseMapping, &view.ResponseMapping); err != nil {
+		return nil, fmt.Errorf("invalid response mapping of webhook tool %s: %w", tool.Name, err)
+	}
+	return view, nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func containsFold(values []string, s string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
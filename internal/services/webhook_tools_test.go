@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func validWebhookToolInput() *WebhookToolInput {
+	return &WebhookToolInput{
+		Name:           "lookup_order",
+		Description:    "Look up an order",
+		Parameters:     map[string]interface{}{"type": "object", "properties": map[string]interface{}{}},
+		URL:            "https://crm.example.com/orders",
+		Method:         http.MethodGet,
+		Headers:        map[string]string{"Authorization": "Bearer {{secret.CRM_TOKEN}}"},
+		TimeoutSeconds: 10,
+	}
+}
+
+func TestValidateWebhookToolInput(t *testing.T) {
+	if errs := validateWebhookToolInput(validWebhookToolInput(), false); len(errs) != 0 {
+		t.Fatalf("expected valid input, got %v", errs)
+	}
+
+	tests := map[string]struct {
+		field  string
+		modify func(*WebhookToolInput)
+	}{
+		"bad name":          {"name", func(in *WebhookToolInput) { in.Name = "1tool" }},
+		"mcp separator":     {"name", func(in *WebhookToolInput) { in.Name = "a__b" }},
+		"no description":    {"description", func(in *WebhookToolInput) { in.Description = " " }},
+		"non-object schema": {"parameters", func(in *WebhookToolInput) { in.Parameters = map[string]interface{}{"type": "string"} }},
+		"file url":          {"url", func(in *WebhookToolInput) { in.URL = "file:///etc/passwd" }},
+		"metadata url":      {"url", func(in *WebhookToolInput) { in.URL = "http://169.254.169.254/latest/meta-data" }},
+		"bad method":        {"method", func(in *WebhookToolInput) { in.Method = "TRACE" }},
+		"reserved header":   {"headers", func(in *WebhookToolInput) { in.Headers = map[string]string{"Host": "internal"} }},
+		"header injection":  {"headers", func(in *WebhookToolInput) { in.Headers = map[string]string{"X-A": "a\r\nX-B: b"} }},
+		"timeout too long":  {"timeout_seconds", func(in *WebhookToolInput) { in.TimeoutSeconds = 120 }},
+		"empty mapping":     {"response_mapping", func(in *WebhookToolInput) { in.ResponseMapping = map[string]string{"status": ""} }},
+	}
+	for name, tt := range tests {
+		input := validWebhookToolInput()
+		tt.modify(input)
+		if errs := validateWebhookToolInput(input, false); errs[tt.field] == "" {
+			t.Errorf("%s: expected an error for %s, got %v", name, tt.field, errs)
+		}
+	}
+
+	// Private addresses are accepted when explicitly allowed
+	input := validWebhookToolInput()
+	input.URL = "http://10.0.0.5/hook"
+	if errs := validateWebhookToolInput(input, true); len(errs) != 0 {
+		t.Errorf("expected private URL to be allowed, got %v", errs)
+	}
+}
+
+func TestResolveWebhookSecrets(t *testing.T) {
+	t.Setenv("TOOL_SECRET_CRM_TOKEN", "s3cret")
+	t.Setenv("JWT_SECRET", "do-not-leak")
+
+	got, err := resolveWebhookSecrets("Bearer {{ secret.crm_token }}")
+	if err != nil || got != "Bearer s3cret" {
+		t.Fatalf("resolveWebhookSecrets() = %q, %v", got, err)
+	}
+
+	// Only TOOL_SECRET_ variables can be referenced
+	if _, err := resolveWebhookSecrets("{{secret.JWT_SECRET}}"); err == nil {
+		t.Fatal("expected a reference outside the secret prefix to fail")
+	}
+}
+
+func TestWebhookToolHandler(t *testing.T) {
+	t.Setenv("TOOL_SECRET_CRM_TOKEN", "s3cret")
+
+	var gotReq *http.Request
+	var gotBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotReq = r
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		switch r.URL.Path {
+		case "/large":
+			w.Write([]byte(strings.Repeat("x", 100)))
+		case "/fail":
+			w.WriteHeader(http.StatusBadGateway)
+			w.Write([]byte(`{"error":"upstream down"}`))
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{"order":{"status":"shipped","items":[{"sku":"A1"}]}}`))
+		}
+	}))
+	defer server.Close()
+
+	service := NewWebhookToolService(nil, NewToolRegistry(), WebhookToolsConfig{AllowPrivateNetworks: true, MaxResponseBytes: 64})
+	userID := uuid.New()
+	ctx := context.Background()
+
+	tool := &WebhookToolView{
+		Name:            "lookup_order",
+		URL:             server.URL + "/orders",
+		Method:          http.MethodGet,
+		Headers:         map[string]string{"Authorization": "Bearer {{secret.CRM_TOKEN}}"},
+		ResponseMapping: map[string]string{"status": "order.status", "first_sku": "order.items.0.sku", "missing": "order.total"},
+	}
+	result, _ := service.handler(tool)(ctx, userID, `{"order_id":"42","expand":["items"]}`)
+	if !result.Success {
+		t.Fatalf("expected success, got %+v", result)
+	}
+	if result.Data["status"] != "shipped" || result.Data["first_sku"] != "A1" || result.Data["missing"] != nil {
+		t.Errorf("unexpected mapped data: %+v", result.Data)
+	}
+	if gotReq.URL.Query().Get("order_id") != "42" || gotReq.URL.Query().Get("expand") != "items" {
+		t.Errorf("expected arguments as query parameters, got %s", gotReq.URL.RawQuery)
+	}
+	if gotReq.Header.Get("Authorization") != "Bearer s3cret" || gotReq.Header.Get("X-Chatbot-User-Id") != userID.String() {
+		t.Errorf("unexpected headers: %v", gotReq.Header)
+	}
+
+	tool = &WebhookToolView{Name: "create_ticket", URL: server.URL + "/tickets", Method: http.MethodPost}
+	result, _ = service.handler(tool)(ctx, userID, `{"title":"Broken"}`)
+	if !result.Success || gotBody != `{"title":"Broken"}` || gotReq.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("expected arguments as JSON body, got %q (%+v)", gotBody, result)
+	}
+	if response, _ := result.Data["response"].(map[string]interface{}); response["order"] == nil {
+		t.Errorf("expected the whole response without a mapping, got %+v", result.Data)
+	}
+
+	tool = &WebhookToolView{Name: "fail", URL: server.URL + "/fail", Method: http.MethodPost}
+	result, _ = service.handler(tool)(ctx, userID, "")
+	if result.Success || result.Data["status"] != http.StatusBadGateway {
+		t.Errorf("expected error status to fail the call, got %+v", result)
+	}
+
+	tool = &WebhookToolView{Name: "large", URL: server.URL + "/large", Method: http.MethodPost}
+	result, _ = service.handler(tool)(ctx, userID, "{}")
+	if result.Success || !strings.Contains(result.Error, "exceeded 64 bytes") {
+		t.Errorf("expected oversized response to fail, got %+v", result)
+	}
+}
+
+func TestWebhookToolHandlerBlocksPrivateNetworks(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	service := NewWebhookToolService(nil, NewToolRegistry(), WebhookToolsConfig{})
+	tool := &WebhookToolView{Name: "internal", URL: server.URL, Method: http.MethodPost}
+
+	result, _ := service.handler(tool)(context.Background(), uuid.New(), "{}")
+	if result.Success || called {
+		t.Fatalf("expected request to a loopback address to be blocked, got %+v", result)
+	}
+}
+
+func TestWebhookToolInputNamesTakenByBuiltInTools(t *testing.T) {
+	registry := NewToolRegistry()
+	registry.Register("add_understanding", ToolDefinition{Name: "add_understanding"}, nil)
+	service := NewWebhookToolService(nil, registry, WebhookToolsConfig{})
+
+	input := validWebhookToolInput()
+	input.Name = "add_understanding"
+	_, err := service.prepare("", input)
+
+	validationErr, ok := err.(*WebhookToolValidationError)
+	if !ok || validationErr.Fields["name"] == "" {
+		t.Fatalf("expected a name conflict, got %v", err)
+	}
+
+	// Defaults are filled in before encoding
+	input = &WebhookToolInput{Name: "ping_service", Description: "Ping", URL: "https://example.com"}
+	params, err := service.prepare("", input)
+	if err != nil {
+		t.Fatalf("prepare() error = %v", err)
+	}
+	var schema map[string]interface{}
+	json.Unmarshal(params.parameters, &schema)
+	if input.Method != http.MethodPost || input.TimeoutSeconds != DefaultWebhookTimeoutSeconds || schema["type"] != "object" {
+		t.Errorf("unexpected defaults: %+v, %v", input, schema)
+	}
+}
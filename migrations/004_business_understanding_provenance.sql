@@ -0,0 +1,31 @@
+-- Migration: Business Understanding Field Provenance
+-- Purpose: Record who set each business understanding field (model tool call or user edit) and when,
+-- so users can correct mistakes and we can audit what the model inferred
+
+-- Provenance table
+-- One row per field, overwritten each time the field changes
+CREATE TABLE IF NOT EXISTS business_understanding_provenance (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    understanding_id UUID NOT NULL REFERENCES business_understanding(id) ON DELETE CASCADE,
+
+    -- Field name as exposed by the API (e.g. 'industry', 'pain_points')
+    field_name VARCHAR(100) NOT NULL,
+
+    -- Who set the field
+    source VARCHAR(50) NOT NULL, -- 'tool', 'user'
+
+    -- Chat context for tool-sourced values
+    session_id UUID REFERENCES chat_sessions(id) ON DELETE SET NULL,
+    message_id UUID REFERENCES chat_messages(id) ON DELETE SET NULL,
+    tool_name VARCHAR(100),
+    tool_call_id VARCHAR(255),
+
+    -- When the field was last set
+    set_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    -- One provenance record per field
+    UNIQUE(understanding_id, field_name)
+);
+
+CREATE INDEX IF NOT EXISTS idx_business_understanding_provenance_session ON business_understanding_provenance(session_id)
+    WHERE session_id IS NOT NULL;
@@ -0,0 +1,35 @@
+-- Migration: Business Understanding Change Log
+-- Purpose: Append-only history of every change to a user's business understanding,
+-- so mistakes recorded by the model can be reviewed and undone
+
+-- Change log table
+-- Rows are only ever inserted; reverting writes a new row pointing at the reverted change
+CREATE TABLE IF NOT EXISTS business_understanding_changes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+    -- Who made the change
+    source VARCHAR(50) NOT NULL, -- 'tool', 'user', 'revert'
+
+    -- Chat context for tool-sourced changes
+    session_id UUID REFERENCES chat_sessions(id) ON DELETE SET NULL,
+    message_id UUID REFERENCES chat_messages(id) ON DELETE SET NULL,
+    tool_name VARCHAR(100),
+    tool_call_id VARCHAR(255),
+
+    -- What changed (field names as exposed by the API)
+    changed_fields TEXT[] NOT NULL DEFAULT '{}',
+
+    -- Full snapshots of the understanding before and after the change
+    before_snapshot JSONB NOT NULL DEFAULT '{}'::jsonb,
+    after_snapshot JSONB NOT NULL DEFAULT '{}'::jsonb,
+
+    -- Set when this change undid an earlier one
+    reverted_change_id UUID REFERENCES business_understanding_changes(id) ON DELETE SET NULL,
+
+    -- Timestamps
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Index for listing a user's history newest first
+CREATE INDEX IF NOT EXISTS idx_business_understanding_changes_user ON business_understanding_changes(user_id, created_at DESC);
@@ -0,0 +1,76 @@
+-- Migration: Organizations and Shared Business Understanding
+-- Purpose: Let colleagues share one company-level business understanding
+-- that each member's personal understanding is layered on top of
+
+-- Organizations table
+CREATE TABLE IF NOT EXISTS organizations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+
+    -- Timestamps
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Trigger for updated_at
+CREATE TRIGGER update_organizations_updated_at
+    BEFORE UPDATE ON organizations
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
+
+-- Organization members table
+-- A user belongs to at most one organization, so there is never any doubt
+-- which company context applies to their chats
+CREATE TABLE IF NOT EXISTS organization_members (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(50) NOT NULL DEFAULT 'member', -- 'owner', 'admin', 'member'
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    UNIQUE(user_id)
+);
+
+-- Index for listing an organization's members
+CREATE INDEX IF NOT EXISTS idx_organization_members_org ON organization_members(organization_id);
+
+-- Organization business understanding table
+-- Company-level fields only; personal fields (name, job title, role, daily activities)
+-- stay on each member's business_understanding row
+CREATE TABLE IF NOT EXISTS organization_understanding (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+
+    -- Company Context
+    business_name VARCHAR(500),
+    industry VARCHAR(255),
+    business_size VARCHAR(50), -- '1-10', '11-50', '51-200', '201-1000', '1000+'
+
+    -- Workflows, Challenges & Goals (JSONB arrays, as in business_understanding)
+    key_workflows JSONB NOT NULL DEFAULT '[]'::jsonb,
+    pain_points JSONB NOT NULL DEFAULT '[]'::jsonb,
+    bottlenecks JSONB NOT NULL DEFAULT '[]'::jsonb,
+    manual_tasks JSONB NOT NULL DEFAULT '[]'::jsonb,
+    automation_goals JSONB NOT NULL DEFAULT '[]'::jsonb,
+
+    -- Current Tech Stack
+    current_software JSONB NOT NULL DEFAULT '[]'::jsonb,
+    existing_automation JSONB NOT NULL DEFAULT '[]'::jsonb,
+
+    -- Additional Context
+    additional_notes TEXT,
+
+    -- Timestamps
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    -- Ensure one understanding record per organization
+    UNIQUE(organization_id)
+);
+
+-- Trigger for updated_at
+CREATE TRIGGER update_organization_understanding_updated_at
+    BEFORE UPDATE ON organization_understanding
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
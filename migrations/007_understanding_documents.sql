@@ -0,0 +1,72 @@
+-- Migration: Schema-Driven Business Understanding Documents
+-- Purpose: Store business understanding as a single JSONB document keyed by field name,
+-- so fields can be added through the understanding schema without a migration
+
+-- Personal understanding
+ALTER TABLE business_understanding ADD COLUMN IF NOT EXISTS data JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+-- Copy existing columns into the document, leaving out empty values
+UPDATE business_understanding SET data = jsonb_strip_nulls(jsonb_build_object(
+    'user_name', NULLIF(user_name, ''),
+    'job_title', NULLIF(job_title, ''),
+    'business_name', NULLIF(business_name, ''),
+    'industry', NULLIF(industry, ''),
+    'business_size', NULLIF(business_size, ''),
+    'user_role', NULLIF(user_role, ''),
+    'key_workflows', NULLIF(key_workflows, '[]'::jsonb),
+    'daily_activities', NULLIF(daily_activities, '[]'::jsonb),
+    'pain_points', NULLIF(pain_points, '[]'::jsonb),
+    'bottlenecks', NULLIF(bottlenecks, '[]'::jsonb),
+    'manual_tasks', NULLIF(manual_tasks, '[]'::jsonb),
+    'automation_goals', NULLIF(automation_goals, '[]'::jsonb),
+    'current_software', NULLIF(current_software, '[]'::jsonb),
+    'existing_automation', NULLIF(existing_automation, '[]'::jsonb),
+    'additional_notes', NULLIF(additional_notes, '')
+));
+
+ALTER TABLE business_understanding
+    DROP COLUMN IF EXISTS user_name,
+    DROP COLUMN IF EXISTS job_title,
+    DROP COLUMN IF EXISTS business_name,
+    DROP COLUMN IF EXISTS industry,
+    DROP COLUMN IF EXISTS business_size,
+    DROP COLUMN IF EXISTS user_role,
+    DROP COLUMN IF EXISTS key_workflows,
+    DROP COLUMN IF EXISTS daily_activities,
+    DROP COLUMN IF EXISTS pain_points,
+    DROP COLUMN IF EXISTS bottlenecks,
+    DROP COLUMN IF EXISTS manual_tasks,
+    DROP COLUMN IF EXISTS automation_goals,
+    DROP COLUMN IF EXISTS current_software,
+    DROP COLUMN IF EXISTS existing_automation,
+    DROP COLUMN IF EXISTS additional_notes;
+
+-- Organization understanding
+ALTER TABLE organization_understanding ADD COLUMN IF NOT EXISTS data JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+UPDATE organization_understanding SET data = jsonb_strip_nulls(jsonb_build_object(
+    'business_name', NULLIF(business_name, ''),
+    'industry', NULLIF(industry, ''),
+    'business_size', NULLIF(business_size, ''),
+    'key_workflows', NULLIF(key_workflows, '[]'::jsonb),
+    'pain_points', NULLIF(pain_points, '[]'::jsonb),
+    'bottlenecks', NULLIF(bottlenecks, '[]'::jsonb),
+    'manual_tasks', NULLIF(manual_tasks, '[]'::jsonb),
+    'automation_goals', NULLIF(automation_goals, '[]'::jsonb),
+    'current_software', NULLIF(current_software, '[]'::jsonb),
+    'existing_automation', NULLIF(existing_automation, '[]'::jsonb),
+    'additional_notes', NULLIF(additional_notes, '')
+));
+
+ALTER TABLE organization_understanding
+    DROP COLUMN IF EXISTS business_name,
+    DROP COLUMN IF EXISTS industry,
+    DROP COLUMN IF EXISTS business_size,
+    DROP COLUMN IF EXISTS key_workflows,
+    DROP COLUMN IF EXISTS pain_points,
+    DROP COLUMN IF EXISTS bottlenecks,
+    DROP COLUMN IF EXISTS manual_tasks,
+    DROP COLUMN IF EXISTS automation_goals,
+    DROP COLUMN IF EXISTS current_software,
+    DROP COLUMN IF EXISTS existing_automation,
+    DROP COLUMN IF EXISTS additional_notes;
@@ -0,0 +1,25 @@
+-- Migration: Understanding Interview State
+-- Purpose: Remember per chat session which business understanding questions the
+-- assistant has been told to ask and which report the user is working towards,
+-- so follow-up questions are not repeated
+
+CREATE TABLE IF NOT EXISTS understanding_interviews (
+    session_id UUID PRIMARY KEY REFERENCES chat_sessions(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+    -- Report type last requested in the session, used to rank questions
+    report_type VARCHAR(50),
+
+    -- Fields whose questions have already been suggested to the assistant
+    asked_fields TEXT[] NOT NULL DEFAULT '{}',
+
+    -- Timestamps
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Trigger for updated_at
+CREATE TRIGGER update_understanding_interviews_updated_at
+    BEFORE UPDATE ON understanding_interviews
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,37 @@
+-- Migration: Tool Call Confirmations
+-- Purpose: Hold tool calls whose policy requires the user's approval until the
+-- user approves or rejects them, and keep the outcome for the session
+
+CREATE TABLE IF NOT EXISTS tool_call_confirmations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    session_id UUID NOT NULL REFERENCES chat_sessions(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    message_id UUID REFERENCES chat_messages(id) ON DELETE SET NULL,
+
+    -- The call as the model made it
+    tool_call_id VARCHAR(255) NOT NULL,
+    tool_name VARCHAR(100) NOT NULL,
+    arguments TEXT NOT NULL,
+
+    -- pending until the user decides; the tool only runs once approved
+    status VARCHAR(20) NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'approved', 'rejected')),
+    result JSONB,
+    resolved_at TIMESTAMPTZ,
+
+    -- Timestamps
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    UNIQUE (session_id, tool_call_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tool_call_confirmations_pending
+    ON tool_call_confirmations(session_id, created_at)
+    WHERE status = 'pending';
+
+-- Trigger for updated_at
+CREATE TRIGGER update_tool_call_confirmations_updated_at
+    BEFORE UPDATE ON tool_call_confirmations
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,37 @@
+-- Migration: Webhook Tools
+-- Purpose: Let admins define tools that call an external HTTP endpoint without
+-- a code change. Header values reference secrets by name; the secrets
+-- themselves stay in the environment.
+
+CREATE TABLE IF NOT EXISTS webhook_tools (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+
+    -- What the model sees
+    name VARCHAR(64) NOT NULL UNIQUE,
+    description TEXT NOT NULL,
+    parameters JSONB NOT NULL DEFAULT '{"type": "object", "properties": {}}',
+
+    -- The request the tool makes
+    url TEXT NOT NULL,
+    method VARCHAR(10) NOT NULL DEFAULT 'POST'
+        CHECK (method IN ('GET', 'POST', 'PUT', 'PATCH', 'DELETE')),
+    headers JSONB NOT NULL DEFAULT '{}',
+    timeout_seconds INTEGER NOT NULL DEFAULT 10
+        CHECK (timeout_seconds BETWEEN 1 AND 60),
+
+    -- Result field -> path into the JSON response; empty returns the whole response
+    response_mapping JSONB NOT NULL DEFAULT '{}',
+
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+
+    -- Timestamps
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Trigger for updated_at
+CREATE TRIGGER update_webhook_tools_updated_at
+    BEFORE UPDATE ON webhook_tools
+    FOR EACH ROW
+    EXECUTE FUNCTION update_updated_at_column();
@@ -0,0 +1,20 @@
+-- Migration: Chat Attachments
+-- Purpose: Store files users upload to a chat session, such as spreadsheets of
+-- their operations, so the run_analysis tool can read them. Files are scoped
+-- to one session and deleted with it.
+
+CREATE TABLE IF NOT EXISTS chat_attachments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    session_id UUID NOT NULL REFERENCES chat_sessions(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+    -- Scripts refer to files by name, so names are unique within a session
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(127) NOT NULL,
+    size_bytes INTEGER NOT NULL CHECK (size_bytes >= 0),
+    data BYTEA NOT NULL,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    UNIQUE (session_id, filename)
+);
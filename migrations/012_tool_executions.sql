@@ -0,0 +1,31 @@
+-- Migration: Tool Executions
+-- Purpose: Audit log of every tool call, so bad calls can be traced back to the
+-- chat message that triggered them. Arguments are stored redacted and
+-- truncated, with a hash of the original for grouping identical calls.
+
+CREATE TABLE IF NOT EXISTS tool_executions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+
+    -- Chat context; empty for calls made outside a chat, such as over MCP
+    session_id UUID REFERENCES chat_sessions(id) ON DELETE SET NULL,
+    message_id UUID REFERENCES chat_messages(id) ON DELETE SET NULL,
+    tool_call_id VARCHAR(255),
+
+    tool_name VARCHAR(255) NOT NULL,
+    arguments_hash CHAR(64) NOT NULL, -- SHA-256 of the arguments as sent
+    arguments JSONB NOT NULL,
+
+    -- ok, failed, invalid_arguments, unknown_tool or error
+    outcome VARCHAR(32) NOT NULL,
+    success BOOLEAN NOT NULL,
+    error TEXT,
+    duration_ms INTEGER NOT NULL,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_tool_executions_created_at ON tool_executions(created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_tool_executions_tool_name ON tool_executions(tool_name, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_tool_executions_user_id ON tool_executions(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_tool_executions_session_id ON tool_executions(session_id);
@@ -0,0 +1,11 @@
+-- Migration: Session Tools
+-- Purpose: Let a chat session offer a chosen set of tools and steer tool use,
+-- either directly or through an agent profile picked when it is created.
+
+ALTER TABLE chat_sessions
+    -- Agent profile the session was created from, if any
+    ADD COLUMN IF NOT EXISTS profile VARCHAR(64),
+    -- Tools offered to the model; NULL offers every tool, an empty array none
+    ADD COLUMN IF NOT EXISTS tools TEXT[],
+    -- auto, required, none or a tool name; NULL is auto
+    ADD COLUMN IF NOT EXISTS tool_choice VARCHAR(255);
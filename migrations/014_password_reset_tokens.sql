@@ -0,0 +1,14 @@
+-- Migration: Password Reset Tokens
+-- Purpose: Let users who forgot their password set a new one through an
+-- emailed link. Only a SHA-256 hash of each token is stored; a token is
+-- deleted when it is used, and requesting a new one replaces the old.
+
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens(user_id);
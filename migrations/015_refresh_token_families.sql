@@ -0,0 +1,26 @@
+-- Migration: Refresh Token Families
+-- Purpose: Detect replay of stolen refresh tokens. Every token issued by
+-- rotating another joins the family started at sign-in. A rotated token is
+-- kept until it expires; presenting it again means two parties hold the same
+-- session, so the whole family is revoked.
+
+ALTER TABLE refresh_tokens
+    -- Shared by all tokens descending from one sign-in
+    ADD COLUMN IF NOT EXISTS family_id UUID NOT NULL DEFAULT uuid_generate_v4(),
+    -- When the token was exchanged for a new one; NULL if it never was
+    ADD COLUMN IF NOT EXISTS rotated_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family ON refresh_tokens(family_id);
+
+-- Keep rotated tokens until they expire so their reuse can be recognised
+CREATE OR REPLACE FUNCTION clean_expired_tokens()
+RETURNS INTEGER AS $$
+DECLARE
+    deleted_count INTEGER;
+BEGIN
+    DELETE FROM refresh_tokens
+    WHERE expires_at < NOW() OR (revoked = TRUE AND rotated_at IS NULL);
+    GET DIAGNOSTICS deleted_count = ROW_COUNT;
+    RETURN deleted_count;
+END;
+$$ LANGUAGE plpgsql;
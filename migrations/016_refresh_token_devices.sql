@@ -0,0 +1,12 @@
+-- Migration: Refresh Token Devices
+-- Purpose: Let users see where they are signed in and sign out a device. Each
+-- refresh token family is one device; its tokens record the client they were
+-- issued to. Only a keyed hash of the IP address is stored.
+
+ALTER TABLE refresh_tokens
+    ADD COLUMN IF NOT EXISTS user_agent TEXT,
+    ADD COLUMN IF NOT EXISTS ip_hash VARCHAR(64),
+    -- When the family was started; copied to every token issued by rotation
+    ADD COLUMN IF NOT EXISTS signed_in_at TIMESTAMPTZ NOT NULL DEFAULT NOW();
+
+UPDATE refresh_tokens SET signed_in_at = created_at WHERE created_at IS NOT NULL;
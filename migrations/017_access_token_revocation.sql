@@ -0,0 +1,19 @@
+-- Migration: Access Token Revocation
+-- Purpose: End sessions immediately instead of when their access tokens
+-- expire. A single token is revoked by its jti; every token of a user issued
+-- before tokens_valid_after is rejected, for logging out everywhere or
+-- locking an account out.
+
+ALTER TABLE users
+    -- Access tokens issued before this time are rejected; NULL rejects none
+    ADD COLUMN IF NOT EXISTS tokens_valid_after TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS revoked_access_tokens (
+    jti UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- When the token expires anyway; the row can be deleted after that
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_access_tokens_user ON revoked_access_tokens(user_id);
+CREATE INDEX IF NOT EXISTS idx_revoked_access_tokens_expires ON revoked_access_tokens(expires_at);
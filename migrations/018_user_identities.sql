@@ -0,0 +1,26 @@
+-- Migration: User Identities
+-- Purpose: Let one user sign in with several methods. Each OAuth account a
+-- user signs in with is an identity, matched by provider and the user's ID
+-- at the provider; the password stays on users. users.provider and
+-- provider_id keep recording how the account was created.
+
+CREATE TABLE IF NOT EXISTS user_identities (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    provider_id VARCHAR(255) NOT NULL,
+    -- The address the provider reported when the identity was linked
+    email VARCHAR(255),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ,
+    UNIQUE (provider, provider_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities(user_id);
+
+-- Accounts created through OAuth before identities existed
+INSERT INTO user_identities (user_id, provider, provider_id, email, created_at)
+SELECT id, provider, provider_id, email, COALESCE(created_at, NOW())
+FROM users
+WHERE provider IS NOT NULL AND provider <> 'local' AND provider_id IS NOT NULL
+ON CONFLICT (provider, provider_id) DO NOTHING;
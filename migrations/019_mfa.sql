@@ -0,0 +1,28 @@
+-- Migration: Multi-Factor Authentication
+-- Purpose: Let users protect their account with a TOTP authenticator app.
+-- Secrets are stored encrypted; recovery codes, which each work once when
+-- the app is unavailable, only as SHA-256 hashes. Admins can require MFA
+-- of a user, who then can only reach their account settings until they enroll.
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS mfa_required BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS user_totp (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    -- AES-GCM encrypted secret, prefixed with its nonce
+    secret_encrypted BYTEA NOT NULL,
+    -- NULL until the user confirms enrollment with a code
+    enabled_at TIMESTAMPTZ,
+    -- The newest time step a code was accepted for, so codes can't be replayed
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS mfa_recovery_codes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (user_id, code_hash)
+);
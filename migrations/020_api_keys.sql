@@ -0,0 +1,21 @@
+-- Migration: Personal API Keys
+-- Purpose: Let integrations call the API with a long-lived key instead of
+-- access and refresh tokens. Keys belong to a user and act as them, limited
+-- to their scopes. Only a SHA-256 hash of each key is stored; the prefix is
+-- kept so users can tell their keys apart.
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    -- The start of the key, e.g. agpt_1a2b3c4d
+    prefix VARCHAR(16) NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL,
+    -- NULL keys don't expire
+    expires_at TIMESTAMPTZ,
+    last_used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys(user_id);
@@ -0,0 +1,14 @@
+-- Migration: Revoked Sessions
+-- Purpose: Sign a device out immediately. Access tokens carry the refresh
+-- token family they were issued to as sid; revoking the device records the
+-- family here so its access tokens are rejected until they expire.
+
+CREATE TABLE IF NOT EXISTS revoked_sessions (
+    family_id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- When the last access token issued to the session expires; the row can be deleted after that
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_revoked_sessions_user ON revoked_sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_revoked_sessions_expires ON revoked_sessions(expires_at);
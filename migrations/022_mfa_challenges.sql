@@ -0,0 +1,17 @@
+-- Migration: MFA Challenges
+-- Purpose: Keep pending MFA sign-ins in their own table, so each code
+-- attempt is counted with one conditional UPDATE and parallel guesses can't
+-- exceed the limit, and so the challenges open for a user can be counted.
+
+CREATE TABLE IF NOT EXISTS mfa_challenges (
+    -- SHA-256 of the token handed to the client
+    token_hash CHAR(64) PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    -- Codes tried so far, including the one that completes the sign-in
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_mfa_challenges_user ON mfa_challenges(user_id);
+CREATE INDEX IF NOT EXISTS idx_mfa_challenges_expires ON mfa_challenges(expires_at);
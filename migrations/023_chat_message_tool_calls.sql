@@ -0,0 +1,12 @@
+-- Migration: Chat Message Tool Calls
+-- Purpose: Keep the tool calls of a streamed model step and their results in
+-- the chat history, so the next request shows the model what its tools
+-- returned. An assistant message lists the calls it made; each result is a
+-- 'tool' message naming the call it answers.
+
+ALTER TABLE chat_messages
+    -- Calls requested by an assistant message, as [{"id", "type", "function": {"name", "arguments"}}]
+    ADD COLUMN IF NOT EXISTS tool_calls JSONB,
+    -- For 'tool' messages: the call answered and the tool that ran
+    ADD COLUMN IF NOT EXISTS tool_call_id VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS tool_name VARCHAR(255);
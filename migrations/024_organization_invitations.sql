@@ -0,0 +1,18 @@
+-- Migration: Organization Invitations
+-- Purpose: Owners and admins invite people by email instead of adding them
+-- directly. The invited user joins only by accepting, so nobody is put into
+-- an organization (and given its business understanding) without consent.
+
+CREATE TABLE IF NOT EXISTS organization_invitations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    organization_id UUID NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    -- Lowercased, matched against the invited user's verified address
+    email VARCHAR(255) NOT NULL,
+    role VARCHAR(50) NOT NULL DEFAULT 'member', -- 'owner', 'admin', 'member'
+    invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE(organization_id, email)
+);
+
+CREATE INDEX IF NOT EXISTS idx_organization_invitations_email ON organization_invitations(email);
@@ -0,0 +1,11 @@
+-- Migration: MFA Code Attempts
+-- Purpose: Limit the codes a signed-in user can try when disabling MFA or
+-- regenerating recovery codes. Each attempt is counted with one conditional
+-- upsert, so parallel guesses can't exceed the limit.
+
+CREATE TABLE IF NOT EXISTS mfa_code_attempts (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    -- Codes tried in the current window
+    attempts INTEGER NOT NULL DEFAULT 0,
+    window_ends_at TIMESTAMPTZ NOT NULL
+);